@@ -1,17 +1,90 @@
 package main
 
 import (
+	acquirerpg "3tcapital/goclonacion/internal/adapters/acquirer/postgres"
+	auditpg "3tcapital/goclonacion/internal/adapters/audit/postgres"
+	batchpg "3tcapital/goclonacion/internal/adapters/batch/postgres"
+	contingencypg "3tcapital/goclonacion/internal/adapters/contingency/postgres"
+	currencypg "3tcapital/goclonacion/internal/adapters/currency/postgres"
+	"3tcapital/goclonacion/internal/adapters/dane/embedded"
+	danehttp "3tcapital/goclonacion/internal/adapters/dane/http"
+	danepg "3tcapital/goclonacion/internal/adapters/dane/postgres"
+	documentpg "3tcapital/goclonacion/internal/adapters/document/postgres"
+	acquirerhttp "3tcapital/goclonacion/internal/adapters/http/acquirer"
+	audithttp "3tcapital/goclonacion/internal/adapters/http/audit"
+	batchhttp "3tcapital/goclonacion/internal/adapters/http/batch"
+	cataloghttp "3tcapital/goclonacion/internal/adapters/http/catalog"
+	contingencyhttp "3tcapital/goclonacion/internal/adapters/http/contingency"
+	currencyhttp "3tcapital/goclonacion/internal/adapters/http/currency"
+	documenthttp "3tcapital/goclonacion/internal/adapters/http/document"
+	eventhttp "3tcapital/goclonacion/internal/adapters/http/event"
+	healthhttp "3tcapital/goclonacion/internal/adapters/http/health"
+	invoicehttp "3tcapital/goclonacion/internal/adapters/http/invoice"
+	ofehttp "3tcapital/goclonacion/internal/adapters/http/ofe"
+	providerhttp "3tcapital/goclonacion/internal/adapters/http/provider"
+	receptionhttp "3tcapital/goclonacion/internal/adapters/http/reception"
+	resolutionhttp "3tcapital/goclonacion/internal/adapters/http/resolution"
+	withholdinghttp "3tcapital/goclonacion/internal/adapters/http/withholding"
+	idempotencypg "3tcapital/goclonacion/internal/adapters/idempotency/postgres"
+	"3tcapital/goclonacion/internal/adapters/invoice/dian"
+	"3tcapital/goclonacion/internal/adapters/invoice/numrot"
+	"3tcapital/goclonacion/internal/adapters/invoice/routing"
+	"3tcapital/goclonacion/internal/adapters/invoice/ubl"
+	"3tcapital/goclonacion/internal/adapters/invoice/xades"
+	ofepg "3tcapital/goclonacion/internal/adapters/ofe/postgres"
+	providerpg "3tcapital/goclonacion/internal/adapters/provider/postgres"
+	resolutionpg "3tcapital/goclonacion/internal/adapters/resolution/postgres"
+	withholdingpg "3tcapital/goclonacion/internal/adapters/withholding/postgres"
+	appacquirer "3tcapital/goclonacion/internal/application/acquirer"
+	appaudit "3tcapital/goclonacion/internal/application/audit"
+	appbatch "3tcapital/goclonacion/internal/application/batch"
+	appcatalog "3tcapital/goclonacion/internal/application/catalog"
+	appcontingency "3tcapital/goclonacion/internal/application/contingency"
+	appcurrency "3tcapital/goclonacion/internal/application/currency"
+	appdocument "3tcapital/goclonacion/internal/application/document"
+	appevent "3tcapital/goclonacion/internal/application/event"
+	apphealth "3tcapital/goclonacion/internal/application/health"
+	appinvoice "3tcapital/goclonacion/internal/application/invoice"
+	appofe "3tcapital/goclonacion/internal/application/ofe"
+	appprovider "3tcapital/goclonacion/internal/application/provider"
+	appresolution "3tcapital/goclonacion/internal/application/resolution"
+	appwithholding "3tcapital/goclonacion/internal/application/withholding"
+	"3tcapital/goclonacion/internal/core/acquirer"
 	"3tcapital/goclonacion/internal/core/audit"
+	"3tcapital/goclonacion/internal/core/batch"
+	"3tcapital/goclonacion/internal/core/contingency"
+	"3tcapital/goclonacion/internal/core/cufe"
+	"3tcapital/goclonacion/internal/core/currency"
+	"3tcapital/goclonacion/internal/core/dane"
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/idempotency"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/ofe"
+	"3tcapital/goclonacion/internal/core/provider"
+	"3tcapital/goclonacion/internal/core/resolution"
+	"3tcapital/goclonacion/internal/core/withholding"
 	"3tcapital/goclonacion/internal/infrastructure/config"
+	"3tcapital/goclonacion/internal/infrastructure/database"
+	infrahttp "3tcapital/goclonacion/internal/infrastructure/http"
 	"3tcapital/goclonacion/internal/infrastructure/http/server"
 	"3tcapital/goclonacion/internal/infrastructure/logger"
+	"3tcapital/goclonacion/internal/infrastructure/metrics"
+	"3tcapital/goclonacion/internal/infrastructure/security"
+	"3tcapital/goclonacion/internal/infrastructure/tracing"
 	"context"
+	"crypto/ed25519"
 	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
 )
 
@@ -33,8 +106,29 @@ func run() error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Métricas Prometheus expuestas en /metrics
+	var appMetrics *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		appMetrics = metrics.New()
+	}
+
+	// Trazas OpenTelemetry exportadas por OTLP; se exportan las pendientes al terminar
+	if cfg.Tracing.Enabled {
+		stopTracing := startTracing(cfg, log)
+		defer stopTracing()
+	}
+
+	// Llave que cifra la contraseña y el secret Numrot almacenados en el registro de OFEs
+	var credentialsCipher *security.SecretCipher
+	if cfg.InvoiceProviders.Numrot.CredentialsKey != "" {
+		credentialsCipher, err = security.NewSecretCipher(cfg.InvoiceProviders.Numrot.CredentialsKey)
+		if err != nil {
+			return fmt.Errorf("invalid NUMROT_CREDENTIALS_KEY: %w", err)
+		}
+	}
+
 	// Initialize database connection
-	var auditRepo audit.Repository
+	var repos repositories
 	var sqlDB *sql.DB
 	if cfg.Database.Host != "" && cfg.Database.Database != "" {
 		connString := fmt.Sprintf(
@@ -76,12 +170,30 @@ func run() error {
 		)
 	}
 
+	// Pool pgx para los repositorios de facturación (auditoría, adquirentes, proveedores, documentos)
+	if sqlDB != nil {
+		pool, err := newPool(ctx, cfg.Database, cfg.Tracing.Enabled, log)
+		if err != nil {
+			log.Warn("Failed to initialize invoicing database pool, audit trail and acquirer service will be disabled", "error", err)
+			log.Info("Acquirer endpoints will be available but will return 503 until database connection is established")
+		} else {
+			defer pool.Close()
+			repos = newRepositories(pool, credentialsCipher, log)
+			if err := encryptOFECredentials(ctx, repos.ofe, log); err != nil {
+				return err
+			}
+			if appMetrics != nil {
+				registerPoolMetrics(appMetrics.Registry(), pool)
+			}
+		}
+	}
+
 	// Log overall audit configuration status
 	if cfg.Audit.Enabled {
-		if auditRepo != nil {
+		if repos.audit != nil {
 			log.Info("Audit trail configuration: ENABLED",
 				"database_connected", sqlDB != nil,
-				"audit_repo_available", auditRepo != nil,
+				"audit_repo_available", repos.audit != nil,
 				"max_body_size", cfg.Audit.MaxBodySize,
 			)
 		} else {
@@ -102,6 +214,7 @@ func run() error {
 	canCreateResolutions := canCreateProvider && cfg.InvoiceProviders.Numrot.Username != "" && cfg.InvoiceProviders.Numrot.Password != ""
 	canCreateInvoices := canCreateProvider && cfg.InvoiceProviders.Numrot.Key != "" && cfg.InvoiceProviders.Numrot.Secret != ""
 
+	var invoiceProvider *numrot.Client
 	if canCreateResolutions || canCreateInvoices {
 		// Create traced HTTP client for external API calls with audit support
 		// Use Numrot-specific API timeout if configured, otherwise use HTTP ReadTimeout
@@ -120,25 +233,25 @@ func run() error {
 		}
 
 		// For document registration, disable console logging of request/response bodies
-		auditEnabled := cfg.Audit.Enabled && auditRepo != nil
+		auditEnabled := cfg.Audit.Enabled && repos.audit != nil
 
 		// Log detailed audit configuration for Numrot provider
 		if auditEnabled {
 			log.Info("Audit trail enabled for Numrot provider",
 				"max_body_size", cfg.Audit.MaxBodySize,
-				"audit_repo", auditRepo != nil,
+				"audit_repo", repos.audit != nil,
 				"provider", "numrot",
 			)
 		} else {
 			var reason string
 			if !cfg.Audit.Enabled {
 				reason = "audit disabled in configuration"
-			} else if auditRepo == nil {
+			} else if repos.audit == nil {
 				reason = "audit repository not available (database not connected)"
 			}
 			log.Warn("Audit trail disabled for Numrot provider",
 				"audit_enabled_config", cfg.Audit.Enabled,
-				"audit_repo_available", auditRepo != nil,
+				"audit_repo_available", repos.audit != nil,
 				"reason", reason,
 				"provider", "numrot",
 			)
@@ -159,6 +272,25 @@ func run() error {
 		} else {
 			log.Info("Resolution queries ENABLED - will query resolutions from API")
 		}
+
+		httpClient := infrahttp.NewTracedClient(&infrahttp.TracedClientConfig{
+			Timeout:         apiTimeout,
+			AuditEnabled:    auditEnabled,
+			LogRequestBody:  cfg.Audit.LogRequestBody,
+			LogResponseBody: cfg.Audit.LogResponseBody,
+			MaxBodySize:     cfg.Audit.MaxBodySize,
+			MaxConnsPerHost: maxConnsPerHost,
+		}, log, repos.audit, "numrot")
+		if appMetrics != nil {
+			httpClient.WithMetrics(appMetrics)
+		}
+
+		invoiceProvider = newNumrotClient(cfg, httpClient, repos, log)
+		if appMetrics != nil {
+			registerNumrotMetrics(appMetrics.Registry(), invoiceProvider)
+		}
+	} else {
+		log.Warn("Numrot provider not configured, invoicing endpoints will return 503")
 	}
 
 	// Create and start HTTP server
@@ -166,11 +298,42 @@ func run() error {
 		return fmt.Errorf("database connection required")
 	}
 
-	srv, err := server.New(server.Options{
+	opts := server.Options{
+		Config: cfg,
 		Addr:   fmt.Sprintf(":%d", cfg.HTTP.Port),
 		Logger: log,
 		DB:     sqlDB,
-	})
+	}
+
+	healthHandler := healthhttp.NewHandler(apphealth.NewService(apphealth.Metadata{
+		Service:     cfg.App.Name,
+		Version:     cfg.App.Version,
+		Environment: cfg.App.Environment,
+	}))
+	opts.HealthHandler = http.HandlerFunc(healthHandler.Status)
+	if appMetrics != nil {
+		opts.Metrics = appMetrics
+		opts.MetricsHandler = appMetrics.Handler()
+	}
+
+	municipalities := wireCatalog(ctx, &opts, repos, log)
+
+	jobs := wireInvoicing(&opts, cfg, invoiceProvider, municipalities, repos, appMetrics, log)
+	jobs = append(jobs, wireAudit(&opts, cfg, repos, log)...)
+	for _, job := range jobs {
+		job.Start(ctx)
+	}
+	if len(jobs) > 0 {
+		defer func() {
+			// Detener los workers antes de esperar; los lotes pendientes se reanudan al reiniciar
+			stop()
+			for _, job := range jobs {
+				job.Wait()
+			}
+		}()
+	}
+
+	srv, err := server.New(opts)
 	if err != nil {
 		return fmt.Errorf("create server: %w", err)
 	}
@@ -179,3 +342,452 @@ func run() error {
 	log.Info("Starting HTTP server", "port", cfg.HTTP.Port)
 	return srv.Run(ctx)
 }
+
+// repositories agrupa los repositorios Postgres de facturación.
+// Todos son nil cuando la base de datos no está disponible.
+type repositories struct {
+	audit       audit.Repository
+	acquirer    acquirer.Repository
+	provider    provider.Repository
+	document    document.Repository
+	batch       batch.Repository
+	idempotency idempotency.Repository
+	ofe         ofe.Repository
+	resolution  resolution.Repository
+	withholding withholding.Repository
+	currency    currency.Repository
+	divipola    dane.Repository
+	contingency contingency.Repository
+}
+
+func newRepositories(pool *pgxpool.Pool, credentialsCipher *security.SecretCipher, log *slog.Logger) repositories {
+	return repositories{
+		audit:       auditpg.NewRepositoryWithLogger(pool, log),
+		acquirer:    acquirerpg.NewRepository(pool, log),
+		provider:    providerpg.NewRepository(pool),
+		document:    documentpg.NewRepository(pool),
+		batch:       batchpg.NewRepository(pool),
+		idempotency: idempotencypg.NewRepository(pool),
+		ofe:         ofepg.NewRepository(pool, credentialsCipher),
+		resolution:  resolutionpg.NewRepository(pool),
+		withholding: withholdingpg.NewRepository(pool),
+		currency:    currencypg.NewRepository(pool),
+		divipola:    danepg.NewRepository(pool),
+		contingency: contingencypg.NewRepository(pool),
+	}
+}
+
+// encryptOFECredentials cifra las credenciales Numrot guardadas en texto plano antes de
+// configurar NUMROT_CREDENTIALS_KEY. Sin llave el registro rechaza guardar contraseñas y secrets.
+func encryptOFECredentials(ctx context.Context, repo ofe.Repository, log *slog.Logger) error {
+	store, ok := repo.(*ofepg.Repository)
+	if !ok {
+		return nil
+	}
+	count, err := store.EncryptStoredCredentials(ctx)
+	if errors.Is(err, ofepg.ErrCredentialsKey) {
+		log.Warn("NUMROT_CREDENTIALS_KEY not configured, Numrot passwords and secrets cannot be stored in the OFE registry")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("encrypt stored OFE credentials: %w", err)
+	}
+	if count > 0 {
+		log.Info("Encrypted plaintext Numrot credentials of the OFE registry", "ofes", count)
+	}
+	return nil
+}
+
+// newPool abre el pool pgx usado por los adaptadores de facturación y aplica las migraciones.
+// Con tracing habilitado se registra un span por cada consulta ejecutada dentro de una traza.
+func newPool(ctx context.Context, dbCfg config.DatabaseSettings, traceQueries bool, log *slog.Logger) (*pgxpool.Pool, error) {
+	pool, err := database.NewPool(ctx, database.Config{
+		Host:            dbCfg.Host,
+		Port:            dbCfg.Port,
+		Database:        dbCfg.Database,
+		User:            dbCfg.User,
+		Password:        dbCfg.Password,
+		SSLMode:         dbCfg.SSLMode,
+		MaxOpenConns:    dbCfg.MaxOpenConns,
+		MaxIdleConns:    dbCfg.MaxIdleConns,
+		ConnMaxLifetime: dbCfg.ConnMaxLifetime,
+		Tracing:         traceQueries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.RunMigrations(ctx, pool, log); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("run migrations: %w", err)
+	}
+
+	log.Info("Invoicing database pool established", "database", dbCfg.Database)
+	return pool, nil
+}
+
+// newNumrotClient construye el cliente Numrot a partir de la configuración.
+// Las credenciales por OFE se toman del registro de OFEs y de NUMROT_OFE_CREDENTIALS.
+func newNumrotClient(cfg config.AppConfig, httpClient numrot.HTTPClient, repos repositories, log *slog.Logger) *numrot.Client {
+	nc := cfg.InvoiceProviders.Numrot
+	auth := numrot.NewAuthManager(nc.BaseURL, nc.Username, nc.Password, nc.TokenTTL, httpClient, log).
+		WithRefreshBefore(nc.TokenRefreshBefore)
+
+	client := numrot.NewClientWithDSBaseURL(
+		nc.BaseURL, nc.DSBaseURL, auth, httpClient, log,
+		nc.Key, nc.Secret, nc.RadianURL, repos.acquirer,
+		cfg.DocumentProcessing.MaxConcurrentRequests,
+		cfg.DocumentProcessing.BatchSize,
+		cfg.DocumentProcessing.RateLimitRPS,
+		nc.ResolutionsEnabled,
+		nc.HardcodedInvoiceAuth, nc.HardcodedStartDate, nc.HardcodedEndDate,
+		nc.HardcodedPrefix, nc.HardcodedFrom, nc.HardcodedTo,
+		nc.NCInvoicePeriodStartDate, nc.NCInvoicePeriodStartTime,
+		nc.NCInvoicePeriodEndDate, nc.NCInvoicePeriodEndTime,
+	).(*numrot.Client)
+
+	configured, err := numrot.ParseCredentials(nc.Credentials)
+	if err != nil {
+		log.Warn("Invalid NUMROT_OFE_CREDENTIALS, configured OFE credentials will be ignored", "error", err)
+		configured = nil
+	}
+	if len(configured) > 0 || repos.ofe != nil {
+		client.WithCredentials(numrot.NewOFECredentials(configured, repos.ofe))
+		log.Info("Numrot credentials per OFE enabled", "configured_ofes", len(configured), "registry", repos.ofe != nil)
+	}
+
+	return client
+}
+
+// wireCatalog construye el catálogo DIVIPOLA embebido, lo reemplaza por el de la tabla
+// divipola_municipios si ya fue actualizado con cmd/divipola y registra los endpoints de catálogos
+// (DIVIPOLA y listas de códigos DIAN).
+// Retorna el catálogo usado para completar los datos de ubicación de los adquirentes.
+func wireCatalog(ctx context.Context, opts *server.Options, repos repositories, log *slog.Logger) dane.Service {
+	divipola, err := embedded.New()
+	if err != nil {
+		log.Error("Embedded DIVIPOLA catalog unavailable, falling back to the DANE API", "error", err)
+		return danehttp.NewClient("", &http.Client{
+			Timeout:   danehttp.DefaultTimeout,
+			Transport: tracing.NewTransport(nil),
+		}, log)
+	}
+
+	catalogService := appcatalog.NewService(divipola, log)
+	if repos.divipola != nil {
+		if err := catalogService.WithRepository(repos.divipola).Load(ctx); err != nil {
+			log.Warn("Using the embedded DIVIPOLA catalog", "error", err)
+		}
+	}
+
+	catalogHandler := cataloghttp.NewHandler(catalogService)
+	opts.ListDepartmentsHandler = http.HandlerFunc(catalogHandler.ListDepartments)
+	opts.SearchMunicipalitiesHandler = http.HandlerFunc(catalogHandler.SearchMunicipalities)
+	opts.GetMunicipalityHandler = http.HandlerFunc(catalogHandler.GetMunicipality)
+	opts.GetCodeListHandler = http.HandlerFunc(catalogHandler.GetCodeList)
+
+	return divipola
+}
+
+// wireAudit registra los endpoints de consulta, exportación y verificación de la auditoría de
+// proveedores y retorna los jobs de retención de particiones y de checkpoints firmados.
+func wireAudit(opts *server.Options, cfg config.AppConfig, repos repositories, log *slog.Logger) []backgroundJob {
+	reader, ok := repos.audit.(audit.Reader)
+	if !ok {
+		return nil
+	}
+	auditService := appaudit.NewService(reader)
+	var jobs []backgroundJob
+
+	// Checkpoints firmados de la cadena de hashes; sin llave solo se verifican los hashes
+	if chains, ok := repos.audit.(audit.ChainStore); ok {
+		var signingKey ed25519.PrivateKey
+		if cfg.Audit.CheckpointSigningKey != "" {
+			key, err := audit.ParseSigningKey(cfg.Audit.CheckpointSigningKey)
+			if err != nil {
+				log.Error("Invalid AUDIT_CHECKPOINT_SIGNING_KEY, audit chain checkpoints will be disabled", "error", err)
+			} else {
+				signingKey = key
+			}
+		}
+
+		var publicKey ed25519.PublicKey
+		if signingKey != nil {
+			publicKey = signingKey.Public().(ed25519.PublicKey)
+			jobs = append(jobs, appaudit.NewCheckpointJob(chains, signingKey, log).WithInterval(cfg.Audit.CheckpointInterval))
+			log.Info("Audit chain checkpoints enabled",
+				"interval", cfg.Audit.CheckpointInterval,
+				"public_key", base64.StdEncoding.EncodeToString(publicKey))
+		}
+		auditService.WithChainStore(chains, publicKey)
+	}
+
+	auditHandler := audithttp.NewHandler(auditService)
+	opts.SearchAuditLogsHandler = http.HandlerFunc(auditHandler.SearchLogs)
+	opts.ExportAuditLogsHandler = http.HandlerFunc(auditHandler.ExportLogs)
+	opts.VerifyAuditChainsHandler = http.HandlerFunc(auditHandler.VerifyChains)
+
+	if retention, ok := repos.audit.(audit.Retention); ok {
+		jobs = append(jobs, appaudit.NewRetentionJob(retention, cfg.Audit.RetentionMonths, log))
+	}
+	return jobs
+}
+
+// backgroundJob es un servicio con trabajos en segundo plano que terminan al cancelar el contexto.
+type backgroundJob interface {
+	Start(ctx context.Context)
+	Wait()
+}
+
+// wireInvoicing construye los servicios y handlers de facturación disponibles.
+// Las dependencias ausentes dejan su handler en nil y el servidor responde 503.
+// Retorna los trabajos en segundo plano (lotes asíncronos, refresco de resoluciones) para que el llamador los inicie.
+// appMetrics es nil cuando las métricas están deshabilitadas.
+func wireInvoicing(opts *server.Options, cfg config.AppConfig, client *numrot.Client, municipalities dane.Service, repos repositories, appMetrics *metrics.Metrics, log *slog.Logger) []backgroundJob {
+	nc := cfg.InvoiceProviders.Numrot
+
+	if repos.acquirer != nil {
+		acquirerHandler := acquirerhttp.NewHandler(appacquirer.NewService(repos.acquirer, municipalities, log))
+		opts.CreateAcquirerHandler = http.HandlerFunc(acquirerHandler.CreateAcquirer)
+		opts.UpdateAcquirerHandler = http.HandlerFunc(acquirerHandler.UpdateAcquirer)
+		opts.ListAcquirersHandler = http.HandlerFunc(acquirerHandler.ListAcquirers)
+		opts.SearchAcquirerHandler = http.HandlerFunc(acquirerHandler.SearchAcquirer)
+	}
+
+	if repos.provider != nil {
+		providerHandler := providerhttp.NewHandler(appprovider.NewService(repos.provider))
+		opts.CreateProviderHandler = http.HandlerFunc(providerHandler.CreateProvider)
+		opts.UpdateProviderHandler = http.HandlerFunc(providerHandler.UpdateProvider)
+		opts.ListProvidersHandler = http.HandlerFunc(providerHandler.ListProviders)
+		opts.SearchProviderHandler = http.HandlerFunc(providerHandler.SearchProvider)
+	}
+
+	if repos.ofe != nil {
+		ofeHandler := ofehttp.NewHandler(appofe.NewService(repos.ofe))
+		opts.ListOFEsHandler = http.HandlerFunc(ofeHandler.ListOFEs)
+		opts.GetOFEHandler = http.HandlerFunc(ofeHandler.GetOFE)
+		opts.CreateOFEHandler = http.HandlerFunc(ofeHandler.CreateOFE)
+		opts.UpdateOFEHandler = http.HandlerFunc(ofeHandler.UpdateOFE)
+	}
+
+	if repos.document != nil {
+		documentHandler := documenthttp.NewHandler(appdocument.NewService(repos.document))
+		opts.ListDocumentsHandler = http.HandlerFunc(documentHandler.ListDocuments)
+		opts.GetDocumentHandler = http.HandlerFunc(documentHandler.GetDocument)
+	}
+
+	receptionHandler := receptionhttp.NewHandler(client, nc.EmisorNit, nc.GeneratorNombre, nc.GeneratorApellido, nc.GeneratorIdentificacion, log)
+	opts.ReceptionConsultaDocumentosHandler = http.HandlerFunc(receptionHandler.ConsultaDocumentos)
+	opts.ReceptionListarDocumentosHandler = http.HandlerFunc(receptionHandler.ListarDocumentos)
+
+	if client == nil {
+		return nil
+	}
+
+	renderer := ubl.NewRenderer(
+		ubl.Settings{SoftwareID: cfg.DIAN.SoftwareID, ProviderNIT: cfg.DIAN.ProviderNIT},
+		cufe.StaticKeys{ClaveTecnica: cfg.DIAN.TechnicalKey, SoftwarePIN: cfg.DIAN.SoftwarePIN},
+	)
+	var certificate *xades.Certificate
+	if cfg.DIAN.CertificatePath != "" {
+		cert, err := xades.LoadPKCS12(cfg.DIAN.CertificatePath, cfg.DIAN.CertificatePassword)
+		if err != nil {
+			log.Warn("Failed to load signing certificate, UBL documents will not be signed",
+				"path", cfg.DIAN.CertificatePath,
+				"error", err)
+		} else {
+			certificate = cert
+			renderer.WithSigner(xades.NewSigner(certificate))
+			log.Info("XAdES signing enabled",
+				"subject", certificate.Leaf.Subject.CommonName,
+				"expires", certificate.Leaf.NotAfter)
+		}
+	}
+	invoiceProvider := newInvoiceProvider(cfg, client, certificate, renderer, repos, appMetrics, log)
+
+	resolutionService := appresolution.NewService(invoiceProvider)
+	if repos.resolution != nil {
+		resolutionService.WithRepository(repos.resolution, log).
+			WithRefreshInterval(cfg.Resolutions.RefreshInterval).
+			WithAlerts(float64(cfg.Resolutions.AlertUsagePercent), time.Duration(cfg.Resolutions.AlertExpiryDays)*24*time.Hour)
+	}
+
+	invoiceService := appinvoice.NewServiceWithWorkerPool(invoiceProvider, repos.acquirer, repos.provider, cfg.DocumentProcessing.WorkerPoolSize, cfg.DocumentProcessing.CdoAmbienteDefault)
+	if repos.document != nil {
+		invoiceService.WithLedger(repos.document, log)
+	}
+	if repos.idempotency != nil {
+		invoiceService.WithIdempotency(repos.idempotency)
+	}
+	if cfg.DIAN.TechnicalKey != "" || cfg.DIAN.SoftwarePIN != "" {
+		invoiceService.WithCUFEVerification(cufe.StaticKeys{ClaveTecnica: cfg.DIAN.TechnicalKey, SoftwarePIN: cfg.DIAN.SoftwarePIN}, log)
+	}
+	if repos.ofe != nil {
+		invoiceService.WithOFERegistry(repos.ofe)
+	} else {
+		log.Warn("OFE registry disabled (no database): issuers are not validated and documents keep the issuer data of the request")
+	}
+	if repos.resolution != nil {
+		invoiceService.WithResolutionTracker(resolutionService, log)
+	}
+	if repos.currency != nil {
+		invoiceService.WithExchangeRates(repos.currency)
+	}
+	var withholdingService *appwithholding.Service
+	if repos.withholding != nil {
+		withholdingService = appwithholding.NewService(repos.withholding)
+		if cfg.DocumentProcessing.Withholdings {
+			invoiceService.WithWithholdings(withholdingService, cfg.DocumentProcessing.WithholdingOverride)
+		}
+	}
+	if cfg.DocumentProcessing.TotalsValidation {
+		invoiceService.WithTotalsCheck(cfg.DocumentProcessing.TotalsAutofill)
+	}
+	if nc := cfg.InvoiceProviders.Numrot; nc.NCInvoicePeriodStartDate != "" && nc.NCInvoicePeriodEndDate != "" {
+		invoiceService.WithNotesPeriod(invoice.OpenETLPeriodoFacturacion{
+			FechaInicio: nc.NCInvoicePeriodStartDate,
+			HoraInicio:  nc.NCInvoicePeriodStartTime,
+			FechaFin:    nc.NCInvoicePeriodEndDate,
+			HoraFin:     nc.NCInvoicePeriodEndTime,
+		})
+	}
+	var contingencyService *appcontingency.Service
+	if repos.contingency != nil && cfg.Contingency.Enabled {
+		contingencyService = appcontingency.NewService(invoiceProvider, repos.contingency, log).
+			WithPrefix(cfg.Contingency.Prefix).
+			WithAutoActivation(cfg.Contingency.Type, cfg.Contingency.OpenThreshold).
+			WithCheckInterval(cfg.Contingency.CheckInterval)
+		if repos.document != nil {
+			contingencyService.WithLedger(repos.document)
+		}
+		invoiceService.WithContingency(contingencyService)
+	}
+	if appMetrics != nil {
+		invoiceService.WithMetrics(appMetrics)
+	}
+	invoiceService.WithRenderer(renderer)
+	invoiceHandler := invoicehttp.NewHandler(invoiceService, client, log)
+	opts.InvoiceHandler = http.HandlerFunc(invoiceHandler.GetDocuments)
+	opts.InvoiceByNumberHandler = http.HandlerFunc(invoiceHandler.GetDocumentByNumber)
+	opts.ReceivedInvoiceHandler = http.HandlerFunc(invoiceHandler.GetReceivedDocuments)
+	opts.DownloadPDFHandler = http.HandlerFunc(invoiceHandler.DownloadPDF)
+	opts.DownloadPDFNumrotHandler = http.HandlerFunc(invoiceHandler.DownloadPDFFromNumrot)
+	opts.RegisterDocumentHandler = http.HandlerFunc(invoiceHandler.RegisterDocument)
+	opts.PreviewDocumentHandler = http.HandlerFunc(invoiceHandler.PreviewDocuments)
+	opts.ValidateDocumentsHandler = http.HandlerFunc(invoiceHandler.ValidateDocuments)
+
+	eventService := appevent.NewService(invoiceProvider, nc.EmisorNit, nc.RazonSocial)
+	if repos.ofe != nil {
+		eventService.WithOFERegistry(repos.ofe)
+	}
+	eventHandler := eventhttp.NewHandler(eventService)
+	opts.EventHandler = http.HandlerFunc(eventHandler.RegisterEvent)
+
+	resolutionHandler := resolutionhttp.NewHandler(resolutionService, nc.EmisorNit)
+	opts.ResolutionHandler = http.HandlerFunc(resolutionHandler.GetResolutions)
+	jobs := []backgroundJob{resolutionService}
+	if repos.resolution != nil {
+		opts.ListResolutionRangesHandler = http.HandlerFunc(resolutionHandler.ListRanges)
+		opts.AllocateConsecutivoHandler = http.HandlerFunc(resolutionHandler.AllocateConsecutivo)
+		opts.ResolutionAlertsHandler = http.HandlerFunc(resolutionHandler.GetAlerts)
+	}
+
+	if contingencyService != nil {
+		contingencyHandler := contingencyhttp.NewHandler(contingencyService)
+		opts.ContingencyDashboardHandler = http.HandlerFunc(contingencyHandler.Dashboard)
+		opts.ActivateContingencyHandler = http.HandlerFunc(contingencyHandler.Activate)
+		opts.DeactivateContingencyHandler = http.HandlerFunc(contingencyHandler.Deactivate)
+		opts.TransmitContingencyHandler = http.HandlerFunc(contingencyHandler.Transmit)
+		jobs = append(jobs, contingencyService)
+	}
+
+	if withholdingService != nil {
+		withholdingHandler := withholdinghttp.NewHandler(withholdingService)
+		opts.ListWithholdingRulesHandler = http.HandlerFunc(withholdingHandler.ListRules)
+		opts.CreateWithholdingRuleHandler = http.HandlerFunc(withholdingHandler.CreateRule)
+		opts.UpdateWithholdingRuleHandler = http.HandlerFunc(withholdingHandler.UpdateRule)
+		opts.DeleteWithholdingRuleHandler = http.HandlerFunc(withholdingHandler.DeleteRule)
+		opts.ListUVTHandler = http.HandlerFunc(withholdingHandler.ListUVT)
+		opts.SetUVTHandler = http.HandlerFunc(withholdingHandler.SetUVT)
+	}
+
+	if repos.currency != nil {
+		currencyHandler := currencyhttp.NewHandler(appcurrency.NewService(repos.currency))
+		opts.ListExchangeRatesHandler = http.HandlerFunc(currencyHandler.ListRates)
+		opts.SetExchangeRateHandler = http.HandlerFunc(currencyHandler.SetRate)
+	}
+
+	opts.ReceptionRegistrarEventoHandler = http.HandlerFunc(receptionHandler.RegistrarEvento)
+
+	if repos.batch == nil {
+		return jobs
+	}
+
+	batchService := appbatch.NewService(repos.batch, invoiceService, cfg.DocumentProcessing.BatchSize, cfg.DocumentProcessing.BatchJobWorkers, log)
+	if repos.idempotency != nil {
+		batchService.WithIdempotency(repos.idempotency)
+	}
+	batchHandler := batchhttp.NewHandler(batchService)
+	opts.CreateBatchHandler = http.HandlerFunc(batchHandler.CreateBatch)
+	opts.GetBatchHandler = http.HandlerFunc(batchHandler.GetBatch)
+
+	return append(jobs, batchService)
+}
+
+// newInvoiceProvider retorna el proveedor de facturación de los servicios. Con el
+// proveedor DIAN configurado, o reglas de enrutamiento definidas, envuelve a Numrot
+// en un router que elige el proveedor por OFE, tipo de documento y operación.
+func newInvoiceProvider(cfg config.AppConfig, client *numrot.Client, certificate *xades.Certificate, renderer *ubl.Renderer, repos repositories, appMetrics *metrics.Metrics, log *slog.Logger) invoice.Provider {
+	routingCfg := cfg.InvoiceProviders.Routing
+	providers := map[string]invoice.Provider{"numrot": client}
+
+	dianCfg := cfg.InvoiceProviders.DIAN
+	if dianCfg.URL != "" {
+		if certificate == nil {
+			log.Warn("DIAN provider requires a signing certificate, provider disabled", "url", dianCfg.URL)
+		} else {
+			httpClient := infrahttp.NewTracedClient(&infrahttp.TracedClientConfig{
+				Timeout:         cfg.InvoiceProviders.Numrot.APITimeout,
+				AuditEnabled:    cfg.Audit.Enabled && repos.audit != nil,
+				LogRequestBody:  cfg.Audit.LogRequestBody,
+				LogResponseBody: cfg.Audit.LogResponseBody,
+				MaxBodySize:     cfg.Audit.MaxBodySize,
+			}, log, repos.audit, "dian")
+			if appMetrics != nil {
+				httpClient.WithMetrics(appMetrics)
+			}
+			providers["dian"] = dian.NewClient(dianCfg.URL, dian.Settings{
+				SoftwareID:  cfg.DIAN.SoftwareID,
+				SoftwarePIN: cfg.DIAN.SoftwarePIN,
+				ProviderNIT: cfg.DIAN.ProviderNIT,
+				TestSetID:   dianCfg.TestSetID,
+				Ambiente:    cfg.DocumentProcessing.CdoAmbienteDefault,
+			}, certificate, renderer, httpClient, log)
+			log.Info("DIAN provider configured", "url", dianCfg.URL, "test_set", dianCfg.TestSetID != "")
+		}
+	}
+
+	if len(routingCfg.Rules) == 0 && routingCfg.Fallback == "" && routingCfg.Default == "numrot" {
+		return client
+	}
+
+	rules, err := routing.ParseRules(routingCfg.Rules)
+	if err != nil {
+		log.Warn("Invalid invoice routing rules, using Numrot for every request", "error", err)
+		return client
+	}
+	var auditRepo audit.Repository
+	if cfg.Audit.Enabled {
+		auditRepo = repos.audit
+	}
+	router, err := routing.NewRouter(providers, rules, routingCfg.Default, routingCfg.Fallback, auditRepo, log)
+	if err != nil {
+		log.Warn("Invalid invoice routing configuration, using Numrot for every request", "error", err)
+		return client
+	}
+	log.Info("Invoice provider routing enabled",
+		"default", routingCfg.Default,
+		"fallback", routingCfg.Fallback,
+		"rules", len(rules))
+	return router
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"3tcapital/goclonacion/internal/adapters/invoice/numrot"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registerNumrotMetrics expone el estado del circuit breaker, los limitadores y la
+// renovación de tokens del cliente Numrot. Los valores se leen en cada scrape.
+func registerNumrotMetrics(r prometheus.Registerer, client *numrot.Client) {
+	provider := prometheus.Labels{"provider": "numrot"}
+	gauge := func(name, help string, value func(numrot.ClientStats) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help, ConstLabels: provider},
+			func() float64 { return value(client.Stats()) })
+	}
+	counter := func(name, help string, labels prometheus.Labels, value func(numrot.ClientStats) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help, ConstLabels: labels},
+			func() float64 { return value(client.Stats()) })
+	}
+
+	r.MustRegister(
+		gauge("goclonacion_provider_circuit_state",
+			"Circuit breaker state of the provider (0 closed, 1 open, 2 half-open).",
+			func(s numrot.ClientStats) float64 { return float64(s.Circuit.State) }),
+		gauge("goclonacion_provider_limiter_active_requests",
+			"Requests holding a concurrency limiter slot.",
+			func(s numrot.ClientStats) float64 { return float64(s.Limiter.ActiveCount) }),
+		gauge("goclonacion_provider_limiter_peak_requests",
+			"Highest number of concurrent requests since the service started.",
+			func(s numrot.ClientStats) float64 { return float64(s.Limiter.PeakCount) }),
+		gauge("goclonacion_provider_limiter_waiting_requests",
+			"Requests waiting for a concurrency limiter slot.",
+			func(s numrot.ClientStats) float64 { return float64(s.Limiter.WaitCount) }),
+		gauge("goclonacion_provider_limiter_max_requests",
+			"Maximum concurrent requests allowed by the concurrency limiter.",
+			func(s numrot.ClientStats) float64 { return float64(s.Limiter.MaxConcurrent) }),
+		counter("goclonacion_provider_rate_limiter_waits_total",
+			"Requests that waited for a rate limiter token.", provider,
+			func(s numrot.ClientStats) float64 { return float64(s.RateLimiter.Waits) }),
+		counter("goclonacion_provider_rate_limiter_wait_seconds_total",
+			"Total time spent waiting for rate limiter tokens, in seconds.", provider,
+			func(s numrot.ClientStats) float64 { return s.RateLimiter.WaitTime.Seconds() }),
+		counter("goclonacion_provider_token_refreshes_total",
+			"Authentication token refreshes, by result.", prometheus.Labels{"provider": "numrot", "result": "success"},
+			func(s numrot.ClientStats) float64 { return float64(s.Auth.Refreshes) }),
+		counter("goclonacion_provider_token_refreshes_total",
+			"Authentication token refreshes, by result.", prometheus.Labels{"provider": "numrot", "result": "error"},
+			func(s numrot.ClientStats) float64 { return float64(s.Auth.RefreshErrors) }),
+	)
+}
+
+// registerPoolMetrics expone las estadísticas del pool pgx de facturación.
+func registerPoolMetrics(r prometheus.Registerer, pool *pgxpool.Pool) {
+	gauge := func(name, help string, labels prometheus.Labels, value func(*pgxpool.Stat) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help, ConstLabels: labels},
+			func() float64 { return value(pool.Stat()) })
+	}
+	counter := func(name, help string, value func(*pgxpool.Stat) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help},
+			func() float64 { return value(pool.Stat()) })
+	}
+	connections := func(state string, value func(*pgxpool.Stat) float64) prometheus.Collector {
+		return gauge("goclonacion_db_pool_connections", "Open connections in the database pool, by state.",
+			prometheus.Labels{"state": state}, value)
+	}
+
+	r.MustRegister(
+		connections("acquired", func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) }),
+		connections("idle", func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) }),
+		connections("constructing", func(s *pgxpool.Stat) float64 { return float64(s.ConstructingConns()) }),
+		gauge("goclonacion_db_pool_max_connections",
+			"Maximum size of the database pool.", nil,
+			func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) }),
+		counter("goclonacion_db_pool_acquires_total",
+			"Connections acquired from the database pool.",
+			func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) }),
+		counter("goclonacion_db_pool_empty_acquires_total",
+			"Acquires that waited for a connection because the pool was empty.",
+			func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) }),
+		counter("goclonacion_db_pool_acquire_seconds_total",
+			"Total time spent acquiring connections from the database pool, in seconds.",
+			func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() }),
+	)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"3tcapital/goclonacion/internal/infrastructure/config"
+	"3tcapital/goclonacion/internal/infrastructure/tracing"
+)
+
+// startTracing instala el proveedor de trazas global con exportación OTLP.
+// La función retornada detiene la exportación y envía los spans pendientes; se llama al final
+// del apagado para no perder los spans de los trabajos en segundo plano.
+func startTracing(cfg config.AppConfig, log *slog.Logger) func() {
+	exporter, err := tracing.NewOTLPExporter(context.Background(), cfg.Tracing.OTLPEndpoint)
+	if err != nil {
+		log.Error("Failed to create OTLP exporter, tracing disabled", "error", err)
+		return func() {}
+	}
+	provider := tracing.NewProvider(exporter, cfg.Tracing.SampleRatio,
+		attribute.String("service.name", cfg.App.Name),
+		attribute.String("service.version", cfg.App.Version),
+		attribute.String("deployment.environment", cfg.App.Environment),
+	)
+	tracing.Install(provider)
+	log.Info("Tracing enabled", "otlp_endpoint", cfg.Tracing.OTLPEndpoint, "sample_ratio", cfg.Tracing.SampleRatio)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Warn("Failed to export pending trace spans", "error", err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Command divipola actualiza el catálogo DIVIPOLA almacenado en la tabla divipola_municipios.
+// El servicio lo carga al iniciar en lugar del catálogo embebido en el binario.
+//
+// Uso:
+//
+//	divipola -csv DIVIPOLA_Municipios.csv   # archivo descargado del DANE (cod_dpto;dpto;cod_mpio;nom_mpio)
+//	divipola -dane                          # API de datos abiertos del DANE
+//
+// Con -out el catálogo se escribe en un archivo CSV en lugar de la base de datos; así se
+// regenera el catálogo embebido:
+//
+//	divipola -dane -out internal/adapters/dane/embedded/divipola.csv
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"3tcapital/goclonacion/internal/adapters/dane/embedded"
+	danehttp "3tcapital/goclonacion/internal/adapters/dane/http"
+	danepg "3tcapital/goclonacion/internal/adapters/dane/postgres"
+	appcatalog "3tcapital/goclonacion/internal/application/catalog"
+	"3tcapital/goclonacion/internal/core/dane"
+	"3tcapital/goclonacion/internal/infrastructure/config"
+	"3tcapital/goclonacion/internal/infrastructure/database"
+	"3tcapital/goclonacion/internal/infrastructure/logger"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "divipola: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	csvPath := flag.String("csv", "", "archivo CSV de municipios DIVIPOLA")
+	fromDANE := flag.Bool("dane", false, "descargar el catálogo de la API de datos abiertos del DANE")
+	daneURL := flag.String("url", danehttp.DANEBaseURL, "URL de la API del DANE (con -dane)")
+	outPath := flag.String("out", "", "escribir el catálogo en este archivo CSV en lugar de la base de datos")
+	flag.Parse()
+
+	if (*csvPath == "") == !*fromDANE {
+		flag.Usage()
+		return errors.New("indique -csv o -dane")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *outPath != "" {
+		log := logger.New("divipola", "info", "development")
+		var source dane.Source = csvFile(*csvPath)
+		if *fromDANE {
+			source = danehttp.NewClient(*daneURL, nil, log)
+		}
+		return writeCatalog(ctx, source, *outPath, log)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	log := logger.New(cfg.App.Name, cfg.Log.Level, cfg.App.Environment)
+
+	var source dane.Source = csvFile(*csvPath)
+	if *fromDANE {
+		source = danehttp.NewClient(*daneURL, nil, log)
+	}
+
+	pool, err := database.NewPool(ctx, database.Config{
+		Host:            cfg.Database.Host,
+		Port:            cfg.Database.Port,
+		Database:        cfg.Database.Database,
+		User:            cfg.Database.User,
+		Password:        cfg.Database.Password,
+		SSLMode:         cfg.Database.SSLMode,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	})
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer pool.Close()
+
+	if err := database.RunMigrations(ctx, pool, log); err != nil {
+		return fmt.Errorf("run migrations: %w", err)
+	}
+
+	catalog, err := embedded.New()
+	if err != nil {
+		return err
+	}
+	n, err := appcatalog.NewService(catalog, log).
+		WithRepository(danepg.NewRepository(pool)).
+		Refresh(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	log.Info("DIVIPOLA catalog refreshed", "municipios", n, "departamentos", len(catalog.Departments()))
+	return nil
+}
+
+// writeCatalog validates the catalog of source and writes it to path in the format of the
+// embedded catalog.
+func writeCatalog(ctx context.Context, source dane.Source, path string, log *slog.Logger) error {
+	municipalities, err := source.ListMunicipalities(ctx)
+	if err != nil {
+		return fmt.Errorf("list municipalities: %w", err)
+	}
+	for _, m := range municipalities {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := dane.WriteCSV(f, municipalities); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Info("DIVIPOLA catalog written", "path", path, "municipios", len(municipalities))
+	return nil
+}
+
+// csvFile reads the catalog from a DIVIPOLA CSV file.
+type csvFile string
+
+func (path csvFile) ListMunicipalities(ctx context.Context) ([]dane.Municipality, error) {
+	f, err := os.Open(string(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return dane.ParseCSV(f)
+}
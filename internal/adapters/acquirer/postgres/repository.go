@@ -7,7 +7,7 @@ import (
 	"log/slog"
 	"strings"
 
-	"3tcapital/ms_facturacion_core/internal/core/acquirer"
+	"3tcapital/goclonacion/internal/core/acquirer"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
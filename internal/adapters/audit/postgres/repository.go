@@ -3,11 +3,16 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
+	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/audit"
+	"3tcapital/goclonacion/internal/core/audit"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -42,14 +47,6 @@ func (r *Repository) Save(ctx context.Context, log audit.ProviderAuditLog) error
 		)
 	}
 
-	query := `
-		INSERT INTO provider_audit_log (
-			correlation_id, provider, operation, request_method, request_url,
-			request_headers, request_body, response_status, response_headers,
-			response_body, duration_ms, error_message
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-	`
-
 	// Convert headers to JSON
 	requestHeadersJSON, err := json.Marshal(log.RequestHeaders)
 	if err != nil {
@@ -88,20 +85,7 @@ func (r *Repository) Save(ctx context.Context, log audit.ProviderAuditLog) error
 		responseBodyJSON = log.ResponseBody
 	}
 
-	_, err = r.pool.Exec(ctx, query,
-		log.CorrelationID,
-		log.Provider,
-		log.Operation,
-		log.RequestMethod,
-		log.RequestURL,
-		requestHeadersJSON,
-		requestBodyJSON,
-		log.ResponseStatus,
-		responseHeadersJSON,
-		responseBodyJSON,
-		log.DurationMs,
-		log.ErrorMessage,
-	)
+	err = r.insertChained(ctx, &log, requestHeadersJSON, requestBodyJSON, responseHeadersJSON, responseBodyJSON)
 	if err != nil {
 		errMsg := fmt.Errorf("insert audit log: %w", err)
 		if r.log != nil {
@@ -135,12 +119,81 @@ func (r *Repository) Save(ctx context.Context, log audit.ProviderAuditLog) error
 	return nil
 }
 
+// insertChained inserts the log at the head of the hash chain of its month. The chain is
+// locked for the transaction, so concurrent inserts are chained one after the other.
+func (r *Repository) insertChained(ctx context.Context, log *audit.ProviderAuditLog, requestHeadersJSON, requestBodyJSON, responseHeadersJSON, responseBodyJSON interface{}) error {
+	log.CreatedAt = time.Now().Truncate(time.Microsecond)
+	chain := audit.ChainOf(log.CreatedAt)
+	start, end := chainRange(log.CreatedAt)
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", partitionPrefix+chain); err != nil {
+		return fmt.Errorf("lock audit chain: %w", err)
+	}
+	if err := tx.QueryRow(ctx, "SELECT nextval('provider_audit_log_id_seq')").Scan(&log.ID); err != nil {
+		return fmt.Errorf("next audit log id: %w", err)
+	}
+
+	var prevHash *string
+	err = tx.QueryRow(ctx, `
+		SELECT hash FROM provider_audit_log
+		WHERE created_at >= $1 AND created_at < $2
+		ORDER BY id DESC
+		LIMIT 1
+	`, start, end).Scan(&prevHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("find audit chain head: %w", err)
+	}
+	log.PrevHash = ""
+	if prevHash != nil {
+		log.PrevHash = *prevHash
+	}
+
+	log.Hash, err = audit.ChainHash(log.PrevHash, *log)
+	if err != nil {
+		return fmt.Errorf("hash audit log: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO provider_audit_log (
+			id, correlation_id, provider, operation, request_method, request_url,
+			request_headers, request_body, response_status, response_headers,
+			response_body, duration_ms, error_message, created_at, prev_hash, hash
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`,
+		log.ID,
+		log.CorrelationID,
+		log.Provider,
+		log.Operation,
+		log.RequestMethod,
+		log.RequestURL,
+		requestHeadersJSON,
+		requestBodyJSON,
+		log.ResponseStatus,
+		responseHeadersJSON,
+		responseBodyJSON,
+		log.DurationMs,
+		log.ErrorMessage,
+		log.CreatedAt,
+		nullIfEmpty(log.PrevHash),
+		log.Hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 // FindByCorrelationID retrieves all audit logs with the given correlation ID.
 func (r *Repository) FindByCorrelationID(ctx context.Context, correlationID string) ([]audit.ProviderAuditLog, error) {
 	query := `
-		SELECT id, correlation_id, provider, operation, request_method, request_url,
-		       request_headers, request_body, response_status, response_headers,
-		       response_body, duration_ms, error_message, created_at
+		SELECT ` + selectColumns + `
 		FROM provider_audit_log
 		WHERE correlation_id = $1
 		ORDER BY created_at DESC
@@ -154,48 +207,396 @@ func (r *Repository) FindByCorrelationID(ctx context.Context, correlationID stri
 
 	var logs []audit.ProviderAuditLog
 	for rows.Next() {
-		var log audit.ProviderAuditLog
-		var requestHeadersJSON, responseHeadersJSON []byte
-		var requestBodyJSON, responseBodyJSON []byte
-
-		err := rows.Scan(
-			&log.ID,
-			&log.CorrelationID,
-			&log.Provider,
-			&log.Operation,
-			&log.RequestMethod,
-			&log.RequestURL,
-			&requestHeadersJSON,
-			&requestBodyJSON,
-			&log.ResponseStatus,
-			&responseHeadersJSON,
-			&responseBodyJSON,
-			&log.DurationMs,
-			&log.ErrorMessage,
-			&log.CreatedAt,
-		)
+		log, err := scanLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return logs, nil
+}
+
+// Search retrieves the audit logs matching the filter, newest first, and the total count.
+func (r *Repository) Search(ctx context.Context, filter audit.Filter) ([]audit.ProviderAuditLog, int, error) {
+	whereClause, queryArgs := filterClause(filter)
+
+	var total int
+	if err := r.pool.QueryRow(ctx, "SELECT COUNT(*) FROM provider_audit_log "+whereClause, queryArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count audit logs: %w", err)
+	}
+
+	query := `SELECT ` + selectColumns + `
+		FROM provider_audit_log
+		` + whereClause + `
+		ORDER BY created_at DESC, id DESC`
+	if filter.Length > 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(queryArgs)+1, len(queryArgs)+2)
+		queryArgs = append(queryArgs, filter.Length, filter.Start)
+	}
+
+	rows, err := r.pool.Query(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := make([]audit.ProviderAuditLog, 0)
+	for rows.Next() {
+		log, err := scanLog(rows)
 		if err != nil {
-			return nil, fmt.Errorf("scan audit log: %w", err)
+			return nil, 0, err
 		}
+		logs = append(logs, log)
+	}
 
-		// Unmarshal headers
-		if err := json.Unmarshal(requestHeadersJSON, &log.RequestHeaders); err != nil {
-			return nil, fmt.Errorf("unmarshal request headers: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return logs, total, nil
+}
+
+// Export calls fn for every audit log matching the filter, oldest first.
+// Rows are streamed from the database, so large exports are not held in memory.
+func (r *Repository) Export(ctx context.Context, filter audit.Filter, fn func(audit.ProviderAuditLog) error) error {
+	whereClause, queryArgs := filterClause(filter)
+
+	query := `SELECT ` + selectColumns + `
+		FROM provider_audit_log
+		` + whereClause + `
+		ORDER BY created_at, id`
+
+	rows, err := r.pool.Query(ctx, query, queryArgs...)
+	if err != nil {
+		return fmt.Errorf("query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		log, err := scanLog(rows)
+		if err != nil {
+			return err
 		}
-		if err := json.Unmarshal(responseHeadersJSON, &log.ResponseHeaders); err != nil {
-			return nil, fmt.Errorf("unmarshal response headers: %w", err)
+		if err := fn(log); err != nil {
+			return err
 		}
+	}
 
-		// Assign body bytes
-		log.RequestBody = requestBodyJSON
-		log.ResponseBody = responseBodyJSON
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate rows: %w", err)
+	}
 
-		logs = append(logs, log)
+	return nil
+}
+
+// EnsurePartition creates the partition for the month of t if it does not exist.
+func (r *Repository) EnsurePartition(ctx context.Context, t time.Time) error {
+	if _, err := r.pool.Exec(ctx, "SELECT create_provider_audit_log_partition($1)", monthStart(t)); err != nil {
+		return fmt.Errorf("create audit log partition: %w", err)
+	}
+	return nil
+}
+
+// DropPartitionsBefore drops the partitions of the months before the month of t.
+func (r *Repository) DropPartitionsBefore(ctx context.Context, t time.Time) ([]string, error) {
+	partitions, err := r.partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := monthStart(t)
+	var dropped []string
+	for _, name := range partitions {
+		month, ok := partitionMonth(name)
+		if !ok || !month.Before(cutoff) {
+			continue
+		}
+		if _, err := r.pool.Exec(ctx, "DROP TABLE IF EXISTS "+pgx.Identifier{name}.Sanitize()); err != nil {
+			return dropped, fmt.Errorf("drop audit log partition %s: %w", name, err)
+		}
+		dropped = append(dropped, name)
+	}
+
+	return dropped, nil
+}
+
+// Chains returns the identifiers of the chains (one per monthly partition), oldest first.
+func (r *Repository) Chains(ctx context.Context) ([]string, error) {
+	partitions, err := r.partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var months []time.Time
+	for _, name := range partitions {
+		if month, ok := partitionMonth(name); ok {
+			months = append(months, month)
+		}
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+
+	chains := make([]string, 0, len(months))
+	for _, month := range months {
+		chains = append(chains, audit.ChainOf(month))
+	}
+	return chains, nil
+}
+
+// WalkChain calls fn for every log of the chain in chain (id) order.
+func (r *Repository) WalkChain(ctx context.Context, chain string, fn func(audit.ProviderAuditLog) error) error {
+	month, err := audit.ParseChain(chain)
+	if err != nil {
+		return fmt.Errorf("parse chain %q: %w", chain, err)
+	}
+	start, end := chainRange(month)
+
+	rows, err := r.pool.Query(ctx, `SELECT `+selectColumns+`
+		FROM provider_audit_log
+		WHERE created_at >= $1 AND created_at < $2
+		ORDER BY id`, start, end)
+	if err != nil {
+		return fmt.Errorf("query audit chain: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		log, err := scanLog(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(log); err != nil {
+			return err
+		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate rows: %w", err)
+		return fmt.Errorf("iterate rows: %w", err)
 	}
 
-	return logs, nil
+	return nil
+}
+
+// ChainHead returns the last log of the chain, or nil if the chain is empty.
+func (r *Repository) ChainHead(ctx context.Context, chain string) (*audit.ProviderAuditLog, error) {
+	month, err := audit.ParseChain(chain)
+	if err != nil {
+		return nil, fmt.Errorf("parse chain %q: %w", chain, err)
+	}
+	start, end := chainRange(month)
+
+	row := r.pool.QueryRow(ctx, `SELECT `+selectColumns+`
+		FROM provider_audit_log
+		WHERE created_at >= $1 AND created_at < $2
+		ORDER BY id DESC
+		LIMIT 1`, start, end)
+	log, err := scanLog(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// SaveCheckpoint stores a signed checkpoint.
+func (r *Repository) SaveCheckpoint(ctx context.Context, checkpoint audit.Checkpoint) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO provider_audit_checkpoint (chain, last_id, last_hash, signature, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, checkpoint.Chain, checkpoint.LastID, checkpoint.LastHash, checkpoint.Signature, checkpoint.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert audit checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Checkpoints returns the checkpoints of the chain, oldest first.
+func (r *Repository) Checkpoints(ctx context.Context, chain string) ([]audit.Checkpoint, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, chain, last_id, last_hash, signature, created_at
+		FROM provider_audit_checkpoint
+		WHERE chain = $1
+		ORDER BY id
+	`, chain)
+	if err != nil {
+		return nil, fmt.Errorf("query audit checkpoints: %w", err)
+	}
+
+	checkpoints, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (audit.Checkpoint, error) {
+		var cp audit.Checkpoint
+		err := row.Scan(&cp.ID, &cp.Chain, &cp.LastID, &cp.LastHash, &cp.Signature, &cp.CreatedAt)
+		return cp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan audit checkpoint: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// partitions returns the names of the partitions of provider_audit_log.
+func (r *Repository) partitions(ctx context.Context) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'provider_audit_log'::regclass
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list audit log partitions: %w", err)
+	}
+	partitions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list audit log partitions: %w", err)
+	}
+	return partitions, nil
+}
+
+// selectColumns lists the columns read by scanLog, in order.
+const selectColumns = `id, correlation_id, provider, operation, request_method, request_url,
+		       request_headers, request_body, response_status, response_headers,
+		       response_body, duration_ms, error_message, created_at, prev_hash, hash`
+
+// partitionPrefix is the name prefix of the monthly partitions, followed by YYYY_MM.
+const partitionPrefix = "provider_audit_log_"
+
+// filterClause builds the WHERE clause and its arguments for the filter.
+func filterClause(filter audit.Filter) (string, []interface{}) {
+	whereConditions := []string{}
+	queryArgs := []interface{}{}
+
+	addCondition := func(condition string, value interface{}) {
+		queryArgs = append(queryArgs, value)
+		whereConditions = append(whereConditions, fmt.Sprintf(condition, len(queryArgs)))
+	}
+
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+	if filter.Provider != "" {
+		addCondition("provider = $%d", filter.Provider)
+	}
+	if filter.Operation != "" {
+		addCondition("operation = $%d", filter.Operation)
+	}
+	if filter.Status != nil {
+		addCondition("response_status = $%d", *filter.Status)
+	}
+	if filter.MinDurationMs > 0 {
+		addCondition("duration_ms >= $%d", filter.MinDurationMs)
+	}
+	if filter.CorrelationID != "" {
+		addCondition("correlation_id = $%d", filter.CorrelationID)
+	}
+	if filter.Document != "" {
+		addCondition("(request_url ILIKE $%[1]d OR request_body::text ILIKE $%[1]d OR response_body::text ILIKE $%[1]d)",
+			"%"+escapeLike(filter.Document)+"%")
+	}
+
+	if len(whereConditions) == 0 {
+		return "", queryArgs
+	}
+	return "WHERE " + strings.Join(whereConditions, " AND "), queryArgs
+}
+
+// escapeLike escapes the LIKE wildcards of a search term.
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term)
+}
+
+// chainRange returns the creation time range of the logs of the chain that holds t.
+func chainRange(t time.Time) (time.Time, time.Time) {
+	start := monthStart(t)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// nullIfEmpty stores empty strings as NULL.
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// monthStart returns the first day of the month of t.
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// partitionMonth returns the month held by a partition named provider_audit_log_YYYY_MM.
+func partitionMonth(name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, partitionPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	month, err := time.Parse("2006_01", suffix)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return month, true
+}
+
+// scanLog maps an audit log row (in selectColumns order) to an audit log.
+func scanLog(row pgx.Row) (audit.ProviderAuditLog, error) {
+	var log audit.ProviderAuditLog
+	var requestHeadersJSON, responseHeadersJSON []byte
+	var requestBodyJSON, responseBodyJSON []byte
+	var errorMessage, prevHash, hash *string
+
+	err := row.Scan(
+		&log.ID,
+		&log.CorrelationID,
+		&log.Provider,
+		&log.Operation,
+		&log.RequestMethod,
+		&log.RequestURL,
+		&requestHeadersJSON,
+		&requestBodyJSON,
+		&log.ResponseStatus,
+		&responseHeadersJSON,
+		&responseBodyJSON,
+		&log.DurationMs,
+		&errorMessage,
+		&log.CreatedAt,
+		&prevHash,
+		&hash,
+	)
+	if err != nil {
+		return log, fmt.Errorf("scan audit log: %w", err)
+	}
+
+	// Unmarshal headers
+	if len(requestHeadersJSON) > 0 {
+		if err := json.Unmarshal(requestHeadersJSON, &log.RequestHeaders); err != nil {
+			return log, fmt.Errorf("unmarshal request headers: %w", err)
+		}
+	}
+	if len(responseHeadersJSON) > 0 {
+		if err := json.Unmarshal(responseHeadersJSON, &log.ResponseHeaders); err != nil {
+			return log, fmt.Errorf("unmarshal response headers: %w", err)
+		}
+	}
+
+	// Assign body bytes
+	log.RequestBody = requestBodyJSON
+	log.ResponseBody = responseBodyJSON
+	if errorMessage != nil {
+		log.ErrorMessage = *errorMessage
+	}
+	if prevHash != nil {
+		log.PrevHash = *prevHash
+	}
+	if hash != nil {
+		log.Hash = *hash
+	}
+
+	return log, nil
 }
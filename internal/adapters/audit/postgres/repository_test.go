@@ -6,7 +6,7 @@ import (
 	"testing"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/audit"
+	"3tcapital/goclonacion/internal/core/audit"
 )
 
 // Note: These tests require a PostgreSQL database connection.
@@ -137,3 +137,74 @@ func ExampleRepository_Save() {
 	_ = ctx
 	_ = log
 }
+
+func TestRepositoryImplementsReaderAndRetention(t *testing.T) {
+	var _ audit.Reader = (*Repository)(nil)
+	var _ audit.Retention = (*Repository)(nil)
+}
+
+func TestFilterClause(t *testing.T) {
+	status := 500
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	where, args := filterClause(audit.Filter{
+		From:          from,
+		Provider:      "numrot",
+		Status:        &status,
+		MinDurationMs: 2000,
+		Document:      "SETT_100%",
+	})
+
+	expected := "WHERE created_at >= $1 AND provider = $2 AND response_status = $3 AND duration_ms >= $4 AND " +
+		"(request_url ILIKE $5 OR request_body::text ILIKE $5 OR response_body::text ILIKE $5)"
+	if where != expected {
+		t.Errorf("unexpected where clause:\n got: %s\nwant: %s", where, expected)
+	}
+	if len(args) != 5 {
+		t.Fatalf("expected 5 args, got %d", len(args))
+	}
+	if args[4] != `%SETT\_100\%%` {
+		t.Errorf("expected escaped document pattern, got %v", args[4])
+	}
+
+	if where, args := filterClause(audit.Filter{}); where != "" || len(args) != 0 {
+		t.Errorf("expected no conditions for an empty filter, got %q %v", where, args)
+	}
+}
+
+func TestPartitionMonth(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected time.Time
+		ok       bool
+	}{
+		{name: "provider_audit_log_2026_03", expected: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{name: "provider_audit_log_2026_13"},
+		{name: "provider_audit_log_default"},
+		{name: "document_ledger"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			month, ok := partitionMonth(tt.name)
+			if ok != tt.ok {
+				t.Fatalf("expected ok=%v, got %v", tt.ok, ok)
+			}
+			if ok && !month.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, month)
+			}
+		})
+	}
+}
+
+func TestRepositoryImplementsChainStore(t *testing.T) {
+	var _ audit.ChainStore = (*Repository)(nil)
+}
+
+func TestChainRange(t *testing.T) {
+	start, end := chainRange(time.Date(2026, 12, 31, 23, 59, 59, 0, time.Local))
+
+	if !start.Equal(time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected chain range %v - %v", start, end)
+	}
+}
@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/batch"
+	"3tcapital/goclonacion/internal/core/invoice"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository implements the batch.Repository interface using PostgreSQL.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new PostgreSQL batch repository.
+func NewRepository(pool *pgxpool.Pool) batch.Repository {
+	return &Repository{pool: pool}
+}
+
+const selectColumns = `
+	id, estado, tipo_documento, total, cursor_posicion, procesados, fallidos, request,
+	documentos_procesados, documentos_fallidos, error_message, created_at, started_at, finished_at`
+
+// Create persists a new batch.
+func (r *Repository) Create(ctx context.Context, b batch.Batch) error {
+	requestJSON, err := json.Marshal(b.Request)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	query := `
+		INSERT INTO document_batch (id, estado, tipo_documento, total, request)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	if _, err := r.pool.Exec(ctx, query, b.ID, string(b.Estado), b.TipoDocumento, b.Total, requestJSON); err != nil {
+		return fmt.Errorf("create batch: %w", err)
+	}
+
+	return nil
+}
+
+// FindByID retrieves a batch by its ID.
+func (r *Repository) FindByID(ctx context.Context, id string) (*batch.Batch, error) {
+	query := `SELECT ` + selectColumns + ` FROM document_batch WHERE id = $1`
+
+	b, err := scanBatch(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query batch: %w", err)
+	}
+
+	return b, nil
+}
+
+// Claim takes the oldest batch that is pending, or processing with an expired lease.
+// Rows locked by a concurrent claim are skipped, so each batch is claimed once.
+func (r *Repository) Claim(ctx context.Context, lease time.Duration) (*batch.Batch, error) {
+	query := `
+		UPDATE document_batch
+		SET estado = 'processing', started_at = COALESCE(started_at, NOW()),
+			lease_until = NOW() + make_interval(secs => $1)
+		WHERE id = (
+			SELECT id FROM document_batch
+			WHERE estado = 'pending'
+				OR (estado = 'processing' AND (lease_until IS NULL OR lease_until < NOW()))
+			ORDER BY created_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + selectColumns
+
+	b, err := scanBatch(r.pool.QueryRow(ctx, query, lease.Seconds()))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("claim batch: %w", err)
+	}
+
+	return b, nil
+}
+
+// Extend renews the lease of a batch being processed.
+func (r *Repository) Extend(ctx context.Context, id string, lease time.Duration) error {
+	query := `
+		UPDATE document_batch
+		SET lease_until = NOW() + make_interval(secs => $2)
+		WHERE id = $1 AND estado = 'processing'
+	`
+
+	if _, err := r.pool.Exec(ctx, query, id, lease.Seconds()); err != nil {
+		return fmt.Errorf("extend batch lease: %w", err)
+	}
+
+	return nil
+}
+
+// Release ends the lease of a batch whose processing was interrupted.
+func (r *Repository) Release(ctx context.Context, id string) error {
+	query := `UPDATE document_batch SET lease_until = NULL WHERE id = $1 AND estado = 'processing'`
+
+	if _, err := r.pool.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("release batch: %w", err)
+	}
+
+	return nil
+}
+
+// AppendResults stores the results of a processed chunk and advances the cursor. The update
+// is fenced on the expected cursor and an unexpired lease, so an instance whose lease was
+// taken over cannot overwrite the progress of the new owner.
+func (r *Repository) AppendResults(ctx context.Context, id string, from, to int, processed []invoice.ProcessedDocument, failed []invoice.FailedDocument) error {
+	if processed == nil {
+		processed = []invoice.ProcessedDocument{}
+	}
+	if failed == nil {
+		failed = []invoice.FailedDocument{}
+	}
+
+	processedJSON, err := json.Marshal(processed)
+	if err != nil {
+		return fmt.Errorf("marshal processed documents: %w", err)
+	}
+	failedJSON, err := json.Marshal(failed)
+	if err != nil {
+		return fmt.Errorf("marshal failed documents: %w", err)
+	}
+
+	query := `
+		UPDATE document_batch SET
+			cursor_posicion = $3,
+			procesados = procesados + $4,
+			fallidos = fallidos + $5,
+			documentos_procesados = documentos_procesados || $6::jsonb,
+			documentos_fallidos = documentos_fallidos || $7::jsonb
+		WHERE id = $1 AND estado = 'processing' AND cursor_posicion = $2 AND lease_until > NOW()
+	`
+
+	tag, err := r.pool.Exec(ctx, query, id, from, to, len(processed), len(failed), processedJSON, failedJSON)
+	if err != nil {
+		return fmt.Errorf("append batch results: %w", err)
+	}
+	if tag.RowsAffected() != 1 {
+		return batch.ErrLeaseLost
+	}
+
+	return nil
+}
+
+// Finish sets the final status of the batch.
+func (r *Repository) Finish(ctx context.Context, id string, status batch.Status, errMsg string) error {
+	var errValue *string
+	if errMsg != "" {
+		errValue = &errMsg
+	}
+
+	query := `
+		UPDATE document_batch
+		SET estado = $2, error_message = $3, finished_at = NOW(), lease_until = NULL
+		WHERE id = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, id, string(status), errValue); err != nil {
+		return fmt.Errorf("finish batch: %w", err)
+	}
+
+	return nil
+}
+
+// scanBatch maps a batch row (in selectColumns order) to a batch.
+func scanBatch(row pgx.Row) (*batch.Batch, error) {
+	var b batch.Batch
+	var estado string
+	var requestJSON, processedJSON, failedJSON []byte
+	var errMsg *string
+
+	err := row.Scan(
+		&b.ID,
+		&estado,
+		&b.TipoDocumento,
+		&b.Total,
+		&b.Cursor,
+		&b.Procesados,
+		&b.Fallidos,
+		&requestJSON,
+		&processedJSON,
+		&failedJSON,
+		&errMsg,
+		&b.CreatedAt,
+		&b.StartedAt,
+		&b.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Estado = batch.Status(estado)
+	if errMsg != nil {
+		b.Error = *errMsg
+	}
+	if err := json.Unmarshal(requestJSON, &b.Request); err != nil {
+		return nil, fmt.Errorf("unmarshal request: %w", err)
+	}
+	if err := json.Unmarshal(processedJSON, &b.DocumentosProcesados); err != nil {
+		return nil, fmt.Errorf("unmarshal documentos_procesados: %w", err)
+	}
+	if err := json.Unmarshal(failedJSON, &b.DocumentosFallidos); err != nil {
+		return nil, fmt.Errorf("unmarshal documentos_fallidos: %w", err)
+	}
+
+	return &b, nil
+}
@@ -0,0 +1,300 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/contingency"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// transmitLock is the key of the advisory lock held while the backlog is transmitted.
+const transmitLock = "contingencia_transmision"
+
+// Repository implements the contingency.Repository interface using PostgreSQL.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new PostgreSQL contingency repository.
+func NewRepository(pool *pgxpool.Pool) contingency.Repository {
+	return &Repository{pool: pool}
+}
+
+// NextNumber reserves the next number of the OFE contingency numbering.
+func (r *Repository) NextNumber(ctx context.Context, ofeIdentificacion string) (int64, error) {
+	query := `
+		INSERT INTO contingencia_numeracion (ofe_identificacion, ultimo_numero)
+		VALUES ($1, 1)
+		ON CONFLICT (ofe_identificacion)
+		DO UPDATE SET ultimo_numero = contingencia_numeracion.ultimo_numero + 1
+		RETURNING ultimo_numero
+	`
+
+	var number int64
+	if err := r.pool.QueryRow(ctx, query, ofeIdentificacion).Scan(&number); err != nil {
+		return 0, fmt.Errorf("reserve contingency number: %w", err)
+	}
+
+	return number, nil
+}
+
+// Save stores a pending document and returns its ID.
+func (r *Repository) Save(ctx context.Context, doc contingency.Document) (int64, error) {
+	payload, err := json.Marshal(doc.Payload)
+	if err != nil {
+		return 0, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO contingencia_documentos (
+			ofe_identificacion, tipo, prefijo, consecutivo, numero, tipo_contingencia, estado, payload
+		) VALUES ($1, $2, $3, $4, $5, $6, 'pending', $7)
+		RETURNING id
+	`
+
+	var id int64
+	err = r.pool.QueryRow(ctx, query,
+		doc.OfeIdentificacion,
+		doc.Tipo,
+		doc.Prefijo,
+		doc.Consecutivo,
+		doc.Numero,
+		doc.TipoContingencia,
+		payload,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("save contingency document: %w", err)
+	}
+
+	return id, nil
+}
+
+// ListPending retrieves the pending documents in the order they were stored.
+func (r *Repository) ListPending(ctx context.Context) ([]contingency.Document, error) {
+	query := `
+		SELECT id, ofe_identificacion, tipo, prefijo, consecutivo, numero, tipo_contingencia,
+			estado, payload, intentos, errors, created_at
+		FROM contingencia_documentos
+		WHERE estado = 'pending'
+		ORDER BY id ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query contingency documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []contingency.Document
+	for rows.Next() {
+		var doc contingency.Document
+		var estado string
+		var payload, errorsJSON []byte
+		if err := rows.Scan(
+			&doc.ID,
+			&doc.OfeIdentificacion,
+			&doc.Tipo,
+			&doc.Prefijo,
+			&doc.Consecutivo,
+			&doc.Numero,
+			&doc.TipoContingencia,
+			&estado,
+			&payload,
+			&doc.Intentos,
+			&errorsJSON,
+			&doc.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan contingency document: %w", err)
+		}
+		doc.Estado = contingency.Status(estado)
+		if err := json.Unmarshal(payload, &doc.Payload); err != nil {
+			return nil, fmt.Errorf("unmarshal payload: %w", err)
+		}
+		if len(errorsJSON) > 0 {
+			if err := json.Unmarshal(errorsJSON, &doc.Errors); err != nil {
+				return nil, fmt.Errorf("unmarshal errors: %w", err)
+			}
+		}
+		docs = append(docs, doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return docs, nil
+}
+
+// RecordAttempt counts a transmission attempt that could not reach the provider.
+func (r *Repository) RecordAttempt(ctx context.Context, id int64, errMsg string) error {
+	errorsJSON, err := json.Marshal([]string{errMsg})
+	if err != nil {
+		return fmt.Errorf("marshal errors: %w", err)
+	}
+
+	query := `UPDATE contingencia_documentos SET intentos = intentos + 1, errors = $2 WHERE id = $1`
+	if _, err := r.pool.Exec(ctx, query, id, errorsJSON); err != nil {
+		return fmt.Errorf("record transmission attempt: %w", err)
+	}
+
+	return nil
+}
+
+// MarkTransmitted flags the document as transmitted and accepted.
+func (r *Repository) MarkTransmitted(ctx context.Context, id int64, cufe string) error {
+	query := `
+		UPDATE contingencia_documentos
+		SET estado = 'transmitted', cufe = NULLIF($2, ''), errors = NULL,
+			intentos = intentos + 1, transmitted_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, id, cufe); err != nil {
+		return fmt.Errorf("mark contingency document transmitted: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed flags the document as transmitted and rejected.
+func (r *Repository) MarkFailed(ctx context.Context, id int64, errs []string) error {
+	errorsJSON, err := json.Marshal(errs)
+	if err != nil {
+		return fmt.Errorf("marshal errors: %w", err)
+	}
+
+	query := `
+		UPDATE contingencia_documentos
+		SET estado = 'failed', errors = $2, intentos = intentos + 1, transmitted_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, id, errorsJSON); err != nil {
+		return fmt.Errorf("mark contingency document failed: %w", err)
+	}
+
+	return nil
+}
+
+// Summary counts the stored documents by transmission state.
+func (r *Repository) Summary(ctx context.Context) (*contingency.Summary, error) {
+	query := `
+		SELECT ofe_identificacion, estado, COUNT(*), MIN(created_at)
+		FROM contingencia_documentos
+		GROUP BY ofe_identificacion, estado
+	`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query contingency summary: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &contingency.Summary{PendientesPorOfe: make(map[string]int)}
+	for rows.Next() {
+		var ofeIdentificacion, estado string
+		var count int
+		var oldest time.Time
+		if err := rows.Scan(&ofeIdentificacion, &estado, &count, &oldest); err != nil {
+			return nil, fmt.Errorf("scan contingency summary: %w", err)
+		}
+		switch contingency.Status(estado) {
+		case contingency.StatusPending:
+			summary.Pendientes += count
+			summary.PendientesPorOfe[ofeIdentificacion] = count
+			if summary.PendienteMasAntiguo == nil || oldest.Before(*summary.PendienteMasAntiguo) {
+				summary.PendienteMasAntiguo = &oldest
+			}
+		case contingency.StatusTransmitted:
+			summary.Transmitidos += count
+		case contingency.StatusFailed:
+			summary.Fallidos += count
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return summary, nil
+}
+
+// LoadState retrieves the persisted contingency mode. It is inactive if it was never saved.
+func (r *Repository) LoadState(ctx context.Context) (contingency.State, error) {
+	query := `
+		SELECT activa, COALESCE(tipo_contingencia, ''), COALESCE(origen, ''), COALESCE(motivo, ''), desde
+		FROM contingencia_estado
+		WHERE id = 1
+	`
+
+	var state contingency.State
+	var origen string
+	err := r.pool.QueryRow(ctx, query).Scan(&state.Activa, &state.Tipo, &origen, &state.Motivo, &state.Desde)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return contingency.State{}, nil
+	}
+	if err != nil {
+		return contingency.State{}, fmt.Errorf("load contingency state: %w", err)
+	}
+	state.Origen = contingency.Origin(origen)
+
+	return state, nil
+}
+
+// SaveState persists the contingency mode.
+func (r *Repository) SaveState(ctx context.Context, state contingency.State) error {
+	query := `
+		INSERT INTO contingencia_estado (id, activa, tipo_contingencia, origen, motivo, desde, updated_at)
+		VALUES (1, $1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5, NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			activa = EXCLUDED.activa,
+			tipo_contingencia = EXCLUDED.tipo_contingencia,
+			origen = EXCLUDED.origen,
+			motivo = EXCLUDED.motivo,
+			desde = EXCLUDED.desde,
+			updated_at = NOW()
+	`
+
+	if _, err := r.pool.Exec(ctx, query, state.Activa, state.Tipo, string(state.Origen), state.Motivo, state.Desde); err != nil {
+		return fmt.Errorf("save contingency state: %w", err)
+	}
+
+	return nil
+}
+
+// LockTransmission takes the advisory lock that lets a single instance transmit the
+// backlog at a time. The lock belongs to the database session, so the connection is
+// kept out of the pool until unlock is called.
+func (r *Repository) LockTransmission(ctx context.Context) (func(), bool, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("acquire connection: %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", transmitLock).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("lock contingency transmission: %w", err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock(hashtext($1))", transmitLock); err != nil {
+			// Closing the session releases the lock
+			conn.Conn().Close(ctx)
+		}
+		conn.Release()
+	}
+	return unlock, true, nil
+}
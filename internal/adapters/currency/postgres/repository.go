@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/currency"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository implements the currency.Repository interface using PostgreSQL.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new PostgreSQL exchange rate repository.
+func NewRepository(pool *pgxpool.Pool) currency.Repository {
+	return &Repository{pool: pool}
+}
+
+// Rate retrieves the rate in force on the date: the latest one on or before it.
+func (r *Repository) Rate(ctx context.Context, code string, date time.Time) (*currency.Rate, error) {
+	query := `SELECT moneda, fecha, valor::text FROM tasas_cambio
+		WHERE moneda = $1 AND fecha <= $2
+		ORDER BY fecha DESC
+		LIMIT 1`
+
+	var rate currency.Rate
+	err := r.pool.QueryRow(ctx, query, code, date).Scan(&rate.Currency, &rate.Date, &rate.Value)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query exchange rate: %w", err)
+	}
+	return &rate, nil
+}
+
+// Save creates or replaces the rate of a currency and date.
+func (r *Repository) Save(ctx context.Context, rate currency.Rate) error {
+	query := `
+		INSERT INTO tasas_cambio (moneda, fecha, valor) VALUES ($1, $2, $3::numeric)
+		ON CONFLICT (moneda, fecha) DO UPDATE SET valor = EXCLUDED.valor, fecha_modificacion = NOW()
+	`
+	if _, err := r.pool.Exec(ctx, query, rate.Currency, rate.Date, rate.Value); err != nil {
+		return fmt.Errorf("save exchange rate: %w", err)
+	}
+	return nil
+}
+
+// List retrieves the rates of a currency between two dates (inclusive), newest first.
+func (r *Repository) List(ctx context.Context, code string, from, to time.Time) ([]currency.Rate, error) {
+	query := `SELECT moneda, fecha, valor::text FROM tasas_cambio
+		WHERE moneda = $1 AND fecha BETWEEN $2 AND $3
+		ORDER BY fecha DESC`
+
+	rows, err := r.pool.Query(ctx, query, code, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []currency.Rate
+	for rows.Next() {
+		var rate currency.Rate
+		if err := rows.Scan(&rate.Currency, &rate.Date, &rate.Value); err != nil {
+			return nil, fmt.Errorf("scan exchange rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return rates, nil
+}
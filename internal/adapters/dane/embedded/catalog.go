@@ -0,0 +1,131 @@
+// Package embedded implements dane.Service with an in-memory DIVIPOLA catalog. The binary
+// ships a snapshot of the catalog (divipola.csv); a newer one can replace it at runtime.
+package embedded
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"3tcapital/goclonacion/internal/core/dane"
+)
+
+//go:embed divipola.csv
+var divipolaCSV []byte
+
+// Catalog is an in-memory DIVIPOLA catalog safe for concurrent use.
+type Catalog struct {
+	mu             sync.RWMutex
+	municipalities []dane.Municipality // Sorted by code
+	byCode         map[string]dane.Municipality
+	departments    []dane.Department
+}
+
+var _ dane.Catalog = (*Catalog)(nil)
+
+// New creates a catalog with the snapshot embedded in the binary.
+func New() (*Catalog, error) {
+	municipalities, err := dane.ParseCSV(bytes.NewReader(divipolaCSV))
+	if err != nil {
+		return nil, fmt.Errorf("embedded DIVIPOLA catalog: %w", err)
+	}
+	c := &Catalog{}
+	if err := c.Replace(municipalities); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Replace swaps the catalog contents.
+func (c *Catalog) Replace(municipalities []dane.Municipality) error {
+	byCode := make(map[string]dane.Municipality, len(municipalities))
+	depNames := make(map[string]string)
+	for _, m := range municipalities {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		byCode[m.Codigo] = m
+		depNames[m.DepCodigo] = m.DepNombre
+	}
+
+	sorted := make([]dane.Municipality, 0, len(byCode))
+	for _, m := range byCode {
+		sorted = append(sorted, m)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Codigo < sorted[j].Codigo })
+
+	departments := make([]dane.Department, 0, len(depNames))
+	for code, name := range depNames {
+		departments = append(departments, dane.Department{Codigo: code, Nombre: name})
+	}
+	sort.Slice(departments, func(i, j int) bool { return departments[i].Codigo < departments[j].Codigo })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.municipalities = sorted
+	c.byCode = byCode
+	c.departments = departments
+	return nil
+}
+
+// GetMunicipalityByCode looks up a municipality by its 5 digit DIVIPOLA code.
+func (c *Catalog) GetMunicipalityByCode(ctx context.Context, codigoDivipola string) (*dane.Municipality, error) {
+	if codigoDivipola == "" {
+		return nil, fmt.Errorf("código DIVIPOLA no puede estar vacío")
+	}
+
+	c.mu.RLock()
+	m, ok := c.byCode[strings.TrimSpace(codigoDivipola)]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("municipio con código DIVIPOLA %s no encontrado en DANE", codigoDivipola)
+	}
+	return &m, nil
+}
+
+// ListMunicipalities returns every municipality sorted by code.
+func (c *Catalog) ListMunicipalities(ctx context.Context) ([]dane.Municipality, error) {
+	return c.Search("", "", 0), nil
+}
+
+// Search returns the municipalities whose name contains query, ignoring case and accents.
+func (c *Catalog) Search(query, depCodigo string, limit int) []dane.Municipality {
+	query = fold(query)
+	depCodigo = strings.TrimSpace(depCodigo)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := []dane.Municipality{}
+	for _, m := range c.municipalities {
+		if depCodigo != "" && m.DepCodigo != depCodigo {
+			continue
+		}
+		if query != "" && !strings.Contains(fold(m.Nombre), query) {
+			continue
+		}
+		result = append(result, m)
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+// Departments returns the departments sorted by code.
+func (c *Catalog) Departments() []dane.Department {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]dane.Department(nil), c.departments...)
+}
+
+var accents = strings.NewReplacer("Á", "A", "É", "E", "Í", "I", "Ó", "O", "Ú", "U", "Ü", "U", "Ñ", "N")
+
+// fold upper-cases s and removes the Spanish accents so that "bogota" matches "BOGOTÁ".
+func fold(s string) string {
+	return accents.Replace(strings.ToUpper(strings.TrimSpace(s)))
+}
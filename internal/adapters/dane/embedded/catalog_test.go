@@ -0,0 +1,78 @@
+package embedded
+
+import (
+	"context"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/dane"
+)
+
+func TestNew_EmbeddedCatalog(t *testing.T) {
+	c, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m, err := c.GetMunicipalityByCode(context.Background(), "11001")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Nombre != "BOGOTÁ, D.C." || m.DepCodigo != "11" {
+		t.Errorf("unexpected municipality: %+v", m)
+	}
+
+	if _, err := c.GetMunicipalityByCode(context.Background(), "00000"); err == nil {
+		t.Error("expected error for an unknown code")
+	}
+
+	if got := len(c.Departments()); got != 33 {
+		t.Errorf("expected the 33 departments, got %d", got)
+	}
+	// 1,102 municipalities, Bogotá D.C. and the non-municipalized areas
+	if got := len(c.Search("", "", 0)); got != 1122 {
+		t.Errorf("expected the full DIVIPOLA catalog, got %d municipalities", got)
+	}
+}
+
+func TestCatalog_Search(t *testing.T) {
+	c, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Search("medellin", "", 0)
+	if len(result) != 1 || result[0].Codigo != "05001" {
+		t.Errorf("expected accent-insensitive match for Medellín, got %+v", result)
+	}
+
+	result = c.Search("", "25", 3)
+	if len(result) != 3 {
+		t.Fatalf("expected the limit to apply, got %d", len(result))
+	}
+	for _, m := range result {
+		if m.DepCodigo != "25" {
+			t.Errorf("expected only Cundinamarca, got %+v", m)
+		}
+	}
+}
+
+func TestCatalog_Replace(t *testing.T) {
+	c, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.Replace([]dane.Municipality{{Codigo: "05002", Nombre: "ABEJORRAL", DepCodigo: "05", DepNombre: "ANTIOQUIA"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.GetMunicipalityByCode(context.Background(), "05002"); err != nil {
+		t.Errorf("expected replaced catalog, got %v", err)
+	}
+	if _, err := c.GetMunicipalityByCode(context.Background(), "05001"); err == nil {
+		t.Error("expected previous municipalities to be dropped")
+	}
+
+	if err := c.Replace([]dane.Municipality{{Codigo: "5002"}}); err == nil {
+		t.Error("expected invalid municipality to be rejected")
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"net/url"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/dane"
+	"3tcapital/goclonacion/internal/core/dane"
 )
 
 const (
@@ -18,9 +18,12 @@ const (
 	DANEBaseURL = "https://www.datos.gov.co/resource/gdxc-w37w.json"
 	// DefaultTimeout is the default timeout for DANE API requests
 	DefaultTimeout = 10 * time.Second
+	// catalogLimit is the page size used to download the whole catalog (about 1,100 municipalities)
+	catalogLimit = 5000
 )
 
-// Client implements the dane.Service interface using HTTP requests to datos.gov.co
+// Client implements the dane.Service interface using HTTP requests to datos.gov.co.
+// It is also a dane.Source, used to refresh the DIVIPOLA catalog.
 type Client struct {
 	baseURL string
 	client  *http.Client
@@ -29,7 +32,7 @@ type Client struct {
 
 // NewClient creates a new DANE HTTP client.
 // If baseURL is empty, uses the default DANE API URL.
-func NewClient(baseURL string, httpClient *http.Client, log *slog.Logger) dane.Service {
+func NewClient(baseURL string, httpClient *http.Client, log *slog.Logger) *Client {
 	if baseURL == "" {
 		baseURL = DANEBaseURL
 	}
@@ -140,3 +143,54 @@ func (c *Client) GetMunicipalityByCode(ctx context.Context, codigoDivipola strin
 
 	return municipality, nil
 }
+
+// ListMunicipalities downloads the whole DIVIPOLA catalog from the DANE API.
+func (c *Client) ListMunicipalities(ctx context.Context) ([]dane.Municipality, error) {
+	apiURL, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	query := apiURL.Query()
+	query.Set("$limit", fmt.Sprint(catalogLimit))
+	query.Set("$order", "cod_mpio")
+	apiURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DANE API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DANE API returned status %d", resp.StatusCode)
+	}
+
+	var results []daneResponse
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("parse DANE API response: %w", err)
+	}
+
+	municipalities := make([]dane.Municipality, 0, len(results))
+	for _, result := range results {
+		m := dane.Municipality{
+			Codigo:    result.CodMpio,
+			Nombre:    result.NomMpio,
+			DepCodigo: result.CodDpto,
+			DepNombre: result.Dpto,
+		}
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("DANE API: %w", err)
+		}
+		municipalities = append(municipalities, m)
+	}
+
+	c.log.Info("Downloaded DIVIPOLA catalog from DANE", "municipios", len(municipalities))
+	return municipalities, nil
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"3tcapital/goclonacion/internal/core/dane"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository implements the dane.Repository interface using PostgreSQL.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new PostgreSQL DIVIPOLA repository.
+func NewRepository(pool *pgxpool.Pool) dane.Repository {
+	return &Repository{pool: pool}
+}
+
+// ListMunicipalities retrieves the stored catalog sorted by code.
+func (r *Repository) ListMunicipalities(ctx context.Context) ([]dane.Municipality, error) {
+	query := `SELECT mun_codigo, mun_nombre, dep_codigo, dep_nombre FROM divipola_municipios ORDER BY mun_codigo`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query municipalities: %w", err)
+	}
+	defer rows.Close()
+
+	var municipalities []dane.Municipality
+	for rows.Next() {
+		var m dane.Municipality
+		if err := rows.Scan(&m.Codigo, &m.Nombre, &m.DepCodigo, &m.DepNombre); err != nil {
+			return nil, fmt.Errorf("scan municipality: %w", err)
+		}
+		municipalities = append(municipalities, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return municipalities, nil
+}
+
+// ReplaceMunicipalities replaces the stored catalog in a single transaction.
+func (r *Repository) ReplaceMunicipalities(ctx context.Context, municipalities []dane.Municipality) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM divipola_municipios`); err != nil {
+		return fmt.Errorf("delete municipalities: %w", err)
+	}
+
+	query := `INSERT INTO divipola_municipios (mun_codigo, mun_nombre, dep_codigo, dep_nombre) VALUES ($1, $2, $3, $4)`
+	for _, m := range municipalities {
+		if _, err := tx.Exec(ctx, query, m.Codigo, m.Nombre, m.DepCodigo, m.DepNombre); err != nil {
+			return fmt.Errorf("insert municipality %s: %w", m.Codigo, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
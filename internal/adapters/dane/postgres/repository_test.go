@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/dane"
+)
+
+// Note: These tests require a PostgreSQL database connection.
+// They are integration tests and should be run with a test database.
+
+func TestRepositoryIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	t.Run("mock test for structure validation", func(t *testing.T) {
+		var _ dane.Repository = (*Repository)(nil)
+	})
+}
@@ -0,0 +1,371 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/document"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository implements the document.Repository interface using PostgreSQL.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new PostgreSQL document ledger repository.
+func NewRepository(pool *pgxpool.Pool) document.Repository {
+	return &Repository{pool: pool}
+}
+
+const selectColumns = `
+	id, ofe_identificacion, tipo, prefijo, consecutivo, estado, cufe, cdo_id,
+	payload_original, payload_enriquecido, xml_base64, pdf_base64, xml_ref, pdf_ref,
+	errores, payload_hash, referencia, cufe_calculado, created_at, updated_at`
+
+// insertColumns and resetColumns are the columns written when an entry is created or reset.
+const (
+	insertColumns = `
+		INSERT INTO document_ledger (
+			ofe_identificacion, tipo, prefijo, consecutivo, estado, cufe, cdo_id,
+			payload_original, payload_enriquecido, xml_base64, pdf_base64, xml_ref, pdf_ref, errores,
+			payload_hash, referencia, cufe_calculado
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`
+	resetColumns = `
+			estado = EXCLUDED.estado,
+			cufe = EXCLUDED.cufe,
+			cdo_id = EXCLUDED.cdo_id,
+			payload_original = EXCLUDED.payload_original,
+			payload_enriquecido = EXCLUDED.payload_enriquecido,
+			xml_base64 = EXCLUDED.xml_base64,
+			pdf_base64 = EXCLUDED.pdf_base64,
+			xml_ref = EXCLUDED.xml_ref,
+			pdf_ref = EXCLUDED.pdf_ref,
+			errores = EXCLUDED.errores,
+			payload_hash = EXCLUDED.payload_hash,
+			referencia = EXCLUDED.referencia,
+			cufe_calculado = EXCLUDED.cufe_calculado,
+			updated_at = NOW()`
+)
+
+// Save creates the ledger entry or resets an existing one with the same key.
+func (r *Repository) Save(ctx context.Context, doc document.Document) (int64, error) {
+	args, err := insertArgs(doc)
+	if err != nil {
+		return 0, err
+	}
+
+	query := insertColumns + `
+		ON CONFLICT (ofe_identificacion, tipo, prefijo, consecutivo) DO UPDATE SET` + resetColumns + `
+		RETURNING id
+	`
+
+	var id int64
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("save document: %w", err)
+	}
+
+	return id, nil
+}
+
+// Claim creates the ledger entry or resets an existing one whose status allows a takeover.
+// The conflict clause locks the existing row, so concurrent claims of the same key are
+// decided one after the other against its latest status.
+func (r *Repository) Claim(ctx context.Context, doc document.Document, staleAfter time.Duration, takeover ...document.Status) (bool, error) {
+	args, err := insertArgs(doc)
+	if err != nil {
+		return false, err
+	}
+	statuses := make([]string, 0, len(takeover))
+	for _, status := range takeover {
+		statuses = append(statuses, string(status))
+	}
+	args = append(args, statuses, staleAfter.Seconds())
+
+	query := insertColumns + `
+		ON CONFLICT (ofe_identificacion, tipo, prefijo, consecutivo) DO UPDATE SET` + resetColumns + `
+		WHERE document_ledger.estado = ANY($17::text[])
+			OR (document_ledger.estado IN ('received', 'validated')
+				AND document_ledger.updated_at < NOW() - make_interval(secs => $18))
+		RETURNING id
+	`
+
+	var id int64
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("claim document: %w", err)
+	}
+
+	return true, nil
+}
+
+// insertArgs returns the values of insertColumns for the document.
+func insertArgs(doc document.Document) ([]interface{}, error) {
+	errorsJSON, err := json.Marshal(doc.Errors)
+	if err != nil {
+		return nil, fmt.Errorf("marshal errores: %w", err)
+	}
+	return []interface{}{
+		doc.OfeIdentificacion,
+		doc.Tipo,
+		doc.Prefijo,
+		doc.Consecutivo,
+		string(doc.Estado),
+		nullString(doc.CUFE),
+		doc.CdoID,
+		nullJSON(doc.OriginalPayload),
+		nullJSON(doc.EnrichedPayload),
+		nullString(doc.XmlBase64),
+		nullString(doc.PdfBase64),
+		nullString(doc.XmlRef),
+		nullString(doc.PdfRef),
+		errorsJSON,
+		nullString(doc.PayloadHash),
+		nullString(doc.Referencia),
+		nullString(doc.CUFECalculado),
+	}, nil
+}
+
+// UpdateStatus records a state transition. Empty fields in the update keep the stored values.
+func (r *Repository) UpdateStatus(ctx context.Context, key document.Key, update document.StatusUpdate) error {
+	var errorsJSON []byte
+	if update.Errors != nil {
+		var err error
+		errorsJSON, err = json.Marshal(update.Errors)
+		if err != nil {
+			return fmt.Errorf("marshal errores: %w", err)
+		}
+	}
+
+	query := `
+		UPDATE document_ledger SET
+			estado = $1,
+			payload_enriquecido = COALESCE($2, payload_enriquecido),
+			cufe = COALESCE($3, cufe),
+			cdo_id = COALESCE($4, cdo_id),
+			xml_base64 = COALESCE($5, xml_base64),
+			pdf_base64 = COALESCE($6, pdf_base64),
+			xml_ref = COALESCE($7, xml_ref),
+			pdf_ref = COALESCE($8, pdf_ref),
+			errores = COALESCE($9, errores),
+			cufe_calculado = COALESCE($14, cufe_calculado),
+			updated_at = NOW()
+		WHERE ofe_identificacion = $10 AND tipo = $11 AND prefijo = $12 AND consecutivo = $13
+	`
+
+	result, err := r.pool.Exec(ctx, query,
+		string(update.Estado),
+		nullJSON(update.EnrichedPayload),
+		nullString(update.CUFE),
+		update.CdoID,
+		nullString(update.XmlBase64),
+		nullString(update.PdfBase64),
+		nullString(update.XmlRef),
+		nullString(update.PdfRef),
+		nullJSON(errorsJSON),
+		key.OfeIdentificacion,
+		key.Tipo,
+		key.Prefijo,
+		key.Consecutivo,
+		nullString(update.CUFECalculado),
+	)
+	if err != nil {
+		return fmt.Errorf("update document status: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("documento no encontrado: ofe=%s tipo=%s prefijo=%s consecutivo=%s", key.OfeIdentificacion, key.Tipo, key.Prefijo, key.Consecutivo)
+	}
+
+	return nil
+}
+
+// FindByKey retrieves a ledger entry by its key.
+func (r *Repository) FindByKey(ctx context.Context, key document.Key) (*document.Document, error) {
+	query := `SELECT ` + selectColumns + `
+		FROM document_ledger
+		WHERE ofe_identificacion = $1 AND tipo = $2 AND prefijo = $3 AND consecutivo = $4
+	`
+
+	doc, err := scanDocument(r.pool.QueryRow(ctx, query, key.OfeIdentificacion, key.Tipo, key.Prefijo, key.Consecutivo))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// List retrieves ledger entries matching the filter, newest first.
+func (r *Repository) List(ctx context.Context, filter document.Filter) ([]document.Document, int, error) {
+	whereConditions := []string{}
+	queryArgs := []interface{}{}
+	argIndex := 1
+
+	addCondition := func(column string, value interface{}) {
+		whereConditions = append(whereConditions, fmt.Sprintf("%s = $%d", column, argIndex))
+		queryArgs = append(queryArgs, value)
+		argIndex++
+	}
+
+	if filter.OfeIdentificacion != "" {
+		addCondition("ofe_identificacion", filter.OfeIdentificacion)
+	}
+	if filter.Tipo != "" {
+		addCondition("tipo", filter.Tipo)
+	}
+	if filter.Prefijo != "" {
+		addCondition("prefijo", filter.Prefijo)
+	}
+	if filter.Consecutivo != "" {
+		addCondition("consecutivo", filter.Consecutivo)
+	}
+	if filter.CUFE != "" {
+		addCondition("cufe", filter.CUFE)
+	}
+	if filter.Referencia != "" {
+		addCondition("referencia", filter.Referencia)
+	}
+	if filter.CdoID != nil {
+		addCondition("cdo_id", *filter.CdoID)
+	}
+	if filter.Estado != "" {
+		addCondition("estado", string(filter.Estado))
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, "SELECT COUNT(*) FROM document_ledger "+whereClause, queryArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count documents: %w", err)
+	}
+
+	query := `SELECT ` + selectColumns + `
+		FROM document_ledger
+		` + whereClause + `
+		ORDER BY created_at DESC, id DESC`
+
+	if filter.Length != -1 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+		queryArgs = append(queryArgs, filter.Length, filter.Start)
+	}
+
+	rows, err := r.pool.Query(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query documents: %w", err)
+	}
+	defer rows.Close()
+
+	docs := make([]document.Document, 0)
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan document: %w", err)
+		}
+		docs = append(docs, *doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return docs, total, nil
+}
+
+// scanDocument maps a ledger row (in selectColumns order) to a document.
+func scanDocument(row pgx.Row) (*document.Document, error) {
+	var doc document.Document
+	var estado string
+	var cufe, xmlBase64, pdfBase64, xmlRef, pdfRef, payloadHash, referencia, cufeCalculado *string
+	var cdoID *int64
+	var originalPayload, enrichedPayload, errorsJSON []byte
+
+	err := row.Scan(
+		&doc.ID,
+		&doc.OfeIdentificacion,
+		&doc.Tipo,
+		&doc.Prefijo,
+		&doc.Consecutivo,
+		&estado,
+		&cufe,
+		&cdoID,
+		&originalPayload,
+		&enrichedPayload,
+		&xmlBase64,
+		&pdfBase64,
+		&xmlRef,
+		&pdfRef,
+		&errorsJSON,
+		&payloadHash,
+		&referencia,
+		&cufeCalculado,
+		&doc.CreatedAt,
+		&doc.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Estado = document.Status(estado)
+	doc.CUFE = derefString(cufe)
+	doc.XmlBase64 = derefString(xmlBase64)
+	doc.PdfBase64 = derefString(pdfBase64)
+	doc.XmlRef = derefString(xmlRef)
+	doc.PdfRef = derefString(pdfRef)
+	doc.PayloadHash = derefString(payloadHash)
+	doc.Referencia = derefString(referencia)
+	doc.CUFECalculado = derefString(cufeCalculado)
+	if cdoID != nil {
+		id := int(*cdoID)
+		doc.CdoID = &id
+	}
+	if len(originalPayload) > 0 {
+		doc.OriginalPayload = originalPayload
+	}
+	if len(enrichedPayload) > 0 {
+		doc.EnrichedPayload = enrichedPayload
+	}
+	if len(errorsJSON) > 0 {
+		if err := json.Unmarshal(errorsJSON, &doc.Errors); err != nil {
+			return nil, fmt.Errorf("unmarshal errores: %w", err)
+		}
+	}
+
+	return &doc, nil
+}
+
+// nullString maps an empty string to SQL NULL.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// nullJSON maps an empty JSON payload to SQL NULL.
+func nullJSON(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/document"
+)
+
+// Note: These tests require a PostgreSQL database connection.
+// They are integration tests and should be run with a test database.
+
+func TestRepositoryIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	t.Run("mock test for structure validation", func(t *testing.T) {
+		var _ document.Repository = (*Repository)(nil)
+	})
+}
+
+func TestNullHelpers(t *testing.T) {
+	if nullString("") != nil {
+		t.Error("expected empty string to map to NULL")
+	}
+	if v := nullString("abc"); v == nil || *v != "abc" {
+		t.Errorf("expected pointer to %q, got %v", "abc", v)
+	}
+	if nullJSON(nil) != nil {
+		t.Error("expected empty payload to map to NULL")
+	}
+	if derefString(nil) != "" {
+		t.Error("expected nil pointer to map to empty string")
+	}
+}
@@ -6,8 +6,8 @@ import (
 	"strconv"
 	"strings"
 
-	appacquirer "3tcapital/ms_facturacion_core/internal/application/acquirer"
-	httperrors "3tcapital/ms_facturacion_core/internal/infrastructure/http"
+	appacquirer "3tcapital/goclonacion/internal/application/acquirer"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
 
 	"github.com/go-chi/chi/v5"
 )
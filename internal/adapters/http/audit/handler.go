@@ -0,0 +1,204 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appaudit "3tcapital/goclonacion/internal/application/audit"
+	"3tcapital/goclonacion/internal/core/audit"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+)
+
+// exportFlushEvery is the number of exported lines written between flushes to the client.
+const exportFlushEvery = 100
+
+// Handler bridges HTTP traffic with the audit log query service.
+type Handler struct {
+	service *appaudit.Service
+}
+
+// NewHandler creates a new audit log HTTP handler.
+func NewHandler(service *appaudit.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// SearchLogs handles GET /api/v1/auditoria requests.
+// Supported filters: desde, hasta, proveedor, operacion, status, duracion_min_ms,
+// correlation_id, documento, start, length.
+func (h *Handler) SearchLogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{err.Error()}, nil)
+		return
+	}
+
+	response, err := h.service.Search(r.Context(), filter)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
+// ExportLogs handles GET /api/v1/auditoria/exportar requests.
+// It accepts the same filters as SearchLogs, ignores pagination and streams every match
+// as NDJSON (one audit log per line), oldest first.
+func (h *Handler) ExportLogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{err.Error()}, nil)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	written := 0
+	writeHeader := func() {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="auditoria.ndjson"`)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	err = h.service.Export(r.Context(), filter, func(log audit.ProviderAuditLog) error {
+		if written == 0 {
+			writeHeader()
+		}
+		if err := encoder.Encode(log); err != nil {
+			return err
+		}
+		written++
+		if flusher != nil && written%exportFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Once the first line is sent the status cannot change; the client sees a truncated export
+		if written == 0 {
+			h.handleError(w, err)
+		}
+		return
+	}
+
+	if written == 0 {
+		writeHeader()
+	}
+}
+
+// VerifyChains handles GET /api/v1/auditoria/verificar requests.
+// It walks the hash chain of the month given by mes (AAAA-MM), or every chain without it,
+// and reports the first broken link of each one.
+func (h *Handler) VerifyChains(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.Verify(r.Context(), strings.TrimSpace(r.URL.Query().Get("mes")))
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
+// parseFilter reads the audit log filters from the query string.
+func parseFilter(r *http.Request) (audit.Filter, error) {
+	q := r.URL.Query()
+	filter := audit.Filter{
+		Provider:      q.Get("proveedor"),
+		Operation:     q.Get("operacion"),
+		CorrelationID: q.Get("correlation_id"),
+		Document:      strings.TrimSpace(q.Get("documento")),
+	}
+
+	var err error
+	if filter.From, err = parseTime(q.Get("desde"), false); err != nil {
+		return filter, fmt.Errorf("desde debe ser una fecha (AAAA-MM-DD) o fecha y hora RFC 3339")
+	}
+	if filter.To, err = parseTime(q.Get("hasta"), true); err != nil {
+		return filter, fmt.Errorf("hasta debe ser una fecha (AAAA-MM-DD) o fecha y hora RFC 3339")
+	}
+
+	if statusStr := q.Get("status"); statusStr != "" {
+		status, err := strconv.Atoi(statusStr)
+		if err != nil {
+			return filter, fmt.Errorf("status debe ser un número entero")
+		}
+		filter.Status = &status
+	}
+
+	if durationStr := q.Get("duracion_min_ms"); durationStr != "" {
+		duration, err := strconv.ParseInt(durationStr, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("duracion_min_ms debe ser un número entero no negativo")
+		}
+		filter.MinDurationMs = duration
+	}
+
+	if startStr := q.Get("start"); startStr != "" {
+		start, err := strconv.Atoi(startStr)
+		if err != nil || start < 0 {
+			return filter, fmt.Errorf("start debe ser un número entero no negativo")
+		}
+		filter.Start = start
+	}
+
+	if lengthStr := q.Get("length"); lengthStr != "" {
+		length, err := strconv.Atoi(lengthStr)
+		if err != nil || length <= 0 {
+			return filter, fmt.Errorf("length debe ser un número entero positivo")
+		}
+		filter.Length = length
+	}
+
+	return filter, nil
+}
+
+// parseTime parses an RFC 3339 timestamp or a date. A date used as the end of the range
+// covers the whole day.
+func parseTime(value string, endOfRange bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// handleError maps service errors to appropriate HTTP status codes and formats.
+func (h *Handler) handleError(w http.ResponseWriter, err error) {
+	errorMsg := err.Error()
+
+	if errors.Is(err, appaudit.ErrChainUnavailable) {
+		httperrors.WriteError(w, http.StatusServiceUnavailable, "Servicio No Disponible", []string{errorMsg}, nil)
+		return
+	}
+
+	if errors.Is(err, appaudit.ErrInvalidChain) || strings.Contains(errorMsg, "debe ser") {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{errorMsg}, nil)
+		return
+	}
+
+	httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+}
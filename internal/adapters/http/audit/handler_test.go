@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	appaudit "3tcapital/goclonacion/internal/application/audit"
+	"3tcapital/goclonacion/internal/core/audit"
+	"3tcapital/goclonacion/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	repo := testutil.NewMockAuditRepository()
+	ok, failed := 200, 502
+	logs := []audit.ProviderAuditLog{
+		{CorrelationID: "req-1", Provider: "numrot", Operation: "RegisterDocument", ResponseStatus: &ok, DurationMs: 250,
+			RequestBody: json.RawMessage(`{"Numero":"SETT100"}`), ResponseBody: json.RawMessage(`{"Estado":"Aceptado"}`),
+			CreatedAt: time.Date(2026, 10, 1, 10, 0, 0, 0, time.Local)},
+		{CorrelationID: "req-2", Provider: "numrot", Operation: "RegisterDocument", ResponseStatus: &failed, DurationMs: 8000,
+			RequestBody: json.RawMessage(`{"Numero":"SETT101"}`),
+			CreatedAt:   time.Date(2026, 10, 2, 10, 0, 0, 0, time.Local)},
+	}
+	for _, log := range logs {
+		if err := repo.Save(context.Background(), log); err != nil {
+			t.Fatalf("seed audit log: %v", err)
+		}
+	}
+
+	handler := NewHandler(appaudit.NewService(repo).WithChainStore(repo, nil))
+	router := chi.NewRouter()
+	router.Get("/api/v1/auditoria", handler.SearchLogs)
+	router.Get("/api/v1/auditoria/exportar", handler.ExportLogs)
+	router.Get("/api/v1/auditoria/verificar", handler.VerifyChains)
+	return router
+}
+
+func TestHandler_SearchLogs(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"all", "", http.StatusOK, `"total":2`},
+		{"by document with bodies", "?documento=SETT100", http.StatusOK, `"response_body":{"Estado":"Aceptado"}`},
+		{"by status", "?status=502", http.StatusOK, `"correlation_id":"req-2"`},
+		{"by duration", "?duracion_min_ms=5000", http.StatusOK, `"total":1`},
+		{"whole day", "?desde=2026-10-01&hasta=2026-10-01", http.StatusOK, `"correlation_id":"req-1"`},
+		{"invalid date", "?desde=ayer", http.StatusBadRequest, "desde debe ser una fecha"},
+		{"invalid status", "?status=ok", http.StatusBadRequest, "status debe ser un número entero"},
+		{"inverted range", "?desde=2026-10-02&hasta=2026-10-01T00:00:00Z", http.StatusBadRequest, "hasta debe ser posterior a desde"},
+		{"page too large", "?length=500", http.StatusBadRequest, "length debe ser como máximo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auditoria"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_ExportLogs(t *testing.T) {
+	router := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auditoria/exportar?proveedor=numrot", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected NDJSON content type, got %q", ct)
+	}
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), rec.Body.String())
+	}
+	var first audit.ProviderAuditLog
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("line is not valid JSON: %v", err)
+	}
+	if first.CorrelationID != "req-1" {
+		t.Errorf("expected the oldest log first, got %s", first.CorrelationID)
+	}
+}
+
+func TestHandler_ExportLogsEmpty(t *testing.T) {
+	router := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auditoria/exportar?proveedor=dane", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("expected an empty export, got %d: %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandler_VerifyChains(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"all chains", "", http.StatusOK, `"valid":true`},
+		{"one month", "?mes=2026-10", http.StatusOK, `"verified":2`},
+		{"invalid month", "?mes=10-2026", http.StatusBadRequest, "AAAA-MM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auditoria/verificar"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
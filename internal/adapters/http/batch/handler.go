@@ -0,0 +1,112 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	appbatch "3tcapital/goclonacion/internal/application/batch"
+	appidempotency "3tcapital/goclonacion/internal/application/idempotency"
+	"3tcapital/goclonacion/internal/core/invoice"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler bridges HTTP traffic with the asynchronous batch service.
+type Handler struct {
+	service *appbatch.Service
+}
+
+// NewHandler creates a new batch HTTP handler.
+func NewHandler(service *appbatch.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// CreateBatchResponse represents the response returned when a batch is enqueued.
+type CreateBatchResponse struct {
+	Message string `json:"message"`
+	Lote    string `json:"lote"`
+	Estado  string `json:"estado"`
+	Total   int    `json:"total"`
+}
+
+// CreateBatch handles POST /api/v1/documentos/lotes requests.
+// The batch is stored and processed in background; the response returns the lote id immediately.
+func (h *Handler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperrors.WriteError(w, http.StatusMethodNotAllowed, "Método no permitido", []string{"Este endpoint solo acepta POST"}, nil)
+		return
+	}
+
+	var reqBody invoice.DocumentRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la solicitud no es un JSON válido"}, nil)
+		return
+	}
+
+	b, replayed, err := h.service.SubmitWithKey(r.Context(), strings.TrimSpace(r.Header.Get("Idempotency-Key")), reqBody)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/documentos/lotes/"+b.ID)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(CreateBatchResponse{
+		Message: "Lote recibido para procesamiento",
+		Lote:    b.ID,
+		Estado:  string(b.Estado),
+		Total:   b.Total,
+	})
+}
+
+// GetBatch handles GET /api/v1/documentos/lotes/{id} requests.
+func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperrors.WriteError(w, http.StatusMethodNotAllowed, "Método no permitido", []string{"Este endpoint solo acepta GET"}, nil)
+		return
+	}
+
+	b, err := h.service.GetBatch(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(b); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
+// handleError maps domain errors to appropriate HTTP status codes and formats.
+func (h *Handler) handleError(w http.ResponseWriter, err error) {
+	errorMsg := err.Error()
+
+	if errors.Is(err, appidempotency.ErrKeyReused) || errors.Is(err, appidempotency.ErrInProgress) {
+		httperrors.WriteError(w, http.StatusConflict, "Conflicto de Idempotencia", []string{errorMsg}, nil)
+		return
+	}
+
+	if strings.Contains(errorMsg, "no existe") {
+		httperrors.WriteError(w, http.StatusNotFound, "Lote no encontrado", []string{errorMsg}, nil)
+		return
+	}
+
+	if errors.Is(err, appidempotency.ErrKeyTooLong) || strings.Contains(errorMsg, "inválido") || strings.Contains(errorMsg, "no documents provided") || strings.Contains(errorMsg, "only one document type") {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{errorMsg}, nil)
+		return
+	}
+
+	httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+}
@@ -0,0 +1,105 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	appcatalog "3tcapital/goclonacion/internal/application/catalog"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler bridges HTTP traffic with the catalog application service.
+type Handler struct {
+	service *appcatalog.Service
+}
+
+// NewHandler creates a new catalog HTTP handler.
+func NewHandler(service *appcatalog.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// ListDepartments handles GET /api/v1/catalogos/departamentos requests.
+func (h *Handler) ListDepartments(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.service.Departments())
+}
+
+// SearchMunicipalities handles GET /api/v1/catalogos/municipios?q=&dep_codigo=&limit= requests.
+func (h *Handler) SearchMunicipalities(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"limit debe ser un número entero"}, nil)
+			return
+		}
+		limit = parsed
+	}
+
+	municipalities, err := h.service.SearchMunicipalities(query.Get("q"), query.Get("dep_codigo"), limit)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, municipalities)
+}
+
+// GetMunicipality handles GET /api/v1/catalogos/municipios/{codigo} requests.
+func (h *Handler) GetMunicipality(w http.ResponseWriter, r *http.Request) {
+	codigo := chi.URLParam(r, "codigo")
+
+	municipality, err := h.service.GetMunicipality(r.Context(), codigo)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+	if municipality == nil {
+		httperrors.WriteError(w, http.StatusNotFound, "Municipio No Encontrado", []string{fmt.Sprintf("el municipio [%s] no existe en el catálogo DIVIPOLA", codigo)}, nil)
+		return
+	}
+
+	writeJSON(w, municipality)
+}
+
+// GetCodeList handles GET /api/v1/catalogos/{lista} requests for the DIAN code lists.
+func (h *Handler) GetCodeList(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "lista")
+
+	list := h.service.CodeList(name)
+	if list == nil {
+		detail := fmt.Sprintf("la lista [%s] no existe; listas disponibles: %s", name, strings.Join(h.service.CodeListNames(), ", "))
+		httperrors.WriteError(w, http.StatusNotFound, "Lista No Encontrada", []string{detail}, nil)
+		return
+	}
+
+	writeJSON(w, list)
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
+// handleError maps domain errors to appropriate HTTP status codes and formats.
+func (h *Handler) handleError(w http.ResponseWriter, err error) {
+	errorMsg := err.Error()
+
+	switch {
+	case strings.Contains(errorMsg, "debe ser"):
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{errorMsg}, nil)
+	default:
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
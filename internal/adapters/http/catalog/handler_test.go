@@ -0,0 +1,90 @@
+package catalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/adapters/dane/embedded"
+	appcatalog "3tcapital/goclonacion/internal/application/catalog"
+	"3tcapital/goclonacion/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandler_Municipalities(t *testing.T) {
+	c, err := embedded.New()
+	if err != nil {
+		t.Fatalf("embedded catalog: %v", err)
+	}
+	handler := NewHandler(appcatalog.NewService(c, testutil.NewTestLogger()))
+	router := chi.NewRouter()
+	router.Get("/api/v1/catalogos/departamentos", handler.ListDepartments)
+	router.Get("/api/v1/catalogos/municipios", handler.SearchMunicipalities)
+	router.Get("/api/v1/catalogos/municipios/{codigo}", handler.GetMunicipality)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"departments", "/api/v1/catalogos/departamentos", http.StatusOK, `"dep_nombre":"ANTIOQUIA"`},
+		{"search", "/api/v1/catalogos/municipios?q=cucuta", http.StatusOK, `"mun_codigo":"54001"`},
+		{"search by department", "/api/v1/catalogos/municipios?dep_codigo=76&limit=1", http.StatusOK, `"mun_nombre":"CALI"`},
+		{"invalid limit", "/api/v1/catalogos/municipios?limit=x", http.StatusBadRequest, "limit debe ser"},
+		{"invalid department", "/api/v1/catalogos/municipios?dep_codigo=ABC", http.StatusBadRequest, "dep_codigo debe ser"},
+		{"by code", "/api/v1/catalogos/municipios/05001", http.StatusOK, `"mun_nombre":"MEDELLÍN"`},
+		{"unknown code", "/api/v1/catalogos/municipios/05999", http.StatusNotFound, "no existe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_GetCodeList(t *testing.T) {
+	c, err := embedded.New()
+	if err != nil {
+		t.Fatalf("embedded catalog: %v", err)
+	}
+	handler := NewHandler(appcatalog.NewService(c, testutil.NewTestLogger()))
+	router := chi.NewRouter()
+	router.Get("/api/v1/catalogos/{lista}", handler.GetCodeList)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"taxes", "/api/v1/catalogos/tributos", http.StatusOK, `{"codigo":"01","nombre":"IVA"`},
+		{"payment means", "/api/v1/catalogos/medios-pago", http.StatusOK, `"codigo":"ZZZ"`},
+		{"unknown list", "/api/v1/catalogos/colores", http.StatusNotFound, "listas disponibles: unidades"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
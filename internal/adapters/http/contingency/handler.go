@@ -0,0 +1,104 @@
+package contingency
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	appcontingency "3tcapital/goclonacion/internal/application/contingency"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+)
+
+// Handler bridges HTTP traffic with the contingency application service.
+type Handler struct {
+	service *appcontingency.Service
+}
+
+// NewHandler creates a new contingency HTTP handler.
+func NewHandler(service *appcontingency.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// activateRequest is the body of POST /api/v1/contingencia/activar.
+type activateRequest struct {
+	TipoContingencia string `json:"tipo_contingencia"`
+	Motivo           string `json:"motivo"`
+}
+
+// Dashboard handles GET /api/v1/contingencia requests.
+// It returns the contingency mode with the counts of pending documents.
+func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	dashboard, err := h.service.Dashboard(r.Context())
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, dashboard)
+}
+
+// Activate handles POST /api/v1/contingencia/activar requests.
+func (h *Handler) Activate(w http.ResponseWriter, r *http.Request) {
+	var reqBody activateRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+
+	state, err := h.service.Activate(r.Context(), reqBody.TipoContingencia, reqBody.Motivo)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"estado": state})
+}
+
+// Deactivate handles POST /api/v1/contingencia/desactivar requests.
+// The pending documents are transmitted once the provider is available.
+func (h *Handler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	state, err := h.service.Deactivate(r.Context())
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"estado": state})
+}
+
+// Transmit handles POST /api/v1/contingencia/transmitir requests.
+// It transmits the pending documents now and returns the updated dashboard.
+func (h *Handler) Transmit(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Transmit(r.Context()); err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	h.Dashboard(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
+// handleError maps domain errors to appropriate HTTP status codes and formats.
+func (h *Handler) handleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, appcontingency.ErrInvalidType):
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{err.Error()}, nil)
+	case errors.Is(err, appcontingency.ErrActive):
+		httperrors.WriteError(w, http.StatusConflict, "Contingencia Activa", []string{err.Error()}, nil)
+	case errors.Is(err, appcontingency.ErrTransmitting):
+		httperrors.WriteError(w, http.StatusConflict, "Transmisión en Curso", []string{err.Error()}, nil)
+	case errors.Is(err, appcontingency.ErrTransmission):
+		httperrors.WriteError(w, http.StatusBadGateway, "Error del Proveedor", []string{err.Error()}, nil)
+	default:
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
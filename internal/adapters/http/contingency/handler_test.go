@@ -0,0 +1,69 @@
+package contingency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appcontingency "3tcapital/goclonacion/internal/application/contingency"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandler_Contingency(t *testing.T) {
+	provider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			return nil, errors.New("numrot unavailable")
+		},
+	}
+	service := appcontingency.NewService(provider, testutil.NewMockContingencyRepository(), testutil.NewNullLogger())
+	handler := NewHandler(service)
+	router := chi.NewRouter()
+	router.Get("/api/v1/contingencia", handler.Dashboard)
+	router.Post("/api/v1/contingencia/activar", handler.Activate)
+	router.Post("/api/v1/contingencia/desactivar", handler.Deactivate)
+	router.Post("/api/v1/contingencia/transmitir", handler.Transmit)
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"inactive", http.MethodGet, "/api/v1/contingencia", "", http.StatusOK, `"activa":false`},
+		{"invalid type", http.MethodPost, "/api/v1/contingencia/activar", `{"tipo_contingencia":"01"}`, http.StatusBadRequest, "03 (facturador) o 04 (DIAN)"},
+		{"invalid body", http.MethodPost, "/api/v1/contingencia/activar", `{`, http.StatusBadRequest, "no es válido"},
+		{"activate", http.MethodPost, "/api/v1/contingencia/activar", `{"tipo_contingencia":"04","motivo":"DIAN no disponible"}`, http.StatusOK, `"origen":"manual"`},
+		{"transmit while active", http.MethodPost, "/api/v1/contingencia/transmitir", "", http.StatusConflict, "está activo"},
+		{"store", http.MethodGet, "/api/v1/contingencia", "", http.StatusOK, `"pendientes":1`},
+		{"deactivate", http.MethodPost, "/api/v1/contingencia/desactivar", "", http.StatusOK, `"activa":false`},
+		{"transmit provider down", http.MethodPost, "/api/v1/contingencia/transmitir", "", http.StatusBadGateway, "CONT1"},
+	}
+
+	for _, step := range steps {
+		if step.name == "store" {
+			doc := invoice.OpenETLDocument{OfeIdentificacion: "860011153", RfaPrefijo: "SETT", CdoConsecutivo: "1"}
+			if _, _, err := service.Store(context.Background(), []invoice.OpenETLDocument{doc}, "FC"); err != nil {
+				t.Fatalf("store: %v", err)
+			}
+		}
+
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != step.wantStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.wantStatus, rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), step.wantBody) {
+			t.Errorf("%s: expected body to contain %q, got %s", step.name, step.wantBody, rec.Body.String())
+		}
+	}
+}
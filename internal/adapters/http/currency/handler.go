@@ -0,0 +1,107 @@
+package currency
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	appcurrency "3tcapital/goclonacion/internal/application/currency"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// dateLayout is the format of the fecha, desde and hasta parameters.
+const dateLayout = "2006-01-02"
+
+// Handler bridges HTTP traffic with the exchange rate application service.
+type Handler struct {
+	service *appcurrency.Service
+}
+
+// NewHandler creates a new exchange rate HTTP handler.
+func NewHandler(service *appcurrency.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// setRateRequest is the body of PUT /api/v1/tasas-cambio/{moneda}/{fecha}.
+type setRateRequest struct {
+	Valor string `json:"valor"`
+}
+
+// ListRates handles GET /api/v1/tasas-cambio/{moneda}?desde=&hasta= requests.
+func (h *Handler) ListRates(w http.ResponseWriter, r *http.Request) {
+	from, ok := dateParam(w, "desde", r.URL.Query().Get("desde"))
+	if !ok {
+		return
+	}
+	to, ok := dateParam(w, "hasta", r.URL.Query().Get("hasta"))
+	if !ok {
+		return
+	}
+
+	rates, err := h.service.ListRates(r.Context(), chi.URLParam(r, "moneda"), from, to)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, rates)
+}
+
+// SetRate handles PUT /api/v1/tasas-cambio/{moneda}/{fecha} requests.
+func (h *Handler) SetRate(w http.ResponseWriter, r *http.Request) {
+	date, ok := dateParam(w, "fecha", chi.URLParam(r, "fecha"))
+	if !ok {
+		return
+	}
+
+	var reqBody setRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+
+	if err := h.service.SetRate(r.Context(), chi.URLParam(r, "moneda"), date, reqBody.Valor); err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"success": true})
+}
+
+// dateParam parses an optional YYYY-MM-DD parameter, writing a validation error when it is invalid.
+func dateParam(w http.ResponseWriter, name, value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, true
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{name + " debe tener el formato YYYY-MM-DD"}, nil)
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
+// handleError maps domain errors to appropriate HTTP status codes and formats.
+func (h *Handler) handleError(w http.ResponseWriter, err error) {
+	errorMsg := err.Error()
+
+	switch {
+	case strings.Contains(errorMsg, "inválida") || strings.Contains(errorMsg, "debe ser"):
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{errorMsg}, nil)
+	default:
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
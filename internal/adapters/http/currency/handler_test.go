@@ -0,0 +1,52 @@
+package currency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appcurrency "3tcapital/goclonacion/internal/application/currency"
+	"3tcapital/goclonacion/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandler_Rates(t *testing.T) {
+	handler := NewHandler(appcurrency.NewService(testutil.NewMockExchangeRateRepository()))
+	router := chi.NewRouter()
+	router.Get("/api/v1/tasas-cambio/{moneda}", handler.ListRates)
+	router.Put("/api/v1/tasas-cambio/{moneda}/{fecha}", handler.SetRate)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"set", http.MethodPut, "/api/v1/tasas-cambio/USD/2024-03-15", `{"valor":"3950.25"}`, http.StatusOK, `"success":true`},
+		{"invalid value", http.MethodPut, "/api/v1/tasas-cambio/USD/2024-03-15", `{"valor":"-1"}`, http.StatusBadRequest, "mayor que 0"},
+		{"invalid date", http.MethodPut, "/api/v1/tasas-cambio/USD/15-03-2024", `{"valor":"1"}`, http.StatusBadRequest, "YYYY-MM-DD"},
+		{"invalid body", http.MethodPut, "/api/v1/tasas-cambio/USD/2024-03-15", `{`, http.StatusBadRequest, "no es válido"},
+		{"national currency", http.MethodPut, "/api/v1/tasas-cambio/COP/2024-03-15", `{"valor":"1"}`, http.StatusBadRequest, "moneda nacional"},
+		{"list", http.MethodGet, "/api/v1/tasas-cambio/USD?desde=2024-03-01&hasta=2024-03-31", "", http.StatusOK, `"valor":"3950.250000"`},
+		{"unknown currency", http.MethodGet, "/api/v1/tasas-cambio/XYZ", "", http.StatusBadRequest, "ISO 4217"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package document
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	appdocument "3tcapital/goclonacion/internal/application/document"
+	"3tcapital/goclonacion/internal/core/document"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler bridges HTTP traffic with the documents ledger service.
+type Handler struct {
+	service *appdocument.Service
+}
+
+// NewHandler creates a new documents ledger HTTP handler.
+func NewHandler(service *appdocument.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// ListDocuments handles GET /api/v1/documentos requests.
+// Supported filters: ofe, tipo, prefijo, consecutivo, cufe, cdo_id, estado, start, length.
+func (h *Handler) ListDocuments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperrors.WriteError(w, http.StatusMethodNotAllowed, "Método no permitido", []string{"Este endpoint solo acepta GET"}, nil)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := document.Filter{
+		OfeIdentificacion: q.Get("ofe"),
+		Tipo:              q.Get("tipo"),
+		Prefijo:           q.Get("prefijo"),
+		Consecutivo:       q.Get("consecutivo"),
+		CUFE:              q.Get("cufe"),
+		Estado:            document.Status(q.Get("estado")),
+	}
+
+	if cdoIDStr := q.Get("cdo_id"); cdoIDStr != "" {
+		cdoID, err := strconv.Atoi(cdoIDStr)
+		if err != nil {
+			httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"cdo_id debe ser un número entero"}, nil)
+			return
+		}
+		filter.CdoID = &cdoID
+	}
+
+	if startStr := q.Get("start"); startStr != "" {
+		start, err := strconv.Atoi(startStr)
+		if err != nil || start < 0 {
+			httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"start debe ser un número entero no negativo"}, nil)
+			return
+		}
+		filter.Start = start
+	}
+
+	filter.Length = 10 // Default
+	if lengthStr := q.Get("length"); lengthStr != "" {
+		length, err := strconv.Atoi(lengthStr)
+		if err != nil || length < -1 {
+			httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"length debe ser un número entero (-1 para traer todos)"}, nil)
+			return
+		}
+		filter.Length = length
+	}
+
+	response, err := h.service.ListDocuments(r.Context(), filter)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	h.writeJSON(w, response)
+}
+
+// GetDocument handles GET /api/v1/documentos/{ofe}/{tipo}/{prefijo}/{consecutivo} requests.
+// Documents without prefijo use "-" as placeholder.
+func (h *Handler) GetDocument(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperrors.WriteError(w, http.StatusMethodNotAllowed, "Método no permitido", []string{"Este endpoint solo acepta GET"}, nil)
+		return
+	}
+
+	prefijo := chi.URLParam(r, "prefijo")
+	if prefijo == "-" {
+		prefijo = ""
+	}
+
+	doc, err := h.service.GetDocument(r.Context(),
+		chi.URLParam(r, "ofe"),
+		chi.URLParam(r, "tipo"),
+		prefijo,
+		chi.URLParam(r, "consecutivo"),
+	)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	h.writeJSON(w, map[string]interface{}{"data": doc})
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
+// handleError maps domain errors to appropriate HTTP status codes and formats.
+func (h *Handler) handleError(w http.ResponseWriter, err error) {
+	errorMsg := err.Error()
+
+	if strings.Contains(errorMsg, "no existe") {
+		httperrors.WriteError(w, http.StatusNotFound, "Documento no encontrado", []string{errorMsg}, nil)
+		return
+	}
+
+	if strings.Contains(errorMsg, "es requerido") || strings.Contains(errorMsg, "debe ser") || strings.Contains(errorMsg, "inválido") {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{errorMsg}, nil)
+		return
+	}
+
+	httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+}
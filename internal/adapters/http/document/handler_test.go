@@ -0,0 +1,55 @@
+package document
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appdocument "3tcapital/goclonacion/internal/application/document"
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandler_Documents(t *testing.T) {
+	repo := testutil.NewMockDocumentRepository()
+	if _, err := repo.Save(context.Background(), document.Document{OfeIdentificacion: "860011153", Tipo: "FC", Consecutivo: "1", CUFE: "abc"}); err != nil {
+		t.Fatalf("seed ledger: %v", err)
+	}
+	handler := NewHandler(appdocument.NewService(repo))
+	router := chi.NewRouter()
+	router.Get("/api/v1/documentos", handler.ListDocuments)
+	router.Get("/api/v1/documentos/{ofe}/{tipo}/{prefijo}/{consecutivo}", handler.GetDocument)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"list", "/api/v1/documentos?ofe=860011153-6", http.StatusOK, `"total":1`},
+		{"list all", "/api/v1/documentos?length=-1", http.StatusOK, `"total":1`},
+		{"invalid length", "/api/v1/documentos?length=-2", http.StatusBadRequest, "length debe ser"},
+		{"invalid start", "/api/v1/documentos?start=-1", http.StatusBadRequest, "start debe ser"},
+		{"get with DV", "/api/v1/documentos/860011153-6/FC/-/1", http.StatusOK, `"cufe":"abc"`},
+		{"not found", "/api/v1/documentos/860011153/FC/-/2", http.StatusNotFound, "no existe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"strings"
 	"time"
 
-	appevent "3tcapital/ms_facturacion_core/internal/application/event"
-	"3tcapital/ms_facturacion_core/internal/core/event"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	httperrors "3tcapital/ms_facturacion_core/internal/infrastructure/http"
+	appevent "3tcapital/goclonacion/internal/application/event"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/invoice"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
 )
 
 // Handler bridges HTTP traffic with the event application service.
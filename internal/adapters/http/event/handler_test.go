@@ -10,10 +10,10 @@ import (
 	"testing"
 	"time"
 
-	appevent "3tcapital/ms_facturacion_core/internal/application/event"
-	"3tcapital/ms_facturacion_core/internal/core/event"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	appevent "3tcapital/goclonacion/internal/application/event"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 func TestNewHandler(t *testing.T) {
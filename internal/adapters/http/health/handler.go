@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
-	apphealth "3tcapital/ms_facturacion_core/internal/application/health"
+	apphealth "3tcapital/goclonacion/internal/application/health"
 )
 
 // Handler bridges HTTP traffic with the health application service.
@@ -6,8 +6,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
-	apphealth "3tcapital/ms_facturacion_core/internal/application/health"
-	corehealth "3tcapital/ms_facturacion_core/internal/core/health"
+	apphealth "3tcapital/goclonacion/internal/application/health"
+	corehealth "3tcapital/goclonacion/internal/core/health"
 )
 
 func TestNewHandler(t *testing.T) {
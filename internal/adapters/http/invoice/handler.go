@@ -2,6 +2,7 @@ package invoice
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -9,13 +10,17 @@ import (
 	"sync"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/adapters/invoice/numrot"
-	appinvoice "3tcapital/ms_facturacion_core/internal/application/invoice"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	ctxutil "3tcapital/ms_facturacion_core/internal/infrastructure/context"
-	httperrors "3tcapital/ms_facturacion_core/internal/infrastructure/http"
+	"3tcapital/goclonacion/internal/adapters/invoice/numrot"
+	appidempotency "3tcapital/goclonacion/internal/application/idempotency"
+	appinvoice "3tcapital/goclonacion/internal/application/invoice"
+	"3tcapital/goclonacion/internal/core/invoice"
+	ctxutil "3tcapital/goclonacion/internal/infrastructure/context"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
 )
 
+// idempotencyKeyHeader carries the client key that makes a whole registration request idempotent.
+const idempotencyKeyHeader = "Idempotency-Key"
+
 // Handler bridges HTTP traffic with the invoice application service.
 type Handler struct {
 	service      *appinvoice.Service
@@ -468,6 +473,15 @@ func (h *Handler) handleError(w http.ResponseWriter, r *http.Request, err error)
 		statusCode = http.StatusBadRequest
 		logLevel = "warn"
 		httperrors.WriteError(w, statusCode, "Error de Validación", []string{errorMsg}, nil)
+	case errors.Is(err, appidempotency.ErrKeyTooLong):
+		statusCode = http.StatusBadRequest
+		logLevel = "warn"
+		httperrors.WriteError(w, statusCode, "Error de Validación", []string{errorMsg}, nil)
+	// Idempotency-Key reused with another payload or still in progress
+	case errors.Is(err, appidempotency.ErrKeyReused) || errors.Is(err, appidempotency.ErrInProgress):
+		statusCode = http.StatusConflict
+		logLevel = "warn"
+		httperrors.WriteError(w, statusCode, "Conflicto de Idempotencia", []string{errorMsg}, nil)
 	// Document registration validation errors
 	case contains(errorMsg, "no documents provided") || contains(errorMsg, "only one document type") || contains(errorMsg, "is required") || contains(errorMsg, "invalid cdo_fecha format") || contains(errorMsg, "invalid cdo_hora format") || contains(errorMsg, "at least one item is required") || contains(errorMsg, "does not match document type") || contains(errorMsg, "FAD09e compliance") || contains(errorMsg, "must be today's date") || contains(errorMsg, "cdo_fecha must be today"):
 		statusCode = http.StatusBadRequest
@@ -478,6 +492,16 @@ func (h *Handler) handleError(w http.ResponseWriter, r *http.Request, err error)
 		statusCode = http.StatusBadRequest
 		logLevel = "warn"
 		httperrors.WriteError(w, statusCode, "Error de Validación", []string{errorMsg}, nil)
+	// Optional features not configured
+	case contains(errorMsg, "no está habilitada"):
+		statusCode = http.StatusServiceUnavailable
+		logLevel = "warn"
+		httperrors.WriteError(w, statusCode, "Servicio No Disponible", []string{errorMsg}, nil)
+	// Operations the configured provider does not offer
+	case contains(errorMsg, "operación no soportada"):
+		statusCode = http.StatusNotImplemented
+		logLevel = "warn"
+		httperrors.WriteError(w, statusCode, "Operación No Soportada", []string{errorMsg}, nil)
 	// Provider configuration errors
 	case contains(errorMsg, "key and secret are required"):
 		statusCode = http.StatusBadGateway
@@ -554,6 +578,17 @@ func (h *Handler) RegisterDocument(w http.ResponseWriter, r *http.Request) {
 
 	// Count total documents
 	totalDocs := len(reqBody.Documentos.FC) + len(reqBody.Documentos.NC) + len(reqBody.Documentos.ND) + len(reqBody.Documentos.DS)
+	if totalDocs == 0 {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"Debe enviar al menos un documento"}, nil)
+		return
+	}
+
+	// Requests with an Idempotency-Key are processed in a single service call, since the
+	// whole response is stored to be replayed on retries
+	if key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader)); key != "" {
+		h.registerDocumentWithKey(w, r, key, reqBody)
+		return
+	}
 
 	// Use streaming for all document registrations
 	// This enables partial processing (each document validated/processed individually)
@@ -562,7 +597,39 @@ func (h *Handler) RegisterDocument(w http.ResponseWriter, r *http.Request) {
 	h.registerDocumentStreaming(w, r, reqBody, totalDocs)
 }
 
-// registerDocumentStreaming handles massive document registration with streaming response.
+// registerDocumentWithKey registers all documents of the request at most once per
+// Idempotency-Key and writes the consolidated response, as the non-streaming fallback does.
+func (h *Handler) registerDocumentWithKey(w http.ResponseWriter, r *http.Request, key string, reqBody invoice.DocumentRegistrationRequest) {
+	response, replayed, err := h.service.RegisterDocumentWithKey(r.Context(), key, reqBody)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+
+	if response.Message == "" {
+		response.Message = h.generateSummaryMessage(len(response.DocumentosProcesados), len(response.DocumentosFallidos))
+	}
+
+	statusCode := http.StatusOK
+	if len(response.DocumentosFallidos) > 0 {
+		statusCode = http.StatusBadRequest
+		h.log.Warn("Documents failed during registration",
+			"failed_count", len(response.DocumentosFallidos),
+			"processed_count", len(response.DocumentosProcesados))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("Failed to encode response", "error", err)
+	}
+}
+
+// registerDocumentStreaming handles document registration with streaming response.
 // This prevents timeouts by sending results progressively as documents are processed.
 func (h *Handler) registerDocumentStreaming(w http.ResponseWriter, r *http.Request, reqBody invoice.DocumentRegistrationRequest, totalDocs int) {
 	// Check if response writer supports flushing
@@ -876,7 +943,7 @@ func (h *Handler) registerDocumentStreaming(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Close JSON array and add summary
-	w.Write([]byte("\n  ],\n  \"summary\": {\n"))
+	w.Write([]byte("\n  ],\n  \"summary\": "))
 
 	// Generate lote if not set
 	if firstLote == "" {
@@ -891,12 +958,12 @@ func (h *Handler) registerDocumentStreaming(w http.ResponseWriter, r *http.Reque
 		"message":   h.generateSummaryMessage(processedCount, failedCount),
 	}
 
-	summaryJSON, _ := json.MarshalIndent(summary, "    ", "  ")
+	summaryJSON, _ := json.MarshalIndent(summary, "  ", "  ")
 	if _, err := w.Write(summaryJSON); err != nil {
 		h.log.Warn("Failed to write summary - connection may be closed", "error", err)
 		return
 	}
-	if _, err := w.Write([]byte("\n  }\n}")); err != nil {
+	if _, err := w.Write([]byte("\n}")); err != nil {
 		h.log.Warn("Failed to write closing JSON - connection may be closed", "error", err)
 		return
 	}
@@ -921,3 +988,66 @@ func (h *Handler) generateSummaryMessage(processed, failed int) string {
 	}
 	return "Procesamiento completado"
 }
+
+// PreviewDocuments handles POST /api/v1/documentos/previsualizar-xml requests.
+// It returns the UBL XML that would be generated for the documents without sending them.
+// A single rendered document is returned as raw XML when the client accepts application/xml.
+func (h *Handler) PreviewDocuments(w http.ResponseWriter, r *http.Request) {
+	var reqBody invoice.DocumentRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+
+	response, err := h.service.PreviewDocuments(r.Context(), reqBody)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/xml") && len(response.Documentos) == 1 && len(response.DocumentosFallidos) == 0 {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(response.Documentos[0].XML))
+		return
+	}
+
+	statusCode := http.StatusOK
+	if len(response.DocumentosFallidos) > 0 {
+		statusCode = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("Failed to encode response", "error", err)
+	}
+}
+
+// ValidateDocuments handles POST /api/v1/documentos/validar requests.
+// It validates and enriches the documents as registration does, without sending them, and
+// returns every violation of each document.
+func (h *Handler) ValidateDocuments(w http.ResponseWriter, r *http.Request) {
+	var reqBody invoice.DocumentRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+
+	response, err := h.service.ValidateDocuments(r.Context(), reqBody)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	statusCode := http.StatusOK
+	if response.Invalidos > 0 {
+		statusCode = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("Failed to encode response", "error", err)
+	}
+}
@@ -5,14 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
-	appinvoice "3tcapital/ms_facturacion_core/internal/application/invoice"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	appidempotency "3tcapital/goclonacion/internal/application/idempotency"
+	appinvoice "3tcapital/goclonacion/internal/application/invoice"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 // getTodayDate returns today's date in Colombia timezone (UTC-5) formatted as YYYY-MM-DD
@@ -289,6 +292,12 @@ func TestHandler_handleError(t *testing.T) {
 			expectedStatus: http.StatusBadGateway,
 			expectedMsg:    "Error del Proveedor",
 		},
+		{
+			name:           "operation not supported by provider",
+			err:            errors.New("operación no soportada por el proveedor dian: consulta de documentos emitidos por rango de fechas"),
+			expectedStatus: http.StatusNotImplemented,
+			expectedMsg:    "Operación No Soportada",
+		},
 		{
 			name:           "FAD09e compliance error",
 			err:            errors.New("document 1: cdo_fecha must be today's date (2025-12-17) for DIAN FAD09e compliance. Provided: 2025-12-16"),
@@ -307,6 +316,18 @@ func TestHandler_handleError(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedMsg:    "Error de Validación",
 		},
+		{
+			name:           "idempotency key in progress",
+			err:            appidempotency.ErrInProgress,
+			expectedStatus: http.StatusConflict,
+			expectedMsg:    "Conflicto de Idempotencia",
+		},
+		{
+			name:           "idempotency key too long",
+			err:            appidempotency.ErrKeyTooLong,
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "Error de Validación",
+		},
 		{
 			name:           "unknown error",
 			err:            errors.New("unknown error"),
@@ -683,11 +704,15 @@ func TestHandler_RegisterDocument(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody: func(t *testing.T, body map[string]interface{}) {
-				if body["message"] != "Documentos procesados exitosamente" {
-					t.Errorf("expected message 'Documentos procesados exitosamente', got %v", body["message"])
+				results, summary := streamedBody(t, body)
+				if len(results) != 1 || results[0]["status"] != "processed" {
+					t.Errorf("expected 1 processed result, got %v", results)
+				}
+				if summary["message"] != "Documentos procesados exitosamente" {
+					t.Errorf("expected message 'Documentos procesados exitosamente', got %v", summary["message"])
 				}
-				if body["lote"] != "lote-20240115-103000" {
-					t.Errorf("expected lote 'lote-20240115-103000', got %v", body["lote"])
+				if summary["lote"] != "lote-20240115-103000" {
+					t.Errorf("expected lote 'lote-20240115-103000', got %v", summary["lote"])
 				}
 			},
 		},
@@ -724,7 +749,8 @@ func TestHandler_RegisterDocument(t *testing.T) {
 			setupService: func() *appinvoice.Service {
 				return appinvoice.NewService(&testutil.MockProvider{}, nil, nil, "2")
 			},
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusOK,
+			expectedBody:   expectStreamedFailure("cdo_consecutivo es requerido"),
 		},
 		{
 			name:   "provider error - authentication failed",
@@ -774,7 +800,8 @@ func TestHandler_RegisterDocument(t *testing.T) {
 				}
 				return appinvoice.NewService(provider, nil, nil, "2")
 			},
-			expectedStatus: http.StatusBadGateway,
+			expectedStatus: http.StatusOK,
+			expectedBody:   expectStreamedFailure("authentication failed"),
 		},
 		{
 			name:   "FAD09e validation error - wrong date",
@@ -819,12 +846,8 @@ func TestHandler_RegisterDocument(t *testing.T) {
 			setupService: func() *appinvoice.Service {
 				return appinvoice.NewService(&testutil.MockProvider{}, nil, nil, "2")
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody: func(t *testing.T, body map[string]interface{}) {
-				if body["message"] != "Error de Validación" {
-					t.Errorf("expected message 'Error de Validación', got %v", body["message"])
-				}
-			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   expectStreamedFailure("FAD09e"),
 		},
 		{
 			name:   "response with failed documents",
@@ -888,12 +911,8 @@ func TestHandler_RegisterDocument(t *testing.T) {
 				}
 				return appinvoice.NewService(provider, nil, nil, "2")
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody: func(t *testing.T, body map[string]interface{}) {
-				if body["message"] != "Error de Validación" {
-					t.Errorf("expected message 'Error de Validación', got %v", body["message"])
-				}
-			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   expectStreamedFailure("Regla FAJ24"),
 		},
 		{
 			name:   "response with empty message but processed documents",
@@ -959,8 +978,9 @@ func TestHandler_RegisterDocument(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedBody: func(t *testing.T, body map[string]interface{}) {
 				// Should have default message
-				if body["message"] != "Documentos procesados exitosamente" {
-					t.Errorf("expected message 'Documentos procesados exitosamente', got %v", body["message"])
+				_, summary := streamedBody(t, body)
+				if summary["message"] != "Documentos procesados exitosamente" {
+					t.Errorf("expected message 'Documentos procesados exitosamente', got %v", summary["message"])
 				}
 			},
 		},
@@ -1003,3 +1023,337 @@ func TestHandler_RegisterDocument(t *testing.T) {
 		})
 	}
 }
+
+// streamedBody returns the results and summary of a streamed registration response.
+func streamedBody(t *testing.T, body map[string]interface{}) ([]map[string]interface{}, map[string]interface{}) {
+	t.Helper()
+	if body["streaming"] != true {
+		t.Fatalf("expected a streamed response, got %v", body)
+	}
+	rawResults, _ := body["results"].([]interface{})
+	results := make([]map[string]interface{}, 0, len(rawResults))
+	for _, raw := range rawResults {
+		result, _ := raw.(map[string]interface{})
+		results = append(results, result)
+	}
+	summary, _ := body["summary"].(map[string]interface{})
+	if summary == nil {
+		t.Fatalf("expected a summary, got %v", body)
+	}
+	return results, summary
+}
+
+// expectStreamedFailure checks that the single streamed document failed with an error containing substr.
+func expectStreamedFailure(substr string) func(t *testing.T, body map[string]interface{}) {
+	return func(t *testing.T, body map[string]interface{}) {
+		results, summary := streamedBody(t, body)
+		if len(results) != 1 || results[0]["status"] != "failed" {
+			t.Fatalf("expected 1 failed result, got %v", results)
+		}
+		if !strings.Contains(fmt.Sprint(results[0]["error"], results[0]["errors"]), substr) {
+			t.Errorf("expected error to contain %q, got %v", substr, results[0])
+		}
+		if summary["failed"] != float64(1) || summary["processed"] != float64(0) {
+			t.Errorf("expected 1 failed and 0 processed documents, got %v", summary)
+		}
+	}
+}
+
+// nonFlushingWriter hides the http.Flusher implementation of the wrapped recorder.
+type nonFlushingWriter struct {
+	http.ResponseWriter
+}
+
+func newRegistrationTestDocument(consecutivo, fecha string) invoice.OpenETLDocument {
+	return invoice.OpenETLDocument{
+		TdeCodigo:            "01",
+		OfeIdentificacion:    "860011153",
+		AdqIdentificacion:    "900123456",
+		RfaPrefijo:           "SETT",
+		RfaResolucion:        "18760000001",
+		CdoConsecutivo:       consecutivo,
+		CdoFecha:             fecha,
+		CdoHora:              "14:37:00",
+		MonCodigo:            "COP",
+		CdoValorSinImpuestos: "100000.00",
+		CdoImpuestos:         "19000.00",
+		CdoTotal:             "119000.00",
+		Items: []invoice.OpenETLItem{
+			{DdoSecuencia: "1", DdoDescripcionUno: "Producto", DdoCantidad: "1", DdoValorUnitario: "100000.00", DdoTotal: "100000.00"},
+		},
+	}
+}
+
+func newRegistrationTestService(calls *int) *appinvoice.Service {
+	provider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			*calls++
+			doc := req.Documentos.FC[0]
+			return &invoice.DocumentRegistrationResponse{
+				Lote: "lote-20240115-103000",
+				DocumentosProcesados: []invoice.ProcessedDocument{
+					{CdoID: 1, RfaPrefijo: doc.RfaPrefijo, CdoConsecutivo: doc.CdoConsecutivo},
+				},
+			}, nil
+		},
+	}
+	return appinvoice.NewService(provider, nil, nil, "2")
+}
+
+func TestHandler_RegisterDocument_Modes(t *testing.T) {
+	body, err := json.Marshal(invoice.DocumentRegistrationRequest{Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{
+		newRegistrationTestDocument("5604", getTodayDate()),
+		newRegistrationTestDocument("5605", "2020-01-01"),
+	}}})
+	if err != nil {
+		t.Fatalf("failed to marshal body: %v", err)
+	}
+
+	t.Run("streaming reports each document", func(t *testing.T) {
+		calls := 0
+		handler := NewHandler(newRegistrationTestService(&calls), nil, testutil.NewNullLogger())
+		w := httptest.NewRecorder()
+
+		handler.RegisterDocument(w, httptest.NewRequest(http.MethodPost, "/api/v1/registrar-documentos", bytes.NewReader(body)))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var response map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		results, summary := streamedBody(t, response)
+		if len(results) != 2 || results[0]["status"] != "processed" || results[1]["status"] != "failed" {
+			t.Errorf("expected the first document processed and the second failed, got %v", results)
+		}
+		if summary["processed"] != float64(1) || summary["failed"] != float64(1) {
+			t.Errorf("expected 1 processed and 1 failed document, got %v", summary)
+		}
+		if calls != 1 {
+			t.Errorf("expected only the valid document to reach the provider, got %d calls", calls)
+		}
+	})
+
+	t.Run("fallback without flushing", func(t *testing.T) {
+		calls := 0
+		handler := NewHandler(newRegistrationTestService(&calls), nil, testutil.NewNullLogger())
+		w := httptest.NewRecorder()
+
+		handler.RegisterDocument(nonFlushingWriter{w}, httptest.NewRequest(http.MethodPost, "/api/v1/registrar-documentos", bytes.NewReader(body)))
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+		var response invoice.DocumentRegistrationResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(response.DocumentosProcesados) != 1 || len(response.DocumentosFallidos) != 1 {
+			t.Errorf("expected 1 processed and 1 failed document, got %+v", response)
+		}
+	})
+
+	t.Run("idempotency key replays the consolidated response", func(t *testing.T) {
+		calls := 0
+		service := newRegistrationTestService(&calls)
+		service.WithIdempotency(testutil.NewMockIdempotencyRepository())
+		handler := NewHandler(service, nil, testutil.NewNullLogger())
+		single, err := json.Marshal(invoice.DocumentRegistrationRequest{Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{
+			newRegistrationTestDocument("5604", getTodayDate()),
+		}}})
+		if err != nil {
+			t.Fatalf("failed to marshal body: %v", err)
+		}
+
+		for i, replayed := range []string{"", "true"} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/registrar-documentos", bytes.NewReader(single))
+			req.Header.Set(idempotencyKeyHeader, "key-1")
+			w := httptest.NewRecorder()
+
+			handler.RegisterDocument(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("Idempotent-Replayed"); got != replayed {
+				t.Errorf("request %d: expected Idempotent-Replayed %q, got %q", i, replayed, got)
+			}
+			var response invoice.DocumentRegistrationResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Message != "Documentos procesados exitosamente" || len(response.DocumentosProcesados) != 1 {
+				t.Errorf("request %d: expected consolidated response, got %+v", i, response)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("expected provider to be called once, got %d", calls)
+		}
+	})
+}
+
+type stubRenderer struct{}
+
+func (stubRenderer) Render(doc invoice.OpenETLDocument, documentType string) ([]byte, string, error) {
+	return []byte("<Invoice><ID>" + doc.RfaPrefijo + doc.CdoConsecutivo + "</ID></Invoice>"), "cufe", nil
+}
+
+func TestHandler_PreviewDocuments(t *testing.T) {
+	doc := invoice.OpenETLDocument{
+		TdeCodigo:            "01",
+		OfeIdentificacion:    "860011153",
+		AdqIdentificacion:    "900123456",
+		RfaPrefijo:           "SETT",
+		RfaResolucion:        "18760000001",
+		CdoConsecutivo:       "5604",
+		CdoFecha:             getTodayDate(),
+		CdoHora:              "14:37:00",
+		MonCodigo:            "COP",
+		CdoValorSinImpuestos: "100000.00",
+		CdoImpuestos:         "19000.00",
+		CdoTotal:             "119000.00",
+		Items: []invoice.OpenETLItem{
+			{DdoSecuencia: "1", DdoDescripcionUno: "Producto", DdoCantidad: "1", DdoValorUnitario: "100000.00", DdoTotal: "100000.00"},
+		},
+	}
+	body, err := json.Marshal(invoice.DocumentRegistrationRequest{Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{doc}}})
+	if err != nil {
+		t.Fatalf("failed to marshal body: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		service        *appinvoice.Service
+		accept         string
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+	}{
+		{
+			name:           "JSON response",
+			service:        appinvoice.NewService(&testutil.MockProvider{}, nil, nil, "2").WithRenderer(stubRenderer{}),
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/json",
+			expectedBody:   `"cdo_consecutivo":"5604","cufe":"cufe"`,
+		},
+		{
+			name:           "raw XML response",
+			service:        appinvoice.NewService(&testutil.MockProvider{}, nil, nil, "2").WithRenderer(stubRenderer{}),
+			accept:         "application/xml",
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/xml; charset=utf-8",
+			expectedBody:   "<Invoice><ID>SETT5604</ID></Invoice>",
+		},
+		{
+			name:           "renderer not configured",
+			service:        appinvoice.NewService(&testutil.MockProvider{}, nil, nil, "2"),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(tt.service, nil, testutil.NewNullLogger())
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/documentos/previsualizar-xml", bytes.NewReader(body))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			handler.PreviewDocuments(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedType != "" && w.Header().Get("Content-Type") != tt.expectedType {
+				t.Errorf("expected content type %q, got %q", tt.expectedType, w.Header().Get("Content-Type"))
+			}
+			if tt.expectedBody != "" && !bytes.Contains(w.Body.Bytes(), []byte(tt.expectedBody)) {
+				t.Errorf("expected body to contain %s, got %s", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_ValidateDocuments(t *testing.T) {
+	valid := invoice.OpenETLDocument{
+		TdeCodigo:            "01",
+		OfeIdentificacion:    "860011153",
+		AdqIdentificacion:    "900123456",
+		RfaPrefijo:           "SETT",
+		RfaResolucion:        "18760000001",
+		CdoConsecutivo:       "5604",
+		CdoFecha:             getTodayDate(),
+		CdoHora:              "14:37:00",
+		MonCodigo:            "COP",
+		CdoValorSinImpuestos: "100000.00",
+		CdoImpuestos:         "19000.00",
+		CdoTotal:             "119000.00",
+		Items: []invoice.OpenETLItem{
+			{DdoSecuencia: "1", DdoDescripcionUno: "Producto", DdoCantidad: "1", DdoValorUnitario: "100000.00", DdoTotal: "100000.00"},
+		},
+	}
+	invalid := valid
+	invalid.CdoConsecutivo = "5605"
+	invalid.CdoFecha = "2020-01-01"
+
+	tests := []struct {
+		name           string
+		documents      []invoice.OpenETLDocument
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "valid documents",
+			documents:      []invoice.OpenETLDocument{valid},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"validos":1,"invalidos":0`,
+		},
+		{
+			name:           "invalid document",
+			documents:      []invoice.OpenETLDocument{valid, invalid},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"regla":"FAD09e","severidad":"RECHAZO"`,
+		},
+		{
+			name:           "invalid body",
+			body:           "{",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(tt.body)
+			if tt.documents != nil {
+				var err error
+				body, err = json.Marshal(invoice.DocumentRegistrationRequest{Documentos: invoice.DocumentsByType{FC: tt.documents}})
+				if err != nil {
+					t.Fatalf("failed to marshal body: %v", err)
+				}
+			}
+			mockProvider := &testutil.MockProvider{
+				RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+					t.Error("validation must not send documents")
+					return nil, errors.New("unexpected call")
+				},
+			}
+			handler := NewHandler(appinvoice.NewService(mockProvider, nil, nil, "2"), nil, testutil.NewNullLogger())
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/documentos/validar", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.ValidateDocuments(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedBody != "" && !bytes.Contains(w.Body.Bytes(), []byte(tt.expectedBody)) {
+				t.Errorf("expected body to contain %s, got %s", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package ofe
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	appofe "3tcapital/goclonacion/internal/application/ofe"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler bridges HTTP traffic with the OFE application service.
+type Handler struct {
+	service *appofe.Service
+}
+
+// NewHandler creates a new OFE HTTP handler.
+func NewHandler(service *appofe.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// ListOFEs handles GET /api/v1/ofes requests with pagination and search.
+func (h *Handler) ListOFEs(w http.ResponseWriter, r *http.Request) {
+	start := 0
+	if startStr := r.URL.Query().Get("start"); startStr != "" {
+		var err error
+		start, err = strconv.Atoi(startStr)
+		if err != nil || start < 0 {
+			httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"start debe ser un número entero no negativo"}, nil)
+			return
+		}
+	}
+
+	length := 10 // Default
+	if lengthStr := r.URL.Query().Get("length"); lengthStr != "" {
+		var err error
+		length, err = strconv.Atoi(lengthStr)
+		if err != nil {
+			httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"length debe ser un número entero (-1 para traer todos)"}, nil)
+			return
+		}
+	}
+
+	response, err := h.service.ListOFEs(r.Context(), start, length, r.URL.Query().Get("buscar"))
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+// GetOFE handles GET /api/v1/ofes/{ofeIdentificacion} requests.
+func (h *Handler) GetOFE(w http.ResponseWriter, r *http.Request) {
+	o, err := h.service.GetOFE(r.Context(), chi.URLParam(r, "ofeIdentificacion"))
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, o)
+}
+
+// CreateOFE handles POST /api/v1/ofes requests.
+func (h *Handler) CreateOFE(w http.ResponseWriter, r *http.Request) {
+	var reqBody appofe.CreateOFERequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+
+	response, err := h.service.CreateOFE(r.Context(), reqBody)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+// UpdateOFE handles PUT /api/v1/ofes/{ofeIdentificacion} requests.
+func (h *Handler) UpdateOFE(w http.ResponseWriter, r *http.Request) {
+	ofeIdentificacion := chi.URLParam(r, "ofeIdentificacion")
+	if ofeIdentificacion == "" {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"ofeIdentificacion es requerido en la URL"}, nil)
+		return
+	}
+
+	var reqBody appofe.UpdateOFERequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+
+	if err := h.service.UpdateOFE(r.Context(), ofeIdentificacion, reqBody); err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"success": true})
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
+// handleError maps domain errors to appropriate HTTP status codes and formats.
+func (h *Handler) handleError(w http.ResponseWriter, err error) {
+	errorMsg := err.Error()
+
+	switch {
+	case strings.Contains(errorMsg, "NUMROT_CREDENTIALS_KEY no configurada"):
+		httperrors.WriteError(w, http.StatusServiceUnavailable, "Servicio No Disponible", []string{errorMsg}, nil)
+	case strings.Contains(errorMsg, "ya existe"):
+		httperrors.WriteError(w, http.StatusConflict, "Errores al crear el OFE", []string{errorMsg}, nil)
+	case strings.Contains(errorMsg, "no existe"):
+		httperrors.WriteError(w, http.StatusNotFound, "OFE No Encontrado", []string{errorMsg}, nil)
+	case strings.Contains(errorMsg, "es requerido") || strings.Contains(errorMsg, "debe ser") ||
+		strings.Contains(errorMsg, "inválido") || strings.Contains(errorMsg, "excede") ||
+		strings.Contains(errorMsg, "no pertenece") || strings.Contains(errorMsg, "deben ser enviados"):
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{errorMsg}, nil)
+	default:
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
@@ -6,8 +6,8 @@ import (
 	"strconv"
 	"strings"
 
-	appprovider "3tcapital/ms_facturacion_core/internal/application/provider"
-	httperrors "3tcapital/ms_facturacion_core/internal/infrastructure/http"
+	appprovider "3tcapital/goclonacion/internal/application/provider"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
 
 	"github.com/go-chi/chi/v5"
 )
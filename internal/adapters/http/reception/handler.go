@@ -9,10 +9,10 @@ import (
 	"strings"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/adapters/invoice/numrot"
-	"3tcapital/ms_facturacion_core/internal/core/event"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	httperrors "3tcapital/ms_facturacion_core/internal/infrastructure/http"
+	"3tcapital/goclonacion/internal/adapters/invoice/numrot"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/invoice"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
 )
 
 // Handler provides legacy reception endpoints.
@@ -7,7 +7,7 @@ import (
 	"net/http"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/adapters/invoice/numrot"
+	"3tcapital/goclonacion/internal/adapters/invoice/numrot"
 )
 
 // mapDocumentTypeIDToClasificacion mapea DocumentTypeId de Numrot a cdo_clasificacion
@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"strings"
 
-	appresolution "3tcapital/ms_facturacion_core/internal/application/resolution"
-	httperrors "3tcapital/ms_facturacion_core/internal/infrastructure/http"
+	appresolution "3tcapital/goclonacion/internal/application/resolution"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+
+	"github.com/go-chi/chi/v5"
 )
 
 // Handler bridges HTTP traffic with the resolution application service.
@@ -50,12 +52,73 @@ func (h *Handler) GetResolutions(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ListRanges handles GET /api/v1/resoluciones/{ofeIdentificacion} requests.
+// It returns the numbering ranges of the OFE with the consecutivos used.
+func (h *Handler) ListRanges(w http.ResponseWriter, r *http.Request) {
+	ranges, err := h.service.Ranges(r.Context(), chi.URLParam(r, "ofeIdentificacion"))
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"resoluciones": ranges})
+}
+
+// allocateRequest is the body of the consecutive allocation endpoint.
+type allocateRequest struct {
+	RfaPrefijo string `json:"rfa_prefijo"`
+}
+
+// AllocateConsecutivo handles POST /api/v1/resoluciones/{ofeIdentificacion}/consecutivos requests.
+// It hands out the next free consecutivo of the OFE for the prefix.
+func (h *Handler) AllocateConsecutivo(w http.ResponseWriter, r *http.Request) {
+	var reqBody allocateRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+
+	allocation, err := h.service.Allocate(r.Context(), chi.URLParam(r, "ofeIdentificacion"), reqBody.RfaPrefijo)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, allocation)
+}
+
+// GetAlerts handles GET /api/v1/resoluciones/alertas requests.
+// The optional ofe query parameter restricts the alerts to one OFE.
+func (h *Handler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	alerts, err := h.service.Alerts(r.Context(), r.URL.Query().Get("ofe"))
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"alertas": alerts})
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
 // handleError maps domain errors to appropriate HTTP status codes.
 func (h *Handler) handleError(w http.ResponseWriter, err error) {
 	// Check error type and return appropriate status
 	errorMsg := err.Error()
 
 	switch {
+	case contains(errorMsg, "no está habilitado"):
+		httperrors.WriteError(w, http.StatusServiceUnavailable, "Servicio No Disponible", []string{errorMsg}, nil)
+	case contains(errorMsg, "no hay una resolución vigente"):
+		httperrors.WriteError(w, http.StatusConflict, "Error de Numeración", []string{errorMsg}, nil)
+	case contains(errorMsg, "no encontrada"):
+		httperrors.WriteError(w, http.StatusNotFound, "Resolución No Encontrada", []string{errorMsg}, nil)
 	case contains(errorMsg, "nit is required"):
 		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El parámetro NIT es requerido"}, nil)
 	case contains(errorMsg, "invalid nit format"):
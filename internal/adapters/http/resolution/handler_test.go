@@ -6,11 +6,15 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
-	appresolution "3tcapital/ms_facturacion_core/internal/application/resolution"
-	"3tcapital/ms_facturacion_core/internal/core/resolution"
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	appresolution "3tcapital/goclonacion/internal/application/resolution"
+	"3tcapital/goclonacion/internal/core/resolution"
+	"3tcapital/goclonacion/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
 )
 
 func TestNewHandler(t *testing.T) {
@@ -253,6 +257,60 @@ func TestHandler_handleError(t *testing.T) {
 	}
 }
 
+func TestHandler_AllocateConsecutivo(t *testing.T) {
+	mockProvider := &testutil.MockProvider{
+		GetResolutionsFunc: func(ctx context.Context, nit string) ([]resolution.Resolution, error) {
+			return []resolution.Resolution{{
+				ResolutionNumber: "18760000001",
+				Prefix:           "SETT",
+				FromNumber:       1,
+				ToNumber:         1,
+				ValidDateFrom:    time.Now().AddDate(0, -1, 0),
+				ValidDateTo:      time.Now().AddDate(1, 0, 0),
+			}}, nil
+		},
+	}
+	service := appresolution.NewService(mockProvider).WithRepository(testutil.NewMockResolutionRepository(), testutil.NewTestLogger())
+	handler := NewHandler(service, "860011153")
+
+	allocate := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/resoluciones/860011153/consecutivos", strings.NewReader(`{"rfa_prefijo":"SETT"}`))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("ofeIdentificacion", "860011153")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.AllocateConsecutivo(w, req)
+		return w
+	}
+
+	w := allocate()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var allocation resolution.Allocation
+	if err := json.NewDecoder(w.Body).Decode(&allocation); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if allocation.Consecutivo != 1 || allocation.Range.Prefix != "SETT" {
+		t.Errorf("unexpected allocation: %+v", allocation)
+	}
+
+	// The range has a single number
+	if w := allocate(); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for an exhausted range, got %d", w.Code)
+	}
+}
+
+func TestHandler_GetAlerts_TrackingDisabled(t *testing.T) {
+	handler := NewHandler(appresolution.NewService(&testutil.MockProvider{}), "860011153")
+
+	w := httptest.NewRecorder()
+	handler.GetAlerts(w, httptest.NewRequest(http.MethodGet, "/api/v1/resoluciones/alertas", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
+
 func TestContains(t *testing.T) {
 	tests := []struct {
 		name     string
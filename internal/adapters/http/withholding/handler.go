@@ -0,0 +1,163 @@
+package withholding
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	appwithholding "3tcapital/goclonacion/internal/application/withholding"
+	"3tcapital/goclonacion/internal/core/withholding"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler bridges HTTP traffic with the withholding application service.
+type Handler struct {
+	service *appwithholding.Service
+}
+
+// NewHandler creates a new withholding HTTP handler.
+func NewHandler(service *appwithholding.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// setUVTRequest is the body of PUT /api/v1/retenciones/uvt/{anio}.
+type setUVTRequest struct {
+	Valor string `json:"valor"`
+}
+
+// ListRules handles GET /api/v1/retenciones/reglas requests.
+func (h *Handler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.ListRules(r.Context())
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, rules)
+}
+
+// CreateRule handles POST /api/v1/retenciones/reglas requests.
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var rule withholding.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+	rule.ID = 0
+
+	id, err := h.service.SaveRule(r.Context(), rule)
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"success": true, "id": id})
+}
+
+// UpdateRule handles PUT /api/v1/retenciones/reglas/{id} requests.
+func (h *Handler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	var rule withholding.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+	rule.ID = id
+
+	if _, err := h.service.SaveRule(r.Context(), rule); err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"success": true})
+}
+
+// DeleteRule handles DELETE /api/v1/retenciones/reglas/{id} requests.
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRule(r.Context(), id); err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"success": true})
+}
+
+// ListUVT handles GET /api/v1/retenciones/uvt requests.
+func (h *Handler) ListUVT(w http.ResponseWriter, r *http.Request) {
+	values, err := h.service.ListUVT(r.Context())
+	if err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, values)
+}
+
+// SetUVT handles PUT /api/v1/retenciones/uvt/{anio} requests.
+func (h *Handler) SetUVT(w http.ResponseWriter, r *http.Request) {
+	anio, err := strconv.Atoi(chi.URLParam(r, "anio"))
+	if err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"anio debe ser un número entero"}, nil)
+		return
+	}
+
+	var reqBody setUVTRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"El cuerpo de la petición no es válido"}, nil)
+		return
+	}
+
+	if err := h.service.SetUVT(r.Context(), anio, reqBody.Valor); err != nil {
+		h.handleError(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"success": true})
+}
+
+// ruleID parses the {id} URL parameter, writing a validation error when it is invalid.
+func ruleID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{"id debe ser un número entero positivo"}, nil)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
+
+// handleError maps domain errors to appropriate HTTP status codes and formats.
+func (h *Handler) handleError(w http.ResponseWriter, err error) {
+	errorMsg := err.Error()
+
+	switch {
+	case strings.Contains(errorMsg, "no existe"):
+		httperrors.WriteError(w, http.StatusNotFound, "Regla No Encontrada", []string{errorMsg}, nil)
+	case strings.Contains(errorMsg, "es requerido") || strings.Contains(errorMsg, "debe ser") ||
+		strings.Contains(errorMsg, "inválido"):
+		httperrors.WriteError(w, http.StatusBadRequest, "Error de Validación", []string{errorMsg}, nil)
+	default:
+		httperrors.WriteError(w, http.StatusInternalServerError, "Error Interno del Servidor", []string{"Ha ocurrido un error interno"}, nil)
+	}
+}
@@ -0,0 +1,69 @@
+package withholding
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appwithholding "3tcapital/goclonacion/internal/application/withholding"
+	"3tcapital/goclonacion/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newTestRouter() (http.Handler, *testutil.MockWithholdingRepository) {
+	repo := testutil.NewMockWithholdingRepository()
+	handler := NewHandler(appwithholding.NewService(repo))
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/retenciones/reglas", handler.ListRules)
+	r.Post("/api/v1/retenciones/reglas", handler.CreateRule)
+	r.Put("/api/v1/retenciones/reglas/{id}", handler.UpdateRule)
+	r.Delete("/api/v1/retenciones/reglas/{id}", handler.DeleteRule)
+	r.Put("/api/v1/retenciones/uvt/{anio}", handler.SetUVT)
+	return r, repo
+}
+
+func TestHandler_Rules(t *testing.T) {
+	router, repo := newTestRouter()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"create", http.MethodPost, "/api/v1/retenciones/reglas", `{"tipo":"RETEIVA","porcentaje":"15","base_minima_uvt":"4","razon":"Retención de IVA","activo":true}`, http.StatusOK, `"id":1`},
+		{"invalid type", http.MethodPost, "/api/v1/retenciones/reglas", `{"tipo":"RETECREE","porcentaje":"15","razon":"x"}`, http.StatusBadRequest, "tipo [RETECREE] inválido"},
+		{"invalid body", http.MethodPost, "/api/v1/retenciones/reglas", `{`, http.StatusBadRequest, "no es válido"},
+		{"update missing", http.MethodPut, "/api/v1/retenciones/reglas/9", `{"tipo":"RETEIVA","porcentaje":"15","razon":"x"}`, http.StatusNotFound, "no existe"},
+		{"invalid id", http.MethodDelete, "/api/v1/retenciones/reglas/abc", "", http.StatusBadRequest, "id debe ser"},
+		{"list", http.MethodGet, "/api/v1/retenciones/reglas", "", http.StatusOK, `"tipo":"RETEIVA"`},
+		{"delete", http.MethodDelete, "/api/v1/retenciones/reglas/1", "", http.StatusOK, `"success":true`},
+		{"set uvt", http.MethodPut, "/api/v1/retenciones/uvt/2026", `{"valor":"52374"}`, http.StatusOK, `"success":true`},
+		{"invalid uvt", http.MethodPut, "/api/v1/retenciones/uvt/2026", `{"valor":"0"}`, http.StatusBadRequest, "valor debe ser"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+
+	if uvt, _ := repo.FindUVT(context.Background(), 2026); uvt == nil || uvt.Valor != "52374.00" {
+		t.Errorf("expected UVT to be stored, got %+v", uvt)
+	}
+}
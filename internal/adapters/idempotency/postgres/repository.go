@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/idempotency"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository implements the idempotency.Repository interface using PostgreSQL.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new PostgreSQL idempotency repository.
+func NewRepository(pool *pgxpool.Pool) idempotency.Repository {
+	return &Repository{pool: pool}
+}
+
+// Reserve creates an in-progress record for the key if none exists, or takes over an
+// in-progress record of the same request whose lease expired.
+func (r *Repository) Reserve(ctx context.Context, scope, key, requestHash string, lease time.Duration) (*idempotency.Record, bool, error) {
+	insert := `
+		INSERT INTO idempotency_key (scope, idempotency_key, request_hash, estado, lease_until)
+		VALUES ($1, $2, $3, 'in_progress', NOW() + make_interval(secs => $4))
+		ON CONFLICT (scope, idempotency_key) DO UPDATE SET
+			lease_until = EXCLUDED.lease_until,
+			created_at = NOW()
+		WHERE idempotency_key.estado = 'in_progress'
+			AND idempotency_key.request_hash = EXCLUDED.request_hash
+			AND (idempotency_key.lease_until IS NULL OR idempotency_key.lease_until < NOW())
+	`
+
+	result, err := r.pool.Exec(ctx, insert, scope, key, requestHash, lease.Seconds())
+	if err != nil {
+		return nil, false, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+	if result.RowsAffected() == 1 {
+		return nil, true, nil
+	}
+
+	query := `
+		SELECT scope, idempotency_key, request_hash, estado, response, created_at, lease_until
+		FROM idempotency_key
+		WHERE scope = $1 AND idempotency_key = $2
+	`
+
+	var record idempotency.Record
+	var estado string
+	var response []byte
+	var leaseUntil *time.Time
+	err = r.pool.QueryRow(ctx, query, scope, key).Scan(
+		&record.Scope,
+		&record.Key,
+		&record.RequestHash,
+		&estado,
+		&response,
+		&record.CreatedAt,
+		&leaseUntil,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("query idempotency key: %w", err)
+	}
+
+	record.Status = idempotency.Status(estado)
+	if leaseUntil != nil {
+		record.LeaseUntil = *leaseUntil
+	}
+	if len(response) > 0 {
+		record.Response = response
+	}
+
+	return &record, false, nil
+}
+
+// Extend renews the lease of an in-progress record.
+func (r *Repository) Extend(ctx context.Context, scope, key string, lease time.Duration) error {
+	query := `
+		UPDATE idempotency_key
+		SET lease_until = NOW() + make_interval(secs => $3)
+		WHERE scope = $1 AND idempotency_key = $2 AND estado = 'in_progress'
+	`
+
+	if _, err := r.pool.Exec(ctx, query, scope, key, lease.Seconds()); err != nil {
+		return fmt.Errorf("extend idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Complete stores the response of the request and marks the record as completed.
+func (r *Repository) Complete(ctx context.Context, scope, key string, response json.RawMessage) error {
+	query := `
+		UPDATE idempotency_key
+		SET estado = 'completed', response = $3, completed_at = NOW()
+		WHERE scope = $1 AND idempotency_key = $2
+	`
+
+	if _, err := r.pool.Exec(ctx, query, scope, key, []byte(response)); err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Release removes an in-progress reservation so the request can be retried.
+func (r *Repository) Release(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_key WHERE scope = $1 AND idempotency_key = $2 AND estado = 'in_progress'`
+
+	if _, err := r.pool.Exec(ctx, query, scope, key); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+
+	return nil
+}
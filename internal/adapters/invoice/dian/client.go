@@ -0,0 +1,410 @@
+// Package dian implements invoice.Provider directly against the DIAN SOAP web
+// services (WcfDianCustomerServices), without a technology provider in between.
+//
+// Requests are signed with WS-Security using the certificate of the invoicing
+// software. Documents are rendered to UBL 2.1 and signed (XAdES-EPES) by the
+// configured renderer, zipped and sent with SendBillSync, or with
+// SendTestSetAsync while the software is in habilitation. DIAN has no operation
+// to list documents by date, so GetDocuments and GetReceivedDocuments are not
+// supported, and documents are looked up by CUFE/CUDE instead of by number.
+package dian
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"3tcapital/goclonacion/internal/adapters/invoice/xades"
+	"3tcapital/goclonacion/internal/core/cufe"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/resolution"
+)
+
+const (
+	// ProductionURL and HabilitationURL are the endpoints of the DIAN web services.
+	ProductionURL   = "https://vpfe.dian.gov.co/WcfDianCustomerServices.svc"
+	HabilitationURL = "https://vpfe-hab.dian.gov.co/WcfDianCustomerServices.svc"
+)
+
+// HTTPClient interface allows using both standard and traced HTTP clients.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Settings identifies the invoicing software registered with DIAN.
+type Settings struct {
+	SoftwareID  string // Identifier of the software registered at DIAN
+	SoftwarePIN string // PIN of the software, used for the CUDE of events
+	ProviderNIT string // NIT of the software owner, optionally with DV ("900123456-7")
+	TestSetID   string // When set, documents are sent to this habilitation test set (SendTestSetAsync)
+	Ambiente    string // "1" production, "2" habilitation (default)
+}
+
+// Client implements invoice.Provider on top of the DIAN web services.
+type Client struct {
+	url        string
+	settings   Settings
+	cert       *xades.Certificate
+	signer     *xades.Signer
+	renderer   invoice.DocumentRenderer
+	httpClient HTTPClient
+	log        *slog.Logger
+	now        func() time.Time
+	sequence   atomic.Uint32 // Disambiguates the names of the zip files sent in the same second
+}
+
+var _ invoice.Provider = (*Client)(nil)
+
+// NewClient creates a DIAN web services client. The certificate signs the SOAP
+// requests and the RADIAN events; renderer produces the signed UBL documents.
+func NewClient(url string, settings Settings, cert *xades.Certificate, renderer invoice.DocumentRenderer, httpClient HTTPClient, log *slog.Logger) *Client {
+	if url == "" {
+		url = HabilitationURL
+	}
+	if settings.Ambiente != "1" {
+		settings.Ambiente = "2"
+	}
+	return &Client{
+		url:        url,
+		settings:   settings,
+		cert:       cert,
+		signer:     xades.NewSigner(cert),
+		renderer:   renderer,
+		httpClient: httpClient,
+		log:        log,
+		now:        time.Now,
+	}
+}
+
+// GetResolutions retrieves the numbering ranges of the issuer with GetNumberingRange.
+func (c *Client) GetResolutions(ctx context.Context, nit string) ([]resolution.Resolution, error) {
+	if nit == "" {
+		return nil, fmt.Errorf("nit is required")
+	}
+
+	ranges, err := c.GetNumberingRange(ctx, identification.Base(nit), identification.Base(c.settings.ProviderNIT), c.settings.SoftwareID)
+	if err != nil {
+		return nil, err
+	}
+	if ranges.OperationCode != operationCodeOK {
+		return nil, fmt.Errorf("DIAN GetNumberingRange: %s %s", ranges.OperationCode, ranges.OperationDescription)
+	}
+
+	resolutions := make([]resolution.Resolution, 0, len(ranges.ResponseList))
+	for _, r := range ranges.ResponseList {
+		res, err := toResolution(r)
+		if err != nil {
+			c.log.Warn("Skipping invalid DIAN numbering range", "resolution", r.ResolutionNumber, "error", err)
+			continue
+		}
+		resolutions = append(resolutions, res)
+	}
+	return resolutions, nil
+}
+
+func toResolution(r NumberRange) (resolution.Resolution, error) {
+	const layout = "2006-01-02"
+	resolutionDate, err := time.Parse(layout, r.ResolutionDate)
+	if err != nil {
+		return resolution.Resolution{}, fmt.Errorf("parse ResolutionDate: %w", err)
+	}
+	validFrom, err := time.Parse(layout, r.ValidDateFrom)
+	if err != nil {
+		return resolution.Resolution{}, fmt.Errorf("parse ValidDateFrom: %w", err)
+	}
+	validTo, err := time.Parse(layout, r.ValidDateTo)
+	if err != nil {
+		return resolution.Resolution{}, fmt.Errorf("parse ValidDateTo: %w", err)
+	}
+	return resolution.Resolution{
+		ResolutionNumber: r.ResolutionNumber,
+		ResolutionDate:   resolutionDate,
+		Prefix:           r.Prefix,
+		FromNumber:       r.FromNumber,
+		ToNumber:         r.ToNumber,
+		ValidDateFrom:    validFrom,
+		ValidDateTo:      validTo,
+	}, nil
+}
+
+// GetDocuments is not offered by the DIAN web services.
+func (c *Client) GetDocuments(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
+	return nil, fmt.Errorf("operación no soportada por el proveedor dian: consulta de documentos emitidos por rango de fechas")
+}
+
+// GetReceivedDocuments is not offered by the DIAN web services.
+func (c *Client) GetReceivedDocuments(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
+	return nil, fmt.Errorf("operación no soportada por el proveedor dian: consulta de documentos recibidos por rango de fechas")
+}
+
+// GetDocumentByNumber retrieves a document validated by DIAN. DIAN indexes documents
+// by CUFE/CUDE, so DocumentNumber must be the CUFE/CUDE of the document.
+func (c *Client) GetDocumentByNumber(ctx context.Context, query invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
+	if query.DocumentNumber == "" {
+		return nil, fmt.Errorf("document number is required")
+	}
+	if !cufe.ValidFormat(query.DocumentNumber) {
+		return nil, fmt.Errorf("operación no soportada por el proveedor dian: la consulta requiere el CUFE/CUDE del documento")
+	}
+
+	summary, err := c.documentByKey(ctx, query.DocumentNumber)
+	if err != nil {
+		return nil, err
+	}
+	if summary == nil {
+		return []invoice.Document{}, nil
+	}
+	doc, err := summary.document(query.DocumentNumber)
+	if err != nil {
+		return nil, err
+	}
+	return []invoice.Document{doc}, nil
+}
+
+// documentByKey downloads and parses the XML of a document. Returns nil when DIAN
+// does not know the document.
+func (c *Client) documentByKey(ctx context.Context, key string) (*ublSummary, error) {
+	resp, err := c.GetXmlByDocumentKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != operationCodeOK || resp.XmlBytesBase64 == "" {
+		c.log.Info("Document not found in DIAN", "cufe", key, "code", resp.Code, "message", resp.Message)
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.XmlBytesBase64)
+	if err != nil {
+		return nil, fmt.Errorf("decode XmlBytesBase64: %w", err)
+	}
+	return parseUBLSummary(data)
+}
+
+// RegisterDocument renders, signs and sends each document to DIAN.
+func (c *Client) RegisterDocument(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+	if c.renderer == nil {
+		return nil, fmt.Errorf("DIAN provider requires a document renderer")
+	}
+
+	resp := &invoice.DocumentRegistrationResponse{
+		DocumentosProcesados: make([]invoice.ProcessedDocument, 0),
+		DocumentosFallidos:   make([]invoice.FailedDocument, 0),
+	}
+
+	groups := []struct {
+		documentType string
+		documents    []invoice.OpenETLDocument
+	}{
+		{"FC", req.Documentos.FC},
+		{"NC", req.Documentos.NC},
+		{"ND", req.Documentos.ND},
+		{"DS", req.Documentos.DS},
+	}
+	total := 0
+	for _, group := range groups {
+		for _, doc := range group.documents {
+			total++
+			processed, failed, lote := c.registerDocument(ctx, doc, group.documentType)
+			if failed != nil {
+				resp.DocumentosFallidos = append(resp.DocumentosFallidos, *failed)
+				continue
+			}
+			resp.DocumentosProcesados = append(resp.DocumentosProcesados, *processed)
+			if resp.Lote == "" {
+				resp.Lote = lote
+			}
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no documents provided")
+	}
+
+	switch {
+	case len(resp.DocumentosFallidos) == 0:
+		resp.Message = "Documentos procesados exitosamente"
+	case len(resp.DocumentosProcesados) == 0:
+		resp.Message = "Error al procesar documentos"
+	default:
+		resp.Message = "Algunos documentos fueron procesados, otros fallaron"
+	}
+	return resp, nil
+}
+
+// registerDocument sends one document. In habilitation (TestSetID configured) the
+// ZipKey of the upload is returned as lote.
+func (c *Client) registerDocument(ctx context.Context, doc invoice.OpenETLDocument, documentType string) (*invoice.ProcessedDocument, *invoice.FailedDocument, string) {
+	now := c.now()
+	fail := func(errs ...string) (*invoice.ProcessedDocument, *invoice.FailedDocument, string) {
+		c.log.Warn("Document rejected by DIAN",
+			"prefijo", doc.RfaPrefijo,
+			"consecutivo", doc.CdoConsecutivo,
+			"errors", errs)
+		return nil, &invoice.FailedDocument{
+			Documento:          documentType,
+			Consecutivo:        doc.CdoConsecutivo,
+			Prefijo:            doc.RfaPrefijo,
+			Errors:             errs,
+			FechaProcesamiento: now.Format("2006-01-02"),
+			HoraProcesamiento:  now.Format("15:04:05"),
+		}, ""
+	}
+
+	signed, code, err := c.renderer.Render(doc, documentType)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	fileName := c.fileName(filePrefixes[documentType], issuerNIT(doc, documentType))
+	content, err := zipFile(fileName+".xml", signed)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	processed := &invoice.ProcessedDocument{
+		RfaPrefijo:         doc.RfaPrefijo,
+		CdoConsecutivo:     doc.CdoConsecutivo,
+		FechaProcesamiento: now.Format("2006-01-02"),
+		HoraProcesamiento:  now.Format("15:04:05"),
+		CUFE:               code,
+		XmlBase64:          base64.StdEncoding.EncodeToString(signed),
+	}
+	if id, err := strconv.Atoi(doc.CdoConsecutivo); err == nil {
+		processed.CdoID = id
+	}
+
+	if c.settings.TestSetID != "" {
+		upload, err := c.SendTestSetAsync(ctx, "z"+fileName[2:]+".zip", content, c.settings.TestSetID)
+		if err != nil {
+			return fail(err.Error())
+		}
+		if len(upload.ErrorMessageList) > 0 {
+			errs := make([]string, 0, len(upload.ErrorMessageList))
+			for _, e := range upload.ErrorMessageList {
+				errs = append(errs, e.ProcessedMessage)
+			}
+			return fail(errs...)
+		}
+		c.log.Info("Document uploaded to DIAN test set", "prefijo", doc.RfaPrefijo, "consecutivo", doc.CdoConsecutivo, "zip_key", upload.ZipKey)
+		return processed, nil, upload.ZipKey
+	}
+
+	result, err := c.SendBillSync(ctx, "z"+fileName[2:]+".zip", content)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if !result.IsValid {
+		return fail(result.Errors()...)
+	}
+	if result.XmlDocumentKey != "" {
+		processed.CUFE = result.XmlDocumentKey
+	}
+	c.log.Info("Document validated by DIAN", "prefijo", doc.RfaPrefijo, "consecutivo", doc.CdoConsecutivo, "status", result.StatusCode)
+	return processed, nil, ""
+}
+
+// filePrefixes are the file name prefixes of the Anexo Técnico per document type.
+var filePrefixes = map[string]string{
+	"FC": "fv",
+	"NC": "nc",
+	"ND": "nd",
+	"DS": "ds",
+}
+
+// fileName builds the DIAN file name: prefix, NIT (10 digits), software code (000),
+// year (2 digits) and an 8 hex digit consecutive.
+func (c *Client) fileName(prefix, nit string) string {
+	now := c.now()
+	consecutive := uint32(now.Unix())<<4 ^ c.sequence.Add(1)
+	return fmt.Sprintf("%s%010s000%s%08x", prefix, identification.Base(nit), now.Format("06"), consecutive)
+}
+
+// issuerNIT returns the NIT of the OFE issuing the document. In DS requests the OFE
+// (buyer) is sent as adq_identificacion.
+func issuerNIT(doc invoice.OpenETLDocument, documentType string) string {
+	if documentType == "DS" && doc.AdqIdentificacion != "" {
+		return doc.AdqIdentificacion
+	}
+	return doc.OfeIdentificacion
+}
+
+func zipFile(name string, content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(name)
+	if err != nil {
+		return nil, fmt.Errorf("zip %s: %w", name, err)
+	}
+	if _, err := w.Write(content); err != nil {
+		return nil, fmt.Errorf("zip %s: %w", name, err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("zip %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RegisterEvent registers a RADIAN event by sending a signed ApplicationResponse.
+// DIAN references invoices by CUFE, so evt.DocumentNumber must be the CUFE of the
+// received invoice.
+func (c *Client) RegisterEvent(ctx context.Context, evt event.Event, emisorNit, razonSocial string) (*invoice.EventRegistrationResult, error) {
+	radianCode, err := event.ToRadianCode(evt.EventType)
+	if err != nil {
+		return nil, fmt.Errorf("translate event type: %w", err)
+	}
+	if !cufe.ValidFormat(evt.DocumentNumber) {
+		return nil, fmt.Errorf("operación no soportada por el proveedor dian: el evento requiere el CUFE de la factura")
+	}
+
+	referenced, err := c.documentByKey(ctx, evt.DocumentNumber)
+	if err != nil {
+		return nil, err
+	}
+	if referenced == nil {
+		return nil, fmt.Errorf("document not found: %s", evt.DocumentNumber)
+	}
+
+	response, err := c.applicationResponse(evt, radianCode, emisorNit, razonSocial, referenced)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := c.signer.Sign(response)
+	if err != nil {
+		return nil, fmt.Errorf("firmar evento: %w", err)
+	}
+
+	fileName := c.fileName("ar", emisorNit)
+	content, err := zipFile(fileName+".xml", signed)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.SendEventUpdateStatus(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	eventResult := invoice.EventResult{
+		TipoEvento:      string(evt.EventType),
+		Mensaje:         strings.TrimSpace(result.StatusDescription),
+		CodigoRespuesta: result.StatusCode,
+	}
+	out := &invoice.EventRegistrationResult{
+		Code:            result.StatusCode,
+		NumeroDocumento: referenced.ID,
+	}
+	if !result.IsValid {
+		eventResult.MensajeError = strings.Join(result.Errors(), "; ")
+		out.MensajeError = eventResult.MensajeError
+		c.log.Warn("Event rejected by DIAN", "eventType", evt.EventType, "cufe", evt.DocumentNumber, "errors", eventResult.MensajeError)
+	}
+	out.Resultado = []invoice.EventResult{eventResult}
+	return out, nil
+}
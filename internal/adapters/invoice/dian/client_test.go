@@ -0,0 +1,511 @@
+package dian
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/adapters/invoice/xades"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+const testCUFE = "6a6b2a5cbe6e0ab5ab0e3ff1e0b8a4b5c7cd1d6e9a25f1bbbbd1a3ea92d7d1f7dbe5c6f2c6bcd5c9b1a6b2e4a0c7d5e3"
+
+var (
+	certOnce sync.Once
+	testCert *xades.Certificate
+)
+
+// testCertificate returns a self-signed certificate shared by the tests.
+func testCertificate(t *testing.T) *xades.Certificate {
+	t.Helper()
+	certOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "Software de Facturación de Prueba"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().AddDate(1, 0, 0),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			t.Fatalf("create certificate: %v", err)
+		}
+		leaf, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatalf("parse certificate: %v", err)
+		}
+		testCert = &xades.Certificate{Leaf: leaf, PrivateKey: key}
+	})
+	return testCert
+}
+
+// stubDIAN replays recorded DIAN responses per SOAP action and verifies the
+// WS-Security signature of every request.
+type stubDIAN struct {
+	t         *testing.T
+	responses map[string]string // SOAP action -> file in testdata
+	status    int
+
+	mu       sync.Mutex
+	requests map[string][]string
+}
+
+var actionPattern = regexp.MustCompile(`action="` + regexp.QuoteMeta(actionPrefix) + `(\w+)"`)
+
+func (s *stubDIAN) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	match := actionPattern.FindStringSubmatch(r.Header.Get("Content-Type"))
+	if match == nil {
+		s.t.Errorf("missing SOAP action in Content-Type %q", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	action := match[1]
+	if err := verifyWSSecurity(body, "http://"+r.Host+r.URL.Path); err != nil {
+		s.t.Errorf("%s: invalid WS-Security: %v", action, err)
+	}
+
+	s.mu.Lock()
+	if s.requests == nil {
+		s.requests = map[string][]string{}
+	}
+	s.requests[action] = append(s.requests[action], string(body))
+	s.mu.Unlock()
+
+	file, ok := s.responses[action]
+	if !ok {
+		s.t.Errorf("unexpected SOAP action %s", action)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		s.t.Fatalf("read testdata: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+	}
+	w.Write(data)
+}
+
+func (s *stubDIAN) request(action string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests[action]) == 0 {
+		s.t.Fatalf("no %s request received", action)
+	}
+	return s.requests[action][len(s.requests[action])-1]
+}
+
+// verifyWSSecurity checks the signature over wsa:To the way the DIAN service does.
+func verifyWSSecurity(body []byte, url string) error {
+	between := func(start, end string) string {
+		s := string(body)
+		i := strings.Index(s, start)
+		j := strings.Index(s, end)
+		if i < 0 || j < i {
+			return ""
+		}
+		return s[i : j+len(end)]
+	}
+	inner := func(element, name string) string {
+		re := regexp.MustCompile(`<` + name + `[^>]*>([^<]*)</` + name + `>`)
+		if m := re.FindStringSubmatch(element); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+
+	to := between("<wsa:To ", "</wsa:To>")
+	signedInfo := between("<ds:SignedInfo ", "</ds:SignedInfo>")
+	if to == "" || signedInfo == "" {
+		return errors.New("missing wsa:To or ds:SignedInfo")
+	}
+	if inner(to, "wsa:To") != url {
+		return fmt.Errorf("wsa:To %q does not match %q", inner(to, "wsa:To"), url)
+	}
+	if !strings.Contains(string(body), "<wsu:Created>") || !strings.Contains(string(body), "<wsa:Action>") {
+		return errors.New("missing timestamp or action")
+	}
+
+	id := regexp.MustCompile(`wsu:Id="([^"]+)"`).FindStringSubmatch(to)[1]
+	if !strings.Contains(signedInfo, `URI="#`+id+`"`) {
+		return errors.New("reference does not point to wsa:To")
+	}
+	digest := sha256.Sum256([]byte(to))
+	if inner(signedInfo, "ds:DigestValue") != base64.StdEncoding.EncodeToString(digest[:]) {
+		return errors.New("digest of wsa:To does not match")
+	}
+
+	der, err := base64.StdEncoding.DecodeString(inner(string(body), "wsse:BinarySecurityToken"))
+	if err != nil {
+		return fmt.Errorf("decode token: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return fmt.Errorf("parse token: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(inner(string(body), "ds:SignatureValue"))
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	hashed := sha256.Sum256([]byte(signedInfo))
+	return rsa.VerifyPKCS1v15(cert.PublicKey.(*rsa.PublicKey), crypto.SHA256, hashed[:], signature)
+}
+
+// fakeRenderer returns a fixed signed document.
+type fakeRenderer struct {
+	err error
+}
+
+func (f fakeRenderer) Render(doc invoice.OpenETLDocument, documentType string) ([]byte, string, error) {
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	return []byte("<Invoice><ID>" + doc.RfaPrefijo + doc.CdoConsecutivo + "</ID></Invoice>"), "local-cufe", nil
+}
+
+func newTestClient(t *testing.T, stub *stubDIAN, settings Settings) *Client {
+	t.Helper()
+	stub.t = t
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	if settings.SoftwareID == "" {
+		settings.SoftwareID = "56f2ae4e-9812-4fad-9255-643fc46e7cc2"
+		settings.SoftwarePIN = "12345"
+		settings.ProviderNIT = "900508908-5"
+	}
+	client := NewClient(server.URL+"/WcfDianCustomerServices.svc", settings, testCertificate(t), fakeRenderer{}, server.Client(), testutil.NewTestLogger())
+	client.now = func() time.Time { return time.Date(2024, 3, 15, 19, 37, 0, 0, time.UTC) }
+	return client
+}
+
+func unzipSingle(t *testing.T, data []byte) (string, []byte) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	if len(zr.File) != 1 {
+		t.Fatalf("expected one file in zip, got %d", len(zr.File))
+	}
+	f, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("open zip entry: %v", err)
+	}
+	defer f.Close()
+	content, _ := io.ReadAll(f)
+	return zr.File[0].Name, content
+}
+
+func contentFile(t *testing.T, request string) []byte {
+	t.Helper()
+	m := regexp.MustCompile(`<wcf:contentFile>([^<]*)</wcf:contentFile>`).FindStringSubmatch(request)
+	if m == nil {
+		t.Fatal("request without contentFile")
+	}
+	data, err := base64.StdEncoding.DecodeString(m[1])
+	if err != nil {
+		t.Fatalf("decode contentFile: %v", err)
+	}
+	return data
+}
+
+func testDocuments() invoice.DocumentRegistrationRequest {
+	return invoice.DocumentRegistrationRequest{Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{
+		{OfeIdentificacion: "900373115-6", RfaPrefijo: "SETP", CdoConsecutivo: "990000002"},
+	}}}
+}
+
+func TestClient_RegisterDocument_SendBillSync(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{"SendBillSync": "send_bill_sync_valid.xml"}}
+	client := newTestClient(t, stub, Settings{})
+
+	resp, err := client.RegisterDocument(context.Background(), testDocuments())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.DocumentosProcesados) != 1 || len(resp.DocumentosFallidos) != 0 {
+		t.Fatalf("expected one processed document, got %+v", resp)
+	}
+	processed := resp.DocumentosProcesados[0]
+	if processed.CUFE != testCUFE {
+		t.Errorf("expected CUFE from DIAN, got %s", processed.CUFE)
+	}
+	if processed.CdoID != 990000002 || processed.FechaProcesamiento != "2024-03-15" {
+		t.Errorf("unexpected processed document: %+v", processed)
+	}
+	if resp.Message != "Documentos procesados exitosamente" {
+		t.Errorf("unexpected message %q", resp.Message)
+	}
+
+	request := stub.request("SendBillSync")
+	if !strings.Contains(request, "<wcf:fileName>z0900373115000") {
+		t.Errorf("expected zip file name with the issuer NIT, got %s", request)
+	}
+	name, content := unzipSingle(t, contentFile(t, request))
+	if !strings.HasPrefix(name, "fv0900373115000") || !strings.HasSuffix(name, ".xml") {
+		t.Errorf("unexpected file name %s", name)
+	}
+	if string(content) != "<Invoice><ID>SETP990000002</ID></Invoice>" {
+		t.Errorf("unexpected zipped document %s", content)
+	}
+}
+
+func TestClient_RegisterDocument_Rejected(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{"SendBillSync": "send_bill_sync_rejected.xml"}}
+	client := newTestClient(t, stub, Settings{})
+
+	resp, err := client.RegisterDocument(context.Background(), testDocuments())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.DocumentosFallidos) != 1 {
+		t.Fatalf("expected one failed document, got %+v", resp)
+	}
+	failed := resp.DocumentosFallidos[0]
+	if failed.Documento != "FC" || failed.Prefijo != "SETP" || len(failed.Errors) != 2 || !strings.Contains(failed.Errors[0], "FAD06") {
+		t.Errorf("unexpected failed document: %+v", failed)
+	}
+	if resp.Message != "Error al procesar documentos" {
+		t.Errorf("unexpected message %q", resp.Message)
+	}
+}
+
+func TestClient_RegisterDocument_TestSet(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{"SendTestSetAsync": "send_test_set_async.xml"}}
+	client := newTestClient(t, stub, Settings{SoftwareID: "sw", TestSetID: "b7e6c3a4-test-set"})
+
+	resp, err := client.RegisterDocument(context.Background(), testDocuments())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Lote != "2f8f0c8c-9a0f-4ab6-8f4c-46f2b5a1e8a9" {
+		t.Errorf("expected ZipKey as lote, got %q", resp.Lote)
+	}
+	if len(resp.DocumentosProcesados) != 1 || resp.DocumentosProcesados[0].CUFE != "local-cufe" {
+		t.Errorf("expected processed document with the local CUFE, got %+v", resp)
+	}
+	if !strings.Contains(stub.request("SendTestSetAsync"), "<wcf:testSetId>b7e6c3a4-test-set</wcf:testSetId>") {
+		t.Error("expected test set id in request")
+	}
+}
+
+func TestClient_RegisterDocument_Errors(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{"SendBillSync": "soap_fault.xml"}, status: http.StatusInternalServerError}
+	client := newTestClient(t, stub, Settings{})
+
+	resp, err := client.RegisterDocument(context.Background(), testDocuments())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.DocumentosFallidos) != 1 || !strings.Contains(resp.DocumentosFallidos[0].Errors[0], "SOAP fault s:Sender: An error occurred when verifying security") {
+		t.Errorf("expected SOAP fault as document error, got %+v", resp)
+	}
+
+	client.renderer = fakeRenderer{err: errors.New("cdo_total: valor inválido")}
+	resp, err = client.RegisterDocument(context.Background(), testDocuments())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.DocumentosFallidos) != 1 || resp.DocumentosFallidos[0].Errors[0] != "cdo_total: valor inválido" {
+		t.Errorf("expected render error as document error, got %+v", resp)
+	}
+
+	if _, err := client.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{}); err == nil || !strings.Contains(err.Error(), "no documents provided") {
+		t.Errorf("expected no documents error, got %v", err)
+	}
+}
+
+func TestClient_GetResolutions(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{"GetNumberingRange": "get_numbering_range.xml"}}
+	client := newTestClient(t, stub, Settings{})
+
+	resolutions, err := client.GetResolutions(context.Background(), "900373115-6")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resolutions) != 1 {
+		t.Fatalf("expected one resolution, got %d", len(resolutions))
+	}
+	r := resolutions[0]
+	if r.ResolutionNumber != "18760000001" || r.Prefix != "SETP" || r.FromNumber != 990000000 || r.ToNumber != 995000000 {
+		t.Errorf("unexpected resolution: %+v", r)
+	}
+	if r.ValidDateTo != time.Date(2030, 1, 19, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("unexpected ValidDateTo %s", r.ValidDateTo)
+	}
+
+	request := stub.request("GetNumberingRange")
+	for _, fragment := range []string{
+		"<wcf:accountCode>900373115</wcf:accountCode>",
+		"<wcf:accountCodeT>900508908</wcf:accountCodeT>",
+		"<wcf:softwareCode>56f2ae4e-9812-4fad-9255-643fc46e7cc2</wcf:softwareCode>",
+	} {
+		if !strings.Contains(request, fragment) {
+			t.Errorf("expected request to contain %s", fragment)
+		}
+	}
+}
+
+func TestClient_GetResolutions_OperationError(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{"GetNumberingRange": "get_numbering_range_unauthorized.xml"}}
+	client := newTestClient(t, stub, Settings{})
+
+	_, err := client.GetResolutions(context.Background(), "900373115")
+	if err == nil || !strings.Contains(err.Error(), "301 Nit del proveedor tecnológico no autorizado") {
+		t.Errorf("expected operation error, got %v", err)
+	}
+}
+
+func TestClient_GetStatus(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{"GetStatus": "get_status.xml"}}
+	client := newTestClient(t, stub, Settings{})
+
+	status, err := client.GetStatus(context.Background(), testCUFE)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.IsValid || status.StatusCode != "00" || status.XmlDocumentKey != testCUFE {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if !strings.Contains(stub.request("GetStatus"), "<wcf:trackId>"+testCUFE+"</wcf:trackId>") {
+		t.Error("expected trackId in request")
+	}
+}
+
+func TestClient_GetDocumentByNumber(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{"GetXmlByDocumentKey": "get_xml_by_document_key.xml"}}
+	client := newTestClient(t, stub, Settings{})
+
+	docs, err := client.GetDocumentByNumber(context.Background(), invoice.DocumentByNumberQuery{CompanyNit: "860011153", DocumentNumber: testCUFE})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("expected one document, got %d", len(docs))
+	}
+	doc := docs[0]
+	if doc.OFE != "900373115" || doc.Proveedor != "Proveedor de Pruebas S.A.S." || doc.Tipo != "01" {
+		t.Errorf("unexpected issuer: %+v", doc)
+	}
+	if doc.Prefijo != "SETP" || doc.Consecutivo != "990000002" || doc.CUFE != testCUFE || doc.Valor != 119000 || doc.Hora != "14:37:00-05:00" {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+
+	_, err = client.GetDocumentByNumber(context.Background(), invoice.DocumentByNumberQuery{DocumentNumber: "SETP990000002"})
+	if err == nil || !strings.Contains(err.Error(), "operación no soportada") {
+		t.Errorf("expected unsupported error for document numbers, got %v", err)
+	}
+}
+
+func TestClient_GetDocumentByNumber_NotFound(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{"GetXmlByDocumentKey": "get_xml_by_document_key_not_found.xml"}}
+	client := newTestClient(t, stub, Settings{})
+
+	docs, err := client.GetDocumentByNumber(context.Background(), invoice.DocumentByNumberQuery{DocumentNumber: testCUFE})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(docs) != 0 {
+		t.Errorf("expected no documents, got %+v", docs)
+	}
+}
+
+func TestClient_UnsupportedQueries(t *testing.T) {
+	client := newTestClient(t, &stubDIAN{}, Settings{})
+	query := invoice.DocumentQuery{CompanyNit: "860011153", InitialDate: "2024-03-01", FinalDate: "2024-03-31"}
+
+	if _, err := client.GetDocuments(context.Background(), query); err == nil || !strings.Contains(err.Error(), "operación no soportada") {
+		t.Errorf("expected unsupported error, got %v", err)
+	}
+	if _, err := client.GetReceivedDocuments(context.Background(), query); err == nil || !strings.Contains(err.Error(), "operación no soportada") {
+		t.Errorf("expected unsupported error, got %v", err)
+	}
+}
+
+func TestClient_RegisterEvent(t *testing.T) {
+	stub := &stubDIAN{responses: map[string]string{
+		"GetXmlByDocumentKey":   "get_xml_by_document_key.xml",
+		"SendEventUpdateStatus": "send_event_update_status.xml",
+	}}
+	client := newTestClient(t, stub, Settings{})
+
+	evt := event.Event{
+		EventType:               event.EventTypeAcuse,
+		DocumentNumber:          testCUFE,
+		NombreGenerador:         "Ana",
+		ApellidoGenerador:       "Pérez",
+		IdentificacionGenerador: "1020304050",
+		EventGenerationDate:     time.Now(),
+	}
+	result, err := client.RegisterEvent(context.Background(), evt, "860011153-6", "Empresa Adquirente S.A.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Code != "00" || result.NumeroDocumento != "SETP990000002" || result.MensajeError != "" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(result.Resultado) != 1 || result.Resultado[0].TipoEvento != "ACUSE" {
+		t.Errorf("unexpected event results: %+v", result.Resultado)
+	}
+
+	name, content := unzipSingle(t, contentFile(t, stub.request("SendEventUpdateStatus")))
+	if !strings.HasPrefix(name, "ar0860011153000") {
+		t.Errorf("unexpected file name %s", name)
+	}
+	verification, err := xades.Verify(content)
+	if err != nil {
+		t.Fatalf("expected signed ApplicationResponse, got %v", err)
+	}
+	if !verification.Certificate.Equal(testCertificate(t).Leaf) {
+		t.Error("expected the event to be signed with the client certificate")
+	}
+	for _, fragment := range []string{
+		"<cbc:ResponseCode>030</cbc:ResponseCode>",
+		`<cbc:CompanyID schemeAgencyID="195" schemeID="6" schemeName="31">860011153</cbc:CompanyID>`,
+		`<cbc:CompanyID schemeAgencyID="195" schemeName="31">900373115</cbc:CompanyID>`,
+		"<cbc:UUID schemeName=\"CUFE-SHA384\">" + testCUFE + "</cbc:UUID>",
+		"<cbc:FamilyName>Pérez</cbc:FamilyName>",
+	} {
+		if !strings.Contains(string(content), fragment) {
+			t.Errorf("expected ApplicationResponse to contain %s", fragment)
+		}
+	}
+}
+
+func TestClient_RegisterEvent_RequiresCUFE(t *testing.T) {
+	client := newTestClient(t, &stubDIAN{}, Settings{})
+	_, err := client.RegisterEvent(context.Background(), event.Event{EventType: event.EventTypeAcuse, DocumentNumber: "SETP990000002"}, "860011153", "Empresa")
+	if err == nil || !strings.Contains(err.Error(), "requiere el CUFE") {
+		t.Errorf("expected CUFE required error, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package dian
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// ublSummary holds the fields of a UBL document (Invoice, CreditNote, DebitNote)
+// needed to describe it. AttachedDocument containers are unwrapped by parseUBLSummary.
+type ublSummary struct {
+	XMLName          xml.Name
+	ID               string `xml:"ID"`
+	UUID             string `xml:"UUID"`
+	IssueDate        string `xml:"IssueDate"`
+	IssueTime        string `xml:"IssueTime"`
+	InvoiceTypeCode  string `xml:"InvoiceTypeCode"`
+	SupplierNIT      string `xml:"AccountingSupplierParty>Party>PartyTaxScheme>CompanyID"`
+	SupplierName     string `xml:"AccountingSupplierParty>Party>PartyTaxScheme>RegistrationName"`
+	CustomerNIT      string `xml:"AccountingCustomerParty>Party>PartyTaxScheme>CompanyID"`
+	CustomerName     string `xml:"AccountingCustomerParty>Party>PartyTaxScheme>RegistrationName"`
+	PayableAmount    string `xml:"LegalMonetaryTotal>PayableAmount"`
+	RequestedAmount  string `xml:"RequestedMonetaryTotal>PayableAmount"`
+	AttachedDocument string `xml:"Attachment>ExternalReference>Description"`
+}
+
+// parseUBLSummary parses a UBL document, unwrapping the document embedded in an
+// AttachedDocument.
+func parseUBLSummary(data []byte) (*ublSummary, error) {
+	var summary ublSummary
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("parse document XML: %w", err)
+	}
+	if summary.XMLName.Local == "AttachedDocument" {
+		embedded := strings.TrimSpace(summary.AttachedDocument)
+		if embedded == "" {
+			return nil, fmt.Errorf("parse document XML: AttachedDocument sin documento embebido")
+		}
+		return parseUBLSummary([]byte(embedded))
+	}
+	return &summary, nil
+}
+
+// document converts the summary to a domain Document.
+func (s *ublSummary) document(key string) (invoice.Document, error) {
+	fecha, err := time.Parse("2006-01-02", strings.TrimSpace(s.IssueDate))
+	if err != nil {
+		return invoice.Document{}, fmt.Errorf("parse IssueDate [%s]: %w", s.IssueDate, err)
+	}
+
+	amount := s.PayableAmount
+	if amount == "" {
+		amount = s.RequestedAmount
+	}
+	var valor float64
+	if amount = strings.TrimSpace(amount); amount != "" {
+		if valor, err = strconv.ParseFloat(amount, 64); err != nil {
+			return invoice.Document{}, fmt.Errorf("parse PayableAmount [%s]: %w", amount, err)
+		}
+	}
+
+	tipo := s.InvoiceTypeCode
+	switch s.XMLName.Local {
+	case "CreditNote":
+		tipo = "91"
+	case "DebitNote":
+		tipo = "92"
+	}
+
+	if s.UUID != "" {
+		key = strings.TrimSpace(s.UUID)
+	}
+	prefijo, consecutivo := splitDocumentNumber(strings.TrimSpace(s.ID))
+	return invoice.Document{
+		OFE:         strings.TrimSpace(s.SupplierNIT),
+		Proveedor:   strings.TrimSpace(s.SupplierName),
+		Tipo:        tipo,
+		Prefijo:     prefijo,
+		Consecutivo: consecutivo,
+		CUFE:        key,
+		Fecha:       fecha,
+		Hora:        strings.TrimSpace(s.IssueTime),
+		Valor:       valor,
+	}, nil
+}
+
+// splitDocumentNumber splits "SETT5608" into prefix "SETT" and consecutive "5608".
+func splitDocumentNumber(number string) (prefijo, consecutivo string) {
+	for i := 0; i < len(number); i++ {
+		if number[i] >= '0' && number[i] <= '9' {
+			return number[:i], number[i:]
+		}
+	}
+	return number, ""
+}
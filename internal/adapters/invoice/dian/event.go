@@ -0,0 +1,103 @@
+package dian
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/identification"
+)
+
+const (
+	// authorizationProviderNIT is the NIT of DIAN as authorization provider.
+	authorizationProviderNIT = "800197268"
+
+	eventProfileID = "DIAN 2.1: ApplicationResponse de la Factura Electrónica de Venta"
+)
+
+// colombia is the fixed UTC-5 offset DIAN expects in issue times.
+var colombia = time.FixedZone("COT", -5*60*60)
+
+// eventDescriptions are the descriptions of the RADIAN response codes.
+var eventDescriptions = map[event.RadianCode]string{
+	event.RadianCodeAcuse:      "Acuse de recibo de Factura Electrónica de Venta",
+	event.RadianCodeReclamo:    "Reclamo de la Factura Electrónica de Venta",
+	event.RadianCodeReciboBien: "Recibo del bien y/o prestación del servicio",
+	event.RadianCodeAceptacion: "Aceptación expresa",
+}
+
+// applicationResponse builds the unsigned ApplicationResponse of a RADIAN event. The
+// sender is the acquirer registering the event and the receiver the issuer of the
+// referenced invoice. The second UBLExtension is left empty for the signature.
+func (c *Client) applicationResponse(evt event.Event, code event.RadianCode, emisorNit, razonSocial string, referenced *ublSummary) ([]byte, error) {
+	generated := evt.EventGenerationDate
+	if generated.IsZero() {
+		generated = c.now()
+	}
+	generated = generated.In(colombia)
+
+	id := "EV" + strconv.FormatInt(generated.Unix(), 10)
+	issueDate := generated.Format("2006-01-02")
+	issueTime := generated.Format("15:04:05-07:00")
+	sender := identification.Base(emisorNit)
+	receiver := identification.Base(referenced.SupplierNIT)
+	referencedKey := strings.TrimSpace(referenced.UUID)
+	if referencedKey == "" {
+		referencedKey = evt.DocumentNumber
+	}
+
+	cudeInput := id + issueDate + issueTime + sender + receiver + string(code) + referenced.ID + "01" + c.settings.SoftwarePIN
+	cude := sha512.Sum384([]byte(cudeInput))
+	securityCode := sha512.Sum384([]byte(c.settings.SoftwareID + c.settings.SoftwarePIN + id))
+
+	var b bytes.Buffer
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format, args...) }
+	b.WriteString(xml.Header)
+	w(`<ApplicationResponse xmlns="urn:oasis:names:specification:ubl:schema:xsd:ApplicationResponse-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2" xmlns:sts="dian:gov:co:facturaelectronica:Structures-2-1" xmlns:xades="http://uri.etsi.org/01903/v1.3.2#">`)
+	w(`<ext:UBLExtensions><ext:UBLExtension><ext:ExtensionContent><sts:DianExtensions>`)
+	w(`<sts:SoftwareProvider><sts:ProviderID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)"%s schemeName="31">%s</sts:ProviderID>`,
+		dvAttr(c.settings.ProviderNIT), escape(identification.Base(c.settings.ProviderNIT)))
+	w(`<sts:SoftwareID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)">%s</sts:SoftwareID></sts:SoftwareProvider>`, escape(c.settings.SoftwareID))
+	w(`<sts:SoftwareSecurityCode schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)">%s</sts:SoftwareSecurityCode>`, hex.EncodeToString(securityCode[:]))
+	w(`<sts:AuthorizationProvider><sts:AuthorizationProviderID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)" schemeID="4" schemeName="31">%s</sts:AuthorizationProviderID></sts:AuthorizationProvider>`, authorizationProviderNIT)
+	w(`</sts:DianExtensions></ext:ExtensionContent></ext:UBLExtension>`)
+	w(`<ext:UBLExtension><ext:ExtensionContent></ext:ExtensionContent></ext:UBLExtension></ext:UBLExtensions>`)
+	w(`<cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID><cbc:CustomizationID>1</cbc:CustomizationID>`)
+	w(`<cbc:ProfileID>%s</cbc:ProfileID><cbc:ProfileExecutionID>%s</cbc:ProfileExecutionID>`, eventProfileID, c.settings.Ambiente)
+	w(`<cbc:ID>%s</cbc:ID>`, id)
+	w(`<cbc:UUID schemeID="%s" schemeName="CUDE-SHA384">%s</cbc:UUID>`, c.settings.Ambiente, hex.EncodeToString(cude[:]))
+	w(`<cbc:IssueDate>%s</cbc:IssueDate><cbc:IssueTime>%s</cbc:IssueTime>`, issueDate, issueTime)
+	w(`<cac:SenderParty><cac:PartyTaxScheme><cbc:RegistrationName>%s</cbc:RegistrationName><cbc:CompanyID schemeAgencyID="195"%s schemeName="31">%s</cbc:CompanyID><cac:TaxScheme><cbc:ID>01</cbc:ID><cbc:Name>IVA</cbc:Name></cac:TaxScheme></cac:PartyTaxScheme></cac:SenderParty>`,
+		escape(razonSocial), dvAttr(emisorNit), escape(sender))
+	w(`<cac:ReceiverParty><cac:PartyTaxScheme><cbc:RegistrationName>%s</cbc:RegistrationName><cbc:CompanyID schemeAgencyID="195"%s schemeName="31">%s</cbc:CompanyID><cac:TaxScheme><cbc:ID>01</cbc:ID><cbc:Name>IVA</cbc:Name></cac:TaxScheme></cac:PartyTaxScheme></cac:ReceiverParty>`,
+		escape(referenced.SupplierName), dvAttr(referenced.SupplierNIT), escape(receiver))
+	w(`<cac:DocumentResponse><cac:Response>`)
+	if evt.RejectionCode != nil {
+		w(`<cbc:ResponseCode listID="%s">%s</cbc:ResponseCode>`, escape(string(*evt.RejectionCode)), code)
+	} else {
+		w(`<cbc:ResponseCode>%s</cbc:ResponseCode>`, code)
+	}
+	w(`<cbc:Description>%s</cbc:Description></cac:Response>`, eventDescriptions[code])
+	w(`<cac:DocumentReference><cbc:ID>%s</cbc:ID><cbc:UUID schemeName="CUFE-SHA384">%s</cbc:UUID><cbc:DocumentTypeCode>01</cbc:DocumentTypeCode></cac:DocumentReference>`,
+		escape(referenced.ID), escape(referencedKey))
+	if evt.IdentificacionGenerador != "" {
+		w(`<cac:IssuerParty><cac:Person><cbc:ID schemeName="13">%s</cbc:ID><cbc:FirstName>%s</cbc:FirstName><cbc:FamilyName>%s</cbc:FamilyName></cac:Person></cac:IssuerParty>`,
+			escape(evt.IdentificacionGenerador), escape(evt.NombreGenerador), escape(evt.ApellidoGenerador))
+	}
+	w(`</cac:DocumentResponse></ApplicationResponse>`)
+	return b.Bytes(), nil
+}
+
+// dvAttr returns the schemeID attribute with the verification digit of nit, when present.
+func dvAttr(nit string) string {
+	if _, dv := identification.Split(nit); dv != "" {
+		return fmt.Sprintf(` schemeID="%s"`, escape(dv))
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+package dian
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// DianResponse is the result of SendBillSync, GetStatus and SendEventUpdateStatus.
+type DianResponse struct {
+	IsValid           bool     `xml:"IsValid"`
+	StatusCode        string   `xml:"StatusCode"`
+	StatusDescription string   `xml:"StatusDescription"`
+	StatusMessage     string   `xml:"StatusMessage"`
+	ErrorMessage      []string `xml:"ErrorMessage>string"`
+	XmlBase64Bytes    string   `xml:"XmlBase64Bytes"` // ApplicationResponse of the DIAN
+	XmlDocumentKey    string   `xml:"XmlDocumentKey"` // CUFE/CUDE of the document
+	XmlFileName       string   `xml:"XmlFileName"`
+}
+
+// Errors returns the rejection messages of the response, falling back to the
+// status description when DIAN does not list the failed rules.
+func (r DianResponse) Errors() []string {
+	var errs []string
+	for _, msg := range r.ErrorMessage {
+		if msg = strings.TrimSpace(msg); msg != "" {
+			errs = append(errs, msg)
+		}
+	}
+	if len(errs) == 0 {
+		if desc := strings.TrimSpace(r.StatusDescription); desc != "" {
+			errs = append(errs, desc)
+		} else {
+			errs = append(errs, "Error desconocido")
+		}
+	}
+	return errs
+}
+
+// UploadDocumentResponse is the result of SendTestSetAsync.
+type UploadDocumentResponse struct {
+	ZipKey           string           `xml:"ZipKey"`
+	ErrorMessageList []XmlParamsError `xml:"ErrorMessageList>XmlParamsResponseTrackId"`
+}
+
+// XmlParamsError describes a document rejected while uploading a test set.
+type XmlParamsError struct {
+	DocumentKey      string `xml:"DocumentKey"`
+	ProcessedMessage string `xml:"ProcessedMessage"`
+	Success          bool   `xml:"Success"`
+	XmlFileName      string `xml:"XmlFileName"`
+}
+
+// NumberRangeResponseList is the result of GetNumberingRange.
+type NumberRangeResponseList struct {
+	OperationCode        string        `xml:"OperationCode"`
+	OperationDescription string        `xml:"OperationDescription"`
+	ResponseList         []NumberRange `xml:"ResponseList>NumberRangeResponse"`
+}
+
+// NumberRange is a numbering range authorized by DIAN.
+type NumberRange struct {
+	ResolutionNumber string `xml:"ResolutionNumber"`
+	ResolutionDate   string `xml:"ResolutionDate"`
+	Prefix           string `xml:"Prefix"`
+	FromNumber       int64  `xml:"FromNumber"`
+	ToNumber         int64  `xml:"ToNumber"`
+	ValidDateFrom    string `xml:"ValidDateFrom"`
+	ValidDateTo      string `xml:"ValidDateTo"`
+	TechnicalKey     string `xml:"TechnicalKey"`
+}
+
+// EventResponse is the result of GetXmlByDocumentKey.
+type EventResponse struct {
+	Code           string `xml:"Code"`
+	Message        string `xml:"Message"`
+	XmlBytesBase64 string `xml:"XmlBytesBase64"`
+}
+
+// operationCodeOK is the OperationCode/Code DIAN returns on success.
+const operationCodeOK = "100"
+
+// SendBillSync sends a zipped, signed document and waits for its validation.
+func (c *Client) SendBillSync(ctx context.Context, fileName string, content []byte) (*DianResponse, error) {
+	var out struct {
+		Result DianResponse `xml:"SendBillSyncResult"`
+	}
+	body := fmt.Sprintf(`<wcf:SendBillSync><wcf:fileName>%s</wcf:fileName><wcf:contentFile>%s</wcf:contentFile></wcf:SendBillSync>`,
+		escape(fileName), base64.StdEncoding.EncodeToString(content))
+	if err := c.call(ctx, "SendBillSync", body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Result, nil
+}
+
+// SendTestSetAsync uploads a zipped, signed document to the habilitation test set.
+// DIAN validates it asynchronously; the returned ZipKey identifies the upload.
+func (c *Client) SendTestSetAsync(ctx context.Context, fileName string, content []byte, testSetID string) (*UploadDocumentResponse, error) {
+	var out struct {
+		Result UploadDocumentResponse `xml:"SendTestSetAsyncResult"`
+	}
+	body := fmt.Sprintf(`<wcf:SendTestSetAsync><wcf:fileName>%s</wcf:fileName><wcf:contentFile>%s</wcf:contentFile><wcf:testSetId>%s</wcf:testSetId></wcf:SendTestSetAsync>`,
+		escape(fileName), base64.StdEncoding.EncodeToString(content), escape(testSetID))
+	if err := c.call(ctx, "SendTestSetAsync", body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Result, nil
+}
+
+// GetStatus returns the validation status of a document by its CUFE/CUDE.
+func (c *Client) GetStatus(ctx context.Context, trackID string) (*DianResponse, error) {
+	var out struct {
+		Result DianResponse `xml:"GetStatusResult"`
+	}
+	body := fmt.Sprintf(`<wcf:GetStatus><wcf:trackId>%s</wcf:trackId></wcf:GetStatus>`, escape(trackID))
+	if err := c.call(ctx, "GetStatus", body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Result, nil
+}
+
+// GetNumberingRange returns the numbering ranges of an issuer associated with the software.
+// accountCode is the NIT of the issuer and accountCodeT the NIT of the technology provider.
+func (c *Client) GetNumberingRange(ctx context.Context, accountCode, accountCodeT, softwareCode string) (*NumberRangeResponseList, error) {
+	var out struct {
+		Result NumberRangeResponseList `xml:"GetNumberingRangeResult"`
+	}
+	body := fmt.Sprintf(`<wcf:GetNumberingRange><wcf:accountCode>%s</wcf:accountCode><wcf:accountCodeT>%s</wcf:accountCodeT><wcf:softwareCode>%s</wcf:softwareCode></wcf:GetNumberingRange>`,
+		escape(accountCode), escape(accountCodeT), escape(softwareCode))
+	if err := c.call(ctx, "GetNumberingRange", body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Result, nil
+}
+
+// GetXmlByDocumentKey returns the XML of a document validated by DIAN.
+func (c *Client) GetXmlByDocumentKey(ctx context.Context, trackID string) (*EventResponse, error) {
+	var out struct {
+		Result EventResponse `xml:"GetXmlByDocumentKeyResult"`
+	}
+	body := fmt.Sprintf(`<wcf:GetXmlByDocumentKey><wcf:trackId>%s</wcf:trackId></wcf:GetXmlByDocumentKey>`, escape(trackID))
+	if err := c.call(ctx, "GetXmlByDocumentKey", body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Result, nil
+}
+
+// SendEventUpdateStatus sends a zipped, signed ApplicationResponse (RADIAN event).
+func (c *Client) SendEventUpdateStatus(ctx context.Context, content []byte) (*DianResponse, error) {
+	var out struct {
+		Result DianResponse `xml:"SendEventUpdateStatusResult"`
+	}
+	body := fmt.Sprintf(`<wcf:SendEventUpdateStatus><wcf:contentFile>%s</wcf:contentFile></wcf:SendEventUpdateStatus>`,
+		base64.StdEncoding.EncodeToString(content))
+	if err := c.call(ctx, "SendEventUpdateStatus", body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Result, nil
+}
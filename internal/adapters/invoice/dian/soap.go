@@ -0,0 +1,165 @@
+package dian
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	nsSOAP    = "http://www.w3.org/2003/05/soap-envelope"
+	nsWCF     = "http://wcf.dian.colombia"
+	nsWSA     = "http://www.w3.org/2005/08/addressing"
+	nsWSSE    = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	nsWSU     = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+	nsDS      = "http://www.w3.org/2000/09/xmldsig#"
+	nsExcC14N = "http://www.w3.org/2001/10/xml-exc-c14n#"
+
+	actionPrefix    = "http://wcf.dian.colombia/IWcfDianCustomerServices/"
+	x509TokenType   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-x509-token-profile-1.0#X509v3"
+	base64Encoding  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+	algRSASHA256    = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algSHA256       = "http://www.w3.org/2001/04/xmlenc#sha256"
+	timestampLayout = "2006-01-02T15:04:05.000Z"
+
+	// timestampTTL is the validity of the WS-Security timestamp. DIAN rejects
+	// requests whose timestamp has expired.
+	timestampTTL = 60 * time.Second
+)
+
+// envelope builds a SOAP 1.2 request for the DIAN web services, signed with the
+// WS-Security X.509 token profile DIAN requires: the signature covers the wsa:To
+// header with exclusive canonicalization and RSA-SHA256.
+//
+// The signed elements are written already in their exclusive canonical form
+// (namespace declarations sorted, no empty elements), so their digest can be
+// computed over the serialized bytes.
+func (c *Client) envelope(action, body string) ([]byte, error) {
+	now := c.now().UTC()
+	suffix := strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))
+	toID := "id-" + suffix
+	tokenID := "X509-" + suffix
+
+	to := fmt.Sprintf(`<wsa:To xmlns:soap="%s" xmlns:wcf="%s" xmlns:wsa="%s" xmlns:wsu="%s" wsu:Id="%s">%s</wsa:To>`,
+		nsSOAP, nsWCF, nsWSA, nsWSU, toID, escape(c.url))
+	toDigest := sha256.Sum256([]byte(to))
+
+	signedInfo := fmt.Sprintf(`<ds:SignedInfo xmlns:ds="%s" xmlns:soap="%s" xmlns:wcf="%s" xmlns:wsa="%s">`+
+		`<ds:CanonicalizationMethod Algorithm="%s"><ec:InclusiveNamespaces xmlns:ec="%s" PrefixList="wsa soap wcf"></ec:InclusiveNamespaces></ds:CanonicalizationMethod>`+
+		`<ds:SignatureMethod Algorithm="%s"></ds:SignatureMethod>`+
+		`<ds:Reference URI="#%s"><ds:Transforms><ds:Transform Algorithm="%s"><ec:InclusiveNamespaces xmlns:ec="%s" PrefixList="soap wcf"></ec:InclusiveNamespaces></ds:Transform></ds:Transforms>`+
+		`<ds:DigestMethod Algorithm="%s"></ds:DigestMethod><ds:DigestValue>%s</ds:DigestValue></ds:Reference></ds:SignedInfo>`,
+		nsDS, nsSOAP, nsWCF, nsWSA, nsExcC14N, nsExcC14N, algRSASHA256,
+		toID, nsExcC14N, nsExcC14N, algSHA256, base64.StdEncoding.EncodeToString(toDigest[:]))
+	signedInfoDigest := sha256.Sum256([]byte(signedInfo))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.cert.PrivateKey, crypto.SHA256, signedInfoDigest[:])
+	if err != nil {
+		return nil, fmt.Errorf("firmar solicitud SOAP: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<soap:Envelope xmlns:soap="%s" xmlns:wcf="%s">`, nsSOAP, nsWCF)
+	fmt.Fprintf(&buf, `<soap:Header xmlns:wsa="%s">`, nsWSA)
+	fmt.Fprintf(&buf, `<wsse:Security xmlns:wsse="%s" xmlns:wsu="%s">`, nsWSSE, nsWSU)
+	fmt.Fprintf(&buf, `<wsu:Timestamp wsu:Id="TS-%s"><wsu:Created>%s</wsu:Created><wsu:Expires>%s</wsu:Expires></wsu:Timestamp>`,
+		suffix, now.Format(timestampLayout), now.Add(timestampTTL).Format(timestampLayout))
+	fmt.Fprintf(&buf, `<wsse:BinarySecurityToken EncodingType="%s" ValueType="%s" wsu:Id="%s">%s</wsse:BinarySecurityToken>`,
+		base64Encoding, x509TokenType, tokenID, base64.StdEncoding.EncodeToString(c.cert.Leaf.Raw))
+	fmt.Fprintf(&buf, `<ds:Signature Id="SIG-%s" xmlns:ds="%s">`, suffix, nsDS)
+	buf.WriteString(signedInfo)
+	fmt.Fprintf(&buf, `<ds:SignatureValue>%s</ds:SignatureValue>`, base64.StdEncoding.EncodeToString(signature))
+	fmt.Fprintf(&buf, `<ds:KeyInfo Id="KI-%s"><wsse:SecurityTokenReference wsu:Id="STR-%s"><wsse:Reference URI="#%s" ValueType="%s"/></wsse:SecurityTokenReference></ds:KeyInfo>`,
+		suffix, suffix, tokenID, x509TokenType)
+	buf.WriteString(`</ds:Signature></wsse:Security>`)
+	fmt.Fprintf(&buf, `<wsa:Action>%s</wsa:Action>`, actionPrefix+action)
+	buf.WriteString(to)
+	buf.WriteString(`</soap:Header><soap:Body>`)
+	buf.WriteString(body)
+	buf.WriteString(`</soap:Body></soap:Envelope>`)
+	return buf.Bytes(), nil
+}
+
+// soapResponse captures the body of a SOAP 1.2 response. The operation result is
+// decoded from Content by the caller.
+type soapResponse struct {
+	Body struct {
+		Content []byte     `xml:",innerxml"`
+		Fault   *soapFault `xml:"Fault"`
+	} `xml:"Body"`
+}
+
+type soapFault struct {
+	Code   string `xml:"Code>Value"`
+	Reason string `xml:"Reason>Text"`
+}
+
+// call sends a signed request for action and decodes the operation result into out.
+func (c *Client) call(ctx context.Context, action, body string, out interface{}) error {
+	payload, err := c.envelope(action, body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", fmt.Sprintf(`application/soap+xml;charset=UTF-8;action="%s"`, actionPrefix+action))
+
+	c.log.Debug("Calling DIAN web service", "action", action, "url", c.url)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.log.Error("Failed to execute request to DIAN web service", "action", action, "error", err)
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body: %w", err)
+	}
+
+	var envelope soapResponse
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, truncate(string(data), 512))
+		}
+		return fmt.Errorf("decode SOAP response: %w", err)
+	}
+	if fault := envelope.Body.Fault; fault != nil {
+		c.log.Warn("DIAN web service returned a SOAP fault", "action", action, "code", fault.Code, "reason", fault.Reason)
+		return fmt.Errorf("DIAN %s: SOAP fault %s: %s", action, strings.TrimSpace(fault.Code), strings.TrimSpace(fault.Reason))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, truncate(string(data), 512))
+	}
+
+	if err := xml.Unmarshal(envelope.Body.Content, out); err != nil {
+		return fmt.Errorf("decode %s response: %w", action, err)
+	}
+	return nil
+}
+
+// escape escapes character data the way canonical XML writes it, so escaped
+// values can be placed inside the signed elements.
+var escape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;").Replace
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
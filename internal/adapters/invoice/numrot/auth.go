@@ -10,9 +10,10 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/infrastructure/cache"
+	"3tcapital/goclonacion/internal/infrastructure/cache"
 )
 
 // HTTPClient interface allows using both standard and traced HTTP clients.
@@ -21,15 +22,42 @@ type HTTPClient interface {
 }
 
 // AuthManager handles Numrot authentication with token caching.
+// Each credential set (username) has its own token cache. Tokens are refreshed in
+// the background when they are about to expire and concurrent refreshes of the
+// same credential set share a single request to the auth endpoint.
 type AuthManager struct {
-	baseURL  string
-	username string
-	password string
-	tokenTTL time.Duration
-	cache    *cache.TokenCache
-	client   HTTPClient
-	log      *slog.Logger
-	mu       sync.Mutex // Protects token refresh to avoid concurrent requests
+	baseURL       string
+	username      string
+	password      string
+	tokenTTL      time.Duration
+	refreshBefore time.Duration // Refresh proactively when the token expires within this window
+	cache         *cache.TokenCache
+	client        HTTPClient
+	log           *slog.Logger
+	mu            sync.Mutex // Protects sessions
+	sessions      map[string]*tokenSession
+	refreshes     atomic.Int64 // Successful token refreshes, all credential sets
+	refreshErrors atomic.Int64 // Failed token refreshes, all credential sets
+}
+
+// AuthStats holds the token refresh counters of an AuthManager.
+type AuthStats struct {
+	Refreshes     int64
+	RefreshErrors int64
+}
+
+// tokenSession holds the token of a credential set and its in-flight refresh.
+type tokenSession struct {
+	cache  *cache.TokenCache
+	mu     sync.Mutex   // Protects flight
+	flight *refreshCall // nil when no refresh is in progress
+}
+
+// refreshCall is a token refresh shared by every caller of the same credential set.
+type refreshCall struct {
+	done  chan struct{}
+	token string
+	err   error
 }
 
 // tokenRequest represents the authentication request payload.
@@ -38,59 +66,129 @@ type tokenRequest struct {
 	Password string `json:"password"`
 }
 
-
 // NewAuthManager creates a new Numrot authentication manager.
+// username/password are the default credential set, used for OFEs without credentials of their own.
 func NewAuthManager(baseURL, username, password string, tokenTTL time.Duration, client HTTPClient, log *slog.Logger) *AuthManager {
+	defaultCache := cache.NewTokenCache()
 	return &AuthManager{
-		baseURL:  baseURL,
-		username: username,
-		password: password,
-		tokenTTL: tokenTTL,
-		cache:    cache.NewTokenCache(),
-		client:   client,
-		log:      log,
+		baseURL:       baseURL,
+		username:      username,
+		password:      password,
+		tokenTTL:      tokenTTL,
+		refreshBefore: tokenTTL / 10,
+		cache:         defaultCache,
+		client:        client,
+		log:           log,
+		sessions: map[string]*tokenSession{
+			username: {cache: defaultCache},
+		},
+	}
+}
+
+// WithRefreshBefore sets how long before TokenTTL expires the token is refreshed
+// in the background. Values <= 0 or >= TokenTTL keep the default (10% of TokenTTL).
+func (a *AuthManager) WithRefreshBefore(d time.Duration) *AuthManager {
+	if d > 0 && d < a.tokenTTL {
+		a.refreshBefore = d
 	}
+	return a
+}
+
+// DefaultCredentials returns the default username/password credential set.
+func (a *AuthManager) DefaultCredentials() Credentials {
+	return Credentials{Username: a.username, Password: a.password}
 }
 
-// GetToken returns a valid authentication token, refreshing if necessary.
+// GetToken returns a valid token for the default credential set, refreshing if necessary.
 func (a *AuthManager) GetToken(ctx context.Context) (string, error) {
-	a.log.Debug("Getting Numrot token", "username", a.username)
+	return a.TokenFor(ctx, a.DefaultCredentials())
+}
+
+// TokenFor returns a valid token for the given credential set, refreshing if necessary.
+func (a *AuthManager) TokenFor(ctx context.Context, creds Credentials) (string, error) {
+	a.log.Debug("Getting Numrot token", "username", creds.Username)
+	session := a.session(creds.Username)
+
 	// Try to get from cache first
-	if token, ok := a.cache.Get(); ok {
+	if token, ok := session.cache.Get(); ok {
+		if session.cache.Remaining() <= a.refreshBefore {
+			// About to expire: refresh in background and keep using the current token
+			a.startRefresh(ctx, session, creds)
+		}
 		return token, nil
 	}
 
-	// Token expired or not cached, need to refresh
-	// Use mutex to prevent multiple concurrent refresh requests
+	// Token expired or not cached: wait for the (shared) refresh
+	call := a.startRefresh(ctx, session, creds)
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if call.err != nil {
+		return "", fmt.Errorf("numrot authentication failed: %w", call.err)
+	}
+	return call.token, nil
+}
+
+// session returns the token session of a credential set, creating it if needed.
+func (a *AuthManager) session(username string) *tokenSession {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	// Double-check after acquiring lock (another goroutine might have refreshed)
-	if token, ok := a.cache.Get(); ok {
-		return token, nil
+	s, ok := a.sessions[username]
+	if !ok {
+		s = &tokenSession{cache: cache.NewTokenCache()}
+		a.sessions[username] = s
 	}
+	return s
+}
 
-	// Perform authentication
-	token, err := a.authenticate(ctx)
-	if err != nil {
-		a.log.Error("Numrot authentication failed", "error", err)
-		return "", fmt.Errorf("numrot authentication failed: %w", err)
-	}
+// startRefresh starts a token refresh for the session unless one is already in progress,
+// and returns the in-flight call. The refresh is detached from the caller's cancellation
+// so that other callers waiting on it are not affected.
+func (a *AuthManager) startRefresh(ctx context.Context, s *tokenSession, creds Credentials) *refreshCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Cache the token
-	a.cache.Set(token, a.tokenTTL)
-	a.log.Debug("Numrot token refreshed and cached", "ttl", a.tokenTTL)
+	if s.flight != nil {
+		return s.flight
+	}
 
-	return token, nil
+	call := &refreshCall{done: make(chan struct{})}
+	s.flight = call
+	go func() {
+		token, err := a.authenticateWith(context.WithoutCancel(ctx), creds)
+		if err != nil {
+			a.refreshErrors.Add(1)
+			a.log.Error("Numrot authentication failed", "username", creds.Username, "error", err)
+		} else {
+			a.refreshes.Add(1)
+			s.cache.Set(token, a.tokenTTL)
+			a.log.Debug("Numrot token refreshed and cached", "username", creds.Username, "ttl", a.tokenTTL)
+		}
+
+		s.mu.Lock()
+		call.token, call.err = token, err
+		s.flight = nil
+		s.mu.Unlock()
+		close(call.done)
+	}()
+	return call
 }
 
-// authenticate performs the actual authentication request to Numrot.
+// authenticate performs the authentication request with the default credential set.
 func (a *AuthManager) authenticate(ctx context.Context) (string, error) {
+	return a.authenticateWith(ctx, a.DefaultCredentials())
+}
+
+// authenticateWith performs the actual authentication request to Numrot.
+func (a *AuthManager) authenticateWith(ctx context.Context, creds Credentials) (string, error) {
 	url := fmt.Sprintf("%s/v2/api/Token", a.baseURL)
 
 	reqBody := tokenRequest{
-		Username: a.username,
-		Password: a.password,
+		Username: creds.Username,
+		Password: creds.Password,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -130,7 +228,20 @@ func (a *AuthManager) authenticate(ctx context.Context) (string, error) {
 	return token, nil
 }
 
-// ClearToken removes the cached token, forcing a refresh on next request.
+// Stats returns the token refresh counters.
+func (a *AuthManager) Stats() AuthStats {
+	return AuthStats{
+		Refreshes:     a.refreshes.Load(),
+		RefreshErrors: a.refreshErrors.Load(),
+	}
+}
+
+// ClearToken removes the cached token of the default credential set, forcing a refresh on next request.
 func (a *AuthManager) ClearToken() {
 	a.cache.Clear()
 }
+
+// ClearTokenFor removes the cached token of a credential set, forcing a refresh on next request.
+func (a *AuthManager) ClearTokenFor(creds Credentials) {
+	a.session(creds.Username).cache.Clear()
+}
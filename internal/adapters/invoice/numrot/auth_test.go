@@ -6,10 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 func TestNewAuthManager(t *testing.T) {
@@ -242,3 +243,121 @@ func TestAuthManager_ClearToken(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestAuthManager_TokenFor_PerCredentialSet(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]string
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		mu.Lock()
+		calls[reqBody["username"]]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("token-" + reqBody["username"]))
+	}))
+	defer server.Close()
+
+	auth := NewAuthManager(server.URL, "user", "pass", 1*time.Hour, server.Client(), testutil.NewTestLogger())
+	other := Credentials{Username: "otro", Password: "clave"}
+
+	for i := 0; i < 3; i++ {
+		if token, err := auth.GetToken(context.Background()); err != nil || token != "token-user" {
+			t.Fatalf("unexpected default token %q, err %v", token, err)
+		}
+		if token, err := auth.TokenFor(context.Background(), other); err != nil || token != "token-otro" {
+			t.Fatalf("unexpected token %q, err %v", token, err)
+		}
+	}
+
+	// Clearing one credential set does not affect the other
+	auth.ClearTokenFor(other)
+	if _, err := auth.TokenFor(context.Background(), other); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := auth.GetToken(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["user"] != 1 || calls["otro"] != 2 {
+		t.Errorf("expected one token cache per credential set, got calls %v", calls)
+	}
+}
+
+func TestAuthManager_TokenFor_ProactiveRefresh(t *testing.T) {
+	var mu sync.Mutex
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		callCount++
+		n := callCount
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("token-" + string(rune('0'+n))))
+	}))
+	defer server.Close()
+
+	auth := NewAuthManager(server.URL, "user", "pass", 200*time.Millisecond, server.Client(), testutil.NewTestLogger()).
+		WithRefreshBefore(150 * time.Millisecond)
+
+	if token, _ := auth.GetToken(context.Background()); token != "token-1" {
+		t.Fatalf("expected first token, got %q", token)
+	}
+
+	// Inside the refresh window: the current token is returned while a new one is fetched
+	time.Sleep(80 * time.Millisecond)
+	if token, _ := auth.GetToken(context.Background()); token != "token-1" {
+		t.Fatalf("expected current token while refreshing, got %q", token)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		token, _ := auth.cache.Get()
+		if token == "token-2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected token to be refreshed in background, got %q", token)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAuthManager_WithRefreshBefore_Invalid(t *testing.T) {
+	auth := NewAuthManager("http://localhost", "user", "pass", time.Hour, &http.Client{}, testutil.NewTestLogger())
+	auth.WithRefreshBefore(2 * time.Hour)
+	if auth.refreshBefore != 6*time.Minute {
+		t.Errorf("expected default refresh window of 10%% of TTL, got %v", auth.refreshBefore)
+	}
+}
+
+func TestAuthManager_Stats(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("token"))
+	}))
+	defer server.Close()
+
+	auth := NewAuthManager(server.URL, "user", "pass", time.Hour, server.Client(), testutil.NewTestLogger())
+
+	if _, err := auth.GetToken(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	auth.ClearToken()
+	fail.Store(true)
+	if _, err := auth.GetToken(context.Background()); err == nil {
+		t.Fatal("expected error when authentication fails")
+	}
+
+	stats := auth.Stats()
+	if stats.Refreshes != 1 || stats.RefreshErrors != 1 {
+		t.Errorf("expected 1 refresh and 1 refresh error, got %+v", stats)
+	}
+}
@@ -127,6 +127,14 @@ func (cb *CircuitBreaker) Execute(ctx context.Context, fn func() error) error {
 	return err
 }
 
+// Allows reports whether a request would be let through: the circuit is closed,
+// half-open, or open with the cooldown period already elapsed.
+func (cb *CircuitBreaker) Allows() bool {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.state != CircuitBreakerOpen || time.Since(cb.lastStateChange) >= cb.cooldownPeriod
+}
+
 // State returns the current circuit breaker state
 func (cb *CircuitBreaker) State() CircuitBreakerState {
 	cb.mu.RLock()
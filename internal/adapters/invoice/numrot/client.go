@@ -14,12 +14,22 @@ import (
 	"sync"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/acquirer"
-	"3tcapital/ms_facturacion_core/internal/core/event"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	"3tcapital/ms_facturacion_core/internal/core/resolution"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"3tcapital/goclonacion/internal/core/acquirer"
+	"3tcapital/goclonacion/internal/core/codelist"
+	"3tcapital/goclonacion/internal/core/currency"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/resolution"
 )
 
+// tracer records the spans of the Numrot calls.
+var tracer = otel.Tracer("3tcapital/goclonacion/internal/adapters/invoice/numrot")
+
 // getMapKeys returns all keys from a map as a slice of strings
 func getMapKeys(m map[string]interface{}) []string {
 	keys := make([]string, 0, len(m))
@@ -111,6 +121,7 @@ type Client struct {
 	batchSize            int
 	maxConcurrentBatches int
 	circuitBreaker       *CircuitBreaker
+	credentials          CredentialStore // Optional: nil uses the default credentials for every OFE
 	// Resolution query settings (for testing environments)
 	resolutionsEnabled   bool
 	hardcodedInvoiceAuth string
@@ -195,6 +206,39 @@ func NewClientWithDSBaseURL(baseURL, dsBaseURL string, auth *AuthManager, httpCl
 	}
 }
 
+// CircuitOpen reports whether requests to Numrot are failing fast because the
+// circuit breaker is open.
+func (c *Client) CircuitOpen() bool {
+	return c.circuitBreaker != nil && !c.circuitBreaker.Allows()
+}
+
+// ClientStats aggregates the runtime statistics of the client's circuit breaker,
+// limiters and token manager.
+type ClientStats struct {
+	Circuit     CircuitBreakerStats
+	Limiter     LimiterStats
+	RateLimiter RateLimiterStats
+	Auth        AuthStats
+}
+
+// Stats returns the current statistics of the client.
+func (c *Client) Stats() ClientStats {
+	var stats ClientStats
+	if c.circuitBreaker != nil {
+		stats.Circuit = c.circuitBreaker.Stats()
+	}
+	if c.concurrencyLimiter != nil {
+		stats.Limiter = c.concurrencyLimiter.Stats()
+	}
+	if c.rateLimiter != nil {
+		stats.RateLimiter = c.rateLimiter.Stats()
+	}
+	if c.auth != nil {
+		stats.Auth = c.auth.Stats()
+	}
+	return stats
+}
+
 // numrotResolutionResponse represents the response structure from Numrot API.
 type numrotResolutionResponse struct {
 	OperationCode        string              `json:"OperationCode"`
@@ -280,7 +324,7 @@ func (c *Client) GetResolutions(ctx context.Context, nit string) ([]resolution.R
 	}
 
 	// Normal flow: query resolutions from API
-	token, err := c.auth.GetToken(ctx)
+	token, creds, err := c.tokenFor(ctx, nit)
 	if err != nil {
 		c.log.Error("Failed to get Numrot authentication token", "error", err)
 		return nil, fmt.Errorf("get authentication token: %w", err)
@@ -328,7 +372,7 @@ func (c *Client) GetResolutions(ctx context.Context, nit string) ([]resolution.R
 
 	if resp.StatusCode == http.StatusUnauthorized {
 		// Token might be expired, clear cache and retry once
-		c.auth.ClearToken()
+		c.auth.ClearTokenFor(creds)
 		c.log.Warn("Token expired or invalid, clearing cache", "status", resp.StatusCode, "body", string(body))
 		return nil, fmt.Errorf("authentication failed: token expired or invalid")
 	}
@@ -482,15 +526,19 @@ type numrotReceivedDocument struct {
 
 // GetDocuments retrieves documents/invoices from Numrot Radian API.
 func (c *Client) GetDocuments(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
-	if c.key == "" || c.secret == "" {
+	creds, err := c.credentialsFor(ctx, query.CompanyNit)
+	if err != nil {
+		return nil, err
+	}
+	if creds.Key == "" || creds.Secret == "" {
 		return nil, fmt.Errorf("key and secret are required for document queries")
 	}
 
 	url := fmt.Sprintf("%s/api/Radian/GetInfoDocument", c.radianURL)
 
 	reqBody := numrotDocumentRequest{
-		Key:         c.key,
-		Secret:      c.secret,
+		Key:         creds.Key,
+		Secret:      creds.Secret,
 		CompanyNit:  query.CompanyNit,
 		InitialDate: query.InitialDate,
 		FinalDate:   query.FinalDate,
@@ -570,15 +618,19 @@ func (c *Client) GetDocuments(ctx context.Context, query invoice.DocumentQuery)
 
 // GetDocumentByNumber retrieves a document/invoice by document number from Numrot Radian API.
 func (c *Client) GetDocumentByNumber(ctx context.Context, query invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
-	if c.key == "" || c.secret == "" {
+	creds, err := c.credentialsFor(ctx, query.CompanyNit)
+	if err != nil {
+		return nil, err
+	}
+	if creds.Key == "" || creds.Secret == "" {
 		return nil, fmt.Errorf("key and secret are required for document queries")
 	}
 
 	url := fmt.Sprintf("%s/api/Radian/GetDocumentByNumber", c.radianURL)
 
 	reqBody := numrotDocumentByNumberRequest{
-		Key:            c.key,
-		Secret:         c.secret,
+		Key:            creds.Key,
+		Secret:         creds.Secret,
 		CompanyNit:     query.CompanyNit,
 		DocumentNumber: query.DocumentNumber,
 		SupplierNit:    query.SupplierNit,
@@ -658,15 +710,19 @@ func (c *Client) GetDocumentByNumber(ctx context.Context, query invoice.Document
 
 // GetReceivedDocuments retrieves received documents/invoices from Numrot Radian API.
 func (c *Client) GetReceivedDocuments(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
-	if c.key == "" || c.secret == "" {
+	creds, err := c.credentialsFor(ctx, query.CompanyNit)
+	if err != nil {
+		return nil, err
+	}
+	if creds.Key == "" || creds.Secret == "" {
 		return nil, fmt.Errorf("key and secret are required for document queries")
 	}
 
 	url := fmt.Sprintf("%s/api/Radian/DocumentsReceived", c.radianURL)
 
 	reqBody := numrotDocumentRequest{
-		Key:         c.key,
-		Secret:      c.secret,
+		Key:         creds.Key,
+		Secret:      creds.Secret,
 		CompanyNit:  query.CompanyNit,
 		InitialDate: query.InitialDate,
 		FinalDate:   query.FinalDate,
@@ -788,8 +844,8 @@ func (c *Client) GetReceivedDocuments(ctx context.Context, query invoice.Documen
 // Este método consulta el endpoint /api/DocumentInfo/{nit}/{cufe} que retorna información
 // detallada del documento identificado por su CUFE.
 func (c *Client) GetDocumentInfo(ctx context.Context, nit, cufe string) (*numrotDocumentInfoResponse, error) {
-	// 1. Obtener token de autenticación (reutiliza AuthManager existente)
-	token, err := c.auth.GetToken(ctx)
+	// 1. Obtener token de autenticación con las credenciales del OFE
+	token, creds, err := c.tokenFor(ctx, nit)
 	if err != nil {
 		c.log.Error("Failed to get Numrot auth token", "error", err)
 		return nil, fmt.Errorf("get authentication token: %w", err)
@@ -842,7 +898,7 @@ func (c *Client) GetDocumentInfo(ctx context.Context, nit, cufe string) (*numrot
 
 	// 8. Manejar códigos de estado HTTP
 	if resp.StatusCode == http.StatusUnauthorized {
-		c.auth.ClearToken()
+		c.auth.ClearTokenFor(creds)
 		c.log.Warn("Token expired or invalid, clearing cache", "status", resp.StatusCode)
 		return nil, fmt.Errorf("authentication failed: token expired")
 	}
@@ -936,8 +992,8 @@ type NumrotSearchEstadosDIANData struct {
 // detallada del documento incluyendo estados y eventos.
 // Host: https://numrotapiprueba.net (configured via NUMROT_BASE_URL)
 func (c *Client) SearchEstadosDIAN(ctx context.Context, nit, documento string) (*numrotSearchEstadosDIANResponse, error) {
-	// 1. Obtener token de autenticación (reutiliza AuthManager existente)
-	token, err := c.auth.GetToken(ctx)
+	// 1. Obtener token de autenticación con las credenciales del OFE
+	token, creds, err := c.tokenFor(ctx, nit)
 	if err != nil {
 		c.log.Error("Failed to get Numrot auth token", "error", err)
 		return nil, fmt.Errorf("get authentication token: %w", err)
@@ -989,7 +1045,7 @@ func (c *Client) SearchEstadosDIAN(ctx context.Context, nit, documento string) (
 
 	// 8. Manejar códigos de estado HTTP
 	if resp.StatusCode == http.StatusUnauthorized {
-		c.auth.ClearToken()
+		c.auth.ClearTokenFor(creds)
 		c.log.Warn("Token expired or invalid, clearing cache", "status", resp.StatusCode)
 		return nil, fmt.Errorf("authentication failed: token expired")
 	}
@@ -1387,7 +1443,11 @@ type numrotSetEventErrorResponse struct {
 
 // RegisterEvent registers a Radian event for a document.
 func (c *Client) RegisterEvent(ctx context.Context, evt event.Event, emisorNit, razonSocial string) (*invoice.EventRegistrationResult, error) {
-	if c.key == "" || c.secret == "" {
+	creds, err := c.credentialsFor(ctx, emisorNit)
+	if err != nil {
+		return nil, err
+	}
+	if creds.Key == "" || creds.Secret == "" {
 		return nil, fmt.Errorf("key and secret are required for event registration")
 	}
 
@@ -1403,8 +1463,8 @@ func (c *Client) RegisterEvent(ctx context.Context, evt event.Event, emisorNit,
 
 	// Build request
 	reqBody := numrotSetEventRequest{
-		Key:                     c.key,
-		Secret:                  c.secret,
+		Key:                     creds.Key,
+		Secret:                  creds.Secret,
 		EmisorNit:               emisorNit,
 		RazonSocial:             razonSocial,
 		DocumentoNumeroCompleto: evt.DocumentNumber,
@@ -1554,7 +1614,9 @@ type numrotDiscrepancyResponse struct {
 
 // numrotInvoiceDocumentReference represents reference to original invoice for NC/ND.
 type numrotInvoiceDocumentReference struct {
-	ID string `json:"ID"`
+	ID        string `json:"ID"`
+	UUID      string `json:"UUID,omitempty"`
+	IssueDate string `json:"IssueDate,omitempty"`
 }
 
 // numrotAdditionalDocumentReference represents additional document references.
@@ -1911,6 +1973,14 @@ func (c *Client) registerDocumentsConcurrent(ctx context.Context, documents []in
 				// Process each document with proper cleanup using anonymous function
 				// This ensures defer is executed per-document, not per-worker
 				func() {
+					// Span of the document, covering the limiter waits and the Numrot call
+					ctx, span := tracer.Start(ctx, "numrot.registerDocument", trace.WithAttributes(
+						attribute.String("document.type", documentType),
+						attribute.String("document.prefix", document.RfaPrefijo),
+						attribute.String("document.consecutivo", document.CdoConsecutivo),
+					))
+					defer span.End()
+
 					// Acquire rate limiter token FIRST (before concurrency limiter)
 					// This prevents too many goroutines from waiting on rate limit
 					if c.rateLimiter != nil {
@@ -2398,6 +2468,7 @@ func (c *Client) parseSendDIANResponse(body []byte, documents []invoice.OpenETLD
 				CdoConsecutivo:     consecutivo,
 				FechaProcesamiento: fechaProcesamiento,
 				HoraProcesamiento:  horaProcesamiento,
+				CUFE:               docResp.Uuid,
 				XmlBase64:          docResp.Document,
 				PdfBase64:          docResp.Pdfdocument,
 			})
@@ -2684,7 +2755,7 @@ func (c *Client) parseSendDIANResponse(body []byte, documents []invoice.OpenETLD
 }
 
 // registerDSDocument registers a DS (Documento Soporte) document with Numrot documentSinc API.
-func (c *Client) registerDSDocument(ctx context.Context, doc invoice.OpenETLDocument, token string) (*invoice.DocumentRegistrationResponse, error) {
+func (c *Client) registerDSDocument(ctx context.Context, doc invoice.OpenETLDocument, token string, creds Credentials) (*invoice.DocumentRegistrationResponse, error) {
 	// Transform document to Numrot format
 	numrotInv, err := c.transformOpenETLToNumrot(ctx, doc, "DS")
 	if err != nil {
@@ -2803,7 +2874,7 @@ func (c *Client) registerDSDocument(ctx context.Context, doc invoice.OpenETLDocu
 	c.log.Debug("Numrot documentSinc API response", "status", resp.StatusCode, "body_length", len(body))
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		c.auth.ClearToken()
+		c.auth.ClearTokenFor(creds)
 		c.log.Warn("Token expired or invalid, clearing cache", "status", resp.StatusCode, "body_length", len(body))
 		return nil, fmt.Errorf("authentication failed: token expired or invalid")
 	}
@@ -2843,7 +2914,12 @@ func (c *Client) registerDocumentsSingleBatch(ctx context.Context, req invoice.D
 		}
 	}
 
-	token, err := c.auth.GetToken(ctx)
+	// Select the credential set of the issuing OFE (for DS the OFE is adq_identificacion)
+	issuer := documents[0].OfeIdentificacion
+	if documentType == "DS" {
+		issuer = documents[0].AdqIdentificacion
+	}
+	token, creds, err := c.tokenFor(ctx, issuer)
 	if err != nil {
 		c.log.Error("Failed to get Numrot authentication token", "error", err)
 		return nil, fmt.Errorf("get authentication token: %w", err)
@@ -2851,7 +2927,7 @@ func (c *Client) registerDocumentsSingleBatch(ctx context.Context, req invoice.D
 
 	// DS documents use a different endpoint (SendEnr) than FC/NC/ND (SendDIAN)
 	if documentType == "DS" {
-		return c.registerDSDocument(ctx, documents[0], token)
+		return c.registerDSDocument(ctx, documents[0], token, creds)
 	}
 
 	// Transform documents to Numrot format
@@ -2980,7 +3056,7 @@ func (c *Client) registerDocumentsSingleBatch(ctx context.Context, req invoice.D
 
 	if resp.StatusCode == http.StatusUnauthorized {
 		// Token might be expired, clear cache
-		c.auth.ClearToken()
+		c.auth.ClearTokenFor(creds)
 		c.log.Warn("Token expired or invalid, clearing cache", "status", resp.StatusCode, "body_length", len(body))
 		return nil, fmt.Errorf("authentication failed: token expired or invalid")
 	}
@@ -3126,7 +3202,10 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 	// For DS documents, always include PaymentExchangeRate
 	// For FC/NC/ND, only include if different currencies are used
 	var paymentExchangeRate *numrotPaymentExchangeRate
-	if documentType == "DS" {
+	if rate := foreignExchangeRate(doc); rate != nil {
+		// Foreign currency: convert to COP with the TRM of the document
+		paymentExchangeRate = rate
+	} else if documentType == "DS" {
 		// DS: Always include PaymentExchangeRate with default values
 		// Use purchase date from first item if available, otherwise use document date
 		exchangeDate := doc.CdoFecha
@@ -3224,7 +3303,7 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 		return numrotInvoice{}, fmt.Errorf("ofe_identificacion is required")
 	}
 
-	supplierBaseNIT, supplierDV := parseNITWithDV(doc.OfeIdentificacion)
+	supplierBaseNIT, supplierDV := identification.Split(doc.OfeIdentificacion)
 
 	// Determine schemeID: use DV if provided, otherwise use environment-based value
 	schemeID := "2" // Default to test
@@ -3255,21 +3334,11 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 		providerName := getStringOrDefault(doc.AdqRazonSocial, doc.OfeIdentificacion)
 		providerCompanyID := doc.OfeIdentificacion // This is pro_identificacion from the request
 
-		// Build OFE location (hardcoded) for DS supplier party
-		// PhysicalLocation should use OFE hardcoded data, not provider data
-		ofeLocation := &numrotPhysicalLocation{
-			ID:                   "05380",
-			CityName:             "LA ESTRELLA",
-			PostalZone:           "55468",
-			CountrySubentity:     "ANTIOQUIA",
-			CountrySubentityCode: "05",
-			Line:                 "CLL 50 - 96",
-			IdentificationCode:   "CO",
-			Name:                 "Colombia",
-		}
+		// PhysicalLocation uses the OFE data enriched from the OFE registry, not provider data
+		ofeLocation := buildSupplierPhysicalLocation(doc)
 
 		// Parse provider NIT with DV if available
-		providerBaseNIT, _ := parseNITWithDV(providerCompanyID)
+		providerBaseNIT, _ := identification.Split(providerCompanyID)
 		if providerBaseNIT == "" {
 			providerBaseNIT = providerCompanyID
 		}
@@ -3312,7 +3381,7 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 				Name:           "",
 				Telephone:      "",
 				Telefax:        "",
-				ElectronicMail: "fact.electronica.positiva@3tcapital.co",
+				ElectronicMail: getStringValue(doc.OfeCorreo),
 			}
 		}
 
@@ -3326,7 +3395,7 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 				CompanyID:           supplierCompanyID,
 				SchemeID:            &schemeID,
 				SchemeName:          &supplierSchemeName,
-				TaxLevelCode:        "R-99-PN",
+				TaxLevelCode:        ofeTaxLevelCode(doc),
 				RegistrationAddress: supplierLocation,
 				TaxScheme: numrotTaxScheme{
 					ID:   "01",
@@ -3345,20 +3414,20 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 	}
 
 	// Build customer party with location data (fixes DIAN FAJ25, FAK48, FAJ43b)
-	// For DS documents, use OFE data (hardcoded "Positiva")
+	// For DS documents, use OFE data (from ofe_* fields)
 	// For FC/NC/ND documents, use acquirer data
 	var customerParty numrotAccountingParty
 	if documentType == "DS" {
-		// DS: Use OFE data (hardcoded "Positiva")
+		// DS: Use OFE data (from ofe_* fields)
 		// Note: For DS, adq_identificacion in request is ofe_identificacion
 		// So we use doc.AdqIdentificacion which contains the ofe_identificacion
-		ofeName := "Positiva"
 		ofeCompanyID := doc.AdqIdentificacion // This is ofe_identificacion from the request
+		ofeName := getStringOrDefault(doc.OfeRazonSocial, ofeCompanyID)
 		ofeSchemeID := "6"
 		ofeSchemeName := "31"
 
 		// Parse OFE NIT with DV if available
-		ofeBaseNIT, ofeDV := parseNITWithDV(ofeCompanyID)
+		ofeBaseNIT, ofeDV := identification.Split(ofeCompanyID)
 		if ofeBaseNIT == "" {
 			ofeBaseNIT = ofeCompanyID
 		}
@@ -3376,7 +3445,7 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 				CompanyID:        ofeBaseNIT,
 				SchemeID:         &ofeSchemeID,
 				SchemeName:       &ofeSchemeName,
-				TaxLevelCode:     "R-99-PN",
+				TaxLevelCode:     ofeTaxLevelCode(doc),
 				// Omit RegistrationAddress for DS
 				TaxScheme: numrotTaxScheme{
 					ID:   "ZZ",
@@ -3494,9 +3563,17 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 		notes = doc.Note
 	}
 
-	// Build InvoicePeriod for NC without reference (CustomizationID "22")
+	// Build InvoicePeriod for NC without reference (CustomizationID "22"): the period of the
+	// document, or the configured one when the document has none
 	var invoicePeriod *numrotDocumentInvoicePeriod
-	if customizationID == "22" && c.ncInvoicePeriodStartDate != "" && c.ncInvoicePeriodEndDate != "" {
+	if p := doc.CdoPeriodoFacturacion; customizationID == "22" && p != nil && p.FechaInicio != "" && p.FechaFin != "" {
+		invoicePeriod = &numrotDocumentInvoicePeriod{
+			StartDate: p.FechaInicio,
+			StartTime: p.HoraInicio,
+			EndDate:   p.FechaFin,
+			EndTime:   p.HoraFin,
+		}
+	} else if customizationID == "22" && c.ncInvoicePeriodStartDate != "" && c.ncInvoicePeriodEndDate != "" {
 		invoicePeriod = &numrotDocumentInvoicePeriod{
 			StartDate: c.ncInvoicePeriodStartDate,
 			StartTime: c.ncInvoicePeriodStartTime,
@@ -3533,7 +3610,9 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 
 			// Construir InvoiceDocumentReference
 			invoiceDocumentReference = &numrotInvoiceDocumentReference{
-				ID: referenceID,
+				ID:        referenceID,
+				UUID:      doc.FacturaReferencia.CufeFC,
+				IssueDate: doc.FacturaReferencia.FechaEmisionFC,
 			}
 		}
 	}
@@ -3568,6 +3647,16 @@ func (c *Client) transformOpenETLToNumrot(ctx context.Context, doc invoice.OpenE
 		numrotInv.TaxTotal = taxTotals
 	}
 
+	// Contingency invoices (03/04) reference their contingency document
+	if ref := doc.CdoDocumentoContingencia; ref != nil && ref.Numero != "" {
+		additionalRef := numrotAdditionalDocumentReference{ID: ref.Numero, DocumentTypeCode: ref.Tipo}
+		if ref.Fecha != "" {
+			issueDate := ref.Fecha
+			additionalRef.IssueDate = &issueDate
+		}
+		numrotInv.AdditionalDocumentReference = []numrotAdditionalDocumentReference{additionalRef}
+	}
+
 	return numrotInv, nil
 }
 
@@ -3641,7 +3730,7 @@ func (c *Client) buildTaxTotals(doc invoice.OpenETLDocument, documentType string
 			Percent:       percent,
 			CurrencyID:    doc.MonCodigo,
 			ID:            taxCode,
-			Name:          c.getTaxName(taxCode),
+			Name:          codelist.TaxName(taxCode),
 		}
 
 		taxMap[taxCode].TaxSubtotal = append(taxMap[taxCode].TaxSubtotal, taxSubtotal)
@@ -3731,7 +3820,7 @@ func (c *Client) buildInvoiceLine(item invoice.OpenETLItem, doc invoice.OpenETLD
 								Percent:       percent,
 								CurrencyID:    doc.MonCodigo,
 								ID:            tributo.TriCodigo,
-								Name:          c.getTaxName(tributo.TriCodigo),
+								Name:          codelist.TaxName(tributo.TriCodigo),
 							},
 						},
 					}
@@ -3761,7 +3850,7 @@ func (c *Client) buildInvoiceLine(item invoice.OpenETLItem, doc invoice.OpenETLD
 	}
 
 	// Map unit code from OpenETL to UBL standard codes
-	unitCode := mapUnitCode(item.UndCodigo)
+	unitCode := codelist.UnitCode(item.UndCodigo)
 
 	// Build InvoicePeriod for DS items if ddo_fecha_compra is present
 	var invoicePeriod *numrotInvoicePeriod
@@ -3812,24 +3901,6 @@ func (c *Client) buildInvoiceLine(item invoice.OpenETLItem, doc invoice.OpenETLD
 	return invoiceLine
 }
 
-// getTaxName returns the tax name for a given tax code.
-func (c *Client) getTaxName(taxCode string) string {
-	taxNames := map[string]string{
-		"01": "IVA",
-		"02": "Consumo",
-		"03": "ICA",
-		"04": "INC",
-		"05": "ReteIVA",
-		"06": "ReteFuente",
-		"07": "ReteICA",
-	}
-
-	if name, exists := taxNames[taxCode]; exists {
-		return name
-	}
-	return "Impuesto"
-}
-
 // getStringValue returns the string value from a pointer, or empty string if nil.
 func getStringValue(ptr *string) string {
 	if ptr == nil {
@@ -3859,33 +3930,6 @@ func normalizeMonetaryValue(value string) string {
 	return value
 }
 
-// mapUnitCode maps OpenETL unit codes to UBL standard codes.
-func mapUnitCode(openETLCode string) string {
-	// Map common Colombian unit codes to UBL codes
-	unitCodeMap := map[string]string{
-		"UN":  "94",  // Unit/Unidad
-		"KG":  "KGM", // Kilogram
-		"GR":  "GRM", // Gram
-		"LT":  "LTR", // Liter
-		"MT":  "MTR", // Meter
-		"M2":  "MTK", // Square meter
-		"M3":  "MTQ", // Cubic meter
-		"HR":  "HUR", // Hour
-		"MIN": "MIN", // Minute
-		"DIA": "DAY", // Day
-		"PAR": "PR",  // Pair
-		"DOC": "DZN", // Dozen (12 units)
-		"CM":  "CMT", // Centimeter
-		"MM":  "MMT", // Millimeter
-	}
-
-	if ublCode, exists := unitCodeMap[openETLCode]; exists {
-		return ublCode
-	}
-	// If not in map, return as is (might need validation)
-	return openETLCode
-}
-
 // getStringOrDefault returns the pointer value or default if nil.
 func getStringOrDefault(ptr *string, defaultVal string) string {
 	if ptr == nil || *ptr == "" {
@@ -3949,7 +3993,7 @@ func buildSupplierPhysicalLocation(doc invoice.OpenETLDocument) *numrotPhysicalL
 	location := &numrotPhysicalLocation{
 		ID:                   getStringValue(doc.OfeMunicipioCodigo),
 		CityName:             getStringValue(doc.OfeMunicipioNombre),
-		PostalZone:           getStringValue(doc.OfeMunicipioCodigo), // Use municipality code as postal zone
+		PostalZone:           getStringOrDefault(doc.OfeCpoCodigo, getStringValue(doc.OfeMunicipioCodigo)), // Municipality code when no postal code
 		CountrySubentity:     getStringValue(doc.OfeDepartamentoNombre),
 		CountrySubentityCode: getStringValue(doc.OfeDepartamentoCodigo),
 		Line:                 getStringValue(doc.OfeDireccion),
@@ -3960,13 +4004,22 @@ func buildSupplierPhysicalLocation(doc invoice.OpenETLDocument) *numrotPhysicalL
 	return location
 }
 
+// ofeTaxLevelCode returns the tax responsibilities of the OFE separated by ";",
+// defaulting to R-99-PN (no aplica) when the OFE registry has none.
+func ofeTaxLevelCode(doc invoice.OpenETLDocument) string {
+	if len(doc.OfeRefCodigo) == 0 {
+		return "R-99-PN"
+	}
+	return strings.Join(doc.OfeRefCodigo, ";")
+}
+
 // buildDocumentSincURL builds the URL for Numrot documentSinc API endpoint.
 // Format: /api/documentSinc/{nit}/{documento} or /documentSinc/{nit}/{documento} if baseURL already includes /api
 // nit: NIT del OFE (sin DV)
 // documento: Número completo del documento (prefijo + consecutivo)
 func buildDocumentSincURL(baseURL, ofeIdentificacion, prefijo, consecutivo string) string {
 	// Extract base NIT (without DV) for URL
-	baseNIT, _ := parseNITWithDV(ofeIdentificacion)
+	baseNIT, _ := identification.Split(ofeIdentificacion)
 	documento := prefijo + consecutivo
 
 	// Check if baseURL already includes /api (e.g., https://numrotapiprueba.net/api)
@@ -3979,35 +4032,22 @@ func buildDocumentSincURL(baseURL, ofeIdentificacion, prefijo, consecutivo strin
 	return fmt.Sprintf("%s/api/documentSinc/%s/%s", baseURL, baseNIT, documento)
 }
 
-// parseNITWithDV parses a NIT that may include a check digit (DV).
-// Returns the base NIT (without DV) and the DV digit.
-// If no DV is provided, returns the original NIT as baseNIT and empty string for DV.
-// Examples:
-//   - "860011153-1" -> baseNIT: "860011153", dv: "1"
-//   - "860011153" -> baseNIT: "860011153", dv: ""
-func parseNITWithDV(nit string) (baseNIT string, dv string) {
-	if nit == "" {
-		return "", ""
+// foreignExchangeRate returns the PaymentExchangeRate of a document in foreign currency
+// (mon_codigo other than COP), or nil for COP documents or when cdo_trm is missing.
+func foreignExchangeRate(doc invoice.OpenETLDocument) *numrotPaymentExchangeRate {
+	if !currency.IsForeign(doc.MonCodigo) || doc.CdoTrm == nil || *doc.CdoTrm == "" {
+		return nil
 	}
-
-	// Split by "-" to separate NIT from DV
-	parts := strings.Split(nit, "-")
-
-	if len(parts) == 1 {
-		// No DV provided, return NIT as is
-		return nit, ""
+	date := doc.CdoFecha
+	if doc.CdoTrmFecha != nil && *doc.CdoTrmFecha != "" {
+		date = *doc.CdoTrmFecha
 	}
-
-	if len(parts) == 2 {
-		// DV provided
-		baseNIT = strings.TrimSpace(parts[0])
-		dv = strings.TrimSpace(parts[1])
-		return baseNIT, dv
+	return &numrotPaymentExchangeRate{
+		SourceCurrencyCode:     doc.MonCodigo,
+		SourceCurrencyBaseRate: "1.00",
+		TargetCurrencyCode:     currency.COP,
+		TargetCurrencyBaseRate: "1.00",
+		CalculationRate:        *doc.CdoTrm,
+		Date:                   date,
 	}
-
-	// Multiple dashes - take first part as NIT, last part as DV
-	// This handles edge cases like "860011153-1-extra"
-	baseNIT = strings.TrimSpace(parts[0])
-	dv = strings.TrimSpace(parts[len(parts)-1])
-	return baseNIT, dv
 }
@@ -10,9 +10,9 @@ import (
 	"testing"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/event"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 func TestNewClient(t *testing.T) {
@@ -405,6 +405,7 @@ func TestClient_GetDocuments_Success(t *testing.T) {
 					EmisorNit:         "123456987",
 					EmisorNombre:      "ALMACENES S.A.",
 					ReferenciaFactura: "c1234567890",
+					CUFE:              "c1234567890abcdef",
 				},
 			},
 		}
@@ -717,6 +718,7 @@ func TestClient_transformToDocument_Success(t *testing.T) {
 		EmisorNit:         "123456987",
 		EmisorNombre:      "ALMACENES S.A.",
 		ReferenciaFactura: "c1234567890",
+		CUFE:              "c1234567890abcdef",
 	}
 
 	doc, err := client.transformToDocument(nd)
@@ -2131,3 +2133,96 @@ func TestTransformOpenETLToNumrot_NC_WithoutIVA(t *testing.T) {
 		t.Errorf("expected TaxInclusiveAmount to be 50000.00 (cdo_total), got %s", numrotInv.LegalMonetaryTotal.TaxInclusiveAmount)
 	}
 }
+
+func TestTransformOpenETLToNumrot_ForeignCurrency(t *testing.T) {
+	client := &Client{log: testutil.NewTestLogger()}
+
+	trm := "4000.50"
+	doc := invoice.OpenETLDocument{
+		TdeCodigo:               "01",
+		TopCodigo:               "10",
+		OfeIdentificacion:       "860011153-3",
+		AdqIdentificacion:       "900123456",
+		RfaPrefijo:              "SETT",
+		CdoConsecutivo:          "100",
+		CdoFecha:                "2024-03-15",
+		CdoHora:                 "10:00:00",
+		MonCodigo:               "USD",
+		CdoTrm:                  &trm,
+		CdoValorSinImpuestos:    "100.00",
+		CdoImpuestos:            "0.00",
+		CdoTotal:                "100.00",
+		CdoRetencionesSugeridas: "0.00",
+		CdoAnticipo:             "0.00",
+		CdoRedondeo:             "0.00",
+		Items: []invoice.OpenETLItem{
+			{
+				DdoTipoItem:       "BIEN",
+				DdoSecuencia:      "1",
+				DdoCodigo:         "PROD001",
+				DdoDescripcionUno: "Producto de exportación",
+				DdoCantidad:       "1",
+				UndCodigo:         "UN",
+				DdoValorUnitario:  "100.00",
+				DdoTotal:          "100.00",
+			},
+		},
+	}
+
+	numrotInv, err := client.transformOpenETLToNumrot(context.Background(), doc, "FC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rate := numrotInv.PaymentExchangeRate
+	if rate == nil {
+		t.Fatal("expected PaymentExchangeRate for a USD document")
+	}
+	if rate.SourceCurrencyCode != "USD" || rate.TargetCurrencyCode != "COP" {
+		t.Errorf("expected USD -> COP, got %s -> %s", rate.SourceCurrencyCode, rate.TargetCurrencyCode)
+	}
+	if rate.CalculationRate != "4000.50" {
+		t.Errorf("expected CalculationRate 4000.50, got %s", rate.CalculationRate)
+	}
+	if rate.Date != "2024-03-15" {
+		t.Errorf("expected Date to default to cdo_fecha, got %s", rate.Date)
+	}
+}
+
+func TestTransformOpenETLToNumrot_ContingencyReference(t *testing.T) {
+	client := &Client{log: testutil.NewTestLogger()}
+
+	doc := invoice.OpenETLDocument{
+		TdeCodigo:         "04",
+		TopCodigo:         "10",
+		OfeIdentificacion: "860011153-3",
+		AdqIdentificacion: "900123456",
+		RfaPrefijo:        "SETT",
+		CdoConsecutivo:    "101",
+		CdoFecha:          "2024-03-15",
+		CdoHora:           "10:00:00",
+		MonCodigo:         "COP",
+		CdoTotal:          "100.00",
+		CdoDocumentoContingencia: &invoice.OpenETLDocumentoContingencia{
+			Numero: "CONT7",
+			Fecha:  "2024-03-15",
+			Tipo:   "FTC",
+		},
+	}
+
+	numrotInv, err := client.transformOpenETLToNumrot(context.Background(), doc, "FC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if numrotInv.InvoiceTypeCode != "04" {
+		t.Errorf("expected InvoiceTypeCode 04, got %s", numrotInv.InvoiceTypeCode)
+	}
+	refs := numrotInv.AdditionalDocumentReference
+	if len(refs) != 1 {
+		t.Fatalf("expected one AdditionalDocumentReference, got %+v", refs)
+	}
+	if refs[0].ID != "CONT7" || refs[0].DocumentTypeCode != "FTC" || refs[0].IssueDate == nil || *refs[0].IssueDate != "2024-03-15" {
+		t.Errorf("unexpected contingency reference %+v", refs[0])
+	}
+}
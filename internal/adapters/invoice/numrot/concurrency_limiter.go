@@ -14,6 +14,7 @@ type ConcurrentRequestLimiter struct {
 	maxConcurrent int
 	mu            sync.RWMutex
 	activeCount   int
+	peakCount     int
 	waitCount     int64
 	totalAcquired int64
 }
@@ -46,6 +47,9 @@ func (l *ConcurrentRequestLimiter) Acquire(ctx context.Context) error {
 		l.activeCount++
 		l.totalAcquired++
 		l.waitCount--
+		if l.activeCount > l.peakCount {
+			l.peakCount = l.activeCount
+		}
 		active := l.activeCount
 		l.mu.Unlock()
 
@@ -86,6 +90,7 @@ func (l *ConcurrentRequestLimiter) MaxConcurrent() int {
 type LimiterStats struct {
 	MaxConcurrent int
 	ActiveCount   int
+	PeakCount     int // Highest ActiveCount since the limiter was created
 	WaitCount     int64
 	TotalAcquired int64
 	Available     int
@@ -98,6 +103,7 @@ func (l *ConcurrentRequestLimiter) Stats() LimiterStats {
 	return LimiterStats{
 		MaxConcurrent: l.maxConcurrent,
 		ActiveCount:   l.activeCount,
+		PeakCount:     l.peakCount,
 		WaitCount:     l.waitCount,
 		TotalAcquired: l.totalAcquired,
 		Available:     l.maxConcurrent - l.activeCount,
@@ -111,6 +117,15 @@ type RateLimiter struct {
 	rate         int // requests per second
 	mu           sync.RWMutex
 	closed       bool
+	waits        int64         // Acquisitions that found the bucket empty
+	waitTime     time.Duration // Total time spent waiting for a token
+}
+
+// RateLimiterStats holds statistics about the waits on a rate limiter
+type RateLimiterStats struct {
+	Rate     int
+	Waits    int64
+	WaitTime time.Duration
 }
 
 // NewRateLimiter creates a new rate limiter
@@ -158,6 +173,21 @@ func (rl *RateLimiter) refill() {
 
 // Acquire acquires a token from the rate limiter
 func (rl *RateLimiter) Acquire(ctx context.Context) error {
+	select {
+	case <-rl.tokens:
+		return nil
+	default:
+	}
+
+	// The bucket is empty: record how long the request is throttled
+	start := time.Now()
+	defer func() {
+		rl.mu.Lock()
+		rl.waits++
+		rl.waitTime += time.Since(start)
+		rl.mu.Unlock()
+	}()
+
 	select {
 	case <-rl.tokens:
 		return nil
@@ -173,6 +203,17 @@ func (rl *RateLimiter) Rate() int {
 	return rl.rate
 }
 
+// Stats returns current statistics
+func (rl *RateLimiter) Stats() RateLimiterStats {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return RateLimiterStats{
+		Rate:     rl.rate,
+		Waits:    rl.waits,
+		WaitTime: rl.waitTime,
+	}
+}
+
 // Close stops the rate limiter
 func (rl *RateLimiter) Close() {
 	rl.mu.Lock()
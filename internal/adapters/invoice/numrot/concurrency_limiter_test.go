@@ -0,0 +1,54 @@
+package numrot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConcurrentRequestLimiter_PeakCount(t *testing.T) {
+	l := NewConcurrentRequestLimiter(10)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := l.Acquire(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	l.Release()
+	l.Release()
+	if err := l.Acquire(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stats := l.Stats()
+	if stats.ActiveCount != 2 || stats.PeakCount != 3 {
+		t.Errorf("expected 2 active and peak of 3, got %+v", stats)
+	}
+}
+
+func TestRateLimiter_StatsCountsWaits(t *testing.T) {
+	rl := NewRateLimiter(1)
+	defer rl.Close()
+	ctx := context.Background()
+
+	// The first token is available immediately
+	if err := rl.Acquire(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats := rl.Stats(); stats.Waits != 0 {
+		t.Errorf("expected no waits while tokens are available, got %+v", stats)
+	}
+
+	// The bucket is empty: the next acquisition waits for the refill
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := rl.Acquire(ctx); err == nil {
+		t.Fatal("expected the acquisition to time out")
+	}
+
+	stats := rl.Stats()
+	if stats.Rate != 1 || stats.Waits != 1 || stats.WaitTime <= 0 {
+		t.Errorf("expected one recorded wait, got %+v", stats)
+	}
+}
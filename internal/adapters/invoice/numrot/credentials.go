@@ -0,0 +1,155 @@
+package numrot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/ofe"
+)
+
+// credentialsCacheTTL bounds how long registry credentials are reused before reading them again,
+// so that changes made through the OFE endpoints are picked up without restarting.
+const credentialsCacheTTL = time.Minute
+
+// Credentials is a Numrot credential set. Username/Password obtain the bearer token used
+// by the resolution, document info and registration endpoints; Key/Secret authenticate
+// the Radian queries and event registration.
+type Credentials struct {
+	Username string
+	Password string
+	Key      string
+	Secret   string
+}
+
+// CredentialStore resolves the Numrot credentials of an OFE.
+// It returns nil when the OFE has no credentials of its own.
+type CredentialStore interface {
+	CredentialsFor(ctx context.Context, ofeIdentificacion string) (*Credentials, error)
+}
+
+// ParseCredentials parses credential sets configured as "ofe=usuario:password:key:secret".
+// Either pair may be empty ("ofe=::key:secret") to use the default one.
+func ParseCredentials(entries []string) (map[string]Credentials, error) {
+	sets := make(map[string]Credentials, len(entries))
+	for _, entry := range entries {
+		nit, value, ok := strings.Cut(entry, "=")
+		nit = strings.TrimSpace(nit)
+		if !ok || nit == "" {
+			return nil, fmt.Errorf("credenciales Numrot inválidas [%s]: se espera ofe=usuario:password:key:secret", entry)
+		}
+		parts := strings.SplitN(value, ":", 4)
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("credenciales Numrot inválidas para el OFE [%s]: se espera usuario:password:key:secret", nit)
+		}
+		base, _ := identification.Split(nit)
+		if base == "" {
+			base = nit
+		}
+		sets[base] = Credentials{
+			Username: strings.TrimSpace(parts[0]),
+			Password: strings.TrimSpace(parts[1]),
+			Key:      strings.TrimSpace(parts[2]),
+			Secret:   strings.TrimSpace(parts[3]),
+		}
+	}
+	return sets, nil
+}
+
+// OFECredentials resolves credentials from the OFE registry, falling back to the
+// credential sets given in configuration.
+type OFECredentials struct {
+	configured map[string]Credentials
+	repo       ofe.Repository // Optional: nil if the OFE registry is disabled
+	cache      sync.Map       // key = NIT, value = cachedCredentials
+}
+
+type cachedCredentials struct {
+	creds     *Credentials
+	expiresAt time.Time
+}
+
+// NewOFECredentials creates a credential store. Both sources are optional.
+func NewOFECredentials(configured map[string]Credentials, repo ofe.Repository) *OFECredentials {
+	return &OFECredentials{configured: configured, repo: repo}
+}
+
+// CredentialsFor returns the credentials of the OFE, or nil if it has none.
+func (s *OFECredentials) CredentialsFor(ctx context.Context, ofeIdentificacion string) (*Credentials, error) {
+	if s.repo != nil {
+		if cached, ok := s.cache.Load(ofeIdentificacion); ok {
+			if entry := cached.(cachedCredentials); time.Now().Before(entry.expiresAt) {
+				return entry.creds, nil
+			}
+		}
+
+		o, err := s.repo.FindByIdentificacion(ctx, ofeIdentificacion)
+		if err != nil {
+			return nil, fmt.Errorf("buscar credenciales del OFE %s: %w", ofeIdentificacion, err)
+		}
+		var creds *Credentials
+		if o != nil && o.Numrot != nil {
+			creds = &Credentials{Username: o.Numrot.Username, Password: o.Numrot.Password, Key: o.Numrot.Key, Secret: o.Numrot.Secret}
+		} else if configured, ok := s.configured[ofeIdentificacion]; ok {
+			creds = &configured
+		}
+		s.cache.Store(ofeIdentificacion, cachedCredentials{creds: creds, expiresAt: time.Now().Add(credentialsCacheTTL)})
+		return creds, nil
+	}
+
+	if configured, ok := s.configured[ofeIdentificacion]; ok {
+		return &configured, nil
+	}
+	return nil, nil
+}
+
+// WithCredentials makes every call select its credential set by the OFE of the
+// request. OFEs without credentials (or with only one of the pairs) use the default ones.
+func (c *Client) WithCredentials(store CredentialStore) *Client {
+	c.credentials = store
+	return c
+}
+
+// credentialsFor returns the credential set for the OFE, completing missing pairs with the defaults.
+func (c *Client) credentialsFor(ctx context.Context, ofeIdentificacion string) (Credentials, error) {
+	creds := Credentials{Key: c.key, Secret: c.secret}
+	if c.auth != nil {
+		def := c.auth.DefaultCredentials()
+		creds.Username, creds.Password = def.Username, def.Password
+	}
+	if c.credentials == nil {
+		return creds, nil
+	}
+
+	nit, _ := identification.Split(ofeIdentificacion)
+	if nit == "" {
+		nit = strings.TrimSpace(ofeIdentificacion)
+	}
+	own, err := c.credentials.CredentialsFor(ctx, nit)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if own == nil {
+		return creds, nil
+	}
+	if own.Username != "" && own.Password != "" {
+		creds.Username, creds.Password = own.Username, own.Password
+	}
+	if own.Key != "" && own.Secret != "" {
+		creds.Key, creds.Secret = own.Key, own.Secret
+	}
+	return creds, nil
+}
+
+// tokenFor returns the bearer token of the OFE credential set.
+func (c *Client) tokenFor(ctx context.Context, ofeIdentificacion string) (string, Credentials, error) {
+	creds, err := c.credentialsFor(ctx, ofeIdentificacion)
+	if err != nil {
+		return "", Credentials{}, err
+	}
+	token, err := c.auth.TokenFor(ctx, creds)
+	return token, creds, err
+}
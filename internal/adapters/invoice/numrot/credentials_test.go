@@ -0,0 +1,117 @@
+package numrot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/ofe"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func TestParseCredentials(t *testing.T) {
+	sets, err := ParseCredentials([]string{"860011153-6=usuario:clave:key:sec:ret", "900000001=::key2:secret2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := sets["860011153"]; got.Username != "usuario" || got.Password != "clave" || got.Secret != "sec:ret" {
+		t.Errorf("unexpected credentials: %+v", got)
+	}
+	if got := sets["900000001"]; got.Username != "" || got.Key != "key2" {
+		t.Errorf("unexpected credentials: %+v", got)
+	}
+
+	for _, invalid := range []string{"sin-igual", "=a:b:c:d", "860011153=usuario:clave"} {
+		if _, err := ParseCredentials([]string{invalid}); err == nil || !strings.Contains(err.Error(), "inválidas") {
+			t.Errorf("expected error for %q, got %v", invalid, err)
+		}
+	}
+}
+
+func TestOFECredentials_CredentialsFor(t *testing.T) {
+	repo := testutil.NewMockOFERepository(
+		ofe.OFE{OfeIdentificacion: "860011153", Numrot: &ofe.NumrotCredentials{Username: "registro", Password: "clave"}},
+		ofe.OFE{OfeIdentificacion: "900000001"},
+	)
+	store := NewOFECredentials(map[string]Credentials{
+		"860011153": {Username: "config"},
+		"900000001": {Key: "key-config", Secret: "secret-config"},
+	}, repo)
+
+	creds, err := store.CredentialsFor(context.Background(), "860011153")
+	if err != nil || creds == nil || creds.Username != "registro" {
+		t.Fatalf("expected registry credentials to prevail, got %+v (%v)", creds, err)
+	}
+	creds, _ = store.CredentialsFor(context.Background(), "900000001")
+	if creds == nil || creds.Key != "key-config" {
+		t.Errorf("expected configured credentials for OFE without registry credentials, got %+v", creds)
+	}
+	if creds, _ := store.CredentialsFor(context.Background(), "999"); creds != nil {
+		t.Errorf("expected nil for unknown OFE, got %+v", creds)
+	}
+
+	lookups := repo.Lookups
+	store.CredentialsFor(context.Background(), "860011153")
+	if repo.Lookups != lookups {
+		t.Errorf("expected registry credentials to be cached")
+	}
+}
+
+func TestClient_SelectsCredentialsByOFE(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]string
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("token-" + reqBody["username"]))
+	}))
+	defer authServer.Close()
+
+	var authorization string
+	var radianKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/api/Resoluciones/") {
+			authorization = r.Header.Get("Authorization")
+			json.NewEncoder(w).Encode(numrotResolutionResponse{OperationCode: "100"})
+			return
+		}
+		var reqBody map[string]string
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		radianKey = reqBody["Key"]
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	auth := NewAuthManager(authServer.URL, "user", "pass", time.Hour, authServer.Client(), testutil.NewTestLogger())
+	client := NewClient(server.URL, auth, server.Client(), testutil.NewTestLogger(), "key", "secret", server.URL, nil).(*Client)
+	client.WithCredentials(NewOFECredentials(map[string]Credentials{
+		"860011153": {Username: "positiva", Password: "clave", Key: "key-positiva", Secret: "secret-positiva"},
+		"900000001": {Key: "key-otro"}, // incomplete pair: defaults are used
+	}, nil))
+
+	tests := []struct {
+		nit           string
+		expectedToken string
+		expectedKey   string
+	}{
+		{nit: "860011153-6", expectedToken: "Bearer token-positiva", expectedKey: "key-positiva"},
+		{nit: "900000001", expectedToken: "Bearer token-user", expectedKey: "key"},
+		{nit: "123456789", expectedToken: "Bearer token-user", expectedKey: "key"},
+	}
+	for _, tt := range tests {
+		if _, err := client.GetResolutions(context.Background(), tt.nit); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if authorization != tt.expectedToken {
+			t.Errorf("%s: expected %q, got %q", tt.nit, tt.expectedToken, authorization)
+		}
+		client.GetDocuments(context.Background(), invoice.DocumentQuery{CompanyNit: tt.nit})
+		if radianKey != tt.expectedKey {
+			t.Errorf("%s: expected key %q, got %q", tt.nit, tt.expectedKey, radianKey)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+// Package routing implements an invoice.Provider that dispatches every request to
+// one of several concrete providers (Numrot, DIAN web services, ...) according to
+// rules per OFE, document type and operation. It fails over to a secondary
+// provider while the circuit breaker of the primary one is open, and records in
+// the audit trail which provider handled each registered document.
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/audit"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/resolution"
+	ctxutil "3tcapital/goclonacion/internal/infrastructure/context"
+)
+
+// auditOperation is the operation recorded for each routed document.
+const auditOperation = "route_document"
+
+// Router implements invoice.Provider on top of named providers.
+type Router struct {
+	providers       map[string]invoice.Provider
+	rules           []Rule
+	defaultProvider string
+	fallback        string
+	auditRepo       audit.Repository // Optional: nil disables the routing audit trail
+	log             *slog.Logger
+}
+
+var _ invoice.Provider = (*Router)(nil)
+
+// NewRouter creates a router. Requests no rule matches go to defaultProvider;
+// fallback (optional) is the secondary provider of rules that do not name one.
+// Every provider referenced by the rules must be registered in providers.
+func NewRouter(providers map[string]invoice.Provider, rules []Rule, defaultProvider, fallback string, auditRepo audit.Repository, log *slog.Logger) (*Router, error) {
+	known := func(name string) error {
+		if name == "" {
+			return nil
+		}
+		if providers[name] == nil {
+			return fmt.Errorf("proveedor de facturación %q no configurado", name)
+		}
+		return nil
+	}
+	if defaultProvider == "" {
+		return nil, fmt.Errorf("proveedor de facturación por defecto requerido")
+	}
+	if err := known(defaultProvider); err != nil {
+		return nil, err
+	}
+	if err := known(fallback); err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if err := known(rule.Primary); err != nil {
+			return nil, err
+		}
+		if err := known(rule.Fallback); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Router{
+		providers:       providers,
+		rules:           rules,
+		defaultProvider: defaultProvider,
+		fallback:        fallback,
+		auditRepo:       auditRepo,
+		log:             log,
+	}, nil
+}
+
+// route is the pair of providers selected for a request.
+type route struct {
+	primary  string
+	fallback string
+}
+
+// route returns the providers of the first rule matching the request.
+func (r *Router) route(ofe, tipo, operation string) route {
+	selected := route{primary: r.defaultProvider, fallback: r.fallback}
+	ofe = normalizeNIT(ofe)
+	for _, rule := range r.rules {
+		if rule.matches(ofe, tipo, operation) {
+			selected = route{primary: rule.Primary, fallback: rule.Fallback}
+			if selected.fallback == "" {
+				selected.fallback = r.fallback
+			}
+			break
+		}
+	}
+	if selected.fallback == selected.primary {
+		selected.fallback = ""
+	}
+	return selected
+}
+
+// circuitOpen reports whether the provider is failing fast.
+func (r *Router) circuitOpen(name string) bool {
+	reporter, ok := r.providers[name].(invoice.CircuitReporter)
+	return ok && reporter.CircuitOpen()
+}
+
+// CircuitOpen reports whether the default provider is failing fast and no fallback
+// can take its requests, so that documents cannot be sent at all.
+func (r *Router) CircuitOpen() bool {
+	if !r.circuitOpen(r.defaultProvider) {
+		return false
+	}
+	return r.fallback == "" || r.fallback == r.defaultProvider || r.circuitOpen(r.fallback)
+}
+
+// pick returns the provider that should handle the route now.
+func (r *Router) pick(rt route) (name string, failover bool) {
+	if rt.fallback != "" && r.circuitOpen(rt.primary) {
+		return rt.fallback, true
+	}
+	return rt.primary, false
+}
+
+// call runs fn on the provider selected for the request. Queries are retried on the
+// secondary provider when the call fails and the circuit of the primary is open; writes
+// (events) fail over only when the circuit was already open before the call, because a
+// failed write may still have reached DIAN through the primary.
+func (r *Router) call(ctx context.Context, ofe, tipo, operation string, fn func(invoice.Provider) error) error {
+	rt := r.route(ofe, tipo, operation)
+	name, failover := r.pick(rt)
+	if failover {
+		r.log.Warn("Primary invoicing provider circuit is open, using fallback",
+			"operation", operation, "ofe", ofe, "primary", rt.primary, "fallback", rt.fallback)
+	}
+
+	err := fn(r.providers[name])
+	if err != nil && operation != OpEvents && !failover && rt.fallback != "" && r.circuitOpen(rt.primary) {
+		r.log.Warn("Invoicing provider failed with circuit open, retrying on fallback",
+			"operation", operation, "ofe", ofe, "primary", rt.primary, "fallback", rt.fallback, "error", err)
+		return fn(r.providers[rt.fallback])
+	}
+	return err
+}
+
+// GetResolutions routes by the NIT queried.
+func (r *Router) GetResolutions(ctx context.Context, nit string) ([]resolution.Resolution, error) {
+	var out []resolution.Resolution
+	err := r.call(ctx, nit, "", OpResolutions, func(p invoice.Provider) (err error) {
+		out, err = p.GetResolutions(ctx, nit)
+		return err
+	})
+	return out, err
+}
+
+// GetDocuments routes by the company NIT of the query.
+func (r *Router) GetDocuments(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
+	var out []invoice.Document
+	err := r.call(ctx, query.CompanyNit, "", OpDocuments, func(p invoice.Provider) (err error) {
+		out, err = p.GetDocuments(ctx, query)
+		return err
+	})
+	return out, err
+}
+
+// GetDocumentByNumber routes by the company NIT of the query.
+func (r *Router) GetDocumentByNumber(ctx context.Context, query invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
+	var out []invoice.Document
+	err := r.call(ctx, query.CompanyNit, "", OpDocument, func(p invoice.Provider) (err error) {
+		out, err = p.GetDocumentByNumber(ctx, query)
+		return err
+	})
+	return out, err
+}
+
+// GetReceivedDocuments routes by the company NIT of the query.
+func (r *Router) GetReceivedDocuments(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
+	var out []invoice.Document
+	err := r.call(ctx, query.CompanyNit, "", OpReceived, func(p invoice.Provider) (err error) {
+		out, err = p.GetReceivedDocuments(ctx, query)
+		return err
+	})
+	return out, err
+}
+
+// RegisterEvent routes by the NIT of the company registering the event.
+func (r *Router) RegisterEvent(ctx context.Context, evt event.Event, emisorNit, razonSocial string) (*invoice.EventRegistrationResult, error) {
+	var out *invoice.EventRegistrationResult
+	err := r.call(ctx, emisorNit, "", OpEvents, func(p invoice.Provider) (err error) {
+		out, err = p.RegisterEvent(ctx, evt, emisorNit, razonSocial)
+		return err
+	})
+	return out, err
+}
+
+// RegisterDocument splits the request by route, sends each part to its provider
+// and merges the results. The provider that handled each document is recorded in
+// the audit trail. When a provider call fails the outcome of its documents is
+// unknown (they may have reached DIAN), so the error is returned as is and the
+// remaining parts are not sent: the caller keeps every document as sent and a
+// retry reconciles them with the provider instead of rejecting them.
+func (r *Router) RegisterDocument(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+	groups := map[route]*invoice.DocumentRegistrationRequest{}
+	add := func(documentType string, docs []invoice.OpenETLDocument) {
+		for _, doc := range docs {
+			rt := r.route(issuerNIT(doc, documentType), documentType, OpRegister)
+			group := groups[rt]
+			if group == nil {
+				group = &invoice.DocumentRegistrationRequest{}
+				groups[rt] = group
+			}
+			switch documentType {
+			case "FC":
+				group.Documentos.FC = append(group.Documentos.FC, doc)
+			case "NC":
+				group.Documentos.NC = append(group.Documentos.NC, doc)
+			case "ND":
+				group.Documentos.ND = append(group.Documentos.ND, doc)
+			case "DS":
+				group.Documentos.DS = append(group.Documentos.DS, doc)
+			}
+		}
+	}
+	add("FC", req.Documentos.FC)
+	add("NC", req.Documentos.NC)
+	add("ND", req.Documentos.ND)
+	add("DS", req.Documentos.DS)
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no documents provided")
+	}
+
+	routes := make([]route, 0, len(groups))
+	for rt := range groups {
+		routes = append(routes, rt)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].primary != routes[j].primary {
+			return routes[i].primary < routes[j].primary
+		}
+		return routes[i].fallback < routes[j].fallback
+	})
+
+	merged := &invoice.DocumentRegistrationResponse{
+		DocumentosProcesados: make([]invoice.ProcessedDocument, 0),
+		DocumentosFallidos:   make([]invoice.FailedDocument, 0),
+	}
+	for _, rt := range routes {
+		group := *groups[rt]
+		resp, err := r.registerGroup(ctx, rt, group)
+		if err != nil {
+			return nil, err
+		}
+		if len(routes) == 1 {
+			// Single route: return the response of the underlying provider as is
+			return resp, nil
+		}
+		merged.DocumentosProcesados = append(merged.DocumentosProcesados, resp.DocumentosProcesados...)
+		merged.DocumentosFallidos = append(merged.DocumentosFallidos, resp.DocumentosFallidos...)
+		if merged.Lote == "" {
+			merged.Lote = resp.Lote
+		}
+	}
+
+	switch {
+	case len(merged.DocumentosFallidos) == 0:
+		merged.Message = "Documentos procesados exitosamente"
+	case len(merged.DocumentosProcesados) == 0:
+		merged.Message = "Error al procesar documentos"
+	default:
+		merged.Message = "Algunos documentos fueron procesados, otros fallaron"
+	}
+	return merged, nil
+}
+
+// registerGroup sends the documents of one route. It fails over only when the circuit
+// of the primary provider is already open: a call that fails (and perhaps opens the
+// circuit) may have reached DIAN, so it is never repeated on another provider.
+func (r *Router) registerGroup(ctx context.Context, rt route, group invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+	name, failover := r.pick(rt)
+	reason := "regla"
+	if failover {
+		reason = "circuito abierto en " + rt.primary
+		r.log.Warn("Primary invoicing provider circuit is open, registering documents on fallback",
+			"primary", rt.primary, "fallback", rt.fallback)
+	}
+
+	start := time.Now()
+	resp, err := r.providers[name].RegisterDocument(ctx, group)
+	r.auditGroup(ctx, name, rt, reason, group, resp, err, time.Since(start))
+	return resp, err
+}
+
+// routedDocument is the request body of a routing audit record.
+type routedDocument struct {
+	OfeIdentificacion string `json:"ofe_identificacion"`
+	Tipo              string `json:"tipo"`
+	Prefijo           string `json:"prefijo"`
+	Consecutivo       string `json:"consecutivo"`
+	Primario          string `json:"proveedor_primario"`
+	Secundario        string `json:"proveedor_secundario,omitempty"`
+	Motivo            string `json:"motivo"`
+}
+
+// routedResult is the response body of a routing audit record.
+type routedResult struct {
+	Estado string   `json:"estado"` // procesado, fallido
+	CUFE   string   `json:"cufe,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// auditGroup records one audit entry per document with the provider that handled it.
+func (r *Router) auditGroup(ctx context.Context, name string, rt route, reason string, group invoice.DocumentRegistrationRequest, resp *invoice.DocumentRegistrationResponse, callErr error, duration time.Duration) {
+	if r.auditRepo == nil {
+		return
+	}
+
+	results := map[string]routedResult{}
+	if resp != nil {
+		for _, p := range resp.DocumentosProcesados {
+			results[p.RfaPrefijo+p.CdoConsecutivo] = routedResult{Estado: "procesado", CUFE: p.CUFE}
+		}
+		for _, f := range resp.DocumentosFallidos {
+			results[f.Prefijo+f.Consecutivo] = routedResult{Estado: "fallido", Errors: f.Errors}
+		}
+	}
+
+	correlationID := ctxutil.GetCorrelationID(ctx)
+	forEach(group, func(documentType string, doc invoice.OpenETLDocument) {
+		entry := audit.ProviderAuditLog{
+			CorrelationID: correlationID,
+			Provider:      name,
+			Operation:     auditOperation,
+			RequestMethod: "ROUTE",
+			RequestURL:    "",
+			DurationMs:    duration.Milliseconds(),
+		}
+		entry.RequestBody, _ = json.Marshal(routedDocument{
+			OfeIdentificacion: issuerNIT(doc, documentType),
+			Tipo:              documentType,
+			Prefijo:           doc.RfaPrefijo,
+			Consecutivo:       doc.CdoConsecutivo,
+			Primario:          rt.primary,
+			Secundario:        rt.fallback,
+			Motivo:            reason,
+		})
+		if result, ok := results[doc.RfaPrefijo+doc.CdoConsecutivo]; ok {
+			entry.ResponseBody, _ = json.Marshal(result)
+		}
+		if callErr != nil {
+			entry.ErrorMessage = callErr.Error()
+		}
+		if err := r.auditRepo.Save(ctx, entry); err != nil {
+			r.log.Error("Failed to persist routing audit log",
+				"error", err,
+				"correlation_id", correlationID,
+				"provider", name,
+				"prefijo", doc.RfaPrefijo,
+				"consecutivo", doc.CdoConsecutivo)
+		}
+	})
+}
+
+func forEach(req invoice.DocumentRegistrationRequest, fn func(documentType string, doc invoice.OpenETLDocument)) {
+	for _, doc := range req.Documentos.FC {
+		fn("FC", doc)
+	}
+	for _, doc := range req.Documentos.NC {
+		fn("NC", doc)
+	}
+	for _, doc := range req.Documentos.ND {
+		fn("ND", doc)
+	}
+	for _, doc := range req.Documentos.DS {
+		fn("DS", doc)
+	}
+}
+
+// issuerNIT returns the NIT of the OFE issuing the document. In DS requests the OFE
+// (buyer) is sent as adq_identificacion.
+func issuerNIT(doc invoice.OpenETLDocument, documentType string) string {
+	if documentType == "DS" && doc.AdqIdentificacion != "" {
+		return doc.AdqIdentificacion
+	}
+	return doc.OfeIdentificacion
+}
@@ -0,0 +1,338 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/audit"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/resolution"
+	ctxutil "3tcapital/goclonacion/internal/infrastructure/context"
+)
+
+// fakeProvider records the calls it receives.
+type fakeProvider struct {
+	name        string
+	open        bool  // circuit state reported by CircuitOpen
+	openOnError bool  // opens the circuit when returning err
+	err         error // returned by every call
+	calls       int
+	registered  []string // prefijo+consecutivo of registered documents
+}
+
+func (f *fakeProvider) CircuitOpen() bool { return f.open }
+
+func (f *fakeProvider) fail() error {
+	f.calls++
+	if f.err != nil && f.openOnError {
+		f.open = true
+	}
+	return f.err
+}
+
+func (f *fakeProvider) GetResolutions(ctx context.Context, nit string) ([]resolution.Resolution, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	return []resolution.Resolution{{Prefix: f.name}}, nil
+}
+
+func (f *fakeProvider) GetDocuments(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
+	return nil, f.fail()
+}
+
+func (f *fakeProvider) GetDocumentByNumber(ctx context.Context, query invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
+	return nil, f.fail()
+}
+
+func (f *fakeProvider) GetReceivedDocuments(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
+	return nil, f.fail()
+}
+
+func (f *fakeProvider) RegisterEvent(ctx context.Context, evt event.Event, emisorNit, razonSocial string) (*invoice.EventRegistrationResult, error) {
+	return nil, f.fail()
+}
+
+func (f *fakeProvider) RegisterDocument(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	resp := &invoice.DocumentRegistrationResponse{Message: "Documentos procesados exitosamente", Lote: "lote-" + f.name}
+	forEach(req, func(documentType string, doc invoice.OpenETLDocument) {
+		f.registered = append(f.registered, doc.RfaPrefijo+doc.CdoConsecutivo)
+		resp.DocumentosProcesados = append(resp.DocumentosProcesados, invoice.ProcessedDocument{
+			RfaPrefijo:     doc.RfaPrefijo,
+			CdoConsecutivo: doc.CdoConsecutivo,
+			CUFE:           f.name + "-" + doc.CdoConsecutivo,
+		})
+	})
+	return resp, nil
+}
+
+// mockAuditRepo is a mock implementation of audit.Repository for testing.
+type mockAuditRepo struct {
+	saved []audit.ProviderAuditLog
+}
+
+func (m *mockAuditRepo) Save(ctx context.Context, log audit.ProviderAuditLog) error {
+	m.saved = append(m.saved, log)
+	return nil
+}
+
+func (m *mockAuditRepo) FindByCorrelationID(ctx context.Context, correlationID string) ([]audit.ProviderAuditLog, error) {
+	return m.saved, nil
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func newTestRouter(t *testing.T, specs []string, fallback string, repo audit.Repository) (*Router, *fakeProvider, *fakeProvider) {
+	t.Helper()
+	numrot := &fakeProvider{name: "numrot"}
+	dian := &fakeProvider{name: "dian"}
+	rules, err := ParseRules(specs)
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	router, err := NewRouter(map[string]invoice.Provider{"numrot": numrot, "dian": dian}, rules, "numrot", fallback, repo, testLogger())
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	return router, numrot, dian
+}
+
+func TestNewRouter_UnknownProvider(t *testing.T) {
+	providers := map[string]invoice.Provider{"numrot": &fakeProvider{}}
+	if _, err := NewRouter(providers, nil, "", "", nil, testLogger()); err == nil {
+		t.Error("expected error without default provider")
+	}
+	if _, err := NewRouter(providers, nil, "numrot", "dian", nil, testLogger()); err == nil {
+		t.Error("expected error for unknown fallback")
+	}
+	rules := []Rule{{OFE: "*", Tipo: "*", Operation: "*", Primary: "carvajal"}}
+	if _, err := NewRouter(providers, rules, "numrot", "", nil, testLogger()); err == nil {
+		t.Error("expected error for unknown rule provider")
+	}
+}
+
+func TestRouter_RoutesByOFEAndOperation(t *testing.T) {
+	router, numrot, dian := newTestRouter(t, []string{"900373115:*:resolutions=dian"}, "", nil)
+	ctx := context.Background()
+
+	got, err := router.GetResolutions(ctx, "900373115-6")
+	if err != nil {
+		t.Fatalf("GetResolutions() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Prefix != "dian" {
+		t.Errorf("GetResolutions() routed to %+v, want dian", got)
+	}
+
+	if _, err := router.GetResolutions(ctx, "860000000"); err != nil {
+		t.Fatalf("GetResolutions() error = %v", err)
+	}
+	if _, err := router.GetDocuments(ctx, invoice.DocumentQuery{CompanyNit: "900373115"}); err != nil {
+		t.Fatalf("GetDocuments() error = %v", err)
+	}
+	if dian.calls != 1 || numrot.calls != 2 {
+		t.Errorf("calls dian=%d numrot=%d, want 1 and 2", dian.calls, numrot.calls)
+	}
+}
+
+func TestRouter_FallbackWhenCircuitOpen(t *testing.T) {
+	router, numrot, _ := newTestRouter(t, nil, "dian", nil)
+	numrot.open = true
+
+	got, err := router.GetResolutions(context.Background(), "900373115")
+	if err != nil {
+		t.Fatalf("GetResolutions() error = %v", err)
+	}
+	if got[0].Prefix != "dian" || numrot.calls != 0 {
+		t.Errorf("expected fallback to dian without calling numrot, got %+v (numrot calls %d)", got, numrot.calls)
+	}
+}
+
+func TestRouter_RetriesOnFallbackWhenCircuitOpens(t *testing.T) {
+	router, numrot, dian := newTestRouter(t, nil, "dian", nil)
+	numrot.err = errors.New("numrot unavailable")
+	numrot.openOnError = true
+
+	if _, err := router.GetResolutions(context.Background(), "900373115"); err != nil {
+		t.Fatalf("GetResolutions() error = %v", err)
+	}
+	if numrot.calls != 1 || dian.calls != 1 {
+		t.Errorf("calls numrot=%d dian=%d, want 1 and 1", numrot.calls, dian.calls)
+	}
+}
+
+func TestRouter_NoRetryWhileCircuitClosed(t *testing.T) {
+	router, numrot, dian := newTestRouter(t, nil, "dian", nil)
+	numrot.err = errors.New("invalid request")
+
+	if _, err := router.GetResolutions(context.Background(), "900373115"); err == nil {
+		t.Fatal("expected error from primary provider")
+	}
+	if dian.calls != 0 {
+		t.Errorf("fallback should not be used while the circuit is closed, got %d calls", dian.calls)
+	}
+}
+
+func TestRouter_CircuitOpen(t *testing.T) {
+	router, numrot, dian := newTestRouter(t, nil, "dian", nil)
+	if router.CircuitOpen() {
+		t.Error("expected the circuit closed while numrot is available")
+	}
+
+	numrot.open = true
+	if router.CircuitOpen() {
+		t.Error("expected the circuit closed while the fallback is available")
+	}
+
+	dian.open = true
+	if !router.CircuitOpen() {
+		t.Error("expected the circuit open when every provider fails fast")
+	}
+}
+
+func TestRouter_RegisterDocument_SplitsAndAudits(t *testing.T) {
+	repo := &mockAuditRepo{}
+	router, numrot, dian := newTestRouter(t, []string{"900373115:FC:register=dian/numrot"}, "", repo)
+	ctx := ctxutil.WithCorrelationID(context.Background(), "corr-1")
+
+	req := invoice.DocumentRegistrationRequest{}
+	req.Documentos.FC = []invoice.OpenETLDocument{
+		{OfeIdentificacion: "900373115", RfaPrefijo: "SETP", CdoConsecutivo: "1"},
+		{OfeIdentificacion: "860000000", RfaPrefijo: "FE", CdoConsecutivo: "2"},
+	}
+	req.Documentos.NC = []invoice.OpenETLDocument{
+		{OfeIdentificacion: "900373115", RfaPrefijo: "NC", CdoConsecutivo: "3"},
+	}
+
+	resp, err := router.RegisterDocument(ctx, req)
+	if err != nil {
+		t.Fatalf("RegisterDocument() error = %v", err)
+	}
+	if len(resp.DocumentosProcesados) != 3 || len(resp.DocumentosFallidos) != 0 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.Message != "Documentos procesados exitosamente" {
+		t.Errorf("Message = %q", resp.Message)
+	}
+	if len(dian.registered) != 1 || dian.registered[0] != "SETP1" {
+		t.Errorf("dian registered %v, want [SETP1]", dian.registered)
+	}
+	if len(numrot.registered) != 2 {
+		t.Errorf("numrot registered %v, want 2 documents", numrot.registered)
+	}
+
+	if len(repo.saved) != 3 {
+		t.Fatalf("audit records = %d, want 3", len(repo.saved))
+	}
+	for _, entry := range repo.saved {
+		if entry.CorrelationID != "corr-1" || entry.Operation != auditOperation {
+			t.Errorf("unexpected audit entry %+v", entry)
+		}
+		var body routedDocument
+		if err := json.Unmarshal(entry.RequestBody, &body); err != nil {
+			t.Fatalf("invalid audit request body: %v", err)
+		}
+		want := "numrot"
+		if body.Prefijo == "SETP" {
+			want = "dian"
+		}
+		if entry.Provider != want {
+			t.Errorf("document %s%s audited with provider %q, want %q", body.Prefijo, body.Consecutivo, entry.Provider, want)
+		}
+	}
+}
+
+func TestRouter_RegisterDocument_FallbackAndFailedCall(t *testing.T) {
+	repo := &mockAuditRepo{}
+	router, numrot, dian := newTestRouter(t, []string{"900373115:FC:register=dian/numrot"}, "", repo)
+	dian.open = true
+	numrot.err = errors.New("numrot unavailable")
+
+	req := invoice.DocumentRegistrationRequest{}
+	req.Documentos.FC = []invoice.OpenETLDocument{{OfeIdentificacion: "900373115", RfaPrefijo: "SETP", CdoConsecutivo: "1"}}
+	req.Documentos.DS = []invoice.OpenETLDocument{{OfeIdentificacion: "12345", AdqIdentificacion: "900373115", RfaPrefijo: "DS", CdoConsecutivo: "9"}}
+
+	resp, err := router.RegisterDocument(context.Background(), req)
+	// The outcome of the failed call is unknown: the error is returned, not a rejection
+	if !errors.Is(err, numrot.err) || resp != nil {
+		t.Fatalf("expected the provider error, got resp=%+v err=%v", resp, err)
+	}
+	if numrot.calls != 1 {
+		t.Errorf("expected the remaining parts not to be sent after the failure, got %d numrot calls", numrot.calls)
+	}
+	if dian.calls != 0 {
+		t.Errorf("dian should be skipped while its circuit is open, got %d calls", dian.calls)
+	}
+
+	if len(repo.saved) == 0 {
+		t.Fatal("expected audit entries for the failed call")
+	}
+	var body routedDocument
+	for _, entry := range repo.saved {
+		_ = json.Unmarshal(entry.RequestBody, &body)
+		if body.Prefijo == "SETP" && (entry.Provider != "numrot" || body.Motivo != "circuito abierto en dian") {
+			t.Errorf("fallback not recorded: provider %q, motivo %q", entry.Provider, body.Motivo)
+		}
+		if entry.ErrorMessage == "" {
+			t.Errorf("expected error message in audit entry for %s", body.Prefijo)
+		}
+	}
+}
+
+func TestRouter_RegisterDocument_SingleRouteReturnsProviderError(t *testing.T) {
+	router, numrot, _ := newTestRouter(t, nil, "", nil)
+	numrot.err = errors.New("numrot unavailable")
+
+	req := invoice.DocumentRegistrationRequest{}
+	req.Documentos.FC = []invoice.OpenETLDocument{{OfeIdentificacion: "900373115", RfaPrefijo: "FE", CdoConsecutivo: "1"}}
+	resp, err := router.RegisterDocument(context.Background(), req)
+	if !errors.Is(err, numrot.err) || resp != nil {
+		t.Errorf("expected the provider error instead of failed documents, got resp=%+v err=%v", resp, err)
+	}
+	if _, err := router.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{}); err == nil {
+		t.Error("expected error without documents")
+	}
+}
+
+func TestRouter_RegisterDocument_NoFailoverAfterFailedCall(t *testing.T) {
+	router, numrot, dian := newTestRouter(t, nil, "dian", nil)
+	// The call times out after Numrot may have submitted the document, opening the circuit
+	numrot.err = errors.New("context deadline exceeded")
+	numrot.openOnError = true
+
+	req := invoice.DocumentRegistrationRequest{}
+	req.Documentos.FC = []invoice.OpenETLDocument{{OfeIdentificacion: "900373115", RfaPrefijo: "FE", CdoConsecutivo: "1"}}
+	if _, err := router.RegisterDocument(context.Background(), req); !errors.Is(err, numrot.err) {
+		t.Fatalf("expected the provider error, got %v", err)
+	}
+	if dian.calls != 0 {
+		t.Errorf("expected the document not to be resent on the fallback, got %d dian calls", dian.calls)
+	}
+
+	// The next request finds the circuit open and goes to the fallback directly
+	if _, err := router.RegisterDocument(context.Background(), req); err != nil || dian.calls != 1 {
+		t.Errorf("expected the fallback to be used once the circuit is open, dian calls=%d err=%v", dian.calls, err)
+	}
+}
+
+func TestRouter_RegisterEvent_NoFailoverAfterFailedCall(t *testing.T) {
+	router, numrot, dian := newTestRouter(t, nil, "dian", nil)
+	numrot.err = errors.New("context deadline exceeded")
+	numrot.openOnError = true
+
+	if _, err := router.RegisterEvent(context.Background(), event.Event{}, "900373115", "Empresa"); err == nil {
+		t.Error("expected the provider error")
+	}
+	if dian.calls != 0 {
+		t.Errorf("expected the event not to be resent on the fallback, got %d calls", dian.calls)
+	}
+}
@@ -0,0 +1,106 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/identification"
+)
+
+// Operations that can be routed independently.
+const (
+	OpResolutions = "resolutions" // GetResolutions
+	OpDocuments   = "documents"   // GetDocuments
+	OpDocument    = "document"    // GetDocumentByNumber
+	OpReceived    = "received"    // GetReceivedDocuments
+	OpEvents      = "events"      // RegisterEvent
+	OpRegister    = "register"    // RegisterDocument
+)
+
+var operations = map[string]bool{
+	OpResolutions: true,
+	OpDocuments:   true,
+	OpDocument:    true,
+	OpReceived:    true,
+	OpEvents:      true,
+	OpRegister:    true,
+}
+
+// wildcard matches any OFE, document type or operation.
+const wildcard = "*"
+
+// Rule sends the requests that match OFE, document type and operation to Primary,
+// and to Fallback (optional) while the circuit of Primary is open.
+type Rule struct {
+	OFE       string
+	Tipo      string
+	Operation string
+	Primary   string
+	Fallback  string
+}
+
+// ParseRules parses rules written as "ofe:tipo:operacion=primario[/secundario]",
+// e.g. "900373115:FC:register=dian/numrot". Each selector accepts "*" and trailing
+// selectors may be omitted ("900373115=dian" routes every request of that OFE).
+func ParseRules(specs []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(specs))
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		selector, target, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("regla de enrutamiento inválida %q: se espera ofe:tipo:operacion=proveedor", spec)
+		}
+
+		parts := strings.Split(selector, ":")
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("regla de enrutamiento inválida %q: demasiados selectores", spec)
+		}
+		for len(parts) < 3 {
+			parts = append(parts, wildcard)
+		}
+		for i := range parts {
+			if parts[i] = strings.TrimSpace(parts[i]); parts[i] == "" {
+				parts[i] = wildcard
+			}
+		}
+
+		rule := Rule{
+			OFE:       normalizeNIT(parts[0]),
+			Tipo:      strings.ToUpper(parts[1]),
+			Operation: strings.ToLower(parts[2]),
+		}
+		if rule.OFE == "" {
+			rule.OFE = wildcard
+		}
+		if rule.Operation != wildcard && !operations[rule.Operation] {
+			return nil, fmt.Errorf("regla de enrutamiento inválida %q: operación desconocida %q", spec, rule.Operation)
+		}
+
+		primary, fallback, _ := strings.Cut(target, "/")
+		rule.Primary = strings.ToLower(strings.TrimSpace(primary))
+		rule.Fallback = strings.ToLower(strings.TrimSpace(fallback))
+		if rule.Primary == "" {
+			return nil, fmt.Errorf("regla de enrutamiento inválida %q: proveedor requerido", spec)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r Rule) matches(ofe, tipo, operation string) bool {
+	return (r.OFE == wildcard || r.OFE == ofe) &&
+		(r.Tipo == wildcard || r.Tipo == tipo) &&
+		(r.Operation == wildcard || r.Operation == operation)
+}
+
+// normalizeNIT strips the verification digit so "900373115-6" and "900373115" match.
+func normalizeNIT(nit string) string {
+	nit = strings.TrimSpace(nit)
+	if nit == wildcard {
+		return nit
+	}
+	return identification.Base(nit)
+}
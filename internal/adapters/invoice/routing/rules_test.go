@@ -0,0 +1,45 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []Rule
+		wantErr bool
+	}{
+		{
+			name:  "full rule with fallback",
+			specs: []string{"900373115-6:fc:Register=DIAN/numrot"},
+			want:  []Rule{{OFE: "900373115", Tipo: "FC", Operation: OpRegister, Primary: "dian", Fallback: "numrot"}},
+		},
+		{
+			name:  "omitted selectors default to wildcard",
+			specs: []string{"900373115=dian", " ", "*:DS=numrot"},
+			want: []Rule{
+				{OFE: "900373115", Tipo: "*", Operation: "*", Primary: "dian"},
+				{OFE: "*", Tipo: "DS", Operation: "*", Primary: "numrot"},
+			},
+		},
+		{name: "missing provider", specs: []string{"900373115:FC:register="}, wantErr: true},
+		{name: "missing target", specs: []string{"900373115"}, wantErr: true},
+		{name: "unknown operation", specs: []string{"*:*:cancel=dian"}, wantErr: true},
+		{name: "too many selectors", specs: []string{"a:b:c:d=dian"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRules(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
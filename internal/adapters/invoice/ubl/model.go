@@ -0,0 +1,363 @@
+package ubl
+
+import "encoding/xml"
+
+// UBL 2.1 and DIAN namespaces.
+const (
+	nsCAC     = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	nsCBC     = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+	nsEXT     = "urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2"
+	nsSTS     = "dian:gov:co:facturaelectronica:Structures-2-1"
+	nsDS      = "http://www.w3.org/2000/09/xmldsig#"
+	nsXADES   = "http://uri.etsi.org/01903/v1.3.2#"
+	nsXADES14 = "http://uri.etsi.org/01903/v1.4.1#"
+	nsXSI     = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// The XML types below use literal prefixes in their tags (cbc:, cac:, ext:, sts:)
+// because encoding/xml cannot declare prefixed namespaces by itself.
+
+type rootDocument struct {
+	XMLName        xml.Name
+	Xmlns          string `xml:"xmlns,attr"`
+	XmlnsCAC       string `xml:"xmlns:cac,attr"`
+	XmlnsCBC       string `xml:"xmlns:cbc,attr"`
+	XmlnsDS        string `xml:"xmlns:ds,attr"`
+	XmlnsEXT       string `xml:"xmlns:ext,attr"`
+	XmlnsSTS       string `xml:"xmlns:sts,attr"`
+	XmlnsXADES     string `xml:"xmlns:xades,attr"`
+	XmlnsXADES141  string `xml:"xmlns:xades141,attr"`
+	XmlnsXSI       string `xml:"xmlns:xsi,attr"`
+	SchemaLocation string `xml:"xsi:schemaLocation,attr"`
+
+	UBLExtensions ublExtensions `xml:"ext:UBLExtensions"`
+
+	UBLVersionID       string         `xml:"cbc:UBLVersionID"`
+	CustomizationID    string         `xml:"cbc:CustomizationID"`
+	ProfileID          string         `xml:"cbc:ProfileID"`
+	ProfileExecutionID string         `xml:"cbc:ProfileExecutionID"`
+	ID                 string         `xml:"cbc:ID"`
+	UUID               uuid           `xml:"cbc:UUID"`
+	IssueDate          string         `xml:"cbc:IssueDate"`
+	IssueTime          string         `xml:"cbc:IssueTime"`
+	DueDate            string         `xml:"cbc:DueDate,omitempty"`
+	InvoiceTypeCode    string         `xml:"cbc:InvoiceTypeCode,omitempty"`
+	CreditNoteTypeCode string         `xml:"cbc:CreditNoteTypeCode,omitempty"`
+	Note               []string       `xml:"cbc:Note,omitempty"`
+	DocumentCurrency   string         `xml:"cbc:DocumentCurrencyCode"`
+	LineCountNumeric   int            `xml:"cbc:LineCountNumeric"`
+	InvoicePeriod      *invoicePeriod `xml:"cac:InvoicePeriod,omitempty"`
+	Discrepancy        *discrepancy   `xml:"cac:DiscrepancyResponse,omitempty"`
+	OrderReference     *reference     `xml:"cac:OrderReference,omitempty"`
+	BillingReference   *billingRef    `xml:"cac:BillingReference,omitempty"`
+	AdditionalRef      *additionalRef `xml:"cac:AdditionalDocumentReference,omitempty"` // Contingency document (03/04)
+	SupplierParty      accountingPart `xml:"cac:AccountingSupplierParty"`
+	CustomerParty      accountingPart `xml:"cac:AccountingCustomerParty"`
+	PaymentMeans       []paymentMeans `xml:"cac:PaymentMeans,omitempty"`
+	PrepaidPayment     []prepaid      `xml:"cac:PrepaidPayment,omitempty"`
+	ExchangeRate       *exchangeRate  `xml:"cac:PaymentExchangeRate,omitempty"`
+	TaxTotal           []taxTotal     `xml:"cac:TaxTotal,omitempty"`
+	LegalMonetaryTotal *monetaryTotal `xml:"cac:LegalMonetaryTotal,omitempty"`
+	RequestedTotal     *monetaryTotal `xml:"cac:RequestedMonetaryTotal,omitempty"`
+	InvoiceLines       []line         `xml:"cac:InvoiceLine,omitempty"`
+	CreditNoteLines    []line         `xml:"cac:CreditNoteLine,omitempty"`
+	DebitNoteLines     []line         `xml:"cac:DebitNoteLine,omitempty"`
+}
+
+type ublExtensions struct {
+	Extensions []ublExtension `xml:"ext:UBLExtension"`
+}
+
+// ublExtension holds either the DIAN extensions or, once signed, the XAdES signature.
+type ublExtension struct {
+	Content extensionContent `xml:"ext:ExtensionContent"`
+}
+
+type extensionContent struct {
+	DianExtensions *dianExtensions `xml:"sts:DianExtensions,omitempty"`
+}
+
+type dianExtensions struct {
+	InvoiceControl        *invoiceControl `xml:"sts:InvoiceControl,omitempty"`
+	InvoiceSource         invoiceSource   `xml:"sts:InvoiceSource"`
+	SoftwareProvider      softwareProv    `xml:"sts:SoftwareProvider"`
+	SoftwareSecurityCode  dianID          `xml:"sts:SoftwareSecurityCode"`
+	AuthorizationProvider authProvider    `xml:"sts:AuthorizationProvider"`
+	QRCode                string          `xml:"sts:QRCode"`
+}
+
+type invoiceControl struct {
+	InvoiceAuthorization string            `xml:"sts:InvoiceAuthorization"`
+	AuthorizationPeriod  period            `xml:"sts:AuthorizationPeriod"`
+	AuthorizedInvoices   authorizedNumbers `xml:"sts:AuthorizedInvoices"`
+}
+
+type period struct {
+	StartDate string `xml:"cbc:StartDate"`
+	EndDate   string `xml:"cbc:EndDate"`
+}
+
+type authorizedNumbers struct {
+	Prefix string `xml:"sts:Prefix,omitempty"`
+	From   string `xml:"sts:From"`
+	To     string `xml:"sts:To"`
+}
+
+type invoiceSource struct {
+	IdentificationCode countryCode `xml:"cbc:IdentificationCode"`
+}
+
+type countryCode struct {
+	ListAgencyID   string `xml:"listAgencyID,attr"`
+	ListAgencyName string `xml:"listAgencyName,attr"`
+	ListSchemeURI  string `xml:"listSchemeURI,attr"`
+	Value          string `xml:",chardata"`
+}
+
+type softwareProv struct {
+	ProviderID dianID `xml:"sts:ProviderID"`
+	SoftwareID dianID `xml:"sts:SoftwareID"`
+}
+
+type authProvider struct {
+	AuthorizationProviderID dianID `xml:"sts:AuthorizationProviderID"`
+}
+
+// dianID is an identifier issued by DIAN (schemeAgencyID 195).
+type dianID struct {
+	SchemeAgencyID   string `xml:"schemeAgencyID,attr"`
+	SchemeAgencyName string `xml:"schemeAgencyName,attr"`
+	SchemeID         string `xml:"schemeID,attr,omitempty"`
+	SchemeName       string `xml:"schemeName,attr,omitempty"`
+	Value            string `xml:",chardata"`
+}
+
+type uuid struct {
+	SchemeID   string `xml:"schemeID,attr"`
+	SchemeName string `xml:"schemeName,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type discrepancy struct {
+	ReferenceID  string `xml:"cbc:ReferenceID,omitempty"`
+	ResponseCode string `xml:"cbc:ResponseCode"`
+	Description  string `xml:"cbc:Description,omitempty"`
+}
+
+type reference struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type billingRef struct {
+	InvoiceDocumentReference invoiceReference `xml:"cac:InvoiceDocumentReference"`
+}
+
+type invoiceReference struct {
+	ID        string         `xml:"cbc:ID"`
+	UUID      *referenceUUID `xml:"cbc:UUID,omitempty"`
+	IssueDate string         `xml:"cbc:IssueDate,omitempty"`
+}
+
+type referenceUUID struct {
+	SchemeName string `xml:"schemeName,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type additionalRef struct {
+	ID               string `xml:"cbc:ID"`
+	IssueDate        string `xml:"cbc:IssueDate,omitempty"`
+	DocumentTypeCode string `xml:"cbc:DocumentTypeCode"`
+}
+
+type invoicePeriod struct {
+	StartDate string `xml:"cbc:StartDate"`
+	StartTime string `xml:"cbc:StartTime,omitempty"`
+	EndDate   string `xml:"cbc:EndDate"`
+	EndTime   string `xml:"cbc:EndTime,omitempty"`
+}
+
+type accountingPart struct {
+	AdditionalAccountID string `xml:"cbc:AdditionalAccountID"`
+	Party               party  `xml:"cac:Party"`
+}
+
+type party struct {
+	PartyIdentification *partyID      `xml:"cac:PartyIdentification,omitempty"`
+	PartyName           partyName     `xml:"cac:PartyName"`
+	PhysicalLocation    *location     `xml:"cac:PhysicalLocation,omitempty"`
+	PartyTaxScheme      partyTax      `xml:"cac:PartyTaxScheme"`
+	PartyLegalEntity    partyLegal    `xml:"cac:PartyLegalEntity"`
+	Contact             *partyContact `xml:"cac:Contact,omitempty"`
+}
+
+type partyID struct {
+	ID companyID `xml:"cbc:ID"`
+}
+
+type partyName struct {
+	Name string `xml:"cbc:Name"`
+}
+
+type location struct {
+	Address address `xml:"cac:Address"`
+}
+
+type address struct {
+	ID                   string      `xml:"cbc:ID,omitempty"`
+	CityName             string      `xml:"cbc:CityName,omitempty"`
+	PostalZone           string      `xml:"cbc:PostalZone,omitempty"`
+	CountrySubentity     string      `xml:"cbc:CountrySubentity,omitempty"`
+	CountrySubentityCode string      `xml:"cbc:CountrySubentityCode,omitempty"`
+	AddressLine          addressLine `xml:"cac:AddressLine"`
+	Country              country     `xml:"cac:Country"`
+}
+
+type addressLine struct {
+	Line string `xml:"cbc:Line"`
+}
+
+type country struct {
+	IdentificationCode string      `xml:"cbc:IdentificationCode"`
+	Name               countryName `xml:"cbc:Name"`
+}
+
+type countryName struct {
+	LanguageID string `xml:"languageID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type companyID struct {
+	SchemeAgencyID   string `xml:"schemeAgencyID,attr"`
+	SchemeAgencyName string `xml:"schemeAgencyName,attr"`
+	SchemeID         string `xml:"schemeID,attr,omitempty"`
+	SchemeName       string `xml:"schemeName,attr"`
+	Value            string `xml:",chardata"`
+}
+
+type partyTax struct {
+	RegistrationName    string    `xml:"cbc:RegistrationName"`
+	CompanyID           companyID `xml:"cbc:CompanyID"`
+	TaxLevelCode        listValue `xml:"cbc:TaxLevelCode"`
+	RegistrationAddress *address  `xml:"cac:RegistrationAddress,omitempty"`
+	TaxScheme           taxScheme `xml:"cac:TaxScheme"`
+}
+
+type listValue struct {
+	ListName string `xml:"listName,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type partyLegal struct {
+	RegistrationName            string     `xml:"cbc:RegistrationName"`
+	CompanyID                   companyID  `xml:"cbc:CompanyID"`
+	CorporateRegistrationScheme *reference `xml:"cac:CorporateRegistrationScheme,omitempty"`
+}
+
+type partyContact struct {
+	ElectronicMail string `xml:"cbc:ElectronicMail"`
+}
+
+type taxScheme struct {
+	ID   string `xml:"cbc:ID"`
+	Name string `xml:"cbc:Name"`
+}
+
+type paymentMeans struct {
+	ID               string `xml:"cbc:ID"`
+	PaymentMeansCode string `xml:"cbc:PaymentMeansCode"`
+	PaymentDueDate   string `xml:"cbc:PaymentDueDate,omitempty"`
+	PaymentID        string `xml:"cbc:PaymentID,omitempty"`
+}
+
+type prepaid struct {
+	ID           string `xml:"cbc:ID"`
+	PaidAmount   amount `xml:"cbc:PaidAmount"`
+	ReceivedDate string `xml:"cbc:ReceivedDate"`
+}
+
+// exchangeRate converts a document issued in foreign currency to COP (cbc:CalculationRate is the TRM).
+type exchangeRate struct {
+	SourceCurrencyCode     string `xml:"cbc:SourceCurrencyCode"`
+	SourceCurrencyBaseRate string `xml:"cbc:SourceCurrencyBaseRate"`
+	TargetCurrencyCode     string `xml:"cbc:TargetCurrencyCode"`
+	TargetCurrencyBaseRate string `xml:"cbc:TargetCurrencyBaseRate"`
+	CalculationRate        string `xml:"cbc:CalculationRate"`
+	Date                   string `xml:"cbc:Date"`
+}
+
+type amount struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type quantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type taxTotal struct {
+	TaxAmount      amount        `xml:"cbc:TaxAmount"`
+	RoundingAmount *amount       `xml:"cbc:RoundingAmount,omitempty"`
+	TaxSubtotals   []taxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type taxSubtotal struct {
+	TaxableAmount amount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     amount      `xml:"cbc:TaxAmount"`
+	TaxCategory   taxCategory `xml:"cac:TaxCategory"`
+}
+
+type taxCategory struct {
+	Percent   string    `xml:"cbc:Percent"`
+	TaxScheme taxScheme `xml:"cac:TaxScheme"`
+}
+
+type monetaryTotal struct {
+	LineExtensionAmount   amount  `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount    amount  `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount    amount  `xml:"cbc:TaxInclusiveAmount"`
+	AllowanceTotalAmount  amount  `xml:"cbc:AllowanceTotalAmount"`
+	ChargeTotalAmount     amount  `xml:"cbc:ChargeTotalAmount"`
+	PrepaidAmount         amount  `xml:"cbc:PrepaidAmount"`
+	PayableRoundingAmount *amount `xml:"cbc:PayableRoundingAmount,omitempty"`
+	PayableAmount         amount  `xml:"cbc:PayableAmount"`
+}
+
+type line struct {
+	ID                  string      `xml:"cbc:ID"`
+	InvoicedQuantity    *quantity   `xml:"cbc:InvoicedQuantity,omitempty"`
+	CreditedQuantity    *quantity   `xml:"cbc:CreditedQuantity,omitempty"`
+	DebitedQuantity     *quantity   `xml:"cbc:DebitedQuantity,omitempty"`
+	LineExtensionAmount amount      `xml:"cbc:LineExtensionAmount"`
+	InvoicePeriod       *linePeriod `xml:"cac:InvoicePeriod,omitempty"`
+	TaxTotal            []taxTotal  `xml:"cac:TaxTotal,omitempty"`
+	Item                item        `xml:"cac:Item"`
+	Price               price       `xml:"cac:Price"`
+}
+
+type linePeriod struct {
+	StartDate       string `xml:"cbc:StartDate"`
+	DescriptionCode string `xml:"cbc:DescriptionCode"`
+	Description     string `xml:"cbc:Description"`
+}
+
+type item struct {
+	Description                string      `xml:"cbc:Description"`
+	SellersItemIdentification  *reference  `xml:"cac:SellersItemIdentification,omitempty"`
+	StandardItemIdentification *standardID `xml:"cac:StandardItemIdentification,omitempty"`
+}
+
+type standardID struct {
+	ID itemCode `xml:"cbc:ID"`
+}
+
+type itemCode struct {
+	SchemeID   string `xml:"schemeID,attr"`
+	SchemeName string `xml:"schemeName,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type price struct {
+	PriceAmount  amount   `xml:"cbc:PriceAmount"`
+	BaseQuantity quantity `xml:"cbc:BaseQuantity"`
+}
@@ -0,0 +1,789 @@
+// Package ubl renders OpenETL documents into DIAN UBL 2.1 XML: Invoice for
+// FC and DS, CreditNote for NC and DebitNote for ND, including the DIAN
+// extensions (sts:DianExtensions) required by the Anexo Técnico 1.9.
+//
+// The second UBLExtension holds the XAdES signature. Without a Signer the output
+// is unsigned and that extension is left empty.
+package ubl
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/codelist"
+	"3tcapital/goclonacion/internal/core/cufe"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/totals"
+)
+
+const (
+	dianAgencyID   = "195"
+	dianAgencyName = "CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)"
+	nitSchemeName  = "31"
+	ccSchemeName   = "13"
+
+	qrURLProduction = "https://catalogo-vpfe.dian.gov.co/document/searchqr?documentkey="
+	qrURLTest       = "https://catalogo-vpfe-hab.dian.gov.co/document/searchqr?documentkey="
+)
+
+// Settings identifies the invoicing software and the technology provider.
+type Settings struct {
+	SoftwareID  string // Identifier of the software registered at DIAN
+	ProviderNIT string // NIT of the technology provider, optionally with DV ("900123456-7")
+}
+
+// Signer signs a rendered UBL document, filling the empty signature extension.
+type Signer interface {
+	Sign(data []byte) ([]byte, error)
+}
+
+// Renderer renders OpenETL documents into UBL 2.1 XML.
+type Renderer struct {
+	settings Settings
+	keys     cufe.KeyResolver
+	signer   Signer
+}
+
+// NewRenderer creates a renderer. keys is optional: without keys the CUFE/CUDE
+// and the software security code are left empty.
+func NewRenderer(settings Settings, keys cufe.KeyResolver) *Renderer {
+	return &Renderer{settings: settings, keys: keys}
+}
+
+// WithSigner signs every rendered document with the given signer.
+func (r *Renderer) WithSigner(signer Signer) *Renderer {
+	r.signer = signer
+	return r
+}
+
+// Render returns the UBL 2.1 XML of the document together with its CUFE/CUDE/CUDS.
+func (r *Renderer) Render(doc invoice.OpenETLDocument, documentType string) ([]byte, string, error) {
+	profile, ok := profiles[documentType]
+	if !ok {
+		return nil, "", fmt.Errorf("tipo de documento inválido: %s", documentType)
+	}
+	if doc.OfeIdentificacion == "" {
+		return nil, "", fmt.Errorf("ofe_identificacion es requerido")
+	}
+	if doc.RfaPrefijo+doc.CdoConsecutivo == "" {
+		return nil, "", fmt.Errorf("cdo_consecutivo es requerido")
+	}
+
+	ambiente := "2"
+	if doc.CdoAmbiente != nil && *doc.CdoAmbiente == "1" {
+		ambiente = "1"
+	}
+	currency := doc.MonCodigo
+	if currency == "" {
+		currency = "COP"
+	}
+	m := money{currency: currency}
+
+	code, keys, err := r.documentCode(doc, documentType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	taxes, err := documentTaxes(doc, documentType, m)
+	if err != nil {
+		return nil, "", err
+	}
+	totals, err := monetaryTotals(doc, taxes, m)
+	if err != nil {
+		return nil, "", err
+	}
+	lines, err := invoiceLines(doc, documentType, m)
+	if err != nil {
+		return nil, "", err
+	}
+
+	number := doc.RfaPrefijo + doc.CdoConsecutivo
+	root := rootDocument{
+		XMLName:        xml.Name{Local: profile.root},
+		Xmlns:          "urn:oasis:names:specification:ubl:schema:xsd:" + profile.root + "-2",
+		XmlnsCAC:       nsCAC,
+		XmlnsCBC:       nsCBC,
+		XmlnsDS:        nsDS,
+		XmlnsEXT:       nsEXT,
+		XmlnsSTS:       nsSTS,
+		XmlnsXADES:     nsXADES,
+		XmlnsXADES141:  nsXADES14,
+		XmlnsXSI:       nsXSI,
+		SchemaLocation: "urn:oasis:names:specification:ubl:schema:xsd:" + profile.root + "-2 http://docs.oasis-open.org/ubl/os-UBL-2.1/xsd/maindoc/UBL-" + profile.root + "-2.1.xsd",
+		UBLExtensions: ublExtensions{Extensions: []ublExtension{
+			{Content: extensionContent{DianExtensions: r.dianExtensions(doc, documentType, number, code, keys, ambiente, taxes)}},
+			{}, // Placeholder for the XAdES signature
+		}},
+		UBLVersionID:       "UBL 2.1",
+		CustomizationID:    customizationID(doc, documentType),
+		ProfileID:          profile.profileID,
+		ProfileExecutionID: ambiente,
+		ID:                 number,
+		UUID:               uuid{SchemeID: ambiente, SchemeName: profile.codeScheme, Value: code},
+		IssueDate:          doc.CdoFecha,
+		IssueTime:          cufe.IssueTime(doc.CdoHora),
+		Note:               doc.Note,
+		DocumentCurrency:   currency,
+		LineCountNumeric:   len(lines),
+		SupplierParty:      supplierParty(doc, documentType),
+		CustomerParty:      customerParty(doc, documentType),
+		PaymentMeans:       buildPaymentMeans(doc),
+		TaxTotal:           taxes.totals,
+	}
+
+	switch documentType {
+	case "FC", "DS":
+		root.InvoiceTypeCode = invoiceTypeCode(doc, documentType)
+		if doc.CdoVencimiento != nil {
+			root.DueDate = *doc.CdoVencimiento
+		}
+		root.LegalMonetaryTotal = totals
+		root.InvoiceLines = lines
+	case "NC":
+		root.CreditNoteTypeCode = "91"
+		root.LegalMonetaryTotal = totals
+		root.CreditNoteLines = lines
+	case "ND":
+		root.RequestedTotal = totals
+		root.DebitNoteLines = lines
+	}
+
+	if documentType == "NC" || documentType == "ND" {
+		root.Discrepancy, root.BillingReference = correction(doc)
+		if p := doc.CdoPeriodoFacturacion; p != nil && root.BillingReference == nil {
+			root.InvoicePeriod = &invoicePeriod{StartDate: p.FechaInicio, StartTime: p.HoraInicio, EndDate: p.FechaFin, EndTime: p.HoraFin}
+		}
+	}
+	if doc.OrderReference != nil && doc.OrderReference.ID != "" {
+		root.OrderReference = &reference{ID: doc.OrderReference.ID}
+	}
+	if ref := doc.CdoDocumentoContingencia; ref != nil && ref.Numero != "" {
+		root.AdditionalRef = &additionalRef{ID: ref.Numero, IssueDate: ref.Fecha, DocumentTypeCode: ref.Tipo}
+	}
+	if prepaidAmount, err := m.amount(doc.CdoAnticipo); err != nil {
+		return nil, "", fmt.Errorf("cdo_anticipo: %w", err)
+	} else if !isZero(prepaidAmount.Value) {
+		root.PrepaidPayment = []prepaid{{ID: "1", PaidAmount: prepaidAmount, ReceivedDate: doc.CdoFecha}}
+	}
+	if currency != "COP" && doc.CdoTrm != nil && *doc.CdoTrm != "" {
+		date := doc.CdoFecha
+		if doc.CdoTrmFecha != nil && *doc.CdoTrmFecha != "" {
+			date = *doc.CdoTrmFecha
+		}
+		root.ExchangeRate = &exchangeRate{
+			SourceCurrencyCode:     currency,
+			SourceCurrencyBaseRate: "1.00",
+			TargetCurrencyCode:     "COP",
+			TargetCurrencyBaseRate: "1.00",
+			CalculationRate:        *doc.CdoTrm,
+			Date:                   date,
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return nil, "", fmt.Errorf("encode UBL: %w", err)
+	}
+	buf.WriteString("\n")
+
+	if r.signer == nil {
+		return buf.Bytes(), code, nil
+	}
+	signed, err := r.signer.Sign(buf.Bytes())
+	if err != nil {
+		return nil, "", fmt.Errorf("firmar documento: %w", err)
+	}
+	return signed, code, nil
+}
+
+type profile struct {
+	root       string
+	profileID  string
+	codeScheme string
+}
+
+var profiles = map[string]profile{
+	"FC": {root: "Invoice", profileID: "DIAN 2.1: Factura Electrónica de Venta", codeScheme: "CUFE-SHA384"},
+	"NC": {root: "CreditNote", profileID: "DIAN 2.1: Nota Crédito de Factura Electrónica de Venta", codeScheme: "CUDE-SHA384"},
+	"ND": {root: "DebitNote", profileID: "DIAN 2.1: Nota Débito de Factura Electrónica de Venta", codeScheme: "CUDE-SHA384"},
+	"DS": {root: "Invoice", profileID: "DIAN 2.1: documento soporte en adquisiciones efectuadas a no obligados a facturar.", codeScheme: "CUDS-SHA384"},
+}
+
+// documentCode computes the CUFE/CUDE/CUDS when the keys of the OFE are known.
+func (r *Renderer) documentCode(doc invoice.OpenETLDocument, documentType string) (string, cufe.Keys, error) {
+	if r.keys == nil {
+		return "", cufe.Keys{}, nil
+	}
+	keys, ok := r.keys.Resolve(issuerNIT(doc, documentType), doc.RfaPrefijo)
+	if !ok {
+		return "", cufe.Keys{}, nil
+	}
+	code, err := cufe.ForDocument(doc, documentType, keys)
+	if err != nil {
+		return "", cufe.Keys{}, fmt.Errorf("calcular CUFE/CUDE: %w", err)
+	}
+	return code, keys, nil
+}
+
+func (r *Renderer) dianExtensions(doc invoice.OpenETLDocument, documentType, number, code string, keys cufe.Keys, ambiente string, taxes documentTaxTotals) *dianExtensions {
+	providerNIT, providerDV := identification.Split(r.settings.ProviderNIT)
+
+	ext := &dianExtensions{
+		InvoiceSource: invoiceSource{IdentificationCode: countryCode{
+			ListAgencyID:   "6",
+			ListAgencyName: "United Nations Economic Commission for Europe",
+			ListSchemeURI:  "urn:oasis:names:specification:ubl:codelist:gc:CountryIdentificationCode-2.1",
+			Value:          "CO",
+		}},
+		SoftwareProvider: softwareProv{
+			ProviderID: dianID{SchemeAgencyID: dianAgencyID, SchemeAgencyName: dianAgencyName, SchemeID: providerDV, SchemeName: nitSchemeName, Value: providerNIT},
+			SoftwareID: dianID{SchemeAgencyID: dianAgencyID, SchemeAgencyName: dianAgencyName, Value: r.settings.SoftwareID},
+		},
+		SoftwareSecurityCode: dianID{SchemeAgencyID: dianAgencyID, SchemeAgencyName: dianAgencyName, Value: softwareSecurityCode(r.settings.SoftwareID, keys.SoftwarePIN, number)},
+		AuthorizationProvider: authProvider{AuthorizationProviderID: dianID{
+			SchemeAgencyID:   dianAgencyID,
+			SchemeAgencyName: dianAgencyName,
+			SchemeID:         "4",
+			SchemeName:       nitSchemeName,
+			Value:            "800197268", // NIT of DIAN, the authorization provider
+		}},
+		QRCode: qrCode(doc, documentType, number, code, ambiente, taxes),
+	}
+
+	if documentType == "FC" || documentType == "DS" {
+		ext.InvoiceControl = &invoiceControl{
+			InvoiceAuthorization: doc.RfaResolucion,
+			AuthorizationPeriod: period{
+				StartDate: stringValue(doc.RfaFechaInicio),
+				EndDate:   stringValue(doc.RfaFechaFin),
+			},
+			AuthorizedInvoices: authorizedNumbers{
+				Prefix: doc.RfaPrefijo,
+				From:   stringValue(doc.RfaNumeroInicio),
+				To:     stringValue(doc.RfaNumeroFin),
+			},
+		}
+	}
+
+	return ext
+}
+
+// softwareSecurityCode is SHA-384(SoftwareID + PIN + document number).
+func softwareSecurityCode(softwareID, pin, number string) string {
+	if softwareID == "" || pin == "" {
+		return ""
+	}
+	sum := sha512.Sum384([]byte(softwareID + pin + number))
+	return hex.EncodeToString(sum[:])
+}
+
+func qrCode(doc invoice.OpenETLDocument, documentType, number, code, ambiente string, taxes documentTaxTotals) string {
+	url := qrURLTest
+	if ambiente == "1" {
+		url = qrURLProduction
+	}
+	seller, buyer := identification.Base(doc.OfeIdentificacion), identification.Base(doc.AdqIdentificacion)
+	if documentType == "DS" {
+		seller, buyer = buyer, seller
+	}
+	total, _ := cufe.FormatAmount(doc.CdoTotal)
+	subtotal, _ := cufe.FormatAmount(doc.CdoValorSinImpuestos)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "NumFac: %s\n", number)
+	fmt.Fprintf(&b, "FecFac: %s\n", doc.CdoFecha)
+	fmt.Fprintf(&b, "HorFac: %s\n", cufe.IssueTime(doc.CdoHora))
+	fmt.Fprintf(&b, "NitFac: %s\n", seller)
+	fmt.Fprintf(&b, "DocAdq: %s\n", buyer)
+	fmt.Fprintf(&b, "ValFac: %s\n", subtotal)
+	fmt.Fprintf(&b, "ValIva: %s\n", taxes.byCode(cufe.TaxIVA))
+	fmt.Fprintf(&b, "ValOtroIm: %s\n", taxes.otherThan(cufe.TaxIVA))
+	fmt.Fprintf(&b, "ValTolFac: %s\n", total)
+	fmt.Fprintf(&b, "CUFE: %s\n", code)
+	fmt.Fprintf(&b, "QRCode: %s%s", url, code)
+	return b.String()
+}
+
+func customizationID(doc invoice.OpenETLDocument, documentType string) string {
+	if doc.TopCodigo != "" {
+		return doc.TopCodigo
+	}
+	hasReference := doc.FacturaReferencia != nil && doc.FacturaReferencia.NumeroFacturaFC != ""
+	switch documentType {
+	case "NC":
+		if hasReference {
+			return "20"
+		}
+		return "22"
+	case "ND":
+		if hasReference {
+			return "30"
+		}
+		return "32"
+	default:
+		return "10"
+	}
+}
+
+func invoiceTypeCode(doc invoice.OpenETLDocument, documentType string) string {
+	if documentType == "DS" {
+		return "05"
+	}
+	if doc.TdeCodigo != "" {
+		return doc.TdeCodigo
+	}
+	return "01"
+}
+
+// correction builds the discrepancy response and the reference to the corrected invoice.
+func correction(doc invoice.OpenETLDocument) (*discrepancy, *billingRef) {
+	var referenceID string
+	var billing *billingRef
+	if doc.FacturaReferencia != nil && doc.FacturaReferencia.NumeroFacturaFC != "" {
+		referenceID = doc.FacturaReferencia.PrefijoFC + doc.FacturaReferencia.NumeroFacturaFC
+		billing = &billingRef{InvoiceDocumentReference: invoiceReference{ID: referenceID, IssueDate: doc.FacturaReferencia.FechaEmisionFC}}
+		if doc.FacturaReferencia.CufeFC != "" {
+			billing.InvoiceDocumentReference.UUID = &referenceUUID{SchemeName: "CUFE-SHA384", Value: doc.FacturaReferencia.CufeFC}
+		}
+	}
+
+	var disc *discrepancy
+	if doc.CdoConceptosCorreccion != nil {
+		disc = &discrepancy{
+			ReferenceID:  referenceID,
+			ResponseCode: doc.CdoConceptosCorreccion.CcoCodigo,
+			Description:  doc.CdoConceptosCorreccion.CdoObservacionCorreccion,
+		}
+	}
+	return disc, billing
+}
+
+// issuerNIT returns the NIT of the OFE issuing the document, without DV.
+// In DS requests the OFE (buyer) is sent as adq_identificacion.
+func issuerNIT(doc invoice.OpenETLDocument, documentType string) string {
+	if documentType == "DS" {
+		return identification.Base(doc.AdqIdentificacion)
+	}
+	return identification.Base(doc.OfeIdentificacion)
+}
+
+// supplierParty builds the seller: the OFE for FC/NC/ND and the seller not
+// obliged to invoice (ofe_identificacion, enriched into the adq_* fields) for DS.
+func supplierParty(doc invoice.OpenETLDocument, documentType string) accountingPart {
+	if documentType == "DS" {
+		name := stringOr(doc.AdqRazonSocial, doc.OfeIdentificacion)
+		addr := buildAddress(doc.AdqMunicipioCodigo, doc.AdqMunicipioNombre, doc.AdqCpoCodigo, doc.AdqDepartamentoNombre, doc.AdqDepartamentoCodigo, doc.AdqDireccion, doc.AdqPaisCodigo, doc.AdqPaisNombre)
+		return buildParty(doc.OfeIdentificacion, name, addr, "", noTaxLevel, taxScheme{ID: "ZZ", Name: "No aplica"}, false)
+	}
+	name := stringOr(doc.OfeRazonSocial, doc.OfeIdentificacion)
+	addr := buildAddress(doc.OfeMunicipioCodigo, doc.OfeMunicipioNombre, doc.OfeCpoCodigo, doc.OfeDepartamentoNombre, doc.OfeDepartamentoCodigo, doc.OfeDireccion, nil, nil)
+	return buildParty(doc.OfeIdentificacion, name, addr, doc.RfaPrefijo, ofeTaxLevel(doc), taxScheme{ID: "01", Name: "IVA"}, false)
+}
+
+// customerParty builds the buyer: the acquirer for FC/NC/ND and the OFE for DS.
+func customerParty(doc invoice.OpenETLDocument, documentType string) accountingPart {
+	if documentType == "DS" {
+		name := stringOr(doc.OfeRazonSocial, doc.AdqIdentificacion)
+		addr := buildAddress(doc.OfeMunicipioCodigo, doc.OfeMunicipioNombre, doc.OfeCpoCodigo, doc.OfeDepartamentoNombre, doc.OfeDepartamentoCodigo, doc.OfeDireccion, nil, nil)
+		return buildParty(doc.AdqIdentificacion, name, addr, "", ofeTaxLevel(doc), taxScheme{ID: "01", Name: "IVA"}, false)
+	}
+	name := stringOr(doc.AdqRazonSocial, doc.AdqIdentificacion)
+	addr := buildAddress(doc.AdqMunicipioCodigo, doc.AdqMunicipioNombre, doc.AdqCpoCodigo, doc.AdqDepartamentoNombre, doc.AdqDepartamentoCodigo, doc.AdqDireccion, doc.AdqPaisCodigo, doc.AdqPaisNombre)
+	return buildParty(doc.AdqIdentificacion, name, addr, "", noTaxLevel, taxScheme{ID: "ZZ", Name: "No aplica"}, true)
+}
+
+// noTaxLevel is the tax responsibility of parties without registered responsibilities.
+const noTaxLevel = "R-99-PN"
+
+// ofeTaxLevel returns the tax responsibilities of the OFE registry separated by ";".
+func ofeTaxLevel(doc invoice.OpenETLDocument) string {
+	if len(doc.OfeRefCodigo) == 0 {
+		return noTaxLevel
+	}
+	return strings.Join(doc.OfeRefCodigo, ";")
+}
+
+// buildParty builds an accounting party. Identifications with DV are treated as NIT of
+// a legal person; otherwise as cédula of a natural person.
+func buildParty(number, name string, addr *address, prefix, taxLevel string, scheme taxScheme, withPartyID bool) accountingPart {
+	nit, dv := identification.Split(number)
+	accountID, schemeName := "1", nitSchemeName
+	if dv == "" {
+		accountID, schemeName = "2", ccSchemeName
+	}
+	id := companyID{SchemeAgencyID: dianAgencyID, SchemeAgencyName: dianAgencyName, SchemeID: dv, SchemeName: schemeName, Value: nit}
+
+	p := party{
+		PartyName:      partyName{Name: name},
+		PartyTaxScheme: partyTax{RegistrationName: name, CompanyID: id, TaxLevelCode: listValue{ListName: "48", Value: taxLevel}, RegistrationAddress: addr, TaxScheme: scheme},
+		PartyLegalEntity: partyLegal{
+			RegistrationName: name,
+			CompanyID:        id,
+		},
+	}
+	if addr != nil {
+		p.PhysicalLocation = &location{Address: *addr}
+	}
+	if prefix != "" {
+		p.PartyLegalEntity.CorporateRegistrationScheme = &reference{ID: prefix}
+	}
+	if withPartyID {
+		p.PartyIdentification = &partyID{ID: id}
+	}
+	return accountingPart{AdditionalAccountID: accountID, Party: p}
+}
+
+// buildAddress builds a DIVIPOLA address. Returns nil when no location data is available.
+func buildAddress(munCodigo, munNombre, cpoCodigo, depNombre, depCodigo, direccion, paisCodigo, paisNombre *string) *address {
+	if stringValue(munCodigo) == "" && stringValue(direccion) == "" {
+		return nil
+	}
+	pais := stringOr(paisCodigo, "CO")
+	nombrePais := stringOr(paisNombre, "")
+	if nombrePais == "" && pais == "CO" {
+		nombrePais = "Colombia"
+	}
+
+	mun := stringValue(munCodigo)
+	dep := stringValue(depCodigo)
+	if len(mun) == 3 && dep != "" {
+		// Municipality codes may come without the department prefix
+		mun = dep + mun
+	}
+
+	return &address{
+		ID:                   mun,
+		CityName:             stringValue(munNombre),
+		PostalZone:           stringValue(cpoCodigo),
+		CountrySubentity:     stringValue(depNombre),
+		CountrySubentityCode: dep,
+		AddressLine:          addressLine{Line: stringValue(direccion)},
+		Country:              country{IdentificationCode: pais, Name: countryName{LanguageID: "es", Value: nombrePais}},
+	}
+}
+
+func buildPaymentMeans(doc invoice.OpenETLDocument) []paymentMeans {
+	means := make([]paymentMeans, 0, len(doc.CdoMediosPago))
+	for _, mp := range doc.CdoMediosPago {
+		id := mp.FpaCodigo
+		if id == "" {
+			id = "1"
+		}
+		means = append(means, paymentMeans{
+			ID:               id,
+			PaymentMeansCode: mp.MpaCodigo,
+			PaymentDueDate:   stringValue(mp.MenFechaVencimiento),
+			PaymentID:        mp.MpaCodigo,
+		})
+	}
+	return means
+}
+
+// documentTaxTotals holds the document level TaxTotal groups and the sum per tax code.
+type documentTaxTotals struct {
+	totals []taxTotal
+	sums   map[string]*big.Rat
+	base   *big.Rat
+}
+
+func (t documentTaxTotals) byCode(code string) string {
+	if sum, ok := t.sums[code]; ok {
+		return sum.FloatString(2)
+	}
+	return "0.00"
+}
+
+func (t documentTaxTotals) otherThan(code string) string {
+	other := new(big.Rat)
+	for c, sum := range t.sums {
+		if c != code {
+			other.Add(other, sum)
+		}
+	}
+	return other.FloatString(2)
+}
+
+// documentTaxes groups the tributos by tax code and percentage, in code order.
+func documentTaxes(doc invoice.OpenETLDocument, documentType string, m money) (documentTaxTotals, error) {
+	result := documentTaxTotals{sums: map[string]*big.Rat{}, base: new(big.Rat)}
+
+	type group struct {
+		base, tax *big.Rat
+	}
+	groups := map[string]map[string]*group{}
+
+	tributos := doc.Tributos
+	if len(tributos) == 0 && !isZero(doc.CdoImpuestos) {
+		// Documents without tax detail report cdo_impuestos as IVA
+		tributos = []invoice.OpenETLTributo{{
+			TriCodigo:     cufe.TaxIVA,
+			IidValor:      doc.CdoImpuestos,
+			IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: doc.CdoValorSinImpuestos, IidPorcentaje: "0"},
+		}}
+	}
+
+	for _, t := range tributos {
+		tax, err := parseDecimal(t.IidValor)
+		if err != nil {
+			return result, fmt.Errorf("tributos[%s].iid_valor: %w", t.DdoSecuencia, err)
+		}
+		if tax.Sign() == 0 {
+			continue
+		}
+		code := t.TriCodigo
+		if code == "" {
+			code = cufe.TaxIVA
+		}
+		percent, base := "0", "0"
+		if t.IidPorcentaje != nil {
+			percent, base = t.IidPorcentaje.IidPorcentaje, t.IidPorcentaje.IidBase
+		}
+		baseValue, err := parseDecimal(base)
+		if err != nil {
+			return result, fmt.Errorf("tributos[%s].iid_base: %w", t.DdoSecuencia, err)
+		}
+		percentValue, err := parseDecimal(percent)
+		if err != nil {
+			return result, fmt.Errorf("tributos[%s].iid_porcentaje: %w", t.DdoSecuencia, err)
+		}
+		percentKey := percentValue.FloatString(2)
+
+		if groups[code] == nil {
+			groups[code] = map[string]*group{}
+		}
+		g, ok := groups[code][percentKey]
+		if !ok {
+			g = &group{base: new(big.Rat), tax: new(big.Rat)}
+			groups[code][percentKey] = g
+		}
+		g.base.Add(g.base, baseValue)
+		g.tax.Add(g.tax, tax)
+	}
+
+	for _, code := range sortedKeys(groups) {
+		sum := new(big.Rat)
+		total := taxTotal{}
+		for _, percent := range sortedKeys(groups[code]) {
+			g := groups[code][percent]
+			sum.Add(sum, g.tax)
+			result.base.Add(result.base, g.base)
+			total.TaxSubtotals = append(total.TaxSubtotals, taxSubtotal{
+				TaxableAmount: m.rat(g.base),
+				TaxAmount:     m.rat(g.tax),
+				TaxCategory:   taxCategory{Percent: percent, TaxScheme: taxScheme{ID: code, Name: codelist.TaxName(code)}},
+			})
+		}
+		total.TaxAmount = m.rat(sum)
+		result.sums[code] = sum
+		result.totals = append(result.totals, total)
+	}
+
+	return result, nil
+}
+
+func monetaryTotals(doc invoice.OpenETLDocument, taxes documentTaxTotals, m money) (*monetaryTotal, error) {
+	result := &monetaryTotal{}
+	fields := []struct {
+		name   string
+		value  string
+		target *amount
+	}{
+		{"cdo_valor_sin_impuestos", doc.CdoValorSinImpuestos, &result.LineExtensionAmount},
+		{"cdo_total", doc.CdoTotal, &result.TaxInclusiveAmount},
+		{"cdo_descuentos", doc.CdoDescuentos, &result.AllowanceTotalAmount},
+		{"cdo_cargos", doc.CdoCargos, &result.ChargeTotalAmount},
+		{"cdo_anticipo", doc.CdoAnticipo, &result.PrepaidAmount},
+	}
+	for _, f := range fields {
+		a, err := m.amount(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.target = a
+	}
+
+	// PayableAmount adds charges and subtracts discounts and anticipos (FAU14)
+	payable, err := totals.Payable(doc)
+	if err != nil {
+		return nil, err
+	}
+	result.PayableAmount = m.rat(payable)
+
+	// TaxExclusiveAmount is the sum of the taxable bases (zero without taxes)
+	result.TaxExclusiveAmount = m.rat(taxes.base)
+
+	if !isZero(doc.CdoRedondeo) {
+		rounding, err := m.amount(doc.CdoRedondeo)
+		if err != nil {
+			return nil, fmt.Errorf("cdo_redondeo: %w", err)
+		}
+		result.PayableRoundingAmount = &rounding
+	}
+
+	return result, nil
+}
+
+// invoiceLines builds the document lines, with the quantity element of the document type.
+func invoiceLines(doc invoice.OpenETLDocument, documentType string, m money) ([]line, error) {
+	lines := make([]line, 0, len(doc.Items))
+	for i, it := range doc.Items {
+		unit := codelist.UnitCode(it.UndCodigo)
+		qty, err := parseDecimal(it.DdoCantidad)
+		if err != nil {
+			return nil, fmt.Errorf("items[%d].ddo_cantidad: %w", i, err)
+		}
+		lineTotal, err := m.amount(it.DdoTotal)
+		if err != nil {
+			return nil, fmt.Errorf("items[%d].ddo_total: %w", i, err)
+		}
+		unitPrice, err := m.amount(it.DdoValorUnitario)
+		if err != nil {
+			return nil, fmt.Errorf("items[%d].ddo_valor_unitario: %w", i, err)
+		}
+
+		l := line{
+			ID:                  strconv.Itoa(i + 1),
+			LineExtensionAmount: lineTotal,
+			Item:                item{Description: it.DdoDescripcionUno},
+			Price: price{
+				PriceAmount:  unitPrice,
+				BaseQuantity: quantity{UnitCode: unit, Value: formatQuantity(qty)},
+			},
+		}
+		lineQuantity := &quantity{UnitCode: unit, Value: formatQuantity(qty)}
+		switch documentType {
+		case "NC":
+			l.CreditedQuantity = lineQuantity
+		case "ND":
+			l.DebitedQuantity = lineQuantity
+		default:
+			l.InvoicedQuantity = lineQuantity
+		}
+		if it.DdoCodigo != "" {
+			l.Item.SellersItemIdentification = &reference{ID: it.DdoCodigo}
+			l.Item.StandardItemIdentification = &standardID{ID: itemCode{SchemeID: "999", SchemeName: "Estándar de adopción del contribuyente", Value: it.DdoCodigo}}
+		}
+		if it.DdoFechaCompra != nil && it.DdoFechaCompra.FechaCompra != "" {
+			description := "Por operación"
+			if it.DdoFechaCompra.Codigo == "2" {
+				description = "Acumulado semanal"
+			}
+			l.InvoicePeriod = &linePeriod{StartDate: it.DdoFechaCompra.FechaCompra, DescriptionCode: it.DdoFechaCompra.Codigo, Description: description}
+		}
+
+		for _, t := range doc.Tributos {
+			if t.DdoSecuencia != it.DdoSecuencia {
+				continue
+			}
+			tax, err := parseDecimal(t.IidValor)
+			if err != nil {
+				return nil, fmt.Errorf("tributos[%s].iid_valor: %w", t.DdoSecuencia, err)
+			}
+			if tax.Sign() == 0 {
+				continue
+			}
+			code := t.TriCodigo
+			if code == "" {
+				code = cufe.TaxIVA
+			}
+			percent, base := "0", it.DdoTotal
+			if t.IidPorcentaje != nil {
+				percent, base = t.IidPorcentaje.IidPorcentaje, t.IidPorcentaje.IidBase
+			}
+			percentValue, err := parseDecimal(percent)
+			if err != nil {
+				return nil, fmt.Errorf("tributos[%s].iid_porcentaje: %w", t.DdoSecuencia, err)
+			}
+			baseAmount, err := m.amount(base)
+			if err != nil {
+				return nil, fmt.Errorf("tributos[%s].iid_base: %w", t.DdoSecuencia, err)
+			}
+			l.TaxTotal = append(l.TaxTotal, taxTotal{
+				TaxAmount: m.rat(tax),
+				TaxSubtotals: []taxSubtotal{{
+					TaxableAmount: baseAmount,
+					TaxAmount:     m.rat(tax),
+					TaxCategory:   taxCategory{Percent: percentValue.FloatString(2), TaxScheme: taxScheme{ID: code, Name: codelist.TaxName(code)}},
+				}},
+			})
+		}
+
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// money formats amounts in the document currency with two decimals.
+type money struct {
+	currency string
+}
+
+func (m money) amount(value string) (amount, error) {
+	formatted, err := cufe.FormatAmount(value)
+	if err != nil {
+		return amount{}, err
+	}
+	return amount{CurrencyID: m.currency, Value: formatted}, nil
+}
+
+func (m money) rat(value *big.Rat) amount {
+	return amount{CurrencyID: m.currency, Value: value.FloatString(2)}
+}
+
+func parseDecimal(value string) (*big.Rat, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return new(big.Rat), nil
+	}
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("valor inválido: %q", value)
+	}
+	return r, nil
+}
+
+func formatQuantity(qty *big.Rat) string {
+	if qty.IsInt() {
+		return qty.FloatString(0)
+	}
+	return strings.TrimRight(qty.FloatString(6), "0")
+}
+
+func isZero(value string) bool {
+	r, err := parseDecimal(value)
+	return err != nil || r.Sign() == 0
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func stringOr(s *string, fallback string) string {
+	if s == nil || *s == "" {
+		return fallback
+	}
+	return *s
+}
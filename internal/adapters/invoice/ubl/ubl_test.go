@@ -0,0 +1,382 @@
+package ubl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/cufe"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func strPtr(s string) *string { return &s }
+
+func testRenderer() *Renderer {
+	return NewRenderer(
+		Settings{SoftwareID: "56f2ae4e-9812-4fad-9255-643fc46e7cc2", ProviderNIT: "900508908-5"},
+		cufe.StaticKeys{ClaveTecnica: "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c", SoftwarePIN: "12345"},
+	)
+}
+
+func baseDocument() invoice.OpenETLDocument {
+	return invoice.OpenETLDocument{
+		TdeCodigo:             "01",
+		OfeIdentificacion:     "860011153-6",
+		AdqIdentificacion:     "900123456-1",
+		RfaPrefijo:            "SETT",
+		RfaResolucion:         "18760000001",
+		RfaFechaInicio:        strPtr("2019-01-19"),
+		RfaFechaFin:           strPtr("2030-01-19"),
+		RfaNumeroInicio:       strPtr("1"),
+		RfaNumeroFin:          strPtr("5000000"),
+		CdoAmbiente:           strPtr("2"),
+		CdoConsecutivo:        "1001",
+		CdoFecha:              "2024-03-15",
+		CdoHora:               "14:37:00",
+		CdoVencimiento:        strPtr("2024-04-14"),
+		CdoMediosPago:         []invoice.OpenETLMedioPago{{FpaCodigo: "2", MpaCodigo: "42", MenFechaVencimiento: strPtr("2024-04-14")}},
+		MonCodigo:             "COP",
+		CdoValorSinImpuestos:  "150000.00",
+		CdoImpuestos:          "28500.00",
+		CdoTotal:              "178500.00",
+		OfeRazonSocial:        strPtr("Positiva SAS"),
+		OfeDireccion:          strPtr("CLL 50 - 96"),
+		OfeMunicipioCodigo:    strPtr("05380"),
+		OfeMunicipioNombre:    strPtr("LA ESTRELLA"),
+		OfeDepartamentoCodigo: strPtr("05"),
+		OfeDepartamentoNombre: strPtr("ANTIOQUIA"),
+		AdqRazonSocial:        strPtr("Cliente & Cía S.A.S."),
+		AdqDireccion:          strPtr("CRA 7 # 71-21"),
+		AdqMunicipioCodigo:    strPtr("11001"),
+		AdqMunicipioNombre:    strPtr("BOGOTÁ, D.C."),
+		AdqDepartamentoCodigo: strPtr("11"),
+		AdqDepartamentoNombre: strPtr("BOGOTÁ"),
+		AdqPaisCodigo:         strPtr("CO"),
+		AdqCpoCodigo:          strPtr("110231"),
+		Note:                  []string{"Factura de prueba"},
+		OrderReference:        &invoice.OpenETLOrderReference{ID: "OC-778"},
+		Items: []invoice.OpenETLItem{
+			{DdoSecuencia: "1", DdoCodigo: "SERV-01", DdoDescripcionUno: "Servicio de consultoría", DdoCantidad: "1", UndCodigo: "UN", DdoValorUnitario: "100000.00", DdoTotal: "100000.00"},
+			{DdoSecuencia: "2", DdoCodigo: "SERV-02", DdoDescripcionUno: "Horas de soporte", DdoCantidad: "2.5", UndCodigo: "HR", DdoValorUnitario: "20000.00", DdoTotal: "50000.00"},
+		},
+		Tributos: []invoice.OpenETLTributo{
+			{DdoSecuencia: "1", TriCodigo: "01", IidValor: "19000.00", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "100000.00", IidPorcentaje: "19.00"}},
+			{DdoSecuencia: "2", TriCodigo: "01", IidValor: "9500.00", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "50000.00", IidPorcentaje: "19.00"}},
+		},
+	}
+}
+
+func creditNote() invoice.OpenETLDocument {
+	doc := baseDocument()
+	doc.TdeCodigo = "91"
+	doc.RfaPrefijo = "NC"
+	doc.CdoConsecutivo = "55"
+	doc.CdoVencimiento = nil
+	doc.CdoMediosPago = []invoice.OpenETLMedioPago{{FpaCodigo: "1", MpaCodigo: "10"}}
+	doc.CdoValorSinImpuestos = "100000.00"
+	doc.CdoImpuestos = "19000.00"
+	doc.CdoTotal = "119000.00"
+	doc.OrderReference = nil
+	doc.FacturaReferencia = &invoice.OpenETLFacturaReferencia{PrefijoFC: "SETT", NumeroFacturaFC: "1001"}
+	doc.CdoConceptosCorreccion = &invoice.OpenETLConceptoCorreccion{CcoCodigo: "2", CdoObservacionCorreccion: "Anulación parcial del servicio"}
+	doc.Items = doc.Items[:1]
+	doc.Tributos = doc.Tributos[:1]
+	return doc
+}
+
+func debitNote() invoice.OpenETLDocument {
+	doc := creditNote()
+	doc.TdeCodigo = "92"
+	doc.RfaPrefijo = "ND"
+	doc.CdoConsecutivo = "12"
+	doc.CdoConceptosCorreccion = &invoice.OpenETLConceptoCorreccion{CcoCodigo: "3", CdoObservacionCorreccion: "Ajuste por intereses"}
+	return doc
+}
+
+func supportDocument() invoice.OpenETLDocument {
+	doc := baseDocument()
+	doc.TdeCodigo = "05"
+	doc.OfeIdentificacion = "1020304050"
+	doc.AdqIdentificacion = "860011153-6"
+	doc.RfaPrefijo = "DSP"
+	doc.CdoConsecutivo = "800"
+	doc.CdoVencimiento = nil
+	doc.CdoMediosPago = []invoice.OpenETLMedioPago{{FpaCodigo: "1", MpaCodigo: "10"}}
+	doc.CdoValorSinImpuestos = "350000.00"
+	doc.CdoImpuestos = "0.00"
+	doc.CdoTotal = "350000.00"
+	doc.AdqRazonSocial = strPtr("Juan Pérez")
+	doc.AdqDireccion = strPtr("CLL 10 # 20-30")
+	doc.AdqMunicipioCodigo = strPtr("05001")
+	doc.AdqMunicipioNombre = strPtr("MEDELLÍN")
+	doc.AdqDepartamentoCodigo = strPtr("05")
+	doc.AdqDepartamentoNombre = strPtr("ANTIOQUIA")
+	doc.AdqCpoCodigo = strPtr("050021")
+	doc.OrderReference = nil
+	doc.Note = nil
+	doc.Items = []invoice.OpenETLItem{
+		{DdoSecuencia: "1", DdoCodigo: "TRANS", DdoDescripcionUno: "Servicio de transporte", DdoCantidad: "1", UndCodigo: "UN", DdoValorUnitario: "350000.00", DdoTotal: "350000.00", DdoFechaCompra: &invoice.OpenETLFechaCompra{FechaCompra: "2024-03-14", Codigo: "1"}},
+	}
+	doc.Tributos = nil
+	return doc
+}
+
+func TestRender_Golden(t *testing.T) {
+	tests := []struct {
+		name         string
+		documentType string
+		doc          invoice.OpenETLDocument
+	}{
+		{name: "invoice", documentType: "FC", doc: baseDocument()},
+		{name: "credit_note", documentType: "NC", doc: creditNote()},
+		{name: "debit_note", documentType: "ND", doc: debitNote()},
+		{name: "support_document", documentType: "DS", doc: supportDocument()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, code, err := testRenderer().Render(tt.doc, tt.documentType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			expectedCode, err := cufe.ForDocument(tt.doc, tt.documentType, cufe.Keys{ClaveTecnica: "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c", SoftwarePIN: "12345"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code != expectedCode {
+				t.Errorf("expected code %s, got %s", expectedCode, code)
+			}
+
+			if err := xml.Unmarshal(got, new(struct{})); err != nil {
+				t.Fatalf("rendered XML is not well formed: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".xml")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("write golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("rendered XML differs from %s (run with -update to refresh):\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestRender_WithoutKeys(t *testing.T) {
+	got, code, err := NewRenderer(Settings{SoftwareID: "sw"}, nil).Render(baseDocument(), "FC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != "" {
+		t.Errorf("expected empty CUFE without keys, got %s", code)
+	}
+	if !strings.Contains(string(got), `<cbc:UUID schemeID="2" schemeName="CUFE-SHA384"></cbc:UUID>`) {
+		t.Error("expected empty UUID element")
+	}
+}
+
+func TestRender_ForeignCurrency(t *testing.T) {
+	doc := baseDocument()
+	doc.MonCodigo = "USD"
+	trm := "3950.25"
+	doc.CdoTrm = &trm
+
+	got, _, err := testRenderer().Render(doc, "FC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`<cbc:DocumentCurrencyCode>USD</cbc:DocumentCurrencyCode>`,
+		`<cbc:SourceCurrencyCode>USD</cbc:SourceCurrencyCode>`,
+		`<cbc:TargetCurrencyCode>COP</cbc:TargetCurrencyCode>`,
+		`<cbc:CalculationRate>3950.25</cbc:CalculationRate>`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("expected %s in rendered XML", want)
+		}
+	}
+}
+
+func TestRender_CorrectionReference(t *testing.T) {
+	doc := creditNote()
+	doc.FacturaReferencia.CufeFC = "cufe-fc"
+	doc.FacturaReferencia.FechaEmisionFC = "2024-01-15"
+
+	got, _, err := testRenderer().Render(doc, "NC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`<cbc:UUID schemeName="CUFE-SHA384">cufe-fc</cbc:UUID>`,
+		`<cbc:IssueDate>2024-01-15</cbc:IssueDate>`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("expected %s in rendered XML", want)
+		}
+	}
+	if strings.Contains(string(got), "<cac:InvoicePeriod>") {
+		t.Error("expected no invoice period for a note with invoice reference")
+	}
+
+	doc.FacturaReferencia = nil
+	doc.CdoPeriodoFacturacion = &invoice.OpenETLPeriodoFacturacion{FechaInicio: "2024-01-01", FechaFin: "2024-01-31"}
+	got, _, err = testRenderer().Render(doc, "NC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`<cbc:CustomizationID>22</cbc:CustomizationID>`,
+		`<cbc:StartDate>2024-01-01</cbc:StartDate>`,
+		`<cbc:EndDate>2024-01-31</cbc:EndDate>`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("expected %s in rendered XML", want)
+		}
+	}
+}
+
+func TestRender_ContingencyReference(t *testing.T) {
+	doc := baseDocument()
+	doc.TdeCodigo = "03"
+	doc.CdoDocumentoContingencia = &invoice.OpenETLDocumentoContingencia{Numero: "CONT7", Fecha: "2024-01-15", Tipo: "FTC"}
+
+	got, _, err := testRenderer().Render(doc, "FC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`<cbc:InvoiceTypeCode>03</cbc:InvoiceTypeCode>`,
+		`<cac:AdditionalDocumentReference>`,
+		`<cbc:ID>CONT7</cbc:ID>`,
+		`<cbc:DocumentTypeCode>FTC</cbc:DocumentTypeCode>`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("expected %s in rendered XML", want)
+		}
+	}
+}
+
+type stubSigner struct {
+	input []byte
+	err   error
+}
+
+func (s *stubSigner) Sign(data []byte) ([]byte, error) {
+	s.input = data
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []byte("signed"), nil
+}
+
+func TestRender_WithSigner(t *testing.T) {
+	signer := &stubSigner{}
+	got, _, err := testRenderer().WithSigner(signer).Render(baseDocument(), "FC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != "signed" {
+		t.Errorf("expected the signed document, got %s", got)
+	}
+	if !bytes.Contains(signer.input, []byte("<ext:ExtensionContent></ext:ExtensionContent>")) {
+		t.Error("expected the signer to receive the empty signature extension")
+	}
+
+	_, _, err = testRenderer().WithSigner(&stubSigner{err: errors.New("certificado vencido")}).Render(baseDocument(), "FC")
+	if err == nil || !strings.Contains(err.Error(), "firmar documento: certificado vencido") {
+		t.Errorf("expected signing error, got %v", err)
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		documentType string
+		mutate       func(*invoice.OpenETLDocument)
+		wantErr      string
+	}{
+		{name: "unknown type", documentType: "XX", mutate: func(d *invoice.OpenETLDocument) {}, wantErr: "tipo de documento inválido"},
+		{name: "missing OFE", documentType: "FC", mutate: func(d *invoice.OpenETLDocument) { d.OfeIdentificacion = "" }, wantErr: "ofe_identificacion es requerido"},
+		{name: "invalid total", documentType: "FC", mutate: func(d *invoice.OpenETLDocument) { d.CdoTotal = "abc" }, wantErr: "cdo_total"},
+		{name: "invalid tax", documentType: "FC", mutate: func(d *invoice.OpenETLDocument) { d.Tributos[0].IidValor = "1,5" }, wantErr: "iid_valor"},
+		{name: "invalid quantity", documentType: "FC", mutate: func(d *invoice.OpenETLDocument) { d.Items[0].DdoCantidad = "uno" }, wantErr: "ddo_cantidad"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := baseDocument()
+			tt.mutate(&doc)
+			_, _, err := testRenderer().Render(doc, tt.documentType)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDocumentTaxes_GroupsByCodeAndPercent(t *testing.T) {
+	doc := baseDocument()
+	doc.Tributos = append(doc.Tributos,
+		invoice.OpenETLTributo{DdoSecuencia: "1", TriCodigo: "04", IidValor: "8000", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "100000", IidPorcentaje: "8"}},
+		invoice.OpenETLTributo{DdoSecuencia: "2", TriCodigo: "01", IidValor: "2500", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "50000", IidPorcentaje: "5"}},
+	)
+
+	taxes, err := documentTaxes(doc, "FC", money{currency: "COP"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(taxes.totals) != 2 {
+		t.Fatalf("expected IVA and INC groups, got %d", len(taxes.totals))
+	}
+	iva := taxes.totals[0]
+	if iva.TaxAmount.Value != "31000.00" || len(iva.TaxSubtotals) != 2 {
+		t.Errorf("unexpected IVA total: %+v", iva)
+	}
+	if iva.TaxSubtotals[0].TaxCategory.Percent != "19.00" || iva.TaxSubtotals[0].TaxableAmount.Value != "150000.00" {
+		t.Errorf("expected 19%% subtotal to group both lines, got %+v", iva.TaxSubtotals[0])
+	}
+	if taxes.byCode("04") != "8000.00" || taxes.otherThan("01") != "8000.00" {
+		t.Errorf("unexpected sums: IVA=%s other=%s", taxes.byCode("01"), taxes.otherThan("01"))
+	}
+}
+
+func TestMonetaryTotals_Payable(t *testing.T) {
+	doc := supportDocument()
+	doc.CdoImpuestos = "66500.00"
+	doc.CdoTotal = "416500.00"
+	doc.CdoAnticipo = "16500.00"
+	doc.Tributos = []invoice.OpenETLTributo{
+		{DdoSecuencia: "1", TriCodigo: "01", IidValor: "66500.00", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "350000.00", IidPorcentaje: "19.00"}},
+	}
+	m := money{currency: "COP"}
+
+	taxes, err := documentTaxes(doc, "DS", m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(taxes.totals) != 1 || taxes.byCode("01") != "66500.00" {
+		t.Errorf("expected the IVA of the support document, got %+v", taxes.totals)
+	}
+
+	totals, err := monetaryTotals(doc, taxes, m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if totals.TaxInclusiveAmount.Value != "416500.00" || totals.PayableAmount.Value != "400000.00" {
+		t.Errorf("expected tax inclusive 416500.00 and payable 400000.00, got %s %s", totals.TaxInclusiveAmount.Value, totals.PayableAmount.Value)
+	}
+}
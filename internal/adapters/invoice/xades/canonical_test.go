@@ -0,0 +1,122 @@
+package xades
+
+import (
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+func TestCanonicalize_W3CStartAndEndTags(t *testing.T) {
+	// Example 3.3 of Canonical XML 1.0, without the DTD default attribute
+	input := `<doc>
+   <e1   />
+   <e2   ></e2>
+   <e3   name = "elem3"   id="elem3"   />
+   <e4   name="elem4"   id="elem4"   ></e4>
+   <e5 a:attr="out" b:attr="sorted" attr2="all" attr="I'm"
+      xmlns:b="http://www.ietf.org"
+      xmlns:a="http://www.w3.org"
+      xmlns="http://example.org"/>
+   <e6 xmlns="" xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="" xmlns:a="http://www.w3.org">
+            <e9 xmlns="" xmlns:a="http://www.ietf.org"/>
+         </e8>
+      </e7>
+   </e6>
+</doc>`
+	want := `<doc>
+   <e1></e1>
+   <e2></e2>
+   <e3 id="elem3" name="elem3"></e3>
+   <e4 id="elem4" name="elem4"></e4>
+   <e5 xmlns="http://example.org" xmlns:a="http://www.w3.org" xmlns:b="http://www.ietf.org" attr="I'm" attr2="all" b:attr="sorted" a:attr="out"></e5>
+   <e6 xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="">
+            <e9 xmlns:a="http://www.ietf.org"></e9>
+         </e8>
+      </e7>
+   </e6>
+</doc>`
+
+	root, err := parseXML([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := canonicalize(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("unexpected canonical form:\n got  %s\n want %s", got, want)
+	}
+}
+
+func TestCanonicalize_W3CCharacterModifications(t *testing.T) {
+	// Example 3.4 of Canonical XML 1.0, without entity declarations
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- comentario -->
+<doc>
+   <text>First line&#x0d;&#10;Second line</text>
+   <value>&#x32;</value>
+   <compute><![CDATA[value>"0" && value<"10" ?"valid":"error"]]></compute>
+   <compute expr='value>"0" &amp;&amp; value&lt;"10" ?"valid":"error"'>valid</compute>
+   <norm attrib=' &apos;   &#x20;&#13;&#xa;&#9;   &apos; '/>
+</doc>`
+	want := `<doc>
+   <text>First line&#xD;
+Second line</text>
+   <value>2</value>
+   <compute>value&gt;"0" &amp;&amp; value&lt;"10" ?"valid":"error"</compute>
+   <compute expr="value>&quot;0&quot; &amp;&amp; value&lt;&quot;10&quot; ?&quot;valid&quot;:&quot;error&quot;">valid</compute>
+   <norm attrib=" '    &#xD;&#xA;&#x9;   ' "></norm>
+</doc>`
+
+	root, err := parseXML([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := canonicalize(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("unexpected canonical form:\n got  %s\n want %s", got, want)
+	}
+}
+
+func TestCanonicalize_SubsetInheritsNamespaces(t *testing.T) {
+	root, err := parseXML([]byte(`<a:root xmlns:a="urn:a" xmlns="urn:d" xmlns:b="urn:b"><a:child b:x="1" Id="c"><b:leaf xmlns:b="urn:b"/></a:child><sig/></a:root>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	subset, err := canonicalize(findByID(root, "c"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `<a:child xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b" Id="c" b:x="1"><b:leaf></b:leaf></a:child>`; string(subset) != want {
+		t.Errorf("unexpected subset:\n got  %s\n want %s", subset, want)
+	}
+
+	sig := find(root, func(el *etree.Element) bool { return el.Tag == "sig" })
+	enveloped, err := canonicalizeEnveloped(root, sig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `<a:root xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b"><a:child Id="c" b:x="1"><b:leaf></b:leaf></a:child></a:root>`; string(enveloped) != want {
+		t.Errorf("unexpected enveloped form:\n got  %s\n want %s", enveloped, want)
+	}
+	if find(root, func(el *etree.Element) bool { return el.Tag == "sig" }) == nil {
+		t.Error("expected the signature to be restored after canonicalization")
+	}
+}
+
+func TestParseXML_Invalid(t *testing.T) {
+	for _, input := range []string{"", "<a><b></a>", "<a>", "<a/><b/>"} {
+		if _, err := parseXML([]byte(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
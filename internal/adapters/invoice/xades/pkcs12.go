@@ -0,0 +1,59 @@
+package xades
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// Certificate is a signing certificate with its RSA private key.
+type Certificate struct {
+	Leaf       *x509.Certificate
+	Chain      []*x509.Certificate // Remaining certificates of the PKCS#12 file (intermediates, root)
+	PrivateKey *rsa.PrivateKey
+}
+
+// LoadPKCS12 reads a PKCS#12 (.p12/.pfx) file and returns its signing certificate.
+func LoadPKCS12(path, password string) (*Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("leer certificado %s: %w", path, err)
+	}
+	return ParsePKCS12(data, password)
+}
+
+// ParsePKCS12 decodes a DER encoded PKCS#12 file, either with the legacy
+// 3DES/RC2 schemes or with PBES2 (PBKDF2 + AES) as produced by current OpenSSL
+// versions. The file must contain one RSA private key and its certificate; any
+// other certificate is returned as part of the chain.
+func ParsePKCS12(data []byte, password string) (*Certificate, error) {
+	key, first, rest, err := pkcs12.DecodeChain(data, password)
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		return nil, fmt.Errorf("contraseña del certificado incorrecta o archivo PKCS#12 corrupto")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("certificado PKCS#12 inválido: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("la llave privada del certificado debe ser RSA")
+	}
+
+	// The leaf is the certificate of the private key, whatever its position in the file
+	result := &Certificate{PrivateKey: rsaKey}
+	for _, cert := range append([]*x509.Certificate{first}, rest...) {
+		if pub, ok := cert.PublicKey.(*rsa.PublicKey); ok && result.Leaf == nil && pub.Equal(&rsaKey.PublicKey) {
+			result.Leaf = cert
+			continue
+		}
+		result.Chain = append(result.Chain, cert)
+	}
+	if result.Leaf == nil {
+		return nil, fmt.Errorf("el archivo PKCS#12 no contiene el certificado de la llave privada")
+	}
+	return result, nil
+}
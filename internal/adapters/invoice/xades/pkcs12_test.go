@@ -0,0 +1,155 @@
+package xades
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+var (
+	testKeysOnce sync.Once
+	testKeys     [2]*rsa.PrivateKey
+)
+
+// testKey returns one of two RSA keys shared by the tests, since generating them is slow.
+func testKey(t *testing.T, i int) *rsa.PrivateKey {
+	t.Helper()
+	testKeysOnce.Do(func() {
+		for k := range testKeys {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				panic(err)
+			}
+			testKeys[k] = key
+		}
+	})
+	return testKeys[i]
+}
+
+// newTestCertificate creates a certificate for key, self-signed when parent is nil.
+func newTestCertificate(t *testing.T, key *rsa.PrivateKey, cn string, parent *x509.Certificate, parentKey *rsa.PrivateKey, notBefore, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{"Empresa de Prueba S.A.S."}, Country: []string{"CO"}},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return cert
+}
+
+// encodePKCS12 encodes key with its certificates, the first one stored as the leaf.
+func encodePKCS12(t *testing.T, encoder *pkcs12.Encoder, key *rsa.PrivateKey, certs []*x509.Certificate, password string) []byte {
+	t.Helper()
+	data, err := encoder.Encode(key, certs[0], certs[1:], password)
+	if err != nil {
+		t.Fatalf("encode PKCS#12: %v", err)
+	}
+	return data
+}
+
+func TestParsePKCS12(t *testing.T) {
+	now := time.Now()
+	caKey, key := testKey(t, 0), testKey(t, 1)
+	ca := newTestCertificate(t, caKey, "CA de Prueba", nil, nil, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	leaf := newTestCertificate(t, key, "Firmante", ca, caKey, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+
+	encoders := map[string]*pkcs12.Encoder{
+		"PBES2":       pkcs12.Modern,
+		"legacy 3DES": pkcs12.LegacyDES,
+		"legacy RC2":  pkcs12.LegacyRC2,
+	}
+	for name, encoder := range encoders {
+		t.Run(name, func(t *testing.T) {
+			// The CA is stored first: the leaf is found by its private key
+			data := encodePKCS12(t, encoder, key, []*x509.Certificate{ca, leaf}, "clave segura ñ")
+
+			cert, err := ParsePKCS12(data, "clave segura ñ")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cert.Leaf.Equal(leaf) {
+				t.Errorf("expected leaf %s, got %s", leaf.Subject, cert.Leaf.Subject)
+			}
+			if len(cert.Chain) != 1 || !cert.Chain[0].Equal(ca) {
+				t.Errorf("expected CA in chain, got %d certificates", len(cert.Chain))
+			}
+			if !cert.PrivateKey.Equal(key) {
+				t.Error("expected the private key of the file")
+			}
+		})
+	}
+}
+
+func TestParsePKCS12_Errors(t *testing.T) {
+	now := time.Now()
+	key := testKey(t, 0)
+	cert := newTestCertificate(t, key, "Firmante", nil, nil, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	other := newTestCertificate(t, testKey(t, 1), "Otro", nil, nil, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		password string
+		wantErr  string
+	}{
+		{name: "wrong password", data: encodePKCS12(t, pkcs12.Modern, key, []*x509.Certificate{cert}, "correcta"), password: "incorrecta", wantErr: "contraseña"},
+		{name: "certificate of another key", data: encodePKCS12(t, pkcs12.Modern, key, []*x509.Certificate{other}, "clave"), password: "clave", wantErr: "no contiene el certificado"},
+		{name: "not PKCS#12", data: []byte("-----BEGIN CERTIFICATE-----"), password: "clave", wantErr: "inválido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePKCS12(tt.data, tt.password)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadPKCS12(t *testing.T) {
+	now := time.Now()
+	key := testKey(t, 0)
+	cert := newTestCertificate(t, key, "Firmante", nil, nil, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	path := filepath.Join(t.TempDir(), "firma.p12")
+	if err := os.WriteFile(path, encodePKCS12(t, pkcs12.Modern, key, []*x509.Certificate{cert}, "clave"), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+
+	loaded, err := LoadPKCS12(path, "clave")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !loaded.Leaf.Equal(cert) {
+		t.Error("expected the certificate of the file")
+	}
+
+	if _, err := LoadPKCS12(filepath.Join(t.TempDir(), "no-existe.p12"), "clave"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,549 @@
+// Package xades signs and verifies UBL documents with the XAdES-EPES profile
+// required by DIAN: an enveloped XMLDSig signature (RSA-SHA256, inclusive
+// C14N) placed in the last UBLExtension, whose signed properties carry the
+// signing time, the signing certificate and the DIAN signature policy.
+package xades
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/google/uuid"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	dsNamespace    = "http://www.w3.org/2000/09/xmldsig#"
+	xadesNamespace = "http://uri.etsi.org/01903/v1.3.2#"
+	extNamespace   = "urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2"
+
+	algC14N           = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	algEnveloped      = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algRSASHA1        = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	algRSASHA256      = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSASHA384      = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha384"
+	algRSASHA512      = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	algSHA1           = "http://www.w3.org/2000/09/xmldsig#sha1"
+	algSHA256         = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algSHA384         = "http://www.w3.org/2001/04/xmldsig-more#sha384"
+	algSHA512         = "http://www.w3.org/2001/04/xmlenc#sha512"
+	signedPropsType   = "http://uri.etsi.org/01903#SignedProperties"
+	signingTimeLayout = "2006-01-02T15:04:05-07:00"
+
+	// PolicyIdentifier is the DIAN signature policy (v2) referenced by XAdES-EPES signatures.
+	PolicyIdentifier  = "https://facturaelectronica.dian.gov.co/politicadefirma/v2/politicadefirmav2.pdf"
+	policyDescription = "Política de firma para facturas electrónicas de la República de Colombia."
+	// policyHash is the base64 SHA-256 digest of the policy document.
+	policyHash = "dMoMvtcG5aIzgYo0tIsSQeVJBDnUnfSOfBpxXrmor0Y="
+
+	// RoleSupplier is the claimed role of the issuer of a document.
+	RoleSupplier = "supplier"
+	// RoleThirdParty is the claimed role of a party signing on behalf of the issuer.
+	RoleThirdParty = "third party"
+)
+
+var digestAlgorithms = map[string]crypto.Hash{
+	algSHA1:   crypto.SHA1,
+	algSHA256: crypto.SHA256,
+	algSHA384: crypto.SHA384,
+	algSHA512: crypto.SHA512,
+}
+
+var signatureAlgorithms = map[string]crypto.Hash{
+	algRSASHA1:   crypto.SHA1,
+	algRSASHA256: crypto.SHA256,
+	algRSASHA384: crypto.SHA384,
+	algRSASHA512: crypto.SHA512,
+}
+
+// Signer signs UBL documents with a PKCS#12 certificate.
+type Signer struct {
+	cert *Certificate
+	role string
+	now  func() time.Time
+}
+
+// NewSigner creates a signer that claims the supplier role.
+func NewSigner(cert *Certificate) *Signer {
+	return &Signer{cert: cert, role: RoleSupplier, now: time.Now}
+}
+
+// WithRole changes the claimed role of the signer (RoleSupplier or RoleThirdParty).
+func (s *Signer) WithRole(role string) *Signer {
+	s.role = role
+	return s
+}
+
+// Sign adds a XAdES-EPES signature to the document. The signature is placed in the
+// last empty ext:ExtensionContent, which the UBL renderer leaves for it.
+func (s *Signer) Sign(data []byte) ([]byte, error) {
+	if s.cert == nil || s.cert.Leaf == nil || s.cert.PrivateKey == nil {
+		return nil, fmt.Errorf("certificado de firma es requerido")
+	}
+	now := s.now()
+	if now.Before(s.cert.Leaf.NotBefore) || now.After(s.cert.Leaf.NotAfter) {
+		return nil, fmt.Errorf("el certificado de firma no está vigente (válido entre %s y %s)",
+			s.cert.Leaf.NotBefore.Format(time.DateOnly), s.cert.Leaf.NotAfter.Format(time.DateOnly))
+	}
+
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	placeholder := signaturePlaceholder(root)
+	if placeholder == nil {
+		return nil, fmt.Errorf("el documento no tiene un ext:ExtensionContent vacío para la firma")
+	}
+
+	id := "xmlsig-" + uuid.NewString()
+	signature, err := parseXML(s.signatureTemplate(id, now))
+	if err != nil {
+		return nil, err
+	}
+	for len(placeholder.Child) > 0 {
+		placeholder.RemoveChildAt(0)
+	}
+	placeholder.AddChild(signature)
+
+	signedInfo := child(signature, dsNamespace, "SignedInfo")
+	targets := []*etree.Element{root, findByID(root, id+"-keyinfo"), findByID(root, id+"-signedprops")}
+	refs := children(signedInfo, dsNamespace, "Reference")
+	for i, target := range targets {
+		var canonical []byte
+		if target == root {
+			canonical, err = canonicalizeEnveloped(root, signature)
+		} else {
+			canonical, err = canonicalize(target)
+		}
+		if err != nil {
+			return nil, err
+		}
+		digest := crypto.SHA256.New()
+		digest.Write(canonical)
+		child(refs[i], dsNamespace, "DigestValue").SetText(base64.StdEncoding.EncodeToString(digest.Sum(nil)))
+	}
+
+	canonical, err := canonicalize(signedInfo)
+	if err != nil {
+		return nil, err
+	}
+	hashed := crypto.SHA256.New()
+	hashed.Write(canonical)
+	value, err := rsa.SignPKCS1v15(rand.Reader, s.cert.PrivateKey, crypto.SHA256, hashed.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("firmar documento: %w", err)
+	}
+	child(signature, dsNamespace, "SignatureValue").SetText(base64.StdEncoding.EncodeToString(value))
+
+	canonical, err = canonicalize(root)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n")
+	out.Write(canonical)
+	return out.Bytes(), nil
+}
+
+// signatureTemplate returns the ds:Signature element with empty digests and signature value.
+func (s *Signer) signatureTemplate(id string, now time.Time) []byte {
+	leaf := s.cert.Leaf
+	certDigest := crypto.SHA256.New()
+	certDigest.Write(leaf.Raw)
+
+	var b strings.Builder
+	b.WriteString(`<ds:Signature xmlns:ds="` + dsNamespace + `" Id="` + id + `">`)
+	b.WriteString(`<ds:SignedInfo>`)
+	b.WriteString(`<ds:CanonicalizationMethod Algorithm="` + algC14N + `"/>`)
+	b.WriteString(`<ds:SignatureMethod Algorithm="` + algRSASHA256 + `"/>`)
+	b.WriteString(`<ds:Reference Id="` + id + `-ref0" URI="">`)
+	b.WriteString(`<ds:Transforms><ds:Transform Algorithm="` + algEnveloped + `"/></ds:Transforms>`)
+	b.WriteString(`<ds:DigestMethod Algorithm="` + algSHA256 + `"/><ds:DigestValue/>`)
+	b.WriteString(`</ds:Reference>`)
+	b.WriteString(`<ds:Reference URI="#` + id + `-keyinfo">`)
+	b.WriteString(`<ds:DigestMethod Algorithm="` + algSHA256 + `"/><ds:DigestValue/>`)
+	b.WriteString(`</ds:Reference>`)
+	b.WriteString(`<ds:Reference Type="` + signedPropsType + `" URI="#` + id + `-signedprops">`)
+	b.WriteString(`<ds:DigestMethod Algorithm="` + algSHA256 + `"/><ds:DigestValue/>`)
+	b.WriteString(`</ds:Reference>`)
+	b.WriteString(`</ds:SignedInfo>`)
+	b.WriteString(`<ds:SignatureValue Id="` + id + `-sigvalue"/>`)
+	b.WriteString(`<ds:KeyInfo Id="` + id + `-keyinfo"><ds:X509Data><ds:X509Certificate>`)
+	b.WriteString(base64.StdEncoding.EncodeToString(leaf.Raw))
+	b.WriteString(`</ds:X509Certificate></ds:X509Data></ds:KeyInfo>`)
+	b.WriteString(`<ds:Object><xades:QualifyingProperties xmlns:xades="` + xadesNamespace + `" Target="#` + id + `">`)
+	b.WriteString(`<xades:SignedProperties Id="` + id + `-signedprops"><xades:SignedSignatureProperties>`)
+	b.WriteString(`<xades:SigningTime>` + now.In(bogota()).Format(signingTimeLayout) + `</xades:SigningTime>`)
+	b.WriteString(`<xades:SigningCertificate><xades:Cert><xades:CertDigest>`)
+	b.WriteString(`<ds:DigestMethod Algorithm="` + algSHA256 + `"/>`)
+	b.WriteString(`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(certDigest.Sum(nil)) + `</ds:DigestValue>`)
+	b.WriteString(`</xades:CertDigest><xades:IssuerSerial>`)
+	b.WriteString(`<ds:X509IssuerName>` + escapeText(leaf.Issuer.String()) + `</ds:X509IssuerName>`)
+	b.WriteString(`<ds:X509SerialNumber>` + leaf.SerialNumber.String() + `</ds:X509SerialNumber>`)
+	b.WriteString(`</xades:IssuerSerial></xades:Cert></xades:SigningCertificate>`)
+	b.WriteString(`<xades:SignaturePolicyIdentifier><xades:SignaturePolicyId><xades:SigPolicyId>`)
+	b.WriteString(`<xades:Identifier>` + PolicyIdentifier + `</xades:Identifier>`)
+	b.WriteString(`<xades:Description>` + policyDescription + `</xades:Description>`)
+	b.WriteString(`</xades:SigPolicyId><xades:SigPolicyHash>`)
+	b.WriteString(`<ds:DigestMethod Algorithm="` + algSHA256 + `"/>`)
+	b.WriteString(`<ds:DigestValue>` + policyHash + `</ds:DigestValue>`)
+	b.WriteString(`</xades:SigPolicyHash></xades:SignaturePolicyId></xades:SignaturePolicyIdentifier>`)
+	b.WriteString(`<xades:SignerRole><xades:ClaimedRoles><xades:ClaimedRole>` + escapeText(s.role) + `</xades:ClaimedRole></xades:ClaimedRoles></xades:SignerRole>`)
+	b.WriteString(`</xades:SignedSignatureProperties></xades:SignedProperties>`)
+	b.WriteString(`</xades:QualifyingProperties></ds:Object>`)
+	b.WriteString(`</ds:Signature>`)
+	return []byte(b.String())
+}
+
+// Verification is the result of a successful signature verification.
+type Verification struct {
+	Certificate      *x509.Certificate
+	SigningTime      time.Time
+	PolicyIdentifier string
+	Role             string
+}
+
+// Verify validates the XAdES signature of a received document: the digests of every
+// reference, the signature value against the embedded certificate and the signed
+// properties (signing certificate and DIAN policy hash). Trust in the certificate
+// chain is not evaluated.
+func Verify(data []byte) (*Verification, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	signature := find(root, func(el *etree.Element) bool { return is(el, dsNamespace, "Signature") })
+	if signature == nil {
+		return nil, fmt.Errorf("el documento no está firmado")
+	}
+
+	signedInfo := child(signature, dsNamespace, "SignedInfo")
+	if signedInfo == nil {
+		return nil, fmt.Errorf("firma inválida: ds:SignedInfo es requerido")
+	}
+	if alg := algorithmOf(child(signedInfo, dsNamespace, "CanonicalizationMethod")); alg != algC14N {
+		return nil, fmt.Errorf("firma inválida: canonicalización no soportada %q", alg)
+	}
+	signatureHash, ok := signatureAlgorithms[algorithmOf(child(signedInfo, dsNamespace, "SignatureMethod"))]
+	if !ok {
+		return nil, fmt.Errorf("firma inválida: algoritmo de firma no soportado")
+	}
+
+	var signedProps *etree.Element
+	for _, ref := range children(signedInfo, dsNamespace, "Reference") {
+		target, err := verifyReference(root, signature, ref)
+		if err != nil {
+			return nil, err
+		}
+		if ref.SelectAttrValue("Type", "") == signedPropsType {
+			signedProps = target
+		}
+	}
+
+	certNode := path(signature, dsNamespace, "KeyInfo", "X509Data", "X509Certificate")
+	if certNode == nil {
+		return nil, fmt.Errorf("firma inválida: ds:X509Certificate es requerido")
+	}
+	certDER, err := base64.StdEncoding.DecodeString(stripSpaces(certNode.Text()))
+	if err != nil {
+		return nil, fmt.Errorf("firma inválida: certificado mal codificado: %w", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("firma inválida: certificado inválido: %w", err)
+	}
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("firma inválida: solo se soportan certificados RSA")
+	}
+
+	valueNode := child(signature, dsNamespace, "SignatureValue")
+	if valueNode == nil {
+		return nil, fmt.Errorf("firma inválida: ds:SignatureValue es requerido")
+	}
+	value, err := base64.StdEncoding.DecodeString(stripSpaces(valueNode.Text()))
+	if err != nil {
+		return nil, fmt.Errorf("firma inválida: valor de firma mal codificado: %w", err)
+	}
+	canonical, err := canonicalize(signedInfo)
+	if err != nil {
+		return nil, err
+	}
+	hashed := signatureHash.New()
+	hashed.Write(canonical)
+	if err := rsa.VerifyPKCS1v15(publicKey, signatureHash, hashed.Sum(nil), value); err != nil {
+		return nil, fmt.Errorf("firma inválida: el valor de la firma no corresponde al certificado")
+	}
+
+	if signedProps == nil {
+		return nil, fmt.Errorf("firma inválida: la firma no referencia xades:SignedProperties")
+	}
+	return verifySignedProperties(signedProps, cert)
+}
+
+// verifyReference recomputes the digest of a ds:Reference and returns its target.
+func verifyReference(root, signature, ref *etree.Element) (*etree.Element, error) {
+	uri := ref.SelectAttrValue("URI", "")
+	var target *etree.Element
+	switch {
+	case uri == "":
+		target = root
+	case strings.HasPrefix(uri, "#"):
+		target = findByID(root, uri[1:])
+	}
+	if target == nil {
+		return nil, fmt.Errorf("firma inválida: referencia %q no encontrada", uri)
+	}
+
+	enveloped := false
+	if transforms := child(ref, dsNamespace, "Transforms"); transforms != nil {
+		for _, transform := range children(transforms, dsNamespace, "Transform") {
+			switch alg := algorithmOf(transform); alg {
+			case algEnveloped:
+				enveloped = true
+			case algC14N:
+			default:
+				return nil, fmt.Errorf("firma inválida: transformación no soportada %q", alg)
+			}
+		}
+	}
+
+	digestHash, ok := digestAlgorithms[algorithmOf(child(ref, dsNamespace, "DigestMethod"))]
+	if !ok {
+		return nil, fmt.Errorf("firma inválida: algoritmo de digest no soportado en referencia %q", uri)
+	}
+	digestNode := child(ref, dsNamespace, "DigestValue")
+	if digestNode == nil {
+		return nil, fmt.Errorf("firma inválida: ds:DigestValue es requerido en referencia %q", uri)
+	}
+	expected, err := base64.StdEncoding.DecodeString(stripSpaces(digestNode.Text()))
+	if err != nil {
+		return nil, fmt.Errorf("firma inválida: digest mal codificado en referencia %q", uri)
+	}
+
+	var canonical []byte
+	if enveloped {
+		canonical, err = canonicalizeEnveloped(target, signature)
+	} else {
+		canonical, err = canonicalize(target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	digest := digestHash.New()
+	digest.Write(canonical)
+	if subtle.ConstantTimeCompare(digest.Sum(nil), expected) != 1 {
+		return nil, fmt.Errorf("firma inválida: el contenido de la referencia %q fue modificado", uri)
+	}
+	return target, nil
+}
+
+// verifySignedProperties checks the XAdES signed signature properties.
+func verifySignedProperties(signedProps *etree.Element, cert *x509.Certificate) (*Verification, error) {
+	props := child(signedProps, xadesNamespace, "SignedSignatureProperties")
+	if props == nil {
+		return nil, fmt.Errorf("firma inválida: xades:SignedSignatureProperties es requerido")
+	}
+	result := &Verification{Certificate: cert}
+
+	signingTime := child(props, xadesNamespace, "SigningTime")
+	if signingTime == nil {
+		return nil, fmt.Errorf("firma inválida: xades:SigningTime es requerido")
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(signingTime.Text()))
+	if err != nil {
+		return nil, fmt.Errorf("firma inválida: xades:SigningTime debe ser una fecha ISO 8601")
+	}
+	result.SigningTime = t
+
+	certDigest := path(props, xadesNamespace, "SigningCertificate", "Cert", "CertDigest")
+	if certDigest == nil {
+		return nil, fmt.Errorf("firma inválida: xades:SigningCertificate es requerido")
+	}
+	if err := checkDigest(certDigest, cert.Raw); err != nil {
+		return nil, fmt.Errorf("firma inválida: xades:SigningCertificate no corresponde al certificado")
+	}
+
+	policy := path(props, xadesNamespace, "SignaturePolicyIdentifier", "SignaturePolicyId")
+	if policy == nil {
+		return nil, fmt.Errorf("firma inválida: xades:SignaturePolicyIdentifier es requerido (XAdES-EPES)")
+	}
+	if identifier := path(policy, xadesNamespace, "SigPolicyId", "Identifier"); identifier != nil {
+		result.PolicyIdentifier = strings.TrimSpace(identifier.Text())
+	}
+	if result.PolicyIdentifier == PolicyIdentifier {
+		hash := path(policy, xadesNamespace, "SigPolicyHash")
+		if hash == nil || stripSpaces(textOf(child(hash, dsNamespace, "DigestValue"))) != policyHash {
+			return nil, fmt.Errorf("firma inválida: hash de la política de firma de la DIAN inválido")
+		}
+	}
+
+	if role := path(props, xadesNamespace, "SignerRole", "ClaimedRoles", "ClaimedRole"); role != nil {
+		result.Role = strings.TrimSpace(role.Text())
+	}
+	return result, nil
+}
+
+// checkDigest compares the ds:DigestMethod/ds:DigestValue pair of el with the digest of data.
+func checkDigest(el *etree.Element, data []byte) error {
+	digestHash, ok := digestAlgorithms[algorithmOf(child(el, dsNamespace, "DigestMethod"))]
+	if !ok {
+		return fmt.Errorf("algoritmo de digest no soportado")
+	}
+	expected, err := base64.StdEncoding.DecodeString(stripSpaces(textOf(child(el, dsNamespace, "DigestValue"))))
+	if err != nil {
+		return err
+	}
+	digest := digestHash.New()
+	digest.Write(data)
+	if subtle.ConstantTimeCompare(digest.Sum(nil), expected) != 1 {
+		return fmt.Errorf("digest no coincide")
+	}
+	return nil
+}
+
+// parseXML parses an XML document and returns its root element.
+func parseXML(data []byte) (*etree.Element, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("XML inválido: %w", err)
+	}
+	switch len(doc.ChildElements()) {
+	case 0:
+		return nil, fmt.Errorf("XML inválido: el documento no tiene elemento raíz")
+	case 1:
+		return doc.Root(), nil
+	default:
+		return nil, fmt.Errorf("XML inválido: más de un elemento raíz")
+	}
+}
+
+// canonicalize returns the inclusive Canonical XML 1.0 form (without comments) of el,
+// which renders every namespace it inherits from its ancestors.
+func canonicalize(el *etree.Element) ([]byte, error) {
+	canonical, err := dsig.MakeC14N10RecCanonicalizer().Canonicalize(el)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalizar XML: %w", err)
+	}
+	return canonical, nil
+}
+
+// canonicalizeEnveloped canonicalizes root without the signature element, which
+// implements the enveloped-signature transform.
+func canonicalizeEnveloped(root, signature *etree.Element) ([]byte, error) {
+	parent, index := signature.Parent(), signature.Index()
+	parent.RemoveChildAt(index)
+	defer parent.InsertChildAt(index, signature)
+	return canonicalize(root)
+}
+
+// signaturePlaceholder returns the last empty ext:ExtensionContent of the document.
+func signaturePlaceholder(root *etree.Element) *etree.Element {
+	extensions := child(root, extNamespace, "UBLExtensions")
+	if extensions == nil {
+		return nil
+	}
+	var placeholder *etree.Element
+	for _, extension := range children(extensions, extNamespace, "UBLExtension") {
+		content := child(extension, extNamespace, "ExtensionContent")
+		if content != nil && len(content.ChildElements()) == 0 {
+			placeholder = content
+		}
+	}
+	return placeholder
+}
+
+func is(el *etree.Element, uri, local string) bool {
+	return el.Tag == local && el.NamespaceURI() == uri
+}
+
+func child(el *etree.Element, uri, local string) *etree.Element {
+	for _, c := range el.ChildElements() {
+		if is(c, uri, local) {
+			return c
+		}
+	}
+	return nil
+}
+
+func children(el *etree.Element, uri, local string) []*etree.Element {
+	var out []*etree.Element
+	for _, c := range el.ChildElements() {
+		if is(c, uri, local) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// path follows a chain of child elements in the same namespace.
+func path(el *etree.Element, uri string, locals ...string) *etree.Element {
+	for _, local := range locals {
+		if el == nil {
+			return nil
+		}
+		el = child(el, uri, local)
+	}
+	return el
+}
+
+// find returns the first element of the subtree, in document order, that matches.
+func find(el *etree.Element, match func(*etree.Element) bool) *etree.Element {
+	if match(el) {
+		return el
+	}
+	for _, c := range el.ChildElements() {
+		if found := find(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findByID returns the element whose Id attribute is id.
+func findByID(el *etree.Element, id string) *etree.Element {
+	return find(el, func(e *etree.Element) bool { return e.SelectAttrValue("Id", "") == id })
+}
+
+func algorithmOf(el *etree.Element) string {
+	if el == nil {
+		return ""
+	}
+	return el.SelectAttrValue("Algorithm", "")
+}
+
+func textOf(el *etree.Element) string {
+	if el == nil {
+		return ""
+	}
+	return el.Text()
+}
+
+func escapeText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func stripSpaces(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+// bogota returns the Colombia time zone, falling back to a fixed UTC-5 offset.
+func bogota() *time.Location {
+	loc, err := time.LoadLocation("America/Bogota")
+	if err != nil {
+		return time.FixedZone("America/Bogota", -5*60*60)
+	}
+	return loc
+}
@@ -0,0 +1,186 @@
+package xades
+
+import (
+	"bytes"
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testInvoice = `<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2" xmlns:sts="dian:gov:co:facturaelectronica:Structures-2-1" xmlns:xades="http://uri.etsi.org/01903/v1.3.2#">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>
+        <sts:DianExtensions>
+          <sts:QRCode>NumFac: SETT1001</sts:QRCode>
+        </sts:DianExtensions>
+      </ext:ExtensionContent>
+    </ext:UBLExtension>
+    <ext:UBLExtension>
+      <ext:ExtensionContent></ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID>
+  <cbc:ID>SETT1001</cbc:ID>
+  <cac:AccountingSupplierParty>
+    <cac:Party>
+      <cac:PartyName>
+        <cbc:Name>Empresa &amp; Cía</cbc:Name>
+      </cac:PartyName>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:LegalMonetaryTotal>
+    <cbc:PayableAmount currencyID="COP">119000.00</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>
+</Invoice>`
+
+func newTestSigner(t *testing.T, notBefore, notAfter time.Time) (*Signer, *x509.Certificate) {
+	t.Helper()
+	key := testKey(t, 0)
+	cert := newTestCertificate(t, key, "Empresa de Prueba", nil, nil, notBefore, notAfter)
+	return NewSigner(&Certificate{Leaf: cert, PrivateKey: key}), cert
+}
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Now()
+	signer, cert := newTestSigner(t, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	signer.now = func() time.Time { return time.Date(2024, 3, 15, 19, 37, 0, 0, time.UTC) }
+	cert.NotBefore = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	signed, err := signer.Sign([]byte(testInvoice))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, fragment := range []string{
+		`<ext:ExtensionContent><ds:Signature Id="xmlsig-`,
+		`<xades:SigningTime>2024-03-15T14:37:00-05:00</xades:SigningTime>`,
+		`<xades:Identifier>` + PolicyIdentifier + `</xades:Identifier>`,
+		`<ds:DigestValue>` + policyHash + `</ds:DigestValue>`,
+		`<xades:ClaimedRole>supplier</xades:ClaimedRole>`,
+		`<cbc:Name>Empresa &amp; Cía</cbc:Name>`,
+	} {
+		if !strings.Contains(string(signed), fragment) {
+			t.Errorf("expected signed document to contain %s", fragment)
+		}
+	}
+
+	verification, err := Verify(signed)
+	if err != nil {
+		t.Fatalf("expected signature to verify: %v", err)
+	}
+	if !verification.Certificate.Equal(cert) {
+		t.Error("expected the signing certificate")
+	}
+	if !verification.SigningTime.Equal(signer.now()) {
+		t.Errorf("expected signing time %s, got %s", signer.now(), verification.SigningTime)
+	}
+	if verification.PolicyIdentifier != PolicyIdentifier || verification.Role != RoleSupplier {
+		t.Errorf("unexpected verification: %+v", verification)
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	now := time.Now()
+	signer, _ := newTestSigner(t, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	signed, err := signer.Sign([]byte(testInvoice))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr string
+	}{
+		{name: "document content", from: "119000.00", to: "1190000.00", wantErr: `referencia ""`},
+		{name: "signing time", from: "<xades:SigningTime>", to: "<xades:SigningTime>1", wantErr: "-signedprops"},
+		{name: "signed info", from: `<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"></ds:SignatureMethod>`, to: `<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"> </ds:SignatureMethod>`, wantErr: "valor de la firma"},
+		{name: "policy", from: "politicadefirmav2.pdf", to: "politicadefirmav3.pdf", wantErr: "-signedprops"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !bytes.Contains(signed, []byte(tt.from)) {
+				t.Fatalf("signed document does not contain %s", tt.from)
+			}
+			tampered := bytes.Replace(signed, []byte(tt.from), []byte(tt.to), 1)
+			_, err := Verify(tampered)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerify_Reformatted(t *testing.T) {
+	now := time.Now()
+	signer, _ := newTestSigner(t, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	signed, err := signer.Sign([]byte(testInvoice))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Equivalent serializations keep the same canonical form
+	reformatted := strings.Replace(string(signed), `<cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID>`, `<cbc:UBLVersionID >UBL 2.1</cbc:UBLVersionID >`, 1)
+	reformatted = strings.Replace(reformatted, `<ds:DigestValue>`+policyHash+`</ds:DigestValue>`, "<ds:DigestValue>\n"+policyHash+"\n</ds:DigestValue>", 1)
+	if _, err := Verify([]byte(reformatted)); err == nil || !strings.Contains(err.Error(), "-signedprops") {
+		t.Errorf("expected whitespace inside signed properties to break the digest, got %v", err)
+	}
+	reformatted = strings.Replace(string(signed), `<cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID>`, `<cbc:UBLVersionID >UBL 2.1</cbc:UBLVersionID >`, 1)
+	if _, err := Verify([]byte(reformatted)); err != nil {
+		t.Errorf("expected equivalent serialization to verify, got %v", err)
+	}
+}
+
+func TestSign_Errors(t *testing.T) {
+	now := time.Now()
+	expired, _ := newTestSigner(t, now.AddDate(-2, 0, 0), now.AddDate(-1, 0, 0))
+	valid, _ := newTestSigner(t, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+
+	tests := []struct {
+		name    string
+		signer  *Signer
+		input   string
+		wantErr string
+	}{
+		{name: "expired certificate", signer: expired, input: testInvoice, wantErr: "no está vigente"},
+		{name: "missing certificate", signer: NewSigner(nil), input: testInvoice, wantErr: "certificado de firma es requerido"},
+		{name: "no placeholder", signer: valid, input: strings.Replace(testInvoice, "<ext:ExtensionContent></ext:ExtensionContent>", "<ext:ExtensionContent><x/></ext:ExtensionContent>", 1), wantErr: "ExtensionContent"},
+		{name: "invalid XML", signer: valid, input: "<Invoice>", wantErr: "XML inválido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.signer.Sign([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerify_Unsigned(t *testing.T) {
+	if _, err := Verify([]byte(testInvoice)); err == nil || !strings.Contains(err.Error(), "no está firmado") {
+		t.Errorf("expected unsigned error, got %v", err)
+	}
+}
+
+func TestSigner_WithRole(t *testing.T) {
+	now := time.Now()
+	signer, _ := newTestSigner(t, now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	signed, err := signer.WithRole(RoleThirdParty).Sign([]byte(testInvoice))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verification, err := Verify(signed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verification.Role != RoleThirdParty {
+		t.Errorf("expected role %q, got %q", RoleThirdParty, verification.Role)
+	}
+}
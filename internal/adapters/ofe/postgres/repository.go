@@ -0,0 +1,396 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/ofe"
+	"3tcapital/goclonacion/internal/infrastructure/security"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository implements the ofe.Repository interface using PostgreSQL.
+// The Numrot password and secret are stored encrypted with the cipher; without a
+// cipher, OFEs can only be stored without them.
+type Repository struct {
+	pool   *pgxpool.Pool
+	cipher *security.SecretCipher
+}
+
+// NewRepository creates a new PostgreSQL OFE repository. cipher may be nil.
+func NewRepository(pool *pgxpool.Pool, cipher *security.SecretCipher) *Repository {
+	return &Repository{pool: pool, cipher: cipher}
+}
+
+const selectColumns = `
+	id, ofe_identificacion, ofe_razon_social, ofe_nombre_comercial, tdo_codigo, toj_codigo,
+	ofe_direccion, pai_codigo, dep_codigo, dep_nombre, mun_codigo, mun_nombre, cpo_codigo,
+	ofe_telefono, ofe_correo, rfi_codigo, ref_codigo, cdo_ambiente,
+	numrot_usuario, numrot_password, numrot_key, numrot_secret,
+	estado, fecha_creacion, fecha_modificacion`
+
+// Create persists a new OFE and returns its ID.
+func (r *Repository) Create(ctx context.Context, o ofe.OFE) (int64, error) {
+	refCodigoJSON, err := json.Marshal(o.RefCodigo)
+	if err != nil {
+		return 0, fmt.Errorf("marshal ref_codigo: %w", err)
+	}
+	creds, err := r.credentials(o.Numrot)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO ofe (
+			ofe_identificacion, ofe_razon_social, ofe_nombre_comercial, tdo_codigo, toj_codigo,
+			ofe_direccion, pai_codigo, dep_codigo, dep_nombre, mun_codigo, mun_nombre, cpo_codigo,
+			ofe_telefono, ofe_correo, rfi_codigo, ref_codigo, cdo_ambiente,
+			numrot_usuario, numrot_password, numrot_key, numrot_secret, estado
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+			$18, $19, $20, $21, $22
+		) RETURNING id
+	`
+
+	var id int64
+	err = r.pool.QueryRow(ctx, query,
+		o.OfeIdentificacion,
+		o.OfeRazonSocial,
+		o.OfeNombreComercial,
+		o.TdoCodigo,
+		o.TojCodigo,
+		o.OfeDireccion,
+		o.PaiCodigo,
+		o.DepCodigo,
+		o.DepNombre,
+		o.MunCodigo,
+		o.MunNombre,
+		o.CpoCodigo,
+		o.OfeTelefono,
+		o.OfeCorreo,
+		o.RfiCodigo,
+		refCodigoJSON,
+		o.CdoAmbiente,
+		creds[0], creds[1], creds[2], creds[3],
+		o.Estado,
+	).Scan(&id)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "unique constraint") {
+			return 0, fmt.Errorf("ya existe un OFE con el numero de identificacion [%s]", o.OfeIdentificacion)
+		}
+		return 0, fmt.Errorf("create ofe: %w", err)
+	}
+
+	return id, nil
+}
+
+// Update updates the OFE identified by ofeIdentificacion.
+func (r *Repository) Update(ctx context.Context, ofeIdentificacion string, o ofe.OFE) error {
+	refCodigoJSON, err := json.Marshal(o.RefCodigo)
+	if err != nil {
+		return fmt.Errorf("marshal ref_codigo: %w", err)
+	}
+	creds, err := r.credentials(o.Numrot)
+	if err != nil {
+		return err
+	}
+
+	// $21 = false keeps the stored Numrot credentials
+	query := `
+		UPDATE ofe SET
+			ofe_razon_social = $1,
+			ofe_nombre_comercial = $2,
+			tdo_codigo = $3,
+			toj_codigo = $4,
+			ofe_direccion = $5,
+			pai_codigo = $6,
+			dep_codigo = $7,
+			dep_nombre = $8,
+			mun_codigo = $9,
+			mun_nombre = $10,
+			cpo_codigo = $11,
+			ofe_telefono = $12,
+			ofe_correo = $13,
+			rfi_codigo = $14,
+			ref_codigo = $15,
+			cdo_ambiente = $16,
+			numrot_usuario = CASE WHEN $21 THEN $17 ELSE numrot_usuario END,
+			numrot_password = CASE WHEN $21 THEN $18 ELSE numrot_password END,
+			numrot_key = CASE WHEN $21 THEN $19 ELSE numrot_key END,
+			numrot_secret = CASE WHEN $21 THEN $20 ELSE numrot_secret END,
+			estado = $22,
+			fecha_modificacion = NOW()
+		WHERE ofe_identificacion = $23
+	`
+
+	result, err := r.pool.Exec(ctx, query,
+		o.OfeRazonSocial,
+		o.OfeNombreComercial,
+		o.TdoCodigo,
+		o.TojCodigo,
+		o.OfeDireccion,
+		o.PaiCodigo,
+		o.DepCodigo,
+		o.DepNombre,
+		o.MunCodigo,
+		o.MunNombre,
+		o.CpoCodigo,
+		o.OfeTelefono,
+		o.OfeCorreo,
+		o.RfiCodigo,
+		refCodigoJSON,
+		o.CdoAmbiente,
+		creds[0], creds[1], creds[2], creds[3],
+		o.Numrot != nil,
+		o.Estado,
+		ofeIdentificacion,
+	)
+	if err != nil {
+		return fmt.Errorf("update ofe: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("el OFE [%s] no existe", ofeIdentificacion)
+	}
+
+	return nil
+}
+
+// FindByIdentificacion retrieves an OFE by its NIT.
+func (r *Repository) FindByIdentificacion(ctx context.Context, ofeIdentificacion string) (*ofe.OFE, error) {
+	query := `SELECT ` + selectColumns + ` FROM ofe WHERE ofe_identificacion = $1`
+
+	o, err := r.scanOFE(r.pool.QueryRow(ctx, query, ofeIdentificacion))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query ofe: %w", err)
+	}
+
+	return o, nil
+}
+
+// List retrieves OFEs with pagination and search.
+func (r *Repository) List(ctx context.Context, start, length int, buscar string) ([]ofe.OFE, int, error) {
+	whereClause := ""
+	queryArgs := []interface{}{}
+	argIndex := 1
+
+	if buscar != "" {
+		whereClause = fmt.Sprintf("WHERE (ofe_identificacion ILIKE $%d OR ofe_razon_social ILIKE $%d OR ofe_nombre_comercial ILIKE $%d)",
+			argIndex, argIndex, argIndex)
+		queryArgs = append(queryArgs, "%"+buscar+"%")
+		argIndex++
+	}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, "SELECT COUNT(*) FROM ofe "+whereClause, queryArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count ofes: %w", err)
+	}
+
+	query := `SELECT ` + selectColumns + ` FROM ofe ` + whereClause + ` ORDER BY ofe_identificacion`
+	if length != -1 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+		queryArgs = append(queryArgs, length, start)
+	}
+
+	rows, err := r.pool.Query(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query ofes: %w", err)
+	}
+	defer rows.Close()
+
+	var ofes []ofe.OFE
+	for rows.Next() {
+		o, err := r.scanOFE(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan ofe: %w", err)
+		}
+		ofes = append(ofes, *o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return ofes, total, nil
+}
+
+// EncryptStoredCredentials encrypts the Numrot passwords and secrets stored in plaintext
+// before encryption was enabled, and returns the number of OFEs updated.
+func (r *Repository) EncryptStoredCredentials(ctx context.Context) (int, error) {
+	if r.cipher == nil {
+		return 0, ErrCredentialsKey
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT ofe_identificacion, numrot_password, numrot_secret FROM ofe
+		WHERE numrot_password NOT LIKE $1 OR numrot_secret NOT LIKE $1
+	`, security.EncryptedPrefix+"%")
+	if err != nil {
+		return 0, fmt.Errorf("query plaintext credentials: %w", err)
+	}
+	type plaintext struct {
+		ofeIdentificacion string
+		password, secret  *string
+	}
+	var pending []plaintext
+	for rows.Next() {
+		var p plaintext
+		if err := rows.Scan(&p.ofeIdentificacion, &p.password, &p.secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan plaintext credentials: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	for _, p := range pending {
+		password, err := r.encrypt(derefString(p.password))
+		if err != nil {
+			return 0, err
+		}
+		secret, err := r.encrypt(derefString(p.secret))
+		if err != nil {
+			return 0, err
+		}
+		_, err = r.pool.Exec(ctx,
+			`UPDATE ofe SET numrot_password = $1, numrot_secret = $2 WHERE ofe_identificacion = $3`,
+			password, secret, p.ofeIdentificacion)
+		if err != nil {
+			return 0, fmt.Errorf("encrypt credentials of ofe %s: %w", p.ofeIdentificacion, err)
+		}
+	}
+	return len(pending), nil
+}
+
+// scanOFE reads a row selected with selectColumns.
+func (r *Repository) scanOFE(row pgx.Row) (*ofe.OFE, error) {
+	var o ofe.OFE
+	var refCodigoJSON []byte
+	var usuario, password, key, secret *string
+
+	err := row.Scan(
+		&o.ID,
+		&o.OfeIdentificacion,
+		&o.OfeRazonSocial,
+		&o.OfeNombreComercial,
+		&o.TdoCodigo,
+		&o.TojCodigo,
+		&o.OfeDireccion,
+		&o.PaiCodigo,
+		&o.DepCodigo,
+		&o.DepNombre,
+		&o.MunCodigo,
+		&o.MunNombre,
+		&o.CpoCodigo,
+		&o.OfeTelefono,
+		&o.OfeCorreo,
+		&o.RfiCodigo,
+		&refCodigoJSON,
+		&o.CdoAmbiente,
+		&usuario,
+		&password,
+		&key,
+		&secret,
+		&o.Estado,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(refCodigoJSON) > 0 {
+		if err := json.Unmarshal(refCodigoJSON, &o.RefCodigo); err != nil {
+			return nil, fmt.Errorf("unmarshal ref_codigo: %w", err)
+		}
+	}
+
+	if usuario != nil || key != nil {
+		o.Numrot = &ofe.NumrotCredentials{
+			Username: derefString(usuario),
+			Key:      derefString(key),
+		}
+		if o.Numrot.Password, err = r.decrypt(derefString(password)); err != nil {
+			return nil, fmt.Errorf("numrot_password del OFE [%s]: %w", o.OfeIdentificacion, err)
+		}
+		if o.Numrot.Secret, err = r.decrypt(derefString(secret)); err != nil {
+			return nil, fmt.Errorf("numrot_secret del OFE [%s]: %w", o.OfeIdentificacion, err)
+		}
+	}
+
+	return &o, nil
+}
+
+// ErrCredentialsKey is returned when a Numrot password or secret has to be encrypted
+// or decrypted and no key is configured.
+var ErrCredentialsKey = errors.New("NUMROT_CREDENTIALS_KEY no configurada: no se pueden almacenar ni leer la contraseña y el secret Numrot del OFE")
+
+// credentials returns the Numrot credential columns (usuario, password, key, secret),
+// with the password and the secret encrypted.
+func (r *Repository) credentials(c *ofe.NumrotCredentials) ([4]*string, error) {
+	if c == nil {
+		return [4]*string{}, nil
+	}
+	password, err := r.encrypt(c.Password)
+	if err != nil {
+		return [4]*string{}, err
+	}
+	secret, err := r.encrypt(c.Secret)
+	if err != nil {
+		return [4]*string{}, err
+	}
+	return [4]*string{nullString(c.Username), password, nullString(c.Key), secret}, nil
+}
+
+// encrypt returns the column value of a secret: NULL when empty, encrypted otherwise.
+func (r *Repository) encrypt(value string) (*string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if r.cipher == nil {
+		return nil, ErrCredentialsKey
+	}
+	encrypted, err := r.cipher.Encrypt(value)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt numrot credentials: %w", err)
+	}
+	return &encrypted, nil
+}
+
+// decrypt reads a secret column. Values stored in plaintext before encryption was
+// enabled are returned as they are until EncryptStoredCredentials migrates them.
+func (r *Repository) decrypt(value string) (string, error) {
+	if !security.IsEncrypted(value) {
+		return value, nil
+	}
+	if r.cipher == nil {
+		return "", ErrCredentialsKey
+	}
+	return r.cipher.Decrypt(value)
+}
+
+// nullString maps an empty string to SQL NULL.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/ofe"
+	"3tcapital/goclonacion/internal/infrastructure/security"
+)
+
+// Note: These tests require a PostgreSQL database connection.
+// They are integration tests and should be run with a test database.
+
+func TestRepositoryIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	t.Run("mock test for structure validation", func(t *testing.T) {
+		var _ ofe.Repository = (*Repository)(nil)
+	})
+}
+
+func TestCredentials(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	cipher, err := security.NewSecretCipher(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := &Repository{cipher: cipher}
+
+	if got, err := r.credentials(nil); err != nil || got != [4]*string{} {
+		t.Errorf("expected NULL columns without credentials, got %v (%v)", got, err)
+	}
+
+	got, err := r.credentials(&ofe.NumrotCredentials{Username: "usuario", Password: "clave"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if derefString(got[0]) != "usuario" {
+		t.Errorf("unexpected user column: %v", derefString(got[0]))
+	}
+	if password := derefString(got[1]); !security.IsEncrypted(password) || strings.Contains(password, "clave") {
+		t.Errorf("expected encrypted password column, got %s", password)
+	}
+	if got[2] != nil || got[3] != nil {
+		t.Error("expected empty key/secret to map to NULL")
+	}
+	if password, err := r.decrypt(derefString(got[1])); err != nil || password != "clave" {
+		t.Errorf("expected password to decrypt to clave, got %q (%v)", password, err)
+	}
+	// Values stored before encryption was enabled are read as they are
+	if password, err := r.decrypt("clave"); err != nil || password != "clave" {
+		t.Errorf("expected plaintext password to be kept, got %q (%v)", password, err)
+	}
+}
+
+func TestCredentials_WithoutKey(t *testing.T) {
+	r := &Repository{}
+
+	// Username and key are not secret and can be stored without a key
+	if _, err := r.credentials(&ofe.NumrotCredentials{Username: "usuario", Key: "key"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := r.credentials(&ofe.NumrotCredentials{Username: "usuario", Password: "clave"}); !errors.Is(err, ErrCredentialsKey) {
+		t.Errorf("expected ErrCredentialsKey for a password, got %v", err)
+	}
+	if _, err := r.credentials(&ofe.NumrotCredentials{Key: "key", Secret: "secret"}); !errors.Is(err, ErrCredentialsKey) {
+		t.Errorf("expected ErrCredentialsKey for a secret, got %v", err)
+	}
+	if _, err := r.decrypt(security.EncryptedPrefix + "AAAA"); !errors.Is(err, ErrCredentialsKey) {
+		t.Errorf("expected ErrCredentialsKey reading an encrypted value, got %v", err)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"strings"
 
-	"3tcapital/ms_facturacion_core/internal/core/provider"
+	"3tcapital/goclonacion/internal/core/provider"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
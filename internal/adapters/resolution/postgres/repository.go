@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/resolution"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository implements the resolution.Repository interface using PostgreSQL.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new PostgreSQL resolution repository.
+func NewRepository(pool *pgxpool.Pool) resolution.Repository {
+	return &Repository{pool: pool}
+}
+
+const selectColumns = `
+	id, ofe_identificacion, resolution_number, resolution_date, prefix, from_number, to_number,
+	valid_date_from, valid_date_to, last_number, used_count, synced_at`
+
+// Sync inserts or updates the resolutions of an OFE, keeping the consecutivos already used.
+func (r *Repository) Sync(ctx context.Context, ofeIdentificacion string, resolutions []resolution.Resolution) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO resoluciones (
+			ofe_identificacion, resolution_number, resolution_date, prefix,
+			from_number, to_number, valid_date_from, valid_date_to, synced_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (ofe_identificacion, resolution_number, prefix) DO UPDATE SET
+			resolution_date = EXCLUDED.resolution_date,
+			from_number = EXCLUDED.from_number,
+			to_number = EXCLUDED.to_number,
+			valid_date_from = EXCLUDED.valid_date_from,
+			valid_date_to = EXCLUDED.valid_date_to,
+			synced_at = NOW(),
+			fecha_modificacion = NOW()
+	`
+
+	for _, res := range resolutions {
+		var resolutionDate *time.Time
+		if !res.ResolutionDate.IsZero() {
+			resolutionDate = &res.ResolutionDate
+		}
+		if _, err := tx.Exec(ctx, query,
+			ofeIdentificacion,
+			res.ResolutionNumber,
+			resolutionDate,
+			res.Prefix,
+			res.FromNumber,
+			res.ToNumber,
+			res.ValidDateFrom,
+			res.ValidDateTo,
+		); err != nil {
+			return fmt.Errorf("upsert resolution %s: %w", res.ResolutionNumber, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves the ranges of an OFE, or of every OFE when ofeIdentificacion is empty.
+func (r *Repository) List(ctx context.Context, ofeIdentificacion string) ([]resolution.Range, error) {
+	query := `SELECT ` + selectColumns + ` FROM resoluciones WHERE ($1 = '' OR ofe_identificacion = $1)
+		ORDER BY ofe_identificacion, prefix, valid_date_from, from_number`
+
+	rows, err := r.pool.Query(ctx, query, ofeIdentificacion)
+	if err != nil {
+		return nil, fmt.Errorf("query resolutions: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []resolution.Range
+	for rows.Next() {
+		rng, err := scanRange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan resolution: %w", err)
+		}
+		ranges = append(ranges, *rng)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return ranges, nil
+}
+
+// Find retrieves a range by OFE, resolution number and prefix.
+func (r *Repository) Find(ctx context.Context, ofeIdentificacion, resolutionNumber, prefix string) (*resolution.Range, error) {
+	query := `SELECT ` + selectColumns + ` FROM resoluciones
+		WHERE ofe_identificacion = $1 AND resolution_number = $2 AND prefix = $3`
+
+	rng, err := scanRange(r.pool.QueryRow(ctx, query, ofeIdentificacion, resolutionNumber, prefix))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query resolution: %w", err)
+	}
+
+	return rng, nil
+}
+
+// Allocate hands out the next free consecutivo of the range valid at the given date.
+// The range row is locked until the transaction commits, so concurrent allocations
+// of the same OFE and prefix never obtain the same number.
+func (r *Repository) Allocate(ctx context.Context, ofeIdentificacion, prefix string, at time.Time) (*resolution.Allocation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT ` + selectColumns + ` FROM resoluciones
+		WHERE ofe_identificacion = $1 AND prefix = $2
+			AND valid_date_from <= $3::date AND valid_date_to >= $3::date
+			AND GREATEST(last_number + 1, from_number) <= to_number
+		ORDER BY valid_date_from, from_number
+		LIMIT 1
+		FOR UPDATE`
+
+	rng, err := scanRange(tx.QueryRow(ctx, query, ofeIdentificacion, prefix, at))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("no hay una resolución vigente con consecutivos disponibles para el OFE [%s] y prefijo [%s]", ofeIdentificacion, prefix)
+		}
+		return nil, fmt.Errorf("lock resolution: %w", err)
+	}
+
+	next := rng.LastNumber + 1
+	if next < rng.FromNumber {
+		next = rng.FromNumber
+	}
+
+	inserted, err := tx.Exec(ctx, `INSERT INTO resolucion_consecutivos (resolucion_id, consecutivo) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, rng.ID, next)
+	if err != nil {
+		return nil, fmt.Errorf("insert consecutivo: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE resoluciones SET last_number = $2, used_count = used_count + $3, fecha_modificacion = NOW()
+		WHERE id = $1`, rng.ID, next, inserted.RowsAffected()); err != nil {
+		return nil, fmt.Errorf("update resolution: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	rng.LastNumber = next
+	rng.UsedCount += inserted.RowsAffected()
+	return &resolution.Allocation{Consecutivo: next, Range: *rng}, nil
+}
+
+// MarkUsed records a consecutivo of the range as used.
+func (r *Repository) MarkUsed(ctx context.Context, rangeID, consecutivo int64) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO resolucion_consecutivos (resolucion_id, consecutivo) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+			RETURNING consecutivo
+		)
+		UPDATE resoluciones SET
+			last_number = GREATEST(last_number, $2),
+			used_count = used_count + (SELECT COUNT(*) FROM inserted),
+			fecha_modificacion = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, rangeID, consecutivo); err != nil {
+		return fmt.Errorf("mark consecutivo used: %w", err)
+	}
+
+	return nil
+}
+
+// scanRange reads a row selected with selectColumns.
+func scanRange(row pgx.Row) (*resolution.Range, error) {
+	var rng resolution.Range
+	var resolutionDate *time.Time
+
+	err := row.Scan(
+		&rng.ID,
+		&rng.OfeIdentificacion,
+		&rng.ResolutionNumber,
+		&resolutionDate,
+		&rng.Prefix,
+		&rng.FromNumber,
+		&rng.ToNumber,
+		&rng.ValidDateFrom,
+		&rng.ValidDateTo,
+		&rng.LastNumber,
+		&rng.UsedCount,
+		&rng.SyncedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolutionDate != nil {
+		rng.ResolutionDate = *resolutionDate
+	}
+
+	return &rng, nil
+}
@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"3tcapital/goclonacion/internal/core/withholding"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository implements the withholding.Repository interface using PostgreSQL.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new PostgreSQL withholding repository.
+func NewRepository(pool *pgxpool.Pool) withholding.Repository {
+	return &Repository{pool: pool}
+}
+
+// Amounts are read as text to keep their exact decimal value.
+const selectColumns = `
+	id, tipo, concepto, mun_codigo, agente_responsabilidades, exento_responsabilidades,
+	base_minima_uvt::text, porcentaje::text, razon, activo`
+
+// ListRules retrieves every rule, active or not, ordered by ID.
+func (r *Repository) ListRules(ctx context.Context) ([]withholding.Rule, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+selectColumns+` FROM retencion_reglas ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("query withholding rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []withholding.Rule
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan withholding rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return rules, nil
+}
+
+// SaveRule creates the rule when its ID is 0, or updates it otherwise.
+func (r *Repository) SaveRule(ctx context.Context, rule withholding.Rule) (int64, error) {
+	agentes, err := marshalCodes(rule.AgenteResponsabilidades)
+	if err != nil {
+		return 0, fmt.Errorf("marshal agente_responsabilidades: %w", err)
+	}
+	exentos, err := marshalCodes(rule.ExentoResponsabilidades)
+	if err != nil {
+		return 0, fmt.Errorf("marshal exento_responsabilidades: %w", err)
+	}
+	base := rule.BaseMinimaUVT
+	if base == "" {
+		base = "0"
+	}
+
+	if rule.ID == 0 {
+		query := `
+			INSERT INTO retencion_reglas (
+				tipo, concepto, mun_codigo, agente_responsabilidades, exento_responsabilidades,
+				base_minima_uvt, porcentaje, razon, activo
+			) VALUES ($1, $2, $3, $4, $5, $6::numeric, $7::numeric, $8, $9)
+			RETURNING id
+		`
+		var id int64
+		if err := r.pool.QueryRow(ctx, query,
+			rule.Tipo, rule.Concepto, rule.MunCodigo, agentes, exentos,
+			base, rule.Porcentaje, rule.Razon, rule.Activo,
+		).Scan(&id); err != nil {
+			return 0, fmt.Errorf("create withholding rule: %w", err)
+		}
+		return id, nil
+	}
+
+	query := `
+		UPDATE retencion_reglas SET
+			tipo = $1,
+			concepto = $2,
+			mun_codigo = $3,
+			agente_responsabilidades = $4,
+			exento_responsabilidades = $5,
+			base_minima_uvt = $6::numeric,
+			porcentaje = $7::numeric,
+			razon = $8,
+			activo = $9,
+			fecha_modificacion = NOW()
+		WHERE id = $10
+	`
+	result, err := r.pool.Exec(ctx, query,
+		rule.Tipo, rule.Concepto, rule.MunCodigo, agentes, exentos,
+		base, rule.Porcentaje, rule.Razon, rule.Activo, rule.ID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("update withholding rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, fmt.Errorf("la regla de retención [%d] no existe", rule.ID)
+	}
+	return rule.ID, nil
+}
+
+// DeleteRule removes a rule.
+func (r *Repository) DeleteRule(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM retencion_reglas WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete withholding rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("la regla de retención [%d] no existe", id)
+	}
+	return nil
+}
+
+// ListUVT retrieves the UVT values ordered by year.
+func (r *Repository) ListUVT(ctx context.Context) ([]withholding.UVT, error) {
+	rows, err := r.pool.Query(ctx, `SELECT anio, valor::text FROM uvt_valores ORDER BY anio`)
+	if err != nil {
+		return nil, fmt.Errorf("query uvt: %w", err)
+	}
+	defer rows.Close()
+
+	var values []withholding.UVT
+	for rows.Next() {
+		var uvt withholding.UVT
+		if err := rows.Scan(&uvt.Anio, &uvt.Valor); err != nil {
+			return nil, fmt.Errorf("scan uvt: %w", err)
+		}
+		values = append(values, uvt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return values, nil
+}
+
+// FindUVT retrieves the UVT value of a year.
+func (r *Repository) FindUVT(ctx context.Context, anio int) (*withholding.UVT, error) {
+	var uvt withholding.UVT
+	err := r.pool.QueryRow(ctx, `SELECT anio, valor::text FROM uvt_valores WHERE anio = $1`, anio).Scan(&uvt.Anio, &uvt.Valor)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query uvt: %w", err)
+	}
+	return &uvt, nil
+}
+
+// SetUVT creates or replaces the UVT value of a year.
+func (r *Repository) SetUVT(ctx context.Context, uvt withholding.UVT) error {
+	query := `
+		INSERT INTO uvt_valores (anio, valor) VALUES ($1, $2::numeric)
+		ON CONFLICT (anio) DO UPDATE SET valor = EXCLUDED.valor, fecha_modificacion = NOW()
+	`
+	if _, err := r.pool.Exec(ctx, query, uvt.Anio, uvt.Valor); err != nil {
+		return fmt.Errorf("set uvt: %w", err)
+	}
+	return nil
+}
+
+func scanRule(row pgx.Row) (*withholding.Rule, error) {
+	var rule withholding.Rule
+	var agentes, exentos []byte
+	if err := row.Scan(
+		&rule.ID,
+		&rule.Tipo,
+		&rule.Concepto,
+		&rule.MunCodigo,
+		&agentes,
+		&exentos,
+		&rule.BaseMinimaUVT,
+		&rule.Porcentaje,
+		&rule.Razon,
+		&rule.Activo,
+	); err != nil {
+		return nil, err
+	}
+	if err := unmarshalCodes(agentes, &rule.AgenteResponsabilidades); err != nil {
+		return nil, fmt.Errorf("unmarshal agente_responsabilidades: %w", err)
+	}
+	if err := unmarshalCodes(exentos, &rule.ExentoResponsabilidades); err != nil {
+		return nil, fmt.Errorf("unmarshal exento_responsabilidades: %w", err)
+	}
+	return &rule, nil
+}
+
+// marshalCodes encodes a list of responsibility codes, storing nil as an empty array.
+func marshalCodes(codes []string) ([]byte, error) {
+	if codes == nil {
+		codes = []string{}
+	}
+	return json.Marshal(codes)
+}
+
+func unmarshalCodes(data []byte, codes *[]string) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, codes)
+}
@@ -6,7 +6,8 @@ import (
 	"strings"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/acquirer"
+	"3tcapital/goclonacion/internal/core/acquirer"
+	"3tcapital/goclonacion/internal/core/identification"
 )
 
 // ContactoDTO represents a contact in the response DTO format.
@@ -24,6 +25,7 @@ type AdquirenteDTO struct {
 	ID                                          int         `json:"id"`
 	OfeIdentificacion                           int         `json:"ofe_identificacion"`
 	AdqIdentificacion                           string      `json:"adq_identificacion"`
+	AdqDV                                       string      `json:"adq_dv,omitempty"` // Verification digit when adq_identificacion is a NIT
 	AdqIDPersonalizado                          *string     `json:"adq_id_personalizado"`
 	AdqInformacionPersonalizada                 *string     `json:"adq_informacion_personalizada"`
 	AdqRazonSocial                              string      `json:"adq_razon_social"`
@@ -99,22 +101,6 @@ func stringToInt(s *string) *int {
 	return &val
 }
 
-// normalizeNIT extracts the base NIT without verification digit (DV).
-// Examples:
-//   - "860011153-6" -> "860011153"
-//   - "860011153" -> "860011153"
-func normalizeNIT(nit string) string {
-	if nit == "" {
-		return ""
-	}
-	// Split by "-" to separate NIT from DV
-	parts := strings.Split(nit, "-")
-	if len(parts) > 1 {
-		return strings.TrimSpace(parts[0])
-	}
-	return strings.TrimSpace(nit)
-}
-
 // stringToIntRequired converts a string to int, returning 0 if empty or invalid.
 func stringToIntRequired(s string) int {
 	val, err := strconv.Atoi(strings.TrimSpace(s))
@@ -176,8 +162,9 @@ func ToAdquirenteDTO(acq *acquirer.Acquirer) *AdquirenteDTO {
 
 	dto := &AdquirenteDTO{
 		ID:                          int(acq.ID),
-		OfeIdentificacion:           stringToIntRequired(normalizeNIT(acq.OfeIdentificacion)),
+		OfeIdentificacion:           stringToIntRequired(identification.Base(acq.OfeIdentificacion)),
 		AdqIdentificacion:           acq.AdqIdentificacion,
+		AdqDV:                       identification.DV(acq.TdoCodigo, acq.AdqIdentificacion),
 		AdqIDPersonalizado:          acq.AdqIDPersonalizado,
 		AdqInformacionPersonalizada: acq.AdqInformacionPersonalizada,
 		AdqRazonSocial:              acq.AdqRazonSocial,
@@ -7,8 +7,10 @@ import (
 	"regexp"
 	"strings"
 
-	"3tcapital/ms_facturacion_core/internal/core/acquirer"
-	"3tcapital/ms_facturacion_core/internal/core/dane"
+	"3tcapital/goclonacion/internal/core/acquirer"
+	"3tcapital/goclonacion/internal/core/codelist"
+	"3tcapital/goclonacion/internal/core/dane"
+	"3tcapital/goclonacion/internal/core/identification"
 )
 
 // Service orchestrates acquirer-related use cases.
@@ -67,8 +69,9 @@ type CreateAcquirerRequest struct {
 
 // CreateAcquirerResponse represents the response from creating an acquirer.
 type CreateAcquirerResponse struct {
-	Success bool  `json:"success"`
-	AdqID   int64 `json:"adq_id"`
+	Success bool   `json:"success"`
+	AdqID   int64  `json:"adq_id"`
+	AdqDV   string `json:"adq_dv,omitempty"` // Verification digit when adq_identificacion is a NIT
 }
 
 // UpdateAcquirerRequest represents the request to update an acquirer.
@@ -116,6 +119,9 @@ func (s *Service) CreateAcquirer(ctx context.Context, req CreateAcquirerRequest)
 		return nil, err
 	}
 
+	req.OfeIdentificacion = identification.Base(req.OfeIdentificacion)
+	req.AdqIdentificacion = identification.Canonical(req.TdoCodigo, req.AdqIdentificacion)
+
 	// Check if acquirer already exists
 	adqIDPersonalizado := ""
 	if req.AdqIDPersonalizado != nil {
@@ -146,6 +152,7 @@ func (s *Service) CreateAcquirer(ctx context.Context, req CreateAcquirerRequest)
 	return &CreateAcquirerResponse{
 		Success: true,
 		AdqID:   id,
+		AdqDV:   identification.DV(req.TdoCodigo, req.AdqIdentificacion),
 	}, nil
 }
 
@@ -156,6 +163,11 @@ func (s *Service) UpdateAcquirer(ctx context.Context, ofeIdentificacion, adqIden
 		return err
 	}
 
+	ofeIdentificacion = identification.Base(ofeIdentificacion)
+	adqIdentificacion = identification.Canonical(req.TdoCodigo, adqIdentificacion)
+	req.OfeIdentificacion = identification.Base(req.OfeIdentificacion)
+	req.AdqIdentificacion = identification.Canonical(req.TdoCodigo, req.AdqIdentificacion)
+
 	// Check if acquirer exists
 	exists, err := s.repo.Exists(ctx, ofeIdentificacion, adqIdentificacion, adqIdPersonalizado)
 	if err != nil {
@@ -199,6 +211,19 @@ func (s *Service) validateCreateRequest(req CreateAcquirerRequest) error {
 		return fmt.Errorf("pai_codigo es requerido")
 	}
 
+	// Coded fields must belong to the DIAN code lists
+	if err := validateCodes(req.TdoCodigo, req.TojCodigo, req.PaiCodigo, req.PaiCodigoDomicilioFiscal, req.RfiCodigo, req.RefCodigo, req.ResponsableTributos); err != nil {
+		return err
+	}
+
+	// Identification numbers are validated with the rules of their type (NIT with its DV)
+	if err := identification.ValidateNIT(req.OfeIdentificacion); err != nil {
+		return fmt.Errorf("ofe_identificacion: %w", err)
+	}
+	if err := identification.Validate(req.TdoCodigo, req.AdqIdentificacion); err != nil {
+		return fmt.Errorf("adq_identificacion: %w", err)
+	}
+
 	// Validate based on tipo de organización jurídica (toj_codigo)
 	// "1" = Persona Jurídica, requires razón social or nombre comercial
 	// "2" = Persona Natural, requires primer nombre and primer apellido
@@ -356,6 +381,19 @@ func (s *Service) validateUpdateRequest(req UpdateAcquirerRequest) error {
 		return fmt.Errorf("pai_codigo es requerido")
 	}
 
+	// Coded fields must belong to the DIAN code lists
+	if err := validateCodes(req.TdoCodigo, req.TojCodigo, req.PaiCodigo, req.PaiCodigoDomicilioFiscal, req.RfiCodigo, req.RefCodigo, req.ResponsableTributos); err != nil {
+		return err
+	}
+
+	// Identification numbers are validated with the rules of their type (NIT with its DV)
+	if err := identification.ValidateNIT(req.OfeIdentificacion); err != nil {
+		return fmt.Errorf("ofe_identificacion: %w", err)
+	}
+	if err := identification.Validate(req.TdoCodigo, req.AdqIdentificacion); err != nil {
+		return fmt.Errorf("adq_identificacion: %w", err)
+	}
+
 	// Validate based on tipo de organización jurídica (toj_codigo)
 	// "1" = Persona Jurídica, requires razón social or nombre comercial
 	// "2" = Persona Natural, requires primer nombre and primer apellido
@@ -494,6 +532,39 @@ func (s *Service) validateUpdateRequest(req UpdateAcquirerRequest) error {
 	return nil
 }
 
+// validateCodes checks the coded fields of an acquirer against the DIAN code lists.
+func validateCodes(tdoCodigo, tojCodigo, paiCodigo string, paiCodigoDomicilioFiscal, rfiCodigo *string, refCodigo, responsableTributos []string) error {
+	if err := codelist.Check(codelist.IdentificationTypes, "tdo_codigo", tdoCodigo); err != nil {
+		return err
+	}
+	if err := codelist.Check(codelist.OrganizationTypes, "toj_codigo", tojCodigo); err != nil {
+		return err
+	}
+	if err := codelist.Check(codelist.Countries, "pai_codigo", paiCodigo); err != nil {
+		return err
+	}
+	optional := []struct {
+		list  string
+		field string
+		code  *string
+	}{
+		{codelist.Countries, "pai_codigo_domicilio_fiscal", paiCodigoDomicilioFiscal},
+		{codelist.TaxRegimes, "rfi_codigo", rfiCodigo},
+	}
+	for _, o := range optional {
+		if o.code == nil || *o.code == "" {
+			continue
+		}
+		if err := codelist.Check(o.list, o.field, *o.code); err != nil {
+			return err
+		}
+	}
+	if err := codelist.CheckAll(codelist.TaxResponsibilities, "ref_codigo", refCodigo); err != nil {
+		return err
+	}
+	return codelist.CheckAll(codelist.Taxes, "responsable_tributos", responsableTributos)
+}
+
 // validateMaxLength validates that a string value does not exceed the maximum length.
 func validateMaxLength(value string, maxLen int, fieldName string) error {
 	if len(value) > maxLen {
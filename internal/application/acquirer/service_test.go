@@ -0,0 +1,202 @@
+package acquirer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/adapters/dane/embedded"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+const testOFE = "900373115"
+
+func newTestService(t *testing.T) (*Service, *testutil.MockAcquirerRepository) {
+	t.Helper()
+	catalog, err := embedded.New()
+	if err != nil {
+		t.Fatalf("embedded catalog: %v", err)
+	}
+	repo := testutil.NewMockAcquirerRepository()
+	return NewService(repo, catalog, testutil.NewNullLogger()), repo
+}
+
+func newCreateRequest(tdoCodigo, adqIdentificacion string) CreateAcquirerRequest {
+	return CreateAcquirerRequest{
+		OfeIdentificacion: testOFE + "-3",
+		AdqIdentificacion: adqIdentificacion,
+		AdqRazonSocial:    "ADQUIRENTE DE PRUEBA S.A.S.",
+		TdoCodigo:         tdoCodigo,
+		TojCodigo:         "1",
+		PaiCodigo:         "CO",
+	}
+}
+
+func TestService_CreateAcquirer(t *testing.T) {
+	tests := []struct {
+		name       string
+		tdoCodigo  string
+		number     string
+		wantStored string
+		wantDV     string
+		wantErr    string
+	}{
+		{"NIT with DV and separators", "31", "860.011.153-6", "860011153", "6", ""},
+		{"NIT without DV", "31", "800197268", "800197268", "4", ""},
+		{"cédula with separators", "13", "1.020.304.050", "1020304050", "", ""},
+		{"pasaporte lower-case with spaces", "41", " ab 123456 ", "AB123456", "", ""},
+		{"NIT otro país keeps dashes", "50", "us-12-345", "US-12-345", "", ""},
+		{"NIT with wrong DV", "31", "860011153-1", "", "", "el dígito de verificación debe ser 6"},
+		{"NIT too short", "31", "1234", "", "", "entre 5 y 15 dígitos"},
+		{"cédula with letters", "13", "12A456", "", "", "entre 3 y 10 dígitos"},
+		{"cédula too long", "13", "12345678901", "", "", "entre 3 y 10 dígitos"},
+		{"pasaporte with symbols", "41", "AB#123", "", "", "entre 3 y 20 letras o dígitos"},
+		{"unknown tdo_codigo", "99", "123456", "", "", "tdo_codigo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			service, repo := newTestService(t)
+
+			resp, err := service.CreateAcquirer(ctx, newCreateRequest(tt.tdoCodigo, tt.number))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if !strings.HasPrefix(err.Error(), "adq_identificacion") && tt.tdoCodigo != "99" {
+					t.Errorf("expected error on adq_identificacion, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.AdqDV != tt.wantDV {
+				t.Errorf("expected adq_dv %q, got %q", tt.wantDV, resp.AdqDV)
+			}
+
+			stored, _ := repo.FindByID(ctx, testOFE, tt.wantStored, "")
+			if stored == nil {
+				t.Fatalf("expected acquirer stored as %s/%s", testOFE, tt.wantStored)
+			}
+			if stored.ID != resp.AdqID {
+				t.Errorf("expected adq_id %d, got %d", stored.ID, resp.AdqID)
+			}
+		})
+	}
+}
+
+func TestService_CreateAcquirer_OFE(t *testing.T) {
+	service, _ := newTestService(t)
+
+	req := newCreateRequest("13", "1020304050")
+	req.OfeIdentificacion = testOFE + "-1"
+	if _, err := service.CreateAcquirer(context.Background(), req); err == nil || !strings.HasPrefix(err.Error(), "ofe_identificacion") {
+		t.Errorf("expected ofe_identificacion DV error, got %v", err)
+	}
+}
+
+func TestService_CreateAcquirer_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(t)
+
+	if _, err := service.CreateAcquirer(ctx, newCreateRequest("31", "860011153-6")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The same NIT written differently is the same acquirer
+	_, err := service.CreateAcquirer(ctx, newCreateRequest("31", "860.011.153"))
+	if err == nil || !strings.Contains(err.Error(), "ya existe") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+}
+
+func TestService_UpdateAcquirer(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newTestService(t)
+
+	if _, err := service.CreateAcquirer(ctx, newCreateRequest("31", "860011153")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		wantErr string
+	}{
+		{"NIT with DV in path and body", "860.011.153-6", "860011153-6", ""},
+		{"NIT with wrong DV in body", "860011153", "860011153-2", "el dígito de verificación debe ser 6"},
+		{"unknown acquirer", "800197268", "800197268", "no existe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := UpdateAcquirerRequest{
+				OfeIdentificacion: testOFE,
+				AdqIdentificacion: tt.body,
+				AdqRazonSocial:    "ADQUIRENTE ACTUALIZADO S.A.S.",
+				TdoCodigo:         "31",
+				TojCodigo:         "1",
+				PaiCodigo:         "CO",
+			}
+			err := service.UpdateAcquirer(ctx, testOFE+"-3", tt.path, "", req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			stored, _ := repo.FindByID(ctx, testOFE, "860011153", "")
+			if stored == nil || stored.AdqRazonSocial != "ADQUIRENTE ACTUALIZADO S.A.S." {
+				t.Errorf("expected acquirer updated under its canonical NIT, got %+v", stored)
+			}
+		})
+	}
+}
+
+func TestService_AdqDV(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(t)
+
+	for _, req := range []CreateAcquirerRequest{
+		newCreateRequest("31", "860011153"),
+		newCreateRequest("13", "1020304050"),
+	} {
+		if _, err := service.CreateAcquirer(ctx, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		number string
+		wantDV string
+	}{
+		{"860011153", "6"},
+		{"1020304050", ""},
+	}
+
+	for _, tt := range tests {
+		found, err := service.SearchAcquirer(ctx, "adq_identificacion", tt.number, testOFE, "exacto")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(found) != 1 || found[0].AdqDV != tt.wantDV {
+			t.Errorf("search %s: expected adq_dv %q, got %+v", tt.number, tt.wantDV, found)
+		}
+	}
+
+	list, err := service.ListAcquirers(ctx, 0, -1, "", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, dto := range list.Data {
+		want := map[string]string{"860011153": "6", "1020304050": ""}[dto.AdqIdentificacion]
+		if dto.AdqDV != want {
+			t.Errorf("list %s: expected adq_dv %q, got %q", dto.AdqIdentificacion, want, dto.AdqDV)
+		}
+	}
+}
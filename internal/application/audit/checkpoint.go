@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/audit"
+)
+
+// defaultCheckpointInterval is how often the heads of the hash chains are checkpointed.
+const defaultCheckpointInterval = time.Hour
+
+// CheckpointJob periodically signs the heads of the current and previous month chains and
+// stores them as checkpoints, so a chain rewritten after a checkpoint is detected.
+type CheckpointJob struct {
+	chains   audit.ChainStore
+	key      ed25519.PrivateKey
+	interval time.Duration
+	now      func() time.Time
+	log      *slog.Logger
+
+	wg sync.WaitGroup
+}
+
+// NewCheckpointJob creates a checkpoint job that signs with the given key.
+func NewCheckpointJob(chains audit.ChainStore, key ed25519.PrivateKey, log *slog.Logger) *CheckpointJob {
+	return &CheckpointJob{
+		chains:   chains,
+		key:      key,
+		interval: defaultCheckpointInterval,
+		now:      time.Now,
+		log:      log,
+	}
+}
+
+// WithInterval sets how often the chains are checkpointed.
+func (j *CheckpointJob) WithInterval(d time.Duration) *CheckpointJob {
+	if d > 0 {
+		j.interval = d
+	}
+	return j
+}
+
+// Start checkpoints the chains immediately and then every interval until ctx is cancelled.
+// Use Wait to block until it stops.
+func (j *CheckpointJob) Start(ctx context.Context) {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := j.Run(ctx); err != nil && ctx.Err() == nil {
+				j.log.Error("Audit chain checkpoint failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the job has stopped.
+func (j *CheckpointJob) Wait() {
+	j.wg.Wait()
+}
+
+// Run stores a signed checkpoint of the head of the current and previous month chains,
+// unless the head did not change since their last checkpoint. The previous month is
+// included so its final head is sealed once the month is over.
+func (j *CheckpointJob) Run(ctx context.Context) error {
+	now := j.now()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	for _, month := range []time.Time{current.AddDate(0, -1, 0), current} {
+		chain := audit.ChainOf(month)
+
+		head, err := j.chains.ChainHead(ctx, chain)
+		if err != nil {
+			return fmt.Errorf("find head of chain %s: %w", chain, err)
+		}
+		if head == nil || head.Hash == "" {
+			continue
+		}
+
+		checkpoints, err := j.chains.Checkpoints(ctx, chain)
+		if err != nil {
+			return fmt.Errorf("list checkpoints of chain %s: %w", chain, err)
+		}
+		if n := len(checkpoints); n > 0 && checkpoints[n-1].LastID == head.ID {
+			continue
+		}
+
+		checkpoint := audit.Checkpoint{
+			Chain:     chain,
+			LastID:    head.ID,
+			LastHash:  head.Hash,
+			CreatedAt: now.UTC().Truncate(time.Microsecond),
+		}
+		checkpoint.Sign(j.key)
+		if err := j.chains.SaveCheckpoint(ctx, checkpoint); err != nil {
+			return fmt.Errorf("save checkpoint of chain %s: %w", chain, err)
+		}
+		j.log.Info("Audit chain checkpoint stored", "cadena", chain, "ultimo_id", head.ID)
+	}
+
+	return nil
+}
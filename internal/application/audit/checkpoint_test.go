@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/audit"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func TestCheckpointJob_Run(t *testing.T) {
+	repo := seedAuditLog(t)
+	_, key, _ := ed25519.GenerateKey(nil)
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	job := NewCheckpointJob(repo, key, testutil.NewNullLogger())
+	job.now = func() time.Time { return time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC) }
+
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The previous month chain is sealed; the current one is still empty
+	checkpoints, _ := repo.Checkpoints(context.Background(), "2026-10")
+	if len(checkpoints) != 1 || checkpoints[0].LastID != 3 || !checkpoints[0].VerifySignature(publicKey) {
+		t.Fatalf("expected a signed checkpoint of log 3, got %+v", checkpoints)
+	}
+
+	// An unchanged head is not checkpointed again
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checkpoints, _ := repo.Checkpoints(context.Background(), "2026-10"); len(checkpoints) != 1 {
+		t.Errorf("expected 1 checkpoint, got %d", len(checkpoints))
+	}
+
+	// New logs move the head and get a new checkpoint
+	if err := repo.Save(context.Background(), audit.ProviderAuditLog{Provider: "numrot", CreatedAt: time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checkpoints, _ := repo.Checkpoints(context.Background(), "2026-10"); len(checkpoints) != 2 || checkpoints[1].LastID != 4 {
+		t.Errorf("expected a second checkpoint of log 4, got %+v", checkpoints)
+	}
+}
+
+func TestCheckpointJob_DetectsRewrittenChain(t *testing.T) {
+	repo := seedAuditLog(t)
+	_, key, _ := ed25519.GenerateKey(nil)
+
+	job := NewCheckpointJob(repo, key, testutil.NewNullLogger())
+	job.now = func() time.Time { return time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC) }
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Rewrite the last log and recompute its hash, as someone with database access could
+	repo.Tamper(3, func(log *audit.ProviderAuditLog) {
+		log.DurationMs = 1
+		log.Hash, _ = audit.ChainHash(log.PrevHash, *log)
+	})
+
+	response, err := NewService(repo).WithChainStore(repo, key.Public().(ed25519.PublicKey)).Verify(context.Background(), "2026-10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Valid || response.Chains[0].Break == nil || response.Chains[0].Break.ID != 3 {
+		t.Errorf("expected the checkpoint to detect the rewrite at log 3, got %+v", response.Chains[0])
+	}
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/audit"
+)
+
+// defaultRetentionInterval is how often partitions are created ahead and old ones dropped.
+const defaultRetentionInterval = 24 * time.Hour
+
+// RetentionJob keeps the monthly partitions of the audit log: it creates the partitions of
+// the current and next month and drops the ones older than the retention age.
+type RetentionJob struct {
+	retention audit.Retention
+	months    int // Months kept before the current one; 0 keeps every partition
+	interval  time.Duration
+	now       func() time.Time
+	log       *slog.Logger
+
+	wg sync.WaitGroup
+}
+
+// NewRetentionJob creates a retention job that keeps the given number of months before
+// the current one. months <= 0 disables dropping partitions.
+func NewRetentionJob(retention audit.Retention, months int, log *slog.Logger) *RetentionJob {
+	return &RetentionJob{
+		retention: retention,
+		months:    months,
+		interval:  defaultRetentionInterval,
+		now:       time.Now,
+		log:       log,
+	}
+}
+
+// WithInterval sets how often the job runs.
+func (j *RetentionJob) WithInterval(d time.Duration) *RetentionJob {
+	if d > 0 {
+		j.interval = d
+	}
+	return j
+}
+
+// Start runs the job immediately and then every interval until ctx is cancelled.
+// Use Wait to block until it stops.
+func (j *RetentionJob) Start(ctx context.Context) {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := j.Run(ctx); err != nil && ctx.Err() == nil {
+				j.log.Error("Audit log retention failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the job has stopped.
+func (j *RetentionJob) Wait() {
+	j.wg.Wait()
+}
+
+// Run creates the partitions of the current and next month and drops the partitions
+// of the months before the retention age.
+func (j *RetentionJob) Run(ctx context.Context) error {
+	now := j.now()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	for _, month := range []time.Time{current, current.AddDate(0, 1, 0)} {
+		if err := j.retention.EnsurePartition(ctx, month); err != nil {
+			return fmt.Errorf("ensure partition %s: %w", month.Format("2006-01"), err)
+		}
+	}
+
+	if j.months <= 0 {
+		return nil
+	}
+
+	cutoff := current.AddDate(0, -j.months, 0)
+	dropped, err := j.retention.DropPartitionsBefore(ctx, cutoff)
+	if len(dropped) > 0 {
+		j.log.Info("Dropped expired audit log partitions", "particiones", dropped, "antes_de", cutoff.Format("2006-01"))
+	}
+	if err != nil {
+		return fmt.Errorf("drop partitions before %s: %w", cutoff.Format("2006-01"), err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func TestRetentionJob_Run(t *testing.T) {
+	repo := testutil.NewMockAuditRepository()
+	for _, month := range []time.Time{
+		time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		_ = repo.EnsurePartition(context.Background(), month)
+	}
+
+	job := NewRetentionJob(repo, 12, testutil.NewNullLogger())
+	job.now = func() time.Time { return time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC) }
+
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Twelve months before the current one are kept, and the next month is created ahead
+	expected := "2025_10,2026_10,2026_11"
+	if got := strings.Join(repo.Partitions(), ","); got != expected {
+		t.Errorf("expected partitions %s, got %s", expected, got)
+	}
+}
+
+func TestRetentionJob_RunWithoutRetentionKeepsPartitions(t *testing.T) {
+	repo := testutil.NewMockAuditRepository()
+	_ = repo.EnsurePartition(context.Background(), time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	job := NewRetentionJob(repo, 0, testutil.NewNullLogger())
+	job.now = func() time.Time { return time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC) }
+
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "2020_01,2026_10,2026_11"
+	if got := strings.Join(repo.Partitions(), ","); got != expected {
+		t.Errorf("expected partitions %s, got %s", expected, got)
+	}
+}
+
+func TestRetentionJob_RunDropError(t *testing.T) {
+	repo := testutil.NewMockAuditRepository()
+	repo.DropErr = errors.New("permission denied")
+
+	job := NewRetentionJob(repo, 6, testutil.NewNullLogger())
+	if err := job.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected drop error, got %v", err)
+	}
+}
+
+func TestRetentionJob_StartRunsImmediately(t *testing.T) {
+	repo := testutil.NewMockAuditRepository()
+	job := NewRetentionJob(repo, 0, testutil.NewNullLogger()).WithInterval(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job.Start(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(repo.Partitions()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	job.Wait()
+
+	if len(repo.Partitions()) != 2 {
+		t.Errorf("expected the current and next month partitions, got %v", repo.Partitions())
+	}
+}
@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"3tcapital/goclonacion/internal/core/audit"
+)
+
+var (
+	// ErrInvalidChain is returned when the chain to verify is not a month.
+	ErrInvalidChain = errors.New("mes debe tener el formato AAAA-MM")
+	// ErrChainUnavailable is returned when the hash chain cannot be read.
+	ErrChainUnavailable = errors.New("la cadena de hashes de auditoría no está disponible")
+
+	// errChainBroken stops walking a chain at its first broken link.
+	errChainBroken = errors.New("chain broken")
+)
+
+const (
+	// defaultLength is the page size used when the request does not set one.
+	defaultLength = 10
+	// maxLength bounds the page size, since every entry carries its full request and response bodies.
+	maxLength = 100
+)
+
+// Service orchestrates the support and compliance queries over the provider audit logs.
+type Service struct {
+	reader audit.Reader
+
+	chains    audit.ChainStore  // Optional: nil disables the chain verification
+	publicKey ed25519.PublicKey // Optional: nil skips the checkpoint signature check
+}
+
+// NewService creates a new audit log query service with the given reader.
+func NewService(reader audit.Reader) *Service {
+	return &Service{
+		reader: reader,
+	}
+}
+
+// WithChainStore enables the verification of the hash chains. publicKey verifies the
+// signature of the checkpoints; without it only their hashes are compared.
+func (s *Service) WithChainStore(chains audit.ChainStore, publicKey ed25519.PublicKey) *Service {
+	s.chains = chains
+	s.publicKey = publicKey
+	return s
+}
+
+// SearchResponse represents a page of audit logs and the total number of matches.
+type SearchResponse struct {
+	Total int                      `json:"total"`
+	Data  []audit.ProviderAuditLog `json:"data"`
+}
+
+// Search returns a page of the audit logs matching the filter, newest first.
+func (s *Service) Search(ctx context.Context, filter audit.Filter) (*SearchResponse, error) {
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+	if filter.Start < 0 {
+		filter.Start = 0
+	}
+	if filter.Length <= 0 {
+		filter.Length = defaultLength
+	}
+	if filter.Length > maxLength {
+		return nil, fmt.Errorf("length debe ser como máximo %d", maxLength)
+	}
+
+	logs, total, err := s.reader.Search(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("search audit logs: %w", err)
+	}
+
+	return &SearchResponse{
+		Total: total,
+		Data:  logs,
+	}, nil
+}
+
+// Export calls fn for every audit log matching the filter, oldest first.
+// Pagination is ignored: the export covers every match.
+func (s *Service) Export(ctx context.Context, filter audit.Filter, fn func(audit.ProviderAuditLog) error) error {
+	if err := validateFilter(filter); err != nil {
+		return err
+	}
+	filter.Start, filter.Length = 0, 0
+
+	if err := s.reader.Export(ctx, filter, fn); err != nil {
+		return fmt.Errorf("export audit logs: %w", err)
+	}
+	return nil
+}
+
+// VerifyResponse reports the verification of one or more hash chains.
+type VerifyResponse struct {
+	Valid     bool                 `json:"valid"`
+	PublicKey string               `json:"public_key,omitempty"` // Base64 key that verified the checkpoint signatures
+	Chains    []audit.Verification `json:"chains"`
+}
+
+// Verify walks the hash chain of the given month (YYYY-MM), or every chain when chain is
+// empty, and reports the first broken link of each one.
+func (s *Service) Verify(ctx context.Context, chain string) (*VerifyResponse, error) {
+	if s.chains == nil {
+		return nil, ErrChainUnavailable
+	}
+
+	chains := []string{chain}
+	if chain == "" {
+		var err error
+		if chains, err = s.chains.Chains(ctx); err != nil {
+			return nil, fmt.Errorf("list audit chains: %w", err)
+		}
+	} else if _, err := audit.ParseChain(chain); err != nil {
+		return nil, ErrInvalidChain
+	}
+
+	response := &VerifyResponse{Valid: true, Chains: make([]audit.Verification, 0, len(chains))}
+	if s.publicKey != nil {
+		response.PublicKey = base64.StdEncoding.EncodeToString(s.publicKey)
+	}
+
+	for _, chain := range chains {
+		verification, err := s.verifyChain(ctx, chain)
+		if err != nil {
+			return nil, err
+		}
+		response.Valid = response.Valid && verification.Valid
+		response.Chains = append(response.Chains, verification)
+	}
+
+	return response, nil
+}
+
+func (s *Service) verifyChain(ctx context.Context, chain string) (audit.Verification, error) {
+	checkpoints, err := s.chains.Checkpoints(ctx, chain)
+	if err != nil {
+		return audit.Verification{}, fmt.Errorf("list checkpoints of chain %s: %w", chain, err)
+	}
+
+	verifier := audit.NewChainVerifier(chain, checkpoints, s.publicKey)
+	err = s.chains.WalkChain(ctx, chain, func(log audit.ProviderAuditLog) error {
+		if !verifier.Add(log) {
+			return errChainBroken
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errChainBroken) {
+		return audit.Verification{}, fmt.Errorf("walk chain %s: %w", chain, err)
+	}
+
+	return verifier.Result(), nil
+}
+
+// validateFilter checks the time range and the duration threshold of the filter.
+func validateFilter(filter audit.Filter) error {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		return fmt.Errorf("hasta debe ser posterior a desde")
+	}
+	if filter.MinDurationMs < 0 {
+		return fmt.Errorf("duracion_min_ms debe ser un número entero no negativo")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/audit"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func seedAuditLog(t *testing.T) *testutil.MockAuditRepository {
+	t.Helper()
+	repo := testutil.NewMockAuditRepository()
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	ok, failed := 200, 500
+	logs := []audit.ProviderAuditLog{
+		{CorrelationID: "req-1", Provider: "numrot", Operation: "RegisterDocument", ResponseStatus: &ok, DurationMs: 300,
+			RequestBody: json.RawMessage(`{"Prefijo":"SETT","Numero":"100"}`), CreatedAt: base},
+		{CorrelationID: "req-2", Provider: "numrot", Operation: "RegisterDocument", ResponseStatus: &failed, DurationMs: 4500,
+			RequestBody: json.RawMessage(`{"Prefijo":"SETT","Numero":"101"}`), CreatedAt: base.Add(time.Hour)},
+		{CorrelationID: "req-3", Provider: "dane", Operation: "GetMunicipality", ResponseStatus: &ok, DurationMs: 80,
+			CreatedAt: base.Add(2 * time.Hour)},
+	}
+	for _, log := range logs {
+		if err := repo.Save(context.Background(), log); err != nil {
+			t.Fatalf("seed audit log: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestService_Search(t *testing.T) {
+	service := NewService(seedAuditLog(t))
+	failed := 500
+
+	tests := []struct {
+		name          string
+		filter        audit.Filter
+		expectedTotal int
+		expectedFirst string
+		expectedErr   string
+	}{
+		{name: "all newest first", filter: audit.Filter{}, expectedTotal: 3, expectedFirst: "req-3"},
+		{name: "by provider", filter: audit.Filter{Provider: "numrot"}, expectedTotal: 2, expectedFirst: "req-2"},
+		{name: "by status", filter: audit.Filter{Status: &failed}, expectedTotal: 1, expectedFirst: "req-2"},
+		{name: "by duration", filter: audit.Filter{MinDurationMs: 1000}, expectedTotal: 1, expectedFirst: "req-2"},
+		{name: "by document", filter: audit.Filter{Document: `"Numero":"100"`}, expectedTotal: 1, expectedFirst: "req-1"},
+		{
+			name:          "by time range",
+			filter:        audit.Filter{From: time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC), To: time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)},
+			expectedTotal: 1,
+			expectedFirst: "req-2",
+		},
+		{name: "paginated", filter: audit.Filter{Start: 1, Length: 1}, expectedTotal: 3, expectedFirst: "req-2"},
+		{
+			name:        "inverted range",
+			filter:      audit.Filter{From: time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC), To: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+			expectedErr: "hasta debe ser posterior a desde",
+		},
+		{name: "negative duration", filter: audit.Filter{MinDurationMs: -1}, expectedErr: "duracion_min_ms"},
+		{name: "page too large", filter: audit.Filter{Length: maxLength + 1}, expectedErr: "length debe ser como máximo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.Search(context.Background(), tt.filter)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.Total != tt.expectedTotal {
+				t.Errorf("expected total %d, got %d", tt.expectedTotal, response.Total)
+			}
+			if len(response.Data) == 0 || response.Data[0].CorrelationID != tt.expectedFirst {
+				t.Errorf("expected first log %s, got %+v", tt.expectedFirst, response.Data)
+			}
+		})
+	}
+}
+
+func TestService_Export(t *testing.T) {
+	service := NewService(seedAuditLog(t))
+
+	var exported []string
+	err := service.Export(context.Background(), audit.Filter{Provider: "numrot", Length: 1}, func(log audit.ProviderAuditLog) error {
+		exported = append(exported, log.CorrelationID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Pagination is ignored and the logs are exported oldest first
+	if strings.Join(exported, ",") != "req-1,req-2" {
+		t.Errorf("expected req-1,req-2, got %v", exported)
+	}
+}
+
+func TestService_Verify(t *testing.T) {
+	repo := seedAuditLog(t)
+	_, key, _ := ed25519.GenerateKey(nil)
+	service := NewService(repo).WithChainStore(repo, key.Public().(ed25519.PublicKey))
+
+	response, err := service.Verify(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !response.Valid || len(response.Chains) != 1 || response.Chains[0].Verified != 3 || response.PublicKey == "" {
+		t.Fatalf("expected a valid chain of 3 logs, got %+v", response)
+	}
+
+	repo.Tamper(2, func(log *audit.ProviderAuditLog) { log.DurationMs = 10 })
+
+	response, err = service.Verify(context.Background(), "2026-10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chain := response.Chains[0]
+	if response.Valid || chain.Break == nil || chain.Break.ID != 2 || chain.Verified != 1 {
+		t.Errorf("expected the chain to break at log 2, got %+v", chain)
+	}
+}
+
+func TestService_VerifyErrors(t *testing.T) {
+	repo := seedAuditLog(t)
+
+	if _, err := NewService(repo).Verify(context.Background(), ""); !errors.Is(err, ErrChainUnavailable) {
+		t.Errorf("expected ErrChainUnavailable, got %v", err)
+	}
+	if _, err := NewService(repo).WithChainStore(repo, nil).Verify(context.Background(), "octubre"); !errors.Is(err, ErrInvalidChain) {
+		t.Errorf("expected ErrInvalidChain, got %v", err)
+	}
+}
@@ -0,0 +1,360 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	appidempotency "3tcapital/goclonacion/internal/application/idempotency"
+	"3tcapital/goclonacion/internal/core/batch"
+	"3tcapital/goclonacion/internal/core/idempotency"
+	"3tcapital/goclonacion/internal/core/invoice"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the spans of the batch processing.
+var tracer = otel.Tracer("3tcapital/goclonacion/internal/application/batch")
+
+// submitBatchScope identifies batch submissions in the idempotency store.
+const submitBatchScope = "documentos-lotes"
+
+// defaultPollInterval is how often unfinished batches are looked up even without new submissions.
+const defaultPollInterval = 30 * time.Second
+
+// lease is how long a claimed batch is reserved to the worker processing it. The lease is
+// renewed while the batch is processed; once it expires (e.g. the instance stopped),
+// another instance takes the batch over.
+const lease = 2 * time.Minute
+
+// DocumentRegistrar registers a group of documents of a single type.
+// It is implemented by the invoice application service.
+type DocumentRegistrar interface {
+	RegisterDocument(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error)
+}
+
+// Service enqueues registration batches (lotes) durably and processes them in background workers.
+// Batches are processed in chunks; progress is persisted after each chunk so an interrupted
+// batch resumes from the first unprocessed document after a restart. Workers claim batches
+// in the repository, so several instances can share the queue.
+type Service struct {
+	repo         batch.Repository
+	registrar    DocumentRegistrar
+	log          *slog.Logger
+	chunkSize    int
+	workers      int
+	pollInterval time.Duration
+
+	wake chan struct{}
+	wg   sync.WaitGroup
+
+	idempotency idempotency.Repository // Optional: nil if Idempotency-Key support is disabled
+}
+
+// NewService creates a new batch service.
+// chunkSize is the number of documents sent to the registrar per call (defaults to 50).
+// workers is the number of batches processed concurrently (defaults to 2).
+func NewService(repo batch.Repository, registrar DocumentRegistrar, chunkSize, workers int, log *slog.Logger) *Service {
+	if chunkSize <= 0 {
+		chunkSize = 50
+	}
+	if workers <= 0 {
+		workers = 2
+	}
+	return &Service{
+		repo:         repo,
+		registrar:    registrar,
+		log:          log,
+		chunkSize:    chunkSize,
+		workers:      workers,
+		pollInterval: defaultPollInterval,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Start launches the workers. Unfinished batches from previous runs are picked up
+// immediately. Workers stop when ctx is cancelled; use Wait to block until they do.
+func (s *Service) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.worker(ctx)
+	}
+
+	s.notify()
+}
+
+// WithIdempotency enables Idempotency-Key support for batch submissions.
+func (s *Service) WithIdempotency(repo idempotency.Repository) *Service {
+	s.idempotency = repo
+	return s
+}
+
+// SubmitWithKey submits a batch at most once per Idempotency-Key. A retry with the same key
+// and payload returns the originally created batch (replayed=true) instead of enqueuing a new one.
+func (s *Service) SubmitWithKey(ctx context.Context, key string, req invoice.DocumentRegistrationRequest) (*batch.Batch, bool, error) {
+	if key == "" || s.idempotency == nil {
+		b, err := s.Submit(ctx, req)
+		return b, false, err
+	}
+
+	return appidempotency.Execute(ctx, s.idempotency, submitBatchScope, key, req, func(ctx context.Context) (*batch.Batch, error) {
+		return s.Submit(ctx, req)
+	})
+}
+
+// Wait blocks until all workers have stopped.
+func (s *Service) Wait() {
+	s.wg.Wait()
+}
+
+// Submit validates and persists a new batch, then schedules it for processing.
+func (s *Service) Submit(ctx context.Context, req invoice.DocumentRegistrationRequest) (*batch.Batch, error) {
+	typeCount := 0
+	for _, docs := range [][]invoice.OpenETLDocument{req.Documentos.FC, req.Documentos.NC, req.Documentos.ND, req.Documentos.DS} {
+		if len(docs) > 0 {
+			typeCount++
+		}
+	}
+	if typeCount == 0 {
+		return nil, fmt.Errorf("no documents provided")
+	}
+	if typeCount > 1 {
+		return nil, fmt.Errorf("only one document type (FC, NC, ND, or DS) can be provided per request")
+	}
+
+	b := batch.Batch{
+		ID:                   uuid.New().String(),
+		Estado:               batch.StatusPending,
+		Request:              req,
+		DocumentosProcesados: []invoice.ProcessedDocument{},
+		DocumentosFallidos:   []invoice.FailedDocument{},
+		CreatedAt:            time.Now(),
+	}
+	docs, documentType := b.Documents()
+	b.TipoDocumento = documentType
+	b.Total = len(docs)
+
+	if err := s.repo.Create(ctx, b); err != nil {
+		return nil, fmt.Errorf("create batch: %w", err)
+	}
+
+	s.log.Info("Batch enqueued", "lote", b.ID, "tipo", documentType, "total", b.Total)
+	s.notify()
+
+	return &b, nil
+}
+
+// GetBatch retrieves the progress and results of a batch.
+func (s *Service) GetBatch(ctx context.Context, id string) (*batch.Batch, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("lote [%s] inválido", id)
+	}
+
+	b, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("find batch: %w", err)
+	}
+	if b == nil {
+		return nil, fmt.Errorf("el lote [%s] no existe", id)
+	}
+
+	if b.DocumentosProcesados == nil {
+		b.DocumentosProcesados = []invoice.ProcessedDocument{}
+	}
+	if b.DocumentosFallidos == nil {
+		b.DocumentosFallidos = []invoice.FailedDocument{}
+	}
+
+	return b, nil
+}
+
+// notify wakes up an idle worker without blocking.
+func (s *Service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// worker claims and processes batches until none is left, then waits to be woken up or
+// for the poll interval to elapse.
+func (s *Service) worker(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.processNext(ctx) {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext claims the oldest available batch and processes it. It reports whether a
+// batch was claimed.
+func (s *Service) processNext(ctx context.Context) bool {
+	b, err := s.repo.Claim(ctx, lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Error("Failed to claim batch", "error", err)
+		}
+		return false
+	}
+	if b == nil {
+		return false
+	}
+
+	// Another idle worker may take the next batch
+	s.notify()
+
+	stop := s.keepLease(ctx, b.ID)
+	s.traceProcess(ctx, b)
+	stop()
+	return true
+}
+
+// keepLease renews the lease of a batch until the returned function is called.
+func (s *Service) keepLease(ctx context.Context, id string) func() {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.repo.Extend(ctx, id, lease); err != nil {
+					s.log.Warn("Failed to extend batch lease", "lote", id, "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// traceProcess processes a batch inside its own span.
+func (s *Service) traceProcess(ctx context.Context, b *batch.Batch) {
+	ctx, span := tracer.Start(ctx, "batch.process", trace.WithAttributes(attribute.String("batch.id", b.ID)))
+	defer span.End()
+	s.process(ctx, b)
+}
+
+// process registers the remaining documents of a claimed batch chunk by chunk.
+func (s *Service) process(ctx context.Context, b *batch.Batch) {
+	id := b.ID
+	docs, documentType := b.Documents()
+	s.log.Info("Processing batch", "lote", id, "tipo", documentType, "total", len(docs), "cursor", b.Cursor)
+
+	for cursor := b.Cursor; cursor < len(docs); {
+		if ctx.Err() != nil {
+			s.interrupted(ctx, id, cursor)
+			return
+		}
+
+		end := cursor + s.chunkSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		chunk := docs[cursor:end]
+
+		var processed []invoice.ProcessedDocument
+		var failed []invoice.FailedDocument
+
+		response, err := s.registrar.RegisterDocument(ctx, chunkRequest(documentType, chunk))
+		if err != nil {
+			if ctx.Err() != nil {
+				s.interrupted(ctx, id, cursor)
+				return
+			}
+			failed = failChunk(documentType, chunk, err)
+		} else {
+			processed = response.DocumentosProcesados
+			failed = response.DocumentosFallidos
+		}
+
+		if err := s.repo.AppendResults(ctx, id, cursor, end, processed, failed); err != nil {
+			if errors.Is(err, batch.ErrLeaseLost) {
+				// Another instance owns the batch now; its lease must not be released
+				s.log.Warn("Batch lease lost, stopping its processing", "lote", id, "cursor", cursor)
+				return
+			}
+			s.log.Error("Failed to store batch progress", "lote", id, "cursor", end, "error", err)
+			return
+		}
+		cursor = end
+	}
+
+	if err := s.repo.Finish(ctx, id, batch.StatusCompleted, ""); err != nil {
+		s.log.Error("Failed to finish batch", "lote", id, "error", err)
+		return
+	}
+
+	s.log.Info("Batch completed", "lote", id, "total", len(docs))
+}
+
+// interrupted releases a batch whose processing was stopped, so that it is resumed by the
+// next instance that claims it.
+func (s *Service) interrupted(ctx context.Context, id string, cursor int) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := s.repo.Release(ctx, id); err != nil {
+		s.log.Warn("Failed to release batch", "lote", id, "error", err)
+	}
+	s.log.Info("Batch processing interrupted, it will resume on restart", "lote", id, "cursor", cursor)
+}
+
+// chunkRequest builds a registration request for a chunk of documents of the given type.
+func chunkRequest(documentType string, docs []invoice.OpenETLDocument) invoice.DocumentRegistrationRequest {
+	req := invoice.DocumentRegistrationRequest{}
+	switch documentType {
+	case "FC":
+		req.Documentos.FC = docs
+	case "NC":
+		req.Documentos.NC = docs
+	case "ND":
+		req.Documentos.ND = docs
+	case "DS":
+		req.Documentos.DS = docs
+	}
+	return req
+}
+
+// failChunk reports every document of a chunk as failed with the given error.
+func failChunk(documentType string, docs []invoice.OpenETLDocument, err error) []invoice.FailedDocument {
+	now := time.Now()
+	failed := make([]invoice.FailedDocument, 0, len(docs))
+	for _, doc := range docs {
+		failed = append(failed, invoice.FailedDocument{
+			Documento:          documentType,
+			Consecutivo:        doc.CdoConsecutivo,
+			Prefijo:            doc.RfaPrefijo,
+			Errors:             []string{err.Error()},
+			FechaProcesamiento: now.Format("2006-01-02"),
+			HoraProcesamiento:  now.Format("15:04:05"),
+		})
+	}
+	return failed
+}
@@ -0,0 +1,390 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/batch"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+// memoryRepository is an in-memory batch.Repository used by the tests.
+type memoryRepository struct {
+	mu      sync.Mutex
+	batches map[string]*batch.Batch
+	leases  map[string]time.Time
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{batches: make(map[string]*batch.Batch), leases: make(map[string]time.Time)}
+}
+
+func (m *memoryRepository) Create(ctx context.Context, b batch.Batch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches[b.ID] = &b
+	return nil
+}
+
+func (m *memoryRepository) FindByID(ctx context.Context, id string) (*batch.Batch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.batches[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *b
+	return &copied, nil
+}
+
+func (m *memoryRepository) Claim(ctx context.Context, lease time.Duration) (*batch.Batch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var oldest *batch.Batch
+	for id, b := range m.batches {
+		claimable := b.Estado == batch.StatusPending ||
+			(b.Estado == batch.StatusProcessing && time.Now().After(m.leases[id]))
+		if claimable && (oldest == nil || b.CreatedAt.Before(oldest.CreatedAt)) {
+			oldest = b
+		}
+	}
+	if oldest == nil {
+		return nil, nil
+	}
+	oldest.Estado = batch.StatusProcessing
+	m.leases[oldest.ID] = time.Now().Add(lease)
+	copied := *oldest
+	return &copied, nil
+}
+
+func (m *memoryRepository) Extend(ctx context.Context, id string, lease time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.leases[id] = time.Now().Add(lease)
+	return nil
+}
+
+func (m *memoryRepository) Release(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.leases, id)
+	return nil
+}
+
+func (m *memoryRepository) AppendResults(ctx context.Context, id string, from, to int, processed []invoice.ProcessedDocument, failed []invoice.FailedDocument) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := m.batches[id]
+	if b.Estado != batch.StatusProcessing || b.Cursor != from || !time.Now().Before(m.leases[id]) {
+		return batch.ErrLeaseLost
+	}
+	b.Cursor = to
+	b.Procesados += len(processed)
+	b.Fallidos += len(failed)
+	b.DocumentosProcesados = append(b.DocumentosProcesados, processed...)
+	b.DocumentosFallidos = append(b.DocumentosFallidos, failed...)
+	return nil
+}
+
+func (m *memoryRepository) Finish(ctx context.Context, id string, status batch.Status, errMsg string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches[id].Estado = status
+	m.batches[id].Error = errMsg
+	return nil
+}
+
+// recordingRegistrar processes every document successfully and records the chunk sizes.
+type recordingRegistrar struct {
+	mu         sync.Mutex
+	chunks     []int
+	err        error
+	onRegister func() // Optional: called while each chunk is registered
+}
+
+func (r *recordingRegistrar) RegisterDocument(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+	r.mu.Lock()
+	r.chunks = append(r.chunks, len(req.Documentos.FC))
+	r.mu.Unlock()
+	if r.onRegister != nil {
+		r.onRegister()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	resp := &invoice.DocumentRegistrationResponse{}
+	for _, doc := range req.Documentos.FC {
+		resp.DocumentosProcesados = append(resp.DocumentosProcesados, invoice.ProcessedDocument{
+			RfaPrefijo:     doc.RfaPrefijo,
+			CdoConsecutivo: doc.CdoConsecutivo,
+		})
+	}
+	return resp, nil
+}
+
+func newFCRequest(n int) invoice.DocumentRegistrationRequest {
+	docs := make([]invoice.OpenETLDocument, n)
+	for i := range docs {
+		docs[i] = invoice.OpenETLDocument{RfaPrefijo: "SETT", CdoConsecutivo: string(rune('1' + i))}
+	}
+	return invoice.DocumentRegistrationRequest{Documentos: invoice.DocumentsByType{FC: docs}}
+}
+
+func waitForStatus(t *testing.T, service *Service, id string, status batch.Status) *batch.Batch {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		b, err := service.GetBatch(context.Background(), id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.Estado == status {
+			return b
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("batch %s did not reach status %s", id, status)
+	return nil
+}
+
+func TestService_Submit_Validation(t *testing.T) {
+	service := NewService(newMemoryRepository(), &recordingRegistrar{}, 2, 1, testutil.NewNullLogger())
+
+	tests := []struct {
+		name        string
+		req         invoice.DocumentRegistrationRequest
+		expectedErr string
+	}{
+		{name: "no documents", req: invoice.DocumentRegistrationRequest{}, expectedErr: "no documents provided"},
+		{
+			name: "multiple types",
+			req: invoice.DocumentRegistrationRequest{Documentos: invoice.DocumentsByType{
+				FC: []invoice.OpenETLDocument{{}},
+				NC: []invoice.OpenETLDocument{{}},
+			}},
+			expectedErr: "only one document type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Submit(context.Background(), tt.req)
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestService_ProcessesBatchInChunks(t *testing.T) {
+	registrar := &recordingRegistrar{}
+	service := NewService(newMemoryRepository(), registrar, 2, 1, testutil.NewNullLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		service.Wait()
+	}()
+	service.Start(ctx)
+
+	b, err := service.Submit(context.Background(), newFCRequest(5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Estado != batch.StatusPending || b.Total != 5 || b.TipoDocumento != "FC" {
+		t.Errorf("unexpected submitted batch: %+v", b)
+	}
+
+	done := waitForStatus(t, service, b.ID, batch.StatusCompleted)
+	if done.Procesados != 5 || done.Fallidos != 0 || len(done.DocumentosProcesados) != 5 {
+		t.Errorf("expected 5 processed documents, got %+v", done)
+	}
+
+	registrar.mu.Lock()
+	defer registrar.mu.Unlock()
+	if len(registrar.chunks) != 3 || registrar.chunks[0] != 2 || registrar.chunks[2] != 1 {
+		t.Errorf("expected chunks [2 2 1], got %v", registrar.chunks)
+	}
+}
+
+func TestService_ResumesUnfinishedBatch(t *testing.T) {
+	repo := newMemoryRepository()
+	req := newFCRequest(4)
+	_ = repo.Create(context.Background(), batch.Batch{
+		ID:            "2f1c6b9e-7c1e-4a53-9d35-1f0b8d6a0a11",
+		Estado:        batch.StatusProcessing,
+		TipoDocumento: "FC",
+		Total:         4,
+		Cursor:        3,
+		Procesados:    3,
+		Request:       req,
+	})
+
+	registrar := &recordingRegistrar{}
+	service := NewService(repo, registrar, 2, 1, testutil.NewNullLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		service.Wait()
+	}()
+	service.Start(ctx)
+
+	done := waitForStatus(t, service, "2f1c6b9e-7c1e-4a53-9d35-1f0b8d6a0a11", batch.StatusCompleted)
+	if done.Procesados != 4 {
+		t.Errorf("expected 4 processed documents after resume, got %d", done.Procesados)
+	}
+
+	registrar.mu.Lock()
+	defer registrar.mu.Unlock()
+	if len(registrar.chunks) != 1 || registrar.chunks[0] != 1 {
+		t.Errorf("expected only the remaining document to be sent, got %v", registrar.chunks)
+	}
+}
+
+func TestService_RegistrarErrorFailsChunk(t *testing.T) {
+	registrar := &recordingRegistrar{err: errors.New("numrot unavailable")}
+	service := NewService(newMemoryRepository(), registrar, 10, 1, testutil.NewNullLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		service.Wait()
+	}()
+	service.Start(ctx)
+
+	b, err := service.Submit(context.Background(), newFCRequest(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := waitForStatus(t, service, b.ID, batch.StatusCompleted)
+	if done.Fallidos != 3 || len(done.DocumentosFallidos) != 3 {
+		t.Fatalf("expected 3 failed documents, got %+v", done)
+	}
+	if done.DocumentosFallidos[0].Errors[0] != "numrot unavailable" {
+		t.Errorf("expected registrar error in failed document, got %v", done.DocumentosFallidos[0].Errors)
+	}
+}
+
+func TestService_GetBatch_Errors(t *testing.T) {
+	service := NewService(newMemoryRepository(), &recordingRegistrar{}, 2, 1, testutil.NewNullLogger())
+
+	if _, err := service.GetBatch(context.Background(), "not-a-uuid"); err == nil || !strings.Contains(err.Error(), "inválido") {
+		t.Errorf("expected invalid id error, got %v", err)
+	}
+	if _, err := service.GetBatch(context.Background(), "2f1c6b9e-7c1e-4a53-9d35-1f0b8d6a0a11"); err == nil || !strings.Contains(err.Error(), "no existe") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestService_InstancesShareQueue(t *testing.T) {
+	repo := newMemoryRepository()
+	registrar := &recordingRegistrar{}
+	first := NewService(repo, registrar, 1, 2, testutil.NewNullLogger())
+	second := NewService(repo, registrar, 1, 2, testutil.NewNullLogger())
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		b, err := first.Submit(context.Background(), newFCRequest(3))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, b.ID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		first.Wait()
+		second.Wait()
+	}()
+	first.Start(ctx)
+	second.Start(ctx)
+
+	for _, id := range ids {
+		if done := waitForStatus(t, first, id, batch.StatusCompleted); done.Procesados != 3 {
+			t.Errorf("expected 3 processed documents in batch %s, got %d", id, done.Procesados)
+		}
+	}
+
+	registrar.mu.Lock()
+	defer registrar.mu.Unlock()
+	if len(registrar.chunks) != 12 {
+		t.Errorf("expected every document to be sent once, got %d chunks", len(registrar.chunks))
+	}
+}
+
+func TestService_SkipsLeasedBatch(t *testing.T) {
+	repo := newMemoryRepository()
+	_ = repo.Create(context.Background(), batch.Batch{
+		ID:            "2f1c6b9e-7c1e-4a53-9d35-1f0b8d6a0a11",
+		Estado:        batch.StatusPending,
+		TipoDocumento: "FC",
+		Total:         1,
+		Request:       newFCRequest(1),
+	})
+	// Another instance is processing the batch
+	if b, _ := repo.Claim(context.Background(), time.Minute); b == nil {
+		t.Fatal("expected the batch to be claimed")
+	}
+
+	registrar := &recordingRegistrar{}
+	service := NewService(repo, registrar, 1, 1, testutil.NewNullLogger())
+	if service.processNext(context.Background()) {
+		t.Fatal("expected a leased batch not to be claimed")
+	}
+
+	// The lease expires when the other instance stops
+	_ = repo.Release(context.Background(), "2f1c6b9e-7c1e-4a53-9d35-1f0b8d6a0a11")
+	if !service.processNext(context.Background()) {
+		t.Fatal("expected the batch to be taken over")
+	}
+	if b, _ := service.GetBatch(context.Background(), "2f1c6b9e-7c1e-4a53-9d35-1f0b8d6a0a11"); b.Estado != batch.StatusCompleted {
+		t.Errorf("expected the batch completed, got %s", b.Estado)
+	}
+}
+
+func TestService_StopsWhenLeaseIsLost(t *testing.T) {
+	const id = "2f1c6b9e-7c1e-4a53-9d35-1f0b8d6a0a11"
+	repo := newMemoryRepository()
+	_ = repo.Create(context.Background(), batch.Batch{
+		ID:            id,
+		Estado:        batch.StatusPending,
+		TipoDocumento: "FC",
+		Total:         3,
+		Request:       newFCRequest(3),
+	})
+
+	// The lease expires while the first chunk is registered and another instance takes
+	// the batch over and stores its progress
+	registrar := &recordingRegistrar{onRegister: func() {
+		repo.mu.Lock()
+		repo.batches[id].Cursor = 1
+		repo.mu.Unlock()
+	}}
+	service := NewService(repo, registrar, 1, 1, testutil.NewNullLogger())
+	if !service.processNext(context.Background()) {
+		t.Fatal("expected the batch to be claimed")
+	}
+
+	b, _ := service.GetBatch(context.Background(), id)
+	if b.Estado != batch.StatusProcessing || b.Procesados != 0 || b.Cursor != 1 {
+		t.Errorf("expected the progress of the new owner kept, got %+v", b)
+	}
+	registrar.mu.Lock()
+	defer registrar.mu.Unlock()
+	if len(registrar.chunks) != 1 {
+		t.Errorf("expected the processing to stop after the lost lease, got %d chunks", len(registrar.chunks))
+	}
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if _, ok := repo.leases[id]; !ok {
+		t.Error("expected the lease of the new owner not to be released")
+	}
+}
@@ -0,0 +1,131 @@
+// Package catalog serves the reference catalogs used by frontends and validations: the
+// DIVIPOLA departments and municipalities and the DIAN code lists.
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/codelist"
+	"3tcapital/goclonacion/internal/core/dane"
+)
+
+// defaultSearchLimit bounds the municipalities returned by a search when no limit is given.
+const defaultSearchLimit = 50
+
+// Service orchestrates the reference catalogs.
+type Service struct {
+	municipalities dane.Catalog
+	repo           dane.Repository // Optional: nil if the catalog is only the embedded snapshot
+	log            *slog.Logger
+}
+
+// NewService creates a new catalog service over the given in-memory DIVIPOLA catalog.
+func NewService(municipalities dane.Catalog, log *slog.Logger) *Service {
+	return &Service{municipalities: municipalities, log: log}
+}
+
+// WithRepository enables the divipola_municipios table, which overrides the embedded
+// catalog once it has been loaded by the refresh command.
+func (s *Service) WithRepository(repo dane.Repository) *Service {
+	s.repo = repo
+	return s
+}
+
+// Load replaces the in-memory catalog with the stored one, if any.
+func (s *Service) Load(ctx context.Context) error {
+	if s.repo == nil {
+		return nil
+	}
+	municipalities, err := s.repo.ListMunicipalities(ctx)
+	if err != nil {
+		return fmt.Errorf("load DIVIPOLA catalog: %w", err)
+	}
+	if len(municipalities) == 0 {
+		s.log.Info("DIVIPOLA table is empty, using the embedded catalog")
+		return nil
+	}
+	if err := s.municipalities.Replace(municipalities); err != nil {
+		return fmt.Errorf("load DIVIPOLA catalog: %w", err)
+	}
+	s.log.Info("DIVIPOLA catalog loaded from database", "municipios", len(municipalities))
+	return nil
+}
+
+// Refresh reads the catalog from source, stores it and replaces the in-memory catalog.
+func (s *Service) Refresh(ctx context.Context, source dane.Source) (int, error) {
+	if s.repo == nil {
+		return 0, fmt.Errorf("refresh DIVIPOLA catalog: database not configured")
+	}
+	municipalities, err := source.ListMunicipalities(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("read DIVIPOLA catalog: %w", err)
+	}
+	if len(municipalities) == 0 {
+		return 0, fmt.Errorf("read DIVIPOLA catalog: no municipalities")
+	}
+	for _, m := range municipalities {
+		if err := m.Validate(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.repo.ReplaceMunicipalities(ctx, municipalities); err != nil {
+		return 0, fmt.Errorf("store DIVIPOLA catalog: %w", err)
+	}
+	if err := s.municipalities.Replace(municipalities); err != nil {
+		return 0, err
+	}
+	return len(municipalities), nil
+}
+
+// Departments returns the DIVIPOLA departments.
+func (s *Service) Departments() []dane.Department {
+	return s.municipalities.Departments()
+}
+
+// SearchMunicipalities returns the municipalities whose name contains query, optionally
+// restricted to a department. A limit of 0 uses defaultSearchLimit.
+func (s *Service) SearchMunicipalities(query, depCodigo string, limit int) ([]dane.Municipality, error) {
+	depCodigo = strings.TrimSpace(depCodigo)
+	if depCodigo != "" && (len(depCodigo) != 2 || strings.Trim(depCodigo, "0123456789") != "") {
+		return nil, fmt.Errorf("dep_codigo debe ser el código DIVIPOLA de 2 dígitos del departamento")
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("limit debe ser mayor o igual a 0")
+	}
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	return s.municipalities.Search(query, depCodigo, limit), nil
+}
+
+// GetMunicipality returns a municipality by its 5 digit DIVIPOLA code, or nil if it does
+// not exist.
+func (s *Service) GetMunicipality(ctx context.Context, codigo string) (*dane.Municipality, error) {
+	codigo = strings.TrimSpace(codigo)
+	if len(codigo) != 5 || strings.Trim(codigo, "0123456789") != "" {
+		return nil, fmt.Errorf("mun_codigo debe ser el código DIVIPOLA de 5 dígitos del municipio")
+	}
+	m, err := s.municipalities.GetMunicipalityByCode(ctx, codigo)
+	if err != nil {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// CodeList returns a DIAN code list by name, or nil if it does not exist.
+func (s *Service) CodeList(name string) *codelist.List {
+	list, ok := codelist.Get(strings.TrimSpace(name))
+	if !ok {
+		return nil
+	}
+	return &list
+}
+
+// CodeListNames returns the names of the DIAN code lists.
+func (s *Service) CodeListNames() []string {
+	return codelist.Names()
+}
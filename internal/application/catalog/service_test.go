@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/adapters/dane/embedded"
+	"3tcapital/goclonacion/internal/core/dane"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+var abejorral = dane.Municipality{Codigo: "05002", Nombre: "ABEJORRAL", DepCodigo: "05", DepNombre: "ANTIOQUIA"}
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	c, err := embedded.New()
+	if err != nil {
+		t.Fatalf("embedded catalog: %v", err)
+	}
+	return NewService(c, testutil.NewTestLogger())
+}
+
+func TestService_Load(t *testing.T) {
+	ctx := context.Background()
+
+	// An empty table keeps the embedded catalog
+	service := newTestService(t).WithRepository(testutil.NewMockDivipolaRepository())
+	if err := service.Load(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m, _ := service.GetMunicipality(ctx, "05001"); m == nil {
+		t.Error("expected embedded catalog to be kept")
+	}
+
+	service = newTestService(t).WithRepository(testutil.NewMockDivipolaRepository(abejorral))
+	if err := service.Load(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m, _ := service.GetMunicipality(ctx, "05002"); m == nil || m.Nombre != "ABEJORRAL" {
+		t.Errorf("expected catalog loaded from the table, got %+v", m)
+	}
+}
+
+func TestService_Refresh(t *testing.T) {
+	ctx := context.Background()
+	repo := testutil.NewMockDivipolaRepository()
+	service := newTestService(t).WithRepository(repo)
+
+	count, err := service.Refresh(ctx, testutil.NewMockDivipolaRepository(abejorral))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 municipality, got %d", count)
+	}
+	stored, _ := repo.ListMunicipalities(ctx)
+	if len(stored) != 1 {
+		t.Errorf("expected catalog to be stored, got %d", len(stored))
+	}
+	if m, _ := service.GetMunicipality(ctx, "05002"); m == nil {
+		t.Error("expected in-memory catalog to be replaced")
+	}
+
+	if _, err := newTestService(t).Refresh(ctx, repo); err == nil {
+		t.Error("expected error without database")
+	}
+}
+
+func TestService_SearchMunicipalities_Validation(t *testing.T) {
+	service := newTestService(t)
+
+	if _, err := service.SearchMunicipalities("", "5", 0); err == nil || !strings.Contains(err.Error(), "dep_codigo debe ser") {
+		t.Errorf("expected dep_codigo error, got %v", err)
+	}
+	if _, err := service.GetMunicipality(context.Background(), "5001"); err == nil || !strings.Contains(err.Error(), "mun_codigo debe ser") {
+		t.Errorf("expected mun_codigo error, got %v", err)
+	}
+}
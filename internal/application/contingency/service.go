@@ -0,0 +1,441 @@
+package contingency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"3tcapital/goclonacion/internal/core/contingency"
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// tracer records the spans of the backlog transmission.
+var tracer = otel.Tracer("3tcapital/goclonacion/internal/application/contingency")
+
+var (
+	// ErrInvalidType is returned when the contingency type is neither 03 nor 04.
+	ErrInvalidType = errors.New("tipo_contingencia debe ser 03 (facturador) o 04 (DIAN)")
+	// ErrInactive is returned when documents are stored while the mode is not active.
+	ErrInactive = errors.New("el modo de contingencia no está activo")
+	// ErrActive is returned when the backlog is transmitted while the mode is active.
+	ErrActive = errors.New("el modo de contingencia está activo; desactívelo para transmitir los documentos pendientes")
+	// ErrTransmission is returned when the provider cannot receive a pending document.
+	ErrTransmission = errors.New("el proveedor no pudo recibir el documento en contingencia")
+	// ErrTransmitting is returned when another instance is already transmitting the backlog.
+	ErrTransmitting = errors.New("los documentos en contingencia ya se están transmitiendo")
+)
+
+const (
+	defaultPrefix        = "CONT"
+	defaultCheckInterval = 30 * time.Second
+)
+
+// Service manages the contingency mode: it stores the documents issued while the
+// provider or DIAN are unavailable and transmits them in order once they recover.
+// The mode is persisted, so it is shared by every instance of the service, and only
+// one instance transmits the backlog at a time.
+type Service struct {
+	provider      invoice.Provider
+	repo          contingency.Repository
+	ledger        document.Repository // Optional: nil if the documents ledger is disabled
+	log           *slog.Logger
+	prefix        string
+	autoType      string        // Type of the mode activated automatically
+	threshold     time.Duration // Time the circuit must stay open to activate the mode (0 disables it)
+	checkInterval time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	state     contingency.State // Last state read from the repository
+	openSince time.Time         // Since when this instance sees the provider circuit open
+
+	wg sync.WaitGroup
+}
+
+// NewService creates a contingency service that transmits the stored documents through provider.
+func NewService(provider invoice.Provider, repo contingency.Repository, log *slog.Logger) *Service {
+	return &Service{
+		provider:      provider,
+		repo:          repo,
+		log:           log,
+		prefix:        defaultPrefix,
+		autoType:      contingency.TypeDIAN,
+		checkInterval: defaultCheckInterval,
+		now:           time.Now,
+	}
+}
+
+// WithPrefix sets the prefix of the contingency numbering.
+func (s *Service) WithPrefix(prefix string) *Service {
+	if prefix != "" {
+		s.prefix = prefix
+	}
+	return s
+}
+
+// WithAutoActivation activates the mode with the given type when the provider circuit
+// stays open for threshold, and deactivates it when the circuit closes.
+func (s *Service) WithAutoActivation(tipo string, threshold time.Duration) *Service {
+	if contingency.IsValidType(tipo) {
+		s.autoType = tipo
+	}
+	s.threshold = threshold
+	return s
+}
+
+// WithCheckInterval sets how often the provider circuit and the backlog are checked.
+func (s *Service) WithCheckInterval(d time.Duration) *Service {
+	if d > 0 {
+		s.checkInterval = d
+	}
+	return s
+}
+
+// WithLedger records the transmission result of the stored documents in the documents ledger.
+func (s *Service) WithLedger(ledger document.Repository) *Service {
+	s.ledger = ledger
+	return s
+}
+
+// Active reports whether the contingency mode is active.
+func (s *Service) Active(ctx context.Context) bool {
+	return s.State(ctx).Activa
+}
+
+// State returns the current contingency mode. If the repository cannot be read, the
+// last state read is returned.
+func (s *Service) State(ctx context.Context) contingency.State {
+	state, err := s.repo.LoadState(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.log.Error("Failed to load contingency state", "error", err)
+		return s.state
+	}
+	s.state = state
+	return state
+}
+
+// Activate turns the contingency mode on manually.
+func (s *Service) Activate(ctx context.Context, tipo, motivo string) (contingency.State, error) {
+	if !contingency.IsValidType(tipo) {
+		return contingency.State{}, ErrInvalidType
+	}
+	return s.activate(ctx, tipo, contingency.OriginManual, motivo)
+}
+
+// Deactivate turns the contingency mode off. The pending documents are transmitted on
+// the next check.
+func (s *Service) Deactivate(ctx context.Context) (contingency.State, error) {
+	previous := s.State(ctx)
+	if err := s.saveState(ctx, contingency.State{}); err != nil {
+		return previous, err
+	}
+	if previous.Activa {
+		s.log.Info("Contingency mode deactivated", "tipo", previous.Tipo, "origen", previous.Origen)
+	}
+	return contingency.State{}, nil
+}
+
+func (s *Service) activate(ctx context.Context, tipo string, origin contingency.Origin, motivo string) (contingency.State, error) {
+	since := s.now()
+	state := contingency.State{Activa: true, Tipo: tipo, Origen: origin, Motivo: motivo, Desde: &since}
+	if err := s.saveState(ctx, state); err != nil {
+		return contingency.State{}, err
+	}
+	s.log.Warn("Contingency mode activated", "tipo", tipo, "origen", origin, "motivo", motivo)
+	return state, nil
+}
+
+func (s *Service) saveState(ctx context.Context, state contingency.State) error {
+	if err := s.repo.SaveState(ctx, state); err != nil {
+		return fmt.Errorf("save contingency state: %w", err)
+	}
+	s.mu.Lock()
+	s.state = state
+	s.mu.Unlock()
+	return nil
+}
+
+// Store keeps the documents locally for later transmission, each with the next number of
+// its OFE contingency numbering. Invoices take the contingency type and reference the
+// contingency document. Documents that cannot be stored are returned as failed.
+func (s *Service) Store(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.ContingencyDocument, []invoice.FailedDocument, error) {
+	state := s.State(ctx)
+	if !state.Activa {
+		return nil, nil, ErrInactive
+	}
+
+	var stored []invoice.ContingencyDocument
+	var failed []invoice.FailedDocument
+	for _, doc := range documents {
+		numero, err := s.store(ctx, doc, documentType, state)
+		if err != nil {
+			s.log.Error("Failed to store contingency document",
+				"tipo", documentType, "prefijo", doc.RfaPrefijo, "consecutivo", doc.CdoConsecutivo, "error", err)
+			now := s.now()
+			failed = append(failed, invoice.FailedDocument{
+				Documento:          documentType,
+				Consecutivo:        doc.CdoConsecutivo,
+				Prefijo:            doc.RfaPrefijo,
+				Errors:             []string{fmt.Sprintf("no se pudo almacenar el documento en contingencia: %v", err)},
+				FechaProcesamiento: now.Format("2006-01-02"),
+				HoraProcesamiento:  now.Format("15:04:05"),
+			})
+			continue
+		}
+		stored = append(stored, invoice.ContingencyDocument{
+			RfaPrefijo:         doc.RfaPrefijo,
+			CdoConsecutivo:     doc.CdoConsecutivo,
+			NumeroContingencia: numero,
+			TipoContingencia:   state.Tipo,
+		})
+	}
+	return stored, failed, nil
+}
+
+func (s *Service) store(ctx context.Context, doc invoice.OpenETLDocument, documentType string, state contingency.State) (string, error) {
+	number, err := s.repo.NextNumber(ctx, doc.OfeIdentificacion)
+	if err != nil {
+		return "", err
+	}
+	numero := fmt.Sprintf("%s%d", s.prefix, number)
+
+	if documentType == "FC" {
+		fecha := doc.CdoFecha
+		if fecha == "" {
+			fecha = s.now().Format("2006-01-02")
+		}
+		doc.TdeCodigo = state.Tipo
+		doc.CdoDocumentoContingencia = &invoice.OpenETLDocumentoContingencia{
+			Numero: numero,
+			Fecha:  fecha,
+			Tipo:   contingency.ReferenceType,
+		}
+	}
+
+	_, err = s.repo.Save(ctx, contingency.Document{
+		OfeIdentificacion: doc.OfeIdentificacion,
+		Tipo:              documentType,
+		Prefijo:           doc.RfaPrefijo,
+		Consecutivo:       doc.CdoConsecutivo,
+		Numero:            numero,
+		TipoContingencia:  state.Tipo,
+		Payload:           doc,
+	})
+	return numero, err
+}
+
+// Start launches the job that activates and deactivates the mode from the provider
+// circuit and transmits the backlog once the provider recovers. Use Wait to block
+// until it stops.
+func (s *Service) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.checkInterval)
+		defer ticker.Stop()
+
+		for {
+			s.Check(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the contingency job has stopped.
+func (s *Service) Wait() {
+	s.wg.Wait()
+}
+
+// Check updates the mode from the provider circuit and, when the mode is off and the
+// circuit closed, transmits the pending documents.
+func (s *Service) Check(ctx context.Context) {
+	open := s.circuitOpen()
+	state := s.State(ctx)
+
+	s.mu.Lock()
+	now := s.now()
+	var openSince time.Time
+	if open {
+		if s.openSince.IsZero() {
+			s.openSince = now
+		}
+		openSince = s.openSince
+	} else {
+		s.openSince = time.Time{}
+	}
+	s.mu.Unlock()
+
+	var err error
+	switch {
+	case open && !state.Activa && s.threshold > 0 && now.Sub(openSince) >= s.threshold:
+		state, err = s.activate(ctx, s.autoType, contingency.OriginAutomatic,
+			fmt.Sprintf("el circuito del proveedor de facturación está abierto desde %s", openSince.Format(time.RFC3339)))
+	case !open && state.Activa && state.Origen == contingency.OriginAutomatic:
+		state, err = s.Deactivate(ctx)
+	}
+	if err != nil {
+		s.log.Error("Failed to update contingency mode", "error", err)
+		return
+	}
+
+	if state.Activa || open {
+		return
+	}
+	if err := s.Transmit(ctx); err != nil && ctx.Err() == nil && !errors.Is(err, ErrTransmitting) {
+		s.log.Warn("Contingency backlog transmission interrupted", "error", err)
+	}
+}
+
+// Transmit sends the pending documents to the provider in the order they were stored.
+// It stops at the first document the provider cannot receive, so the order is kept, and
+// returns ErrTransmitting if another instance is already transmitting them.
+func (s *Service) Transmit(ctx context.Context) error {
+	if s.Active(ctx) {
+		return ErrActive
+	}
+
+	unlock, locked, err := s.repo.LockTransmission(ctx)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return ErrTransmitting
+	}
+	defer unlock()
+
+	ctx, span := tracer.Start(ctx, "contingency.Transmit")
+	defer span.End()
+
+	pending, err := s.repo.ListPending(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("list pending contingency documents: %w", err)
+	}
+	span.SetAttributes(attribute.Int("contingency.pending", len(pending)))
+	if len(pending) > 0 {
+		s.log.Info("Transmitting contingency backlog", "pendientes", len(pending))
+	}
+
+	for _, doc := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.transmit(ctx, doc); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) transmit(ctx context.Context, doc contingency.Document) error {
+	req := invoice.DocumentRegistrationRequest{}
+	docs := []invoice.OpenETLDocument{doc.Payload}
+	switch doc.Tipo {
+	case "FC":
+		req.Documentos.FC = docs
+	case "NC":
+		req.Documentos.NC = docs
+	case "ND":
+		req.Documentos.ND = docs
+	case "DS":
+		req.Documentos.DS = docs
+	}
+
+	key := document.Key{
+		OfeIdentificacion: doc.OfeIdentificacion,
+		Tipo:              doc.Tipo,
+		Prefijo:           doc.Prefijo,
+		Consecutivo:       doc.Consecutivo,
+	}
+
+	resp, err := s.provider.RegisterDocument(ctx, req)
+	if err != nil {
+		if recordErr := s.repo.RecordAttempt(ctx, doc.ID, err.Error()); recordErr != nil {
+			s.log.Error("Failed to record contingency transmission attempt", "numero", doc.Numero, "error", recordErr)
+		}
+		return fmt.Errorf("%w %s: %v", ErrTransmission, doc.Numero, err)
+	}
+
+	if len(resp.DocumentosProcesados) > 0 {
+		processed := resp.DocumentosProcesados[0]
+		if err := s.repo.MarkTransmitted(ctx, doc.ID, processed.CUFE); err != nil {
+			return err
+		}
+		update := document.StatusUpdate{
+			Estado:    document.StatusAccepted,
+			CUFE:      processed.CUFE,
+			XmlBase64: processed.XmlBase64,
+			PdfBase64: processed.PdfBase64,
+		}
+		if processed.CdoID != 0 {
+			cdoID := processed.CdoID
+			update.CdoID = &cdoID
+		}
+		if payload, err := json.Marshal(doc.Payload); err == nil {
+			update.EnrichedPayload = payload
+		}
+		s.updateLedger(ctx, key, update)
+		s.log.Info("Contingency document transmitted", "numero", doc.Numero, "prefijo", doc.Prefijo, "consecutivo", doc.Consecutivo)
+		return nil
+	}
+
+	errs := []string{"el proveedor no procesó el documento"}
+	if len(resp.DocumentosFallidos) > 0 && len(resp.DocumentosFallidos[0].Errors) > 0 {
+		errs = resp.DocumentosFallidos[0].Errors
+	}
+	if err := s.repo.MarkFailed(ctx, doc.ID, errs); err != nil {
+		return err
+	}
+	s.updateLedger(ctx, key, document.StatusUpdate{Estado: document.StatusRejected, Errors: errs})
+	s.log.Warn("Contingency document rejected", "numero", doc.Numero, "prefijo", doc.Prefijo, "consecutivo", doc.Consecutivo, "errors", errs)
+	return nil
+}
+
+func (s *Service) updateLedger(ctx context.Context, key document.Key, update document.StatusUpdate) {
+	if s.ledger == nil {
+		return
+	}
+	if err := s.ledger.UpdateStatus(ctx, key, update); err != nil {
+		s.log.Error("Failed to update document ledger",
+			"ofe", key.OfeIdentificacion, "tipo", key.Tipo, "prefijo", key.Prefijo, "consecutivo", key.Consecutivo, "error", err)
+	}
+}
+
+// Dashboard returns the contingency mode with the counts of stored documents.
+func (s *Service) Dashboard(ctx context.Context) (*contingency.Dashboard, error) {
+	summary, err := s.repo.Summary(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("contingency summary: %w", err)
+	}
+	return &contingency.Dashboard{
+		Estado:          s.State(ctx),
+		CircuitoAbierto: s.circuitOpen(),
+		Documentos:      *summary,
+	}, nil
+}
+
+// circuitOpen reports whether the provider circuit breaker is failing fast.
+func (s *Service) circuitOpen() bool {
+	reporter, ok := s.provider.(invoice.CircuitReporter)
+	return ok && reporter.CircuitOpen()
+}
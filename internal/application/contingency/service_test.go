@@ -0,0 +1,243 @@
+package contingency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/contingency"
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+// circuitProvider is a provider whose circuit state is set by the test.
+type circuitProvider struct {
+	*testutil.MockProvider
+	open bool
+}
+
+func (p *circuitProvider) CircuitOpen() bool { return p.open }
+
+func newTestDocument(consecutivo string) invoice.OpenETLDocument {
+	return invoice.OpenETLDocument{
+		TdeCodigo:         "01",
+		OfeIdentificacion: "860011153",
+		RfaPrefijo:        "SETT",
+		CdoConsecutivo:    consecutivo,
+		CdoFecha:          "2024-03-15",
+	}
+}
+
+func newTestService(provider invoice.Provider) (*Service, *testutil.MockContingencyRepository) {
+	repo := testutil.NewMockContingencyRepository()
+	return NewService(provider, repo, testutil.NewNullLogger()), repo
+}
+
+func TestService_Activate(t *testing.T) {
+	service, _ := newTestService(&testutil.MockProvider{})
+
+	if _, err := service.Activate(context.Background(), "01", "caída"); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("expected ErrInvalidType, got %v", err)
+	}
+
+	state, err := service.Activate(context.Background(), contingency.TypeIssuer, "caída del proveedor")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !state.Activa || state.Origen != contingency.OriginManual || state.Desde == nil || !service.Active(context.Background()) {
+		t.Errorf("expected the mode active manually, got %+v", state)
+	}
+
+	if state, err := service.Deactivate(context.Background()); err != nil || state.Activa || service.Active(context.Background()) {
+		t.Errorf("expected the mode inactive, got %+v", state)
+	}
+}
+
+func TestService_Store(t *testing.T) {
+	service, repo := newTestService(&testutil.MockProvider{})
+	service.WithPrefix("CT")
+
+	if _, _, err := service.Store(context.Background(), []invoice.OpenETLDocument{newTestDocument("1")}, "FC"); !errors.Is(err, ErrInactive) {
+		t.Fatalf("expected ErrInactive, got %v", err)
+	}
+
+	service.Activate(context.Background(), contingency.TypeDIAN, "DIAN no disponible")
+	stored, failed, err := service.Store(context.Background(), []invoice.OpenETLDocument{newTestDocument("1"), newTestDocument("2")}, "FC")
+	if err != nil || len(failed) > 0 {
+		t.Fatalf("unexpected error %v %+v", err, failed)
+	}
+	if len(stored) != 2 || stored[0].NumeroContingencia != "CT1" || stored[1].NumeroContingencia != "CT2" || stored[1].TipoContingencia != "04" {
+		t.Errorf("unexpected stored documents %+v", stored)
+	}
+
+	docs := repo.Documents()
+	payload := docs[0].Payload
+	if payload.TdeCodigo != "04" {
+		t.Errorf("expected invoice type 04, got %s", payload.TdeCodigo)
+	}
+	ref := payload.CdoDocumentoContingencia
+	if ref == nil || ref.Numero != "CT1" || ref.Fecha != "2024-03-15" || ref.Tipo != contingency.ReferenceType {
+		t.Errorf("unexpected contingency reference %+v", ref)
+	}
+
+	repo.SaveErr = errors.New("database down")
+	_, failed, _ = service.Store(context.Background(), []invoice.OpenETLDocument{newTestDocument("3")}, "FC")
+	if len(failed) != 1 || failed[0].Consecutivo != "3" {
+		t.Errorf("expected document 3 to fail, got %+v", failed)
+	}
+}
+
+func TestService_Check_AutomaticActivation(t *testing.T) {
+	provider := &circuitProvider{MockProvider: &testutil.MockProvider{}, open: true}
+	service, _ := newTestService(provider)
+	service.WithAutoActivation(contingency.TypeIssuer, time.Minute)
+
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	service.Check(context.Background())
+	if service.Active(context.Background()) {
+		t.Fatal("expected the mode inactive before the threshold")
+	}
+
+	now = now.Add(time.Minute)
+	service.Check(context.Background())
+	state := service.State(context.Background())
+	if !state.Activa || state.Origen != contingency.OriginAutomatic || state.Tipo != "03" {
+		t.Fatalf("expected the mode active automatically, got %+v", state)
+	}
+
+	provider.open = false
+	service.Check(context.Background())
+	if service.Active(context.Background()) {
+		t.Error("expected the mode deactivated when the circuit closes")
+	}
+}
+
+func TestService_Check_KeepsManualActivation(t *testing.T) {
+	service, _ := newTestService(&circuitProvider{MockProvider: &testutil.MockProvider{}})
+	service.WithAutoActivation(contingency.TypeDIAN, time.Minute)
+	service.Activate(context.Background(), contingency.TypeDIAN, "mantenimiento DIAN")
+
+	service.Check(context.Background())
+	if !service.Active(context.Background()) {
+		t.Error("expected the manual mode to stay active with the circuit closed")
+	}
+}
+
+func TestService_Transmit(t *testing.T) {
+	var sent []string
+	provider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			doc := req.Documentos.FC[0]
+			sent = append(sent, doc.CdoConsecutivo)
+			switch doc.CdoConsecutivo {
+			case "2":
+				return &invoice.DocumentRegistrationResponse{
+					DocumentosFallidos: []invoice.FailedDocument{{Prefijo: "SETT", Consecutivo: "2", Errors: []string{"FAD06: CUFE inválido"}}},
+				}, nil
+			case "3":
+				return nil, errors.New("numrot unavailable")
+			}
+			return &invoice.DocumentRegistrationResponse{
+				DocumentosProcesados: []invoice.ProcessedDocument{{RfaPrefijo: "SETT", CdoConsecutivo: doc.CdoConsecutivo, CUFE: "cufe-" + doc.CdoConsecutivo}},
+			}, nil
+		},
+	}
+	service, repo := newTestService(provider)
+	ledger := testutil.NewMockDocumentRepository()
+	service.WithLedger(ledger)
+
+	service.Activate(context.Background(), contingency.TypeDIAN, "DIAN no disponible")
+	var docs []invoice.OpenETLDocument
+	for _, consecutivo := range []string{"1", "2", "3", "4"} {
+		docs = append(docs, newTestDocument(consecutivo))
+		ledger.Save(context.Background(), document.Document{
+			OfeIdentificacion: "860011153", Tipo: "FC", Prefijo: "SETT", Consecutivo: consecutivo, Estado: document.StatusContingency,
+		})
+	}
+	service.Store(context.Background(), docs, "FC")
+
+	if err := service.Transmit(context.Background()); !errors.Is(err, ErrActive) {
+		t.Fatalf("expected ErrActive while the mode is active, got %v", err)
+	}
+
+	service.Deactivate(context.Background())
+	if err := service.Transmit(context.Background()); err == nil {
+		t.Fatal("expected the transmission to stop at the provider error")
+	}
+	if len(sent) != 3 || sent[2] != "3" {
+		t.Fatalf("expected the backlog sent in order up to document 3, got %v", sent)
+	}
+
+	stored := repo.Documents()
+	if stored[0].Estado != contingency.StatusTransmitted || stored[0].CUFE != "cufe-1" {
+		t.Errorf("expected document 1 transmitted, got %+v", stored[0])
+	}
+	if stored[1].Estado != contingency.StatusFailed || stored[1].Errors[0] != "FAD06: CUFE inválido" {
+		t.Errorf("expected document 2 failed, got %+v", stored[1])
+	}
+	if stored[2].Estado != contingency.StatusPending || stored[2].Intentos != 1 || stored[3].Estado != contingency.StatusPending {
+		t.Errorf("expected documents 3 and 4 pending, got %+v %+v", stored[2], stored[3])
+	}
+
+	entry, _ := ledger.FindByKey(context.Background(), document.Key{OfeIdentificacion: "860011153", Tipo: "FC", Prefijo: "SETT", Consecutivo: "1"})
+	if entry == nil || entry.Estado != document.StatusAccepted || entry.CUFE != "cufe-1" {
+		t.Errorf("expected document 1 accepted in the ledger, got %+v", entry)
+	}
+	entry, _ = ledger.FindByKey(context.Background(), document.Key{OfeIdentificacion: "860011153", Tipo: "FC", Prefijo: "SETT", Consecutivo: "2"})
+	if entry == nil || entry.Estado != document.StatusRejected {
+		t.Errorf("expected document 2 rejected in the ledger, got %+v", entry)
+	}
+
+	summary, err := service.Dashboard(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Documentos.Pendientes != 2 || summary.Documentos.Transmitidos != 1 || summary.Documentos.Fallidos != 1 || summary.Documentos.PendientesPorOfe["860011153"] != 2 {
+		t.Errorf("unexpected summary %+v", summary.Documentos)
+	}
+}
+
+func TestService_StateSharedBetweenInstances(t *testing.T) {
+	repo := testutil.NewMockContingencyRepository()
+	first := NewService(&testutil.MockProvider{}, repo, testutil.NewNullLogger())
+	second := NewService(&testutil.MockProvider{}, repo, testutil.NewNullLogger())
+
+	if _, err := first.Activate(context.Background(), contingency.TypeIssuer, "caída del proveedor"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state := second.State(context.Background()); !state.Activa || state.Tipo != contingency.TypeIssuer {
+		t.Fatalf("expected the mode activated by another instance, got %+v", state)
+	}
+
+	if _, _, err := second.Store(context.Background(), []invoice.OpenETLDocument{newTestDocument("1")}, "FC"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := second.Deactivate(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Active(context.Background()) {
+		t.Error("expected the mode deactivated by another instance")
+	}
+}
+
+func TestService_Transmit_SingleInstance(t *testing.T) {
+	service, repo := newTestService(&testutil.MockProvider{})
+
+	unlock, locked, err := repo.LockTransmission(context.Background())
+	if err != nil || !locked {
+		t.Fatalf("unexpected lock result: %v %v", locked, err)
+	}
+	if err := service.Transmit(context.Background()); !errors.Is(err, ErrTransmitting) {
+		t.Errorf("expected ErrTransmitting while another instance transmits, got %v", err)
+	}
+
+	unlock()
+	if err := service.Transmit(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
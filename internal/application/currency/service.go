@@ -0,0 +1,76 @@
+// Package currency manages the exchange rates used to convert foreign-currency documents to COP.
+package currency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/currency"
+)
+
+// defaultListDays is the period listed when no date range is given.
+const defaultListDays = 30
+
+// Service orchestrates the exchange rate table.
+type Service struct {
+	repo currency.Repository
+}
+
+// NewService creates a new exchange rate service with the given repository.
+func NewService(repo currency.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListRates returns the rates of a currency between from and to (inclusive). A zero to
+// means today and a zero from means defaultListDays before to.
+func (s *Service) ListRates(ctx context.Context, code string, from, to time.Time) ([]currency.Rate, error) {
+	code, err := foreignCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -defaultListDays)
+	}
+	if from.After(to) {
+		return nil, fmt.Errorf("desde debe ser anterior o igual a hasta")
+	}
+
+	rates, err := s.repo.List(ctx, code, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("list exchange rates: %w", err)
+	}
+	if rates == nil {
+		rates = []currency.Rate{}
+	}
+	return rates, nil
+}
+
+// SetRate creates or replaces the rate of a currency on a date.
+func (s *Service) SetRate(ctx context.Context, code string, date time.Time, value string) error {
+	code, err := foreignCode(code)
+	if err != nil {
+		return err
+	}
+	rate, err := currency.ParseRate(value)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Save(ctx, currency.Rate{Currency: code, Date: date, Value: rate.FloatString(6)})
+}
+
+// foreignCode normalizes a currency code and checks that it is a valid foreign currency.
+func foreignCode(code string) (string, error) {
+	code = currency.Normalize(code)
+	if !currency.Valid(code) {
+		return "", fmt.Errorf("moneda inválida [%s]: debe ser un código ISO 4217", code)
+	}
+	if code == currency.COP {
+		return "", fmt.Errorf("moneda inválida [%s]: la moneda nacional no requiere tasa de cambio", code)
+	}
+	return code, nil
+}
@@ -0,0 +1,77 @@
+package currency
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func TestService_SetRate(t *testing.T) {
+	repo := testutil.NewMockExchangeRateRepository()
+	service := NewService(repo)
+	date := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	if err := service.SetRate(context.Background(), "usd", date, "3950.25"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rate, err := repo.Rate(context.Background(), "USD", date)
+	if err != nil || rate == nil {
+		t.Fatalf("expected stored rate, got %v (%v)", rate, err)
+	}
+	if rate.Value != "3950.250000" {
+		t.Errorf("expected value 3950.250000, got %s", rate.Value)
+	}
+}
+
+func TestService_SetRate_Validation(t *testing.T) {
+	service := NewService(testutil.NewMockExchangeRateRepository())
+	date := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		code    string
+		value   string
+		wantErr string
+	}{
+		{name: "unknown currency", code: "ABC", value: "1", wantErr: "ISO 4217"},
+		{name: "national currency", code: "COP", value: "1", wantErr: "moneda nacional"},
+		{name: "zero rate", code: "USD", value: "0", wantErr: "mayor que 0"},
+		{name: "not a number", code: "EUR", value: "abc", wantErr: "tasa de cambio inválida"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.SetRate(context.Background(), tt.code, date, tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestService_ListRates(t *testing.T) {
+	repo := testutil.NewMockExchangeRateRepository()
+	service := NewService(repo)
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, value := range []string{"3900", "3910", "3920"} {
+		if err := service.SetRate(context.Background(), "USD", day.AddDate(0, 0, i), value); err != nil {
+			t.Fatalf("set rate: %v", err)
+		}
+	}
+
+	rates, err := service.ListRates(context.Background(), "USD", day.AddDate(0, 0, 1), day.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected 2 rates, got %d", len(rates))
+	}
+
+	if _, err := service.ListRates(context.Background(), "USD", day.AddDate(0, 0, 2), day); err == nil {
+		t.Error("expected error when desde is after hasta")
+	}
+}
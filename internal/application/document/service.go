@@ -0,0 +1,96 @@
+package document
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/identification"
+)
+
+// validDocumentTypes lists the document types tracked by the ledger.
+var validDocumentTypes = map[string]bool{
+	"FC": true,
+	"NC": true,
+	"ND": true,
+	"DS": true,
+}
+
+// Service orchestrates queries over the documents ledger.
+type Service struct {
+	repo document.Repository
+}
+
+// NewService creates a new documents ledger service with the given repository.
+func NewService(repo document.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// ListDocumentsResponse represents the response from listing ledger entries.
+type ListDocumentsResponse struct {
+	Total int                 `json:"total"`
+	Data  []document.Document `json:"data"`
+}
+
+// GetDocument retrieves a ledger entry by OFE, type, prefijo and consecutivo. The OFE
+// is looked up without DV, as the ledger stores it.
+func (s *Service) GetDocument(ctx context.Context, ofeIdentificacion, tipo, prefijo, consecutivo string) (*document.Document, error) {
+	if identification.Base(ofeIdentificacion) == "" {
+		return nil, fmt.Errorf("ofe_identificacion es requerido")
+	}
+	tipo = strings.ToUpper(strings.TrimSpace(tipo))
+	if !validDocumentTypes[tipo] {
+		return nil, fmt.Errorf("tipo [%s] inválido, debe ser FC, NC, ND o DS", tipo)
+	}
+	if strings.TrimSpace(consecutivo) == "" {
+		return nil, fmt.Errorf("consecutivo es requerido")
+	}
+
+	doc, err := s.repo.FindByKey(ctx, document.Key{
+		OfeIdentificacion: identification.Base(ofeIdentificacion),
+		Tipo:              tipo,
+		Prefijo:           strings.TrimSpace(prefijo),
+		Consecutivo:       strings.TrimSpace(consecutivo),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find document: %w", err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("el documento %s %s%s no existe para el OFE [%s]", tipo, prefijo, consecutivo, ofeIdentificacion)
+	}
+
+	return doc, nil
+}
+
+// ListDocuments lists ledger entries matching the filter.
+func (s *Service) ListDocuments(ctx context.Context, filter document.Filter) (*ListDocumentsResponse, error) {
+	filter.OfeIdentificacion = identification.Base(filter.OfeIdentificacion)
+	if filter.Tipo != "" {
+		filter.Tipo = strings.ToUpper(filter.Tipo)
+		if !validDocumentTypes[filter.Tipo] {
+			return nil, fmt.Errorf("tipo [%s] inválido, debe ser FC, NC, ND o DS", filter.Tipo)
+		}
+	}
+	if filter.Estado != "" && !filter.Estado.IsValid() {
+		return nil, fmt.Errorf("estado [%s] inválido", filter.Estado)
+	}
+	if filter.Start < 0 {
+		filter.Start = 0
+	}
+	if filter.Length == 0 {
+		filter.Length = 10
+	}
+
+	docs, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list documents: %w", err)
+	}
+
+	return &ListDocumentsResponse{
+		Total: total,
+		Data:  docs,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package document
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func seedLedger(t *testing.T) *testutil.MockDocumentRepository {
+	t.Helper()
+	repo := testutil.NewMockDocumentRepository()
+	docs := []document.Document{
+		{OfeIdentificacion: "860011153", Tipo: "FC", Prefijo: "SETT", Consecutivo: "1", Estado: document.StatusAccepted, CUFE: "abc"},
+		{OfeIdentificacion: "860011153", Tipo: "NC", Prefijo: "NC", Consecutivo: "1", Estado: document.StatusRejected},
+	}
+	for _, doc := range docs {
+		if _, err := repo.Save(context.Background(), doc); err != nil {
+			t.Fatalf("seed ledger: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestService_GetDocument(t *testing.T) {
+	service := NewService(seedLedger(t))
+
+	tests := []struct {
+		name        string
+		ofe         string
+		tipo        string
+		prefijo     string
+		consecutivo string
+		expectedErr string
+	}{
+		{name: "found", ofe: "860011153", tipo: "fc", prefijo: "SETT", consecutivo: "1"},
+		{name: "found with DV", ofe: " 860.011.153-6 ", tipo: "FC", prefijo: "SETT", consecutivo: "1"},
+		{name: "not found", ofe: "860011153", tipo: "FC", prefijo: "SETT", consecutivo: "2", expectedErr: "no existe"},
+		{name: "missing ofe", tipo: "FC", consecutivo: "1", expectedErr: "ofe_identificacion es requerido"},
+		{name: "invalid tipo", ofe: "860011153", tipo: "XX", consecutivo: "1", expectedErr: "inválido"},
+		{name: "missing consecutivo", ofe: "860011153", tipo: "FC", expectedErr: "consecutivo es requerido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := service.GetDocument(context.Background(), tt.ofe, tt.tipo, tt.prefijo, tt.consecutivo)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if doc.CUFE != "abc" {
+				t.Errorf("expected CUFE abc, got %q", doc.CUFE)
+			}
+		})
+	}
+}
+
+func TestService_ListDocuments(t *testing.T) {
+	service := NewService(seedLedger(t))
+
+	response, err := service.ListDocuments(context.Background(), document.Filter{Estado: document.StatusRejected})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Total != 1 || len(response.Data) != 1 || response.Data[0].Tipo != "NC" {
+		t.Errorf("expected only the rejected NC, got %+v", response)
+	}
+
+	response, err = service.ListDocuments(context.Background(), document.Filter{OfeIdentificacion: "860011153-6"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Total != 2 {
+		t.Errorf("expected the documents of the OFE without DV, got %+v", response)
+	}
+
+	if _, err := service.ListDocuments(context.Background(), document.Filter{Estado: "unknown"}); err == nil {
+		t.Error("expected error for invalid estado")
+	}
+	if _, err := service.ListDocuments(context.Background(), document.Filter{Tipo: "XX"}); err == nil {
+		t.Error("expected error for invalid tipo")
+	}
+}
@@ -4,15 +4,18 @@ import (
 	"context"
 	"fmt"
 
-	"3tcapital/ms_facturacion_core/internal/core/event"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/ofe"
 )
 
 // Service orchestrates event registration use cases.
 type Service struct {
-	provider   invoice.Provider
-	emisorNit  string
+	provider    invoice.Provider
+	emisorNit   string
 	razonSocial string
+	ofeRepo     ofe.Repository // Optional: nil if the OFE registry is disabled
 }
 
 // NewService creates a new event service with the given invoice provider.
@@ -24,6 +27,13 @@ func NewService(provider invoice.Provider, emisorNit, razonSocial string) *Servi
 	}
 }
 
+// WithOFERegistry makes the service take the emisor razón social from the OFE registry,
+// falling back to the configured one when the emisor is not registered.
+func (s *Service) WithOFERegistry(repo ofe.Repository) *Service {
+	s.ofeRepo = repo
+	return s
+}
+
 // RegisterEvent registers a Radian event for a document.
 func (s *Service) RegisterEvent(ctx context.Context, evt event.Event) (*invoice.EventRegistrationResult, error) {
 	// Validate event
@@ -36,15 +46,39 @@ func (s *Service) RegisterEvent(ctx context.Context, evt event.Event) (*invoice.
 		return nil, fmt.Errorf("emisor nit is not configured")
 	}
 
-	if s.razonSocial == "" {
+	razonSocial, err := s.emisorRazonSocial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if razonSocial == "" {
 		return nil, fmt.Errorf("razon social is not configured")
 	}
 
 	// Register event through provider
-	result, err := s.provider.RegisterEvent(ctx, evt, s.emisorNit, s.razonSocial)
+	result, err := s.provider.RegisterEvent(ctx, evt, s.emisorNit, razonSocial)
 	if err != nil {
 		return nil, fmt.Errorf("provider error: %w", err)
 	}
 
 	return result, nil
 }
+
+// emisorRazonSocial returns the razón social of the emisor, preferring the OFE registry.
+func (s *Service) emisorRazonSocial(ctx context.Context) (string, error) {
+	if s.ofeRepo == nil {
+		return s.razonSocial, nil
+	}
+
+	nit := identification.Base(s.emisorNit)
+	o, err := s.ofeRepo.FindByIdentificacion(ctx, nit)
+	if err != nil {
+		return "", fmt.Errorf("Error al buscar OFE: %w", err)
+	}
+	if o == nil {
+		return s.razonSocial, nil
+	}
+	if !o.IsActive() {
+		return "", fmt.Errorf("OFE [%s] inactivo", nit)
+	}
+	return o.OfeRazonSocial, nil
+}
@@ -6,9 +6,10 @@ import (
 	"testing"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/event"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/ofe"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 func TestNewService(t *testing.T) {
@@ -304,3 +305,39 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestService_RegisterEvent_OFERegistry(t *testing.T) {
+	var gotRazonSocial string
+	mockProvider := &testutil.MockProvider{
+		RegisterEventFunc: func(ctx context.Context, evt event.Event, emisorNit, razonSocial string) (*invoice.EventRegistrationResult, error) {
+			gotRazonSocial = razonSocial
+			return &invoice.EventRegistrationResult{Code: "1000"}, nil
+		},
+	}
+	evt := event.Event{
+		EventType:               event.EventTypeAcuse,
+		DocumentNumber:          "FAC12345",
+		NombreGenerador:         "Mauricio",
+		ApellidoGenerador:       "Alemán",
+		IdentificacionGenerador: "1061239585",
+		EventGenerationDate:     time.Now(),
+	}
+
+	registry := testutil.NewMockOFERepository(ofe.OFE{OfeIdentificacion: "860011153", OfeRazonSocial: "RAZON SOCIAL DEL REGISTRO", Estado: ofe.EstadoActivo})
+	service := NewService(mockProvider, "860011153-6", "RAZON SOCIAL DEL ENV").WithOFERegistry(registry)
+	if _, err := service.RegisterEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotRazonSocial != "RAZON SOCIAL DEL REGISTRO" {
+		t.Errorf("expected registry razon social, got %q", gotRazonSocial)
+	}
+
+	// Unregistered emisor falls back to the configured razon social
+	service = NewService(mockProvider, "900000001", "RAZON SOCIAL DEL ENV").WithOFERegistry(registry)
+	if _, err := service.RegisterEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotRazonSocial != "RAZON SOCIAL DEL ENV" {
+		t.Errorf("expected configured razon social, got %q", gotRazonSocial)
+	}
+}
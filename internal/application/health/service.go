@@ -4,7 +4,7 @@ import (
 	"context"
 	"time"
 
-	corehealth "3tcapital/ms_facturacion_core/internal/core/health"
+	corehealth "3tcapital/goclonacion/internal/core/health"
 )
 
 // Metadata contains immutable metadata about the running service.
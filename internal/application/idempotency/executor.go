@@ -0,0 +1,115 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/idempotency"
+)
+
+var (
+	// ErrKeyReused is returned when an Idempotency-Key is sent again with a different payload.
+	ErrKeyReused = errors.New("la Idempotency-Key ya fue utilizada con un contenido diferente")
+	// ErrInProgress is returned when the first request with the same Idempotency-Key has not finished.
+	ErrInProgress = errors.New("una solicitud con la misma Idempotency-Key se encuentra en proceso")
+	// ErrKeyTooLong is returned when the Idempotency-Key exceeds MaxKeyLength.
+	ErrKeyTooLong = fmt.Errorf("la Idempotency-Key no debe superar %d caracteres", MaxKeyLength)
+)
+
+// MaxKeyLength is the maximum accepted length of an Idempotency-Key.
+const MaxKeyLength = 255
+
+// Lease is how long a reservation stays valid without renewal. It is renewed every third
+// of the lease while the request runs, so only an abandoned reservation (e.g. the process
+// crashed mid-request) expires and can be taken over by a retry.
+const Lease = 2 * time.Minute
+
+// Hash returns the SHA-256 hex digest of the JSON encoding of v.
+func Hash(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("marshal payload: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Execute runs fn at most once per scope and key. A retry with the same key and payload gets
+// the stored result back (replayed=true); a retry with a different payload gets ErrKeyReused.
+// If fn fails, the reservation is released so the request can be retried.
+// A retry while the first request is still running gets ErrInProgress.
+func Execute[T any](ctx context.Context, repo idempotency.Repository, scope, key string, request any, fn func(ctx context.Context) (T, error)) (result T, replayed bool, err error) {
+	if len(key) > MaxKeyLength {
+		return result, false, ErrKeyTooLong
+	}
+
+	requestHash, err := Hash(request)
+	if err != nil {
+		return result, false, err
+	}
+
+	existing, reserved, err := repo.Reserve(ctx, scope, key, requestHash, Lease)
+	if err != nil {
+		return result, false, err
+	}
+
+	if !reserved {
+		if existing.RequestHash != requestHash {
+			return result, false, ErrKeyReused
+		}
+		if existing.Status != idempotency.StatusCompleted {
+			return result, false, ErrInProgress
+		}
+		if err := json.Unmarshal(existing.Response, &result); err != nil {
+			return result, false, fmt.Errorf("unmarshal stored response: %w", err)
+		}
+		return result, true, nil
+	}
+
+	stop := keepLease(ctx, repo, scope, key)
+	result, err = fn(ctx)
+	stop()
+	if err != nil {
+		_ = repo.Release(context.WithoutCancel(ctx), scope, key)
+		return result, false, err
+	}
+
+	response, err := json.Marshal(result)
+	if err == nil {
+		err = repo.Complete(context.WithoutCancel(ctx), scope, key, response)
+	}
+	if err != nil {
+		// The result is valid even if it could not be stored; free the key for future retries
+		_ = repo.Release(context.WithoutCancel(ctx), scope, key)
+	}
+
+	return result, false, nil
+}
+
+// keepLease renews the reservation until the returned function is called.
+func keepLease(ctx context.Context, repo idempotency.Repository, scope, key string) func() {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(Lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = repo.Extend(ctx, scope, key, Lease)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
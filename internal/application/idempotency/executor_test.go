@@ -0,0 +1,163 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/idempotency"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+type testResult struct {
+	Value int `json:"value"`
+}
+
+func TestExecute_ReplaysStoredResult(t *testing.T) {
+	repo := testutil.NewMockIdempotencyRepository()
+	calls := 0
+	fn := func(ctx context.Context) (testResult, error) {
+		calls++
+		return testResult{Value: 42}, nil
+	}
+
+	first, replayed, err := Execute(context.Background(), repo, "scope", "key-1", map[string]string{"a": "1"}, fn)
+	if err != nil || replayed || first.Value != 42 {
+		t.Fatalf("unexpected first result: %+v replayed=%v err=%v", first, replayed, err)
+	}
+
+	second, replayed, err := Execute(context.Background(), repo, "scope", "key-1", map[string]string{"a": "1"}, fn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !replayed || second.Value != 42 {
+		t.Errorf("expected replayed result, got %+v replayed=%v", second, replayed)
+	}
+	if calls != 1 {
+		t.Errorf("expected fn to run once, ran %d times", calls)
+	}
+}
+
+func TestExecute_KeyReusedWithDifferentPayload(t *testing.T) {
+	repo := testutil.NewMockIdempotencyRepository()
+	fn := func(ctx context.Context) (testResult, error) { return testResult{Value: 1}, nil }
+
+	if _, _, err := Execute(context.Background(), repo, "scope", "key-1", "payload-a", fn); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _, err := Execute(context.Background(), repo, "scope", "key-1", "payload-b", fn)
+	if !errors.Is(err, ErrKeyReused) {
+		t.Errorf("expected ErrKeyReused, got %v", err)
+	}
+
+	// The same key in another scope is independent
+	if _, replayed, err := Execute(context.Background(), repo, "other", "key-1", "payload-b", fn); err != nil || replayed {
+		t.Errorf("expected new execution in another scope, replayed=%v err=%v", replayed, err)
+	}
+}
+
+func TestExecute_InProgress(t *testing.T) {
+	repo := testutil.NewMockIdempotencyRepository()
+	hash, _ := Hash("payload")
+	if _, _, err := repo.Reserve(context.Background(), "scope", "key-1", hash, Lease); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, _, err := Execute(context.Background(), repo, "scope", "key-1", "payload", func(ctx context.Context) (testResult, error) {
+		t.Fatal("fn must not run while the key is in progress")
+		return testResult{}, nil
+	})
+	if !errors.Is(err, ErrInProgress) {
+		t.Errorf("expected ErrInProgress, got %v", err)
+	}
+}
+
+func TestExecute_TakesOverExpiredReservation(t *testing.T) {
+	repo := testutil.NewMockIdempotencyRepository()
+	hash, _ := Hash("payload")
+	// A reservation left behind by a request that never finished
+	if _, _, err := repo.Reserve(context.Background(), "scope", "key-1", hash, -time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, replayed, err := Execute(context.Background(), repo, "scope", "key-1", "payload", func(ctx context.Context) (testResult, error) {
+		return testResult{Value: 9}, nil
+	})
+	if err != nil || replayed || result.Value != 9 {
+		t.Fatalf("expected the expired reservation to be taken over, got %+v replayed=%v err=%v", result, replayed, err)
+	}
+	if record := repo.Get("scope", "key-1"); record == nil || record.Status != idempotency.StatusCompleted {
+		t.Errorf("expected completed record, got %+v", record)
+	}
+}
+
+func TestExecute_ExpiredReservationWithDifferentPayload(t *testing.T) {
+	repo := testutil.NewMockIdempotencyRepository()
+	hash, _ := Hash("payload-a")
+	if _, _, err := repo.Reserve(context.Background(), "scope", "key-1", hash, -time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, _, err := Execute(context.Background(), repo, "scope", "key-1", "payload-b", func(ctx context.Context) (testResult, error) {
+		t.Fatal("fn must not run for a key reused with another payload")
+		return testResult{}, nil
+	})
+	if !errors.Is(err, ErrKeyReused) {
+		t.Errorf("expected ErrKeyReused, got %v", err)
+	}
+	if record := repo.Get("scope", "key-1"); record == nil || record.RequestHash != hash {
+		t.Errorf("expected the reservation of the first request kept, got %+v", record)
+	}
+}
+
+func TestExecute_ReleasesKeyOnError(t *testing.T) {
+	repo := testutil.NewMockIdempotencyRepository()
+	fnErr := errors.New("provider unavailable")
+
+	_, _, err := Execute(context.Background(), repo, "scope", "key-1", "payload", func(ctx context.Context) (testResult, error) {
+		return testResult{}, fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+	if repo.Get("scope", "key-1") != nil {
+		t.Fatal("expected reservation to be released")
+	}
+
+	result, replayed, err := Execute(context.Background(), repo, "scope", "key-1", "payload", func(ctx context.Context) (testResult, error) {
+		return testResult{Value: 7}, nil
+	})
+	if err != nil || replayed || result.Value != 7 {
+		t.Errorf("expected retry to run, got %+v replayed=%v err=%v", result, replayed, err)
+	}
+	if record := repo.Get("scope", "key-1"); record == nil || record.Status != idempotency.StatusCompleted {
+		t.Errorf("expected completed record, got %+v", record)
+	}
+}
+
+func TestExecute_CompleteFailureReturnsResult(t *testing.T) {
+	repo := testutil.NewMockIdempotencyRepository()
+	repo.CompleteErr = errors.New("db down")
+
+	result, _, err := Execute(context.Background(), repo, "scope", "key-1", "payload", func(ctx context.Context) (testResult, error) {
+		return testResult{Value: 3}, nil
+	})
+	if err != nil || result.Value != 3 {
+		t.Fatalf("expected result despite storage failure, got %+v err=%v", result, err)
+	}
+	if repo.Get("scope", "key-1") != nil {
+		t.Error("expected reservation to be released")
+	}
+}
+
+func TestExecute_KeyTooLong(t *testing.T) {
+	repo := testutil.NewMockIdempotencyRepository()
+	_, _, err := Execute(context.Background(), repo, "scope", strings.Repeat("k", MaxKeyLength+1), "payload", func(ctx context.Context) (testResult, error) {
+		return testResult{}, nil
+	})
+	if !errors.Is(err, ErrKeyTooLong) {
+		t.Errorf("expected key length error, got %v", err)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"sync"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/invoice"
 )
 
 // ResultAggregator aggregates results from multiple concurrent operations
@@ -0,0 +1,55 @@
+package invoice
+
+import (
+	"fmt"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/codelist"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// checkCodes checks the coded fields of a document against the DIAN code lists, so that
+// invalid codes are rejected here instead of by DIAN. Empty fields are not checked.
+//
+// The embedded unit list holds the units in common use, not the whole UN/ECE Rec 20
+// list accepted by DIAN, so an unknown unit is only notified.
+func checkCodes(r *ruleSet, doc invoice.OpenETLDocument, documentType string) {
+	for i, mp := range doc.CdoMediosPago {
+		r.code(codelist.PaymentForms, fmt.Sprintf("cdo_medios_pago[%d].fpa_codigo", i), "FAN02", mp.FpaCodigo)
+		r.code(codelist.PaymentMeans, fmt.Sprintf("cdo_medios_pago[%d].mpa_codigo", i), "FAN03", mp.MpaCodigo)
+	}
+
+	for i, item := range doc.Items {
+		if item.UndCodigo != "" && !codelist.Contains(codelist.Units, codelist.UnitCode(item.UndCodigo)) {
+			r.notify(fmt.Sprintf("items[%d].und_codigo", i), "", "items[%d].und_codigo [%s] no pertenece a la lista %s; verifique que sea un código UN/ECE Rec 20", i, item.UndCodigo, codelist.Units)
+		}
+	}
+
+	for i, tributo := range doc.Tributos {
+		r.code(codelist.Taxes, fmt.Sprintf("tributos[%d].tri_codigo", i), "", tributo.TriCodigo)
+	}
+
+	if list, ok := codelist.CorrectionConcepts(documentType); ok && doc.CdoConceptosCorreccion != nil {
+		r.code(list, "cdo_conceptos_correccion.cco_codigo", "", doc.CdoConceptosCorreccion.CcoCodigo)
+	}
+
+	if doc.AdqPaisCodigo != nil {
+		r.code(codelist.Countries, "adq_pais_codigo", "", *doc.AdqPaisCodigo)
+	}
+	for i, code := range doc.AdqRefCodigo {
+		r.code(codelist.TaxResponsibilities, fmt.Sprintf("adq_ref_codigo[%d]", i), "", strings.TrimSpace(code))
+	}
+	for i, code := range doc.OfeRefCodigo {
+		r.code(codelist.TaxResponsibilities, fmt.Sprintf("ofe_ref_codigo[%d]", i), "", strings.TrimSpace(code))
+	}
+}
+
+// code rejects a code that is present and does not belong to the list.
+func (r *ruleSet) code(list, field, rule, code string) {
+	if code == "" {
+		return
+	}
+	if err := codelist.Check(list, field, code); err != nil {
+		r.reject(field, rule, "%v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package invoice
+
+import (
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+func TestCheckCodes(t *testing.T) {
+	co, xx := "CO", "XX"
+	tests := []struct {
+		name         string
+		documentType string
+		modify       func(d *invoice.OpenETLDocument)
+		wantErr      string
+		severity     invoice.Severity
+	}{
+		{name: "valid", documentType: "FC", modify: func(d *invoice.OpenETLDocument) {}},
+		{name: "unit alias", documentType: "FC", modify: func(d *invoice.OpenETLDocument) { d.Items[0].UndCodigo = "KG" }},
+		{name: "piece", documentType: "FC", modify: func(d *invoice.OpenETLDocument) { d.Items[0].UndCodigo = "H87" }},
+		{name: "unknown unit", documentType: "FC", modify: func(d *invoice.OpenETLDocument) { d.Items[0].UndCodigo = "CAJA" }, wantErr: "items[0].und_codigo [CAJA]", severity: invoice.SeverityNotification},
+		{name: "unknown payment form", documentType: "FC", modify: func(d *invoice.OpenETLDocument) { d.CdoMediosPago[0].FpaCodigo = "3" }, wantErr: "cdo_medios_pago[0].fpa_codigo [3]"},
+		{name: "unknown payment means", documentType: "FC", modify: func(d *invoice.OpenETLDocument) { d.CdoMediosPago[0].MpaCodigo = "100" }, wantErr: "cdo_medios_pago[0].mpa_codigo [100]"},
+		{name: "unknown tax", documentType: "FC", modify: func(d *invoice.OpenETLDocument) { d.Tributos[0].TriCodigo = "09" }, wantErr: "tributos[0].tri_codigo [09]"},
+		{name: "unknown country", documentType: "FC", modify: func(d *invoice.OpenETLDocument) { d.AdqPaisCodigo = &xx }, wantErr: "adq_pais_codigo [XX]"},
+		{name: "unknown responsibility", documentType: "FC", modify: func(d *invoice.OpenETLDocument) { d.AdqRefCodigo = []string{"O-99"} }, wantErr: "adq_ref_codigo[0] [O-99]"},
+		{name: "NC concept", documentType: "NC", modify: func(d *invoice.OpenETLDocument) {
+			d.CdoConceptosCorreccion = &invoice.OpenETLConceptoCorreccion{CcoCodigo: "6"}
+		}},
+		{name: "NC concept in ND", documentType: "ND", modify: func(d *invoice.OpenETLDocument) {
+			d.CdoConceptosCorreccion = &invoice.OpenETLConceptoCorreccion{CcoCodigo: "6"}
+		}, wantErr: "cco_codigo [6]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := invoice.OpenETLDocument{
+				CdoMediosPago: []invoice.OpenETLMedioPago{{FpaCodigo: "1", MpaCodigo: "10"}},
+				Items:         []invoice.OpenETLItem{{UndCodigo: "94"}},
+				Tributos:      []invoice.OpenETLTributo{{TriCodigo: "01"}},
+				AdqPaisCodigo: &co,
+				AdqRefCodigo:  []string{"R-99-PN"},
+			}
+			tt.modify(&doc)
+
+			r := &ruleSet{}
+			checkCodes(r, doc, tt.documentType)
+			if tt.wantErr == "" {
+				if len(r.violations) > 0 {
+					t.Errorf("unexpected violations: %v", r.violations)
+				}
+				return
+			}
+			if len(r.violations) != 1 {
+				t.Fatalf("expected 1 violation, got %v", r.violations)
+			}
+			severity := tt.severity
+			if severity == "" {
+				severity = invoice.SeverityRejection
+			}
+			v := r.violations[0]
+			if v.Severidad != severity || !strings.Contains(v.Mensaje, tt.wantErr) {
+				t.Errorf("expected %s containing %q, got %+v", severity, tt.wantErr, v)
+			}
+		})
+	}
+}
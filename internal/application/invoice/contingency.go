@@ -0,0 +1,55 @@
+package invoice
+
+import (
+	"context"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// ContingencyQueue stores the documents issued while the contingency mode is active,
+// to be transmitted once the provider recovers. It is implemented by the contingency
+// application service.
+type ContingencyQueue interface {
+	Active(ctx context.Context) bool
+	Store(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.ContingencyDocument, []invoice.FailedDocument, error)
+}
+
+// WithContingency enables the contingency mode: while it is active, validated documents
+// are stored in the queue instead of being sent to the provider.
+func (s *Service) WithContingency(queue ContingencyQueue) *Service {
+	s.contingency = queue
+	return s
+}
+
+// storeInContingency stores the validated documents in the contingency queue, records
+// them in the ledger and marks their consecutivos as used.
+func (s *Service) storeInContingency(ctx context.Context, idx ledgerIndex, documents []invoice.OpenETLDocument, documentType string, tracked trackedConsecutivos) (*invoice.DocumentRegistrationResponse, error) {
+	stored, failed, err := s.contingency.Store(ctx, documents, documentType)
+	if err != nil {
+		return nil, err
+	}
+	s.recordFailed(ctx, idx, failed, document.StatusFailed)
+
+	now := time.Now()
+	processed := make([]invoice.ProcessedDocument, 0, len(stored))
+	for _, doc := range stored {
+		processed = append(processed, invoice.ProcessedDocument{RfaPrefijo: doc.RfaPrefijo, CdoConsecutivo: doc.CdoConsecutivo})
+		if key, ok := idx.lookup(doc.RfaPrefijo, doc.CdoConsecutivo); ok && s.ledger != nil {
+			s.updateLedger(ctx, key, document.StatusUpdate{Estado: document.StatusContingency})
+		}
+	}
+	s.markConsecutivosUsed(ctx, tracked, processed)
+
+	if failed == nil {
+		failed = make([]invoice.FailedDocument, 0)
+	}
+	return &invoice.DocumentRegistrationResponse{
+		Message:                "Documentos almacenados en contingencia; se transmitirán cuando el servicio se restablezca",
+		Lote:                   "lote-" + now.Format("2006-01-02") + "-" + now.Format("15:04:05"),
+		DocumentosProcesados:   []invoice.ProcessedDocument{},
+		DocumentosFallidos:     failed,
+		DocumentosContingencia: stored,
+	}, nil
+}
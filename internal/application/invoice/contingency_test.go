@@ -0,0 +1,97 @@
+package invoice
+
+import (
+	"context"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+// stubContingencyQueue records the documents stored while active.
+type stubContingencyQueue struct {
+	active bool
+	stored []invoice.OpenETLDocument
+}
+
+func (q *stubContingencyQueue) Active(ctx context.Context) bool { return q.active }
+
+func (q *stubContingencyQueue) Store(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.ContingencyDocument, []invoice.FailedDocument, error) {
+	var stored []invoice.ContingencyDocument
+	for _, doc := range documents {
+		q.stored = append(q.stored, doc)
+		stored = append(stored, invoice.ContingencyDocument{RfaPrefijo: doc.RfaPrefijo, CdoConsecutivo: doc.CdoConsecutivo, NumeroContingencia: "CONT" + doc.CdoConsecutivo, TipoContingencia: "04"})
+	}
+	return stored, nil, nil
+}
+
+func TestService_RegisterDocument_StoresInContingency(t *testing.T) {
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			t.Fatal("documents must not be sent in contingency mode")
+			return nil, nil
+		},
+	}
+	ledger := testutil.NewMockDocumentRepository()
+	queue := &stubContingencyQueue{active: true}
+	service := NewService(mockProvider, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger()).WithContingency(queue)
+
+	invalid := newLedgerTestDocument("2")
+	invalid.Items = nil
+	resp, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1"), invalid}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(queue.stored) != 1 || queue.stored[0].CdoConsecutivo != "1" {
+		t.Errorf("expected only the valid document stored, got %+v", queue.stored)
+	}
+	if len(resp.DocumentosContingencia) != 1 || resp.DocumentosContingencia[0].NumeroContingencia != "CONT1" {
+		t.Errorf("unexpected contingency documents %+v", resp.DocumentosContingencia)
+	}
+	if len(resp.DocumentosProcesados) != 0 || len(resp.DocumentosFallidos) != 1 {
+		t.Errorf("expected no processed and one failed document, got %+v", resp)
+	}
+
+	entry, _ := ledger.FindByKey(context.Background(), ledgerTestKey("1"))
+	if entry == nil || entry.Estado != document.StatusContingency {
+		t.Fatalf("expected the document in contingency in the ledger, got %+v", entry)
+	}
+
+	// A retry of a document stored in contingency is not stored again
+	resp, err = service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(queue.stored) != 1 || len(resp.DocumentosFallidos) != 1 {
+		t.Errorf("expected the retry to be rejected, got %+v", resp)
+	}
+}
+
+func TestService_RegisterDocument_ContingencyInactive(t *testing.T) {
+	var sent int
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			sent = len(req.Documentos.FC)
+			return &invoice.DocumentRegistrationResponse{
+				DocumentosProcesados: []invoice.ProcessedDocument{{RfaPrefijo: "SETT", CdoConsecutivo: "1"}},
+			}, nil
+		},
+	}
+	queue := &stubContingencyQueue{}
+	service := NewService(mockProvider, nil, nil, "2").WithContingency(queue)
+
+	if _, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1")}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != 1 || len(queue.stored) != 0 {
+		t.Errorf("expected the document sent to the provider, sent %d stored %d", sent, len(queue.stored))
+	}
+}
@@ -0,0 +1,163 @@
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/cufe"
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// WithCUFEVerification enables local computation of CUFE/CUDE for registered documents.
+// The computed code fills in missing provider values; mismatches are returned as
+// cufe_calculado, stored in the ledger and logged. Received documents with a malformed
+// CUFE are logged, and those issued through this service are recomputed as well.
+func (s *Service) WithCUFEVerification(keys cufe.KeyResolver, log *slog.Logger) *Service {
+	s.cufeKeys = keys
+	s.cufeLog = log
+	return s
+}
+
+// expectedCUFEs computes the CUFE/CUDE of each document, indexed by prefijo/consecutivo.
+// Documents whose keys are unknown or whose values cannot be composed are skipped.
+func (s *Service) expectedCUFEs(documents []invoice.OpenETLDocument, documentType string) map[string]string {
+	if s.cufeKeys == nil {
+		return nil
+	}
+
+	expected := make(map[string]string, len(documents))
+	for _, doc := range documents {
+		ofe := doc.OfeIdentificacion
+		if documentType == "DS" {
+			// DS documents are issued by the buyer, sent as adq_identificacion
+			ofe = doc.AdqIdentificacion
+		}
+		keys, ok := s.cufeKeys.Resolve(identification.Base(ofe), doc.RfaPrefijo)
+		if !ok {
+			continue
+		}
+		code, err := cufe.ForDocument(doc, documentType, keys)
+		if err != nil {
+			s.logCUFE("Could not compute CUFE/CUDE locally", doc.RfaPrefijo, doc.CdoConsecutivo, "error", err)
+			continue
+		}
+		expected[doc.RfaPrefijo+"|"+doc.CdoConsecutivo] = code
+	}
+	return expected
+}
+
+// crossCheckCUFEs compares the codes returned by the provider with the local computation.
+// The provider value prevails; a missing one is completed with the computed code and a
+// different one is kept along with the computed code in CUFECalculado.
+func (s *Service) crossCheckCUFEs(expected map[string]string, processed []invoice.ProcessedDocument) {
+	for i := range processed {
+		p := &processed[i]
+		code, ok := expected[p.RfaPrefijo+"|"+p.CdoConsecutivo]
+		if !ok {
+			continue
+		}
+		if p.CUFE == "" {
+			p.CUFE = code
+			continue
+		}
+		if !strings.EqualFold(p.CUFE, code) {
+			p.CUFECalculado = code
+			s.logCUFE("CUFE/CUDE returned by provider does not match local computation", p.RfaPrefijo, p.CdoConsecutivo,
+				"provider_cufe", p.CUFE,
+				"computed_cufe", code,
+			)
+		}
+	}
+}
+
+// checkReceivedCUFEs logs received documents whose CUFE is not a valid SHA-384 digest.
+// The issuer keys are only known for documents issued through this service (an OFE
+// receiving from another OFE): those with an enriched payload in the ledger are
+// recomputed, and a different code is returned in CUFECalculado and logged.
+func (s *Service) checkReceivedCUFEs(ctx context.Context, documents []invoice.Document) {
+	if s.cufeKeys == nil {
+		return
+	}
+	for i := range documents {
+		doc := &documents[i]
+		if !cufe.ValidFormat(strings.ToLower(doc.CUFE)) {
+			s.logCUFE("Received document has an invalid CUFE", doc.Prefijo, doc.Consecutivo,
+				"proveedor", doc.Proveedor,
+				"cufe", doc.CUFE,
+			)
+			continue
+		}
+		code, ok := s.recomputeReceivedCUFE(ctx, *doc)
+		if ok && !strings.EqualFold(doc.CUFE, code) {
+			doc.CUFECalculado = code
+			s.logCUFE("Received document CUFE/CUDE does not match local computation", doc.Prefijo, doc.Consecutivo,
+				"proveedor", doc.Proveedor,
+				"cufe", doc.CUFE,
+				"computed_cufe", code,
+			)
+		}
+	}
+}
+
+// recomputeReceivedCUFE computes the CUFE/CUDE of a received document from the payload
+// stored in the ledger when it was registered. It returns false when the document was
+// not issued through this service or its keys are unknown.
+func (s *Service) recomputeReceivedCUFE(ctx context.Context, doc invoice.Document) (string, bool) {
+	documentType := receivedDocumentType(doc.Tipo)
+	if s.ledger == nil || documentType == "" {
+		return "", false
+	}
+	issuer := identification.Base(doc.Proveedor)
+	entry, err := s.ledger.FindByKey(ctx, document.Key{
+		OfeIdentificacion: issuer,
+		Tipo:              documentType,
+		Prefijo:           doc.Prefijo,
+		Consecutivo:       doc.Consecutivo,
+	})
+	if err != nil || entry == nil || len(entry.EnrichedPayload) == 0 {
+		return "", false
+	}
+	keys, ok := s.cufeKeys.Resolve(issuer, doc.Prefijo)
+	if !ok {
+		return "", false
+	}
+
+	var issued invoice.OpenETLDocument
+	if err := json.Unmarshal(entry.EnrichedPayload, &issued); err != nil {
+		s.logCUFE("Could not read the ledger payload of a received document", doc.Prefijo, doc.Consecutivo, "error", err)
+		return "", false
+	}
+	code, err := cufe.ForDocument(issued, documentType, keys)
+	if err != nil {
+		s.logCUFE("Could not compute CUFE/CUDE locally", doc.Prefijo, doc.Consecutivo, "error", err)
+		return "", false
+	}
+	return code, true
+}
+
+// receivedDocumentType maps the tipo of a received document, given either as the
+// OpenETL type or as the DIAN document type code, to the OpenETL type. Support
+// documents are issued by the buyer itself and are never received.
+func receivedDocumentType(tipo string) string {
+	switch strings.ToUpper(strings.TrimSpace(tipo)) {
+	case "FC", "01", "02", "03", "04":
+		return "FC"
+	case "NC", "91":
+		return "NC"
+	case "ND", "92":
+		return "ND"
+	default:
+		return ""
+	}
+}
+
+func (s *Service) logCUFE(msg, prefijo, consecutivo string, args ...any) {
+	if s.cufeLog == nil {
+		return
+	}
+	s.cufeLog.Warn(msg, append([]any{"prefijo", prefijo, "consecutivo", consecutivo}, args...)...)
+}
@@ -0,0 +1,193 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/cufe"
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func TestService_RegisterDocument_CrossChecksCUFE(t *testing.T) {
+	keys := cufe.StaticKeys{ClaveTecnica: "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c"}
+	docs := []invoice.OpenETLDocument{newLedgerTestDocument("1"), newLedgerTestDocument("2")}
+
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			return &invoice.DocumentRegistrationResponse{
+				DocumentosProcesados: []invoice.ProcessedDocument{
+					{CdoID: 1, RfaPrefijo: "SETT", CdoConsecutivo: "1"},
+					{CdoID: 2, RfaPrefijo: "SETT", CdoConsecutivo: "2", CUFE: "cufe-del-proveedor"},
+				},
+			}, nil
+		},
+	}
+	var logs bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+	ledger := testutil.NewMockDocumentRepository()
+	service := NewService(mockProvider, nil, nil, "2").WithCUFEVerification(keys, log).WithLedger(ledger, nil)
+
+	response, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: docs},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedDoc := service.enrichDocumentWithEnvironment(docs[0])
+	expected, err := cufe.ForDocument(expectedDoc, "FC", cufe.Keys(keys))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byConsecutivo := map[string]invoice.ProcessedDocument{}
+	for _, p := range response.DocumentosProcesados {
+		byConsecutivo[p.CdoConsecutivo] = p
+	}
+	if got := byConsecutivo["1"].CUFE; got != expected {
+		t.Errorf("expected missing CUFE to be completed with %s, got %q", expected, got)
+	}
+	if got := byConsecutivo["2"].CUFE; got != "cufe-del-proveedor" {
+		t.Errorf("expected provider CUFE to prevail, got %q", got)
+	}
+
+	// The mismatch is returned and stored in the ledger along with the provider code
+	expected2, _ := cufe.ForDocument(service.enrichDocumentWithEnvironment(docs[1]), "FC", cufe.Keys(keys))
+	if got := byConsecutivo["2"].CUFECalculado; got != expected2 {
+		t.Errorf("expected cufe_calculado %s, got %q", expected2, got)
+	}
+	if got := byConsecutivo["1"].CUFECalculado; got != "" {
+		t.Errorf("expected no cufe_calculado without a mismatch, got %q", got)
+	}
+	entry, _ := ledger.FindByKey(context.Background(), ledgerTestKey("2"))
+	if entry == nil || entry.CUFE != "cufe-del-proveedor" || entry.CUFECalculado != expected2 {
+		t.Errorf("expected the mismatch to be stored in the ledger, got %+v", entry)
+	}
+	if entry, _ := ledger.FindByKey(context.Background(), ledgerTestKey("1")); entry == nil || entry.CUFECalculado != "" {
+		t.Errorf("expected no cufe_calculado in the ledger without a mismatch, got %+v", entry)
+	}
+	if !strings.Contains(logs.String(), "does not match local computation") {
+		t.Errorf("expected mismatch to be logged, got logs: %s", logs.String())
+	}
+}
+
+func TestService_GetReceivedDocuments_LogsInvalidCUFE(t *testing.T) {
+	mockProvider := &testutil.MockProvider{
+		GetReceivedDocumentsFunc: func(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
+			return []invoice.Document{
+				{Prefijo: "FE", Consecutivo: "1", CUFE: strings.Repeat("a", cufe.Length)},
+				{Prefijo: "FE", Consecutivo: "2", CUFE: "no-es-un-cufe"},
+			}, nil
+		},
+	}
+	var logs bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+	service := NewService(mockProvider, nil, nil, "2").WithCUFEVerification(cufe.StaticKeys{SoftwarePIN: "12345"}, log)
+
+	documents, err := service.GetReceivedDocuments(context.Background(), invoice.DocumentQuery{
+		CompanyNit:  "860011153",
+		InitialDate: "2024-01-01",
+		FinalDate:   "2024-01-31",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(documents) != 2 {
+		t.Fatalf("expected documents to be returned unchanged, got %d", len(documents))
+	}
+	if strings.Count(logs.String(), "invalid CUFE") != 1 || !strings.Contains(logs.String(), "no-es-un-cufe") {
+		t.Errorf("expected only the malformed CUFE to be logged, got logs: %s", logs.String())
+	}
+}
+
+func TestService_GetReceivedDocuments_RecomputesCUFE(t *testing.T) {
+	ctx := context.Background()
+	keys := cufe.StaticKeys{ClaveTecnica: "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c"}
+
+	// Documents 1 and 2 were issued through the service by OFE 860011153
+	ledger := testutil.NewMockDocumentRepository()
+	computed := map[string]string{}
+	for _, consecutivo := range []string{"1", "2"} {
+		doc := newLedgerTestDocument(consecutivo)
+		ambiente := "2"
+		doc.CdoAmbiente = &ambiente
+		payload, _ := json.Marshal(doc)
+		if _, err := ledger.Save(ctx, document.Document{
+			OfeIdentificacion: "860011153",
+			Tipo:              "FC",
+			Prefijo:           "SETT",
+			Consecutivo:       consecutivo,
+			Estado:            document.StatusAccepted,
+			EnrichedPayload:   payload,
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		computed[consecutivo], _ = cufe.ForDocument(doc, "FC", cufe.Keys(keys))
+	}
+	other := strings.Repeat("b", cufe.Length)
+
+	mockProvider := &testutil.MockProvider{
+		GetReceivedDocumentsFunc: func(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
+			return []invoice.Document{
+				{Proveedor: "860011153", Tipo: "01", Prefijo: "SETT", Consecutivo: "1", CUFE: strings.ToUpper(computed["1"])},
+				{Proveedor: "860011153", Tipo: "FC", Prefijo: "SETT", Consecutivo: "2", CUFE: other},
+				{Proveedor: "800197268", Tipo: "01", Prefijo: "FE", Consecutivo: "3", CUFE: other},
+			}, nil
+		},
+	}
+	var logs bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+	service := NewService(mockProvider, nil, nil, "2").WithCUFEVerification(keys, log).WithLedger(ledger, nil)
+
+	documents, err := service.GetReceivedDocuments(ctx, invoice.DocumentQuery{
+		CompanyNit:  "900123456",
+		InitialDate: "2024-01-01",
+		FinalDate:   "2024-01-31",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		consecutivo string
+		want        string
+	}{
+		{"1", ""},            // matches the recomputed code
+		{"2", computed["2"]}, // differs from the recomputed code
+		{"3", ""},            // issued by a third party, keys unknown
+	}
+	for i, tt := range tests {
+		if got := documents[i].CUFECalculado; got != tt.want {
+			t.Errorf("document %s: expected cufe_calculado %q, got %q", tt.consecutivo, tt.want, got)
+		}
+	}
+	if strings.Count(logs.String(), "does not match local computation") != 1 {
+		t.Errorf("expected only the mismatch to be logged, got logs: %s", logs.String())
+	}
+}
+
+func TestReceivedDocumentType(t *testing.T) {
+	tests := []struct {
+		tipo string
+		want string
+	}{
+		{"FC", "FC"},
+		{"01", "FC"},
+		{"nc", "NC"},
+		{"91", "NC"},
+		{"92", "ND"},
+		{"DS", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := receivedDocumentType(tt.tipo); got != tt.want {
+			t.Errorf("receivedDocumentType(%q) = %q, want %q", tt.tipo, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/currency"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// WithExchangeRates sets the exchange rate source used to fill cdo_trm when a document
+// in foreign currency omits it. The TRM in force on cdo_trm_fecha (or cdo_fecha) is used.
+func (s *Service) WithExchangeRates(source currency.Source) *Service {
+	s.exchangeRates = source
+	return s
+}
+
+// applyExchangeRates completes the exchange rate of the documents in foreign currency.
+// Documents without a valid TRM, or whose TRM is not in the exchange rate source,
+// are rejected. COP documents are not changed.
+func (s *Service) applyExchangeRates(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.FailedDocument) {
+	now := time.Now()
+	fechaProcesamiento := now.Format("2006-01-02")
+	horaProcesamiento := now.Format("15:04:05")
+
+	valid := make([]invoice.OpenETLDocument, 0, len(documents))
+	var failed []invoice.FailedDocument
+
+	for _, doc := range documents {
+		if !currency.IsForeign(doc.MonCodigo) {
+			valid = append(valid, doc)
+			continue
+		}
+
+		doc, err := s.exchangeRate(ctx, doc)
+		if err != nil {
+			failed = append(failed, invoice.FailedDocument{
+				Documento:          documentType,
+				Consecutivo:        doc.CdoConsecutivo,
+				Prefijo:            doc.RfaPrefijo,
+				Errors:             []string{err.Error()},
+				FechaProcesamiento: fechaProcesamiento,
+				HoraProcesamiento:  horaProcesamiento,
+			})
+			continue
+		}
+		valid = append(valid, doc)
+	}
+
+	return valid, failed
+}
+
+// exchangeRate validates the TRM of a document in foreign currency, looking it up
+// in the exchange rate source when omitted.
+func (s *Service) exchangeRate(ctx context.Context, doc invoice.OpenETLDocument) (invoice.OpenETLDocument, error) {
+	code := currency.Normalize(doc.MonCodigo)
+
+	fecha := doc.CdoFecha
+	if doc.CdoTrmFecha != nil && strings.TrimSpace(*doc.CdoTrmFecha) != "" {
+		fecha = strings.TrimSpace(*doc.CdoTrmFecha)
+	}
+	date, err := time.Parse("2006-01-02", fecha)
+	if err != nil {
+		return doc, fmt.Errorf("cdo_trm_fecha inválida [%s]", fecha)
+	}
+	doc.CdoTrmFecha = &fecha
+
+	if doc.CdoTrm != nil && strings.TrimSpace(*doc.CdoTrm) != "" {
+		if _, err := currency.ParseRate(*doc.CdoTrm); err != nil {
+			return doc, err
+		}
+		return doc, nil
+	}
+
+	if s.exchangeRates == nil {
+		return doc, fmt.Errorf("cdo_trm es requerido para documentos en moneda [%s]", code)
+	}
+	rate, err := s.exchangeRates.Rate(ctx, code, date)
+	if err != nil {
+		return doc, fmt.Errorf("Error al consultar la tasa de cambio: %w", err)
+	}
+	if rate == nil {
+		return doc, fmt.Errorf("no hay tasa de cambio registrada para la moneda [%s] en la fecha [%s]", code, fecha)
+	}
+
+	value := rate.Value
+	rateDate := rate.Date.Format("2006-01-02")
+	doc.CdoTrm = &value
+	doc.CdoTrmFecha = &rateDate
+	return doc, nil
+}
@@ -0,0 +1,75 @@
+package invoice
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/currency"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func newForeignTestDocument(consecutivo string) invoice.OpenETLDocument {
+	doc := newLedgerTestDocument(consecutivo)
+	doc.MonCodigo = "USD"
+	doc.CdoFecha = "2026-03-16"
+	return doc
+}
+
+func TestService_ApplyExchangeRates(t *testing.T) {
+	rates := testutil.NewMockExchangeRateRepository(
+		currency.Rate{Currency: "USD", Date: time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC), Value: "4102.50"},
+	)
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").WithExchangeRates(rates)
+
+	provided := newForeignTestDocument("2")
+	provided.CdoTrm = strPtr("4000")
+	invalid := newForeignTestDocument("3")
+	invalid.CdoTrm = strPtr("-1")
+	missing := newForeignTestDocument("4")
+	missing.MonCodigo = "EUR"
+
+	valid, failed := service.applyExchangeRates(context.Background(), []invoice.OpenETLDocument{
+		newLedgerTestDocument("0"), newForeignTestDocument("1"), provided, invalid, missing,
+	}, "FC")
+
+	if len(valid) != 3 || len(failed) != 2 {
+		t.Fatalf("expected 3 valid and 2 failed documents, got %d/%d", len(valid), len(failed))
+	}
+	if valid[0].CdoTrm != nil {
+		t.Errorf("expected COP document to be left untouched, got TRM %s", *valid[0].CdoTrm)
+	}
+	if *valid[1].CdoTrm != "4102.50" || *valid[1].CdoTrmFecha != "2026-03-14" {
+		t.Errorf("expected TRM in force on the issue date, got %s %s", *valid[1].CdoTrm, *valid[1].CdoTrmFecha)
+	}
+	if *valid[2].CdoTrm != "4000" || *valid[2].CdoTrmFecha != "2026-03-16" {
+		t.Errorf("expected request TRM to be kept with the issue date, got %s %s", *valid[2].CdoTrm, *valid[2].CdoTrmFecha)
+	}
+	if !strings.Contains(failed[0].Errors[0], "tasa de cambio inválida") {
+		t.Errorf("unexpected error: %v", failed[0].Errors)
+	}
+	if !strings.Contains(failed[1].Errors[0], "no hay tasa de cambio registrada para la moneda [EUR] en la fecha [2026-03-16]") {
+		t.Errorf("unexpected error: %v", failed[1].Errors)
+	}
+}
+
+func TestService_ApplyExchangeRates_WithoutSource(t *testing.T) {
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2")
+
+	_, failed := service.applyExchangeRates(context.Background(), []invoice.OpenETLDocument{newForeignTestDocument("1")}, "FC")
+	if len(failed) != 1 || !strings.Contains(failed[0].Errors[0], "cdo_trm es requerido para documentos en moneda [USD]") {
+		t.Errorf("expected missing TRM error, got %+v", failed)
+	}
+}
+
+func TestService_ValidateDocument_Currency(t *testing.T) {
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2")
+
+	doc := newLedgerTestDocument("1")
+	doc.MonCodigo = "PESOS"
+	if err := service.validateDocument(doc, "FC", 0); err == nil || !strings.Contains(err.Error(), "[FAD15] mon_codigo: mon_codigo [PESOS] no es un código de moneda ISO 4217 válido") {
+		t.Errorf("expected invalid currency error, got %v", err)
+	}
+}
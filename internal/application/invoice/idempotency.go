@@ -0,0 +1,257 @@
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	appidempotency "3tcapital/goclonacion/internal/application/idempotency"
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/idempotency"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// registerDocumentScope identifies document registrations in the idempotency store.
+const registerDocumentScope = "registrar-documentos"
+
+// WithIdempotency enables whole-request idempotency through the Idempotency-Key header.
+func (s *Service) WithIdempotency(repo idempotency.Repository) *Service {
+	s.idempotency = repo
+	return s
+}
+
+// RegisterDocumentWithKey registers documents at most once per Idempotency-Key.
+// A retry with the same key and payload returns the stored response (replayed=true).
+// Without a key, or when idempotency is disabled, it behaves like RegisterDocument.
+func (s *Service) RegisterDocumentWithKey(ctx context.Context, key string, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, bool, error) {
+	if key == "" || s.idempotency == nil {
+		response, err := s.RegisterDocument(ctx, req)
+		return response, false, err
+	}
+
+	return appidempotency.Execute(ctx, s.idempotency, registerDocumentScope, key, req, func(ctx context.Context) (*invoice.DocumentRegistrationResponse, error) {
+		return s.RegisterDocument(ctx, req)
+	})
+}
+
+// claimStaleAfter is how long a ledger entry may stay in received or validated status
+// before a retry takes it over; longer means its registration was interrupted.
+const claimStaleAfter = 30 * time.Minute
+
+// claimDocuments claims each document in the ledger before it is processed, so that
+// concurrent registrations of the same OFE, type, prefijo and consecutivo send it at most
+// once. New documents and documents whose previous attempt failed or was rejected are
+// claimed and returned as pending. Identical retries of accepted documents get the stored
+// result back; a different payload for an already used number, or a retry of a document
+// still in process or stored in contingency, is rejected.
+// A document whose previous attempt ended without a result is looked up at the provider:
+// found, its result is recorded and returned; unknown to the provider, it is sent again.
+func (s *Service) claimDocuments(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.ProcessedDocument, []invoice.FailedDocument) {
+	if s.ledger == nil {
+		return documents, nil, nil
+	}
+
+	pending := make([]invoice.OpenETLDocument, 0, len(documents))
+	var replayed []invoice.ProcessedDocument
+	var rejected []invoice.FailedDocument
+
+	for _, doc := range documents {
+		key := ledgerKey(doc, documentType)
+		payload, hash, err := documentPayload(doc)
+		if err != nil {
+			s.logLedgerError("marshal original payload", key, err)
+			pending = append(pending, doc)
+			continue
+		}
+		entry := document.Document{
+			OfeIdentificacion: key.OfeIdentificacion,
+			Tipo:              key.Tipo,
+			Prefijo:           key.Prefijo,
+			Consecutivo:       key.Consecutivo,
+			Estado:            document.StatusReceived,
+			OriginalPayload:   payload,
+			PayloadHash:       hash,
+			Referencia:        referenceNumber(doc),
+		}
+		claimed, err := s.ledger.Claim(ctx, entry, claimStaleAfter, document.StatusRejected, document.StatusFailed)
+		if err != nil {
+			// The ledger is best effort: without it the document is processed as new
+			s.logLedgerError("claim document", key, err)
+			pending = append(pending, doc)
+			continue
+		}
+		if claimed {
+			pending = append(pending, doc)
+			continue
+		}
+
+		existing, err := s.ledger.FindByKey(ctx, key)
+		if err != nil || existing == nil {
+			if err != nil {
+				s.logLedgerError("find document", key, err)
+			}
+			rejected = append(rejected, duplicateDocument(doc, documentType, time.Now(),
+				"El documento %s %s%s del OFE [%s] se está procesando en otra solicitud; consulte su estado antes de reintentar", key))
+			continue
+		}
+
+		samePayload := existing.PayloadHash == "" || existing.PayloadHash == hash
+		switch {
+		case !samePayload:
+			rejected = append(rejected, duplicateDocument(doc, documentType, existing.UpdatedAt,
+				"El documento %s %s%s ya fue registrado para el OFE [%s] con un contenido diferente; el número no puede reutilizarse", key))
+		case existing.Estado == document.StatusAccepted:
+			replayed = append(replayed, storedResult(doc, existing))
+		case existing.Estado == document.StatusSent:
+			found, err := s.lookupSent(ctx, doc, documentType, existing)
+			switch {
+			case err != nil:
+				failure := duplicateDocument(doc, documentType, existing.UpdatedAt,
+					"El documento %s %s%s ya fue enviado para el OFE [%s] y su resultado está pendiente; consulte su estado antes de reintentar", key)
+				failure.Errors = append(failure.Errors, fmt.Sprintf("No fue posible consultar el documento en el proveedor: %v", err))
+				rejected = append(rejected, failure)
+			case found != nil:
+				replayed = append(replayed, s.recordReconciled(ctx, doc, key, found))
+			default:
+				// The provider never received it: the document is sent again
+				if claimed, err := s.ledger.Claim(ctx, entry, claimStaleAfter, document.StatusSent); err == nil && claimed {
+					pending = append(pending, doc)
+					continue
+				}
+				rejected = append(rejected, duplicateDocument(doc, documentType, time.Now(),
+					"El documento %s %s%s del OFE [%s] se está procesando en otra solicitud; consulte su estado antes de reintentar", key))
+			}
+		case existing.Estado == document.StatusContingency:
+			rejected = append(rejected, duplicateDocument(doc, documentType, existing.UpdatedAt,
+				"El documento %s %s%s ya fue almacenado en contingencia para el OFE [%s] y se transmitirá cuando el servicio se restablezca", key))
+		default:
+			rejected = append(rejected, duplicateDocument(doc, documentType, existing.UpdatedAt,
+				"El documento %s %s%s del OFE [%s] se está procesando en otra solicitud; consulte su estado antes de reintentar", key))
+		}
+	}
+
+	return pending, replayed, rejected
+}
+
+// rejectRepeatedNumbers rejects the documents whose prefijo and consecutivo were already
+// used by an earlier document of the request. The provider results only carry the prefijo
+// and consecutivo, so documents of different OFEs with the same number could not be told
+// apart when their results are recorded and must be sent in separate requests.
+func rejectRepeatedNumbers(documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.FailedDocument) {
+	unique := make([]invoice.OpenETLDocument, 0, len(documents))
+	var rejected []invoice.FailedDocument
+	seen := make(map[string]document.Key, len(documents))
+
+	for _, doc := range documents {
+		key := ledgerKey(doc, documentType)
+		first, ok := seen[doc.RfaPrefijo+"|"+doc.CdoConsecutivo]
+		switch {
+		case !ok:
+			seen[doc.RfaPrefijo+"|"+doc.CdoConsecutivo] = key
+			unique = append(unique, doc)
+		case first.OfeIdentificacion == key.OfeIdentificacion:
+			rejected = append(rejected, duplicateDocument(doc, documentType, time.Now(),
+				"El documento %s %s%s está repetido en la solicitud para el OFE [%s]", key))
+		default:
+			rejected = append(rejected, duplicateDocument(doc, documentType, time.Now(),
+				"El documento %s %s%s del OFE [%s] tiene el mismo número que un documento de otro OFE en la solicitud; envíelo en una solicitud separada", key))
+		}
+	}
+
+	return unique, rejected
+}
+
+// duplicateDocument builds the failure of a document that cannot be registered again.
+// format receives the type, prefijo, consecutivo and OFE of the document.
+func duplicateDocument(doc invoice.OpenETLDocument, documentType string, at time.Time, format string, key document.Key) invoice.FailedDocument {
+	return invoice.FailedDocument{
+		Documento:          documentType,
+		Consecutivo:        doc.CdoConsecutivo,
+		Prefijo:            doc.RfaPrefijo,
+		Errors:             []string{fmt.Sprintf(format, documentType, doc.RfaPrefijo, doc.CdoConsecutivo, key.OfeIdentificacion)},
+		FechaProcesamiento: at.Format("2006-01-02"),
+		HoraProcesamiento:  at.Format("15:04:05"),
+	}
+}
+
+// storedResult returns the DIAN result of an accepted document as stored in the ledger.
+func storedResult(doc invoice.OpenETLDocument, existing *document.Document) invoice.ProcessedDocument {
+	processed := invoice.ProcessedDocument{
+		RfaPrefijo:         doc.RfaPrefijo,
+		CdoConsecutivo:     doc.CdoConsecutivo,
+		FechaProcesamiento: existing.UpdatedAt.Format("2006-01-02"),
+		HoraProcesamiento:  existing.UpdatedAt.Format("15:04:05"),
+		CUFE:               existing.CUFE,
+		CUFECalculado:      existing.CUFECalculado,
+		XmlBase64:          existing.XmlBase64,
+		PdfBase64:          existing.PdfBase64,
+	}
+	if existing.CdoID != nil {
+		processed.CdoID = *existing.CdoID
+	}
+	return processed
+}
+
+// lookupSent asks the provider for a document sent before without a known result. It is
+// looked up by number and, when the provider cannot search by number, by the CUFE/CUDE
+// computed from the stored payload. Returns nil when the provider does not know it.
+func (s *Service) lookupSent(ctx context.Context, doc invoice.OpenETLDocument, documentType string, existing *document.Document) (*invoice.Document, error) {
+	number := doc.RfaPrefijo + doc.CdoConsecutivo
+	query := invoice.DocumentByNumberQuery{
+		CompanyNit:     identification.Base(doc.OfeIdentificacion),
+		DocumentNumber: number,
+		SupplierNit:    identification.Base(doc.OfeIdentificacion),
+	}
+	if documentType == "DS" {
+		// DS documents are issued by the buyer, sent as adq_identificacion
+		query.SupplierNit = identification.Base(doc.AdqIdentificacion)
+	}
+
+	found, err := s.provider.GetDocumentByNumber(ctx, query)
+	code := s.sentCUFE(doc, documentType, existing)
+	if err != nil {
+		if code == "" {
+			return nil, err
+		}
+		query.DocumentNumber = code
+		if found, err = s.provider.GetDocumentByNumber(ctx, query); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range found {
+		if found[i].Prefijo+found[i].Consecutivo == number || (code != "" && strings.EqualFold(found[i].CUFE, code)) {
+			return &found[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// sentCUFE computes the CUFE/CUDE of the document as it was sent, from the enriched
+// payload stored in the ledger. Returns an empty string when it cannot be computed.
+func (s *Service) sentCUFE(doc invoice.OpenETLDocument, documentType string, existing *document.Document) string {
+	if len(existing.EnrichedPayload) > 0 {
+		var sent invoice.OpenETLDocument
+		if err := json.Unmarshal(existing.EnrichedPayload, &sent); err == nil {
+			doc = sent
+		}
+	}
+	return s.expectedCUFEs([]invoice.OpenETLDocument{doc}, documentType)[doc.RfaPrefijo+"|"+doc.CdoConsecutivo]
+}
+
+// recordReconciled records as accepted a sent document found at the provider and returns its result.
+func (s *Service) recordReconciled(ctx context.Context, doc invoice.OpenETLDocument, key document.Key, found *invoice.Document) invoice.ProcessedDocument {
+	s.updateLedger(ctx, key, document.StatusUpdate{Estado: document.StatusAccepted, CUFE: found.CUFE})
+
+	now := time.Now()
+	return invoice.ProcessedDocument{
+		RfaPrefijo:         doc.RfaPrefijo,
+		CdoConsecutivo:     doc.CdoConsecutivo,
+		FechaProcesamiento: now.Format("2006-01-02"),
+		HoraProcesamiento:  now.Format("15:04:05"),
+		CUFE:               found.CUFE,
+	}
+}
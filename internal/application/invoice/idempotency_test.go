@@ -0,0 +1,313 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func newIdempotencyTestService(ledger document.Repository, calls *int) *Service {
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			*calls++
+			processed := make([]invoice.ProcessedDocument, 0, len(req.Documentos.FC))
+			for _, doc := range req.Documentos.FC {
+				processed = append(processed, invoice.ProcessedDocument{
+					CdoID:          10,
+					RfaPrefijo:     doc.RfaPrefijo,
+					CdoConsecutivo: doc.CdoConsecutivo,
+					CUFE:           "cufe-" + doc.CdoConsecutivo,
+				})
+			}
+			return &invoice.DocumentRegistrationResponse{DocumentosProcesados: processed}, nil
+		},
+	}
+	return NewService(mockProvider, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+}
+
+func registerFC(t *testing.T, service *Service, docs ...invoice.OpenETLDocument) *invoice.DocumentRegistrationResponse {
+	t.Helper()
+	response, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: docs},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return response
+}
+
+func TestService_RegisterDocument_ReplaysAcceptedDocument(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	calls := 0
+	service := newIdempotencyTestService(ledger, &calls)
+
+	registerFC(t, service, newLedgerTestDocument("1"))
+	response := registerFC(t, service, newLedgerTestDocument("1"))
+
+	if calls != 1 {
+		t.Errorf("expected provider to be called once, got %d", calls)
+	}
+	if len(response.DocumentosProcesados) != 1 {
+		t.Fatalf("expected 1 replayed document, got %+v", response)
+	}
+	if got := response.DocumentosProcesados[0]; got.CUFE != "cufe-1" || got.CdoID != 10 {
+		t.Errorf("expected stored result to be replayed, got %+v", got)
+	}
+}
+
+func TestService_RegisterDocument_RejectsReusedNumber(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	calls := 0
+	service := newIdempotencyTestService(ledger, &calls)
+
+	registerFC(t, service, newLedgerTestDocument("1"))
+
+	changed := newLedgerTestDocument("1")
+	changed.CdoTotal = "238000.00"
+	response := registerFC(t, service, changed, newLedgerTestDocument("2"))
+
+	if calls != 2 {
+		t.Errorf("expected only the new document to reach the provider, got %d calls", calls)
+	}
+	if len(response.DocumentosFallidos) != 1 || !strings.Contains(response.DocumentosFallidos[0].Errors[0], "no puede reutilizarse") {
+		t.Fatalf("expected reused number to fail, got %+v", response.DocumentosFallidos)
+	}
+	if len(response.DocumentosProcesados) != 1 || response.DocumentosProcesados[0].CdoConsecutivo != "2" {
+		t.Errorf("expected new document to be processed, got %+v", response.DocumentosProcesados)
+	}
+
+	stored, _ := ledger.FindByKey(context.Background(), ledgerTestKey("1"))
+	if stored == nil || stored.Estado != document.StatusAccepted {
+		t.Errorf("expected original ledger entry to be kept, got %+v", stored)
+	}
+}
+
+func TestService_RegisterDocument_ReconcilesSentDocument(t *testing.T) {
+	tests := []struct {
+		name          string
+		lookup        func(ctx context.Context, query invoice.DocumentByNumberQuery) ([]invoice.Document, error)
+		expectedCalls int
+		expectedState document.Status
+		expectedError string
+		expectedCUFE  string
+	}{
+		{
+			name: "found at the provider",
+			lookup: func(ctx context.Context, query invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
+				return []invoice.Document{{Consecutivo: query.DocumentNumber, CUFE: "cufe-dian"}}, nil
+			},
+			expectedState: document.StatusAccepted,
+			expectedCUFE:  "cufe-dian",
+		},
+		{
+			name: "unknown to the provider",
+			lookup: func(ctx context.Context, query invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
+				return []invoice.Document{}, nil
+			},
+			expectedCalls: 1,
+			expectedState: document.StatusAccepted,
+			expectedCUFE:  "cufe-1",
+		},
+		{
+			name: "provider lookup fails",
+			lookup: func(ctx context.Context, query invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
+				return nil, errors.New("circuit breaker is open")
+			},
+			expectedState: document.StatusSent,
+			expectedError: "pendiente",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := testutil.NewMockDocumentRepository()
+			calls := 0
+			service := newIdempotencyTestService(ledger, &calls)
+			service.provider.(*testutil.MockProvider).GetDocumentByNumberFunc = tt.lookup
+
+			doc := newLedgerTestDocument("1")
+			payload, hash, err := documentPayload(doc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			key := ledgerTestKey("1")
+			if _, err := ledger.Save(context.Background(), document.Document{
+				OfeIdentificacion: key.OfeIdentificacion,
+				Tipo:              key.Tipo,
+				Prefijo:           key.Prefijo,
+				Consecutivo:       key.Consecutivo,
+				Estado:            document.StatusSent,
+				OriginalPayload:   payload,
+				PayloadHash:       hash,
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			response := registerFC(t, service, doc)
+
+			if calls != tt.expectedCalls {
+				t.Errorf("expected %d provider calls, got %d", tt.expectedCalls, calls)
+			}
+			if tt.expectedError != "" {
+				if len(response.DocumentosFallidos) != 1 || !strings.Contains(response.DocumentosFallidos[0].Errors[0], tt.expectedError) {
+					t.Errorf("expected pending document to fail, got %+v", response.DocumentosFallidos)
+				}
+			} else if len(response.DocumentosProcesados) != 1 || response.DocumentosProcesados[0].CUFE != tt.expectedCUFE {
+				t.Errorf("expected processed document with CUFE %q, got %+v", tt.expectedCUFE, response)
+			}
+
+			stored, _ := ledger.FindByKey(context.Background(), key)
+			if stored == nil || stored.Estado != tt.expectedState {
+				t.Errorf("expected ledger status %q, got %+v", tt.expectedState, stored)
+			}
+		})
+	}
+}
+
+func TestService_RegisterDocument_ResendsRejectedDocument(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	calls := 0
+	service := newIdempotencyTestService(ledger, &calls)
+
+	key := ledgerTestKey("1")
+	if _, err := ledger.Save(context.Background(), document.Document{
+		OfeIdentificacion: key.OfeIdentificacion,
+		Tipo:              key.Tipo,
+		Prefijo:           key.Prefijo,
+		Consecutivo:       key.Consecutivo,
+		Estado:            document.StatusRejected,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	response := registerFC(t, service, newLedgerTestDocument("1"))
+
+	if calls != 1 || len(response.DocumentosProcesados) != 1 {
+		t.Errorf("expected rejected document to be sent again, calls=%d response=%+v", calls, response)
+	}
+}
+
+func TestService_RegisterDocument_RejectsDocumentRepeatedInRequest(t *testing.T) {
+	calls := 0
+	service := newIdempotencyTestService(testutil.NewMockDocumentRepository(), &calls)
+
+	response := registerFC(t, service, newLedgerTestDocument("1"), newLedgerTestDocument("1"))
+
+	if len(response.DocumentosProcesados) != 1 {
+		t.Errorf("expected the first copy to be processed, got %+v", response.DocumentosProcesados)
+	}
+	if len(response.DocumentosFallidos) != 1 || !strings.Contains(response.DocumentosFallidos[0].Errors[0], "repetido en la solicitud") {
+		t.Errorf("expected the second copy to fail, got %+v", response.DocumentosFallidos)
+	}
+}
+
+func TestService_RegisterDocument_RejectsNumberRepeatedAcrossOFEs(t *testing.T) {
+	calls := 0
+	ledger := testutil.NewMockDocumentRepository()
+	service := newIdempotencyTestService(ledger, &calls)
+
+	other := newLedgerTestDocument("1")
+	other.OfeIdentificacion = "900373115"
+	response := registerFC(t, service, newLedgerTestDocument("1"), other)
+
+	if calls != 1 || len(response.DocumentosProcesados) != 1 {
+		t.Errorf("expected only the first document to be sent, calls=%d response=%+v", calls, response)
+	}
+	if len(response.DocumentosFallidos) != 1 || !strings.Contains(response.DocumentosFallidos[0].Errors[0], "900373115") {
+		t.Errorf("expected the document of the other OFE to fail, got %+v", response.DocumentosFallidos)
+	}
+	key := ledgerKey(other, "FC")
+	if stored, _ := ledger.FindByKey(context.Background(), key); stored != nil {
+		t.Errorf("expected the rejected document not to be recorded for the other OFE, got %+v", stored)
+	}
+}
+
+func TestService_RegisterDocument_ConcurrentRetriesSendOnce(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			doc := req.Documentos.FC[0]
+			return &invoice.DocumentRegistrationResponse{DocumentosProcesados: []invoice.ProcessedDocument{
+				{CdoID: 1, RfaPrefijo: doc.RfaPrefijo, CdoConsecutivo: doc.CdoConsecutivo},
+			}}, nil
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2").WithLedger(testutil.NewMockDocumentRepository(), testutil.NewNullLogger())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+				Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1")}},
+			})
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected the document to be sent once, got %d calls", calls)
+	}
+}
+
+func TestService_RegisterDocument_TakesOverInterruptedRegistration(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	calls := 0
+	service := newIdempotencyTestService(ledger, &calls)
+
+	key := ledgerTestKey("1")
+	stale := document.Document{
+		OfeIdentificacion: key.OfeIdentificacion,
+		Tipo:              key.Tipo,
+		Prefijo:           key.Prefijo,
+		Consecutivo:       key.Consecutivo,
+		Estado:            document.StatusValidated,
+	}
+	if claimed, err := ledger.Claim(context.Background(), stale, claimStaleAfter); err != nil || !claimed {
+		t.Fatalf("unexpected claim result: %v %v", claimed, err)
+	}
+
+	// A registration still in process is not taken over
+	response := registerFC(t, service, newLedgerTestDocument("1"))
+	if calls != 0 || len(response.DocumentosFallidos) != 1 || !strings.Contains(response.DocumentosFallidos[0].Errors[0], "otra solicitud") {
+		t.Fatalf("expected the document in process to be rejected, calls=%d response=%+v", calls, response)
+	}
+
+	// Once stale, the entry is claimed again
+	if claimed, _ := ledger.Claim(context.Background(), stale, -time.Second); !claimed {
+		t.Error("expected an entry left in validated status to be taken over once stale")
+	}
+}
+
+func TestService_RegisterDocumentWithKey_Replays(t *testing.T) {
+	calls := 0
+	service := newIdempotencyTestService(testutil.NewMockDocumentRepository(), &calls)
+	service.WithIdempotency(testutil.NewMockIdempotencyRepository())
+
+	req := invoice.DocumentRegistrationRequest{Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1")}}}
+	if _, replayed, err := service.RegisterDocumentWithKey(context.Background(), "key-1", req); err != nil || replayed {
+		t.Fatalf("unexpected first result: replayed=%v err=%v", replayed, err)
+	}
+	response, replayed, err := service.RegisterDocumentWithKey(context.Background(), "key-1", req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !replayed || len(response.DocumentosProcesados) != 1 {
+		t.Errorf("expected replayed response, replayed=%v response=%+v", replayed, response)
+	}
+	if calls != 1 {
+		t.Errorf("expected provider to be called once, got %d", calls)
+	}
+}
@@ -0,0 +1,157 @@
+package invoice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// WithLedger enables recording of every registered document in the documents ledger.
+// Ledger failures are logged and never interrupt the registration flow.
+func (s *Service) WithLedger(repo document.Repository, log *slog.Logger) *Service {
+	s.ledger = repo
+	s.ledgerLog = log
+	return s
+}
+
+// ledgerKey builds the ledger key for a document of the given type.
+func ledgerKey(doc invoice.OpenETLDocument, documentType string) document.Key {
+	return document.Key{
+		OfeIdentificacion: identification.Base(doc.OfeIdentificacion),
+		Tipo:              documentType,
+		Prefijo:           doc.RfaPrefijo,
+		Consecutivo:       doc.CdoConsecutivo,
+	}
+}
+
+// documentPayload returns the JSON payload of a document and its SHA-256 hex digest.
+func documentPayload(doc invoice.OpenETLDocument) ([]byte, string, error) {
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(payload)
+	return payload, hex.EncodeToString(sum[:]), nil
+}
+
+// ledgerIndex maps prefijo/consecutivo pairs of a batch to their ledger keys, so results
+// returned by the provider (which do not carry the OFE) can be matched back. The numbers
+// are unique within a request (see rejectRepeatedNumbers), so the OFE is not needed.
+type ledgerIndex map[string]document.Key
+
+func newLedgerIndex(documents []invoice.OpenETLDocument, documentType string) ledgerIndex {
+	idx := make(ledgerIndex, len(documents))
+	for _, doc := range documents {
+		idx[doc.RfaPrefijo+"|"+doc.CdoConsecutivo] = ledgerKey(doc, documentType)
+	}
+	return idx
+}
+
+func (idx ledgerIndex) lookup(prefijo, consecutivo string) (document.Key, bool) {
+	key, ok := idx[prefijo+"|"+consecutivo]
+	return key, ok
+}
+
+// recordEnriched moves validated documents to the given status, storing the enriched payload.
+func (s *Service) recordEnriched(ctx context.Context, documents []invoice.OpenETLDocument, documentType string, status document.Status) {
+	if s.ledger == nil {
+		return
+	}
+	for _, doc := range documents {
+		key := ledgerKey(doc, documentType)
+		update := document.StatusUpdate{Estado: status}
+		if status == document.StatusValidated {
+			payload, err := json.Marshal(doc)
+			if err != nil {
+				s.logLedgerError("marshal enriched payload", key, err)
+			} else {
+				update.EnrichedPayload = payload
+			}
+		}
+		s.updateLedger(ctx, key, update)
+	}
+}
+
+// recordFailed marks documents as failed (local validation) or rejected (provider/DIAN).
+func (s *Service) recordFailed(ctx context.Context, idx ledgerIndex, failed []invoice.FailedDocument, status document.Status) {
+	if s.ledger == nil {
+		return
+	}
+	for _, f := range failed {
+		key, ok := idx.lookup(f.Prefijo, f.Consecutivo)
+		if !ok {
+			continue
+		}
+		errs := f.Errors
+		if errs == nil {
+			errs = []string{}
+		}
+		s.updateLedger(ctx, key, document.StatusUpdate{Estado: status, Errors: errs})
+	}
+}
+
+// recordAccepted stores the DIAN result (CUFE/CUDE, cdo_id, XML and PDF) of processed documents.
+func (s *Service) recordAccepted(ctx context.Context, idx ledgerIndex, processed []invoice.ProcessedDocument) {
+	if s.ledger == nil {
+		return
+	}
+	for _, p := range processed {
+		key, ok := idx.lookup(p.RfaPrefijo, p.CdoConsecutivo)
+		if !ok {
+			continue
+		}
+		update := document.StatusUpdate{
+			Estado:        document.StatusAccepted,
+			CUFE:          p.CUFE,
+			CUFECalculado: p.CUFECalculado,
+			XmlBase64:     p.XmlBase64,
+			PdfBase64:     p.PdfBase64,
+		}
+		if p.CdoID != 0 {
+			cdoID := p.CdoID
+			update.CdoID = &cdoID
+		}
+		s.updateLedger(ctx, key, update)
+	}
+}
+
+func (s *Service) updateLedger(ctx context.Context, key document.Key, update document.StatusUpdate) {
+	if err := s.ledger.UpdateStatus(ctx, key, update); err != nil {
+		s.logLedgerError("update document status", key, err)
+	}
+}
+
+func (s *Service) logLedgerError(operation string, key document.Key, err error) {
+	if s.ledgerLog == nil {
+		return
+	}
+	s.ledgerLog.Warn("Documents ledger operation failed",
+		"operation", operation,
+		"ofe", key.OfeIdentificacion,
+		"tipo", key.Tipo,
+		"prefijo", key.Prefijo,
+		"consecutivo", key.Consecutivo,
+		"error", err,
+	)
+}
+
+// recordProviderError keeps sent documents in the sent state, storing the provider error.
+// The outcome at DIAN is unknown when the provider call itself fails; a retry looks the
+// document up at the provider before sending it again.
+func (s *Service) recordProviderError(ctx context.Context, documents []invoice.OpenETLDocument, documentType string, providerErr error) {
+	if s.ledger == nil {
+		return
+	}
+	for _, doc := range documents {
+		s.updateLedger(ctx, ledgerKey(doc, documentType), document.StatusUpdate{
+			Estado: document.StatusSent,
+			Errors: []string{providerErr.Error()},
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func newLedgerTestDocument(consecutivo string) invoice.OpenETLDocument {
+	return invoice.OpenETLDocument{
+		TdeCodigo:            "01",
+		OfeIdentificacion:    "860011153-6",
+		AdqIdentificacion:    "900123456",
+		RfaPrefijo:           "SETT",
+		RfaResolucion:        "18760000001",
+		CdoConsecutivo:       consecutivo,
+		CdoFecha:             getTodayDate(),
+		CdoHora:              "14:37:00",
+		MonCodigo:            "COP",
+		CdoValorSinImpuestos: "100000.00",
+		CdoImpuestos:         "19000.00",
+		CdoTotal:             "119000.00",
+		Items: []invoice.OpenETLItem{
+			{DdoSecuencia: "1", DdoCantidad: "1", DdoValorUnitario: "100000.00", DdoTotal: "100000.00"},
+		},
+	}
+}
+
+func ledgerTestKey(consecutivo string) document.Key {
+	return document.Key{OfeIdentificacion: "860011153", Tipo: "FC", Prefijo: "SETT", Consecutivo: consecutivo}
+}
+
+func TestService_RegisterDocument_RecordsLedger(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			return &invoice.DocumentRegistrationResponse{
+				DocumentosProcesados: []invoice.ProcessedDocument{
+					{CdoID: 77, RfaPrefijo: "SETT", CdoConsecutivo: "1", CUFE: "cufe-1", XmlBase64: "PFhNTD4="},
+				},
+				DocumentosFallidos: []invoice.FailedDocument{
+					{Documento: "FC", Prefijo: "SETT", Consecutivo: "2", Errors: []string{"FAD06: CUFE inválido"}},
+				},
+			}, nil
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+
+	_, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{
+			FC: []invoice.OpenETLDocument{newLedgerTestDocument("1"), newLedgerTestDocument("2")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	accepted, _ := ledger.FindByKey(context.Background(), ledgerTestKey("1"))
+	if accepted == nil {
+		t.Fatal("expected accepted document in ledger")
+	}
+	if accepted.Estado != document.StatusAccepted {
+		t.Errorf("expected status %q, got %q", document.StatusAccepted, accepted.Estado)
+	}
+	if accepted.CUFE != "cufe-1" || accepted.CdoID == nil || *accepted.CdoID != 77 || accepted.XmlBase64 == "" {
+		t.Errorf("expected DIAN result to be stored, got %+v", accepted)
+	}
+	if len(accepted.OriginalPayload) == 0 || len(accepted.EnrichedPayload) == 0 {
+		t.Error("expected original and enriched payloads to be stored")
+	}
+
+	rejected, _ := ledger.FindByKey(context.Background(), ledgerTestKey("2"))
+	if rejected == nil {
+		t.Fatal("expected rejected document in ledger")
+	}
+	if rejected.Estado != document.StatusRejected {
+		t.Errorf("expected status %q, got %q", document.StatusRejected, rejected.Estado)
+	}
+	if len(rejected.Errors) != 1 {
+		t.Errorf("expected rejection errors to be stored, got %v", rejected.Errors)
+	}
+}
+
+func TestService_RegisterDocument_LedgerValidationFailure(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+
+	doc := newLedgerTestDocument("3")
+	doc.Items = nil
+
+	resp, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{doc}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.DocumentosFallidos) != 1 || len(resp.DocumentosProcesados) != 0 {
+		t.Fatalf("expected the document to fail validation, got %+v", resp)
+	}
+
+	failed, _ := ledger.FindByKey(context.Background(), ledgerTestKey("3"))
+	if failed == nil || failed.Estado != document.StatusFailed {
+		t.Fatalf("expected failed document in ledger, got %+v", failed)
+	}
+}
+
+func TestService_RegisterDocument_LedgerProviderError(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			return nil, errors.New("provider unavailable")
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+
+	_, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("4")}},
+	})
+	if err == nil {
+		t.Fatal("expected provider error")
+	}
+
+	sent, _ := ledger.FindByKey(context.Background(), ledgerTestKey("4"))
+	if sent == nil || sent.Estado != document.StatusSent {
+		t.Fatalf("expected document to remain sent, got %+v", sent)
+	}
+	if len(sent.Errors) != 1 {
+		t.Errorf("expected provider error to be stored, got %v", sent.Errors)
+	}
+}
+
+func TestService_RegisterDocument_LedgerErrorsDoNotInterrupt(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	ledger.SaveErr = errors.New("database down")
+	ledger.UpdateErr = errors.New("database down")
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			return &invoice.DocumentRegistrationResponse{
+				DocumentosProcesados: []invoice.ProcessedDocument{{CdoID: 1, RfaPrefijo: "SETT", CdoConsecutivo: "5"}},
+			}, nil
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+
+	response, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("5")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(response.DocumentosProcesados) != 1 {
+		t.Errorf("expected 1 processed document, got %d", len(response.DocumentosProcesados))
+	}
+}
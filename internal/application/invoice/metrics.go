@@ -0,0 +1,20 @@
+package invoice
+
+// DocumentMetrics records the results of the registration requests.
+type DocumentMetrics interface {
+	ObserveDocuments(documentType string, processed, contingency, failed int)
+}
+
+// WithMetrics records the documents processed, stored in contingency and failed per
+// document type on every registration request.
+func (s *Service) WithMetrics(m DocumentMetrics) *Service {
+	s.metrics = m
+	return s
+}
+
+// observeDocuments records the results of a registration request, if metrics are enabled.
+func (s *Service) observeDocuments(documentType string, processed, contingency, failed int) {
+	if s.metrics != nil {
+		s.metrics.ObserveDocuments(documentType, processed, contingency, failed)
+	}
+}
@@ -0,0 +1,92 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+// documentCounts is the result observed for a document type.
+type documentCounts struct {
+	processed, contingency, failed int
+}
+
+// recordingDocumentMetrics accumulates the observed results by document type.
+type recordingDocumentMetrics struct {
+	counts map[string]documentCounts
+}
+
+func (m *recordingDocumentMetrics) ObserveDocuments(documentType string, processed, contingency, failed int) {
+	if m.counts == nil {
+		m.counts = make(map[string]documentCounts)
+	}
+	c := m.counts[documentType]
+	m.counts[documentType] = documentCounts{c.processed + processed, c.contingency + contingency, c.failed + failed}
+}
+
+func TestService_RegisterDocument_ObservesDocuments(t *testing.T) {
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			return &invoice.DocumentRegistrationResponse{
+				DocumentosProcesados: []invoice.ProcessedDocument{{RfaPrefijo: "SETT", CdoConsecutivo: "1"}},
+			}, nil
+		},
+	}
+	metrics := &recordingDocumentMetrics{}
+	service := NewService(mockProvider, nil, nil, "2").WithMetrics(metrics)
+
+	invalid := newLedgerTestDocument("2")
+	invalid.Items = nil
+	_, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1"), invalid}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := metrics.counts["FC"]; got != (documentCounts{processed: 1, failed: 1}) {
+		t.Errorf("expected one processed and one failed invoice, got %+v", got)
+	}
+}
+
+func TestService_RegisterDocument_ObservesProviderError(t *testing.T) {
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			return nil, errors.New("provider unavailable")
+		},
+	}
+	metrics := &recordingDocumentMetrics{}
+	service := NewService(mockProvider, nil, nil, "2").WithMetrics(metrics)
+
+	_, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1")}},
+	})
+	if err == nil {
+		t.Fatal("expected provider error")
+	}
+
+	if got := metrics.counts["FC"]; got != (documentCounts{failed: 1}) {
+		t.Errorf("expected the document counted as failed, got %+v", got)
+	}
+}
+
+func TestService_RegisterDocument_ObservesContingency(t *testing.T) {
+	metrics := &recordingDocumentMetrics{}
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").
+		WithContingency(&stubContingencyQueue{active: true}).
+		WithMetrics(metrics)
+
+	_, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := metrics.counts["FC"]; got != (documentCounts{contingency: 1}) {
+		t.Errorf("expected the document counted in contingency, got %+v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/ofe"
+)
+
+// WithOFERegistry enables the OFE registry. Documents are completed with the
+// registered issuer data and documents from unregistered or inactive OFEs are rejected.
+// Without it (no database) the issuer is not checked and the documents are sent with
+// the issuer data of the request, which the caller then has to provide in full.
+func (s *Service) WithOFERegistry(repo ofe.Repository) *Service {
+	s.ofeRepo = repo
+	return s
+}
+
+// ofeResolver looks up OFEs in the registry, caching the results for the
+// duration of a request. A nil resolver or repository resolves every OFE to nil.
+type ofeResolver struct {
+	repo  ofe.Repository
+	cache sync.Map // key = normalized NIT, value = *ofe.OFE
+}
+
+func newOFEResolver(repo ofe.Repository) *ofeResolver {
+	if repo == nil {
+		return nil
+	}
+	return &ofeResolver{repo: repo}
+}
+
+// resolve returns the registered OFE issuing the document.
+// For DS documents the issuer is adq_identificacion (inverted mapping).
+func (r *ofeResolver) resolve(ctx context.Context, doc invoice.OpenETLDocument, documentType string) (*ofe.OFE, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	issuer := doc.OfeIdentificacion
+	if documentType == "DS" {
+		issuer = doc.AdqIdentificacion
+	}
+	nit := identification.Base(issuer)
+
+	if cached, ok := r.cache.Load(nit); ok {
+		return cached.(*ofe.OFE), nil
+	}
+
+	o, err := r.repo.FindByIdentificacion(ctx, nit)
+	if err != nil {
+		return nil, fmt.Errorf("Error al buscar OFE: %w", err)
+	}
+	if o == nil {
+		return nil, fmt.Errorf("OFE [%s] no registrado", nit)
+	}
+	if !o.IsActive() {
+		return nil, fmt.Errorf("OFE [%s] inactivo", nit)
+	}
+
+	r.cache.Store(nit, o)
+	return o, nil
+}
+
+// enrichDocumentWithOFE completes a document with the issuer data of the OFE registry,
+// overriding any values provided in the request. The OFE default environment is applied
+// when the document does not set cdo_ambiente. A nil OFE, which the resolver returns for
+// every document when the registry is disabled, leaves the document untouched on purpose:
+// the request then carries the issuer data, and main logs a warning at startup.
+func enrichDocumentWithOFE(doc invoice.OpenETLDocument, o *ofe.OFE) invoice.OpenETLDocument {
+	if o == nil {
+		return doc
+	}
+
+	razonSocial := o.OfeRazonSocial
+	direccion := o.OfeDireccion
+	munCodigo := o.MunCodigo
+	munNombre := o.MunNombre
+	depCodigo := o.DepCodigo
+	depNombre := o.DepNombre
+
+	doc.OfeRazonSocial = &razonSocial
+	doc.OfeDireccion = &direccion
+	doc.OfeMunicipioCodigo = &munCodigo
+	doc.OfeMunicipioNombre = &munNombre
+	doc.OfeDepartamentoCodigo = &depCodigo
+	doc.OfeDepartamentoNombre = &depNombre
+	doc.OfeCpoCodigo = o.CpoCodigo
+	doc.OfeCorreo = o.OfeCorreo
+	doc.OfeRefCodigo = o.RefCodigo
+
+	if (doc.CdoAmbiente == nil || *doc.CdoAmbiente == "") && o.CdoAmbiente != nil && *o.CdoAmbiente != "" {
+		ambiente := *o.CdoAmbiente
+		doc.CdoAmbiente = &ambiente
+	}
+
+	return doc
+}
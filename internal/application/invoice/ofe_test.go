@@ -0,0 +1,142 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/acquirer"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/ofe"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func newTestOFE(nit string) ofe.OFE {
+	ambiente := "1"
+	correo := "facturacion@ofe.co"
+	return ofe.OFE{
+		OfeIdentificacion: nit,
+		OfeRazonSocial:    "OFE de Prueba SAS",
+		TdoCodigo:         "31",
+		TojCodigo:         "1",
+		OfeDireccion:      "CRA 7 # 71-21",
+		PaiCodigo:         "CO",
+		DepCodigo:         "11",
+		DepNombre:         "BOGOTÁ, D.C.",
+		MunCodigo:         "11001",
+		MunNombre:         "BOGOTÁ, D.C.",
+		OfeCorreo:         &correo,
+		RefCodigo:         []string{"O-13", "O-15"},
+		CdoAmbiente:       &ambiente,
+		Estado:            ofe.EstadoActivo,
+	}
+}
+
+func TestService_EnrichDocuments_OFERegistry(t *testing.T) {
+	inactive := newTestOFE("900000002")
+	inactive.Estado = ofe.EstadoInactivo
+	repo := testutil.NewMockOFERepository(newTestOFE("860011153"), inactive)
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").WithOFERegistry(repo)
+
+	registered := newLedgerTestDocument("1")
+	registered.OfeRazonSocial = strPtr("Razón social del request")
+	unregistered := newLedgerTestDocument("2")
+	unregistered.OfeIdentificacion = "900000001-1"
+	inactiveDoc := newLedgerTestDocument("3")
+	inactiveDoc.OfeIdentificacion = "900000002"
+	withEnv := newLedgerTestDocument("4")
+	withEnv.CdoAmbiente = strPtr("2")
+
+	valid, failed := service.enrichDocuments(context.Background(), []invoice.OpenETLDocument{registered, unregistered, inactiveDoc, withEnv}, "FC")
+
+	if len(valid) != 2 {
+		t.Fatalf("expected 2 valid documents, got %d", len(valid))
+	}
+	doc := valid[0]
+	if *doc.OfeRazonSocial != "OFE de Prueba SAS" || *doc.OfeMunicipioCodigo != "11001" || *doc.OfeCorreo != "facturacion@ofe.co" {
+		t.Errorf("expected document to be enriched from the registry, got %+v", doc)
+	}
+	if strings.Join(doc.OfeRefCodigo, ";") != "O-13;O-15" {
+		t.Errorf("unexpected tax responsibilities: %v", doc.OfeRefCodigo)
+	}
+	if *doc.CdoAmbiente != "1" {
+		t.Errorf("expected OFE default environment, got %s", *doc.CdoAmbiente)
+	}
+	if *valid[1].CdoAmbiente != "2" {
+		t.Errorf("expected document environment to prevail, got %s", *valid[1].CdoAmbiente)
+	}
+
+	if len(failed) != 2 {
+		t.Fatalf("expected 2 failed documents, got %+v", failed)
+	}
+	if !strings.Contains(failed[0].Errors[0], "OFE [900000001] no registrado") {
+		t.Errorf("unexpected error: %v", failed[0].Errors)
+	}
+	if !strings.Contains(failed[1].Errors[0], "OFE [900000002] inactivo") {
+		t.Errorf("unexpected error: %v", failed[1].Errors)
+	}
+	if repo.Lookups != 3 {
+		t.Errorf("expected registered OFE lookup to be cached, got %d lookups", repo.Lookups)
+	}
+}
+
+func TestService_EnrichDocuments_OFERegistryDS(t *testing.T) {
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").
+		WithOFERegistry(testutil.NewMockOFERepository(newTestOFE("860011153")))
+
+	// DS documents are issued by adq_identificacion
+	doc := newLedgerTestDocument("1")
+	doc.OfeIdentificacion = "900123456"
+	doc.AdqIdentificacion = "860011153-6"
+
+	valid, failed := service.enrichDocuments(context.Background(), []invoice.OpenETLDocument{doc}, "DS")
+	if len(failed) != 0 || len(valid) != 1 {
+		t.Fatalf("expected DS document to be accepted, got failed %+v", failed)
+	}
+	if *valid[0].OfeRazonSocial != "OFE de Prueba SAS" {
+		t.Errorf("expected DS issuer data from the registry, got %s", *valid[0].OfeRazonSocial)
+	}
+}
+
+func TestDocumentWorkerPool_OFERegistry(t *testing.T) {
+	repo := testutil.NewMockOFERepository(newTestOFE("860011153"))
+	acquirers := &stubAcquirerRepository{}
+	pool := NewDocumentWorkerPool(context.Background(), 2, acquirers, nil, "2").WithOFERegistry(repo)
+
+	unregistered := newLedgerTestDocument("2")
+	unregistered.OfeIdentificacion = "900000001"
+	valid, failed := pool.ProcessDocuments(context.Background(), []invoice.OpenETLDocument{newLedgerTestDocument("1"), unregistered}, "FC")
+
+	if len(valid) != 1 || len(failed) != 1 {
+		t.Fatalf("expected 1 valid and 1 failed document, got %d/%d", len(valid), len(failed))
+	}
+	if *valid[0].OfeRazonSocial != "OFE de Prueba SAS" || *valid[0].CdoAmbiente != "1" {
+		t.Errorf("expected OFE enrichment to survive acquirer enrichment, got %+v", valid[0])
+	}
+	if !strings.Contains(failed[0].Errors[0], "no registrado") {
+		t.Errorf("unexpected error: %v", failed[0].Errors)
+	}
+}
+
+func TestService_EnrichDocuments_OFERegistryError(t *testing.T) {
+	repo := testutil.NewMockOFERepository()
+	repo.FindErr = errors.New("conexión rechazada")
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").WithOFERegistry(repo)
+
+	_, failed := service.enrichDocuments(context.Background(), []invoice.OpenETLDocument{newLedgerTestDocument("1")}, "FC")
+	if len(failed) != 1 || !strings.Contains(failed[0].Errors[0], "Error al buscar OFE") {
+		t.Errorf("expected lookup error to fail the document, got %+v", failed)
+	}
+}
+
+// stubAcquirerRepository finds every acquirer; other methods are not used.
+type stubAcquirerRepository struct {
+	acquirer.Repository
+}
+
+func (r *stubAcquirerRepository) FindByID(ctx context.Context, ofeIdentificacion, adqIdentificacion, adqIdPersonalizado string) (*acquirer.Acquirer, error) {
+	return &acquirer.Acquirer{OfeIdentificacion: ofeIdentificacion, AdqIdentificacion: adqIdentificacion, AdqRazonSocial: "Adquiriente de Prueba"}, nil
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,77 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// WithRenderer enables the UBL XML preview of documents.
+func (s *Service) WithRenderer(renderer invoice.DocumentRenderer) *Service {
+	s.renderer = renderer
+	return s
+}
+
+// PreviewDocuments validates and enriches documents exactly as RegisterDocument does and
+// returns their UBL XML. Nothing is sent to the provider nor recorded in the documents ledger.
+func (s *Service) PreviewDocuments(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentPreviewResponse, error) {
+	if s.renderer == nil {
+		return nil, fmt.Errorf("la previsualización de XML no está habilitada")
+	}
+
+	documents, documentType, err := documentsByType(req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	fechaProcesamiento := now.Format("2006-01-02")
+	horaProcesamiento := now.Format("15:04:05")
+	failed := func(doc invoice.OpenETLDocument, err error) invoice.FailedDocument {
+		return invoice.FailedDocument{
+			Documento:          documentType,
+			Consecutivo:        doc.CdoConsecutivo,
+			Prefijo:            doc.RfaPrefijo,
+			Errors:             []string{err.Error()},
+			FechaProcesamiento: fechaProcesamiento,
+			HoraProcesamiento:  horaProcesamiento,
+		}
+	}
+
+	response := &invoice.DocumentPreviewResponse{
+		Documentos:         make([]invoice.PreviewDocument, 0, len(documents)),
+		DocumentosFallidos: make([]invoice.FailedDocument, 0),
+	}
+
+	// A document that fails validation does not abort the whole preview
+	var validated []invoice.OpenETLDocument
+	for idx, doc := range documents {
+		if err := s.validateDocument(doc, documentType, idx); err != nil {
+			response.DocumentosFallidos = append(response.DocumentosFallidos, failed(doc, err))
+			continue
+		}
+		validated = append(validated, doc)
+	}
+
+	enriched, failedDocuments := s.enrichDocuments(ctx, validated, documentType)
+	response.DocumentosFallidos = append(response.DocumentosFallidos, failedDocuments...)
+
+	for _, doc := range enriched {
+		xmlDoc, code, err := s.renderer.Render(doc, documentType)
+		if err != nil {
+			response.DocumentosFallidos = append(response.DocumentosFallidos, failed(doc, err))
+			continue
+		}
+		response.Documentos = append(response.Documentos, invoice.PreviewDocument{
+			Documento:      documentType,
+			RfaPrefijo:     doc.RfaPrefijo,
+			CdoConsecutivo: doc.CdoConsecutivo,
+			CUFE:           code,
+			XML:            string(xmlDoc),
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,85 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+type fakeRenderer struct {
+	rendered []invoice.OpenETLDocument
+	err      error
+}
+
+func (r *fakeRenderer) Render(doc invoice.OpenETLDocument, documentType string) ([]byte, string, error) {
+	if r.err != nil {
+		return nil, "", r.err
+	}
+	r.rendered = append(r.rendered, doc)
+	return []byte("<Invoice>" + documentType + doc.CdoConsecutivo + "</Invoice>"), "cufe-" + doc.CdoConsecutivo, nil
+}
+
+func TestService_PreviewDocuments(t *testing.T) {
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			t.Fatal("preview must not call the provider")
+			return nil, nil
+		},
+	}
+	renderer := &fakeRenderer{}
+	service := NewService(mockProvider, nil, nil, "2").WithRenderer(renderer).
+		WithOFERegistry(testutil.NewMockOFERepository(newTestOFE("860011153")))
+
+	invalid := newLedgerTestDocument("3")
+	invalid.TdeCodigo = "91"
+	response, err := service.PreviewDocuments(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1"), newLedgerTestDocument("2"), invalid}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(response.Documentos) != 2 {
+		t.Fatalf("expected 2 rendered documents, got %d", len(response.Documentos))
+	}
+	if got := response.Documentos[0]; got.XML != "<Invoice>FC1</Invoice>" || got.CUFE != "cufe-1" || got.Documento != "FC" {
+		t.Errorf("unexpected preview: %+v", got)
+	}
+	if len(response.DocumentosFallidos) != 1 || response.DocumentosFallidos[0].Consecutivo != "3" {
+		t.Errorf("expected invalid document to be reported as failed, got %+v", response.DocumentosFallidos)
+	}
+
+	// Documents are enriched before rendering
+	if renderer.rendered[0].OfeRazonSocial == nil || renderer.rendered[0].CdoAmbiente == nil {
+		t.Error("expected rendered document to be enriched with OFE and environment data")
+	}
+}
+
+func TestService_PreviewDocuments_RenderError(t *testing.T) {
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").WithRenderer(&fakeRenderer{err: errors.New("cdo_total inválido")})
+
+	response, err := service.PreviewDocuments(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(response.Documentos) != 0 || len(response.DocumentosFallidos) != 1 {
+		t.Fatalf("expected render failure to be reported, got %+v", response)
+	}
+	if !strings.Contains(response.DocumentosFallidos[0].Errors[0], "cdo_total") {
+		t.Errorf("unexpected errors: %v", response.DocumentosFallidos[0].Errors)
+	}
+}
+
+func TestService_PreviewDocuments_Disabled(t *testing.T) {
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2")
+	_, err := service.PreviewDocuments(context.Background(), invoice.DocumentRegistrationRequest{})
+	if err == nil || !strings.Contains(err.Error(), "no está habilitada") {
+		t.Errorf("expected disabled error, got %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/currency"
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/totals"
+)
+
+// annulmentConcept is the correction concept of a credit note that annuls the invoice.
+const annulmentConcept = "2"
+
+// WithNotesPeriod sets the invoicing period of the credit and debit notes without invoice
+// reference that do not send cdo_periodo_facturacion (NUMROT_NC_INVOICE_PERIOD_*).
+func (s *Service) WithNotesPeriod(period invoice.OpenETLPeriodoFacturacion) *Service {
+	s.notesPeriod = &period
+	return s
+}
+
+// referenceNumber returns the number (prefijo + consecutivo) of the invoice corrected by a
+// note, or "" when the note has no invoice reference.
+func referenceNumber(doc invoice.OpenETLDocument) string {
+	if doc.FacturaReferencia == nil || doc.FacturaReferencia.NumeroFacturaFC == "" {
+		return ""
+	}
+	return doc.FacturaReferencia.PrefijoFC + doc.FacturaReferencia.NumeroFacturaFC
+}
+
+// referencedInvoice is the invoice corrected by a note.
+type referencedInvoice struct {
+	cufe       string
+	fecha      string   // Issue date (YYYY-MM-DD)
+	total      *big.Rat // nil when unknown
+	moneda     string   // Empty when unknown
+	adquirente string   // Identification of the acquirer without DV, empty when unknown
+}
+
+// checkReferences resolves the invoice corrected by each NC/ND, fills its CUFE and issue
+// date and enforces the correction rules: the invoice must exist, belong to the same OFE
+// and acquirer and, for credit notes, the notes may not exceed its total. Notes without
+// invoice reference must carry the corrected period. FC and DS documents are not changed.
+func (s *Service) checkReferences(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.FailedDocument) {
+	if documentType != "NC" && documentType != "ND" {
+		return documents, nil
+	}
+
+	now := time.Now()
+	fechaProcesamiento := now.Format("2006-01-02")
+	horaProcesamiento := now.Format("15:04:05")
+
+	valid := make([]invoice.OpenETLDocument, 0, len(documents))
+	var failed []invoice.FailedDocument
+
+	// Credit notes of this batch already accepted for each invoice
+	credited := make(map[string]*big.Rat)
+	for _, doc := range documents {
+		doc, errs := s.checkReference(ctx, doc, documentType, credited)
+		if len(errs) > 0 {
+			failed = append(failed, invoice.FailedDocument{
+				Documento:          documentType,
+				Consecutivo:        doc.CdoConsecutivo,
+				Prefijo:            doc.RfaPrefijo,
+				Errors:             errs,
+				FechaProcesamiento: fechaProcesamiento,
+				HoraProcesamiento:  horaProcesamiento,
+			})
+			continue
+		}
+		valid = append(valid, doc)
+	}
+
+	return valid, failed
+}
+
+// checkReference checks the invoice reference of a note and returns the note completed
+// with the data of the invoice.
+func (s *Service) checkReference(ctx context.Context, doc invoice.OpenETLDocument, documentType string, credited map[string]*big.Rat) (invoice.OpenETLDocument, []string) {
+	number := referenceNumber(doc)
+	if number == "" {
+		return s.checkNotePeriod(doc)
+	}
+
+	var errs []string
+	if doc.CdoConceptosCorreccion == nil || doc.CdoConceptosCorreccion.CcoCodigo == "" {
+		errs = append(errs, "cdo_conceptos_correccion.cco_codigo es requerido para notas con factura referenciada")
+	} else if strings.TrimSpace(doc.CdoConceptosCorreccion.CdoObservacionCorreccion) == "" {
+		errs = append(errs, "cdo_conceptos_correccion.cdo_observacion_correccion es requerido para notas con factura referenciada")
+	}
+
+	fc, err := s.referencedInvoice(ctx, doc, number)
+	if err != nil {
+		return doc, append(errs, err.Error())
+	}
+
+	ref := *doc.FacturaReferencia
+	if ref.CufeFC == "" {
+		ref.CufeFC = fc.cufe
+	} else if fc.cufe != "" && !strings.EqualFold(ref.CufeFC, fc.cufe) {
+		errs = append(errs, fmt.Sprintf("cufe_fc no corresponde al CUFE de la factura referenciada %s", number))
+	}
+	if ref.FechaEmisionFC == "" {
+		ref.FechaEmisionFC = fc.fecha
+	} else if fc.fecha != "" && ref.FechaEmisionFC != fc.fecha {
+		errs = append(errs, fmt.Sprintf("fecha_emision_fc (%s) no corresponde a la fecha de la factura referenciada %s (%s)", ref.FechaEmisionFC, number, fc.fecha))
+	}
+	doc.FacturaReferencia = &ref
+
+	if fc.adquirente != "" && identification.Base(doc.AdqIdentificacion) != fc.adquirente {
+		errs = append(errs, fmt.Sprintf("adq_identificacion [%s] no corresponde al adquirente de la factura referenciada %s [%s]", doc.AdqIdentificacion, number, fc.adquirente))
+	}
+	if fc.moneda != "" && currency.Normalize(doc.MonCodigo) != currency.Normalize(fc.moneda) {
+		errs = append(errs, fmt.Sprintf("mon_codigo [%s] no corresponde a la moneda de la factura referenciada %s [%s]", doc.MonCodigo, number, fc.moneda))
+	}
+	if fc.fecha != "" && doc.CdoFecha < fc.fecha {
+		errs = append(errs, fmt.Sprintf("cdo_fecha (%s) no puede ser anterior a la fecha de la factura referenciada %s (%s)", doc.CdoFecha, number, fc.fecha))
+	}
+
+	if documentType == "NC" && fc.total != nil && len(errs) == 0 {
+		errs = s.checkCreditedAmount(ctx, doc, number, fc.total, credited)
+	}
+	return doc, errs
+}
+
+// checkNotePeriod checks the invoicing period of a note without invoice reference, taking
+// the configured period when the note does not send one.
+func (s *Service) checkNotePeriod(doc invoice.OpenETLDocument) (invoice.OpenETLDocument, []string) {
+	if doc.CdoPeriodoFacturacion == nil {
+		if s.notesPeriod == nil {
+			return doc, []string{"cdo_periodo_facturacion es requerido para notas sin factura referenciada"}
+		}
+		period := *s.notesPeriod
+		doc.CdoPeriodoFacturacion = &period
+		return doc, nil
+	}
+
+	p := doc.CdoPeriodoFacturacion
+	start, startErr := time.Parse("2006-01-02", p.FechaInicio)
+	end, endErr := time.Parse("2006-01-02", p.FechaFin)
+	if startErr != nil || endErr != nil {
+		return doc, []string{"cdo_periodo_facturacion: fecha_inicio y fecha_fin deben tener el formato AAAA-MM-DD"}
+	}
+	if end.Before(start) {
+		return doc, []string{fmt.Sprintf("cdo_periodo_facturacion: fecha_fin (%s) debe ser igual o posterior a fecha_inicio (%s)", p.FechaFin, p.FechaInicio)}
+	}
+	return doc, nil
+}
+
+// referencedInvoice resolves the invoice corrected by a note from the documents ledger or,
+// when it was not registered through this service, from the provider.
+func (s *Service) referencedInvoice(ctx context.Context, doc invoice.OpenETLDocument, number string) (*referencedInvoice, error) {
+	ofe := identification.Base(doc.OfeIdentificacion)
+
+	if s.ledger != nil {
+		entry, err := s.ledger.FindByKey(ctx, document.Key{
+			OfeIdentificacion: ofe,
+			Tipo:              "FC",
+			Prefijo:           doc.FacturaReferencia.PrefijoFC,
+			Consecutivo:       doc.FacturaReferencia.NumeroFacturaFC,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error al consultar la factura referenciada %s: %w", number, err)
+		}
+		if entry != nil {
+			return ledgerInvoice(entry, number)
+		}
+	}
+
+	documents, err := s.provider.GetDocumentByNumber(ctx, invoice.DocumentByNumberQuery{
+		CompanyNit:     ofe,
+		DocumentNumber: number,
+		SupplierNit:    ofe,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error al consultar la factura referenciada %s: %w", number, err)
+	}
+	for _, d := range documents {
+		if d.Prefijo+d.Consecutivo != number && d.Consecutivo != number {
+			continue
+		}
+		if d.OFE != "" && identification.Base(d.OFE) != ofe {
+			return nil, fmt.Errorf("la factura referenciada %s no pertenece al OFE %s", number, ofe)
+		}
+		fc := &referencedInvoice{cufe: d.CUFE}
+		if !d.Fecha.IsZero() {
+			fc.fecha = d.Fecha.Format("2006-01-02")
+		}
+		if d.Valor > 0 {
+			// The shortest representation is the decimal sent by the provider, without rounding
+			total, err := totals.ParseAmount(strconv.FormatFloat(d.Valor, 'f', -1, 64))
+			if err != nil {
+				return nil, fmt.Errorf("la factura referenciada %s no se puede leer: valor: %w", number, err)
+			}
+			fc.total = total
+		}
+		return fc, nil
+	}
+	return nil, fmt.Errorf("la factura referenciada %s no existe para el OFE %s", number, ofe)
+}
+
+// ledgerInvoice reads the referenced invoice from its ledger entry. Only invoices accepted
+// by the DIAN may be corrected.
+func ledgerInvoice(entry *document.Document, number string) (*referencedInvoice, error) {
+	if entry.Estado != document.StatusAccepted {
+		return nil, fmt.Errorf("la factura referenciada %s no ha sido aceptada por la DIAN (estado: %s)", number, entry.Estado)
+	}
+
+	fc, err := ledgerPayload(entry)
+	if err != nil {
+		return nil, fmt.Errorf("la factura referenciada %s no se puede leer: %w", number, err)
+	}
+	total, err := totals.ParseAmount(fc.CdoTotal)
+	if err != nil {
+		return nil, fmt.Errorf("la factura referenciada %s no se puede leer: cdo_total: %w", number, err)
+	}
+
+	return &referencedInvoice{
+		cufe:       entry.CUFE,
+		fecha:      fc.CdoFecha,
+		total:      total,
+		moneda:     fc.MonCodigo,
+		adquirente: identification.Base(fc.AdqIdentificacion),
+	}, nil
+}
+
+// ledgerPayload decodes the enriched payload of a ledger entry, or the original payload
+// when the document was never enriched.
+func ledgerPayload(entry *document.Document) (invoice.OpenETLDocument, error) {
+	payload := entry.EnrichedPayload
+	if len(payload) == 0 {
+		payload = entry.OriginalPayload
+	}
+	var doc invoice.OpenETLDocument
+	err := json.Unmarshal(payload, &doc)
+	return doc, err
+}
+
+// checkCreditedAmount rejects a credit note that, added to the credit notes already sent
+// for the invoice, exceeds its total. A note with the annulment concept must credit the
+// whole balance of the invoice.
+func (s *Service) checkCreditedAmount(ctx context.Context, doc invoice.OpenETLDocument, number string, total *big.Rat, credited map[string]*big.Rat) []string {
+	amount, err := totals.ParseAmount(doc.CdoTotal)
+	if err != nil {
+		return []string{fmt.Sprintf("cdo_total: %v", err)}
+	}
+
+	previous, err := s.creditedAmount(ctx, doc, number)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	key := identification.Base(doc.OfeIdentificacion) + "|" + number
+	if batch, ok := credited[key]; ok {
+		previous.Add(previous, batch)
+	}
+
+	balance := new(big.Rat).Sub(total, previous)
+	if amount.Cmp(balance) > 0 {
+		return []string{fmt.Sprintf("el total de la nota crédito (%s) supera el saldo de la factura referenciada %s (%s de %s)",
+			amount.FloatString(2), number, balance.FloatString(2), total.FloatString(2))}
+	}
+	if doc.CdoConceptosCorreccion.CcoCodigo == annulmentConcept && amount.Cmp(balance) != 0 {
+		return []string{fmt.Sprintf("el concepto de corrección %s (anulación) requiere que la nota crédito sea por el saldo de la factura referenciada %s (%s)",
+			annulmentConcept, number, balance.FloatString(2))}
+	}
+
+	if credited[key] == nil {
+		credited[key] = new(big.Rat)
+	}
+	credited[key].Add(credited[key], amount)
+	return nil
+}
+
+// creditedAmount adds up the credit notes of an invoice recorded in the ledger that were
+// sent, accepted or stored in contingency, excluding the note itself.
+func (s *Service) creditedAmount(ctx context.Context, doc invoice.OpenETLDocument, number string) (*big.Rat, error) {
+	sum := new(big.Rat)
+	if s.ledger == nil {
+		return sum, nil
+	}
+
+	notes, _, err := s.ledger.List(ctx, document.Filter{
+		OfeIdentificacion: identification.Base(doc.OfeIdentificacion),
+		Tipo:              "NC",
+		Referencia:        number,
+		Length:            -1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error al consultar las notas crédito de la factura %s: %w", number, err)
+	}
+
+	self := ledgerKey(doc, "NC")
+	for i := range notes {
+		note := &notes[i]
+		if note.Key() == self || (note.Estado != document.StatusAccepted && note.Estado != document.StatusSent && note.Estado != document.StatusContingency) {
+			continue
+		}
+		payload, err := ledgerPayload(note)
+		if err != nil {
+			return nil, fmt.Errorf("la nota crédito %s%s de la factura %s no se puede leer: %w", note.Prefijo, note.Consecutivo, number, err)
+		}
+		value, err := totals.ParseAmount(payload.CdoTotal)
+		if err != nil {
+			return nil, fmt.Errorf("la nota crédito %s%s de la factura %s no se puede leer: cdo_total: %w", note.Prefijo, note.Consecutivo, number, err)
+		}
+		sum.Add(sum, value)
+	}
+	return sum, nil
+}
@@ -0,0 +1,248 @@
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+// saveReferenceInvoice records an invoice SETT1 in the ledger.
+func saveReferenceInvoice(t *testing.T, ledger *testutil.MockDocumentRepository, estado document.Status) {
+	t.Helper()
+	fc := newLedgerTestDocument("1")
+	fc.CdoFecha = "2024-01-15"
+	payload, _ := json.Marshal(fc)
+	if _, err := ledger.Save(context.Background(), document.Document{
+		OfeIdentificacion: "860011153",
+		Tipo:              "FC",
+		Prefijo:           "SETT",
+		Consecutivo:       "1",
+		Estado:            estado,
+		CUFE:              "cufe-fc-1",
+		EnrichedPayload:   payload,
+	}); err != nil {
+		t.Fatalf("save invoice: %v", err)
+	}
+}
+
+// newCreditNote returns a credit note of total for the invoice SETT1.
+func newCreditNote(consecutivo, total, concept string) invoice.OpenETLDocument {
+	nc := newLedgerTestDocument(consecutivo)
+	nc.TdeCodigo = "91"
+	nc.RfaPrefijo = "NC"
+	nc.CdoTotal = total
+	nc.FacturaReferencia = &invoice.OpenETLFacturaReferencia{PrefijoFC: "SETT", NumeroFacturaFC: "1"}
+	nc.CdoConceptosCorreccion = &invoice.OpenETLConceptoCorreccion{CcoCodigo: concept, CdoObservacionCorreccion: "Devolución"}
+	return nc
+}
+
+func TestService_CheckReferences_Ledger(t *testing.T) {
+	tests := []struct {
+		name    string
+		estado  document.Status
+		modify  func(nc *invoice.OpenETLDocument)
+		wantErr string
+	}{
+		{name: "valid", estado: document.StatusAccepted},
+		{name: "invoice not accepted", estado: document.StatusRejected, wantErr: "no ha sido aceptada por la DIAN"},
+		{name: "another acquirer", estado: document.StatusAccepted, modify: func(nc *invoice.OpenETLDocument) {
+			nc.AdqIdentificacion = "800111222"
+		}, wantErr: "no corresponde al adquirente"},
+		{name: "wrong CUFE", estado: document.StatusAccepted, modify: func(nc *invoice.OpenETLDocument) {
+			nc.FacturaReferencia.CufeFC = "otro"
+		}, wantErr: "cufe_fc no corresponde"},
+		{name: "exceeds invoice total", estado: document.StatusAccepted, modify: func(nc *invoice.OpenETLDocument) {
+			nc.CdoTotal = "119000.01"
+		}, wantErr: "supera el saldo de la factura referenciada SETT1"},
+		{name: "partial annulment", estado: document.StatusAccepted, modify: func(nc *invoice.OpenETLDocument) {
+			nc.CdoTotal = "1000.00"
+			nc.CdoConceptosCorreccion.CcoCodigo = "2"
+		}, wantErr: "(anulación)"},
+		{name: "missing concept", estado: document.StatusAccepted, modify: func(nc *invoice.OpenETLDocument) {
+			nc.CdoConceptosCorreccion = nil
+		}, wantErr: "cco_codigo es requerido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := testutil.NewMockDocumentRepository()
+			saveReferenceInvoice(t, ledger, tt.estado)
+			service := NewService(&testutil.MockProvider{}, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+
+			nc := newCreditNote("10", "119000.00", "1")
+			if tt.modify != nil {
+				tt.modify(&nc)
+			}
+			valid, failed := service.checkReferences(context.Background(), []invoice.OpenETLDocument{nc}, "NC")
+
+			if tt.wantErr == "" {
+				if len(failed) > 0 {
+					t.Fatalf("unexpected failures %+v", failed)
+				}
+				ref := valid[0].FacturaReferencia
+				if ref.CufeFC != "cufe-fc-1" || ref.FechaEmisionFC != "2024-01-15" {
+					t.Errorf("expected CUFE and issue date of the invoice, got %+v", ref)
+				}
+				return
+			}
+			if len(failed) != 1 || !strings.Contains(strings.Join(failed[0].Errors, "; "), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %+v", tt.wantErr, failed)
+			}
+		})
+	}
+}
+
+func TestService_CheckReferences_CumulativeCredit(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	saveReferenceInvoice(t, ledger, document.StatusAccepted)
+
+	previous := newCreditNote("9", "100000.00", "1")
+	payload, _ := json.Marshal(previous)
+	if _, err := ledger.Save(context.Background(), document.Document{
+		OfeIdentificacion: "860011153",
+		Tipo:              "NC",
+		Prefijo:           "NC",
+		Consecutivo:       "9",
+		Estado:            document.StatusAccepted,
+		Referencia:        "SETT1",
+		EnrichedPayload:   payload,
+	}); err != nil {
+		t.Fatalf("save note: %v", err)
+	}
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+
+	// 100000 already credited: 19000 remain, the batch notes are added up in order
+	valid, failed := service.checkReferences(context.Background(), []invoice.OpenETLDocument{
+		newCreditNote("10", "10000.00", "1"),
+		newCreditNote("11", "9000.00", "1"),
+		newCreditNote("12", "0.01", "1"),
+	}, "NC")
+
+	if len(valid) != 2 {
+		t.Errorf("expected 2 valid notes, got %d", len(valid))
+	}
+	if len(failed) != 1 || failed[0].Consecutivo != "12" || !strings.Contains(failed[0].Errors[0], "(0.00 de 119000.00)") {
+		t.Errorf("expected note 12 to exceed the balance, got %+v", failed)
+	}
+}
+
+func TestService_CheckReferences_ProviderFallback(t *testing.T) {
+	var query invoice.DocumentByNumberQuery
+	mockProvider := &testutil.MockProvider{
+		GetDocumentByNumberFunc: func(ctx context.Context, q invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
+			query = q
+			if q.DocumentNumber != "SETT1" {
+				return []invoice.Document{}, nil
+			}
+			return []invoice.Document{{OFE: "860011153", Prefijo: "SETT", Consecutivo: "1", CUFE: "cufe-numrot", Fecha: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), Valor: 50000}}, nil
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2")
+
+	valid, failed := service.checkReferences(context.Background(), []invoice.OpenETLDocument{newCreditNote("10", "50000.00", "1")}, "NC")
+	if len(failed) > 0 {
+		t.Fatalf("unexpected failures %+v", failed)
+	}
+	if query.CompanyNit != "860011153" || query.SupplierNit != "860011153" {
+		t.Errorf("unexpected query %+v", query)
+	}
+	if ref := valid[0].FacturaReferencia; ref.CufeFC != "cufe-numrot" || ref.FechaEmisionFC != "2024-01-10" {
+		t.Errorf("expected CUFE and issue date from the provider, got %+v", ref)
+	}
+
+	_, failed = service.checkReferences(context.Background(), []invoice.OpenETLDocument{newCreditNote("11", "50000.01", "1")}, "NC")
+	if len(failed) != 1 || !strings.Contains(failed[0].Errors[0], "supera el saldo") {
+		t.Errorf("expected the note to exceed the invoice, got %+v", failed)
+	}
+
+	missing := newCreditNote("12", "1.00", "1")
+	missing.FacturaReferencia.NumeroFacturaFC = "2"
+	_, failed = service.checkReferences(context.Background(), []invoice.OpenETLDocument{missing}, "NC")
+	if len(failed) != 1 || !strings.Contains(failed[0].Errors[0], "la factura referenciada SETT2 no existe") {
+		t.Errorf("expected missing invoice error, got %+v", failed)
+	}
+
+	mockProvider.GetDocumentByNumberFunc = func(ctx context.Context, q invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
+		return nil, errors.New("timeout")
+	}
+	_, failed = service.checkReferences(context.Background(), []invoice.OpenETLDocument{newCreditNote("13", "1.00", "1")}, "NC")
+	if len(failed) != 1 || !strings.Contains(failed[0].Errors[0], "timeout") {
+		t.Errorf("expected provider error, got %+v", failed)
+	}
+}
+
+func TestService_CheckReferences_UnreadableAmounts(t *testing.T) {
+	mockProvider := &testutil.MockProvider{
+		GetDocumentByNumberFunc: func(ctx context.Context, q invoice.DocumentByNumberQuery) ([]invoice.Document, error) {
+			return []invoice.Document{{OFE: "860011153", Prefijo: "SETT", Consecutivo: "1", CUFE: "cufe-numrot", Valor: math.Inf(1)}}, nil
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2")
+	_, failed := service.checkReferences(context.Background(), []invoice.OpenETLDocument{newCreditNote("10", "1.00", "1")}, "NC")
+	if len(failed) != 1 || !strings.Contains(failed[0].Errors[0], "no se puede leer: valor") {
+		t.Errorf("expected the invalid provider amount to fail the note, got %+v", failed)
+	}
+
+	// A credit note of the invoice whose payload cannot be read
+	ledger := testutil.NewMockDocumentRepository()
+	saveReferenceInvoice(t, ledger, document.StatusAccepted)
+	if _, err := ledger.Save(context.Background(), document.Document{
+		OfeIdentificacion: "860011153",
+		Tipo:              "NC",
+		Prefijo:           "NC",
+		Consecutivo:       "9",
+		Estado:            document.StatusAccepted,
+		Referencia:        "SETT1",
+		EnrichedPayload:   []byte("{"),
+	}); err != nil {
+		t.Fatalf("save note: %v", err)
+	}
+	service = NewService(&testutil.MockProvider{}, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+	_, failed = service.checkReferences(context.Background(), []invoice.OpenETLDocument{newCreditNote("10", "1.00", "1")}, "NC")
+	if len(failed) != 1 || !strings.Contains(failed[0].Errors[0], "la nota crédito NC9 de la factura SETT1 no se puede leer") {
+		t.Errorf("expected the unreadable note to fail the validation, got %+v", failed)
+	}
+}
+
+func TestService_CheckReferences_WithoutReference(t *testing.T) {
+	nc := newLedgerTestDocument("10")
+	nc.TdeCodigo = "91"
+
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2")
+	_, failed := service.checkReferences(context.Background(), []invoice.OpenETLDocument{nc}, "NC")
+	if len(failed) != 1 || !strings.Contains(failed[0].Errors[0], "cdo_periodo_facturacion es requerido") {
+		t.Errorf("expected missing period error, got %+v", failed)
+	}
+
+	service.WithNotesPeriod(invoice.OpenETLPeriodoFacturacion{FechaInicio: "2024-01-01", FechaFin: "2024-01-31"})
+	valid, failed := service.checkReferences(context.Background(), []invoice.OpenETLDocument{nc}, "ND")
+	if len(failed) > 0 || valid[0].CdoPeriodoFacturacion == nil || valid[0].CdoPeriodoFacturacion.FechaFin != "2024-01-31" {
+		t.Errorf("expected the configured period, got %+v %+v", valid, failed)
+	}
+
+	nc.CdoPeriodoFacturacion = &invoice.OpenETLPeriodoFacturacion{FechaInicio: "2024-02-01", FechaFin: "2024-01-01"}
+	_, failed = service.checkReferences(context.Background(), []invoice.OpenETLDocument{nc}, "NC")
+	if len(failed) != 1 || !strings.Contains(failed[0].Errors[0], "debe ser igual o posterior a fecha_inicio") {
+		t.Errorf("expected invalid period error, got %+v", failed)
+	}
+}
+
+func TestService_ClaimDocuments_StoresReference(t *testing.T) {
+	ledger := testutil.NewMockDocumentRepository()
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+
+	service.claimDocuments(context.Background(), []invoice.OpenETLDocument{newCreditNote("10", "1.00", "1")}, "NC")
+
+	notes, _, _ := ledger.List(context.Background(), document.Filter{Tipo: "NC", Referencia: "SETT1"})
+	if len(notes) != 1 {
+		t.Errorf("expected the note to be listed by reference, got %+v", notes)
+	}
+}
@@ -0,0 +1,144 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	coreresolution "3tcapital/goclonacion/internal/core/resolution"
+)
+
+// ResolutionTracker validates document numbers against the numbering ranges tracked locally.
+// It is implemented by the resolution application service.
+type ResolutionTracker interface {
+	Validate(ctx context.Context, ofeIdentificacion, resolutionNumber, prefix string, consecutivo int64, date time.Time) (*coreresolution.Range, error)
+	MarkUsed(ctx context.Context, rng *coreresolution.Range, consecutivo int64) error
+}
+
+// WithResolutionTracker enables the numbering range validation. Documents whose consecutivo
+// falls outside the range of their resolution, or whose date falls outside its validity,
+// are rejected; the consecutivos of accepted documents are recorded as used.
+func (s *Service) WithResolutionTracker(tracker ResolutionTracker, log *slog.Logger) *Service {
+	s.resolutions = tracker
+	s.resolutionLog = log
+	return s
+}
+
+// trackedConsecutivo is the range a validated document number belongs to.
+type trackedConsecutivo struct {
+	rng         *coreresolution.Range
+	consecutivo int64
+}
+
+// trackedConsecutivos indexes the validated documents by prefijo and consecutivo.
+type trackedConsecutivos map[string]trackedConsecutivo
+
+// validateResolutions checks every document with a resolution against its numbering range
+// and completes the missing rfa_* fields from it. NC/ND documents without rfa_resolucion
+// are not checked.
+func (s *Service) validateResolutions(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.FailedDocument, trackedConsecutivos) {
+	if s.resolutions == nil {
+		return documents, nil, nil
+	}
+
+	now := time.Now()
+	fechaProcesamiento := now.Format("2006-01-02")
+	horaProcesamiento := now.Format("15:04:05")
+
+	valid := make([]invoice.OpenETLDocument, 0, len(documents))
+	var failed []invoice.FailedDocument
+	tracked := make(trackedConsecutivos, len(documents))
+
+	for _, doc := range documents {
+		if doc.RfaResolucion == "" {
+			valid = append(valid, doc)
+			continue
+		}
+
+		rng, consecutivo, err := s.checkResolution(ctx, doc, documentType)
+		if err != nil {
+			failed = append(failed, invoice.FailedDocument{
+				Documento:          documentType,
+				Consecutivo:        doc.CdoConsecutivo,
+				Prefijo:            doc.RfaPrefijo,
+				Errors:             []string{err.Error()},
+				FechaProcesamiento: fechaProcesamiento,
+				HoraProcesamiento:  horaProcesamiento,
+			})
+			continue
+		}
+
+		tracked[doc.RfaPrefijo+"|"+doc.CdoConsecutivo] = trackedConsecutivo{rng: rng, consecutivo: consecutivo}
+		valid = append(valid, enrichDocumentWithRange(doc, rng))
+	}
+
+	return valid, failed, tracked
+}
+
+// checkResolution validates the document number and date against its resolution.
+// For DS documents the issuer is adq_identificacion (inverted mapping).
+func (s *Service) checkResolution(ctx context.Context, doc invoice.OpenETLDocument, documentType string) (*coreresolution.Range, int64, error) {
+	consecutivo, err := strconv.ParseInt(doc.CdoConsecutivo, 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consecutivo inválido [%s]", doc.CdoConsecutivo)
+	}
+	fecha, err := time.Parse("2006-01-02", doc.CdoFecha)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fecha de documento inválida [%s]", doc.CdoFecha)
+	}
+
+	issuer := doc.OfeIdentificacion
+	if documentType == "DS" {
+		issuer = doc.AdqIdentificacion
+	}
+
+	rng, err := s.resolutions.Validate(ctx, identification.Base(issuer), doc.RfaResolucion, doc.RfaPrefijo, consecutivo, fecha)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rng, consecutivo, nil
+}
+
+// markConsecutivosUsed records the consecutivos of the documents accepted by the provider.
+// Failures are logged: the documents are already registered.
+func (s *Service) markConsecutivosUsed(ctx context.Context, tracked trackedConsecutivos, processed []invoice.ProcessedDocument) {
+	for _, doc := range processed {
+		t, ok := tracked[doc.RfaPrefijo+"|"+doc.CdoConsecutivo]
+		if !ok {
+			continue
+		}
+		if err := s.resolutions.MarkUsed(ctx, t.rng, t.consecutivo); err != nil && s.resolutionLog != nil {
+			s.resolutionLog.Error("Failed to record used consecutivo",
+				"ofe", t.rng.OfeIdentificacion,
+				"resolucion", t.rng.ResolutionNumber,
+				"prefijo", doc.RfaPrefijo,
+				"consecutivo", doc.CdoConsecutivo,
+				"error", err)
+		}
+	}
+}
+
+// enrichDocumentWithRange completes the missing rfa_* fields with the data of the range.
+func enrichDocumentWithRange(doc invoice.OpenETLDocument, rng *coreresolution.Range) invoice.OpenETLDocument {
+	if doc.RfaFechaInicio == nil || *doc.RfaFechaInicio == "" {
+		fechaInicio := rng.ValidDateFrom.Format("2006-01-02")
+		doc.RfaFechaInicio = &fechaInicio
+	}
+	if doc.RfaFechaFin == nil || *doc.RfaFechaFin == "" {
+		fechaFin := rng.ValidDateTo.Format("2006-01-02")
+		doc.RfaFechaFin = &fechaFin
+	}
+	if doc.RfaNumeroInicio == nil || *doc.RfaNumeroInicio == "" {
+		numInicio := strconv.FormatInt(rng.FromNumber, 10)
+		doc.RfaNumeroInicio = &numInicio
+	}
+	if doc.RfaNumeroFin == nil || *doc.RfaNumeroFin == "" {
+		numFin := strconv.FormatInt(rng.ToNumber, 10)
+		doc.RfaNumeroFin = &numFin
+	}
+	return doc
+}
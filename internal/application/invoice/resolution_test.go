@@ -0,0 +1,60 @@
+package invoice
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appresolution "3tcapital/goclonacion/internal/application/resolution"
+	"3tcapital/goclonacion/internal/core/invoice"
+	coreresolution "3tcapital/goclonacion/internal/core/resolution"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func TestService_RegisterDocument_ResolutionTracking(t *testing.T) {
+	var sent []invoice.OpenETLDocument
+	mockProvider := &testutil.MockProvider{
+		GetResolutionsFunc: func(ctx context.Context, nit string) ([]coreresolution.Resolution, error) {
+			return []coreresolution.Resolution{{
+				ResolutionNumber: "18760000001",
+				Prefix:           "SETT",
+				FromNumber:       1,
+				ToNumber:         10,
+				ValidDateFrom:    time.Now().AddDate(0, -1, 0),
+				ValidDateTo:      time.Now().AddDate(1, 0, 0),
+			}}, nil
+		},
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			sent = req.Documentos.FC
+			return &invoice.DocumentRegistrationResponse{
+				DocumentosProcesados: []invoice.ProcessedDocument{{RfaPrefijo: "SETT", CdoConsecutivo: "5"}},
+			}, nil
+		},
+	}
+	repo := testutil.NewMockResolutionRepository()
+	tracker := appresolution.NewService(mockProvider).WithRepository(repo, testutil.NewTestLogger())
+	service := NewService(mockProvider, nil, nil, "2").WithResolutionTracker(tracker, testutil.NewTestLogger())
+
+	response, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("5"), newLedgerTestDocument("11")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sent) != 1 || sent[0].CdoConsecutivo != "5" {
+		t.Fatalf("expected only the document inside the range to be sent, got %+v", sent)
+	}
+	if sent[0].RfaNumeroInicio == nil || *sent[0].RfaNumeroInicio != "1" || *sent[0].RfaNumeroFin != "10" {
+		t.Errorf("expected rfa_* fields completed from the range, got %+v", sent[0])
+	}
+	if len(response.DocumentosFallidos) != 1 || !strings.Contains(response.DocumentosFallidos[0].Errors[0], "fuera del rango autorizado [1-10]") {
+		t.Errorf("expected out of range document to fail, got %+v", response.DocumentosFallidos)
+	}
+
+	ranges, _ := repo.List(context.Background(), "860011153")
+	if len(ranges) != 1 || ranges[0].LastNumber != 5 || ranges[0].UsedCount != 1 {
+		t.Errorf("expected accepted consecutivo to be recorded as used, got %+v", ranges)
+	}
+}
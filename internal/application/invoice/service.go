@@ -3,16 +3,28 @@ package invoice
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
+	"log/slog"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/acquirer"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	"3tcapital/ms_facturacion_core/internal/core/provider"
-	coreresolution "3tcapital/ms_facturacion_core/internal/core/resolution"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"3tcapital/goclonacion/internal/core/acquirer"
+	"3tcapital/goclonacion/internal/core/currency"
+	"3tcapital/goclonacion/internal/core/cufe"
+	"3tcapital/goclonacion/internal/core/document"
+	"3tcapital/goclonacion/internal/core/idempotency"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/ofe"
+	"3tcapital/goclonacion/internal/core/provider"
 )
 
+// tracer records the spans of the invoice use cases.
+var tracer = otel.Tracer("3tcapital/goclonacion/internal/application/invoice")
+
 // Service orchestrates invoice-related use cases.
 type Service struct {
 	provider           invoice.Provider
@@ -20,6 +32,26 @@ type Service struct {
 	providerRepo       provider.Repository // Optional: nil if database not configured (used for DS documents)
 	workerPoolSize     int                 // Number of workers for concurrent processing
 	cdoAmbienteDefault string              // Default environment value for documents ("1"=production, "2"=test)
+	ledger             document.Repository // Optional: nil if the documents ledger is disabled
+	ledgerLog          *slog.Logger
+	idempotency        idempotency.Repository // Optional: nil if Idempotency-Key support is disabled
+	cufeKeys           cufe.KeyResolver       // Optional: nil if local CUFE/CUDE verification is disabled
+	cufeLog            *slog.Logger
+	renderer           invoice.DocumentRenderer // Optional: nil if the UBL XML preview is disabled
+	ofeRepo            ofe.Repository           // Optional: nil if the OFE registry is disabled
+	resolutions        ResolutionTracker        // Optional: nil if numbering ranges are not tracked
+	resolutionLog      *slog.Logger
+	totalsCheck        bool                  // Recompute the document totals before sending
+	totalsAutofill     bool                  // Fill the document totals instead of rejecting mismatches
+	retentions         WithholdingCalculator // Optional: nil if the suggested retentions are not computed
+	retentionOverride  bool                  // Replace the retentions sent in the request
+	exchangeRates      currency.Source       // Optional: nil if cdo_trm must always be sent for foreign currencies
+	// Optional: nil if notes without invoice reference must send cdo_periodo_facturacion
+	notesPeriod *invoice.OpenETLPeriodoFacturacion
+	// Optional: nil if the contingency mode is disabled
+	contingency ContingencyQueue
+	// Optional: nil if the document metrics are not recorded
+	metrics DocumentMetrics
 }
 
 // NewService creates a new invoice service with the given invoice provider.
@@ -44,22 +76,6 @@ func NewServiceWithWorkerPool(provider invoice.Provider, acquirerRepo acquirer.R
 	}
 }
 
-// normalizeNIT extracts the base NIT without verification digit (DV).
-// Examples:
-//   - "860011153-6" -> "860011153"
-//   - "860011153" -> "860011153"
-func normalizeNIT(nit string) string {
-	if nit == "" {
-		return ""
-	}
-	// Split by "-" to separate NIT from DV
-	parts := strings.Split(nit, "-")
-	if len(parts) > 1 {
-		return strings.TrimSpace(parts[0])
-	}
-	return strings.TrimSpace(nit)
-}
-
 // GetDocuments retrieves documents/invoices for a given query.
 func (s *Service) GetDocuments(ctx context.Context, query invoice.DocumentQuery) ([]invoice.Document, error) {
 	// Validate CompanyNit
@@ -183,12 +199,168 @@ func (s *Service) GetReceivedDocuments(ctx context.Context, query invoice.Docume
 	if err != nil {
 		return nil, err
 	}
+	s.checkReceivedCUFEs(ctx, documents)
 
 	return documents, nil
 }
 
 // RegisterDocument registers documents with the invoice provider.
 func (s *Service) RegisterDocument(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+	ctx, span := tracer.Start(ctx, "invoice.RegisterDocument")
+	defer span.End()
+
+	response, err := s.registerDocument(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.Int("documents.processed", len(response.DocumentosProcesados)),
+		attribute.Int("documents.contingency", len(response.DocumentosContingencia)),
+		attribute.Int("documents.failed", len(response.DocumentosFallidos)),
+	)
+	return response, nil
+}
+
+// registerDocument validates, completes and sends the documents of a registration request.
+func (s *Service) registerDocument(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+	documents, documentType, err := documentsByType(req)
+	if err != nil {
+		return nil, err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("document.type", documentType), attribute.Int("document.count", len(documents)))
+
+	// Reject numbers repeated in the request, then claim the documents in the ledger; those
+	// already registered with the same number are answered from it
+	documents, repeatedDocuments := rejectRepeatedNumbers(documents, documentType)
+	documents, replayedDocuments, duplicatedDocuments := s.claimDocuments(ctx, documents, documentType)
+	duplicatedDocuments = append(repeatedDocuments, duplicatedDocuments...)
+	if len(documents) == 0 {
+		return mergeRegistrationResults(&invoice.DocumentRegistrationResponse{}, replayedDocuments, duplicatedDocuments), nil
+	}
+
+	ledgerIdx := newLedgerIndex(documents, documentType)
+
+	// Complete the totals before checking the required fields (DOCUMENT_TOTALS_AUTOFILL)
+	documents = s.fillTotals(documents)
+
+	// Validate each document; only the documents with rejections fail
+	var validated []invoice.OpenETLDocument
+	var failedDocuments []invoice.FailedDocument
+	for _, doc := range documents {
+		if rejections := invoice.Rejections(documentViolations(doc, documentType)); len(rejections) > 0 {
+			failedDocuments = append(failedDocuments, rejectedDocument(doc, documentType, rejections))
+			continue
+		}
+		validated = append(validated, doc)
+	}
+
+	validDocuments, preparationFailures, tracked := s.prepareDocuments(ctx, validated, documentType)
+	failedDocuments = append(failedDocuments, preparationFailures...)
+
+	s.recordFailed(ctx, ledgerIdx, failedDocuments, document.StatusFailed)
+	s.recordEnriched(ctx, validDocuments, documentType, document.StatusValidated)
+
+	// If all documents failed, return early with failed documents
+	if len(validDocuments) == 0 {
+		now := time.Now()
+		fechaProcesamiento := now.Format("2006-01-02")
+		horaProcesamiento := now.Format("15:04:05")
+		// Ensure failedDocuments is never nil (use empty slice instead)
+		if failedDocuments == nil {
+			failedDocuments = make([]invoice.FailedDocument, 0)
+		}
+		response := &invoice.DocumentRegistrationResponse{
+			Message:              "Todos los documentos fallaron la validación",
+			Lote:                 fmt.Sprintf("lote-%s-%s", fechaProcesamiento, horaProcesamiento),
+			DocumentosProcesados: []invoice.ProcessedDocument{},
+			DocumentosFallidos:   failedDocuments,
+		}
+		if len(replayedDocuments) > 0 {
+			response.Message = ""
+		}
+		s.observeDocuments(documentType, 0, 0, len(failedDocuments))
+		return mergeRegistrationResults(response, replayedDocuments, duplicatedDocuments), nil
+	}
+
+	// Build request with only valid documents
+	validReq := invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{},
+	}
+	switch documentType {
+	case "FC":
+		validReq.Documentos.FC = validDocuments
+	case "NC":
+		validReq.Documentos.NC = validDocuments
+	case "ND":
+		validReq.Documentos.ND = validDocuments
+	case "DS":
+		validReq.Documentos.DS = validDocuments
+	}
+
+	// In contingency mode the valid documents are stored for later transmission
+	if s.contingency != nil && s.contingency.Active(ctx) {
+		response, err := s.storeInContingency(ctx, ledgerIdx, validDocuments, documentType, tracked)
+		if err != nil {
+			return nil, err
+		}
+		response.DocumentosFallidos = append(response.DocumentosFallidos, failedDocuments...)
+		s.observeDocuments(documentType, 0, len(response.DocumentosContingencia), len(response.DocumentosFallidos))
+		return mergeRegistrationResults(response, replayedDocuments, duplicatedDocuments), nil
+	}
+
+	// Call provider to register valid documents
+	expectedCUFEs := s.expectedCUFEs(validDocuments, documentType)
+	s.recordEnriched(ctx, validDocuments, documentType, document.StatusSent)
+	response, err := s.provider.RegisterDocument(ctx, validReq)
+	if err != nil {
+		s.recordProviderError(ctx, validDocuments, documentType, err)
+		s.observeDocuments(documentType, 0, 0, len(validDocuments)+len(failedDocuments))
+		return nil, err
+	}
+	s.crossCheckCUFEs(expectedCUFEs, response.DocumentosProcesados)
+	s.markConsecutivosUsed(ctx, tracked, response.DocumentosProcesados)
+	s.recordAccepted(ctx, ledgerIdx, response.DocumentosProcesados)
+	s.recordFailed(ctx, ledgerIdx, response.DocumentosFallidos, document.StatusRejected)
+
+	// Merge failed documents from validation with provider response
+	if len(failedDocuments) > 0 {
+		// Ensure response.DocumentosFallidos is never nil before appending
+		if response.DocumentosFallidos == nil {
+			response.DocumentosFallidos = make([]invoice.FailedDocument, 0)
+		}
+		response.DocumentosFallidos = append(response.DocumentosFallidos, failedDocuments...)
+	}
+	s.observeDocuments(documentType, len(response.DocumentosProcesados), 0, len(response.DocumentosFallidos))
+
+	return mergeRegistrationResults(response, replayedDocuments, duplicatedDocuments), nil
+}
+
+// prepareDocuments completes validated documents as they are sent: acquirer or provider
+// data, exchange rate, withholdings, totals check, invoice reference and numbering range. Documents that
+// cannot be completed are returned as failed. Nothing is recorded.
+func (s *Service) prepareDocuments(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.FailedDocument, trackedConsecutivos) {
+	ctx, span := tracer.Start(ctx, "invoice.prepareDocuments", trace.WithAttributes(attribute.Int("document.count", len(documents))))
+	defer span.End()
+
+	validDocuments, failedDocuments := s.enrichDocuments(ctx, documents, documentType)
+	validDocuments, rateFailures := s.applyExchangeRates(ctx, validDocuments, documentType)
+	failedDocuments = append(failedDocuments, rateFailures...)
+	validDocuments, withholdingFailures := s.applyWithholdings(ctx, validDocuments, documentType)
+	failedDocuments = append(failedDocuments, withholdingFailures...)
+	validDocuments, totalsFailures := s.checkTotals(validDocuments, documentType)
+	failedDocuments = append(failedDocuments, totalsFailures...)
+	validDocuments, referenceFailures := s.checkReferences(ctx, validDocuments, documentType)
+	failedDocuments = append(failedDocuments, referenceFailures...)
+	validDocuments, rangeFailures, tracked := s.validateResolutions(ctx, validDocuments, documentType)
+	failedDocuments = append(failedDocuments, rangeFailures...)
+	return validDocuments, failedDocuments, tracked
+}
+
+// documentsByType returns the documents of the request and their type.
+// Exactly one document type must be provided.
+func documentsByType(req invoice.DocumentRegistrationRequest) ([]invoice.OpenETLDocument, string, error) {
 	// Validate that exactly one document type is provided
 	typeCount := 0
 	if len(req.Documentos.FC) > 0 {
@@ -205,38 +377,27 @@ func (s *Service) RegisterDocument(ctx context.Context, req invoice.DocumentRegi
 	}
 
 	if typeCount == 0 {
-		return nil, fmt.Errorf("no documents provided")
+		return nil, "", fmt.Errorf("no documents provided")
 	}
 
 	if typeCount > 1 {
-		return nil, fmt.Errorf("only one document type (FC, NC, ND, or DS) can be provided per request")
+		return nil, "", fmt.Errorf("only one document type (FC, NC, ND, or DS) can be provided per request")
 	}
 
 	// Get the documents to validate
-	var documents []invoice.OpenETLDocument
-	var documentType string
-
 	if len(req.Documentos.FC) > 0 {
-		documents = req.Documentos.FC
-		documentType = "FC"
+		return req.Documentos.FC, "FC", nil
 	} else if len(req.Documentos.NC) > 0 {
-		documents = req.Documentos.NC
-		documentType = "NC"
+		return req.Documentos.NC, "NC", nil
 	} else if len(req.Documentos.ND) > 0 {
-		documents = req.Documentos.ND
-		documentType = "ND"
-	} else {
-		documents = req.Documentos.DS
-		documentType = "DS"
-	}
-
-	// Validate each document
-	for idx, doc := range documents {
-		if err := s.validateDocument(doc, documentType, idx); err != nil {
-			return nil, err
-		}
+		return req.Documentos.ND, "ND", nil
 	}
+	return req.Documentos.DS, "DS", nil
+}
 
+// enrichDocuments validates the acquirer (FC/NC/ND) or provider (DS) of each document
+// and completes it with the stored data. Documents that cannot be enriched are returned as failed.
+func (s *Service) enrichDocuments(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.FailedDocument) {
 	// Use worker pool for concurrent processing if we have multiple documents and (acquirer or provider) repository
 	// For small batches or no repository, use sequential processing for simplicity
 	var validDocuments []invoice.OpenETLDocument
@@ -245,27 +406,44 @@ func (s *Service) RegisterDocument(ctx context.Context, req invoice.DocumentRegi
 	hasRepo := (documentType == "DS" && s.providerRepo != nil) || (documentType != "DS" && s.acquirerRepo != nil)
 	if hasRepo && len(documents) > 1 {
 		// Use worker pool for concurrent processing
-		pool := NewDocumentWorkerPool(ctx, s.workerPoolSize, s.acquirerRepo, s.providerRepo, s.provider, s.cdoAmbienteDefault)
+		pool := NewDocumentWorkerPool(ctx, s.workerPoolSize, s.acquirerRepo, s.providerRepo, s.cdoAmbienteDefault).
+			WithOFERegistry(s.ofeRepo)
 		validDocuments, failedDocuments = pool.ProcessDocuments(ctx, documents, documentType)
 	} else {
 		// Sequential processing for small batches or when no acquirer repo
 		now := time.Now()
 		fechaProcesamiento := now.Format("2006-01-02")
 		horaProcesamiento := now.Format("15:04:05")
+		ofes := newOFEResolver(s.ofeRepo)
 
 		for _, doc := range documents {
-			// 1. Enrich cdo_ambiente if missing
+			// 1. Validate the issuer against the OFE registry and complete its data
+			o, err := ofes.resolve(ctx, doc, documentType)
+			if err != nil {
+				failedDocuments = append(failedDocuments, invoice.FailedDocument{
+					Documento:          documentType,
+					Consecutivo:        doc.CdoConsecutivo,
+					Prefijo:            doc.RfaPrefijo,
+					Errors:             []string{err.Error()},
+					FechaProcesamiento: fechaProcesamiento,
+					HoraProcesamiento:  horaProcesamiento,
+				})
+				continue
+			}
+			doc = enrichDocumentWithOFE(doc, o)
+
+			// 2. Enrich cdo_ambiente if missing
 			doc = s.enrichDocumentWithEnvironment(doc)
 
-			// 2. Validate acquirer/provider existence based on document type
+			// 3. Validate acquirer/provider existence based on document type
 			if documentType == "DS" {
 				// DS documents: validate and enrich with provider data
 				// IMPORTANT: For DS documents, the mapping is inverted:
 				// - adq_identificacion (from request) maps to ofe_identificacion (in provider table)
 				// - ofe_identificacion (from request) maps to pro_identificacion (in provider table)
 				if s.providerRepo != nil {
-					normalizedAdqNIT := normalizeNIT(doc.AdqIdentificacion)
-					normalizedOfeNIT := normalizeNIT(doc.OfeIdentificacion)
+					normalizedAdqNIT := identification.Base(doc.AdqIdentificacion)
+					normalizedOfeNIT := identification.Base(doc.OfeIdentificacion)
 					prov, err := s.providerRepo.FindByID(ctx, normalizedAdqNIT, normalizedOfeNIT)
 					if err != nil {
 						// Database error - add to failed documents
@@ -283,8 +461,8 @@ func (s *Service) RegisterDocument(ctx context.Context, req invoice.DocumentRegi
 					if prov == nil {
 						// Provider not found - add to failed documents
 						// Note: For DS, we search provider where ofe_identificacion=adq_identificacion and pro_identificacion=ofe_identificacion
-						normalizedAdqNIT := normalizeNIT(doc.AdqIdentificacion)
-						normalizedOfeNIT := normalizeNIT(doc.OfeIdentificacion)
+						normalizedAdqNIT := identification.Base(doc.AdqIdentificacion)
+						normalizedOfeNIT := identification.Base(doc.OfeIdentificacion)
 						failedDocuments = append(failedDocuments, invoice.FailedDocument{
 							Documento:          documentType,
 							Consecutivo:        doc.CdoConsecutivo,
@@ -298,19 +476,16 @@ func (s *Service) RegisterDocument(ctx context.Context, req invoice.DocumentRegi
 
 					// Enrich document with provider data
 					enrichedDoc := s.enrichDocumentWithProvider(doc, prov)
-					// Enrich document with hardcoded OFE data
-					enrichedDoc = s.enrichDocumentWithOFE(enrichedDoc)
 					validDocuments = append(validDocuments, enrichedDoc)
 				} else {
-					// No provider repository - enrich with OFE data only
-					enrichedDoc := s.enrichDocumentWithOFE(doc)
-					validDocuments = append(validDocuments, enrichedDoc)
+					// No provider repository - keep the document as received
+					validDocuments = append(validDocuments, doc)
 				}
 			} else {
 				// FC/NC/ND documents: validate and enrich with acquirer data
 				if s.acquirerRepo != nil {
-					normalizedOfeNIT := normalizeNIT(doc.OfeIdentificacion)
-					normalizedAdqNIT := normalizeNIT(doc.AdqIdentificacion)
+					normalizedOfeNIT := identification.Base(doc.OfeIdentificacion)
+					normalizedAdqNIT := identification.Base(doc.AdqIdentificacion)
 					acq, err := s.acquirerRepo.FindByID(ctx, normalizedOfeNIT, normalizedAdqNIT, "")
 					if err != nil {
 						// Database error - add to failed documents
@@ -327,8 +502,8 @@ func (s *Service) RegisterDocument(ctx context.Context, req invoice.DocumentRegi
 
 					if acq == nil {
 						// Acquirer not found - add to failed documents
-						normalizedOfeNIT := normalizeNIT(doc.OfeIdentificacion)
-						normalizedAdqNIT := normalizeNIT(doc.AdqIdentificacion)
+						normalizedOfeNIT := identification.Base(doc.OfeIdentificacion)
+						normalizedAdqNIT := identification.Base(doc.AdqIdentificacion)
 						failedDocuments = append(failedDocuments, invoice.FailedDocument{
 							Documento:          documentType,
 							Consecutivo:        doc.CdoConsecutivo,
@@ -342,64 +517,23 @@ func (s *Service) RegisterDocument(ctx context.Context, req invoice.DocumentRegi
 
 					// Enrich document with acquirer data
 					enrichedDoc := s.enrichDocumentWithAcquirer(doc, acq)
-					// Enrich document with hardcoded OFE data
-					enrichedDoc = s.enrichDocumentWithOFE(enrichedDoc)
 					validDocuments = append(validDocuments, enrichedDoc)
 				} else {
-					// No acquirer repository - enrich with OFE data only
-					enrichedDoc := s.enrichDocumentWithOFE(doc)
-					validDocuments = append(validDocuments, enrichedDoc)
+					// No acquirer repository - keep the document as received
+					validDocuments = append(validDocuments, doc)
 				}
 			}
 		}
 	}
 
-	// If all documents failed, return early with failed documents
-	if len(validDocuments) == 0 {
-		now := time.Now()
-		fechaProcesamiento := now.Format("2006-01-02")
-		horaProcesamiento := now.Format("15:04:05")
-		// Ensure failedDocuments is never nil (use empty slice instead)
-		if failedDocuments == nil {
-			failedDocuments = make([]invoice.FailedDocument, 0)
-		}
-		return &invoice.DocumentRegistrationResponse{
-			Message:              "Todos los documentos fallaron la validación",
-			Lote:                 fmt.Sprintf("lote-%s-%s", fechaProcesamiento, horaProcesamiento),
-			DocumentosProcesados: []invoice.ProcessedDocument{},
-			DocumentosFallidos:   failedDocuments,
-		}, nil
-	}
-
-	// Build request with only valid documents
-	validReq := invoice.DocumentRegistrationRequest{
-		Documentos: invoice.DocumentsByType{},
-	}
-	switch documentType {
-	case "FC":
-		validReq.Documentos.FC = validDocuments
-	case "NC":
-		validReq.Documentos.NC = validDocuments
-	case "ND":
-		validReq.Documentos.ND = validDocuments
-	case "DS":
-		validReq.Documentos.DS = validDocuments
-	}
-
-	// Call provider to register valid documents
-	response, err := s.provider.RegisterDocument(ctx, validReq)
-	if err != nil {
-		return nil, err
-	}
+	return validDocuments, failedDocuments
+}
 
-	// Merge failed documents from validation with provider response
-	if len(failedDocuments) > 0 {
-		// Ensure response.DocumentosFallidos is never nil before appending
-		if response.DocumentosFallidos == nil {
-			response.DocumentosFallidos = make([]invoice.FailedDocument, 0)
-		}
-		response.DocumentosFallidos = append(response.DocumentosFallidos, failedDocuments...)
-	}
+// mergeRegistrationResults appends results resolved without calling the provider and
+// ensures the response slices are never nil.
+func mergeRegistrationResults(response *invoice.DocumentRegistrationResponse, processed []invoice.ProcessedDocument, failed []invoice.FailedDocument) *invoice.DocumentRegistrationResponse {
+	response.DocumentosProcesados = append(response.DocumentosProcesados, processed...)
+	response.DocumentosFallidos = append(response.DocumentosFallidos, failed...)
 
 	// Ensure slices are never nil (use empty slice instead)
 	if response.DocumentosProcesados == nil {
@@ -408,166 +542,16 @@ func (s *Service) RegisterDocument(ctx context.Context, req invoice.DocumentRegi
 	if response.DocumentosFallidos == nil {
 		response.DocumentosFallidos = make([]invoice.FailedDocument, 0)
 	}
-
-	return response, nil
-}
-
-// validateDocument validates a single document.
-func (s *Service) validateDocument(doc invoice.OpenETLDocument, documentType string, index int) error {
-	// Validate required fields
-	if doc.TdeCodigo == "" {
-		return fmt.Errorf("document %d: tde_codigo is required", index+1)
-	}
-
-	if doc.OfeIdentificacion == "" {
-		return fmt.Errorf("document %d: ofe_identificacion is required", index+1)
-	}
-
-	if doc.AdqIdentificacion == "" {
-		return fmt.Errorf("document %d: adq_identificacion is required", index+1)
-	}
-
-	// For NC and ND, rfa_resolucion can be empty (fields come empty and should remain empty)
-	// Only require rfa_resolucion for FC and DS documents
-	// Skip rfa_resolucion validation for NC and ND documents
-	fmt.Printf("DEBUG validateDocument: documentType=%s, rfa_resolucion='%s', index=%d\n",
-		documentType, doc.RfaResolucion, index)
-	if doc.RfaResolucion == "" && documentType != "NC" && documentType != "ND" {
-		return fmt.Errorf("document %d: rfa_resolucion is required", index+1)
-	}
-
-	if doc.CdoConsecutivo == "" {
-		return fmt.Errorf("document %d: cdo_consecutivo is required", index+1)
-	}
-
-	if doc.CdoFecha == "" {
-		return fmt.Errorf("document %d: cdo_fecha is required", index+1)
-	}
-
-	if doc.CdoHora == "" {
-		return fmt.Errorf("document %d: cdo_hora is required", index+1)
-	}
-
-	if doc.MonCodigo == "" {
-		return fmt.Errorf("document %d: mon_codigo is required", index+1)
-	}
-
-	if doc.CdoValorSinImpuestos == "" {
-		return fmt.Errorf("document %d: cdo_valor_sin_impuestos is required", index+1)
-	}
-
-	if doc.CdoImpuestos == "" {
-		return fmt.Errorf("document %d: cdo_impuestos is required", index+1)
-	}
-
-	if doc.CdoTotal == "" {
-		return fmt.Errorf("document %d: cdo_total is required", index+1)
-	}
-
-	// Validate date format (YYYY-MM-DD)
-	dateLayout := "2006-01-02"
-	if _, err := time.Parse(dateLayout, doc.CdoFecha); err != nil {
-		return fmt.Errorf("document %d: invalid cdo_fecha format: must be YYYY-MM-DD", index+1)
-	}
-
-	// Validate that cdo_fecha is today's date (DIAN FAD09e compliance)
-	if err := validateIssueDateIsToday(doc.CdoFecha, index); err != nil {
-		return err
-	}
-
-	// Validate time format (HH:mm:ss)
-	timeLayout := "15:04:05"
-	if _, err := time.Parse(timeLayout, doc.CdoHora); err != nil {
-		return fmt.Errorf("document %d: invalid cdo_hora format: must be HH:mm:ss", index+1)
-	}
-
-	// Validate items array is not empty
-	if len(doc.Items) == 0 {
-		return fmt.Errorf("document %d: at least one item is required", index+1)
-	}
-
-	// Validate due date if provided
-	if doc.CdoVencimiento != nil && *doc.CdoVencimiento != "" {
-		// Validate date format (YYYY-MM-DD)
-		dateLayout := "2006-01-02"
-		dueDate, err := time.Parse(dateLayout, *doc.CdoVencimiento)
-		if err != nil {
-			return fmt.Errorf("document %d: invalid cdo_vencimiento format: must be YYYY-MM-DD", index+1)
-		}
-
-		// Parse document date for comparison
-		docDate, err := time.Parse(dateLayout, doc.CdoFecha)
-		if err != nil {
-			// This should not happen as cdo_fecha is already validated above
-			return fmt.Errorf("document %d: invalid cdo_fecha format for comparison with cdo_vencimiento", index+1)
-		}
-
-		// Validate that due date is on or after document date
-		if dueDate.Before(docDate) {
-			return fmt.Errorf("document %d: cdo_vencimiento (%s) must be on or after cdo_fecha (%s)",
-				index+1, *doc.CdoVencimiento, doc.CdoFecha)
-		}
-	}
-
-	// Validate document type code matches the array it's in
-	expectedTypeCodes := map[string][]string{
-		"FC": {"01"},
-		"NC": {"03", "91"},
-		"ND": {"04", "92"},
-		"DS": {"05"},
-	}
-
-	validCodes := expectedTypeCodes[documentType]
-	valid := false
-	for _, code := range validCodes {
-		if doc.TdeCodigo == code {
-			valid = true
-			break
-		}
-	}
-
-	if !valid {
-		return fmt.Errorf("document %d: tde_codigo %s does not match document type %s (expected: %v)", index+1, doc.TdeCodigo, documentType, validCodes)
-	}
-
-	// Validate top_codigo for DS documents
-	if documentType == "DS" {
-		if doc.TopCodigo == "" {
-			return fmt.Errorf("document %d: top_codigo is required for DS documents", index+1)
-		}
-		if doc.TopCodigo != "10" {
-			return fmt.Errorf("document %d: top_codigo must be \"10\" for DS documents, got: %s", index+1, doc.TopCodigo)
-		}
-	}
-
-	return nil
+	return response
 }
 
-// validateIssueDateIsToday validates that cdo_fecha is today's date (Colombia timezone).
-// This is required by DIAN rule FAD09e: IssueDate must equal signature date.
-// When documents are signed by Numrot, they use the current date, so cdo_fecha must match.
-func validateIssueDateIsToday(cdoFecha string, index int) error {
-	// Get current date in Colombia timezone (UTC-5)
-	loc, err := time.LoadLocation("America/Bogota")
-	if err != nil {
-		// Fallback to UTC-5 offset if timezone data is not available
-		loc = time.FixedZone("America/Bogota", -5*60*60)
-	}
-	today := time.Now().In(loc)
-	todayDate := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
-
-	// Parse the provided date in the same timezone
-	providedDate, err := time.ParseInLocation("2006-01-02", cdoFecha, loc)
-	if err != nil {
-		return fmt.Errorf("document %d: invalid cdo_fecha format", index+1)
-	}
-
-	// Compare only the date part (year, month, day)
-	if providedDate.Year() != todayDate.Year() || providedDate.Month() != todayDate.Month() || providedDate.Day() != todayDate.Day() {
-		return fmt.Errorf("document %d: cdo_fecha must be today's date (%s) for DIAN FAD09e compliance. Provided: %s",
-			index+1, todayDate.Format("2006-01-02"), cdoFecha)
+// checkNIT validates the verification digit of an identification that carries one
+// ("860011153-6"). When required, the identification must be a NIT even without DV.
+func checkNIT(number string, required bool) error {
+	if _, dv := identification.Split(number); dv == "" && !required {
+		return nil
 	}
-	return nil
+	return identification.ValidateNIT(number)
 }
 
 // enrichDocumentWithAcquirer enriches a document with acquirer data from the database.
@@ -648,6 +632,14 @@ func (s *Service) enrichDocumentWithAcquirer(doc invoice.OpenETLDocument, acq *a
 		}
 	}
 
+	// Tax responsibilities, used to compute the suggested retentions
+	if len(doc.AdqRefCodigo) == 0 {
+		doc.AdqRefCodigo = acq.RefCodigo
+	}
+	if len(doc.AdqResponsableTributos) == 0 {
+		doc.AdqResponsableTributos = acq.ResponsableTributos
+	}
+
 	return doc
 }
 
@@ -726,33 +718,10 @@ func (s *Service) enrichDocumentWithProvider(doc invoice.OpenETLDocument, prov *
 		}
 	}
 
-	return doc
-}
-
-// enrichDocumentWithOFE enriches a document with hardcoded OFE information.
-// These values are hardcoded as per business requirements:
-// - ofe_razon_social: "Positiva SAS"
-// - ofe_direccion: "CLL 50 - 96"
-// - ofe_municipio_codigo: "05380"
-// - ofe_municipio_nombre: "LA ESTRELLA"
-// - ofe_departamento_codigo: "05"
-// - ofe_departamento_nombre: "ANTIOQUIA"
-func (s *Service) enrichDocumentWithOFE(doc invoice.OpenETLDocument) invoice.OpenETLDocument {
-	// Hardcoded OFE values
-	ofeRazonSocial := "Positiva SAS"
-	ofeDireccion := "CLL 50 - 96"
-	ofeMunicipioCodigo := "05380"
-	ofeMunicipioNombre := "LA ESTRELLA"
-	ofeDepartamentoCodigo := "05"
-	ofeDepartamentoNombre := "ANTIOQUIA"
-
-	// Set OFE fields (override any values provided in request)
-	doc.OfeRazonSocial = &ofeRazonSocial
-	doc.OfeDireccion = &ofeDireccion
-	doc.OfeMunicipioCodigo = &ofeMunicipioCodigo
-	doc.OfeMunicipioNombre = &ofeMunicipioNombre
-	doc.OfeDepartamentoCodigo = &ofeDepartamentoCodigo
-	doc.OfeDepartamentoNombre = &ofeDepartamentoNombre
+	// Tax responsibilities of the provider, used to compute the suggested retentions
+	if len(doc.AdqRefCodigo) == 0 {
+		doc.AdqRefCodigo = prov.RefCodigo
+	}
 
 	return doc
 }
@@ -764,125 +733,3 @@ func (s *Service) enrichDocumentWithEnvironment(doc invoice.OpenETLDocument) inv
 	}
 	return doc
 }
-
-// findResolution finds a resolution by OFE, number, and prefix.
-func (s *Service) findResolution(ctx context.Context, ofeIdentificacion, resolutionNumber, prefix string) (*coreresolution.Resolution, error) {
-	// Extract base NIT (without DV) for resolution query
-	// The system receives NIT with DV (e.g., "860011153-6") but Numrot API expects only the base NIT (e.g., "860011153")
-	baseNIT := ofeIdentificacion
-	if parts := strings.Split(ofeIdentificacion, "-"); len(parts) > 1 {
-		baseNIT = parts[0]
-	}
-
-	// Query resolutions from provider using base NIT
-	resolutions, err := s.provider.GetResolutions(ctx, baseNIT)
-	if err != nil {
-		return nil, fmt.Errorf("error al consultar resoluciones para OFE [%s]: %w", baseNIT, err)
-	}
-
-	// Find matching resolution
-	for i := range resolutions {
-		if resolutions[i].ResolutionNumber == resolutionNumber && resolutions[i].Prefix == prefix {
-			return &resolutions[i], nil
-		}
-	}
-
-	return nil, fmt.Errorf("resolución [%s] con prefijo [%s] no encontrada para el OFE [%s]",
-		resolutionNumber, prefix, ofeIdentificacion)
-}
-
-// enrichDocumentWithResolution enriches a document with resolution data if fields are missing.
-// documentType is used to determine if resolution validation is required (DS doesn't require it).
-func (s *Service) enrichDocumentWithResolution(ctx context.Context, doc invoice.OpenETLDocument, documentType string) (invoice.OpenETLDocument, error) {
-	// Check if resolution fields are already present
-	hasAllFields := doc.RfaFechaInicio != nil && *doc.RfaFechaInicio != "" &&
-		doc.RfaFechaFin != nil && *doc.RfaFechaFin != "" &&
-		doc.RfaNumeroInicio != nil && *doc.RfaNumeroInicio != "" &&
-		doc.RfaNumeroFin != nil && *doc.RfaNumeroFin != ""
-
-	if hasAllFields {
-		return doc, nil // All fields present, no need to enrich
-	}
-
-	// For DS, NC, and ND documents, resolution is optional - if not found, return without error
-	// For FC documents, resolution is required
-	findResolution := true
-	if (documentType == "DS" || documentType == "NC" || documentType == "ND") && (doc.RfaResolucion == "" || doc.RfaPrefijo == "") {
-		// DS, NC, and ND don't require resolution if not provided
-		findResolution = false
-	}
-
-	if !findResolution {
-		// For DS, NC, or ND without resolution, return document as-is
-		return doc, nil
-	}
-
-	// Find resolution
-	resolution, err := s.findResolution(ctx, doc.OfeIdentificacion, doc.RfaResolucion, doc.RfaPrefijo)
-	if err != nil {
-		// For DS, if resolution is not found, it's not a fatal error
-		if documentType == "DS" {
-			return doc, nil // Return document without resolution data
-		}
-		return doc, err
-	}
-
-	// Populate missing fields
-	if doc.RfaFechaInicio == nil || *doc.RfaFechaInicio == "" {
-		fechaInicio := resolution.ValidDateFrom.Format("2006-01-02")
-		doc.RfaFechaInicio = &fechaInicio
-	}
-
-	if doc.RfaFechaFin == nil || *doc.RfaFechaFin == "" {
-		fechaFin := resolution.ValidDateTo.Format("2006-01-02")
-		doc.RfaFechaFin = &fechaFin
-	}
-
-	if doc.RfaNumeroInicio == nil || *doc.RfaNumeroInicio == "" {
-		numInicio := fmt.Sprintf("%d", resolution.FromNumber)
-		doc.RfaNumeroInicio = &numInicio
-	}
-
-	if doc.RfaNumeroFin == nil || *doc.RfaNumeroFin == "" {
-		numFin := fmt.Sprintf("%d", resolution.ToNumber)
-		doc.RfaNumeroFin = &numFin
-	}
-
-	return doc, nil
-}
-
-// validateResolutionData validates that the document data matches the resolution.
-func (s *Service) validateResolutionData(ctx context.Context, doc invoice.OpenETLDocument, index int) error {
-	// Find resolution
-	resolution, err := s.findResolution(ctx, doc.OfeIdentificacion, doc.RfaResolucion, doc.RfaPrefijo)
-	if err != nil {
-		return err
-	}
-
-	// Validate consecutivo is within range
-	consecutivo, err := strconv.ParseInt(doc.CdoConsecutivo, 10, 64)
-	if err != nil {
-		return fmt.Errorf("document %d: consecutivo inválido [%s]: %v", index+1, doc.CdoConsecutivo, err)
-	}
-
-	if consecutivo < resolution.FromNumber || consecutivo > resolution.ToNumber {
-		return fmt.Errorf("document %d: consecutivo [%s] fuera del rango autorizado [%d-%d] para la resolución [%s]",
-			index+1, doc.CdoConsecutivo, resolution.FromNumber, resolution.ToNumber, doc.RfaResolucion)
-	}
-
-	// Validate date is within validity period
-	docDate, err := time.Parse("2006-01-02", doc.CdoFecha)
-	if err != nil {
-		return fmt.Errorf("document %d: fecha de documento inválida [%s]: %v", index+1, doc.CdoFecha, err)
-	}
-
-	if docDate.Before(resolution.ValidDateFrom) || docDate.After(resolution.ValidDateTo) {
-		return fmt.Errorf("document %d: fecha [%s] fuera de la vigencia [%s - %s] de la resolución [%s]",
-			index+1, doc.CdoFecha,
-			resolution.ValidDateFrom.Format("2006-01-02"),
-			resolution.ValidDateTo.Format("2006-01-02"),
-			doc.RfaResolucion)
-	}
-
-	return nil
-}
@@ -3,11 +3,12 @@ package invoice
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 // getTodayDate returns today's date in Colombia timezone (UTC-5) formatted as YYYY-MM-DD
@@ -496,7 +497,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "tde_codigo is required",
+			expectedErr: "tde_codigo es requerido",
 		},
 		{
 			name: "missing ofe_identificacion",
@@ -515,7 +516,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "ofe_identificacion is required",
+			expectedErr: "ofe_identificacion es requerido",
 		},
 		{
 			name: "invalid date format",
@@ -547,7 +548,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "invalid cdo_fecha format",
+			expectedErr: "cdo_fecha debe tener el formato AAAA-MM-DD",
 		},
 		{
 			name: "invalid time format",
@@ -581,7 +582,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "invalid cdo_hora format",
+			expectedErr: "cdo_hora debe tener el formato HH:mm:ss",
 		},
 		{
 			name: "no items",
@@ -613,7 +614,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "at least one item is required",
+			expectedErr: "al menos un ítem",
 		},
 		{
 			name: "invalid document type code for FC",
@@ -647,7 +648,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "does not match document type",
+			expectedErr: "no corresponde al tipo de documento",
 		},
 		{
 			name: "valid FC document - success",
@@ -741,7 +742,8 @@ func TestService_RegisterDocument(t *testing.T) {
 										DdoTotal:          "100000.00",
 									},
 								},
-								Tributos: []invoice.OpenETLTributo{},
+								Tributos:              []invoice.OpenETLTributo{},
+								CdoPeriodoFacturacion: &invoice.OpenETLPeriodoFacturacion{FechaInicio: "2024-01-01", FechaFin: "2024-01-31"},
 							},
 						},
 						ND: []invoice.OpenETLDocument{},
@@ -859,7 +861,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "DIAN FAD09e compliance",
+			expectedErr: "[FAD09e] cdo_fecha",
 		},
 		{
 			name: "FAD09e - future date fails",
@@ -903,7 +905,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "DIAN FAD09e compliance",
+			expectedErr: "[FAD09e] cdo_fecha",
 		},
 		{
 			name: "FAD09e - historical date fails",
@@ -945,7 +947,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "DIAN FAD09e compliance",
+			expectedErr: "[FAD09e] cdo_fecha",
 		},
 		{
 			name: "invalid cdo_vencimiento format",
@@ -991,7 +993,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "invalid cdo_vencimiento format",
+			expectedErr: "cdo_vencimiento debe tener el formato AAAA-MM-DD",
 		},
 		{
 			name: "cdo_vencimiento before cdo_fecha",
@@ -1037,7 +1039,7 @@ func TestService_RegisterDocument(t *testing.T) {
 			setupProvider: func() invoice.Provider {
 				return &testutil.MockProvider{}
 			},
-			expectedErr: "must be on or after cdo_fecha",
+			expectedErr: "debe ser igual o posterior a cdo_fecha",
 		},
 	}
 
@@ -1050,6 +1052,10 @@ func TestService_RegisterDocument(t *testing.T) {
 			resp, err := service.RegisterDocument(ctx, tt.req)
 
 			if tt.expectedErr != "" {
+				// Invalid documents are reported as failed instead of failing the request
+				if err == nil {
+					err = failedDocumentsError(resp)
+				}
 				if err == nil {
 					t.Fatalf("expected error %q, got nil", tt.expectedErr)
 				}
@@ -1069,3 +1075,56 @@ func TestService_RegisterDocument(t *testing.T) {
 		})
 	}
 }
+
+// failedDocumentsError joins the errors of the failed documents of a registration.
+func failedDocumentsError(resp *invoice.DocumentRegistrationResponse) error {
+	if resp == nil || len(resp.DocumentosFallidos) == 0 {
+		return nil
+	}
+	var messages []string
+	for _, f := range resp.DocumentosFallidos {
+		messages = append(messages, f.Errors...)
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+func TestService_ValidateDocument_Identification(t *testing.T) {
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2")
+
+	tests := []struct {
+		name         string
+		documentType string
+		ofe          string
+		adq          string
+		wantErr      string
+	}{
+		{name: "valid DVs", documentType: "FC", ofe: "860.011.153-6", adq: "900373115-3"},
+		{name: "acquirer without DV", documentType: "FC", ofe: "860011153", adq: "1020304050"},
+		{name: "wrong OFE DV", documentType: "FC", ofe: "860011153-3", adq: "900373115", wantErr: "ofe_identificacion: NIT [860011153-3] inválido: el dígito de verificación debe ser 6"},
+		{name: "wrong acquirer DV", documentType: "FC", ofe: "860011153", adq: "900373115-6", wantErr: "adq_identificacion: NIT [900373115-6] inválido: el dígito de verificación debe ser 3"},
+		{name: "OFE is not a NIT", documentType: "FC", ofe: "ABC123", adq: "900373115", wantErr: "ofe_identificacion: NIT [ABC123] inválido"},
+		{name: "DS provider with passport", documentType: "DS", ofe: "AB123456", adq: "860011153"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newLedgerTestDocument("1")
+			doc.OfeIdentificacion = tt.ofe
+			doc.AdqIdentificacion = tt.adq
+			if tt.documentType == "DS" {
+				doc.TdeCodigo, doc.TopCodigo = "05", "10"
+			}
+
+			err := service.validateDocument(doc, tt.documentType, 0)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
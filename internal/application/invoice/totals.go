@@ -0,0 +1,83 @@
+package invoice
+
+import (
+	"time"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/totals"
+)
+
+// WithTotalsCheck enables the recalculation of the document totals before sending.
+// When autofill is false, documents whose declared totals do not match the lines and
+// tributos are rejected with the DIAN rule they break; when true, the totals are
+// completed and replaced with the computed values.
+func (s *Service) WithTotalsCheck(autofill bool) *Service {
+	s.totalsCheck = true
+	s.totalsAutofill = autofill
+	return s
+}
+
+// checkTotals recomputes the totals of every document and rejects the documents
+// whose declared amounts do not add up.
+func (s *Service) checkTotals(documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.FailedDocument) {
+	if !s.totalsCheck {
+		return documents, nil
+	}
+
+	now := time.Now()
+	fechaProcesamiento := now.Format("2006-01-02")
+	horaProcesamiento := now.Format("15:04:05")
+
+	valid := make([]invoice.OpenETLDocument, 0, len(documents))
+	var failed []invoice.FailedDocument
+
+	for _, doc := range documents {
+		errs := documentTotalsErrors(doc, documentType)
+		if len(errs) > 0 {
+			failed = append(failed, invoice.FailedDocument{
+				Documento:          documentType,
+				Consecutivo:        doc.CdoConsecutivo,
+				Prefijo:            doc.RfaPrefijo,
+				Errors:             errs,
+				FechaProcesamiento: fechaProcesamiento,
+				HoraProcesamiento:  horaProcesamiento,
+			})
+			continue
+		}
+		valid = append(valid, doc)
+	}
+
+	return valid, failed
+}
+
+// fillTotals completes the totals of every document when autofill is enabled. It runs
+// before the required fields are validated, so that documents may omit cdo_valor_sin_impuestos,
+// cdo_impuestos and cdo_total. Documents that cannot be filled are left untouched and
+// reported by checkTotals.
+func (s *Service) fillTotals(documents []invoice.OpenETLDocument) []invoice.OpenETLDocument {
+	if !s.totalsCheck || !s.totalsAutofill {
+		return documents
+	}
+
+	filled := make([]invoice.OpenETLDocument, len(documents))
+	for i, doc := range documents {
+		if f, err := totals.Fill(doc); err == nil {
+			doc = f
+		}
+		filled[i] = doc
+	}
+	return filled
+}
+
+// documentTotalsErrors returns the mismatches between the declared and the computed totals.
+func documentTotalsErrors(doc invoice.OpenETLDocument, documentType string) []string {
+	issues, err := totals.Validate(doc, documentType)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	errs := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		errs = append(errs, issue.Error())
+	}
+	return errs
+}
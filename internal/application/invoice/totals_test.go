@@ -0,0 +1,76 @@
+package invoice
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func TestService_RegisterDocument_TotalsCheck(t *testing.T) {
+	var sent []invoice.OpenETLDocument
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			sent = req.Documentos.FC
+			return &invoice.DocumentRegistrationResponse{}, nil
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2").WithTotalsCheck(false)
+
+	mismatched := newLedgerTestDocument("2")
+	mismatched.CdoValorSinImpuestos = "90000.00"
+	resp, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1"), mismatched}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sent) != 1 || sent[0].CdoConsecutivo != "1" {
+		t.Fatalf("expected only the consistent document to be sent, got %+v", sent)
+	}
+	if len(resp.DocumentosFallidos) != 1 {
+		t.Fatalf("expected 1 failed document, got %+v", resp.DocumentosFallidos)
+	}
+	errs := strings.Join(resp.DocumentosFallidos[0].Errors, "; ")
+	if !strings.Contains(errs, "[FAU02] cdo_valor_sin_impuestos") || !strings.Contains(errs, "[FAU06] cdo_total") {
+		t.Errorf("expected FAU02 and FAU06 errors, got %s", errs)
+	}
+}
+
+func TestService_RegisterDocument_TotalsAutofill(t *testing.T) {
+	var sent []invoice.OpenETLDocument
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			sent = req.Documentos.FC
+			return &invoice.DocumentRegistrationResponse{}, nil
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2").WithTotalsCheck(true)
+
+	doc := newLedgerTestDocument("1")
+	doc.Items[0].DdoCantidad = "2"
+	doc.Items[0].DdoTotal = ""
+	doc.CdoValorSinImpuestos, doc.CdoTotal = "", ""
+	doc.Tributos = []invoice.OpenETLTributo{
+		{DdoSecuencia: "1", TriCodigo: "01", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "200000.00", IidPorcentaje: "19.00"}},
+	}
+	if _, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{doc}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sent) != 1 {
+		t.Fatalf("expected the document to be sent, got %d", len(sent))
+	}
+	got := sent[0]
+	if got.Items[0].DdoTotal != "200000.00" || got.Tributos[0].IidValor != "38000.00" {
+		t.Errorf("expected line and tax values to be filled, got %s %s", got.Items[0].DdoTotal, got.Tributos[0].IidValor)
+	}
+	if got.CdoValorSinImpuestos != "200000.00" || got.CdoImpuestos != "38000.00" || got.CdoTotal != "238000.00" {
+		t.Errorf("unexpected totals: %s %s %s", got.CdoValorSinImpuestos, got.CdoImpuestos, got.CdoTotal)
+	}
+}
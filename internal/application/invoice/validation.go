@@ -0,0 +1,253 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/currency"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// expectedTypeCodes are the tde_codigo values accepted for each document type.
+var expectedTypeCodes = map[string][]string{
+	"FC": {"01"},
+	"NC": {"03", "91"},
+	"ND": {"04", "92"},
+	"DS": {"05"},
+}
+
+// ruleSet collects the violations found while validating a document.
+type ruleSet struct {
+	violations []invoice.Violation
+}
+
+// reject records a violation that prevents the document from being sent.
+func (r *ruleSet) reject(field, rule, format string, args ...any) {
+	r.add(invoice.SeverityRejection, field, rule, format, args...)
+}
+
+// notify records a violation that is only reported.
+func (r *ruleSet) notify(field, rule, format string, args ...any) {
+	r.add(invoice.SeverityNotification, field, rule, format, args...)
+}
+
+func (r *ruleSet) add(severity invoice.Severity, field, rule, format string, args ...any) {
+	r.violations = append(r.violations, invoice.Violation{
+		Campo:     field,
+		Regla:     rule,
+		Severidad: severity,
+		Mensaje:   fmt.Sprintf(format, args...),
+	})
+}
+
+// required rejects an empty field.
+func (r *ruleSet) required(field, rule, value string) bool {
+	if value == "" {
+		r.reject(field, rule, "%s es requerido", field)
+		return false
+	}
+	return true
+}
+
+// documentViolations checks a document against every validation rule and returns all
+// the violations found, instead of stopping at the first one.
+func documentViolations(doc invoice.OpenETLDocument, documentType string) []invoice.Violation {
+	r := &ruleSet{}
+
+	// Document type
+	if r.required("tde_codigo", "", doc.TdeCodigo) {
+		validCodes := expectedTypeCodes[documentType]
+		valid := false
+		for _, code := range validCodes {
+			if doc.TdeCodigo == code {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			r.reject("tde_codigo", "", "tde_codigo %s no corresponde al tipo de documento %s (esperado: %v)", doc.TdeCodigo, documentType, validCodes)
+		}
+	}
+	if documentType == "DS" {
+		if r.required("top_codigo", "", doc.TopCodigo) && doc.TopCodigo != "10" {
+			r.reject("top_codigo", "", "top_codigo debe ser \"10\" para documentos DS, recibido: %s", doc.TopCodigo)
+		}
+	}
+
+	// Parties: the OFE of FC/NC/ND documents is always a NIT; for DS it is the provider,
+	// which may use another identification type
+	if r.required("ofe_identificacion", "", doc.OfeIdentificacion) {
+		if err := checkNIT(doc.OfeIdentificacion, documentType != "DS"); err != nil {
+			r.reject("ofe_identificacion", "FAJ24", "%v", err)
+		}
+	}
+	if r.required("adq_identificacion", "", doc.AdqIdentificacion) {
+		if err := checkNIT(doc.AdqIdentificacion, false); err != nil {
+			r.reject("adq_identificacion", "FAK24", "%v", err)
+		}
+	}
+
+	// Numbering: NC and ND documents may be sent without resolution
+	if documentType != "NC" && documentType != "ND" {
+		r.required("rfa_resolucion", "", doc.RfaResolucion)
+	}
+	r.required("cdo_consecutivo", "FAD05", doc.CdoConsecutivo)
+
+	// Dates
+	var fecha time.Time
+	if r.required("cdo_fecha", "FAD09", doc.CdoFecha) {
+		parsed, err := time.Parse("2006-01-02", doc.CdoFecha)
+		if err != nil {
+			r.reject("cdo_fecha", "FAD09", "cdo_fecha debe tener el formato AAAA-MM-DD")
+		} else {
+			fecha = parsed
+			checkIssueDateIsToday(r, doc.CdoFecha)
+		}
+	}
+	if r.required("cdo_hora", "FAD10", doc.CdoHora) {
+		if _, err := time.Parse("15:04:05", doc.CdoHora); err != nil {
+			r.reject("cdo_hora", "FAD10", "cdo_hora debe tener el formato HH:mm:ss")
+		}
+	}
+	if doc.CdoVencimiento != nil && *doc.CdoVencimiento != "" {
+		dueDate, err := time.Parse("2006-01-02", *doc.CdoVencimiento)
+		if err != nil {
+			r.reject("cdo_vencimiento", "", "cdo_vencimiento debe tener el formato AAAA-MM-DD")
+		} else if !fecha.IsZero() && dueDate.Before(fecha) {
+			r.reject("cdo_vencimiento", "", "cdo_vencimiento (%s) debe ser igual o posterior a cdo_fecha (%s)", *doc.CdoVencimiento, doc.CdoFecha)
+		}
+	}
+
+	// Currency and totals
+	if r.required("mon_codigo", "FAD15", doc.MonCodigo) && !currency.Valid(doc.MonCodigo) {
+		r.reject("mon_codigo", "FAD15", "mon_codigo [%s] no es un código de moneda ISO 4217 válido", doc.MonCodigo)
+	}
+	r.required("cdo_valor_sin_impuestos", "", doc.CdoValorSinImpuestos)
+	r.required("cdo_impuestos", "", doc.CdoImpuestos)
+	r.required("cdo_total", "", doc.CdoTotal)
+
+	// Lines
+	if len(doc.Items) == 0 {
+		r.reject("items", "", "el documento debe tener al menos un ítem")
+	}
+
+	// Payment: credit documents should state when they are due
+	for i, mp := range doc.CdoMediosPago {
+		if mp.FpaCodigo == "2" && (mp.MenFechaVencimiento == nil || *mp.MenFechaVencimiento == "") && (doc.CdoVencimiento == nil || *doc.CdoVencimiento == "") {
+			r.notify(fmt.Sprintf("cdo_medios_pago[%d].men_fecha_vencimiento", i), "", "la forma de pago es crédito y no se indicó la fecha de vencimiento")
+		}
+	}
+
+	checkCodes(r, doc, documentType)
+
+	return r.violations
+}
+
+// checkIssueDateIsToday rejects a cdo_fecha other than today's date (Colombia timezone).
+// This is required by DIAN rule FAD09e: IssueDate must equal signature date.
+// When documents are signed by Numrot, they use the current date, so cdo_fecha must match.
+func checkIssueDateIsToday(r *ruleSet, cdoFecha string) {
+	// Get current date in Colombia timezone (UTC-5)
+	loc, err := time.LoadLocation("America/Bogota")
+	if err != nil {
+		// Fallback to UTC-5 offset if timezone data is not available
+		loc = time.FixedZone("America/Bogota", -5*60*60)
+	}
+	today := time.Now().In(loc).Format("2006-01-02")
+
+	if cdoFecha != today {
+		r.reject("cdo_fecha", "FAD09e", "cdo_fecha debe ser la fecha actual (%s), que es la fecha de firma del documento. Recibido: %s", today, cdoFecha)
+	}
+}
+
+// validateDocument validates a single document and returns its rejections as one error.
+func (s *Service) validateDocument(doc invoice.OpenETLDocument, documentType string, index int) error {
+	rejections := invoice.Rejections(documentViolations(doc, documentType))
+	if len(rejections) == 0 {
+		return nil
+	}
+	return fmt.Errorf("document %d: %s", index+1, strings.Join(violationMessages(rejections), "; "))
+}
+
+// violationMessages formats violations as the errors of a failed document.
+func violationMessages(violations []invoice.Violation) []string {
+	messages := make([]string, 0, len(violations))
+	for _, v := range violations {
+		messages = append(messages, v.String())
+	}
+	return messages
+}
+
+// ValidateDocuments runs the validation and enrichment of RegisterDocument and reports every
+// violation of each document. Nothing is sent to the provider nor recorded in the documents ledger.
+func (s *Service) ValidateDocuments(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentValidationResponse, error) {
+	documents, documentType, err := documentsByType(req)
+	if err != nil {
+		return nil, err
+	}
+
+	documents = s.fillTotals(documents)
+
+	reports := make([]invoice.DocumentValidation, len(documents))
+	byNumber := make(map[string]int, len(documents))
+	var validated []invoice.OpenETLDocument
+	for i, doc := range documents {
+		violations := documentViolations(doc, documentType)
+		reports[i] = invoice.DocumentValidation{
+			Documento:   documentType,
+			RfaPrefijo:  doc.RfaPrefijo,
+			Consecutivo: doc.CdoConsecutivo,
+			Violaciones: violations,
+		}
+		byNumber[doc.RfaPrefijo+"|"+doc.CdoConsecutivo] = i
+		if len(invoice.Rejections(violations)) == 0 {
+			validated = append(validated, doc)
+		}
+	}
+
+	// Enrichment failures (unknown acquirer, missing TRM, range...) are rejections too
+	prepared, failed, _ := s.prepareDocuments(ctx, validated, documentType)
+	for _, f := range failed {
+		i := byNumber[f.Prefijo+"|"+f.Consecutivo]
+		for _, msg := range f.Errors {
+			reports[i].Violaciones = append(reports[i].Violaciones, invoice.Violation{
+				Campo:     "documento",
+				Severidad: invoice.SeverityRejection,
+				Mensaje:   msg,
+			})
+		}
+	}
+	for _, doc := range prepared {
+		enriched := doc
+		reports[byNumber[doc.RfaPrefijo+"|"+doc.CdoConsecutivo]].Enriquecido = &enriched
+	}
+
+	response := &invoice.DocumentValidationResponse{Documentos: reports}
+	for i := range reports {
+		if reports[i].Violaciones == nil {
+			reports[i].Violaciones = make([]invoice.Violation, 0)
+		}
+		reports[i].Valido = len(invoice.Rejections(reports[i].Violaciones)) == 0
+		if reports[i].Valido {
+			response.Validos++
+		} else {
+			response.Invalidos++
+		}
+	}
+	return response, nil
+}
+
+// rejectedDocument builds the failed document reported for the rejections of a document.
+func rejectedDocument(doc invoice.OpenETLDocument, documentType string, rejections []invoice.Violation) invoice.FailedDocument {
+	now := time.Now()
+	return invoice.FailedDocument{
+		Documento:          documentType,
+		Consecutivo:        doc.CdoConsecutivo,
+		Prefijo:            doc.RfaPrefijo,
+		Errors:             violationMessages(rejections),
+		FechaProcesamiento: now.Format("2006-01-02"),
+		HoraProcesamiento:  now.Format("15:04:05"),
+	}
+}
@@ -0,0 +1,141 @@
+package invoice
+
+import (
+	"context"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func TestDocumentViolations_CollectsEveryViolation(t *testing.T) {
+	doc := newLedgerTestDocument("1")
+	doc.CdoFecha = "2024/01/01"
+	doc.CdoHora = "2pm"
+	doc.MonCodigo = "PESOS"
+	doc.Items[0].UndCodigo = "CAJA"
+	doc.CdoMediosPago = []invoice.OpenETLMedioPago{{FpaCodigo: "2", MpaCodigo: "10"}}
+
+	violations := documentViolations(doc, "FC")
+
+	want := map[string]invoice.Violation{
+		"cdo_fecha":           {Regla: "FAD09", Severidad: invoice.SeverityRejection},
+		"cdo_hora":            {Regla: "FAD10", Severidad: invoice.SeverityRejection},
+		"mon_codigo":          {Regla: "FAD15", Severidad: invoice.SeverityRejection},
+		"items[0].und_codigo": {Severidad: invoice.SeverityNotification},
+		"cdo_medios_pago[0].men_fecha_vencimiento": {Severidad: invoice.SeverityNotification},
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("expected %d violations, got %+v", len(want), violations)
+	}
+	for _, v := range violations {
+		w, ok := want[v.Campo]
+		if !ok {
+			t.Errorf("unexpected violation %+v", v)
+			continue
+		}
+		if v.Regla != w.Regla || v.Severidad != w.Severidad || v.Mensaje == "" {
+			t.Errorf("violation %s: expected rule %q severity %q, got %+v", v.Campo, w.Regla, w.Severidad, v)
+		}
+	}
+	if got := len(invoice.Rejections(violations)); got != 3 {
+		t.Errorf("expected 3 rejections, got %d", got)
+	}
+}
+
+func TestDocumentViolations_NotificationDoesNotReject(t *testing.T) {
+	doc := newLedgerTestDocument("1")
+	doc.CdoMediosPago = []invoice.OpenETLMedioPago{{FpaCodigo: "2", MpaCodigo: "10"}}
+
+	violations := documentViolations(doc, "FC")
+	if len(violations) != 1 || violations[0].Severidad != invoice.SeverityNotification {
+		t.Fatalf("expected one notification, got %+v", violations)
+	}
+
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2")
+	if err := service.validateDocument(doc, "FC", 0); err != nil {
+		t.Errorf("expected notifications not to fail the document, got %v", err)
+	}
+}
+
+func TestService_RegisterDocument_FailsOnlyInvalidDocuments(t *testing.T) {
+	var sent []invoice.OpenETLDocument
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			sent = req.Documentos.FC
+			return &invoice.DocumentRegistrationResponse{
+				DocumentosProcesados: []invoice.ProcessedDocument{{RfaPrefijo: "SETT", CdoConsecutivo: "1"}},
+			}, nil
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2")
+
+	invalid := newLedgerTestDocument("2")
+	invalid.Items = nil
+	resp, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1"), invalid}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sent) != 1 || sent[0].CdoConsecutivo != "1" {
+		t.Errorf("expected only the valid document to be sent, got %+v", sent)
+	}
+	if len(resp.DocumentosProcesados) != 1 {
+		t.Errorf("expected 1 processed document, got %+v", resp.DocumentosProcesados)
+	}
+	if len(resp.DocumentosFallidos) != 1 || resp.DocumentosFallidos[0].Consecutivo != "2" {
+		t.Fatalf("expected document 2 to fail, got %+v", resp.DocumentosFallidos)
+	}
+	if got := resp.DocumentosFallidos[0].Errors; len(got) != 1 || got[0] != "items: el documento debe tener al menos un ítem" {
+		t.Errorf("unexpected errors %v", got)
+	}
+}
+
+func TestService_ValidateDocuments(t *testing.T) {
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			t.Fatal("validation must not send documents")
+			return nil, nil
+		},
+	}
+	ledger := testutil.NewMockDocumentRepository()
+	service := NewService(mockProvider, nil, nil, "2").WithLedger(ledger, testutil.NewNullLogger())
+
+	invalid := newLedgerTestDocument("2")
+	invalid.TdeCodigo = "03"
+	invalid.MonCodigo = "PESOS"
+	resp, err := service.ValidateDocuments(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1"), invalid}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Validos != 1 || resp.Invalidos != 1 || len(resp.Documentos) != 2 {
+		t.Fatalf("unexpected report %+v", resp)
+	}
+	valid := resp.Documentos[0]
+	if !valid.Valido || len(valid.Violaciones) != 0 || valid.Enriquecido == nil {
+		t.Errorf("expected document 1 to be valid and enriched, got %+v", valid)
+	}
+	if valid.Enriquecido != nil && valid.Enriquecido.CdoAmbiente == nil {
+		t.Error("expected the enriched document to carry the environment")
+	}
+	report := resp.Documentos[1]
+	if report.Valido || report.Enriquecido != nil || len(report.Violaciones) != 2 {
+		t.Errorf("expected document 2 to report both violations, got %+v", report)
+	}
+
+	if doc, _ := ledger.FindByKey(context.Background(), ledgerTestKey("1")); doc != nil {
+		t.Errorf("expected nothing recorded in the ledger, got %+v", doc)
+	}
+}
+
+func TestService_ValidateDocuments_NoDocuments(t *testing.T) {
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2")
+	if _, err := service.ValidateDocuments(context.Background(), invoice.DocumentRegistrationRequest{}); err == nil {
+		t.Error("expected error for a request without documents")
+	}
+}
@@ -0,0 +1,67 @@
+package invoice
+
+import (
+	"context"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/withholding"
+)
+
+// WithholdingCalculator computes the suggested retentions of a document.
+// It is implemented by the withholding application service.
+type WithholdingCalculator interface {
+	Suggest(ctx context.Context, doc invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLRetencion, error)
+}
+
+// WithWithholdings enables the computation of cdo_detalle_retenciones_sugeridas and
+// cdo_retenciones_sugeridas. When override is false, the retentions sent in the request
+// are kept and only documents without them are completed.
+func (s *Service) WithWithholdings(calculator WithholdingCalculator, override bool) *Service {
+	s.retentions = calculator
+	s.retentionOverride = override
+	return s
+}
+
+// applyWithholdings completes the suggested retentions of the documents. Documents whose
+// retentions cannot be computed (e.g. no UVT configured for their year) are rejected.
+func (s *Service) applyWithholdings(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.FailedDocument) {
+	if s.retentions == nil {
+		return documents, nil
+	}
+
+	now := time.Now()
+	fechaProcesamiento := now.Format("2006-01-02")
+	horaProcesamiento := now.Format("15:04:05")
+
+	valid := make([]invoice.OpenETLDocument, 0, len(documents))
+	var failed []invoice.FailedDocument
+
+	for _, doc := range documents {
+		if len(doc.CdoDetalleRetencionesSugeridas) > 0 && !s.retentionOverride {
+			valid = append(valid, doc)
+			continue
+		}
+
+		retenciones, err := s.retentions.Suggest(ctx, doc, documentType)
+		if err == nil {
+			doc.CdoRetencionesSugeridas, err = withholding.Total(retenciones)
+		}
+		if err != nil {
+			failed = append(failed, invoice.FailedDocument{
+				Documento:          documentType,
+				Consecutivo:        doc.CdoConsecutivo,
+				Prefijo:            doc.RfaPrefijo,
+				Errors:             []string{err.Error()},
+				FechaProcesamiento: fechaProcesamiento,
+				HoraProcesamiento:  horaProcesamiento,
+			})
+			continue
+		}
+
+		doc.CdoDetalleRetencionesSugeridas = retenciones
+		valid = append(valid, doc)
+	}
+
+	return valid, failed
+}
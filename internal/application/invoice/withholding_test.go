@@ -0,0 +1,74 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+// stubWithholdings suggests a 4% ReteFuente, or fails for consecutivo "9".
+type stubWithholdings struct{}
+
+func (stubWithholdings) Suggest(ctx context.Context, doc invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLRetencion, error) {
+	if doc.CdoConsecutivo == "9" {
+		return nil, errors.New("no hay valor UVT configurado para el año [2026]")
+	}
+	return []invoice.OpenETLRetencion{{
+		Tipo:                "RETEFUENTE",
+		Razon:               "Servicios generales",
+		Porcentaje:          "4.00",
+		ValorMonedaNacional: invoice.OpenETLValorMonedaNacional{Base: doc.CdoValorSinImpuestos, Valor: "4000.00"},
+	}}, nil
+}
+
+func TestService_ApplyWithholdings(t *testing.T) {
+	service := NewService(&testutil.MockProvider{}, nil, nil, "2").WithWithholdings(stubWithholdings{}, false)
+
+	provided := newLedgerTestDocument("2")
+	provided.CdoRetencionesSugeridas = "1000.00"
+	provided.CdoDetalleRetencionesSugeridas = []invoice.OpenETLRetencion{{Tipo: "RETEICA", Porcentaje: "1", ValorMonedaNacional: invoice.OpenETLValorMonedaNacional{Base: "100000.00", Valor: "1000.00"}}}
+
+	valid, failed := service.applyWithholdings(context.Background(), []invoice.OpenETLDocument{newLedgerTestDocument("1"), provided, newLedgerTestDocument("9")}, "FC")
+
+	if len(valid) != 2 || len(failed) != 1 {
+		t.Fatalf("expected 2 valid and 1 failed document, got %d/%d", len(valid), len(failed))
+	}
+	if valid[0].CdoRetencionesSugeridas != "4000.00" || len(valid[0].CdoDetalleRetencionesSugeridas) != 1 {
+		t.Errorf("expected computed retentions, got %s %+v", valid[0].CdoRetencionesSugeridas, valid[0].CdoDetalleRetencionesSugeridas)
+	}
+	if valid[1].CdoDetalleRetencionesSugeridas[0].Tipo != "RETEICA" {
+		t.Errorf("expected request retentions to be kept, got %+v", valid[1].CdoDetalleRetencionesSugeridas)
+	}
+	if failed[0].Consecutivo != "9" {
+		t.Errorf("unexpected failed document: %+v", failed[0])
+	}
+
+	service.WithWithholdings(stubWithholdings{}, true)
+	valid, _ = service.applyWithholdings(context.Background(), []invoice.OpenETLDocument{provided}, "FC")
+	if valid[0].CdoDetalleRetencionesSugeridas[0].Tipo != "RETEFUENTE" || valid[0].CdoRetencionesSugeridas != "4000.00" {
+		t.Errorf("expected request retentions to be replaced, got %+v", valid[0].CdoDetalleRetencionesSugeridas)
+	}
+}
+
+func TestService_RegisterDocument_WithholdingsTotals(t *testing.T) {
+	var sent []invoice.OpenETLDocument
+	mockProvider := &testutil.MockProvider{
+		RegisterDocumentFunc: func(ctx context.Context, req invoice.DocumentRegistrationRequest) (*invoice.DocumentRegistrationResponse, error) {
+			sent = req.Documentos.FC
+			return &invoice.DocumentRegistrationResponse{}, nil
+		},
+	}
+	service := NewService(mockProvider, nil, nil, "2").WithWithholdings(stubWithholdings{}, true).WithTotalsCheck(false)
+
+	if _, err := service.RegisterDocument(context.Background(), invoice.DocumentRegistrationRequest{
+		Documentos: invoice.DocumentsByType{FC: []invoice.OpenETLDocument{newLedgerTestDocument("1")}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sent) != 1 || sent[0].CdoRetencionesSugeridas != "4000.00" {
+		t.Fatalf("expected the document to be sent with its retentions, got %+v", sent)
+	}
+}
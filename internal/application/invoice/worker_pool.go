@@ -3,15 +3,17 @@ package invoice
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/acquirer"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	"3tcapital/ms_facturacion_core/internal/core/provider"
-	coreresolution "3tcapital/ms_facturacion_core/internal/core/resolution"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"3tcapital/goclonacion/internal/core/acquirer"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/ofe"
+	"3tcapital/goclonacion/internal/core/provider"
 )
 
 // DocumentJob represents a job to be processed by a worker
@@ -42,13 +44,12 @@ type DocumentWorkerPool struct {
 	wg                 sync.WaitGroup
 	ctx                context.Context
 	cancel             context.CancelFunc
-	provider           invoice.Provider // For querying resolutions
-	cdoAmbienteDefault string           // Default environment value
-	resolutionCache    *sync.Map        // Cache for resolutions: key = "ofe:resolution:prefix", value = *coreresolution.Resolution
+	cdoAmbienteDefault string       // Default environment value
+	ofes               *ofeResolver // Optional: nil if the OFE registry is disabled
 }
 
 // NewDocumentWorkerPool creates a new worker pool for document processing
-func NewDocumentWorkerPool(ctx context.Context, workerCount int, acquirerRepo acquirer.Repository, providerRepo provider.Repository, provider invoice.Provider, cdoAmbienteDefault string) *DocumentWorkerPool {
+func NewDocumentWorkerPool(ctx context.Context, workerCount int, acquirerRepo acquirer.Repository, providerRepo provider.Repository, cdoAmbienteDefault string) *DocumentWorkerPool {
 	poolCtx, cancel := context.WithCancel(ctx)
 
 	return &DocumentWorkerPool{
@@ -59,14 +60,18 @@ func NewDocumentWorkerPool(ctx context.Context, workerCount int, acquirerRepo ac
 		acquirerCache:      &sync.Map{},
 		providerRepo:       providerRepo,
 		providerCache:      &sync.Map{},
-		provider:           provider,
 		cdoAmbienteDefault: cdoAmbienteDefault,
-		resolutionCache:    &sync.Map{},
 		ctx:                poolCtx,
 		cancel:             cancel,
 	}
 }
 
+// WithOFERegistry enables the issuer lookup in the OFE registry.
+func (p *DocumentWorkerPool) WithOFERegistry(repo ofe.Repository) *DocumentWorkerPool {
+	p.ofes = newOFEResolver(repo)
+	return p
+}
+
 // Start starts the worker pool with the specified number of workers
 func (p *DocumentWorkerPool) Start() {
 	for i := 0; i < p.workerCount; i++ {
@@ -103,7 +108,7 @@ func (p *DocumentWorkerPool) worker(id int) {
 	defer p.wg.Done()
 
 	for job := range p.jobChan {
-		result := p.processDocument(job)
+		result := p.traceDocument(job)
 
 		select {
 		case p.resultChan <- result:
@@ -113,42 +118,65 @@ func (p *DocumentWorkerPool) worker(id int) {
 	}
 }
 
+// traceDocument processes a document job within its own span, so that the lookups
+// of each job appear as its children in the trace of the request.
+func (p *DocumentWorkerPool) traceDocument(job DocumentJob) DocumentResult {
+	ctx, span := tracer.Start(p.ctx, "invoice.worker.processDocument", trace.WithAttributes(
+		attribute.String("document.type", job.DocumentType),
+		attribute.String("document.prefix", job.Document.RfaPrefijo),
+		attribute.String("document.consecutivo", job.Document.CdoConsecutivo),
+	))
+	defer span.End()
+
+	result := p.processDocument(ctx, job)
+	if result.Failed {
+		span.SetAttributes(attribute.String("document.error", result.ErrorMessage))
+	}
+	return result
+}
+
 // processDocument processes a single document job
-func (p *DocumentWorkerPool) processDocument(job DocumentJob) DocumentResult {
+func (p *DocumentWorkerPool) processDocument(ctx context.Context, job DocumentJob) DocumentResult {
 	result := DocumentResult{
 		Document: job.Document,
 		Index:    job.Index,
 		Failed:   false,
 	}
 
-	// 1. Enrich cdo_ambiente if missing
-	result.Document = enrichDocumentWithEnvironment(job.Document, p.cdoAmbienteDefault)
+	// 1. Validate the issuer against the OFE registry and complete its data
+	o, err := p.ofes.resolve(ctx, job.Document, job.DocumentType)
+	if err != nil {
+		result.Failed = true
+		result.Error = err
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	result.Document = enrichDocumentWithOFE(job.Document, o)
+
+	// 2. Enrich cdo_ambiente if missing
+	result.Document = enrichDocumentWithEnvironment(result.Document, p.cdoAmbienteDefault)
 
-	// 2. Validate and enrich acquirer/provider based on document type
+	// 3. Validate and enrich acquirer/provider based on document type
 	// For DS documents, use provider repository; for FC/NC/ND, use acquirer repository
 	if job.DocumentType == "DS" {
 		// DS documents: validate and enrich with provider data
 		// IMPORTANT: For DS documents, the mapping is inverted:
 		// - adq_identificacion (from request) maps to ofe_identificacion (in provider table)
 		// - ofe_identificacion (from request) maps to pro_identificacion (in provider table)
-		// If no provider repository, enrich with OFE data only
 		if p.providerRepo == nil {
-			result.Document = enrichDocumentWithOFE(result.Document)
 			return result
 		}
 
 		// Check cache first
 		// Cache key uses normalized NITs to ensure cache hits work correctly regardless of DV format
 		// (matching the inverted mapping used in FindByID)
-		normalizedAdqNIT := normalizeNIT(job.Document.AdqIdentificacion)
-		normalizedOfeNIT := normalizeNIT(job.Document.OfeIdentificacion)
+		normalizedAdqNIT := identification.Base(job.Document.AdqIdentificacion)
+		normalizedOfeNIT := identification.Base(job.Document.OfeIdentificacion)
 		cacheKey := normalizedAdqNIT + ":" + normalizedOfeNIT
 		if cached, ok := p.providerCache.Load(cacheKey); ok {
 			if prov, ok := cached.(*provider.Provider); ok && prov != nil {
 				// Enrich document with cached provider data
 				result.Document = enrichDocumentWithProvider(result.Document, prov)
-				// Enrich document with hardcoded OFE data
-				result.Document = enrichDocumentWithOFE(result.Document)
 				return result
 			}
 		}
@@ -156,7 +184,7 @@ func (p *DocumentWorkerPool) processDocument(job DocumentJob) DocumentResult {
 		// Lookup provider from repository
 		// Note: Parameters are inverted for DS - adq_identificacion maps to ofe_identificacion in DB
 		// Use normalized NITs for consistent lookups
-		prov, err := p.providerRepo.FindByID(p.ctx, normalizedAdqNIT, normalizedOfeNIT)
+		prov, err := p.providerRepo.FindByID(ctx, normalizedAdqNIT, normalizedOfeNIT)
 		if err != nil {
 			result.Failed = true
 			result.Error = err
@@ -175,37 +203,31 @@ func (p *DocumentWorkerPool) processDocument(job DocumentJob) DocumentResult {
 		p.providerCache.Store(cacheKey, prov)
 
 		// Enrich document with provider data
-		result.Document = enrichDocumentWithProvider(job.Document, prov)
-		// Enrich document with hardcoded OFE data
-		result.Document = enrichDocumentWithOFE(result.Document)
+		result.Document = enrichDocumentWithProvider(result.Document, prov)
 		return result
 	}
 
 	// FC/NC/ND documents: validate and enrich with acquirer data (existing logic)
-	// If no acquirer repository, enrich with OFE data only
 	if p.acquirerRepo == nil {
-		result.Document = enrichDocumentWithOFE(result.Document)
 		return result
 	}
 
 	// Check cache first
 	// Cache key uses normalized NITs to ensure cache hits work correctly regardless of DV format
-	normalizedOfeNIT := normalizeNIT(job.Document.OfeIdentificacion)
-	normalizedAdqNIT := normalizeNIT(job.Document.AdqIdentificacion)
+	normalizedOfeNIT := identification.Base(job.Document.OfeIdentificacion)
+	normalizedAdqNIT := identification.Base(job.Document.AdqIdentificacion)
 	cacheKey := normalizedOfeNIT + ":" + normalizedAdqNIT
 	if cached, ok := p.acquirerCache.Load(cacheKey); ok {
 		if acq, ok := cached.(*acquirer.Acquirer); ok && acq != nil {
 			// Enrich document with cached acquirer data
 			result.Document = enrichDocumentWithAcquirer(result.Document, acq)
-			// Enrich document with hardcoded OFE data
-			result.Document = enrichDocumentWithOFE(result.Document)
 			return result
 		}
 	}
 
 	// Lookup acquirer from repository
 	// Use normalized NITs for consistent lookups
-	acq, err := p.acquirerRepo.FindByID(p.ctx, normalizedOfeNIT, normalizedAdqNIT, "")
+	acq, err := p.acquirerRepo.FindByID(ctx, normalizedOfeNIT, normalizedAdqNIT, "")
 	if err != nil {
 		result.Failed = true
 		result.Error = err
@@ -223,9 +245,7 @@ func (p *DocumentWorkerPool) processDocument(job DocumentJob) DocumentResult {
 	p.acquirerCache.Store(cacheKey, acq)
 
 	// Enrich document with acquirer data
-	result.Document = enrichDocumentWithAcquirer(job.Document, acq)
-	// Enrich document with hardcoded OFE data
-	result.Document = enrichDocumentWithOFE(result.Document)
+	result.Document = enrichDocumentWithAcquirer(result.Document, acq)
 	return result
 }
 
@@ -307,6 +327,14 @@ func enrichDocumentWithAcquirer(doc invoice.OpenETLDocument, acq *acquirer.Acqui
 		}
 	}
 
+	// Tax responsibilities, used to compute the suggested retentions
+	if len(doc.AdqRefCodigo) == 0 {
+		doc.AdqRefCodigo = acq.RefCodigo
+	}
+	if len(doc.AdqResponsableTributos) == 0 {
+		doc.AdqResponsableTributos = acq.ResponsableTributos
+	}
+
 	return doc
 }
 
@@ -382,33 +410,10 @@ func enrichDocumentWithProvider(doc invoice.OpenETLDocument, prov *provider.Prov
 		}
 	}
 
-	return doc
-}
-
-// enrichDocumentWithOFE enriches a document with hardcoded OFE information.
-// These values are hardcoded as per business requirements:
-// - ofe_razon_social: "Positiva SAS"
-// - ofe_direccion: "CLL 50 - 96"
-// - ofe_municipio_codigo: "05380"
-// - ofe_municipio_nombre: "LA ESTRELLA"
-// - ofe_departamento_codigo: "05"
-// - ofe_departamento_nombre: "ANTIOQUIA"
-func enrichDocumentWithOFE(doc invoice.OpenETLDocument) invoice.OpenETLDocument {
-	// Hardcoded OFE values
-	ofeRazonSocial := "Positiva SAS"
-	ofeDireccion := "CLL 50 - 96"
-	ofeMunicipioCodigo := "05380"
-	ofeMunicipioNombre := "LA ESTRELLA"
-	ofeDepartamentoCodigo := "05"
-	ofeDepartamentoNombre := "ANTIOQUIA"
-
-	// Set OFE fields (override any values provided in request)
-	doc.OfeRazonSocial = &ofeRazonSocial
-	doc.OfeDireccion = &ofeDireccion
-	doc.OfeMunicipioCodigo = &ofeMunicipioCodigo
-	doc.OfeMunicipioNombre = &ofeMunicipioNombre
-	doc.OfeDepartamentoCodigo = &ofeDepartamentoCodigo
-	doc.OfeDepartamentoNombre = &ofeDepartamentoNombre
+	// Tax responsibilities of the provider, used to compute the suggested retentions
+	if len(doc.AdqRefCodigo) == 0 {
+		doc.AdqRefCodigo = prov.RefCodigo
+	}
 
 	return doc
 }
@@ -422,138 +427,6 @@ func enrichDocumentWithEnvironment(doc invoice.OpenETLDocument, defaultEnv strin
 	return doc
 }
 
-// enrichDocumentWithResolution enriches a document with resolution data if fields are missing.
-// documentType is used to determine if resolution validation is required (DS doesn't require it).
-func (p *DocumentWorkerPool) enrichDocumentWithResolution(doc invoice.OpenETLDocument, documentType string) (invoice.OpenETLDocument, error) {
-	// Check if resolution fields are already present
-	hasAllFields := doc.RfaFechaInicio != nil && *doc.RfaFechaInicio != "" &&
-		doc.RfaFechaFin != nil && *doc.RfaFechaFin != "" &&
-		doc.RfaNumeroInicio != nil && *doc.RfaNumeroInicio != "" &&
-		doc.RfaNumeroFin != nil && *doc.RfaNumeroFin != ""
-
-	if hasAllFields {
-		return doc, nil // All fields present, no need to enrich
-	}
-
-	// For DS, NC, and ND documents, resolution is optional - if not found, return without error
-	// For FC documents, resolution is required
-	findResolution := true
-	if (documentType == "DS" || documentType == "NC" || documentType == "ND") && (doc.RfaResolucion == "" || doc.RfaPrefijo == "") {
-		// DS, NC, and ND don't require resolution if not provided
-		findResolution = false
-	}
-
-	if !findResolution {
-		// For DS, NC, or ND without resolution, return document as-is
-		return doc, nil
-	}
-
-	// Find resolution
-	resolution, err := p.findResolution(doc.OfeIdentificacion, doc.RfaResolucion, doc.RfaPrefijo)
-	if err != nil {
-		// For DS, NC, and ND, if resolution is not found, it's not a fatal error
-		if documentType == "DS" || documentType == "NC" || documentType == "ND" {
-			return doc, nil // Return document without resolution data
-		}
-		return doc, err
-	}
-
-	// Populate missing fields
-	if doc.RfaFechaInicio == nil || *doc.RfaFechaInicio == "" {
-		fechaInicio := resolution.ValidDateFrom.Format("2006-01-02")
-		doc.RfaFechaInicio = &fechaInicio
-	}
-
-	if doc.RfaFechaFin == nil || *doc.RfaFechaFin == "" {
-		fechaFin := resolution.ValidDateTo.Format("2006-01-02")
-		doc.RfaFechaFin = &fechaFin
-	}
-
-	if doc.RfaNumeroInicio == nil || *doc.RfaNumeroInicio == "" {
-		numInicio := fmt.Sprintf("%d", resolution.FromNumber)
-		doc.RfaNumeroInicio = &numInicio
-	}
-
-	if doc.RfaNumeroFin == nil || *doc.RfaNumeroFin == "" {
-		numFin := fmt.Sprintf("%d", resolution.ToNumber)
-		doc.RfaNumeroFin = &numFin
-	}
-
-	return doc, nil
-}
-
-// validateResolutionData validates that the document data matches the resolution.
-func (p *DocumentWorkerPool) validateResolutionData(doc invoice.OpenETLDocument) error {
-	// Find resolution
-	resolution, err := p.findResolution(doc.OfeIdentificacion, doc.RfaResolucion, doc.RfaPrefijo)
-	if err != nil {
-		return err
-	}
-
-	// Validate consecutivo is within range
-	consecutivo, err := strconv.ParseInt(doc.CdoConsecutivo, 10, 64)
-	if err != nil {
-		return fmt.Errorf("consecutivo inválido [%s]: %v", doc.CdoConsecutivo, err)
-	}
-
-	if consecutivo < resolution.FromNumber || consecutivo > resolution.ToNumber {
-		return fmt.Errorf("consecutivo [%s] fuera del rango autorizado [%d-%d] para la resolución [%s]",
-			doc.CdoConsecutivo, resolution.FromNumber, resolution.ToNumber, doc.RfaResolucion)
-	}
-
-	// Validate date is within validity period
-	docDate, err := time.Parse("2006-01-02", doc.CdoFecha)
-	if err != nil {
-		return fmt.Errorf("fecha de documento inválida [%s]: %v", doc.CdoFecha, err)
-	}
-
-	if docDate.Before(resolution.ValidDateFrom) || docDate.After(resolution.ValidDateTo) {
-		return fmt.Errorf("fecha [%s] fuera de la vigencia [%s - %s] de la resolución [%s]",
-			doc.CdoFecha,
-			resolution.ValidDateFrom.Format("2006-01-02"),
-			resolution.ValidDateTo.Format("2006-01-02"),
-			doc.RfaResolucion)
-	}
-
-	return nil
-}
-
-// findResolution finds a resolution by OFE, number, and prefix (with caching).
-func (p *DocumentWorkerPool) findResolution(ofeIdentificacion, resolutionNumber, prefix string) (*coreresolution.Resolution, error) {
-	// Extract base NIT (without DV) for resolution query
-	// The system receives NIT with DV (e.g., "860011153-6") but Numrot API expects only the base NIT (e.g., "860011153")
-	baseNIT := ofeIdentificacion
-	if parts := strings.Split(ofeIdentificacion, "-"); len(parts) > 1 {
-		baseNIT = parts[0]
-	}
-
-	// Check cache first (using base NIT for cache key)
-	cacheKey := baseNIT + ":" + resolutionNumber + ":" + prefix
-	if cached, ok := p.resolutionCache.Load(cacheKey); ok {
-		if resolution, ok := cached.(*coreresolution.Resolution); ok {
-			return resolution, nil
-		}
-	}
-
-	// Query resolutions from provider using base NIT
-	resolutions, err := p.provider.GetResolutions(p.ctx, baseNIT)
-	if err != nil {
-		return nil, fmt.Errorf("error al consultar resoluciones para OFE [%s]: %w", baseNIT, err)
-	}
-
-	// Find matching resolution
-	for i := range resolutions {
-		if resolutions[i].ResolutionNumber == resolutionNumber && resolutions[i].Prefix == prefix {
-			// Cache the resolution
-			p.resolutionCache.Store(cacheKey, &resolutions[i])
-			return &resolutions[i], nil
-		}
-	}
-
-	return nil, fmt.Errorf("resolución [%s] con prefijo [%s] no encontrada para el OFE [%s]",
-		resolutionNumber, prefix, ofeIdentificacion)
-}
-
 // ProcessDocuments processes documents concurrently using the worker pool
 func (p *DocumentWorkerPool) ProcessDocuments(ctx context.Context, documents []invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLDocument, []invoice.FailedDocument) {
 	startTime := time.Now()
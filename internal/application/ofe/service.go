@@ -0,0 +1,319 @@
+package ofe
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/codelist"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/ofe"
+)
+
+// Service orchestrates OFE registry use cases.
+type Service struct {
+	repo ofe.Repository
+}
+
+// NewService creates a new OFE service with the given repository.
+func NewService(repo ofe.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// OFEData holds the fields of an OFE that can be created and updated.
+type OFEData struct {
+	OfeRazonSocial     string                    `json:"ofe_razon_social"`
+	OfeNombreComercial *string                   `json:"ofe_nombre_comercial"`
+	TdoCodigo          string                    `json:"tdo_codigo"`
+	TojCodigo          string                    `json:"toj_codigo"`
+	OfeDireccion       string                    `json:"ofe_direccion"`
+	PaiCodigo          string                    `json:"pai_codigo"`
+	DepCodigo          string                    `json:"dep_codigo"`
+	DepNombre          string                    `json:"dep_nombre"`
+	MunCodigo          string                    `json:"mun_codigo"`
+	MunNombre          string                    `json:"mun_nombre"`
+	CpoCodigo          *string                   `json:"cpo_codigo"`
+	OfeTelefono        *string                   `json:"ofe_telefono"`
+	OfeCorreo          *string                   `json:"ofe_correo"`
+	RfiCodigo          *string                   `json:"rfi_codigo"`
+	RefCodigo          []string                  `json:"ref_codigo"`
+	CdoAmbiente        *string                   `json:"cdo_ambiente"`
+	Numrot             *NumrotCredentialsRequest `json:"numrot"`
+	Estado             *string                   `json:"estado"`
+}
+
+// NumrotCredentialsRequest carries the Numrot credentials of an OFE.
+type NumrotCredentialsRequest struct {
+	Usuario  string `json:"usuario"`
+	Password string `json:"password"`
+	Key      string `json:"key"`
+	Secret   string `json:"secret"`
+}
+
+// CreateOFERequest represents the request to create an OFE.
+type CreateOFERequest struct {
+	OfeIdentificacion string `json:"ofe_identificacion"`
+	OFEData
+}
+
+// UpdateOFERequest represents the request to update an OFE. When numrot is
+// omitted the stored credentials are kept.
+type UpdateOFERequest struct {
+	OFEData
+}
+
+// CreateOFEResponse represents the response from creating an OFE.
+type CreateOFEResponse struct {
+	Success bool  `json:"success"`
+	OfeID   int64 `json:"ofe_id"`
+}
+
+// ListOFEsResponse represents the response from listing OFEs.
+type ListOFEsResponse struct {
+	Total     int       `json:"total"`
+	Filtrados int       `json:"filtrados"`
+	Data      []ofe.OFE `json:"data"`
+}
+
+var (
+	nitPattern       = regexp.MustCompile(`^[0-9]{5,15}$`)
+	depCodigoPattern = regexp.MustCompile(`^[0-9]{2}$`)
+	munCodigoPattern = regexp.MustCompile(`^[0-9]{5}$`)
+	emailPattern     = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+)
+
+// CreateOFE registers a new OFE. The identification is stored without verification digit.
+func (s *Service) CreateOFE(ctx context.Context, req CreateOFERequest) (*CreateOFEResponse, error) {
+	ofeIdentificacion := identification.Base(req.OfeIdentificacion)
+	if ofeIdentificacion == "" {
+		return nil, fmt.Errorf("ofe_identificacion es requerido")
+	}
+	if !nitPattern.MatchString(ofeIdentificacion) {
+		return nil, fmt.Errorf("ofe_identificacion debe ser numérico, entre 5 y 15 dígitos")
+	}
+	if err := identification.ValidateNIT(req.OfeIdentificacion); err != nil {
+		return nil, fmt.Errorf("ofe_identificacion: %w", err)
+	}
+	if err := validateData(req.OFEData); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindByIdentificacion(ctx, ofeIdentificacion)
+	if err != nil {
+		return nil, fmt.Errorf("check ofe existence: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("ya existe un OFE con el numero de identificacion [%s]", ofeIdentificacion)
+	}
+
+	o := dataToOFE(req.OFEData)
+	o.OfeIdentificacion = ofeIdentificacion
+
+	id, err := s.repo.Create(ctx, o)
+	if err != nil {
+		return nil, fmt.Errorf("create ofe: %w", err)
+	}
+
+	return &CreateOFEResponse{
+		Success: true,
+		OfeID:   id,
+	}, nil
+}
+
+// UpdateOFE updates an existing OFE.
+func (s *Service) UpdateOFE(ctx context.Context, ofeIdentificacion string, req UpdateOFERequest) error {
+	ofeIdentificacion = identification.Base(ofeIdentificacion)
+	if ofeIdentificacion == "" {
+		return fmt.Errorf("ofe_identificacion es requerido")
+	}
+	if err := validateData(req.OFEData); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.FindByIdentificacion(ctx, ofeIdentificacion)
+	if err != nil {
+		return fmt.Errorf("check ofe existence: %w", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("el OFE [%s] no existe", ofeIdentificacion)
+	}
+
+	if err := s.repo.Update(ctx, ofeIdentificacion, dataToOFE(req.OFEData)); err != nil {
+		return fmt.Errorf("update ofe: %w", err)
+	}
+
+	return nil
+}
+
+// GetOFE retrieves an OFE by its identification (with or without verification digit).
+func (s *Service) GetOFE(ctx context.Context, ofeIdentificacion string) (*ofe.OFE, error) {
+	ofeIdentificacion = identification.Base(ofeIdentificacion)
+	if ofeIdentificacion == "" {
+		return nil, fmt.Errorf("ofe_identificacion es requerido")
+	}
+
+	o, err := s.repo.FindByIdentificacion(ctx, ofeIdentificacion)
+	if err != nil {
+		return nil, fmt.Errorf("get ofe: %w", err)
+	}
+	if o == nil {
+		return nil, fmt.Errorf("el OFE [%s] no existe", ofeIdentificacion)
+	}
+
+	return o, nil
+}
+
+// ListOFEs lists OFEs with pagination and search.
+func (s *Service) ListOFEs(ctx context.Context, start, length int, buscar string) (*ListOFEsResponse, error) {
+	if start < 0 {
+		start = 0
+	}
+
+	ofes, total, err := s.repo.List(ctx, start, length, strings.TrimSpace(buscar))
+	if err != nil {
+		return nil, fmt.Errorf("list ofes: %w", err)
+	}
+	if ofes == nil {
+		ofes = make([]ofe.OFE, 0)
+	}
+
+	return &ListOFEsResponse{
+		Total:     total,
+		Filtrados: len(ofes),
+		Data:      ofes,
+	}, nil
+}
+
+// validateData validates the fields shared by create and update requests.
+func validateData(data OFEData) error {
+	required := []struct {
+		value string
+		field string
+	}{
+		{data.OfeRazonSocial, "ofe_razon_social"},
+		{data.TdoCodigo, "tdo_codigo"},
+		{data.TojCodigo, "toj_codigo"},
+		{data.OfeDireccion, "ofe_direccion"},
+		{data.DepCodigo, "dep_codigo"},
+		{data.DepNombre, "dep_nombre"},
+		{data.MunCodigo, "mun_codigo"},
+		{data.MunNombre, "mun_nombre"},
+	}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			return fmt.Errorf("%s es requerido", r.field)
+		}
+	}
+
+	if len(data.OfeRazonSocial) > 255 {
+		return fmt.Errorf("ofe_razon_social excede la longitud máxima de 255 caracteres")
+	}
+	if len(data.OfeDireccion) > 255 {
+		return fmt.Errorf("ofe_direccion excede la longitud máxima de 255 caracteres")
+	}
+	if !identification.ValidType(data.TdoCodigo) {
+		return fmt.Errorf("tdo_codigo [%s] inválido", data.TdoCodigo)
+	}
+	if data.TojCodigo != "1" && data.TojCodigo != "2" {
+		return fmt.Errorf("toj_codigo debe ser '1' (jurídica) o '2' (natural)")
+	}
+
+	// DIVIPOLA: the municipality code starts with the department code
+	if !depCodigoPattern.MatchString(data.DepCodigo) {
+		return fmt.Errorf("dep_codigo debe ser el código DIVIPOLA de 2 dígitos del departamento")
+	}
+	if !munCodigoPattern.MatchString(data.MunCodigo) {
+		return fmt.Errorf("mun_codigo debe ser el código DIVIPOLA de 5 dígitos del municipio")
+	}
+	if !strings.HasPrefix(data.MunCodigo, data.DepCodigo) {
+		return fmt.Errorf("mun_codigo [%s] no pertenece al departamento [%s]", data.MunCodigo, data.DepCodigo)
+	}
+
+	if data.OfeCorreo != nil && *data.OfeCorreo != "" && !emailPattern.MatchString(*data.OfeCorreo) {
+		return fmt.Errorf("ofe_correo tiene un formato de correo inválido")
+	}
+	if pai := strings.ToUpper(strings.TrimSpace(data.PaiCodigo)); pai != "" {
+		if err := codelist.Check(codelist.Countries, "pai_codigo", pai); err != nil {
+			return err
+		}
+	}
+	if data.RfiCodigo != nil && *data.RfiCodigo != "" {
+		if err := codelist.Check(codelist.TaxRegimes, "rfi_codigo", strings.TrimSpace(*data.RfiCodigo)); err != nil {
+			return err
+		}
+	}
+	if err := codelist.CheckAll(codelist.TaxResponsibilities, "ref_codigo", data.RefCodigo); err != nil {
+		return err
+	}
+	if data.CdoAmbiente != nil && *data.CdoAmbiente != "" && *data.CdoAmbiente != "1" && *data.CdoAmbiente != "2" {
+		return fmt.Errorf("cdo_ambiente debe ser '1' (producción) o '2' (pruebas)")
+	}
+	if data.Estado != nil && *data.Estado != "" {
+		estado := strings.ToUpper(*data.Estado)
+		if estado != ofe.EstadoActivo && estado != ofe.EstadoInactivo {
+			return fmt.Errorf("estado debe ser 'ACTIVO' o 'INACTIVO'")
+		}
+	}
+
+	if n := data.Numrot; n != nil {
+		if (n.Usuario == "") != (n.Password == "") {
+			return fmt.Errorf("numrot.usuario y numrot.password deben ser enviados juntos")
+		}
+		if (n.Key == "") != (n.Secret == "") {
+			return fmt.Errorf("numrot.key y numrot.secret deben ser enviados juntos")
+		}
+	}
+
+	return nil
+}
+
+// dataToOFE converts the request fields to a domain entity.
+func dataToOFE(data OFEData) ofe.OFE {
+	o := ofe.OFE{
+		OfeRazonSocial:     strings.TrimSpace(data.OfeRazonSocial),
+		OfeNombreComercial: emptyToNil(data.OfeNombreComercial),
+		TdoCodigo:          data.TdoCodigo,
+		TojCodigo:          data.TojCodigo,
+		OfeDireccion:       strings.TrimSpace(data.OfeDireccion),
+		PaiCodigo:          strings.ToUpper(strings.TrimSpace(data.PaiCodigo)),
+		DepCodigo:          data.DepCodigo,
+		DepNombre:          strings.TrimSpace(data.DepNombre),
+		MunCodigo:          data.MunCodigo,
+		MunNombre:          strings.TrimSpace(data.MunNombre),
+		CpoCodigo:          emptyToNil(data.CpoCodigo),
+		OfeTelefono:        emptyToNil(data.OfeTelefono),
+		OfeCorreo:          emptyToNil(data.OfeCorreo),
+		RfiCodigo:          emptyToNil(data.RfiCodigo),
+		CdoAmbiente:        emptyToNil(data.CdoAmbiente),
+		Estado:             ofe.EstadoActivo,
+	}
+	if o.PaiCodigo == "" {
+		o.PaiCodigo = "CO"
+	}
+	for _, code := range data.RefCodigo {
+		o.RefCodigo = append(o.RefCodigo, strings.TrimSpace(code))
+	}
+	if data.Estado != nil && *data.Estado != "" {
+		o.Estado = strings.ToUpper(*data.Estado)
+	}
+	if n := data.Numrot; n != nil {
+		o.Numrot = &ofe.NumrotCredentials{
+			Username: n.Usuario,
+			Password: n.Password,
+			Key:      n.Key,
+			Secret:   n.Secret,
+		}
+	}
+	return o
+}
+
+func emptyToNil(s *string) *string {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil
+	}
+	v := strings.TrimSpace(*s)
+	return &v
+}
@@ -0,0 +1,138 @@
+package ofe
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func strPtr(s string) *string { return &s }
+
+func validData() OFEData {
+	return OFEData{
+		OfeRazonSocial: "Positiva SAS",
+		TdoCodigo:      "31",
+		TojCodigo:      "1",
+		OfeDireccion:   "CLL 50 - 96",
+		DepCodigo:      "05",
+		DepNombre:      "ANTIOQUIA",
+		MunCodigo:      "05380",
+		MunNombre:      "LA ESTRELLA",
+		OfeCorreo:      strPtr("facturacion@positiva.co"),
+		RefCodigo:      []string{"O-13", " O-15 "},
+		Numrot:         &NumrotCredentialsRequest{Usuario: "usuario", Password: "clave", Key: "key", Secret: "secret"},
+	}
+}
+
+func TestService_CreateOFE(t *testing.T) {
+	repo := testutil.NewMockOFERepository()
+	service := NewService(repo)
+
+	resp, err := service.CreateOFE(context.Background(), CreateOFERequest{OfeIdentificacion: "860011153-6", OFEData: validData()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success || resp.OfeID == 0 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	stored, err := service.GetOFE(context.Background(), "860011153")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.PaiCodigo != "CO" || stored.Estado != "ACTIVO" {
+		t.Errorf("expected defaults CO/ACTIVO, got %s/%s", stored.PaiCodigo, stored.Estado)
+	}
+	if len(stored.RefCodigo) != 2 || stored.RefCodigo[1] != "O-15" {
+		t.Errorf("unexpected ref_codigo: %v", stored.RefCodigo)
+	}
+	if stored.Numrot == nil || stored.Numrot.Secret != "secret" {
+		t.Errorf("expected Numrot credentials to be stored, got %+v", stored.Numrot)
+	}
+
+	_, err = service.CreateOFE(context.Background(), CreateOFERequest{OfeIdentificacion: "860011153", OFEData: validData()})
+	if err == nil || !strings.Contains(err.Error(), "ya existe") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+}
+
+func TestService_CreateOFE_Validation(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		modify      func(*OFEData)
+		expectedErr string
+	}{
+		{name: "missing identification", id: "", expectedErr: "ofe_identificacion es requerido"},
+		{name: "non numeric identification", id: "ABC123", expectedErr: "ofe_identificacion debe ser numérico"},
+		{name: "wrong verification digit", id: "860011153-3", expectedErr: "el dígito de verificación debe ser 6"},
+		{name: "missing razon social", id: "860011153", modify: func(d *OFEData) { d.OfeRazonSocial = " " }, expectedErr: "ofe_razon_social es requerido"},
+		{name: "invalid department", id: "860011153", modify: func(d *OFEData) { d.DepCodigo = "5" }, expectedErr: "dep_codigo"},
+		{name: "municipality of other department", id: "860011153", modify: func(d *OFEData) { d.MunCodigo = "11001" }, expectedErr: "no pertenece"},
+		{name: "invalid tax responsibility", id: "860011153", modify: func(d *OFEData) { d.RefCodigo = []string{"X-1"} }, expectedErr: "ref_codigo"},
+		{name: "invalid environment", id: "860011153", modify: func(d *OFEData) { d.CdoAmbiente = strPtr("3") }, expectedErr: "cdo_ambiente"},
+		{name: "invalid estado", id: "860011153", modify: func(d *OFEData) { d.Estado = strPtr("BORRADO") }, expectedErr: "estado debe ser"},
+		{name: "incomplete credentials", id: "860011153", modify: func(d *OFEData) { d.Numrot = &NumrotCredentialsRequest{Key: "key"} }, expectedErr: "numrot.key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := validData()
+			if tt.modify != nil {
+				tt.modify(&data)
+			}
+			_, err := NewService(testutil.NewMockOFERepository()).CreateOFE(context.Background(), CreateOFERequest{OfeIdentificacion: tt.id, OFEData: data})
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestService_UpdateOFE_KeepsCredentials(t *testing.T) {
+	service := NewService(testutil.NewMockOFERepository())
+	ctx := context.Background()
+	if _, err := service.CreateOFE(ctx, CreateOFERequest{OfeIdentificacion: "860011153", OFEData: validData()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data := validData()
+	data.Numrot = nil
+	data.OfeRazonSocial = "Positiva Compañía de Seguros"
+	data.Estado = strPtr("inactivo")
+	if err := service.UpdateOFE(ctx, "860011153-6", UpdateOFERequest{OFEData: data}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stored, _ := service.GetOFE(ctx, "860011153")
+	if stored.OfeRazonSocial != "Positiva Compañía de Seguros" || stored.IsActive() {
+		t.Errorf("update not applied: %+v", stored)
+	}
+	if stored.Numrot == nil || stored.Numrot.Username != "usuario" {
+		t.Errorf("expected credentials to be kept, got %+v", stored.Numrot)
+	}
+
+	if err := service.UpdateOFE(ctx, "900000000", UpdateOFERequest{OFEData: validData()}); err == nil || !strings.Contains(err.Error(), "no existe") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestService_ListOFEs(t *testing.T) {
+	service := NewService(testutil.NewMockOFERepository())
+	ctx := context.Background()
+	for _, id := range []string{"900000001", "900000002", "900000003"} {
+		if _, err := service.CreateOFE(ctx, CreateOFERequest{OfeIdentificacion: id, OFEData: validData()}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	resp, err := service.ListOFEs(ctx, 1, 1, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Total != 3 || resp.Filtrados != 1 || resp.Data[0].OfeIdentificacion != "900000002" {
+		t.Errorf("unexpected page: %+v", resp)
+	}
+}
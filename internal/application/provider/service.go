@@ -6,7 +6,9 @@ import (
 	"regexp"
 	"strings"
 
-	"3tcapital/ms_facturacion_core/internal/core/provider"
+	"3tcapital/goclonacion/internal/core/codelist"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/provider"
 )
 
 // Service orchestrates provider-related use cases.
@@ -56,8 +58,9 @@ type CreateProviderRequest struct {
 
 // CreateProviderResponse represents the response from creating a provider.
 type CreateProviderResponse struct {
-	Success bool  `json:"success"`
-	ProID   int64 `json:"pro_id"`
+	Success bool   `json:"success"`
+	ProID   int64  `json:"pro_id"`
+	ProDV   string `json:"pro_dv,omitempty"` // Verification digit when pro_identificacion is a NIT
 }
 
 // UpdateProviderRequest represents the request to update a provider.
@@ -107,6 +110,9 @@ func (s *Service) CreateProvider(ctx context.Context, req CreateProviderRequest)
 		return nil, err
 	}
 
+	req.OfeIdentificacion = identification.Base(req.OfeIdentificacion)
+	req.ProIdentificacion = identification.Canonical(req.TdoCodigo, req.ProIdentificacion)
+
 	// Check if provider already exists
 	exists, err := s.repo.Exists(ctx, req.OfeIdentificacion, req.ProIdentificacion)
 	if err != nil {
@@ -129,6 +135,7 @@ func (s *Service) CreateProvider(ctx context.Context, req CreateProviderRequest)
 	return &CreateProviderResponse{
 		Success: true,
 		ProID:   id,
+		ProDV:   identification.DV(req.TdoCodigo, req.ProIdentificacion),
 	}, nil
 }
 
@@ -139,6 +146,11 @@ func (s *Service) UpdateProvider(ctx context.Context, ofeIdentificacion, proIden
 		return err
 	}
 
+	ofeIdentificacion = identification.Base(ofeIdentificacion)
+	proIdentificacion = identification.Canonical(req.TdoCodigo, proIdentificacion)
+	req.OfeIdentificacion = identification.Base(req.OfeIdentificacion)
+	req.ProIdentificacion = identification.Canonical(req.TdoCodigo, req.ProIdentificacion)
+
 	// Check if provider exists
 	exists, err := s.repo.Exists(ctx, ofeIdentificacion, proIdentificacion)
 	if err != nil {
@@ -191,7 +203,7 @@ func (s *Service) ListProviders(ctx context.Context, start, length int, buscar,
 	return &ListProvidersResponse{
 		Total:     total,
 		Filtrados: filtered,
-		Data:      providers,
+		Data:      withDV(providers),
 	}, nil
 }
 
@@ -223,7 +235,15 @@ func (s *Service) SearchProvider(ctx context.Context, campoBuscar, valorBuscar,
 		return nil, fmt.Errorf("search providers: %w", err)
 	}
 
-	return providers, nil
+	return withDV(providers), nil
+}
+
+// withDV fills the verification digit of the providers identified by NIT.
+func withDV(providers []provider.Provider) []provider.Provider {
+	for i := range providers {
+		providers[i].ProDV = identification.DV(providers[i].TdoCodigo, providers[i].ProIdentificacion)
+	}
+	return providers
 }
 
 // validateCreateRequest validates the create request.
@@ -245,6 +265,19 @@ func (s *Service) validateCreateRequest(req CreateProviderRequest) error {
 		return fmt.Errorf("pro_correo es requerido")
 	}
 
+	// Coded fields must belong to the DIAN code lists
+	if err := validateCodes(req.TdoCodigo, req.TojCodigo, req.PaiCodigo, req.PaiCodigoDomicilioFiscal, req.RfiCodigo, req.RefCodigo); err != nil {
+		return err
+	}
+
+	// Identification numbers are validated with the rules of their type (NIT with its DV)
+	if err := identification.ValidateNIT(req.OfeIdentificacion); err != nil {
+		return fmt.Errorf("ofe_identificacion: %w", err)
+	}
+	if err := identification.Validate(req.TdoCodigo, req.ProIdentificacion); err != nil {
+		return fmt.Errorf("pro_identificacion: %w", err)
+	}
+
 	// Length validations for required fields
 	if err := validateMaxLength(req.OfeIdentificacion, 20, "ofe_identificacion"); err != nil {
 		return err
@@ -381,6 +414,19 @@ func (s *Service) validateUpdateRequest(req UpdateProviderRequest) error {
 		return fmt.Errorf("pro_correo es requerido")
 	}
 
+	// Coded fields must belong to the DIAN code lists
+	if err := validateCodes(req.TdoCodigo, req.TojCodigo, req.PaiCodigo, req.PaiCodigoDomicilioFiscal, req.RfiCodigo, req.RefCodigo); err != nil {
+		return err
+	}
+
+	// Identification numbers are validated with the rules of their type (NIT with its DV)
+	if err := identification.ValidateNIT(req.OfeIdentificacion); err != nil {
+		return fmt.Errorf("ofe_identificacion: %w", err)
+	}
+	if err := identification.Validate(req.TdoCodigo, req.ProIdentificacion); err != nil {
+		return fmt.Errorf("pro_identificacion: %w", err)
+	}
+
 	// Length validations for required fields
 	if err := validateMaxLength(req.OfeIdentificacion, 20, "ofe_identificacion"); err != nil {
 		return err
@@ -494,6 +540,34 @@ func (s *Service) validateUpdateRequest(req UpdateProviderRequest) error {
 	return nil
 }
 
+// validateCodes checks the coded fields of a provider against the DIAN code lists.
+func validateCodes(tdoCodigo, tojCodigo string, paiCodigo, paiCodigoDomicilioFiscal, rfiCodigo *string, refCodigo []string) error {
+	if err := codelist.Check(codelist.IdentificationTypes, "tdo_codigo", tdoCodigo); err != nil {
+		return err
+	}
+	if err := codelist.Check(codelist.OrganizationTypes, "toj_codigo", tojCodigo); err != nil {
+		return err
+	}
+	optional := []struct {
+		list  string
+		field string
+		code  *string
+	}{
+		{codelist.Countries, "pai_codigo", paiCodigo},
+		{codelist.Countries, "pai_codigo_domicilio_fiscal", paiCodigoDomicilioFiscal},
+		{codelist.TaxRegimes, "rfi_codigo", rfiCodigo},
+	}
+	for _, o := range optional {
+		if o.code == nil || *o.code == "" {
+			continue
+		}
+		if err := codelist.Check(o.list, o.field, *o.code); err != nil {
+			return err
+		}
+	}
+	return codelist.CheckAll(codelist.TaxResponsibilities, "ref_codigo", refCodigo)
+}
+
 // validateMaxLength validates that a string value does not exceed the maximum length.
 func validateMaxLength(value string, maxLen int, fieldName string) error {
 	if len(value) > maxLen {
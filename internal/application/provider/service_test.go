@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+const testOFE = "900373115"
+
+func strPtr(s string) *string { return &s }
+
+func newCreateRequest(tdoCodigo, proIdentificacion string) CreateProviderRequest {
+	return CreateProviderRequest{
+		OfeIdentificacion: testOFE + "-3",
+		ProIdentificacion: proIdentificacion,
+		ProRazonSocial:    strPtr("PROVEEDOR DE PRUEBA S.A.S."),
+		TdoCodigo:         tdoCodigo,
+		TojCodigo:         "1",
+		ProCorreo:         "proveedor@example.com",
+	}
+}
+
+func TestService_CreateProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		tdoCodigo  string
+		number     string
+		wantStored string
+		wantDV     string
+		wantErr    string
+	}{
+		{"NIT with DV and separators", "31", "860.011.153-6", "860011153", "6", ""},
+		{"NIT without DV", "31", "800197268", "800197268", "4", ""},
+		{"cédula with separators", "13", "1.020.304.050", "1020304050", "", ""},
+		{"cédula de extranjería lower-case", "22", "e 12345", "E12345", "", ""},
+		{"NIT with wrong DV", "31", "860011153-1", "", "", "el dígito de verificación debe ser 6"},
+		{"NIT with letters", "31", "86001115A", "", "", "entre 5 y 15 dígitos"},
+		{"cédula with letters", "13", "12A456", "", "", "entre 3 y 10 dígitos"},
+		{"registro civil too short", "11", "12", "", "", "entre 3 y 15 dígitos"},
+		{"unknown tdo_codigo", "99", "123456", "", "", "tdo_codigo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := testutil.NewMockProviderRepository()
+			service := NewService(repo)
+
+			resp, err := service.CreateProvider(ctx, newCreateRequest(tt.tdoCodigo, tt.number))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.ProDV != tt.wantDV {
+				t.Errorf("expected pro_dv %q, got %q", tt.wantDV, resp.ProDV)
+			}
+
+			stored, _ := repo.FindByID(ctx, testOFE, tt.wantStored)
+			if stored == nil {
+				t.Fatalf("expected provider stored as %s/%s", testOFE, tt.wantStored)
+			}
+			if stored.ID != resp.ProID {
+				t.Errorf("expected pro_id %d, got %d", stored.ID, resp.ProID)
+			}
+		})
+	}
+}
+
+func TestService_CreateProvider_OFE(t *testing.T) {
+	service := NewService(testutil.NewMockProviderRepository())
+
+	req := newCreateRequest("13", "1020304050")
+	req.OfeIdentificacion = testOFE + "-1"
+	if _, err := service.CreateProvider(context.Background(), req); err == nil || !strings.HasPrefix(err.Error(), "ofe_identificacion") {
+		t.Errorf("expected ofe_identificacion DV error, got %v", err)
+	}
+}
+
+func TestService_CreateProvider_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(testutil.NewMockProviderRepository())
+
+	if _, err := service.CreateProvider(ctx, newCreateRequest("31", "860011153-6")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The same NIT written differently is the same provider
+	_, err := service.CreateProvider(ctx, newCreateRequest("31", "860.011.153"))
+	if err == nil || !strings.Contains(err.Error(), "ya existe") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+}
+
+func TestService_UpdateProvider(t *testing.T) {
+	ctx := context.Background()
+	repo := testutil.NewMockProviderRepository()
+	service := NewService(repo)
+
+	if _, err := service.CreateProvider(ctx, newCreateRequest("31", "860011153")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		wantErr string
+	}{
+		{"NIT with DV in path and body", "860.011.153-6", "860011153-6", ""},
+		{"NIT with wrong DV in body", "860011153", "860011153-2", "el dígito de verificación debe ser 6"},
+		{"unknown provider", "800197268", "800197268", "no existe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := UpdateProviderRequest{
+				OfeIdentificacion: testOFE,
+				ProIdentificacion: tt.body,
+				ProRazonSocial:    strPtr("PROVEEDOR ACTUALIZADO S.A.S."),
+				TdoCodigo:         "31",
+				TojCodigo:         "1",
+				ProCorreo:         "proveedor@example.com",
+			}
+			err := service.UpdateProvider(ctx, testOFE+"-3", tt.path, req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			stored, _ := repo.FindByID(ctx, testOFE, "860011153")
+			if stored == nil || stored.ProRazonSocial == nil || *stored.ProRazonSocial != "PROVEEDOR ACTUALIZADO S.A.S." {
+				t.Errorf("expected provider updated under its canonical NIT, got %+v", stored)
+			}
+		})
+	}
+}
+
+func TestService_ProDV(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(testutil.NewMockProviderRepository())
+
+	for _, req := range []CreateProviderRequest{
+		newCreateRequest("31", "860011153"),
+		newCreateRequest("13", "1020304050"),
+	} {
+		if _, err := service.CreateProvider(ctx, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		number string
+		wantDV string
+	}{
+		{"860011153", "6"},
+		{"1020304050", ""},
+	}
+
+	for _, tt := range tests {
+		found, err := service.SearchProvider(ctx, "pro_identificacion", tt.number, testOFE, "exacto")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(found) != 1 || found[0].ProDV != tt.wantDV {
+			t.Errorf("search %s: expected pro_dv %q, got %+v", tt.number, tt.wantDV, found)
+		}
+	}
+
+	list, err := service.ListProviders(ctx, 0, -1, "", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range list.Data {
+		want := map[string]string{"860011153": "6", "1020304050": ""}[p.ProIdentificacion]
+		if p.ProDV != want {
+			t.Errorf("list %s: expected pro_dv %q, got %q", p.ProIdentificacion, want, p.ProDV)
+		}
+	}
+}
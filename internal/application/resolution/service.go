@@ -3,23 +3,79 @@ package resolution
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	coreresolution "3tcapital/ms_facturacion_core/internal/core/resolution"
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	coreresolution "3tcapital/goclonacion/internal/core/resolution"
 )
 
+// Defaults of the local resolution tracking.
+const (
+	defaultRefreshInterval = 6 * time.Hour
+	defaultUsageThreshold  = 80
+	defaultExpiryWindow    = 30 * 24 * time.Hour
+)
+
+// errTrackingDisabled is returned by the operations that need the local store.
+var errTrackingDisabled = fmt.Errorf("el seguimiento de resoluciones no está habilitado")
+
 // Service orchestrates resolution-related use cases.
+// When a repository is configured, resolutions are persisted locally and refreshed
+// periodically from the provider; the local copy also tracks the consecutivos used.
 type Service struct {
 	provider invoice.Provider
+	repo     coreresolution.Repository // Optional: nil if resolutions are not tracked locally
+	log      *slog.Logger
+
+	refreshInterval time.Duration // Local copies older than this are refreshed from the provider
+	usageThreshold  float64       // Alert when a range is used above this percentage
+	expiryWindow    time.Duration // Alert when a range expires within this window
+
+	wg sync.WaitGroup
 }
 
 // NewService creates a new resolution service with the given invoice provider.
 func NewService(provider invoice.Provider) *Service {
 	return &Service{
-		provider: provider,
+		provider:        provider,
+		refreshInterval: defaultRefreshInterval,
+		usageThreshold:  defaultUsageThreshold,
+		expiryWindow:    defaultExpiryWindow,
 	}
 }
 
+// WithRepository enables the local store of resolutions and the consecutive allocator.
+func (s *Service) WithRepository(repo coreresolution.Repository, log *slog.Logger) *Service {
+	s.repo = repo
+	s.log = log
+	return s
+}
+
+// WithRefreshInterval sets how often the stored resolutions are refreshed from the provider.
+// Values <= 0 keep the default (6h).
+func (s *Service) WithRefreshInterval(d time.Duration) *Service {
+	if d > 0 {
+		s.refreshInterval = d
+	}
+	return s
+}
+
+// WithAlerts sets the usage percentage and the expiry window that raise alerts.
+// Values <= 0 keep the defaults (80% and 30 days).
+func (s *Service) WithAlerts(usagePercent float64, expiryWindow time.Duration) *Service {
+	if usagePercent > 0 {
+		s.usageThreshold = usagePercent
+	}
+	if expiryWindow > 0 {
+		s.expiryWindow = expiryWindow
+	}
+	return s
+}
+
 // GetResolutions retrieves all active resolutions for a given NIT.
 func (s *Service) GetResolutions(ctx context.Context, nit string) ([]coreresolution.Resolution, error) {
 	if nit == "" {
@@ -31,6 +87,18 @@ func (s *Service) GetResolutions(ctx context.Context, nit string) ([]coreresolut
 		return nil, fmt.Errorf("invalid nit format: must be between 9 and 15 characters")
 	}
 
+	if s.repo != nil {
+		ranges, err := s.Ranges(ctx, nit)
+		if err != nil {
+			return nil, err
+		}
+		resolutions := make([]coreresolution.Resolution, 0, len(ranges))
+		for _, rng := range ranges {
+			resolutions = append(resolutions, rng.Resolution)
+		}
+		return resolutions, nil
+	}
+
 	resolutions, err := s.provider.GetResolutions(ctx, nit)
 	if err != nil {
 		// Preserve the original error message for better error handling
@@ -39,3 +107,216 @@ func (s *Service) GetResolutions(ctx context.Context, nit string) ([]coreresolut
 
 	return resolutions, nil
 }
+
+// Ranges returns the stored ranges of an OFE with their usage. They are refreshed
+// from the provider when missing or older than the refresh interval; if the refresh
+// fails the stored copy is returned.
+func (s *Service) Ranges(ctx context.Context, ofeIdentificacion string) ([]coreresolution.Range, error) {
+	if s.repo == nil {
+		return nil, errTrackingDisabled
+	}
+	nit := identification.Base(ofeIdentificacion)
+
+	ranges, err := s.repo.List(ctx, nit)
+	if err != nil {
+		return nil, fmt.Errorf("consultar resoluciones almacenadas: %w", err)
+	}
+	if len(ranges) > 0 && !s.stale(ranges) {
+		return ranges, nil
+	}
+
+	synced, err := s.Sync(ctx, nit)
+	if err != nil {
+		if len(ranges) == 0 {
+			return nil, err
+		}
+		s.log.Warn("Failed to refresh resolutions, using stored copy", "ofe", nit, "error", err)
+		return ranges, nil
+	}
+	return synced, nil
+}
+
+// Sync reads the resolutions of an OFE from the provider and stores them locally.
+func (s *Service) Sync(ctx context.Context, ofeIdentificacion string) ([]coreresolution.Range, error) {
+	if s.repo == nil {
+		return nil, errTrackingDisabled
+	}
+	nit := identification.Base(ofeIdentificacion)
+
+	resolutions, err := s.provider.GetResolutions(ctx, nit)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Sync(ctx, nit, resolutions); err != nil {
+		return nil, fmt.Errorf("almacenar resoluciones: %w", err)
+	}
+	return s.repo.List(ctx, nit)
+}
+
+// Allocate hands out the next free consecutivo of the OFE for the prefix, taken from
+// the range valid today.
+func (s *Service) Allocate(ctx context.Context, ofeIdentificacion, prefix string) (*coreresolution.Allocation, error) {
+	if _, err := s.Ranges(ctx, ofeIdentificacion); err != nil {
+		return nil, err
+	}
+	return s.repo.Allocate(ctx, identification.Base(ofeIdentificacion), strings.TrimSpace(prefix), time.Now())
+}
+
+// Validate checks that a document number and issue date fall inside the range of the
+// resolution. Resolutions not stored yet are read from the provider.
+func (s *Service) Validate(ctx context.Context, ofeIdentificacion, resolutionNumber, prefix string, consecutivo int64, date time.Time) (*coreresolution.Range, error) {
+	if s.repo == nil {
+		return nil, errTrackingDisabled
+	}
+	nit := identification.Base(ofeIdentificacion)
+
+	rng, err := s.repo.Find(ctx, nit, resolutionNumber, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("consultar resolución almacenada: %w", err)
+	}
+	if rng == nil {
+		if _, err := s.Sync(ctx, nit); err != nil {
+			return nil, fmt.Errorf("error al consultar resoluciones para OFE [%s]: %w", nit, err)
+		}
+		if rng, err = s.repo.Find(ctx, nit, resolutionNumber, prefix); err != nil {
+			return nil, fmt.Errorf("consultar resolución almacenada: %w", err)
+		}
+	}
+	if rng == nil {
+		return nil, fmt.Errorf("resolución [%s] con prefijo [%s] no encontrada para OFE [%s]", resolutionNumber, prefix, nit)
+	}
+
+	if err := rng.Check(consecutivo, date); err != nil {
+		return nil, err
+	}
+	return rng, nil
+}
+
+// MarkUsed records a consecutivo of the range as used.
+func (s *Service) MarkUsed(ctx context.Context, rng *coreresolution.Range, consecutivo int64) error {
+	if s.repo == nil {
+		return errTrackingDisabled
+	}
+	return s.repo.MarkUsed(ctx, rng.ID, consecutivo)
+}
+
+// Alerts returns the ranges of the OFE (every OFE when empty) used above the configured
+// percentage or expiring within the configured window. Expired ranges are ignored.
+func (s *Service) Alerts(ctx context.Context, ofeIdentificacion string) ([]coreresolution.Alert, error) {
+	if s.repo == nil {
+		return nil, errTrackingDisabled
+	}
+
+	ranges, err := s.repo.List(ctx, identification.Base(ofeIdentificacion))
+	if err != nil {
+		return nil, fmt.Errorf("consultar resoluciones almacenadas: %w", err)
+	}
+	return s.alerts(ranges, time.Now()), nil
+}
+
+func (s *Service) alerts(ranges []coreresolution.Range, now time.Time) []coreresolution.Alert {
+	alerts := make([]coreresolution.Alert, 0)
+	expiryDays := int(s.expiryWindow.Hours() / 24)
+
+	for _, rng := range ranges {
+		days := rng.DaysToExpire(now)
+		if days < 0 {
+			continue
+		}
+		alert := coreresolution.Alert{
+			OfeIdentificacion: rng.OfeIdentificacion,
+			ResolutionNumber:  rng.ResolutionNumber,
+			Prefix:            rng.Prefix,
+			UsagePercent:      rng.UsagePercent(),
+			DaysToExpire:      days,
+		}
+		if usage := rng.UsagePercent(); usage >= s.usageThreshold {
+			alert.Type = coreresolution.AlertUsage
+			alert.Message = fmt.Sprintf("La resolución [%s] con prefijo [%s] ha consumido el %.1f%% del rango [%d-%d]",
+				rng.ResolutionNumber, rng.Prefix, usage, rng.FromNumber, rng.ToNumber)
+			alerts = append(alerts, alert)
+		}
+		if days <= expiryDays {
+			alert.Type = coreresolution.AlertExpiry
+			alert.Message = fmt.Sprintf("La resolución [%s] con prefijo [%s] vence en %d días (%s)",
+				rng.ResolutionNumber, rng.Prefix, days, rng.ValidDateTo.Format("2006-01-02"))
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// Start launches the job that refreshes the stored resolutions of every OFE and logs
+// the alerts. It does nothing without a repository; use Wait to block until it stops.
+func (s *Service) Start(ctx context.Context) {
+	if s.repo == nil {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Wait blocks until the refresh job has stopped.
+func (s *Service) Wait() {
+	s.wg.Wait()
+}
+
+// Refresh re-reads from the provider the resolutions of every OFE stored locally
+// and logs the alerts of the refreshed ranges.
+func (s *Service) Refresh(ctx context.Context) {
+	ranges, err := s.repo.List(ctx, "")
+	if err != nil {
+		s.log.Error("Failed to list stored resolutions", "error", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, rng := range ranges {
+		if seen[rng.OfeIdentificacion] {
+			continue
+		}
+		seen[rng.OfeIdentificacion] = true
+		if _, err := s.Sync(ctx, rng.OfeIdentificacion); err != nil && ctx.Err() == nil {
+			s.log.Error("Failed to refresh resolutions", "ofe", rng.OfeIdentificacion, "error", err)
+		}
+	}
+
+	alerts, err := s.Alerts(ctx, "")
+	if err != nil {
+		s.log.Error("Failed to compute resolution alerts", "error", err)
+		return
+	}
+	for _, alert := range alerts {
+		s.log.Warn("Resolution alert",
+			"tipo", alert.Type,
+			"ofe", alert.OfeIdentificacion,
+			"resolucion", alert.ResolutionNumber,
+			"prefijo", alert.Prefix,
+			"mensaje", alert.Message)
+	}
+}
+
+// stale reports whether any range was synced longer than the refresh interval ago.
+func (s *Service) stale(ranges []coreresolution.Range) bool {
+	for _, rng := range ranges {
+		if time.Since(rng.SyncedAt) > s.refreshInterval {
+			return true
+		}
+	}
+	return false
+}
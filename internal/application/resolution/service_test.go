@@ -3,11 +3,13 @@ package resolution
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	coreresolution "3tcapital/ms_facturacion_core/internal/core/resolution"
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	"3tcapital/goclonacion/internal/core/invoice"
+	coreresolution "3tcapital/goclonacion/internal/core/resolution"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 func TestNewService(t *testing.T) {
@@ -149,6 +151,138 @@ func TestService_GetResolutions(t *testing.T) {
 	}
 }
 
+func newTrackingService(resolutions []coreresolution.Resolution) (*Service, *testutil.MockResolutionRepository, *int) {
+	calls := 0
+	provider := &testutil.MockProvider{
+		GetResolutionsFunc: func(ctx context.Context, nit string) ([]coreresolution.Resolution, error) {
+			calls++
+			return resolutions, nil
+		},
+	}
+	repo := testutil.NewMockResolutionRepository()
+	return NewService(provider).WithRepository(repo, testutil.NewTestLogger()), repo, &calls
+}
+
+func testResolution(prefix string, from, to int64) coreresolution.Resolution {
+	today := time.Now()
+	return coreresolution.Resolution{
+		ResolutionNumber: "18760000001",
+		Prefix:           prefix,
+		FromNumber:       from,
+		ToNumber:         to,
+		ValidDateFrom:    today.AddDate(0, -1, 0),
+		ValidDateTo:      today.AddDate(1, 0, 0),
+	}
+}
+
+func TestService_GetResolutions_StoresLocally(t *testing.T) {
+	service, repo, calls := newTrackingService([]coreresolution.Resolution{testResolution("SETT", 1, 100)})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		resolutions, err := service.GetResolutions(ctx, "860011153")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resolutions) != 1 || resolutions[0].Prefix != "SETT" {
+			t.Fatalf("unexpected resolutions: %+v", resolutions)
+		}
+	}
+	if *calls != 1 || repo.Syncs != 1 {
+		t.Errorf("expected a single provider query, got %d queries and %d syncs", *calls, repo.Syncs)
+	}
+}
+
+func TestService_Validate(t *testing.T) {
+	service, _, calls := newTrackingService([]coreresolution.Resolution{testResolution("SETT", 1, 100)})
+	ctx := context.Background()
+	today := time.Now()
+
+	rng, err := service.Validate(ctx, "860011153-6", "18760000001", "SETT", 10, today)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rng.OfeIdentificacion != "860011153" || *calls != 1 {
+		t.Errorf("expected resolution to be read from the provider once, got %+v (%d queries)", rng, *calls)
+	}
+
+	if _, err := service.Validate(ctx, "860011153", "18760000001", "SETT", 101, today); err == nil || !strings.Contains(err.Error(), "fuera del rango autorizado") {
+		t.Errorf("expected out of range error, got %v", err)
+	}
+	if _, err := service.Validate(ctx, "860011153", "18760000001", "SETT", 10, today.AddDate(2, 0, 0)); err == nil || !strings.Contains(err.Error(), "fuera de la vigencia") {
+		t.Errorf("expected validity error, got %v", err)
+	}
+	if _, err := service.Validate(ctx, "860011153", "18760000002", "SETT", 10, today); err == nil || !strings.Contains(err.Error(), "no encontrada") {
+		t.Errorf("expected unknown resolution error, got %v", err)
+	}
+}
+
+func TestService_Allocate(t *testing.T) {
+	service, repo, _ := newTrackingService([]coreresolution.Resolution{testResolution("SETT", 1, 3)})
+	ctx := context.Background()
+
+	rng, err := service.Validate(ctx, "860011153", "18760000001", "SETT", 2, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.MarkUsed(ctx, rng, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	allocation, err := service.Allocate(ctx, "860011153", "SETT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if allocation.Consecutivo != 3 {
+		t.Errorf("expected next number after the used one, got %d", allocation.Consecutivo)
+	}
+
+	if _, err := service.Allocate(ctx, "860011153", "SETT"); err == nil || !strings.Contains(err.Error(), "no hay una resolución vigente") {
+		t.Errorf("expected exhausted range error, got %v", err)
+	}
+
+	ranges, _ := repo.List(ctx, "860011153")
+	if ranges[0].UsedCount != 2 || ranges[0].LastNumber != 3 {
+		t.Errorf("unexpected usage: %+v", ranges[0])
+	}
+}
+
+func TestService_Alerts(t *testing.T) {
+	expiring := testResolution("EXP", 1, 100)
+	expiring.ResolutionNumber = "18760000002"
+	expiring.ValidDateTo = time.Now().AddDate(0, 0, 10)
+	service, _, _ := newTrackingService([]coreresolution.Resolution{testResolution("SETT", 1, 10), expiring})
+	service.WithAlerts(80, 15*24*time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 8; i++ {
+		if _, err := service.Allocate(ctx, "860011153", "SETT"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	alerts, err := service.Alerts(ctx, "860011153")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %+v", alerts)
+	}
+	if alerts[0].Type != coreresolution.AlertUsage || alerts[0].Prefix != "SETT" || alerts[0].UsagePercent != 80 {
+		t.Errorf("unexpected usage alert: %+v", alerts[0])
+	}
+	if alerts[1].Type != coreresolution.AlertExpiry || alerts[1].Prefix != "EXP" {
+		t.Errorf("unexpected expiry alert: %+v", alerts[1])
+	}
+}
+
+func TestService_TrackingDisabled(t *testing.T) {
+	service := NewService(&testutil.MockProvider{})
+	if _, err := service.Allocate(context.Background(), "860011153", "SETT"); err == nil || !strings.Contains(err.Error(), "no está habilitado") {
+		t.Errorf("expected tracking disabled error, got %v", err)
+	}
+}
+
 func containsString(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || 
 		(len(s) > len(substr) && (s[:len(substr)] == substr || 
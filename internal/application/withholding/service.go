@@ -0,0 +1,193 @@
+package withholding
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/withholding"
+)
+
+// rulesCacheTTL bounds how long the rules are reused before reading them again, so that
+// a batch of documents does not query the rules table once per document.
+const rulesCacheTTL = time.Minute
+
+var munCodigoPattern = regexp.MustCompile(`^[0-9]{5}$`)
+
+// Service orchestrates the withholding rules and computes the suggested retentions.
+type Service struct {
+	repo withholding.Repository
+
+	mu        sync.Mutex // Protects rules and loadedAt
+	rules     []withholding.Rule
+	loadedAt  time.Time
+	uvtValues sync.Map // key = year, value = string
+}
+
+// NewService creates a new withholding service with the given repository.
+func NewService(repo withholding.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListRules returns every withholding rule.
+func (s *Service) ListRules(ctx context.Context) ([]withholding.Rule, error) {
+	rules, err := s.repo.ListRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list withholding rules: %w", err)
+	}
+	if rules == nil {
+		rules = []withholding.Rule{}
+	}
+	return rules, nil
+}
+
+// SaveRule validates and stores a rule. A rule without ID is created.
+func (s *Service) SaveRule(ctx context.Context, rule withholding.Rule) (int64, error) {
+	rule = normalizeRule(rule)
+	if err := rule.Validate(); err != nil {
+		return 0, err
+	}
+	if rule.MunCodigo != "" && !munCodigoPattern.MatchString(rule.MunCodigo) {
+		return 0, fmt.Errorf("mun_codigo debe ser el código DIVIPOLA de 5 dígitos del municipio")
+	}
+
+	id, err := s.repo.SaveRule(ctx, rule)
+	if err != nil {
+		return 0, err
+	}
+	s.invalidate()
+	return id, nil
+}
+
+// DeleteRule removes a rule.
+func (s *Service) DeleteRule(ctx context.Context, id int64) error {
+	if err := s.repo.DeleteRule(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// ListUVT returns the configured UVT values.
+func (s *Service) ListUVT(ctx context.Context) ([]withholding.UVT, error) {
+	values, err := s.repo.ListUVT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list uvt: %w", err)
+	}
+	if values == nil {
+		values = []withholding.UVT{}
+	}
+	return values, nil
+}
+
+// SetUVT stores the UVT value of a year.
+func (s *Service) SetUVT(ctx context.Context, anio int, valor string) error {
+	if anio < 2000 || anio > 2100 {
+		return fmt.Errorf("anio [%d] inválido", anio)
+	}
+	value, ok := new(big.Rat).SetString(strings.TrimSpace(valor))
+	if !ok || value.Sign() <= 0 {
+		return fmt.Errorf("valor debe ser un número mayor que 0")
+	}
+
+	if err := s.repo.SetUVT(ctx, withholding.UVT{Anio: anio, Valor: value.FloatString(2)}); err != nil {
+		return err
+	}
+	s.uvtValues.Delete(anio)
+	return nil
+}
+
+// Suggest computes the suggested retentions of a document with the rules in force
+// and the UVT of the year of the document.
+func (s *Service) Suggest(ctx context.Context, doc invoice.OpenETLDocument, documentType string) ([]invoice.OpenETLRetencion, error) {
+	fecha, err := time.Parse("2006-01-02", doc.CdoFecha)
+	if err != nil {
+		return nil, fmt.Errorf("fecha de documento inválida [%s]", doc.CdoFecha)
+	}
+
+	rules, err := s.loadRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	uvt, err := s.uvt(ctx, fecha.Year())
+	if err != nil {
+		return nil, err
+	}
+
+	return withholding.Compute(doc, documentType, rules, uvt)
+}
+
+// loadRules returns the rules, reading them again once rulesCacheTTL has elapsed.
+func (s *Service) loadRules(ctx context.Context) ([]withholding.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rules != nil && time.Since(s.loadedAt) < rulesCacheTTL {
+		return s.rules, nil
+	}
+
+	rules, err := s.repo.ListRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error al consultar las reglas de retención: %w", err)
+	}
+	if rules == nil {
+		rules = []withholding.Rule{}
+	}
+	s.rules, s.loadedAt = rules, time.Now()
+	return rules, nil
+}
+
+// uvt returns the UVT value of a year.
+func (s *Service) uvt(ctx context.Context, anio int) (string, error) {
+	if cached, ok := s.uvtValues.Load(anio); ok {
+		return cached.(string), nil
+	}
+
+	uvt, err := s.repo.FindUVT(ctx, anio)
+	if err != nil {
+		return "", fmt.Errorf("Error al consultar el valor UVT: %w", err)
+	}
+	if uvt == nil {
+		return "", fmt.Errorf("no hay valor UVT configurado para el año [%d]", anio)
+	}
+	s.uvtValues.Store(anio, uvt.Valor)
+	return uvt.Valor, nil
+}
+
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	s.rules = nil
+	s.mu.Unlock()
+}
+
+// normalizeRule trims the rule fields and upper-cases the type, concept and codes.
+func normalizeRule(rule withholding.Rule) withholding.Rule {
+	rule.Tipo = strings.ToUpper(strings.TrimSpace(rule.Tipo))
+	rule.Concepto = strings.ToUpper(strings.TrimSpace(rule.Concepto))
+	rule.MunCodigo = strings.TrimSpace(rule.MunCodigo)
+	rule.BaseMinimaUVT = strings.TrimSpace(rule.BaseMinimaUVT)
+	rule.Porcentaje = strings.TrimSpace(rule.Porcentaje)
+	rule.Razon = strings.TrimSpace(rule.Razon)
+	rule.AgenteResponsabilidades = normalizeCodes(rule.AgenteResponsabilidades)
+	rule.ExentoResponsabilidades = normalizeCodes(rule.ExentoResponsabilidades)
+	return rule
+}
+
+func normalizeCodes(codes []string) []string {
+	normalized := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			normalized = append(normalized, code)
+		}
+	}
+	return normalized
+}
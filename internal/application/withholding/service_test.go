@@ -0,0 +1,101 @@
+package withholding
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/withholding"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func testRule() withholding.Rule {
+	return withholding.Rule{
+		Tipo:                    "retefuente",
+		Concepto:                " servicios ",
+		AgenteResponsabilidades: []string{"o-13", " "},
+		BaseMinimaUVT:           "4",
+		Porcentaje:              "4",
+		Razon:                   "Servicios generales",
+		Activo:                  true,
+	}
+}
+
+func testDocument(fecha string) invoice.OpenETLDocument {
+	concepto := "SERVICIOS"
+	return invoice.OpenETLDocument{
+		CdoFecha:             fecha,
+		CdoConceptoRetencion: &concepto,
+		AdqRefCodigo:         []string{"O-13"},
+		Items:                []invoice.OpenETLItem{{DdoSecuencia: "1", DdoTotal: "500000.00"}},
+	}
+}
+
+func TestService_SaveRule(t *testing.T) {
+	repo := testutil.NewMockWithholdingRepository()
+	service := NewService(repo)
+
+	id, err := service.SaveRule(context.Background(), testRule())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rules, _ := service.ListRules(context.Background())
+	if len(rules) != 1 || rules[0].ID != id {
+		t.Fatalf("expected rule to be stored, got %+v", rules)
+	}
+	if rules[0].Tipo != "RETEFUENTE" || rules[0].Concepto != "SERVICIOS" || len(rules[0].AgenteResponsabilidades) != 1 || rules[0].AgenteResponsabilidades[0] != "O-13" {
+		t.Errorf("expected normalized rule, got %+v", rules[0])
+	}
+
+	invalid := testRule()
+	invalid.MunCodigo = "110"
+	if _, err := service.SaveRule(context.Background(), invalid); err == nil || !strings.Contains(err.Error(), "mun_codigo debe ser") {
+		t.Errorf("expected municipality error, got %v", err)
+	}
+}
+
+func TestService_Suggest(t *testing.T) {
+	rule := testRule()
+	rule.Tipo, rule.Concepto = "RETEFUENTE", "SERVICIOS"
+	repo := testutil.NewMockWithholdingRepository(rule)
+	service := NewService(repo)
+
+	if _, err := service.Suggest(context.Background(), testDocument("2026-03-15"), "FC"); err == nil || !strings.Contains(err.Error(), "no hay valor UVT configurado para el año [2026]") {
+		t.Fatalf("expected missing UVT error, got %v", err)
+	}
+	if err := service.SetUVT(context.Background(), 2026, "52374"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := service.Suggest(context.Background(), testDocument("2026-03-15"), "FC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].ValorMonedaNacional.Valor != "20000.00" {
+		t.Fatalf("expected ReteFuente of 20000.00, got %+v", got)
+	}
+	if repo.RuleLoads != 1 {
+		t.Errorf("expected rules to be cached, got %d loads", repo.RuleLoads)
+	}
+
+	// Saving a rule reloads the rules
+	disabled := rule
+	disabled.ID, disabled.Activo = 1, false
+	if _, err := service.SaveRule(context.Background(), disabled); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := service.Suggest(context.Background(), testDocument("2026-03-15"), "FC"); len(got) != 0 {
+		t.Errorf("expected inactive rule to be ignored, got %+v", got)
+	}
+}
+
+func TestService_SetUVT_Invalid(t *testing.T) {
+	service := NewService(testutil.NewMockWithholdingRepository())
+	if err := service.SetUVT(context.Background(), 2026, "-1"); err == nil || !strings.Contains(err.Error(), "valor debe ser") {
+		t.Errorf("expected invalid value error, got %v", err)
+	}
+	if err := service.SetUVT(context.Background(), 1990, "1000"); err == nil || !strings.Contains(err.Error(), "anio [1990] inválido") {
+		t.Errorf("expected invalid year error, got %v", err)
+	}
+}
@@ -9,20 +9,37 @@ import (
 // ProviderAuditLog represents an audit record for external provider API calls.
 // It captures complete request/response details for debugging, compliance, and monitoring.
 type ProviderAuditLog struct {
-	ID              int64
-	CorrelationID   string
-	Provider        string
-	Operation       string
-	RequestMethod   string
-	RequestURL      string
-	RequestHeaders  map[string]string
-	RequestBody     json.RawMessage
-	ResponseStatus  *int
-	ResponseHeaders map[string]string
-	ResponseBody    json.RawMessage
-	DurationMs      int64
-	ErrorMessage    string
-	CreatedAt       time.Time
+	ID              int64             `json:"id"`
+	CorrelationID   string            `json:"correlation_id"`
+	Provider        string            `json:"provider"`
+	Operation       string            `json:"operation"`
+	RequestMethod   string            `json:"request_method"`
+	RequestURL      string            `json:"request_url"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	RequestBody     json.RawMessage   `json:"request_body,omitempty"`
+	ResponseStatus  *int              `json:"response_status,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    json.RawMessage   `json:"response_body,omitempty"`
+	DurationMs      int64             `json:"duration_ms"`
+	ErrorMessage    string            `json:"error_message,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	PrevHash        string            `json:"prev_hash,omitempty"` // Hash of the previous log of the same chain (empty for the first one)
+	Hash            string            `json:"hash,omitempty"`      // Chain hash of this log, see ChainHash
+}
+
+// Filter holds the optional criteria for searching audit logs.
+// Empty fields are ignored.
+type Filter struct {
+	From          time.Time // Logs created at or after this time
+	To            time.Time // Logs created before this time
+	Provider      string
+	Operation     string
+	Status        *int  // Response status code
+	MinDurationMs int64 // Logs that took at least this long
+	CorrelationID string
+	Document      string // Document identifier (prefijo and consecutivo, CUFE) found in the request URL or bodies
+	Start         int    // starting index (0-based)
+	Length        int    // number of records to return
 }
 
 // Repository defines the contract for persisting and retrieving audit logs.
@@ -34,3 +51,23 @@ type Repository interface {
 	// This is useful for debugging and tracking the complete flow of a request.
 	FindByCorrelationID(ctx context.Context, correlationID string) ([]ProviderAuditLog, error)
 }
+
+// Reader retrieves audit logs for support and compliance queries.
+type Reader interface {
+	// Search retrieves the audit logs matching the filter, newest first, and the total count.
+	Search(ctx context.Context, filter Filter) ([]ProviderAuditLog, int, error)
+
+	// Export calls fn for every audit log matching the filter, oldest first, ignoring
+	// pagination. It stops at the first error returned by fn.
+	Export(ctx context.Context, filter Filter, fn func(ProviderAuditLog) error) error
+}
+
+// Retention manages the monthly partitions that hold the audit logs.
+type Retention interface {
+	// EnsurePartition creates the partition for the month of t if it does not exist.
+	EnsurePartition(ctx context.Context, t time.Time) error
+
+	// DropPartitionsBefore drops the partitions of the months before the month of t
+	// and returns their names.
+	DropPartitionsBefore(ctx context.Context, t time.Time) ([]string, error)
+}
@@ -0,0 +1,306 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Each monthly partition of the audit log holds one hash chain. Every log stores the hash of
+// its canonical content chained to the hash of the previous log of the same chain, so an
+// altered, removed or reordered log breaks every link after it. Signed checkpoints of the
+// chain heads, kept in a separate table, detect a chain rewritten from scratch.
+
+// chainLayout is the format of a chain identifier: the month of its partition.
+const chainLayout = "2006-01"
+
+// createdAtLayout is the canonical format of CreatedAt. The zone is left out because the
+// column keeps the wall clock only, with microsecond precision.
+const createdAtLayout = "2006-01-02T15:04:05.000000"
+
+// ChainOf returns the identifier (YYYY-MM) of the chain that holds the logs created at t.
+func ChainOf(t time.Time) string {
+	return t.Format(chainLayout)
+}
+
+// ParseChain returns the first day of the month of a chain identifier.
+func ParseChain(chain string) (time.Time, error) {
+	return time.Parse(chainLayout, chain)
+}
+
+// canonicalLog is the serialization of a log that is hashed. Its field order is fixed.
+type canonicalLog struct {
+	ID              int64             `json:"id"`
+	CorrelationID   string            `json:"correlation_id"`
+	Provider        string            `json:"provider"`
+	Operation       string            `json:"operation"`
+	RequestMethod   string            `json:"request_method"`
+	RequestURL      string            `json:"request_url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     interface{}       `json:"request_body"`
+	ResponseStatus  *int              `json:"response_status"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	ResponseBody    interface{}       `json:"response_body"`
+	DurationMs      int64             `json:"duration_ms"`
+	ErrorMessage    string            `json:"error_message"`
+	CreatedAt       string            `json:"created_at"`
+}
+
+// CanonicalContent returns the serialization of the log that is hashed into its chain.
+// Bodies are decoded and re-encoded, so the key order and spacing applied by the
+// database when storing them as JSONB do not change the result.
+func (l ProviderAuditLog) CanonicalContent() ([]byte, error) {
+	requestBody, err := canonicalBody(l.RequestBody)
+	if err != nil {
+		return nil, fmt.Errorf("canonical request body: %w", err)
+	}
+	responseBody, err := canonicalBody(l.ResponseBody)
+	if err != nil {
+		return nil, fmt.Errorf("canonical response body: %w", err)
+	}
+
+	return json.Marshal(canonicalLog{
+		ID:              l.ID,
+		CorrelationID:   l.CorrelationID,
+		Provider:        l.Provider,
+		Operation:       l.Operation,
+		RequestMethod:   l.RequestMethod,
+		RequestURL:      l.RequestURL,
+		RequestHeaders:  l.RequestHeaders,
+		RequestBody:     requestBody,
+		ResponseStatus:  l.ResponseStatus,
+		ResponseHeaders: l.ResponseHeaders,
+		ResponseBody:    responseBody,
+		DurationMs:      l.DurationMs,
+		ErrorMessage:    l.ErrorMessage,
+		CreatedAt:       l.CreatedAt.Format(createdAtLayout),
+	})
+}
+
+// canonicalBody decodes a JSON body; an empty body is stored as NULL.
+func canonicalBody(body json.RawMessage) (interface{}, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// ChainHash returns the hex SHA-256 of the previous hash of the chain followed by the
+// canonical content of the log.
+func ChainHash(prevHash string, log ProviderAuditLog) (string, error) {
+	content, err := log.CanonicalContent()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write([]byte("\n"))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Checkpoint is a signed record of the head of a chain at a point in time.
+type Checkpoint struct {
+	ID        int64     `json:"id"`
+	Chain     string    `json:"chain"`
+	LastID    int64     `json:"last_id"`
+	LastHash  string    `json:"last_hash"`
+	CreatedAt time.Time `json:"created_at"`
+	Signature string    `json:"signature"` // Base64 Ed25519 signature of SigningPayload
+}
+
+// SigningPayload returns the bytes covered by the signature of the checkpoint.
+func (c Checkpoint) SigningPayload() []byte {
+	return []byte(c.Chain + "|" + strconv.FormatInt(c.LastID, 10) + "|" + c.LastHash + "|" + c.CreatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// Sign sets the signature of the checkpoint with the given key.
+func (c *Checkpoint) Sign(key ed25519.PrivateKey) {
+	c.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, c.SigningPayload()))
+}
+
+// VerifySignature reports whether the checkpoint was signed by the key of publicKey.
+func (c Checkpoint) VerifySignature(publicKey ed25519.PublicKey) bool {
+	signature, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(publicKey, c.SigningPayload(), signature)
+}
+
+// ParseSigningKey decodes a base64 Ed25519 private key, given either as its 32-byte seed
+// or as the full 64-byte key.
+func ParseSigningKey(encoded string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode signing key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, errors.New("signing key must be a 32-byte Ed25519 seed or a 64-byte private key")
+	}
+}
+
+// ChainBreak describes the first broken link found in a chain.
+type ChainBreak struct {
+	ID           int64  `json:"id"` // ID of the log where the chain breaks
+	Reason       string `json:"reason"`
+	ExpectedHash string `json:"expected_hash,omitempty"`
+	StoredHash   string `json:"stored_hash,omitempty"`
+}
+
+// Verification is the result of walking a chain.
+type Verification struct {
+	Chain       string      `json:"chain"`
+	Valid       bool        `json:"valid"`
+	Verified    int         `json:"verified"`    // Logs whose hash was recomputed and matched
+	Unsealed    int         `json:"unsealed"`    // Logs stored before the chain was introduced
+	Checkpoints int         `json:"checkpoints"` // Signed checkpoints matched against the chain
+	LastID      int64       `json:"last_id,omitempty"`
+	LastHash    string      `json:"last_hash,omitempty"`
+	Break       *ChainBreak `json:"break,omitempty"`
+}
+
+// ChainVerifier walks a chain log by log and stops at the first broken link.
+// Logs must be added in chain order (ascending ID).
+type ChainVerifier struct {
+	result      Verification
+	prevHash    string
+	checkpoints map[int64]Checkpoint
+	sealed      bool
+}
+
+// NewChainVerifier creates a verifier for the chain with its checkpoints. When publicKey
+// is not nil, the signature of every checkpoint is verified first.
+func NewChainVerifier(chain string, checkpoints []Checkpoint, publicKey ed25519.PublicKey) *ChainVerifier {
+	v := &ChainVerifier{
+		result:      Verification{Chain: chain, Valid: true},
+		checkpoints: make(map[int64]Checkpoint, len(checkpoints)),
+	}
+	for _, cp := range checkpoints {
+		if publicKey != nil && !cp.VerifySignature(publicKey) {
+			v.fail(ChainBreak{ID: cp.LastID, Reason: fmt.Sprintf("la firma del checkpoint %d no es válida", cp.ID)})
+			break
+		}
+		v.checkpoints[cp.LastID] = cp
+	}
+	return v
+}
+
+// Add checks the next log of the chain. It returns false once the chain is broken.
+func (v *ChainVerifier) Add(log ProviderAuditLog) bool {
+	if !v.result.Valid {
+		return false
+	}
+
+	if log.Hash == "" {
+		if v.sealed {
+			return v.fail(ChainBreak{ID: log.ID, Reason: "el registro no tiene hash dentro de la cadena"})
+		}
+		v.result.Unsealed++
+		return true
+	}
+	v.sealed = true
+
+	if log.PrevHash != v.prevHash {
+		return v.fail(ChainBreak{
+			ID:           log.ID,
+			Reason:       "prev_hash no coincide con el hash del registro anterior",
+			ExpectedHash: v.prevHash,
+			StoredHash:   log.PrevHash,
+		})
+	}
+
+	expected, err := ChainHash(log.PrevHash, log)
+	if err != nil {
+		return v.fail(ChainBreak{ID: log.ID, Reason: fmt.Sprintf("el contenido no se puede serializar: %v", err)})
+	}
+	if expected != log.Hash {
+		return v.fail(ChainBreak{
+			ID:           log.ID,
+			Reason:       "el contenido del registro no coincide con su hash",
+			ExpectedHash: expected,
+			StoredHash:   log.Hash,
+		})
+	}
+
+	if cp, ok := v.checkpoints[log.ID]; ok {
+		if cp.LastHash != log.Hash {
+			return v.fail(ChainBreak{
+				ID:           log.ID,
+				Reason:       fmt.Sprintf("el hash no coincide con el checkpoint firmado %d", cp.ID),
+				ExpectedHash: cp.LastHash,
+				StoredHash:   log.Hash,
+			})
+		}
+		delete(v.checkpoints, log.ID)
+		v.result.Checkpoints++
+	}
+
+	v.prevHash = log.Hash
+	v.result.Verified++
+	v.result.LastID = log.ID
+	v.result.LastHash = log.Hash
+	return true
+}
+
+// Result finishes the walk and returns the verification. Checkpoints whose log was not
+// found mean the end of the chain was removed.
+func (v *ChainVerifier) Result() Verification {
+	if v.result.Valid && len(v.checkpoints) > 0 {
+		var missing *Checkpoint
+		for _, cp := range v.checkpoints {
+			if missing == nil || cp.LastID < missing.LastID {
+				missing = &cp
+			}
+		}
+		v.fail(ChainBreak{
+			ID:           missing.LastID,
+			Reason:       fmt.Sprintf("el registro del checkpoint firmado %d no existe", missing.ID),
+			ExpectedHash: missing.LastHash,
+		})
+	}
+	return v.result
+}
+
+func (v *ChainVerifier) fail(b ChainBreak) bool {
+	v.result.Valid = false
+	v.result.Break = &b
+	return false
+}
+
+// ChainStore reads the hash chains of the audit log and stores their signed checkpoints.
+type ChainStore interface {
+	// Chains returns the identifiers of the chains (one per monthly partition), oldest first.
+	Chains(ctx context.Context) ([]string, error)
+
+	// WalkChain calls fn for every log of the chain in chain order.
+	// It stops at the first error returned by fn.
+	WalkChain(ctx context.Context, chain string, fn func(ProviderAuditLog) error) error
+
+	// ChainHead returns the last log of the chain, or nil if the chain is empty.
+	ChainHead(ctx context.Context, chain string) (*ProviderAuditLog, error)
+
+	// SaveCheckpoint stores a signed checkpoint.
+	SaveCheckpoint(ctx context.Context, checkpoint Checkpoint) error
+
+	// Checkpoints returns the checkpoints of the chain, oldest first.
+	Checkpoints(ctx context.Context, chain string) ([]Checkpoint, error)
+}
@@ -0,0 +1,208 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildChain returns n logs chained to each other, as stored by the repository.
+func buildChain(t *testing.T, n int) []ProviderAuditLog {
+	t.Helper()
+	status := 200
+	prev := ""
+	logs := make([]ProviderAuditLog, 0, n)
+	for i := 1; i <= n; i++ {
+		log := ProviderAuditLog{
+			ID:             int64(i),
+			CorrelationID:  "req-" + string(rune('0'+i)),
+			Provider:       "numrot",
+			Operation:      "RegisterDocument",
+			RequestMethod:  "POST",
+			RequestURL:     "https://numrot.example.com/api/documentos",
+			RequestHeaders: map[string]string{"Content-Type": "application/json"},
+			RequestBody:    json.RawMessage(`{"Numero":"SETT100","Total":1190.50}`),
+			ResponseStatus: &status,
+			DurationMs:     120,
+			CreatedAt:      time.Date(2026, 10, 18, 9, 0, i, 123456000, time.UTC),
+			PrevHash:       prev,
+		}
+		hash, err := ChainHash(prev, log)
+		if err != nil {
+			t.Fatalf("chain hash: %v", err)
+		}
+		log.Hash = hash
+		prev = hash
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+func verify(chain string, logs []ProviderAuditLog, checkpoints []Checkpoint, publicKey ed25519.PublicKey) Verification {
+	v := NewChainVerifier(chain, checkpoints, publicKey)
+	for _, log := range logs {
+		if !v.Add(log) {
+			break
+		}
+	}
+	return v.Result()
+}
+
+func TestChainHash_IgnoresJSONBNormalization(t *testing.T) {
+	log := buildChain(t, 1)[0]
+
+	// JSONB reorders keys and adds spaces; the hash must not change
+	stored := log
+	stored.RequestBody = json.RawMessage(`{"Total": 1190.50, "Numero": "SETT100"}`)
+
+	original, _ := ChainHash("", log)
+	roundTrip, err := ChainHash("", stored)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if original != roundTrip {
+		t.Errorf("expected the same hash after the JSONB round trip")
+	}
+
+	changed := stored
+	changed.RequestBody = json.RawMessage(`{"Total": 1190.51, "Numero": "SETT100"}`)
+	if tampered, _ := ChainHash("", changed); tampered == original {
+		t.Errorf("expected a different hash for a different body")
+	}
+}
+
+func TestChainVerifier(t *testing.T) {
+	tests := []struct {
+		name         string
+		tamper       func(logs []ProviderAuditLog) []ProviderAuditLog
+		expectedID   int64
+		expectedText string
+	}{
+		{
+			name:   "intact chain",
+			tamper: func(logs []ProviderAuditLog) []ProviderAuditLog { return logs },
+		},
+		{
+			name: "modified content",
+			tamper: func(logs []ProviderAuditLog) []ProviderAuditLog {
+				logs[1].ResponseBody = json.RawMessage(`{"Estado":"Aceptado"}`)
+				return logs
+			},
+			expectedID:   2,
+			expectedText: "no coincide con su hash",
+		},
+		{
+			name: "removed log",
+			tamper: func(logs []ProviderAuditLog) []ProviderAuditLog {
+				return append(logs[:1], logs[2:]...)
+			},
+			expectedID:   3,
+			expectedText: "prev_hash no coincide",
+		},
+		{
+			name: "log without hash after the chain started",
+			tamper: func(logs []ProviderAuditLog) []ProviderAuditLog {
+				logs[2].Hash = ""
+				return logs
+			},
+			expectedID:   3,
+			expectedText: "no tiene hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := verify("2026-10", tt.tamper(buildChain(t, 4)), nil, nil)
+			if tt.expectedText == "" {
+				if !result.Valid || result.Verified != 4 || result.LastID != 4 {
+					t.Fatalf("expected a valid chain of 4 logs, got %+v", result)
+				}
+				return
+			}
+			if result.Valid || result.Break == nil {
+				t.Fatalf("expected a broken chain, got %+v", result)
+			}
+			if result.Break.ID != tt.expectedID || !strings.Contains(result.Break.Reason, tt.expectedText) {
+				t.Errorf("expected break at %d containing %q, got %+v", tt.expectedID, tt.expectedText, result.Break)
+			}
+		})
+	}
+}
+
+func TestChainVerifier_UnsealedLogsBeforeTheChain(t *testing.T) {
+	logs := append([]ProviderAuditLog{{ID: 0, Provider: "numrot"}}, buildChain(t, 2)...)
+
+	result := verify("2026-10", logs, nil, nil)
+	if !result.Valid || result.Unsealed != 1 || result.Verified != 2 {
+		t.Errorf("expected 1 unsealed and 2 verified logs, got %+v", result)
+	}
+}
+
+func TestChainVerifier_Checkpoints(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	publicKey := key.Public().(ed25519.PublicKey)
+	logs := buildChain(t, 3)
+
+	checkpoint := Checkpoint{ID: 1, Chain: "2026-10", LastID: 3, LastHash: logs[2].Hash, CreatedAt: time.Now()}
+	checkpoint.Sign(key)
+
+	result := verify("2026-10", logs, []Checkpoint{checkpoint}, publicKey)
+	if !result.Valid || result.Checkpoints != 1 {
+		t.Fatalf("expected a valid chain with 1 checkpoint, got %+v", result)
+	}
+
+	// A chain rewritten from scratch has consistent links but no longer matches the checkpoint
+	rewritten := buildChain(t, 3)
+	rewritten[0].DurationMs = 1
+	prev := ""
+	for i := range rewritten {
+		rewritten[i].PrevHash = prev
+		rewritten[i].Hash, _ = ChainHash(prev, rewritten[i])
+		prev = rewritten[i].Hash
+	}
+	result = verify("2026-10", rewritten, []Checkpoint{checkpoint}, publicKey)
+	if result.Valid || !strings.Contains(result.Break.Reason, "checkpoint firmado 1") {
+		t.Errorf("expected the checkpoint to detect the rewrite, got %+v", result)
+	}
+
+	// Removing the end of the chain leaves the checkpoint without its log
+	result = verify("2026-10", logs[:2], []Checkpoint{checkpoint}, publicKey)
+	if result.Valid || result.Break.ID != 3 || !strings.Contains(result.Break.Reason, "no existe") {
+		t.Errorf("expected the truncated chain to be detected, got %+v", result)
+	}
+
+	// A forged checkpoint fails its signature
+	forged := checkpoint
+	forged.LastHash = rewritten[2].Hash
+	result = verify("2026-10", rewritten, []Checkpoint{forged}, publicKey)
+	if result.Valid || !strings.Contains(result.Break.Reason, "firma del checkpoint") {
+		t.Errorf("expected the forged checkpoint to be detected, got %+v", result)
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 7
+
+	key, err := ParseSigningKey(base64.StdEncoding.EncodeToString(seed))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	full, err := ParseSigningKey(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !key.Equal(full) {
+		t.Errorf("expected the seed and the full key to match")
+	}
+
+	if _, err := ParseSigningKey(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("expected an error for a key of the wrong size")
+	}
+	if _, err := ParseSigningKey("not base64!"); err == nil {
+		t.Error("expected an error for an invalid encoding")
+	}
+}
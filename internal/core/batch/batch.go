@@ -0,0 +1,98 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// ErrLeaseLost is returned by AppendResults when the lease of the batch expired or the
+// batch was taken over by another instance: the caller must stop processing it.
+var ErrLeaseLost = errors.New("la concesión del lote expiró o fue tomada por otra instancia")
+
+// Status represents the processing state of an asynchronous registration batch (lote).
+type Status string
+
+const (
+	// StatusPending indicates the batch is queued and has not started.
+	StatusPending Status = "pending"
+	// StatusProcessing indicates the batch is being processed.
+	StatusProcessing Status = "processing"
+	// StatusCompleted indicates every document of the batch was processed.
+	StatusCompleted Status = "completed"
+	// StatusFailed indicates the batch could not be processed.
+	StatusFailed Status = "failed"
+)
+
+// IsFinal reports whether the batch has finished processing.
+func (s Status) IsFinal() bool {
+	return s == StatusCompleted || s == StatusFailed
+}
+
+// Batch is an asynchronous document registration job.
+// Cursor is the number of documents already processed, so an interrupted batch
+// resumes from the first unprocessed document.
+type Batch struct {
+	ID                   string                              `json:"lote"`
+	Estado               Status                              `json:"estado"`
+	TipoDocumento        string                              `json:"tipo_documento"`
+	Total                int                                 `json:"total"`
+	Cursor               int                                 `json:"-"`
+	Procesados           int                                 `json:"procesados"`
+	Fallidos             int                                 `json:"fallidos"`
+	Request              invoice.DocumentRegistrationRequest `json:"-"`
+	DocumentosProcesados []invoice.ProcessedDocument         `json:"documentos_procesados"`
+	DocumentosFallidos   []invoice.FailedDocument            `json:"documentos_fallidos"`
+	Error                string                              `json:"error,omitempty"`
+	CreatedAt            time.Time                           `json:"fecha_creacion"`
+	StartedAt            *time.Time                          `json:"fecha_inicio,omitempty"`
+	FinishedAt           *time.Time                          `json:"fecha_fin,omitempty"`
+}
+
+// Documents returns the documents of the batch and their type.
+func (b *Batch) Documents() ([]invoice.OpenETLDocument, string) {
+	docs := b.Request.Documentos
+	switch {
+	case len(docs.FC) > 0:
+		return docs.FC, "FC"
+	case len(docs.NC) > 0:
+		return docs.NC, "NC"
+	case len(docs.ND) > 0:
+		return docs.ND, "ND"
+	default:
+		return docs.DS, "DS"
+	}
+}
+
+// Repository defines the persistence operations for registration batches.
+type Repository interface {
+	// Create persists a new batch.
+	Create(ctx context.Context, batch Batch) error
+
+	// FindByID retrieves a batch by its ID.
+	// Returns nil if not found.
+	FindByID(ctx context.Context, id string) (*Batch, error)
+
+	// Claim takes the oldest batch that is pending, or processing with an expired lease,
+	// and flags it as being processed with a lease of the given duration. The check and
+	// the update are atomic, so a batch is processed by a single instance at a time.
+	// Returns nil if there is no batch to claim.
+	Claim(ctx context.Context, lease time.Duration) (*Batch, error)
+
+	// Extend renews the lease of a batch being processed.
+	Extend(ctx context.Context, id string, lease time.Duration) error
+
+	// Release ends the lease of a batch whose processing was interrupted, so that it can
+	// be claimed again right away.
+	Release(ctx context.Context, id string) error
+
+	// AppendResults stores the results of the chunk that starts at cursor from and advances
+	// the cursor to to. The update only applies while the lease is held and the cursor is
+	// still at from; otherwise nothing is stored and ErrLeaseLost is returned.
+	AppendResults(ctx context.Context, id string, from, to int, processed []invoice.ProcessedDocument, failed []invoice.FailedDocument) error
+
+	// Finish sets the final status of the batch.
+	Finish(ctx context.Context, id string, status Status, errMsg string) error
+}
@@ -0,0 +1,223 @@
+// Package codelist holds the code lists of the DIAN Anexo Técnico (units, taxes, payment
+// forms and means, identification types, correction concepts, tax responsibilities,
+// countries and currencies) used to validate the coded fields of documents, acquirers and
+// providers. The lists are embedded in the binary as CSV files (codigo;nombre[;descripcion]).
+package codelist
+
+import (
+	"bytes"
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Names of the code lists, as served by GET /catalogos/{lista}.
+const (
+	Units                = "unidades"
+	Taxes                = "tributos"
+	PaymentForms         = "formas-pago"
+	PaymentMeans         = "medios-pago"
+	IdentificationTypes  = "tipos-documento-identidad"
+	OrganizationTypes    = "tipos-organizacion"
+	CorrectionConceptsNC = "conceptos-correccion-nc"
+	CorrectionConceptsND = "conceptos-correccion-nd"
+	TaxResponsibilities  = "responsabilidades-fiscales"
+	TaxRegimes           = "regimenes-fiscales"
+	Countries            = "paises"
+	Currencies           = "monedas"
+)
+
+// DefaultUnit is the unit code used when an item has none (94 = unidad).
+const DefaultUnit = "94"
+
+//go:embed data/*.csv
+var data embed.FS
+
+// Code is an entry of a code list.
+type Code struct {
+	Codigo      string `json:"codigo"`
+	Nombre      string `json:"nombre"`
+	Descripcion string `json:"descripcion,omitempty"`
+}
+
+// List is a DIAN code list.
+type List struct {
+	Nombre      string `json:"lista"`
+	Descripcion string `json:"descripcion"`
+	Codigos     []Code `json:"codigos"`
+}
+
+// definitions describes the lists in the order they are listed.
+var definitions = []struct {
+	name        string
+	description string
+}{
+	{Units, "Unidades de medida (UN/ECE Rec 20)"},
+	{Taxes, "Tributos"},
+	{PaymentForms, "Formas de pago"},
+	{PaymentMeans, "Medios de pago"},
+	{IdentificationTypes, "Tipos de documento de identidad"},
+	{OrganizationTypes, "Tipos de organización jurídica"},
+	{CorrectionConceptsNC, "Conceptos de corrección para notas crédito"},
+	{CorrectionConceptsND, "Conceptos de corrección para notas débito"},
+	{TaxResponsibilities, "Responsabilidades fiscales"},
+	{TaxRegimes, "Regímenes fiscales"},
+	{Countries, "Países (ISO 3166-1)"},
+	{Currencies, "Monedas (ISO 4217)"},
+}
+
+// unitAliases maps the OpenETL unit codes still sent by clients to UN/ECE Rec 20 codes.
+var unitAliases = map[string]string{
+	"UN":  "94",
+	"KG":  "KGM",
+	"GR":  "GRM",
+	"LT":  "LTR",
+	"MT":  "MTR",
+	"M2":  "MTK",
+	"M3":  "MTQ",
+	"HR":  "HUR",
+	"DIA": "DAY",
+	"PAR": "PR",
+	"DOC": "DZN",
+	"CM":  "CMT",
+	"MM":  "MMT",
+}
+
+var (
+	lists   = make(map[string]List)
+	indexes = make(map[string]map[string]Code)
+)
+
+func init() {
+	for _, def := range definitions {
+		content, err := data.ReadFile("data/" + def.name + ".csv")
+		if err != nil {
+			panic(fmt.Sprintf("codelist: %v", err))
+		}
+		codes, err := parse(bytes.NewReader(content))
+		if err != nil {
+			panic(fmt.Sprintf("codelist %s: %v", def.name, err))
+		}
+		index := make(map[string]Code, len(codes))
+		for _, c := range codes {
+			index[c.Codigo] = c
+		}
+		lists[def.name] = List{Nombre: def.name, Descripcion: def.description, Codigos: codes}
+		indexes[def.name] = index
+	}
+}
+
+// parse reads a code list with a header row.
+func parse(r io.Reader) ([]Code, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("empty list")
+	}
+
+	codes := make([]Code, 0, len(records)-1)
+	seen := make(map[string]bool, len(records)-1)
+	for i, record := range records[1:] {
+		if len(record) < 2 || record[0] == "" || record[1] == "" {
+			return nil, fmt.Errorf("line %d: expected codigo;nombre", i+2)
+		}
+		if seen[record[0]] {
+			return nil, fmt.Errorf("line %d: duplicated code %s", i+2, record[0])
+		}
+		seen[record[0]] = true
+		c := Code{Codigo: record[0], Nombre: record[1]}
+		if len(record) > 2 {
+			c.Descripcion = record[2]
+		}
+		codes = append(codes, c)
+	}
+	return codes, nil
+}
+
+// Names returns the names of the available lists.
+func Names() []string {
+	names := make([]string, 0, len(definitions))
+	for _, def := range definitions {
+		names = append(names, def.name)
+	}
+	return names
+}
+
+// Get returns a copy of a list.
+func Get(name string) (List, bool) {
+	list, ok := lists[name]
+	if !ok {
+		return List{}, false
+	}
+	list.Codigos = append([]Code(nil), list.Codigos...)
+	return list, true
+}
+
+// Lookup returns the entry of code in a list.
+func Lookup(name, code string) (Code, bool) {
+	c, ok := indexes[name][code]
+	return c, ok
+}
+
+// Contains reports whether code belongs to a list.
+func Contains(name, code string) bool {
+	_, ok := Lookup(name, code)
+	return ok
+}
+
+// Check returns an error naming the field when code does not belong to the list.
+func Check(name, field, code string) error {
+	if Contains(name, code) {
+		return nil
+	}
+	return fmt.Errorf("%s [%s] inválido: no pertenece a la lista %s de la DIAN", field, code, name)
+}
+
+// CheckAll checks every code of a multi-valued field.
+func CheckAll(name, field string, codes []string) error {
+	for _, code := range codes {
+		if err := Check(name, field, strings.TrimSpace(code)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnitCode returns the UN/ECE Rec 20 code of a unit, translating the OpenETL aliases
+// ("KG" -> "KGM"). An empty unit is DefaultUnit.
+func UnitCode(code string) string {
+	if code == "" {
+		return DefaultUnit
+	}
+	if mapped, ok := unitAliases[code]; ok {
+		return mapped
+	}
+	return code
+}
+
+// TaxName returns the short name of a tax (tri_codigo) used in the UBL TaxScheme.
+func TaxName(code string) string {
+	if c, ok := Lookup(Taxes, code); ok {
+		return c.Nombre
+	}
+	return "Impuesto"
+}
+
+// CorrectionConcepts returns the list of correction concepts of a note type (NC or ND).
+func CorrectionConcepts(documentType string) (string, bool) {
+	switch documentType {
+	case "NC":
+		return CorrectionConceptsNC, true
+	case "ND":
+		return CorrectionConceptsND, true
+	}
+	return "", false
+}
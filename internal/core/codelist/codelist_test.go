@@ -0,0 +1,95 @@
+package codelist
+
+import (
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/identification"
+)
+
+func TestLists_Loaded(t *testing.T) {
+	for _, name := range Names() {
+		list, ok := Get(name)
+		if !ok || len(list.Codigos) == 0 {
+			t.Errorf("list %s is empty", name)
+		}
+		if list.Descripcion == "" {
+			t.Errorf("list %s has no description", name)
+		}
+	}
+	if _, ok := Get("colores"); ok {
+		t.Error("expected unknown list")
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		list string
+		code string
+		want bool
+	}{
+		{Units, "KGM", true},
+		{Units, "KG", false},
+		{Taxes, "01", true},
+		{Taxes, "99", false},
+		{PaymentForms, "2", true},
+		{PaymentMeans, "ZZZ", true},
+		{PaymentMeans, "999", false},
+		{CorrectionConceptsNC, "6", true},
+		{CorrectionConceptsND, "6", false},
+		{TaxResponsibilities, "R-99-PN", true},
+		{TaxResponsibilities, "O-99", false},
+		{Countries, "CO", true},
+		{Countries, "co", false},
+		{Currencies, "USD", true},
+		{Currencies, "XYZ", false},
+	}
+	for _, tt := range tests {
+		if got := Contains(tt.list, tt.code); got != tt.want {
+			t.Errorf("Contains(%s, %s) = %v, want %v", tt.list, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(Countries, "pai_codigo", "CO"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := Check(Countries, "pai_codigo", "XX")
+	if err == nil || !strings.Contains(err.Error(), "pai_codigo [XX] inválido") {
+		t.Errorf("expected pai_codigo error, got %v", err)
+	}
+	if err := CheckAll(TaxResponsibilities, "ref_codigo", []string{"O-13", " O-15 "}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUnitCode(t *testing.T) {
+	tests := map[string]string{"": "94", "UN": "94", "KG": "KGM", "DOC": "DZN", "MTR": "MTR"}
+	for code, want := range tests {
+		if got := UnitCode(code); got != want {
+			t.Errorf("UnitCode(%q) = %s, want %s", code, got, want)
+		}
+		if !Contains(Units, UnitCode(code)) {
+			t.Errorf("UnitCode(%q) = %s is not in the units list", code, UnitCode(code))
+		}
+	}
+}
+
+func TestTaxName(t *testing.T) {
+	if got := TaxName("06"); got != "ReteRenta" {
+		t.Errorf("expected ReteRenta, got %s", got)
+	}
+	if got := TaxName("99"); got != "Impuesto" {
+		t.Errorf("expected fallback name, got %s", got)
+	}
+}
+
+func TestIdentificationTypes_MatchIdentificationPackage(t *testing.T) {
+	list, _ := Get(IdentificationTypes)
+	for _, c := range list.Codigos {
+		if !identification.ValidType(c.Codigo) {
+			t.Errorf("tdo_codigo %s is not supported by the identification package", c.Codigo)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package contingency
+
+import (
+	"context"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+const (
+	// TypeIssuer is the invoice type code (InvoiceTypeCode) of invoices issued in
+	// contingency of the issuer, when the issuer or its provider cannot transmit.
+	TypeIssuer = "03"
+	// TypeDIAN is the invoice type code of invoices issued while DIAN is unavailable.
+	TypeDIAN = "04"
+
+	// ReferenceType is the DocumentTypeCode of the contingency document referenced by
+	// a contingency invoice (factura de talonario o de contingencia).
+	ReferenceType = "FTC"
+)
+
+// IsValidType reports whether tipo is a contingency invoice type code.
+func IsValidType(tipo string) bool {
+	return tipo == TypeIssuer || tipo == TypeDIAN
+}
+
+// Origin tells how the contingency mode was activated.
+type Origin string
+
+const (
+	// OriginManual indicates the mode was activated through the API.
+	OriginManual Origin = "manual"
+	// OriginAutomatic indicates the mode was activated because the provider circuit
+	// stayed open past the configured threshold.
+	OriginAutomatic Origin = "automatica"
+)
+
+// State is the current contingency mode. It is persisted, so every instance of the
+// service shares it.
+type State struct {
+	Activa bool       `json:"activa"`
+	Tipo   string     `json:"tipo_contingencia,omitempty"`
+	Origen Origin     `json:"origen,omitempty"`
+	Motivo string     `json:"motivo,omitempty"`
+	Desde  *time.Time `json:"desde,omitempty"`
+}
+
+// Status represents the transmission state of a document stored in contingency.
+type Status string
+
+const (
+	// StatusPending indicates the document waits to be transmitted.
+	StatusPending Status = "pending"
+	// StatusTransmitted indicates the document was transmitted and accepted.
+	StatusTransmitted Status = "transmitted"
+	// StatusFailed indicates the document was transmitted and rejected.
+	StatusFailed Status = "failed"
+)
+
+// Document is a document stored locally while the contingency mode is active.
+// Numero is the contingency number assigned from the OFE own contingency numbering.
+type Document struct {
+	ID                int64                   `json:"id"`
+	OfeIdentificacion string                  `json:"ofe_identificacion"`
+	Tipo              string                  `json:"tipo"` // FC, NC, ND, DS
+	Prefijo           string                  `json:"prefijo"`
+	Consecutivo       string                  `json:"consecutivo"`
+	Numero            string                  `json:"numero_contingencia"`
+	TipoContingencia  string                  `json:"tipo_contingencia"`
+	Estado            Status                  `json:"estado"`
+	Payload           invoice.OpenETLDocument `json:"-"`
+	Intentos          int                     `json:"intentos"`
+	CUFE              string                  `json:"cufe,omitempty"`
+	Errors            []string                `json:"errors,omitempty"`
+	CreatedAt         time.Time               `json:"fecha_creacion"`
+	TransmittedAt     *time.Time              `json:"fecha_transmision,omitempty"`
+}
+
+// Summary counts the stored documents by transmission state.
+type Summary struct {
+	Pendientes          int            `json:"pendientes"`
+	Transmitidos        int            `json:"transmitidos"`
+	Fallidos            int            `json:"fallidos"`
+	PendientesPorOfe    map[string]int `json:"pendientes_por_ofe"`
+	PendienteMasAntiguo *time.Time     `json:"pendiente_mas_antiguo,omitempty"`
+}
+
+// Repository defines the persistence operations for documents stored in contingency.
+type Repository interface {
+	// NextNumber reserves the next number of the OFE contingency numbering.
+	NextNumber(ctx context.Context, ofeIdentificacion string) (int64, error)
+
+	// Save stores a pending document and returns its ID.
+	Save(ctx context.Context, doc Document) (int64, error)
+
+	// ListPending retrieves the pending documents in the order they were stored.
+	ListPending(ctx context.Context) ([]Document, error)
+
+	// RecordAttempt counts a transmission attempt that could not reach the provider.
+	RecordAttempt(ctx context.Context, id int64, errMsg string) error
+
+	// MarkTransmitted flags the document as transmitted and accepted.
+	MarkTransmitted(ctx context.Context, id int64, cufe string) error
+
+	// MarkFailed flags the document as transmitted and rejected.
+	MarkFailed(ctx context.Context, id int64, errs []string) error
+
+	// Summary counts the stored documents by transmission state.
+	Summary(ctx context.Context) (*Summary, error)
+
+	// LoadState retrieves the persisted contingency mode. It is inactive if it was never saved.
+	LoadState(ctx context.Context) (State, error)
+
+	// SaveState persists the contingency mode.
+	SaveState(ctx context.Context, state State) error
+
+	// LockTransmission takes the lock that lets a single instance transmit the backlog at
+	// a time. It returns false when another instance holds it; unlock releases it.
+	LockTransmission(ctx context.Context) (unlock func(), locked bool, err error)
+}
+
+// Dashboard is the contingency state with the counts of stored documents.
+type Dashboard struct {
+	Estado          State   `json:"estado"`
+	CircuitoAbierto bool    `json:"circuito_abierto"`
+	Documentos      Summary `json:"documentos"`
+}
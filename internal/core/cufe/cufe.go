@@ -0,0 +1,268 @@
+// Package cufe computes the unique codes DIAN assigns to electronic documents:
+// CUFE for invoices (FC), CUDE for credit and debit notes (NC/ND) and CUDS for
+// support documents (DS), following the Anexo Técnico de Factura Electrónica 1.9.
+package cufe
+
+import (
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/identification"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/totals"
+)
+
+// Tax codes that take part in the CUFE/CUDE composition.
+const (
+	TaxIVA = "01"
+	TaxICA = "03"
+	TaxINC = "04"
+)
+
+// Length is the length of a CUFE/CUDE/CUDS: a SHA-384 digest in lowercase hex.
+const Length = sha512.Size384 * 2
+
+// colombiaOffset is appended to issue times without an explicit UTC offset.
+const colombiaOffset = "-05:00"
+
+// Keys holds the secrets used in the composition: the technical key of the
+// numbering range (CUFE) and the PIN of the invoicing software (CUDE/CUDS).
+type Keys struct {
+	ClaveTecnica string
+	SoftwarePIN  string
+}
+
+// KeyResolver returns the keys that apply to documents of an OFE and prefix.
+type KeyResolver interface {
+	Resolve(ofeIdentificacion, prefijo string) (Keys, bool)
+}
+
+// StaticKeys resolves the same keys for every OFE and prefix.
+type StaticKeys Keys
+
+// Resolve implements KeyResolver.
+func (k StaticKeys) Resolve(ofeIdentificacion, prefijo string) (Keys, bool) {
+	return Keys(k), k.ClaveTecnica != "" || k.SoftwarePIN != ""
+}
+
+// Input contains the fields of the composition, already in DIAN format.
+// Amounts use two decimals and a dot separator; NITs go without verification digit.
+type Input struct {
+	Numero           string // Prefix and consecutive (NumFac)
+	FechaEmision     string // YYYY-MM-DD
+	HoraEmision      string // HH:MM:SS-05:00
+	ValorBruto       string // LineExtensionAmount (ValFac)
+	ValorIVA         string // Tax 01
+	ValorINC         string // Tax 04
+	ValorICA         string // Tax 03
+	ValorTotal       string // PayableAmount (ValTot)
+	NitEmisor        string // NitOFE; for DS, the buyer NIT (NitABS)
+	NumeroAdquirente string // NumAdq; for DS, the seller document (NumSNO)
+	Clave            string // ClTec for FC, Software-PIN for NC/ND/DS
+	Ambiente         string // 1=producción, 2=pruebas
+}
+
+// Compose returns the string that is hashed for the given document type.
+func Compose(documentType string, in Input) (string, error) {
+	if in.Numero == "" {
+		return "", fmt.Errorf("el número del documento es requerido")
+	}
+	if in.Clave == "" {
+		return "", fmt.Errorf("la clave técnica o PIN del software es requerido")
+	}
+	if in.Ambiente != "1" && in.Ambiente != "2" {
+		return "", fmt.Errorf("el ambiente debe ser '1' (producción) o '2' (pruebas)")
+	}
+
+	var b strings.Builder
+	switch documentType {
+	case "FC", "NC", "ND":
+		b.WriteString(in.Numero)
+		b.WriteString(in.FechaEmision)
+		b.WriteString(in.HoraEmision)
+		b.WriteString(in.ValorBruto)
+		b.WriteString(TaxIVA)
+		b.WriteString(in.ValorIVA)
+		b.WriteString(TaxINC)
+		b.WriteString(in.ValorINC)
+		b.WriteString(TaxICA)
+		b.WriteString(in.ValorICA)
+		b.WriteString(in.ValorTotal)
+		b.WriteString(in.NitEmisor)
+		b.WriteString(in.NumeroAdquirente)
+		b.WriteString(in.Clave)
+		b.WriteString(in.Ambiente)
+	case "DS":
+		// CUDS: only IVA takes part, and the seller goes before the buyer
+		b.WriteString(in.Numero)
+		b.WriteString(in.FechaEmision)
+		b.WriteString(in.HoraEmision)
+		b.WriteString(in.ValorBruto)
+		b.WriteString(TaxIVA)
+		b.WriteString(in.ValorIVA)
+		b.WriteString(in.ValorTotal)
+		b.WriteString(in.NumeroAdquirente)
+		b.WriteString(in.NitEmisor)
+		b.WriteString(in.Clave)
+		b.WriteString(in.Ambiente)
+	default:
+		return "", fmt.Errorf("tipo de documento inválido: %s", documentType)
+	}
+
+	return b.String(), nil
+}
+
+// Compute returns the CUFE/CUDE/CUDS of the input for the given document type.
+func Compute(documentType string, in Input) (string, error) {
+	composed, err := Compose(documentType, in)
+	if err != nil {
+		return "", err
+	}
+	sum := sha512.Sum384([]byte(composed))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// FromDocument builds the composition input of an OpenETL document.
+// The technical key is used for FC and the software PIN for NC, ND and DS.
+func FromDocument(doc invoice.OpenETLDocument, documentType string, keys Keys) (Input, error) {
+	in := Input{
+		Numero:       doc.RfaPrefijo + doc.CdoConsecutivo,
+		FechaEmision: doc.CdoFecha,
+		HoraEmision:  IssueTime(doc.CdoHora),
+	}
+
+	if documentType == "FC" {
+		in.Clave = keys.ClaveTecnica
+	} else {
+		in.Clave = keys.SoftwarePIN
+	}
+	if doc.CdoAmbiente != nil {
+		in.Ambiente = *doc.CdoAmbiente
+	}
+
+	var err error
+	if in.ValorBruto, err = FormatAmount(doc.CdoValorSinImpuestos); err != nil {
+		return Input{}, fmt.Errorf("cdo_valor_sin_impuestos: %w", err)
+	}
+	payable, err := totals.Payable(doc)
+	if err != nil {
+		return Input{}, err
+	}
+	in.ValorTotal = payable.FloatString(2)
+
+	taxes, err := taxTotals(doc)
+	if err != nil {
+		return Input{}, err
+	}
+	in.ValorIVA = taxes[TaxIVA].FloatString(2)
+	in.ValorINC = taxes[TaxINC].FloatString(2)
+	in.ValorICA = taxes[TaxICA].FloatString(2)
+
+	in.NitEmisor = identification.Base(doc.OfeIdentificacion)
+	in.NumeroAdquirente = identification.Base(doc.AdqIdentificacion)
+	if documentType == "DS" {
+		// In DS requests ofe_identificacion carries the seller and adq_identificacion the buyer (OFE)
+		in.NitEmisor, in.NumeroAdquirente = in.NumeroAdquirente, in.NitEmisor
+	}
+
+	return in, nil
+}
+
+// ForDocument computes the CUFE/CUDE/CUDS of an OpenETL document.
+func ForDocument(doc invoice.OpenETLDocument, documentType string, keys Keys) (string, error) {
+	in, err := FromDocument(doc, documentType, keys)
+	if err != nil {
+		return "", err
+	}
+	return Compute(documentType, in)
+}
+
+// Verify reports whether code is the CUFE/CUDE/CUDS of the input.
+func Verify(code, documentType string, in Input) (bool, error) {
+	expected, err := Compute(documentType, in)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(strings.TrimSpace(code)))) == 1, nil
+}
+
+// ValidFormat reports whether code looks like a CUFE/CUDE/CUDS (96 hex characters).
+func ValidFormat(code string) bool {
+	if len(code) != Length {
+		return false
+	}
+	_, err := hex.DecodeString(code)
+	return err == nil
+}
+
+// IssueTime appends the Colombian UTC offset to times that do not carry one.
+func IssueTime(hora string) string {
+	if hora == "" || strings.ContainsAny(hora, "+-Z") {
+		return hora
+	}
+	return hora + colombiaOffset
+}
+
+// FormatAmount normalizes a decimal amount to two decimals with a dot separator.
+// Empty amounts are treated as zero.
+func FormatAmount(value string) (string, error) {
+	amount, err := parseAmount(value)
+	if err != nil {
+		return "", err
+	}
+	return amount.FloatString(2), nil
+}
+
+func parseAmount(value string) (*big.Rat, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return new(big.Rat), nil
+	}
+	amount, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("valor inválido: %q", value)
+	}
+	return amount, nil
+}
+
+// taxTotals sums the document taxes by code. Documents without tax detail report
+// cdo_impuestos as IVA, as the provider mapping does.
+func taxTotals(doc invoice.OpenETLDocument) (map[string]*big.Rat, error) {
+	totals := map[string]*big.Rat{
+		TaxIVA: new(big.Rat),
+		TaxINC: new(big.Rat),
+		TaxICA: new(big.Rat),
+	}
+
+	if len(doc.Tributos) == 0 {
+		amount, err := parseAmount(doc.CdoImpuestos)
+		if err != nil {
+			return nil, fmt.Errorf("cdo_impuestos: %w", err)
+		}
+		totals[TaxIVA].Add(totals[TaxIVA], amount)
+		return totals, nil
+	}
+
+	for _, tributo := range doc.Tributos {
+		code := tributo.TriCodigo
+		if code == "" {
+			code = TaxIVA
+		}
+		total, ok := totals[code]
+		if !ok {
+			// Other taxes (e.g. bolsas, combustibles) do not take part in the composition
+			continue
+		}
+		amount, err := parseAmount(tributo.IidValor)
+		if err != nil {
+			return nil, fmt.Errorf("tributos[%s].iid_valor: %w", tributo.DdoSecuencia, err)
+		}
+		total.Add(total, amount)
+	}
+
+	return totals, nil
+}
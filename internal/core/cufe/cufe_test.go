@@ -0,0 +1,290 @@
+package cufe
+
+import (
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// annexCUFEInput is the CUFE example of the Anexo Técnico de Factura Electrónica.
+var annexCUFEInput = Input{
+	Numero:           "323200000129",
+	FechaEmision:     "2019-01-16",
+	HoraEmision:      "10:53:10-05:00",
+	ValorBruto:       "1500000.00",
+	ValorIVA:         "285000.00",
+	ValorINC:         "0.00",
+	ValorICA:         "0.00",
+	ValorTotal:       "1785000.00",
+	NitEmisor:        "700085371",
+	NumeroAdquirente: "800199436",
+	Clave:            "693ff6f2a553c3646a063436fd4dd9ded0311471",
+	Ambiente:         "1",
+}
+
+const annexCUFE = "8bb918b19ba22a694f1da11c643b5e9de39adf60311cf179179e9b33381030bcd4c3c3f156c506ed5908f9276f5bd9b4"
+
+func TestCompute_AnnexCUFE(t *testing.T) {
+	composed, err := Compose("FC", annexCUFEInput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedComposition := "3232000001292019-01-1610:53:10-05:001500000.0001285000.00040.00030.001785000.00700085371800199436693ff6f2a553c3646a063436fd4dd9ded03114711"
+	if composed != expectedComposition {
+		t.Errorf("unexpected composition:\n got  %s\n want %s", composed, expectedComposition)
+	}
+
+	got, err := Compute("FC", annexCUFEInput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != annexCUFE {
+		t.Errorf("expected CUFE %s, got %s", annexCUFE, got)
+	}
+}
+
+func TestCompute_CUDE(t *testing.T) {
+	in := Input{
+		Numero:           "8110007871",
+		FechaEmision:     "2019-01-12",
+		HoraEmision:      "07:00:00-05:00",
+		ValorBruto:       "12600.06",
+		ValorIVA:         "2394.01",
+		ValorINC:         "0.00",
+		ValorICA:         "0.00",
+		ValorTotal:       "14994.07",
+		NitEmisor:        "900373076",
+		NumeroAdquirente: "8355990",
+		Clave:            "12301",
+		Ambiente:         "2",
+	}
+
+	for _, documentType := range []string{"NC", "ND"} {
+		got, err := Compute(documentType, in)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", documentType, err)
+		}
+		if want := "7060726cc19b685015b4357a52a63f8149127737702ce9c07f537c3f85c121e111857acca6789c2203d91e3ce248a95f"; got != want {
+			t.Errorf("%s: expected CUDE %s, got %s", documentType, want, got)
+		}
+	}
+}
+
+func TestCompute_CUDS(t *testing.T) {
+	in := Input{
+		Numero:           "DS987654321",
+		FechaEmision:     "2024-03-15",
+		HoraEmision:      "10:00:00-05:00",
+		ValorBruto:       "500000.00",
+		ValorIVA:         "0.00",
+		ValorTotal:       "500000.00",
+		NitEmisor:        "860011153",
+		NumeroAdquirente: "123456789",
+		Clave:            "75341",
+		Ambiente:         "2",
+	}
+
+	composed, err := Compose("DS", in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "DS9876543212024-03-1510:00:00-05:00500000.00010.00500000.00123456789860011153753412"; composed != want {
+		t.Errorf("unexpected composition:\n got  %s\n want %s", composed, want)
+	}
+
+	got, err := Compute("DS", in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "15c6697331e9d551c259a7c2aae4c47a1279dd09a8c8e6da98843179b0daac4e9a10faaaae8548296341122c4a727787"; got != want {
+		t.Errorf("expected CUDS %s, got %s", want, got)
+	}
+}
+
+func TestCompute_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name         string
+		documentType string
+		mutate       func(*Input)
+		wantErr      string
+	}{
+		{name: "unknown type", documentType: "XX", mutate: func(in *Input) {}, wantErr: "tipo de documento inválido"},
+		{name: "missing key", documentType: "FC", mutate: func(in *Input) { in.Clave = "" }, wantErr: "es requerido"},
+		{name: "invalid environment", documentType: "FC", mutate: func(in *Input) { in.Ambiente = "3" }, wantErr: "debe ser"},
+		{name: "missing number", documentType: "FC", mutate: func(in *Input) { in.Numero = "" }, wantErr: "es requerido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := annexCUFEInput
+			tt.mutate(&in)
+			_, err := Compute(tt.documentType, in)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestForDocument_MatchesAnnex(t *testing.T) {
+	ambiente := "1"
+	iva := invoice.OpenETLTributoPorcentaje{IidBase: "1500000", IidPorcentaje: "19.00"}
+	doc := invoice.OpenETLDocument{
+		OfeIdentificacion:    "700085371-5",
+		AdqIdentificacion:    "800199436",
+		RfaPrefijo:           "3232",
+		CdoConsecutivo:       "00000129",
+		CdoFecha:             "2019-01-16",
+		CdoHora:              "10:53:10",
+		CdoAmbiente:          &ambiente,
+		CdoValorSinImpuestos: "1500000",
+		CdoImpuestos:         "285000",
+		CdoTotal:             "1785000.000",
+		Tributos: []invoice.OpenETLTributo{
+			{DdoSecuencia: "1", TriCodigo: "01", IidValor: "190000.00", IidPorcentaje: &iva},
+			{DdoSecuencia: "2", TriCodigo: "01", IidValor: "95000", IidPorcentaje: &iva},
+		},
+	}
+
+	got, err := ForDocument(doc, "FC", Keys{ClaveTecnica: "693ff6f2a553c3646a063436fd4dd9ded0311471", SoftwarePIN: "12345"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != annexCUFE {
+		t.Errorf("expected CUFE %s, got %s", annexCUFE, got)
+	}
+}
+
+func TestFromDocument_WithoutTaxDetail(t *testing.T) {
+	ambiente := "2"
+	doc := invoice.OpenETLDocument{
+		OfeIdentificacion:    "860011153-6",
+		AdqIdentificacion:    "900123456-1",
+		RfaPrefijo:           "NC",
+		CdoConsecutivo:       "15",
+		CdoFecha:             "2024-03-15",
+		CdoHora:              "08:00:00-05:00",
+		CdoAmbiente:          &ambiente,
+		CdoValorSinImpuestos: "100000.005",
+		CdoImpuestos:         "19000",
+		CdoTotal:             "119000",
+	}
+
+	in, err := FromDocument(doc, "NC", Keys{ClaveTecnica: "clave", SoftwarePIN: "pin"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Input{
+		Numero:           "NC15",
+		FechaEmision:     "2024-03-15",
+		HoraEmision:      "08:00:00-05:00",
+		ValorBruto:       "100000.01",
+		ValorIVA:         "19000.00",
+		ValorINC:         "0.00",
+		ValorICA:         "0.00",
+		ValorTotal:       "119000.00",
+		NitEmisor:        "860011153",
+		NumeroAdquirente: "900123456",
+		Clave:            "pin",
+		Ambiente:         "2",
+	}
+	if in != want {
+		t.Errorf("unexpected input:\n got  %+v\n want %+v", in, want)
+	}
+}
+
+func TestFromDocument_InvalidAmount(t *testing.T) {
+	doc := invoice.OpenETLDocument{CdoValorSinImpuestos: "1.000,00"}
+	if _, err := FromDocument(doc, "FC", Keys{}); err == nil {
+		t.Error("expected error for invalid amount")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ok, err := Verify(strings.ToUpper(annexCUFE), "FC", annexCUFEInput)
+	if err != nil || !ok {
+		t.Errorf("expected annex CUFE to verify, ok=%v err=%v", ok, err)
+	}
+
+	tampered := annexCUFEInput
+	tampered.ValorTotal = "1785000.01"
+	ok, err = Verify(annexCUFE, "FC", tampered)
+	if err != nil || ok {
+		t.Errorf("expected tampered input not to verify, ok=%v err=%v", ok, err)
+	}
+}
+
+func TestValidFormat(t *testing.T) {
+	if !ValidFormat(annexCUFE) {
+		t.Error("expected annex CUFE to have a valid format")
+	}
+	for _, code := range []string{"", "abc", annexCUFE[:95] + "g", annexCUFE + "0"} {
+		if ValidFormat(code) {
+			t.Errorf("expected %q to be invalid", code)
+		}
+	}
+}
+
+func TestStaticKeys(t *testing.T) {
+	if _, ok := (StaticKeys{}).Resolve("860011153", "SETT"); ok {
+		t.Error("expected empty keys not to resolve")
+	}
+	keys, ok := StaticKeys{ClaveTecnica: "clave"}.Resolve("860011153", "SETT")
+	if !ok || keys.ClaveTecnica != "clave" {
+		t.Errorf("expected configured keys, got %+v ok=%v", keys, ok)
+	}
+}
+
+func TestForDocument_DSUsesSellerBeforeBuyer(t *testing.T) {
+	ambiente := "2"
+	doc := invoice.OpenETLDocument{
+		OfeIdentificacion:    "123456789",   // Seller (no obligado a facturar)
+		AdqIdentificacion:    "860011153-6", // Buyer (OFE)
+		RfaPrefijo:           "DS",
+		CdoConsecutivo:       "987654321",
+		CdoFecha:             "2024-03-15",
+		CdoHora:              "10:00:00",
+		CdoAmbiente:          &ambiente,
+		CdoValorSinImpuestos: "500000",
+		CdoTotal:             "500000",
+	}
+
+	got, err := ForDocument(doc, "DS", Keys{SoftwarePIN: "75341"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "15c6697331e9d551c259a7c2aae4c47a1279dd09a8c8e6da98843179b0daac4e9a10faaaae8548296341122c4a727787"; got != want {
+		t.Errorf("expected CUDS %s, got %s", want, got)
+	}
+}
+
+func TestFromDocument_DSWithIVAAndAnticipo(t *testing.T) {
+	ambiente := "2"
+	doc := invoice.OpenETLDocument{
+		OfeIdentificacion:    "123456789",
+		AdqIdentificacion:    "860011153-6",
+		RfaPrefijo:           "DS",
+		CdoConsecutivo:       "10",
+		CdoFecha:             "2024-03-15",
+		CdoHora:              "10:00:00",
+		CdoAmbiente:          &ambiente,
+		CdoValorSinImpuestos: "100000",
+		CdoImpuestos:         "19000",
+		CdoTotal:             "119000",
+		CdoAnticipo:          "20000",
+		Tributos: []invoice.OpenETLTributo{
+			{DdoSecuencia: "1", TriCodigo: TaxIVA, IidValor: "19000", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "100000", IidPorcentaje: "19"}},
+		},
+	}
+
+	in, err := FromDocument(doc, "DS", Keys{SoftwarePIN: "75341"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The IVA takes part in the CUDS and ValTot is the payable amount, net of the anticipo
+	if in.ValorIVA != "19000.00" || in.ValorTotal != "99000.00" {
+		t.Errorf("expected IVA 19000.00 and total 99000.00, got %s %s", in.ValorIVA, in.ValorTotal)
+	}
+}
@@ -0,0 +1,89 @@
+// Package currency validates ISO 4217 currency codes and converts document amounts
+// to Colombian pesos (COP) with the TRM (Tasa Representativa del Mercado).
+package currency
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/codelist"
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// COP is the national currency. Documents in any other currency must carry the TRM.
+const COP = "COP"
+
+// Valid reports whether code is an active ISO 4217 currency code.
+func Valid(code string) bool {
+	return codelist.Contains(codelist.Currencies, code)
+}
+
+// Normalize trims and upper-cases a currency code. An empty code is COP.
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return COP
+	}
+	return code
+}
+
+// IsForeign reports whether amounts in the currency must be converted to COP.
+func IsForeign(code string) bool {
+	return Normalize(code) != COP
+}
+
+// Rate is the value in COP of one unit of a currency on a date (the TRM for USD).
+type Rate struct {
+	Currency string    `json:"moneda"`
+	Date     time.Time `json:"fecha"`
+	Value    string    `json:"valor"`
+}
+
+// Source provides exchange rates. Rate returns the rate in force on the date
+// (the latest one published on or before it), or nil if there is none.
+type Source interface {
+	Rate(ctx context.Context, currency string, date time.Time) (*Rate, error)
+}
+
+// Repository stores exchange rates.
+type Repository interface {
+	Source
+
+	// Save creates or replaces the rate of a currency and date.
+	Save(ctx context.Context, rate Rate) error
+
+	// List retrieves the rates of a currency between two dates (inclusive), newest first.
+	List(ctx context.Context, currency string, from, to time.Time) ([]Rate, error)
+}
+
+// ParseRate parses an exchange rate, which must be a positive decimal.
+func ParseRate(value string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || r.Sign() <= 0 {
+		return nil, fmt.Errorf("tasa de cambio inválida [%s]: debe ser un número mayor que 0", value)
+	}
+	return r, nil
+}
+
+// DocumentRate returns the exchange rate of the document currency to COP: 1 for COP
+// documents, cdo_trm otherwise.
+func DocumentRate(doc invoice.OpenETLDocument) (*big.Rat, error) {
+	code := Normalize(doc.MonCodigo)
+	if code == COP {
+		return big.NewRat(1, 1), nil
+	}
+	if doc.CdoTrm == nil || strings.TrimSpace(*doc.CdoTrm) == "" {
+		return nil, fmt.Errorf("cdo_trm es requerido para documentos en moneda [%s]", code)
+	}
+	return ParseRate(*doc.CdoTrm)
+}
+
+// ToCOP converts an amount in the source currency to COP, rounded to two decimals.
+func ToCOP(amount, rate *big.Rat) *big.Rat {
+	converted := new(big.Rat).Mul(amount, rate)
+	rounded, _ := new(big.Rat).SetString(converted.FloatString(2))
+	return rounded
+}
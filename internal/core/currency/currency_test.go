@@ -0,0 +1,44 @@
+package currency
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for _, code := range []string{"COP", "USD", "EUR", "MXN"} {
+		if !Valid(code) {
+			t.Errorf("expected %s to be valid", code)
+		}
+	}
+	for _, code := range []string{"", "usd", "US", "XYZ", "PESOS"} {
+		if Valid(code) {
+			t.Errorf("expected %q to be invalid", code)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if Normalize(" usd ") != "USD" || Normalize("") != COP {
+		t.Errorf("unexpected normalization: %q %q", Normalize(" usd "), Normalize(""))
+	}
+	if IsForeign("cop") || !IsForeign("USD") {
+		t.Error("unexpected IsForeign result")
+	}
+}
+
+func TestParseRateAndConvert(t *testing.T) {
+	rate, err := ParseRate("4150.35")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	amount, _ := new(big.Rat).SetString("123.45")
+	if got := ToCOP(amount, rate).FloatString(2); got != "512360.71" {
+		t.Errorf("expected 512360.71, got %s", got)
+	}
+
+	if _, err := ParseRate("0"); err == nil || !strings.Contains(err.Error(), "tasa de cambio inválida") {
+		t.Errorf("expected invalid rate error, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package dane
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	depCodigoPattern = regexp.MustCompile(`^[0-9]{2}$`)
+	munCodigoPattern = regexp.MustCompile(`^[0-9]{5}$`)
+)
+
+// csvColumns are the columns of the DIVIPOLA export of datos.gov.co.
+var csvColumns = []string{"cod_dpto", "dpto", "cod_mpio", "nom_mpio"}
+
+// Validate checks that the municipality code is a 5 digit DIVIPOLA code that starts with
+// the department code and that both names are present.
+func (m Municipality) Validate() error {
+	if !munCodigoPattern.MatchString(m.Codigo) {
+		return fmt.Errorf("código DIVIPOLA [%s] inválido: debe tener 5 dígitos", m.Codigo)
+	}
+	if !depCodigoPattern.MatchString(m.DepCodigo) || !strings.HasPrefix(m.Codigo, m.DepCodigo) {
+		return fmt.Errorf("municipio [%s]: el código de departamento [%s] no corresponde", m.Codigo, m.DepCodigo)
+	}
+	if strings.TrimSpace(m.Nombre) == "" || strings.TrimSpace(m.DepNombre) == "" {
+		return fmt.Errorf("municipio [%s]: el nombre del municipio y del departamento son requeridos", m.Codigo)
+	}
+	return nil
+}
+
+// ParseCSV reads a DIVIPOLA catalog with the columns cod_dpto, dpto, cod_mpio and nom_mpio
+// (any order, comma or semicolon separated). Codes with fewer digits are zero-padded.
+func ParseCSV(r io.Reader) ([]Municipality, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read DIVIPOLA CSV: %w", err)
+	}
+	content := strings.TrimPrefix(string(data), "\ufeff")
+
+	reader := csv.NewReader(strings.NewReader(content))
+	if header, _, _ := strings.Cut(content, "\n"); strings.Count(header, ";") > strings.Count(header, ",") {
+		reader.Comma = ';'
+	}
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read DIVIPOLA CSV header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, column := range csvColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("DIVIPOLA CSV: falta la columna %s", column)
+		}
+	}
+
+	var municipalities []Municipality
+	seen := make(map[string]bool)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("DIVIPOLA CSV line %d: %w", line, err)
+		}
+
+		m := Municipality{
+			Codigo:    padCode(record[index["cod_mpio"]], 5),
+			Nombre:    strings.TrimSpace(record[index["nom_mpio"]]),
+			DepCodigo: padCode(record[index["cod_dpto"]], 2),
+			DepNombre: strings.TrimSpace(record[index["dpto"]]),
+		}
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("DIVIPOLA CSV line %d: %w", line, err)
+		}
+		if seen[m.Codigo] {
+			return nil, fmt.Errorf("DIVIPOLA CSV line %d: municipio [%s] duplicado", line, m.Codigo)
+		}
+		seen[m.Codigo] = true
+		municipalities = append(municipalities, m)
+	}
+
+	if len(municipalities) == 0 {
+		return nil, fmt.Errorf("DIVIPOLA CSV sin municipios")
+	}
+	return municipalities, nil
+}
+
+// WriteCSV writes the catalog in the comma separated format read by ParseCSV, sorted by
+// municipality code. It is the format of the catalog embedded in the binary.
+func WriteCSV(w io.Writer, municipalities []Municipality) error {
+	sorted := slices.Clone(municipalities)
+	slices.SortFunc(sorted, func(a, b Municipality) int { return strings.Compare(a.Codigo, b.Codigo) })
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, m := range sorted {
+		if err := writer.Write([]string{m.DepCodigo, m.DepNombre, m.Codigo, m.Nombre}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// padCode left-pads a numeric code with zeros ("5001" -> "05001").
+func padCode(code string, length int) string {
+	code = strings.TrimSpace(code)
+	if len(code) < length {
+		code = strings.Repeat("0", length-len(code)) + code
+	}
+	return code
+}
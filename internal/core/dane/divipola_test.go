@@ -0,0 +1,68 @@
+package dane
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := "\ufeffCOD_MPIO;NOM_MPIO;COD_DPTO;DPTO\n5001;MEDELLÍN;5;ANTIOQUIA\n11001;\"BOGOTÁ; D.C.\";11;\"BOGOTÁ, D.C.\"\n"
+
+	municipalities, err := ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(municipalities) != 2 {
+		t.Fatalf("expected 2 municipalities, got %d", len(municipalities))
+	}
+	want := Municipality{Codigo: "05001", Nombre: "MEDELLÍN", DepCodigo: "05", DepNombre: "ANTIOQUIA"}
+	if municipalities[0] != want {
+		t.Errorf("expected %+v, got %+v", want, municipalities[0])
+	}
+}
+
+func TestParseCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"missing column", "cod_dpto,dpto,cod_mpio\n05,ANTIOQUIA,05001\n", "falta la columna nom_mpio"},
+		{"department mismatch", "cod_dpto,dpto,cod_mpio,nom_mpio\n08,ATLÁNTICO,05001,MEDELLÍN\n", "no corresponde"},
+		{"duplicate", "cod_dpto,dpto,cod_mpio,nom_mpio\n05,ANTIOQUIA,05001,MEDELLÍN\n05,ANTIOQUIA,05001,MEDELLÍN\n", "duplicado"},
+		{"empty", "cod_dpto,dpto,cod_mpio,nom_mpio\n", "sin municipios"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWriteCSV_RoundTrip(t *testing.T) {
+	municipalities := []Municipality{
+		{Codigo: "11001", Nombre: "BOGOTÁ, D.C.", DepCodigo: "11", DepNombre: "BOGOTÁ, D.C."},
+		{Codigo: "05001", Nombre: "MEDELLÍN", DepCodigo: "05", DepNombre: "ANTIOQUIA"},
+	}
+
+	var out strings.Builder
+	if err := WriteCSV(&out, municipalities); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "cod_dpto,dpto,cod_mpio,nom_mpio\n05,ANTIOQUIA,05001,MEDELLÍN\n11,\"BOGOTÁ, D.C.\",11001,\"BOGOTÁ, D.C.\"\n"
+	if out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+
+	parsed, err := ParseCSV(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed) != 2 || parsed[0] != municipalities[1] || parsed[1] != municipalities[0] {
+		t.Errorf("expected the catalog to round-trip, got %+v", parsed)
+	}
+}
@@ -14,8 +14,43 @@ type Service interface {
 
 // Municipality represents municipality information from DANE.
 type Municipality struct {
-	Codigo    string // Código DIVIPOLA (5 dígitos, e.g., "05001")
-	Nombre    string // Nombre del municipio (e.g., "MEDELLÍN")
-	DepCodigo string // Código del departamento (2 dígitos, e.g., "05")
-	DepNombre string // Nombre del departamento (e.g., "ANTIOQUIA")
+	Codigo    string `json:"mun_codigo"` // Código DIVIPOLA (5 dígitos, e.g., "05001")
+	Nombre    string `json:"mun_nombre"` // Nombre del municipio (e.g., "MEDELLÍN")
+	DepCodigo string `json:"dep_codigo"` // Código del departamento (2 dígitos, e.g., "05")
+	DepNombre string `json:"dep_nombre"` // Nombre del departamento (e.g., "ANTIOQUIA")
+}
+
+// Department represents a department of the DIVIPOLA catalog.
+type Department struct {
+	Codigo string `json:"dep_codigo"`
+	Nombre string `json:"dep_nombre"`
+}
+
+// Source provides the whole DIVIPOLA municipality catalog (a CSV file, the DANE open-data API
+// or the divipola_municipios table).
+type Source interface {
+	ListMunicipalities(ctx context.Context) ([]Municipality, error)
+}
+
+// Repository stores the DIVIPOLA catalog loaded by the refresh command.
+type Repository interface {
+	Source
+
+	// ReplaceMunicipalities replaces the stored catalog in a single transaction.
+	ReplaceMunicipalities(ctx context.Context, municipalities []Municipality) error
+}
+
+// Catalog is an in-memory DIVIPOLA catalog with lookups by code and name search.
+type Catalog interface {
+	Service
+
+	// Search returns the municipalities whose name contains query (ignoring case and
+	// accents), optionally restricted to a department, up to limit results (0 = all).
+	Search(query, depCodigo string, limit int) []Municipality
+
+	// Departments returns the departments sorted by code.
+	Departments() []Department
+
+	// Replace swaps the catalog contents.
+	Replace(municipalities []Municipality) error
 }
@@ -0,0 +1,134 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Status represents the lifecycle state of a registered document.
+type Status string
+
+const (
+	// StatusReceived indicates the document was received from the client (OpenETL payload).
+	StatusReceived Status = "received"
+	// StatusValidated indicates the document passed local validation and enrichment.
+	StatusValidated Status = "validated"
+	// StatusSent indicates the document was sent to the invoicing provider.
+	StatusSent Status = "sent"
+	// StatusAccepted indicates DIAN accepted the document.
+	StatusAccepted Status = "accepted"
+	// StatusRejected indicates DIAN (through the provider) rejected the document.
+	StatusRejected Status = "rejected"
+	// StatusFailed indicates the document failed local validation and never reached DIAN.
+	StatusFailed Status = "failed"
+	// StatusContingency indicates the document was stored in contingency mode, pending transmission.
+	StatusContingency Status = "contingency"
+)
+
+// IsValid reports whether the status is one of the known lifecycle states.
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusReceived, StatusValidated, StatusSent, StatusAccepted, StatusRejected, StatusFailed, StatusContingency:
+		return true
+	}
+	return false
+}
+
+// Document is a ledger entry for an electronic document registered through the service.
+// It is keyed by OFE, type, prefijo and consecutivo.
+type Document struct {
+	ID                int64           `json:"id"`
+	OfeIdentificacion string          `json:"ofe_identificacion"`
+	Tipo              string          `json:"tipo"` // FC, NC, ND, DS
+	Prefijo           string          `json:"prefijo"`
+	Consecutivo       string          `json:"consecutivo"`
+	Estado            Status          `json:"estado"`
+	CUFE              string          `json:"cufe,omitempty"`           // CUFE (FC) or CUDE (NC/ND/DS)
+	CUFECalculado     string          `json:"cufe_calculado,omitempty"` // Locally computed CUFE/CUDE when it differs from the provider one
+	Referencia        string          `json:"referencia,omitempty"`     // Prefijo and number of the invoice corrected by NC/ND
+	CdoID             *int            `json:"cdo_id,omitempty"`
+	OriginalPayload   json.RawMessage `json:"payload_original,omitempty"`
+	PayloadHash       string          `json:"payload_hash,omitempty"` // SHA-256 of the original payload, used to detect retries
+	EnrichedPayload   json.RawMessage `json:"payload_enriquecido,omitempty"`
+	XmlBase64         string          `json:"xml_base64,omitempty"`
+	PdfBase64         string          `json:"pdf_base64,omitempty"`
+	XmlRef            string          `json:"xml_ref,omitempty"` // Storage reference when the XML is kept outside the ledger
+	PdfRef            string          `json:"pdf_ref,omitempty"` // Storage reference when the PDF is kept outside the ledger
+	Errors            []string        `json:"errors,omitempty"`
+	CreatedAt         time.Time       `json:"fecha_creacion"`
+	UpdatedAt         time.Time       `json:"fecha_modificacion"`
+}
+
+// Key identifies a document in the ledger.
+type Key struct {
+	OfeIdentificacion string
+	Tipo              string
+	Prefijo           string
+	Consecutivo       string
+}
+
+// Key returns the ledger key of the document.
+func (d *Document) Key() Key {
+	return Key{
+		OfeIdentificacion: d.OfeIdentificacion,
+		Tipo:              d.Tipo,
+		Prefijo:           d.Prefijo,
+		Consecutivo:       d.Consecutivo,
+	}
+}
+
+// Filter holds the optional criteria for listing ledger entries.
+// Empty fields are ignored.
+type Filter struct {
+	OfeIdentificacion string
+	Tipo              string
+	Prefijo           string
+	Consecutivo       string
+	CUFE              string
+	Referencia        string
+	CdoID             *int
+	Estado            Status
+	Start             int // starting index (0-based)
+	Length            int // number of records to return (-1 for all)
+}
+
+// StatusUpdate carries the data recorded when a document changes state.
+// Empty fields leave the stored values untouched.
+type StatusUpdate struct {
+	Estado          Status
+	EnrichedPayload json.RawMessage
+	CUFE            string
+	CUFECalculado   string
+	CdoID           *int
+	XmlBase64       string
+	PdfBase64       string
+	XmlRef          string
+	PdfRef          string
+	Errors          []string
+}
+
+// Repository defines the persistence operations for the documents ledger.
+type Repository interface {
+	// Save creates the ledger entry for the document or, if one already exists for the
+	// same key, resets it with the new payload and status. Returns the entry ID.
+	Save(ctx context.Context, doc Document) (int64, error)
+
+	// Claim creates the ledger entry for the document or, if one already exists for the
+	// same key in one of the takeover statuses, resets it with the new payload and status.
+	// Entries left in received or validated status for longer than staleAfter, by a
+	// registration interrupted before reaching the provider, are taken over as well.
+	// Otherwise it returns false and leaves the entry untouched. The check and the write
+	// are atomic, so concurrent registrations of the same document claim it at most once.
+	Claim(ctx context.Context, doc Document, staleAfter time.Duration, takeover ...Status) (bool, error)
+
+	// UpdateStatus records a state transition for the document identified by key.
+	UpdateStatus(ctx context.Context, key Key, update StatusUpdate) error
+
+	// FindByKey retrieves a ledger entry by its key.
+	// Returns nil if not found.
+	FindByKey(ctx context.Context, key Key) (*Document, error)
+
+	// List retrieves ledger entries matching the filter and the total count.
+	List(ctx context.Context, filter Filter) ([]Document, int, error)
+}
@@ -0,0 +1,46 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Status represents the state of an idempotent request.
+type Status string
+
+const (
+	// StatusInProgress indicates the first request with the key is still being processed.
+	StatusInProgress Status = "in_progress"
+	// StatusCompleted indicates the request finished and its response is stored.
+	StatusCompleted Status = "completed"
+)
+
+// Record stores the outcome of a request identified by an Idempotency-Key.
+type Record struct {
+	Scope       string // Operation the key belongs to (e.g. "registrar-documentos")
+	Key         string
+	RequestHash string // SHA-256 of the request payload
+	Status      Status
+	Response    json.RawMessage
+	CreatedAt   time.Time
+	LeaseUntil  time.Time // End of the lease of an in-progress record; after it the record is abandoned
+}
+
+// Repository defines the persistence operations for idempotency records.
+type Repository interface {
+	// Reserve creates an in-progress record for the key, leased for the given duration, if
+	// none exists or the existing one is in progress with an expired lease (its request was
+	// abandoned, e.g. by a crash) and the same request hash. Returns the existing record (and false) when the key is
+	// in use, or nil (and true) when the reservation succeeded.
+	Reserve(ctx context.Context, scope, key, requestHash string, lease time.Duration) (*Record, bool, error)
+
+	// Extend renews the lease of an in-progress record while its request is still running.
+	Extend(ctx context.Context, scope, key string, lease time.Duration) error
+
+	// Complete stores the response of the request and marks the record as completed.
+	Complete(ctx context.Context, scope, key string, response json.RawMessage) error
+
+	// Release removes an in-progress reservation so the request can be retried.
+	Release(ctx context.Context, scope, key string) error
+}
@@ -0,0 +1,153 @@
+// Package identification normalizes and validates Colombian identification numbers and
+// computes the NIT verification digit (DV) with the DIAN module 11 algorithm.
+package identification
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Identification document types (tdo_codigo) of the DIAN code list.
+const (
+	TypeRegistroCivil       = "11"
+	TypeTarjetaIdentidad    = "12"
+	TypeCedulaCiudadania    = "13"
+	TypeTarjetaExtranjeria  = "21"
+	TypeCedulaExtranjeria   = "22"
+	TypeNIT                 = "31"
+	TypePasaporte           = "41"
+	TypeDocumentoExtranjero = "42"
+	TypePEP                 = "47"
+	TypePPT                 = "48"
+	TypeNITOtroPais         = "50"
+	TypeNUIP                = "91"
+)
+
+// weights are the DIAN module 11 factors, applied from the rightmost digit.
+var weights = []int{3, 7, 13, 17, 19, 23, 29, 37, 41, 43, 47, 53, 59, 67, 71}
+
+// rule is the format accepted for the number of an identification type.
+type rule struct {
+	pattern *regexp.Regexp
+	format  string
+}
+
+var (
+	digits      = rule{regexp.MustCompile(`^[0-9]{3,15}$`), "entre 3 y 15 dígitos"}
+	cedula      = rule{regexp.MustCompile(`^[0-9]{3,10}$`), "entre 3 y 10 dígitos"}
+	nit         = rule{regexp.MustCompile(`^[0-9]{5,15}$`), "entre 5 y 15 dígitos"}
+	alphanum    = rule{regexp.MustCompile(`^[A-Z0-9]{3,20}$`), "entre 3 y 20 letras o dígitos"}
+	foreignNIT  = rule{regexp.MustCompile(`^[A-Z0-9-]{1,20}$`), "hasta 20 letras, dígitos o guiones"}
+	typeFormats = map[string]rule{
+		TypeRegistroCivil:       digits,
+		TypeTarjetaIdentidad:    digits,
+		TypeCedulaCiudadania:    cedula,
+		TypeTarjetaExtranjeria:  alphanum,
+		TypeCedulaExtranjeria:   alphanum,
+		TypeNIT:                 nit,
+		TypePasaporte:           alphanum,
+		TypeDocumentoExtranjero: alphanum,
+		TypePEP:                 alphanum,
+		TypePPT:                 alphanum,
+		TypeNITOtroPais:         foreignNIT,
+		TypeNUIP:                digits,
+	}
+)
+
+// ValidType reports whether code is a supported tdo_codigo.
+func ValidType(code string) bool {
+	_, ok := typeFormats[code]
+	return ok
+}
+
+// Normalize removes spaces and thousands separators ("860.011.153 - 6" -> "860011153-6")
+// and upper-cases letters.
+func Normalize(number string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", ".", "", ",", "").Replace(number))
+}
+
+// Split normalizes a NIT and separates the base number from the verification digit.
+// The DV is empty when the NIT has none:
+//   - "860011153-6" -> "860011153", "6"
+//   - "860.011.153" -> "860011153", ""
+func Split(number string) (base, dv string) {
+	base, dv, _ = strings.Cut(Normalize(number), "-")
+	return base, dv
+}
+
+// Base returns the NIT without verification digit ("860011153-6" -> "860011153").
+func Base(number string) string {
+	base, _ := Split(number)
+	return base
+}
+
+// CheckDigit computes the DV of a NIT with the DIAN module 11 algorithm.
+func CheckDigit(number string) (string, error) {
+	base := Base(number)
+	if !nit.pattern.MatchString(base) {
+		return "", fmt.Errorf("NIT [%s] inválido: debe tener %s", number, nit.format)
+	}
+
+	sum := 0
+	for i := 0; i < len(base); i++ {
+		sum += int(base[len(base)-1-i]-'0') * weights[i]
+	}
+	r := sum % 11
+	if r > 1 {
+		r = 11 - r
+	}
+	return strconv.Itoa(r), nil
+}
+
+// ValidateNIT checks the format of a NIT and, when it carries one, its verification digit.
+func ValidateNIT(number string) error {
+	_, dv := Split(number)
+	expected, err := CheckDigit(number)
+	if err != nil {
+		return err
+	}
+	if dv != "" && dv != expected {
+		return fmt.Errorf("NIT [%s] inválido: el dígito de verificación debe ser %s", number, expected)
+	}
+	return nil
+}
+
+// Validate checks an identification number against the rules of its type (tdo_codigo).
+// NITs are checked with ValidateNIT; the other types only by format.
+func Validate(tdoCodigo, number string) error {
+	format, ok := typeFormats[tdoCodigo]
+	if !ok {
+		return fmt.Errorf("tdo_codigo [%s] inválido", tdoCodigo)
+	}
+	if tdoCodigo == TypeNIT {
+		return ValidateNIT(number)
+	}
+	if normalized := Normalize(number); !format.pattern.MatchString(normalized) {
+		return fmt.Errorf("número de identificación [%s] inválido para tdo_codigo [%s]: debe tener %s", number, tdoCodigo, format.format)
+	}
+	return nil
+}
+
+// Canonical returns the number as it is stored: NITs without DV and the other types
+// normalized.
+func Canonical(tdoCodigo, number string) string {
+	if tdoCodigo == TypeNIT {
+		return Base(number)
+	}
+	return Normalize(number)
+}
+
+// DV returns the verification digit of a NIT, or an empty string for other types or
+// invalid numbers.
+func DV(tdoCodigo, number string) string {
+	if tdoCodigo != TypeNIT {
+		return ""
+	}
+	dv, err := CheckDigit(number)
+	if err != nil {
+		return ""
+	}
+	return dv
+}
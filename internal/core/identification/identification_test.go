@@ -0,0 +1,106 @@
+package identification
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		nit  string
+		want string
+	}{
+		{"860011153", "6"},
+		{"900373115", "3"},
+		{"800197268", "4"},
+		{"900000001", "2"},
+		{"900111222", "1"},
+		{"890.903.938", "8"},
+		{"860011153-1", "6"},
+	}
+
+	for _, tt := range tests {
+		got, err := CheckDigit(tt.nit)
+		if err != nil {
+			t.Fatalf("CheckDigit(%q): unexpected error: %v", tt.nit, err)
+		}
+		if got != tt.want {
+			t.Errorf("CheckDigit(%q) = %s, want %s", tt.nit, got, tt.want)
+		}
+	}
+
+	if _, err := CheckDigit("86001A153"); err == nil {
+		t.Error("expected error for a NIT with letters")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		in       string
+		base, dv string
+	}{
+		{"860011153-6", "860011153", "6"},
+		{" 860.011.153 - 6 ", "860011153", "6"},
+		{"860011153", "860011153", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		base, dv := Split(tt.in)
+		if base != tt.base || dv != tt.dv {
+			t.Errorf("Split(%q) = %q, %q, want %q, %q", tt.in, base, dv, tt.base, tt.dv)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tdo     string
+		number  string
+		wantErr string
+	}{
+		{name: "NIT with correct DV", tdo: TypeNIT, number: "860011153-6"},
+		{name: "NIT without DV", tdo: TypeNIT, number: "860.011.153"},
+		{name: "NIT with wrong DV", tdo: TypeNIT, number: "860011153-3", wantErr: "debe ser 6"},
+		{name: "NIT too short", tdo: TypeNIT, number: "123", wantErr: "entre 5 y 15"},
+		{name: "cedula", tdo: TypeCedulaCiudadania, number: "1.020.304.050"},
+		{name: "cedula with letters", tdo: TypeCedulaCiudadania, number: "10203A", wantErr: "dígitos"},
+		{name: "cedula too long", tdo: TypeCedulaCiudadania, number: "12345678901", wantErr: "entre 3 y 10"},
+		{name: "cedula de extranjeria", tdo: TypeCedulaExtranjeria, number: "E123456"},
+		{name: "pasaporte", tdo: TypePasaporte, number: "ab123456"},
+		{name: "pasaporte with symbols", tdo: TypePasaporte, number: "AB-123", wantErr: "letras o dígitos"},
+		{name: "NIT de otro pais", tdo: TypeNITOtroPais, number: "98-7654321"},
+		{name: "unknown type", tdo: "99", number: "123456", wantErr: "tdo_codigo [99] inválido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.tdo, tt.number)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCanonicalAndDV(t *testing.T) {
+	if got := Canonical(TypeNIT, "860.011.153-6"); got != "860011153" {
+		t.Errorf("Canonical NIT = %s", got)
+	}
+	if got := Canonical(TypePasaporte, " ab 123456 "); got != "AB123456" {
+		t.Errorf("Canonical pasaporte = %s", got)
+	}
+	if got := DV(TypeNIT, "860011153"); got != "6" {
+		t.Errorf("DV NIT = %s", got)
+	}
+	if got := DV(TypeCedulaCiudadania, "1020304050"); got != "" {
+		t.Errorf("expected no DV for a cédula, got %s", got)
+	}
+}
@@ -4,18 +4,21 @@ import "time"
 
 // Document represents an invoice/document in the domain.
 type Document struct {
-	OFE         string    `json:"ofe"`
-	Proveedor   string    `json:"proveedor"`
-	Tipo        string    `json:"tipo"`
-	Prefijo     string    `json:"prefijo"`
-	Consecutivo string    `json:"consecutivo"`
-	CUFE        string    `json:"cufe"`
-	Fecha       time.Time `json:"fecha"`
-	Hora        string    `json:"hora"`
-	Valor       float64   `json:"valor"`
-	Marca       bool      `json:"marca"`
-	UrlPDF      string    `json:"urlPDF,omitempty"`
-	UrlXML      string    `json:"urlXML,omitempty"`
+	OFE         string `json:"ofe"`
+	Proveedor   string `json:"proveedor"`
+	Tipo        string `json:"tipo"`
+	Prefijo     string `json:"prefijo"`
+	Consecutivo string `json:"consecutivo"`
+	CUFE        string `json:"cufe"`
+	// CUFECalculado is the CUFE/CUDE recomputed from the ledger entry of the document,
+	// only set when it differs from CUFE
+	CUFECalculado string    `json:"cufe_calculado,omitempty"`
+	Fecha         time.Time `json:"fecha"`
+	Hora          string    `json:"hora"`
+	Valor         float64   `json:"valor"`
+	Marca         bool      `json:"marca"`
+	UrlPDF        string    `json:"urlPDF,omitempty"`
+	UrlXML        string    `json:"urlXML,omitempty"`
 }
 
 // DocumentQuery represents the query parameters for retrieving documents.
@@ -68,6 +71,8 @@ type OpenETLDocument struct {
 	CdoMediosPago                     []OpenETLMedioPago     `json:"cdo_medios_pago"`
 	CdoInformacionAdicional           map[string]interface{} `json:"cdo_informacion_adicional"`
 	MonCodigo                         string                 `json:"mon_codigo"`
+	CdoTrm                            *string                `json:"cdo_trm,omitempty"`       // COP per unit of mon_codigo, required when it is not COP
+	CdoTrmFecha                       *string                `json:"cdo_trm_fecha,omitempty"` // Date of the TRM (defaults to cdo_fecha)
 	CdoValorSinImpuestos              string                 `json:"cdo_valor_sin_impuestos"`
 	CdoImpuestos                      string                 `json:"cdo_impuestos"`
 	CdoTotal                          string                 `json:"cdo_total"`
@@ -77,8 +82,10 @@ type OpenETLDocument struct {
 	CdoDescuentos                     string                 `json:"cdo_descuentos"`
 	CdoAnticipo                       string                 `json:"cdo_anticipo"`
 	CdoRedondeo                       string                 `json:"cdo_redondeo"`
+	CdoValorAPagar                    string                 `json:"cdo_valor_a_pagar,omitempty"` // PayableAmount; defaults to cdo_total + cdo_cargos - cdo_descuentos - cdo_anticipo + cdo_redondeo
 	CdoDetalleAnticipos               []interface{}          `json:"cdo_detalle_anticipos"`
 	CdoDetalleRetencionesSugeridas    []OpenETLRetencion     `json:"cdo_detalle_retenciones_sugeridas"`
+	CdoConceptoRetencion              *string                `json:"cdo_concepto_retencion,omitempty"` // Withholding concept (e.g. SERVICIOS, COMPRAS)
 	Items                             []OpenETLItem          `json:"items"`
 	Tributos                          []OpenETLTributo       `json:"tributos"`
 
@@ -92,6 +99,10 @@ type OpenETLDocument struct {
 	AdqPaisCodigo         *string `json:"adq_pais_codigo"`
 	AdqPaisNombre         *string `json:"adq_pais_nombre"`
 	AdqCpoCodigo          *string `json:"adq_cpo_codigo"`
+	// AdqRefCodigo and AdqResponsableTributos hold the tax responsibilities of the acquirer
+	// (or of the provider in DS documents), taken from the acquirer/provider tables
+	AdqRefCodigo           []string `json:"adq_ref_codigo,omitempty"`
+	AdqResponsableTributos []string `json:"adq_responsable_tributos,omitempty"`
 
 	// Supplier (Oferente) location and identification fields
 	OfeRazonSocial        *string `json:"ofe_razon_social"`
@@ -100,6 +111,10 @@ type OpenETLDocument struct {
 	OfeMunicipioNombre    *string `json:"ofe_municipio_nombre"`
 	OfeDepartamentoCodigo *string `json:"ofe_departamento_codigo"`
 	OfeDepartamentoNombre *string `json:"ofe_departamento_nombre"`
+	OfeCpoCodigo          *string `json:"ofe_cpo_codigo,omitempty"`
+	OfeCorreo             *string `json:"ofe_correo,omitempty"`
+	// OfeRefCodigo holds the tax responsibilities of the OFE (taken from the OFE registry)
+	OfeRefCodigo []string `json:"ofe_ref_codigo,omitempty"`
 
 	// Notes and Order Reference
 	Note           []string               `json:"note"`
@@ -108,6 +123,10 @@ type OpenETLDocument struct {
 	// Invoice reference and correction concepts for NC/ND
 	FacturaReferencia      *OpenETLFacturaReferencia  `json:"factura_referencia,omitempty"`
 	CdoConceptosCorreccion *OpenETLConceptoCorreccion `json:"cdo_conceptos_correccion,omitempty"`
+	// CdoPeriodoFacturacion is the period corrected by NC/ND without invoice reference
+	CdoPeriodoFacturacion *OpenETLPeriodoFacturacion `json:"cdo_periodo_facturacion,omitempty"`
+	// CdoDocumentoContingencia is the contingency document referenced by contingency invoices (03/04)
+	CdoDocumentoContingencia *OpenETLDocumentoContingencia `json:"cdo_documento_contingencia,omitempty"`
 }
 
 // OpenETLOrderReference represents an order reference in OpenETL format.
@@ -122,9 +141,28 @@ type OpenETLConceptoCorreccion struct {
 }
 
 // OpenETLFacturaReferencia represents a reference to the original invoice in OpenETL format.
+// CufeFC and FechaEmisionFC are filled from the referenced invoice when not provided.
 type OpenETLFacturaReferencia struct {
 	PrefijoFC       string `json:"prefijo_fc"`
 	NumeroFacturaFC string `json:"numero_factura_fc"`
+	CufeFC          string `json:"cufe_fc,omitempty"`
+	FechaEmisionFC  string `json:"fecha_emision_fc,omitempty"`
+}
+
+// OpenETLPeriodoFacturacion represents the invoicing period of a note without invoice reference.
+type OpenETLPeriodoFacturacion struct {
+	FechaInicio string `json:"fecha_inicio"`
+	HoraInicio  string `json:"hora_inicio,omitempty"`
+	FechaFin    string `json:"fecha_fin"`
+	HoraFin     string `json:"hora_fin,omitempty"`
+}
+
+// OpenETLDocumentoContingencia represents the contingency document of an invoice issued
+// in contingency mode. Tipo is the DocumentTypeCode of the reference (FTC).
+type OpenETLDocumentoContingencia struct {
+	Numero string `json:"numero"`
+	Fecha  string `json:"fecha"`
+	Tipo   string `json:"tipo"`
 }
 
 // OpenETLMedioPago represents a payment means in OpenETL format.
@@ -190,6 +228,8 @@ type DocumentRegistrationResponse struct {
 	Lote                 string              `json:"lote"`
 	DocumentosProcesados []ProcessedDocument `json:"documentos_procesados"`
 	DocumentosFallidos   []FailedDocument    `json:"documentos_fallidos"`
+	// DocumentosContingencia are the documents stored for later transmission in contingency mode
+	DocumentosContingencia []ContingencyDocument `json:"documentos_contingencia,omitempty"`
 }
 
 // ProcessedDocument represents a successfully processed document.
@@ -199,10 +239,20 @@ type ProcessedDocument struct {
 	CdoConsecutivo     string `json:"cdo_consecutivo"`
 	FechaProcesamiento string `json:"fecha_procesamiento"`
 	HoraProcesamiento  string `json:"hora_procesamiento"`
+	CUFE               string `json:"cufe,omitempty"`           // CUFE/CUDE returned by DIAN, when available
+	CUFECalculado      string `json:"cufe_calculado,omitempty"` // Locally computed CUFE/CUDE, only when it differs from CUFE
 	XmlBase64          string `json:"xml_base64,omitempty"`
 	PdfBase64          string `json:"pdf_base64,omitempty"`
 }
 
+// ContingencyDocument represents a document stored in contingency mode, pending transmission.
+type ContingencyDocument struct {
+	RfaPrefijo         string `json:"rfa_prefijo"`
+	CdoConsecutivo     string `json:"cdo_consecutivo"`
+	NumeroContingencia string `json:"numero_contingencia"`
+	TipoContingencia   string `json:"tipo_contingencia"`
+}
+
 // FailedDocument represents a document that failed to process.
 type FailedDocument struct {
 	Documento          string   `json:"documento"`
@@ -3,8 +3,8 @@ package invoice
 import (
 	"context"
 
-	"3tcapital/ms_facturacion_core/internal/core/event"
-	"3tcapital/ms_facturacion_core/internal/core/resolution"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/resolution"
 )
 
 // EventRegistrationResult represents the result of registering an event.
@@ -46,3 +46,10 @@ type Provider interface {
 	// Returns the registration response with processed and failed documents or an error if the registration fails.
 	RegisterDocument(ctx context.Context, req DocumentRegistrationRequest) (*DocumentRegistrationResponse, error)
 }
+
+// CircuitReporter is implemented by providers protected by a circuit breaker, so
+// callers can avoid them while their circuit is open.
+type CircuitReporter interface {
+	// CircuitOpen reports whether requests to the provider are currently failing fast.
+	CircuitOpen() bool
+}
@@ -0,0 +1,23 @@
+package invoice
+
+// DocumentRenderer renders OpenETL documents into their UBL 2.1 XML representation.
+type DocumentRenderer interface {
+	// Render returns the UBL XML of the document and its CUFE/CUDE/CUDS.
+	// The code is empty when the technical key or software PIN is not known.
+	Render(doc OpenETLDocument, documentType string) ([]byte, string, error)
+}
+
+// DocumentPreviewResponse represents the XML rendered for a set of documents without sending them.
+type DocumentPreviewResponse struct {
+	Documentos         []PreviewDocument `json:"documentos"`
+	DocumentosFallidos []FailedDocument  `json:"documentos_fallidos"`
+}
+
+// PreviewDocument represents the UBL XML rendered for a single document.
+type PreviewDocument struct {
+	Documento      string `json:"documento"`
+	RfaPrefijo     string `json:"rfa_prefijo"`
+	CdoConsecutivo string `json:"cdo_consecutivo"`
+	CUFE           string `json:"cufe,omitempty"`
+	XML            string `json:"xml"`
+}
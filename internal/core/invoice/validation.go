@@ -0,0 +1,57 @@
+package invoice
+
+import "fmt"
+
+// Severity classifies a validation finding as the DIAN does.
+type Severity string
+
+const (
+	// SeverityRejection prevents the document from being sent.
+	SeverityRejection Severity = "RECHAZO"
+	// SeverityNotification is reported but does not prevent the document from being sent.
+	SeverityNotification Severity = "NOTIFICACION"
+)
+
+// Violation is a validation rule not met by a document.
+type Violation struct {
+	Campo     string   `json:"campo"`           // Field path, e.g. items[0].und_codigo
+	Regla     string   `json:"regla,omitempty"` // DIAN rule code (FAD09e), empty for rules of this service
+	Severidad Severity `json:"severidad"`
+	Mensaje   string   `json:"mensaje"`
+}
+
+// String formats the violation as "[FAD09e] cdo_fecha: mensaje".
+func (v Violation) String() string {
+	if v.Regla == "" {
+		return fmt.Sprintf("%s: %s", v.Campo, v.Mensaje)
+	}
+	return fmt.Sprintf("[%s] %s: %s", v.Regla, v.Campo, v.Mensaje)
+}
+
+// Rejections returns the violations that prevent the document from being sent.
+func Rejections(violations []Violation) []Violation {
+	var rejections []Violation
+	for _, v := range violations {
+		if v.Severidad == SeverityRejection {
+			rejections = append(rejections, v)
+		}
+	}
+	return rejections
+}
+
+// DocumentValidation is the validation report of a document.
+type DocumentValidation struct {
+	Documento   string           `json:"documento"`
+	RfaPrefijo  string           `json:"rfa_prefijo"`
+	Consecutivo string           `json:"cdo_consecutivo"`
+	Valido      bool             `json:"valido"`
+	Violaciones []Violation      `json:"violaciones"`
+	Enriquecido *OpenETLDocument `json:"documento_enriquecido,omitempty"` // Document as it would be sent, when valid
+}
+
+// DocumentValidationResponse is the result of validating documents without sending them.
+type DocumentValidationResponse struct {
+	Validos    int                  `json:"validos"`
+	Invalidos  int                  `json:"invalidos"`
+	Documentos []DocumentValidation `json:"documentos"`
+}
@@ -0,0 +1,54 @@
+package ofe
+
+import "time"
+
+// Estados of an OFE.
+const (
+	EstadoActivo   = "ACTIVO"
+	EstadoInactivo = "INACTIVO"
+)
+
+// OFE (Oferente Facturador Electrónico) represents a company that issues
+// electronic documents through the service.
+type OFE struct {
+	ID                 int64   `json:"ofe_id"`
+	OfeIdentificacion  string  `json:"ofe_identificacion"` // NIT without verification digit
+	OfeRazonSocial     string  `json:"ofe_razon_social"`
+	OfeNombreComercial *string `json:"ofe_nombre_comercial"`
+	TdoCodigo          string  `json:"tdo_codigo"` // Document type (31 = NIT)
+	TojCodigo          string  `json:"toj_codigo"` // Organization type (1 = jurídica, 2 = natural)
+	OfeDireccion       string  `json:"ofe_direccion"`
+	PaiCodigo          string  `json:"pai_codigo"`
+	DepCodigo          string  `json:"dep_codigo"` // DIVIPOLA department code (2 digits)
+	DepNombre          string  `json:"dep_nombre"`
+	MunCodigo          string  `json:"mun_codigo"` // DIVIPOLA municipality code (5 digits)
+	MunNombre          string  `json:"mun_nombre"`
+	CpoCodigo          *string `json:"cpo_codigo"`
+	OfeTelefono        *string `json:"ofe_telefono"`
+	OfeCorreo          *string `json:"ofe_correo"`
+	RfiCodigo          *string `json:"rfi_codigo"` // Fiscal regime (48, 49)
+	// RefCodigo holds the tax responsibilities (O-13, O-15, O-23, O-47, R-99-PN)
+	RefCodigo []string `json:"ref_codigo"`
+	// CdoAmbiente is the default environment of the OFE documents ("1"=production, "2"=test).
+	// Empty uses the service default.
+	CdoAmbiente *string            `json:"cdo_ambiente"`
+	Numrot      *NumrotCredentials `json:"numrot,omitempty"`
+	Estado      string             `json:"estado"` // ACTIVO/INACTIVO
+	CreatedAt   time.Time          `json:"fecha_creacion"`
+	UpdatedAt   time.Time          `json:"fecha_modificacion"`
+}
+
+// NumrotCredentials are the credentials of the OFE in Numrot. Username/Password
+// authenticate the resolution and document queries and Key/Secret the document
+// registration. Password and Secret are never serialized.
+type NumrotCredentials struct {
+	Username string `json:"usuario"`
+	Password string `json:"-"`
+	Key      string `json:"key"`
+	Secret   string `json:"-"`
+}
+
+// IsActive reports whether the OFE can issue documents.
+func (o *OFE) IsActive() bool {
+	return o.Estado != EstadoInactivo
+}
@@ -0,0 +1,24 @@
+package ofe
+
+import "context"
+
+// Repository defines the interface for OFE persistence operations.
+type Repository interface {
+	// Create persists a new OFE and returns its ID.
+	Create(ctx context.Context, ofe OFE) (int64, error)
+
+	// Update updates the OFE identified by ofeIdentificacion.
+	// The Numrot credentials are kept when ofe.Numrot is nil.
+	Update(ctx context.Context, ofeIdentificacion string, ofe OFE) error
+
+	// FindByIdentificacion retrieves an OFE by its NIT (without verification digit).
+	// Returns nil if not found.
+	FindByIdentificacion(ctx context.Context, ofeIdentificacion string) (*OFE, error)
+
+	// List retrieves OFEs with pagination and search.
+	// start: starting index (0-based)
+	// length: number of records to return (-1 for all)
+	// buscar: search term matched against identification, razón social and nombre comercial
+	// Returns: list of OFEs, total count, and error
+	List(ctx context.Context, start, length int, buscar string) ([]OFE, int, error)
+}
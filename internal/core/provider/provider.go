@@ -7,6 +7,7 @@ type Provider struct {
 	ID                          int64     `json:"pro_id"`
 	OfeIdentificacion           string    `json:"ofe_identificacion"`
 	ProIdentificacion           string    `json:"pro_identificacion"`
+	ProDV                       string    `json:"pro_dv,omitempty"` // Verification digit when the provider is a NIT (not stored)
 	ProIDPersonalizado          *string   `json:"pro_id_personalizado"`
 	ProRazonSocial              *string   `json:"pro_razon_social"`
 	ProNombreComercial          *string   `json:"pro_nombre_comercial"`
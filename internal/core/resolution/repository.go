@@ -0,0 +1,26 @@
+package resolution
+
+import (
+	"context"
+	"time"
+)
+
+// Repository defines the interface for the local store of numbering ranges.
+type Repository interface {
+	// Sync inserts or updates the resolutions of an OFE as read from the provider.
+	// The consecutivos already used are kept.
+	Sync(ctx context.Context, ofeIdentificacion string, resolutions []Resolution) error
+
+	// List retrieves the ranges of an OFE, or of every OFE when ofeIdentificacion is empty.
+	List(ctx context.Context, ofeIdentificacion string) ([]Range, error)
+
+	// Find retrieves a range by OFE, resolution number and prefix. Returns nil if not found.
+	Find(ctx context.Context, ofeIdentificacion, resolutionNumber, prefix string) (*Range, error)
+
+	// Allocate hands out the next free consecutivo of the range of the OFE and prefix valid
+	// at the given date. Concurrent allocations of the same range are serialized.
+	Allocate(ctx context.Context, ofeIdentificacion, prefix string, at time.Time) (*Allocation, error)
+
+	// MarkUsed records a consecutivo of the range as used. Marking it again is a no-op.
+	MarkUsed(ctx context.Context, rangeID, consecutivo int64) error
+}
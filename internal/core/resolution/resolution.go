@@ -1,6 +1,9 @@
 package resolution
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // Resolution represents a DIAN resolution for invoice numbering ranges.
 type Resolution struct {
@@ -12,3 +15,86 @@ type Resolution struct {
 	ValidDateFrom    time.Time `json:"validDateFrom"`
 	ValidDateTo      time.Time `json:"validDateTo"`
 }
+
+// Range is a resolution of an OFE tracked locally, with the consecutivos used so far.
+type Range struct {
+	ID                int64  `json:"id"`
+	OfeIdentificacion string `json:"ofeIdentificacion"` // NIT without verification digit
+	Resolution
+	LastNumber int64     `json:"lastNumber"` // Highest consecutivo allocated or used; 0 if none
+	UsedCount  int64     `json:"usedCount"`  // Number of distinct consecutivos used
+	SyncedAt   time.Time `json:"syncedAt"`   // Last time the resolution was read from the provider
+}
+
+// Alert types.
+const (
+	AlertUsage  = "CONSUMO"
+	AlertExpiry = "VENCIMIENTO"
+)
+
+// Alert warns that a numbering range is running out of numbers or about to expire.
+type Alert struct {
+	Type              string  `json:"tipo"`
+	OfeIdentificacion string  `json:"ofeIdentificacion"`
+	ResolutionNumber  string  `json:"resolutionNumber"`
+	Prefix            string  `json:"prefix"`
+	UsagePercent      float64 `json:"usagePercent"`
+	DaysToExpire      int     `json:"daysToExpire"`
+	Message           string  `json:"mensaje"`
+}
+
+// Allocation is a consecutivo handed out by the allocator.
+type Allocation struct {
+	Consecutivo int64 `json:"consecutivo"`
+	Range       Range `json:"resolucion"`
+}
+
+// Size returns the number of consecutivos authorized by the range.
+func (r Range) Size() int64 {
+	return r.ToNumber - r.FromNumber + 1
+}
+
+// UsagePercent returns the percentage of the range consumed up to the highest used consecutivo.
+func (r Range) UsagePercent() float64 {
+	if r.Size() <= 0 || r.LastNumber < r.FromNumber {
+		return 0
+	}
+	return float64(r.LastNumber-r.FromNumber+1) * 100 / float64(r.Size())
+}
+
+// Contains reports whether consecutivo is inside FromNumber..ToNumber.
+func (r Range) Contains(consecutivo int64) bool {
+	return consecutivo >= r.FromNumber && consecutivo <= r.ToNumber
+}
+
+// ValidOn reports whether the date (day precision) falls inside the validity period.
+func (r Range) ValidOn(date time.Time) bool {
+	d := day(date)
+	return !d.Before(day(r.ValidDateFrom)) && !d.After(day(r.ValidDateTo))
+}
+
+// DaysToExpire returns the number of days from now until ValidDateTo (negative once expired).
+func (r Range) DaysToExpire(now time.Time) int {
+	return int(day(r.ValidDateTo).Sub(day(now)).Hours() / 24)
+}
+
+// Check validates a document number and issue date against the range.
+func (r Range) Check(consecutivo int64, date time.Time) error {
+	if !r.Contains(consecutivo) {
+		return fmt.Errorf("consecutivo [%d] fuera del rango autorizado [%d-%d] de la resolución [%s]",
+			consecutivo, r.FromNumber, r.ToNumber, r.ResolutionNumber)
+	}
+	if !r.ValidOn(date) {
+		return fmt.Errorf("fecha [%s] fuera de la vigencia [%s - %s] de la resolución [%s]",
+			date.Format("2006-01-02"),
+			r.ValidDateFrom.Format("2006-01-02"),
+			r.ValidDateTo.Format("2006-01-02"),
+			r.ResolutionNumber)
+	}
+	return nil
+}
+
+// day truncates t to its calendar date.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
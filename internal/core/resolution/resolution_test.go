@@ -0,0 +1,57 @@
+package resolution
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRange() Range {
+	return Range{
+		OfeIdentificacion: "860011153",
+		Resolution: Resolution{
+			ResolutionNumber: "18760000001",
+			Prefix:           "SETT",
+			FromNumber:       1,
+			ToNumber:         100,
+			ValidDateFrom:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			ValidDateTo:      time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func TestRange_Check(t *testing.T) {
+	rng := testRange()
+	inside := time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC)
+
+	if err := rng.Check(100, inside); err != nil {
+		t.Errorf("expected last number on last valid day to pass, got %v", err)
+	}
+	if err := rng.Check(101, inside); err == nil || !strings.Contains(err.Error(), "fuera del rango autorizado [1-100]") {
+		t.Errorf("expected out of range error, got %v", err)
+	}
+	if err := rng.Check(50, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil || !strings.Contains(err.Error(), "fuera de la vigencia") {
+		t.Errorf("expected validity error, got %v", err)
+	}
+}
+
+func TestRange_UsagePercent(t *testing.T) {
+	rng := testRange()
+	if rng.UsagePercent() != 0 {
+		t.Errorf("expected unused range to be at 0%%, got %.1f", rng.UsagePercent())
+	}
+	rng.LastNumber = 85
+	if rng.UsagePercent() != 85 {
+		t.Errorf("expected 85%%, got %.1f", rng.UsagePercent())
+	}
+}
+
+func TestRange_DaysToExpire(t *testing.T) {
+	rng := testRange()
+	if days := rng.DaysToExpire(time.Date(2026, 12, 1, 18, 0, 0, 0, time.UTC)); days != 30 {
+		t.Errorf("expected 30 days, got %d", days)
+	}
+	if days := rng.DaysToExpire(time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC)); days >= 0 {
+		t.Errorf("expected expired range, got %d days", days)
+	}
+}
@@ -0,0 +1,562 @@
+// Package totals recomputes the monetary totals of OpenETL documents with exact
+// decimal arithmetic and checks them against the declared values, following the
+// rules of the Anexo Técnico de Factura Electrónica 1.9.
+package totals
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+// Rules checked by Validate. The codes are those of invoices (FC); notes and
+// support documents use the same rule with their own prefix (see RuleCode).
+const (
+	RuleLineTotal      = "FAV10" // ddo_total = ddo_cantidad × ddo_valor_unitario
+	RuleLineExtension  = "FAU02" // cdo_valor_sin_impuestos = Σ ddo_total
+	RuleTaxInclusive   = "FAU06" // cdo_total = cdo_valor_sin_impuestos + cdo_impuestos
+	RulePayable        = "FAU14" // cdo_valor_a_pagar = cdo_total + cdo_cargos - cdo_descuentos - cdo_anticipo + cdo_redondeo >= 0
+	RuleTaxTotal       = "FAS07" // cdo_impuestos = Σ tributos.iid_valor
+	RuleTaxSubtotal    = "FAX07" // iid_valor = iid_base × iid_porcentaje / 100
+	RuleWithholding    = "FAT07" // valor = base × porcentaje / 100 of each suggested retention
+	RuleWithholdings   = "FAT08" // cdo_retenciones_sugeridas = Σ cdo_detalle_retenciones_sugeridas.valor
+	defaultTaxCode     = "01"    // Tributos without tri_codigo are IVA
+	amountDecimals     = 2
+	toleranceNumerator = 1 // Tolerance of one cent for rounded products
+)
+
+// tolerance is the maximum difference accepted between a declared and a computed amount.
+var tolerance = big.NewRat(toleranceNumerator, 100)
+
+// rulePrefixes maps the document type to the prefix of its rule codes.
+var rulePrefixes = map[string]string{"FC": "FA", "NC": "CA", "ND": "DA", "DS": "DS"}
+
+// RuleCode returns the code of an invoice rule for the given document type
+// (e.g. FAU02 is CAU02 for credit notes).
+func RuleCode(rule, documentType string) string {
+	prefix, ok := rulePrefixes[documentType]
+	if !ok || !strings.HasPrefix(rule, "FA") {
+		return rule
+	}
+	return prefix + strings.TrimPrefix(rule, "FA")
+}
+
+// Totals are the amounts of a document computed from its lines and tributos.
+type Totals struct {
+	LineExtension *big.Rat   // Σ ddo_total
+	TaxExclusive  *big.Rat   // Σ taxable bases
+	TaxAmount     *big.Rat   // Σ tributos.iid_valor
+	TaxInclusive  *big.Rat   // LineExtension + TaxAmount
+	Charges       *big.Rat   // cdo_cargos
+	Discounts     *big.Rat   // cdo_descuentos
+	Prepaid       *big.Rat   // cdo_anticipo
+	Rounding      *big.Rat   // cdo_redondeo
+	Payable       *big.Rat   // TaxInclusive + Charges - Discounts - Prepaid + Rounding
+	Withholdings  *big.Rat   // Σ cdo_detalle_retenciones_sugeridas (informative, not part of Payable)
+	Taxes         []TaxTotal // Grouped by tax code, in code order
+}
+
+// TaxTotal is the sum of the tributos of a tax code.
+type TaxTotal struct {
+	Code      string
+	Amount    *big.Rat
+	Subtotals []TaxSubtotal // Grouped by percentage, in percentage order
+}
+
+// TaxSubtotal is the sum of the tributos of a tax code and percentage.
+type TaxSubtotal struct {
+	Percent string // Two decimals
+	Base    *big.Rat
+	Amount  *big.Rat
+}
+
+// InCOP returns the totals converted to COP with the exchange rate of the document
+// currency. Each amount is converted and rounded on its own, as reported to DIAN.
+func (t *Totals) InCOP(rate *big.Rat) *Totals {
+	convert := func(amount *big.Rat) *big.Rat {
+		converted := new(big.Rat).Mul(amount, rate)
+		return round(converted)
+	}
+
+	converted := &Totals{
+		LineExtension: convert(t.LineExtension),
+		TaxExclusive:  convert(t.TaxExclusive),
+		TaxAmount:     convert(t.TaxAmount),
+		TaxInclusive:  convert(t.TaxInclusive),
+		Charges:       convert(t.Charges),
+		Discounts:     convert(t.Discounts),
+		Prepaid:       convert(t.Prepaid),
+		Rounding:      convert(t.Rounding),
+		Payable:       convert(t.Payable),
+		Withholdings:  t.Withholdings, // Retentions are already in COP (valor_moneda_nacional)
+	}
+	for _, tax := range t.Taxes {
+		total := TaxTotal{Code: tax.Code, Amount: convert(tax.Amount)}
+		for _, sub := range tax.Subtotals {
+			total.Subtotals = append(total.Subtotals, TaxSubtotal{Percent: sub.Percent, Base: convert(sub.Base), Amount: convert(sub.Amount)})
+		}
+		converted.Taxes = append(converted.Taxes, total)
+	}
+	return converted
+}
+
+// Issue is a mismatch between a declared amount and the computed one.
+type Issue struct {
+	Rule     string `json:"regla"`
+	Field    string `json:"campo"`
+	Declared string `json:"declarado"`
+	Expected string `json:"calculado"`
+}
+
+// Error formats the issue as a validation message.
+func (i Issue) Error() string {
+	return fmt.Sprintf("[%s] %s: valor declarado %s, valor calculado %s", i.Rule, i.Field, i.Declared, i.Expected)
+}
+
+// Calculate computes the totals of a document. Every document type, support documents
+// (DS) included, adds its taxes, since the IVA takes part in the CUDS.
+func Calculate(doc invoice.OpenETLDocument) (*Totals, error) {
+	t := &Totals{LineExtension: new(big.Rat), TaxExclusive: new(big.Rat), TaxAmount: new(big.Rat)}
+
+	for i, item := range doc.Items {
+		total, err := itemTotal(item)
+		if err != nil {
+			return nil, fmt.Errorf("items[%d]: %w", i, err)
+		}
+		t.LineExtension.Add(t.LineExtension, total)
+	}
+
+	taxes, err := groupTaxes(doc.Tributos)
+	if err != nil {
+		return nil, err
+	}
+	t.Taxes = taxes
+	for _, tax := range taxes {
+		t.TaxAmount.Add(t.TaxAmount, tax.Amount)
+		for _, sub := range tax.Subtotals {
+			t.TaxExclusive.Add(t.TaxExclusive, sub.Base)
+		}
+	}
+
+	fields := []struct {
+		name   string
+		value  string
+		target **big.Rat
+	}{
+		{"cdo_cargos", doc.CdoCargos, &t.Charges},
+		{"cdo_descuentos", doc.CdoDescuentos, &t.Discounts},
+		{"cdo_anticipo", doc.CdoAnticipo, &t.Prepaid},
+		{"cdo_redondeo", doc.CdoRedondeo, &t.Rounding},
+	}
+	for _, f := range fields {
+		value, err := parseDecimal(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.target = value
+	}
+
+	t.Withholdings = new(big.Rat)
+	for _, r := range doc.CdoDetalleRetencionesSugeridas {
+		value, err := parseDecimal(r.ValorMonedaNacional.Valor)
+		if err != nil {
+			return nil, fmt.Errorf("cdo_detalle_retenciones_sugeridas[%s].valor: %w", r.Tipo, err)
+		}
+		t.Withholdings.Add(t.Withholdings, value)
+	}
+
+	t.TaxInclusive = new(big.Rat).Add(t.LineExtension, t.TaxAmount)
+	t.Payable = new(big.Rat).Add(t.TaxInclusive, t.Charges)
+	t.Payable.Sub(t.Payable, t.Discounts)
+	t.Payable.Sub(t.Payable, t.Prepaid)
+	t.Payable.Add(t.Payable, t.Rounding)
+
+	return t, nil
+}
+
+// Validate recomputes the totals of a document and returns the declared amounts that
+// do not match. An error is returned when an amount is not a valid decimal.
+func Validate(doc invoice.OpenETLDocument, documentType string) ([]Issue, error) {
+	t, err := Calculate(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	check := func(rule, field, declared string, expected *big.Rat) error {
+		value, err := parseDecimal(declared)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if !withinTolerance(value, expected) {
+			issues = append(issues, Issue{
+				Rule:     RuleCode(rule, documentType),
+				Field:    field,
+				Declared: value.FloatString(amountDecimals),
+				Expected: expected.FloatString(amountDecimals),
+			})
+		}
+		return nil
+	}
+
+	for i, item := range doc.Items {
+		expected, ok, err := lineTotal(item)
+		if err != nil {
+			return nil, fmt.Errorf("items[%d]: %w", i, err)
+		}
+		if ok {
+			if err := check(RuleLineTotal, fmt.Sprintf("items[%d].ddo_total", i), item.DdoTotal, expected); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, tributo := range doc.Tributos {
+		expected, ok, err := tributoValue(tributo)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			field := fmt.Sprintf("tributos[%s].iid_valor", tributo.DdoSecuencia)
+			if err := check(RuleTaxSubtotal, field, tributo.IidValor, expected); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Documents without tax detail only declare cdo_impuestos
+	if len(doc.Tributos) > 0 {
+		if err := check(RuleTaxTotal, "cdo_impuestos", doc.CdoImpuestos, t.TaxAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := check(RuleLineExtension, "cdo_valor_sin_impuestos", doc.CdoValorSinImpuestos, t.LineExtension); err != nil {
+		return nil, err
+	}
+
+	declaredTaxes, err := parseDecimal(doc.CdoImpuestos)
+	if err != nil {
+		return nil, fmt.Errorf("cdo_impuestos: %w", err)
+	}
+	declaredBase, _ := parseDecimal(doc.CdoValorSinImpuestos)
+	if err := check(RuleTaxInclusive, "cdo_total", doc.CdoTotal, new(big.Rat).Add(declaredBase, declaredTaxes)); err != nil {
+		return nil, err
+	}
+
+	for _, r := range doc.CdoDetalleRetencionesSugeridas {
+		expected, err := withholdingValue(r)
+		if err != nil {
+			return nil, err
+		}
+		field := fmt.Sprintf("cdo_detalle_retenciones_sugeridas[%s].valor", r.Tipo)
+		if err := check(RuleWithholding, field, r.ValorMonedaNacional.Valor, expected); err != nil {
+			return nil, err
+		}
+	}
+	if len(doc.CdoDetalleRetencionesSugeridas) > 0 {
+		if err := check(RuleWithholdings, "cdo_retenciones_sugeridas", doc.CdoRetencionesSugeridas, t.Withholdings); err != nil {
+			return nil, err
+		}
+	}
+
+	// The payable amount is a sum of declared amounts, so it must match to the cent
+	payable, err := declaredPayable(doc)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(doc.CdoValorAPagar) != "" {
+		declared, err := parseDecimal(doc.CdoValorAPagar)
+		if err != nil {
+			return nil, fmt.Errorf("cdo_valor_a_pagar: %w", err)
+		}
+		if round(declared).Cmp(round(payable)) != 0 {
+			issues = append(issues, Issue{
+				Rule:     RuleCode(RulePayable, documentType),
+				Field:    "cdo_valor_a_pagar",
+				Declared: declared.FloatString(amountDecimals),
+				Expected: payable.FloatString(amountDecimals),
+			})
+		}
+	}
+	if payable.Sign() < 0 {
+		issues = append(issues, Issue{
+			Rule:     RuleCode(RulePayable, documentType),
+			Field:    "cdo_anticipo",
+			Declared: t.Prepaid.FloatString(amountDecimals),
+			Expected: new(big.Rat).Add(t.Prepaid, payable).FloatString(amountDecimals),
+		})
+	}
+
+	return issues, nil
+}
+
+// Payable returns the amount payable of a document (PayableAmount, the ValTot of the
+// CUFE/CUDE/CUDS): cdo_valor_a_pagar when declared, otherwise cdo_total plus charges,
+// minus discounts and anticipos, plus redondeo.
+func Payable(doc invoice.OpenETLDocument) (*big.Rat, error) {
+	if strings.TrimSpace(doc.CdoValorAPagar) != "" {
+		payable, err := parseDecimal(doc.CdoValorAPagar)
+		if err != nil {
+			return nil, fmt.Errorf("cdo_valor_a_pagar: %w", err)
+		}
+		return payable, nil
+	}
+	return declaredPayable(doc)
+}
+
+// declaredPayable computes cdo_total + cdo_cargos - cdo_descuentos - cdo_anticipo + cdo_redondeo.
+func declaredPayable(doc invoice.OpenETLDocument) (*big.Rat, error) {
+	fields := []struct {
+		name  string
+		value string
+		sign  int
+	}{
+		{"cdo_total", doc.CdoTotal, 1},
+		{"cdo_cargos", doc.CdoCargos, 1},
+		{"cdo_descuentos", doc.CdoDescuentos, -1},
+		{"cdo_anticipo", doc.CdoAnticipo, -1},
+		{"cdo_redondeo", doc.CdoRedondeo, 1},
+	}
+	payable := new(big.Rat)
+	for _, f := range fields {
+		value, err := parseDecimal(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		if f.sign < 0 {
+			value.Neg(value)
+		}
+		payable.Add(payable, value)
+	}
+	return payable, nil
+}
+
+// Fill completes the document amounts: line totals and tributo values left empty are
+// computed, and cdo_valor_sin_impuestos, cdo_impuestos, cdo_total and cdo_valor_a_pagar
+// are replaced with the computed totals. Documents without tributos keep their declared
+// cdo_impuestos. cdo_retenciones_sugeridas is replaced with the sum of the detailed
+// retentions, if any.
+func Fill(doc invoice.OpenETLDocument) (invoice.OpenETLDocument, error) {
+	items := make([]invoice.OpenETLItem, len(doc.Items))
+	copy(items, doc.Items)
+	for i := range items {
+		if strings.TrimSpace(items[i].DdoTotal) != "" {
+			continue
+		}
+		total, ok, err := lineTotal(items[i])
+		if err != nil {
+			return doc, fmt.Errorf("items[%d]: %w", i, err)
+		}
+		if ok {
+			items[i].DdoTotal = total.FloatString(amountDecimals)
+		}
+	}
+	doc.Items = items
+
+	tributos := make([]invoice.OpenETLTributo, len(doc.Tributos))
+	copy(tributos, doc.Tributos)
+	for i := range tributos {
+		if strings.TrimSpace(tributos[i].IidValor) != "" {
+			continue
+		}
+		value, ok, err := tributoValue(tributos[i])
+		if err != nil {
+			return doc, err
+		}
+		if ok {
+			tributos[i].IidValor = value.FloatString(amountDecimals)
+		}
+	}
+	doc.Tributos = tributos
+
+	t, err := Calculate(doc)
+	if err != nil {
+		return doc, err
+	}
+
+	taxes := t.TaxAmount
+	if len(doc.Tributos) == 0 {
+		if taxes, err = parseDecimal(doc.CdoImpuestos); err != nil {
+			return doc, fmt.Errorf("cdo_impuestos: %w", err)
+		}
+	}
+	doc.CdoValorSinImpuestos = t.LineExtension.FloatString(amountDecimals)
+	doc.CdoImpuestos = taxes.FloatString(amountDecimals)
+	doc.CdoTotal = new(big.Rat).Add(t.LineExtension, taxes).FloatString(amountDecimals)
+	doc.CdoValorAPagar = ""
+	payable, err := declaredPayable(doc)
+	if err != nil {
+		return doc, err
+	}
+	doc.CdoValorAPagar = payable.FloatString(amountDecimals)
+	if len(doc.CdoDetalleRetencionesSugeridas) > 0 {
+		doc.CdoRetencionesSugeridas = t.Withholdings.FloatString(amountDecimals)
+	}
+
+	return doc, nil
+}
+
+// itemTotal returns the declared line total, or the computed one when it is empty.
+func itemTotal(item invoice.OpenETLItem) (*big.Rat, error) {
+	if strings.TrimSpace(item.DdoTotal) != "" {
+		total, err := parseDecimal(item.DdoTotal)
+		if err != nil {
+			return nil, fmt.Errorf("ddo_total: %w", err)
+		}
+		return total, nil
+	}
+	total, _, err := lineTotal(item)
+	return total, err
+}
+
+// lineTotal computes ddo_cantidad × ddo_valor_unitario rounded to two decimals.
+// ok is false when the line does not declare both values.
+func lineTotal(item invoice.OpenETLItem) (*big.Rat, bool, error) {
+	if strings.TrimSpace(item.DdoCantidad) == "" || strings.TrimSpace(item.DdoValorUnitario) == "" {
+		return new(big.Rat), false, nil
+	}
+	qty, err := parseDecimal(item.DdoCantidad)
+	if err != nil {
+		return nil, false, fmt.Errorf("ddo_cantidad: %w", err)
+	}
+	price, err := parseDecimal(item.DdoValorUnitario)
+	if err != nil {
+		return nil, false, fmt.Errorf("ddo_valor_unitario: %w", err)
+	}
+	return round(new(big.Rat).Mul(qty, price)), true, nil
+}
+
+// tributoValue computes iid_base × iid_porcentaje / 100 rounded to two decimals.
+// ok is false when the tributo has no percentage (e.g. taxes per unit).
+func tributoValue(t invoice.OpenETLTributo) (*big.Rat, bool, error) {
+	if t.IidPorcentaje == nil || strings.TrimSpace(t.IidPorcentaje.IidPorcentaje) == "" {
+		return nil, false, nil
+	}
+	base, err := parseDecimal(t.IidPorcentaje.IidBase)
+	if err != nil {
+		return nil, false, fmt.Errorf("tributos[%s].iid_base: %w", t.DdoSecuencia, err)
+	}
+	percent, err := parseDecimal(t.IidPorcentaje.IidPorcentaje)
+	if err != nil {
+		return nil, false, fmt.Errorf("tributos[%s].iid_porcentaje: %w", t.DdoSecuencia, err)
+	}
+	value := new(big.Rat).Mul(base, percent)
+	value.Quo(value, big.NewRat(100, 1))
+	return round(value), true, nil
+}
+
+// withholdingValue computes base × porcentaje / 100 of a suggested retention rounded to two decimals.
+func withholdingValue(r invoice.OpenETLRetencion) (*big.Rat, error) {
+	base, err := parseDecimal(r.ValorMonedaNacional.Base)
+	if err != nil {
+		return nil, fmt.Errorf("cdo_detalle_retenciones_sugeridas[%s].base: %w", r.Tipo, err)
+	}
+	percent, err := parseDecimal(r.Porcentaje)
+	if err != nil {
+		return nil, fmt.Errorf("cdo_detalle_retenciones_sugeridas[%s].porcentaje: %w", r.Tipo, err)
+	}
+	value := new(big.Rat).Mul(base, percent)
+	value.Quo(value, big.NewRat(100, 1))
+	return round(value), nil
+}
+
+// groupTaxes groups the tributos by tax code and percentage, like the document
+// level TaxTotal of the UBL document.
+func groupTaxes(tributos []invoice.OpenETLTributo) ([]TaxTotal, error) {
+	groups := map[string]map[string]*TaxSubtotal{}
+
+	for _, t := range tributos {
+		amount, err := parseDecimal(t.IidValor)
+		if err != nil {
+			return nil, fmt.Errorf("tributos[%s].iid_valor: %w", t.DdoSecuencia, err)
+		}
+		if strings.TrimSpace(t.IidValor) == "" {
+			if computed, ok, err := tributoValue(t); err != nil {
+				return nil, err
+			} else if ok {
+				amount = computed
+			}
+		}
+
+		code := t.TriCodigo
+		if code == "" {
+			code = defaultTaxCode
+		}
+		percent, base := "0", "0"
+		if t.IidPorcentaje != nil {
+			percent, base = t.IidPorcentaje.IidPorcentaje, t.IidPorcentaje.IidBase
+		}
+		baseValue, err := parseDecimal(base)
+		if err != nil {
+			return nil, fmt.Errorf("tributos[%s].iid_base: %w", t.DdoSecuencia, err)
+		}
+		percentValue, err := parseDecimal(percent)
+		if err != nil {
+			return nil, fmt.Errorf("tributos[%s].iid_porcentaje: %w", t.DdoSecuencia, err)
+		}
+		percentKey := percentValue.FloatString(2)
+
+		if groups[code] == nil {
+			groups[code] = map[string]*TaxSubtotal{}
+		}
+		sub, ok := groups[code][percentKey]
+		if !ok {
+			sub = &TaxSubtotal{Percent: percentKey, Base: new(big.Rat), Amount: new(big.Rat)}
+			groups[code][percentKey] = sub
+		}
+		sub.Base.Add(sub.Base, baseValue)
+		sub.Amount.Add(sub.Amount, amount)
+	}
+
+	taxes := make([]TaxTotal, 0, len(groups))
+	for _, code := range sortedKeys(groups) {
+		total := TaxTotal{Code: code, Amount: new(big.Rat)}
+		for _, percent := range sortedKeys(groups[code]) {
+			sub := groups[code][percent]
+			total.Amount.Add(total.Amount, sub.Amount)
+			total.Subtotals = append(total.Subtotals, *sub)
+		}
+		taxes = append(taxes, total)
+	}
+	return taxes, nil
+}
+
+func withinTolerance(declared, expected *big.Rat) bool {
+	diff := new(big.Rat).Sub(declared, expected)
+	return diff.Abs(diff).Cmp(tolerance) <= 0
+}
+
+// round rounds to two decimals, halves away from zero.
+func round(value *big.Rat) *big.Rat {
+	rounded, _ := new(big.Rat).SetString(value.FloatString(amountDecimals))
+	return rounded
+}
+
+// ParseAmount parses a monetary amount of a document. An empty amount is zero.
+func ParseAmount(value string) (*big.Rat, error) {
+	return parseDecimal(value)
+}
+
+func parseDecimal(value string) (*big.Rat, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return new(big.Rat), nil
+	}
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("valor inválido: %q", value)
+	}
+	return r, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,230 @@
+package totals
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+)
+
+func testDocument() invoice.OpenETLDocument {
+	return invoice.OpenETLDocument{
+		CdoValorSinImpuestos: "150000.00",
+		CdoImpuestos:         "23500.00",
+		CdoTotal:             "173500.00",
+		Items: []invoice.OpenETLItem{
+			{DdoSecuencia: "1", DdoCantidad: "2", DdoValorUnitario: "50000.00", DdoTotal: "100000.00"},
+			{DdoSecuencia: "2", DdoCantidad: "1", DdoValorUnitario: "50000.00", DdoTotal: "50000.00"},
+		},
+		Tributos: []invoice.OpenETLTributo{
+			{DdoSecuencia: "1", TriCodigo: "01", IidValor: "19000.00", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "100000.00", IidPorcentaje: "19.00"}},
+			{DdoSecuencia: "2", TriCodigo: "01", IidValor: "2500.00", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "50000.00", IidPorcentaje: "5"}},
+			{DdoSecuencia: "2", TriCodigo: "04", IidValor: "2000.00", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "50000.00", IidPorcentaje: "4.00"}},
+		},
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	doc := testDocument()
+	doc.CdoCargos = "1000"
+	doc.CdoDescuentos = "500"
+	doc.CdoAnticipo = "10000"
+	doc.CdoRedondeo = "0.50"
+
+	got, err := Calculate(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.LineExtension.FloatString(2) != "150000.00" || got.TaxAmount.FloatString(2) != "23500.00" || got.TaxInclusive.FloatString(2) != "173500.00" {
+		t.Errorf("unexpected totals: %s %s %s", got.LineExtension.FloatString(2), got.TaxAmount.FloatString(2), got.TaxInclusive.FloatString(2))
+	}
+	if got.Payable.FloatString(2) != "164000.50" {
+		t.Errorf("expected payable 164000.50, got %s", got.Payable.FloatString(2))
+	}
+	if len(got.Taxes) != 2 || got.Taxes[0].Code != "01" || len(got.Taxes[0].Subtotals) != 2 {
+		t.Fatalf("expected taxes grouped by code and percentage, got %+v", got.Taxes)
+	}
+	if got.Taxes[0].Subtotals[0].Percent != "19.00" || got.Taxes[0].Subtotals[1].Percent != "5.00" {
+		t.Errorf("unexpected percentage order: %+v", got.Taxes[0].Subtotals)
+	}
+
+	ds, err := Calculate(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ds.TaxAmount.FloatString(2) != "23500.00" || ds.Payable.Cmp(got.Payable) != 0 {
+		t.Errorf("expected DS taxes to be included as in the CUDS, got %s", ds.TaxAmount.FloatString(2))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	issues, err := Validate(testDocument(), "FC")
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("expected consistent document, got %v %v", issues, err)
+	}
+
+	doc := testDocument()
+	doc.Items[1].DdoTotal = "50000.02"
+	doc.Tributos[0].IidValor = "19001.00"
+	doc.CdoTotal = "173000.00"
+	issues, err = Validate(doc, "NC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rules := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		rules = append(rules, issue.Rule)
+	}
+	if strings.Join(rules, ",") != "CAV10,CAX07,CAS07,CAU02,CAU06" {
+		t.Errorf("unexpected rules: %v", rules)
+	}
+	if issues[3].Declared != "150000.00" || issues[3].Expected != "150000.02" {
+		t.Errorf("unexpected issue: %+v", issues[3])
+	}
+}
+
+func TestValidate_Tolerance(t *testing.T) {
+	doc := testDocument()
+	doc.Items[0].DdoValorUnitario = "50000.005" // 100000.01 after rounding
+	issues, err := Validate(doc, "FC")
+	if err != nil || len(issues) != 0 {
+		t.Errorf("expected one cent difference to be accepted, got %v %v", issues, err)
+	}
+}
+
+func TestValidate_NegativePayable(t *testing.T) {
+	doc := testDocument()
+	doc.CdoAnticipo = "200000"
+	issues, err := Validate(doc, "FC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Rule != "FAU14" {
+		t.Errorf("expected FAU14, got %v", issues)
+	}
+}
+
+func TestValidate_Payable(t *testing.T) {
+	tests := []struct {
+		name    string
+		payable string
+		rules   []string
+	}{
+		{name: "not declared"},
+		{name: "matches", payable: "164000.50"},
+		{name: "one cent off", payable: "164000.51", rules: []string{"DSU14"}},
+		{name: "ignores charges", payable: "173500.00", rules: []string{"DSU14"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := testDocument()
+			doc.CdoCargos, doc.CdoDescuentos, doc.CdoAnticipo, doc.CdoRedondeo = "1000", "500", "10000", "0.50"
+			doc.CdoValorAPagar = tt.payable
+
+			issues, err := Validate(doc, "DS")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rules := make([]string, 0, len(issues))
+			for _, issue := range issues {
+				rules = append(rules, issue.Rule)
+			}
+			if strings.Join(rules, ",") != strings.Join(tt.rules, ",") {
+				t.Errorf("expected rules %v, got %v", tt.rules, issues)
+			}
+		})
+	}
+}
+
+func TestPayable(t *testing.T) {
+	doc := testDocument()
+	doc.CdoAnticipo = "3500.00"
+	payable, err := Payable(doc)
+	if err != nil || payable.FloatString(2) != "170000.00" {
+		t.Errorf("expected payable 170000.00, got %v %v", payable, err)
+	}
+
+	doc.CdoValorAPagar = "169999.99"
+	if payable, err := Payable(doc); err != nil || payable.FloatString(2) != "169999.99" {
+		t.Errorf("expected the declared payable, got %v %v", payable, err)
+	}
+}
+
+func TestValidate_InvalidAmount(t *testing.T) {
+	doc := testDocument()
+	doc.CdoTotal = "1.000,00"
+	if _, err := Validate(doc, "FC"); err == nil || !strings.Contains(err.Error(), "cdo_total: valor inválido") {
+		t.Errorf("expected invalid amount error, got %v", err)
+	}
+}
+
+func TestFill(t *testing.T) {
+	doc := testDocument()
+	doc.CdoValorSinImpuestos, doc.CdoImpuestos, doc.CdoTotal = "", "", ""
+	doc.Items[0].DdoTotal = ""
+	doc.Tributos[1].IidValor = ""
+
+	filled, err := Fill(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filled.Items[0].DdoTotal != "100000.00" || filled.Tributos[1].IidValor != "2500.00" {
+		t.Errorf("expected line and tax values to be filled, got %s %s", filled.Items[0].DdoTotal, filled.Tributos[1].IidValor)
+	}
+	if filled.CdoValorSinImpuestos != "150000.00" || filled.CdoImpuestos != "23500.00" || filled.CdoTotal != "173500.00" || filled.CdoValorAPagar != "173500.00" {
+		t.Errorf("unexpected totals: %s %s %s %s", filled.CdoValorSinImpuestos, filled.CdoImpuestos, filled.CdoTotal, filled.CdoValorAPagar)
+	}
+	if doc.Items[0].DdoTotal != "" {
+		t.Error("expected the original document to be left untouched")
+	}
+	if issues, err := Validate(filled, "FC"); err != nil || len(issues) != 0 {
+		t.Errorf("expected filled document to be consistent, got %v %v", issues, err)
+	}
+}
+
+func TestValidate_Withholdings(t *testing.T) {
+	doc := testDocument()
+	doc.CdoRetencionesSugeridas = "6000.00"
+	doc.CdoDetalleRetencionesSugeridas = []invoice.OpenETLRetencion{
+		{Tipo: "RETEFUENTE", Porcentaje: "4.00", ValorMonedaNacional: invoice.OpenETLValorMonedaNacional{Base: "150000.00", Valor: "6000.00"}},
+		{Tipo: "RETEIVA", Porcentaje: "15.00", ValorMonedaNacional: invoice.OpenETLValorMonedaNacional{Base: "21500.00", Valor: "3000.00"}},
+	}
+
+	issues, err := Validate(doc, "FC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 2 || issues[0].Rule != "FAT07" || issues[0].Expected != "3225.00" || issues[1].Rule != "FAT08" {
+		t.Fatalf("expected FAT07 and FAT08 issues, got %v", issues)
+	}
+
+	doc.CdoDetalleRetencionesSugeridas[1].ValorMonedaNacional.Valor = "3225.00"
+	filled, err := Fill(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filled.CdoRetencionesSugeridas != "9225.00" {
+		t.Errorf("expected suggested retentions to be filled, got %s", filled.CdoRetencionesSugeridas)
+	}
+}
+
+func TestTotals_InCOP(t *testing.T) {
+	got, err := Calculate(testDocument())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cop := got.InCOP(big.NewRat(415035, 100)) // 4150.35
+	if cop.LineExtension.FloatString(2) != "622552500.00" || cop.TaxAmount.FloatString(2) != "97533225.00" {
+		t.Errorf("unexpected COP totals: %s %s", cop.LineExtension.FloatString(2), cop.TaxAmount.FloatString(2))
+	}
+	if len(cop.Taxes) != 2 || cop.Taxes[0].Subtotals[0].Base.FloatString(2) != "415035000.00" {
+		t.Errorf("expected tax subtotals in COP, got %+v", cop.Taxes)
+	}
+	if got.LineExtension.FloatString(2) != "150000.00" {
+		t.Error("expected the original totals to be left untouched")
+	}
+}
@@ -0,0 +1,25 @@
+package withholding
+
+import "context"
+
+// Repository defines the interface for the withholding rules and UVT tables.
+type Repository interface {
+	// ListRules retrieves every rule, active or not, ordered by ID.
+	ListRules(ctx context.Context) ([]Rule, error)
+
+	// SaveRule creates the rule when its ID is 0, or updates it otherwise.
+	// Returns the ID of the rule.
+	SaveRule(ctx context.Context, rule Rule) (int64, error)
+
+	// DeleteRule removes a rule.
+	DeleteRule(ctx context.Context, id int64) error
+
+	// ListUVT retrieves the UVT values ordered by year.
+	ListUVT(ctx context.Context) ([]UVT, error)
+
+	// FindUVT retrieves the UVT value of a year. Returns nil if not found.
+	FindUVT(ctx context.Context, anio int) (*UVT, error)
+
+	// SetUVT creates or replaces the UVT value of a year.
+	SetUVT(ctx context.Context, uvt UVT) error
+}
@@ -0,0 +1,260 @@
+// Package withholding derives the suggested retentions (ReteFuente, ReteIVA, ReteICA)
+// of a document from the tax responsibilities of the parties, the withholding concept,
+// the municipality and the thresholds and rates configured in the rules tables.
+package withholding
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"3tcapital/goclonacion/internal/core/currency"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/totals"
+)
+
+// Withholding types, as reported in OpenETL cdo_detalle_retenciones_sugeridas.
+const (
+	TipoReteFuente = "RETEFUENTE"
+	TipoReteIVA    = "RETEIVA"
+	TipoReteICA    = "RETEICA"
+)
+
+// taxCodeIVA is the tributo whose value is the base of ReteIVA.
+const taxCodeIVA = "01"
+
+// Tipos lists the supported withholding types in the order they are reported.
+var Tipos = []string{TipoReteFuente, TipoReteIVA, TipoReteICA}
+
+// Rule is a withholding rate that applies when the withholding agent has one of the
+// responsibilities in AgenteResponsabilidades and the beneficiary has none of the
+// responsibilities in ExentoResponsabilidades.
+type Rule struct {
+	ID                      int64    `json:"id"`
+	Tipo                    string   `json:"tipo"`                     // RETEFUENTE, RETEIVA or RETEICA
+	Concepto                string   `json:"concepto"`                 // Empty matches any concept
+	MunCodigo               string   `json:"mun_codigo"`               // DIVIPOLA code of the agent; empty matches any municipality
+	AgenteResponsabilidades []string `json:"agente_responsabilidades"` // ref_codigo/responsable_tributos of the agent; empty matches any agent
+	ExentoResponsabilidades []string `json:"exento_responsabilidades"` // ref_codigo of the beneficiary that exempt it (e.g. O-15 autorretenedor)
+	BaseMinimaUVT           string   `json:"base_minima_uvt"`          // Minimum purchase amount in UVT
+	Porcentaje              string   `json:"porcentaje"`               // Rate applied to the base
+	Razon                   string   `json:"razon"`                    // Description reported in the retention
+	Activo                  bool     `json:"activo"`
+}
+
+// UVT is the value of the Unidad de Valor Tributario for a year.
+type UVT struct {
+	Anio  int    `json:"anio"`
+	Valor string `json:"valor"`
+}
+
+// Parties holds the tax responsibilities of the withholding agent (who pays and withholds)
+// and of the beneficiary (who issues the invoice and is withheld).
+type Parties struct {
+	Agent          []string
+	AgentMunicipio string // DIVIPOLA code of the agent, matched against the ReteICA rules
+	Beneficiary    []string
+}
+
+// PartiesOf returns the parties of a document. For FC/NC/ND documents the acquirer withholds
+// from the OFE; for DS documents the OFE (the buyer) withholds from the provider, whose data
+// is carried in the adq_* fields.
+func PartiesOf(doc invoice.OpenETLDocument, documentType string) Parties {
+	acquirer := append(append([]string{}, doc.AdqRefCodigo...), doc.AdqResponsableTributos...)
+	if documentType == "DS" {
+		return Parties{Agent: doc.OfeRefCodigo, AgentMunicipio: deref(doc.OfeMunicipioCodigo), Beneficiary: acquirer}
+	}
+	return Parties{Agent: acquirer, AgentMunicipio: deref(doc.AdqMunicipioCodigo), Beneficiary: doc.OfeRefCodigo}
+}
+
+// Validate checks the rule fields.
+func (r Rule) Validate() error {
+	switch r.Tipo {
+	case TipoReteFuente, TipoReteIVA, TipoReteICA:
+	default:
+		return fmt.Errorf("tipo [%s] inválido: debe ser %s", r.Tipo, strings.Join(Tipos, ", "))
+	}
+	percent, err := parseDecimal(r.Porcentaje)
+	if err != nil || strings.TrimSpace(r.Porcentaje) == "" || percent.Sign() <= 0 || percent.Cmp(big.NewRat(100, 1)) > 0 {
+		return fmt.Errorf("porcentaje debe ser un número mayor que 0 y menor o igual a 100")
+	}
+	if base, err := parseDecimal(r.BaseMinimaUVT); err != nil || base.Sign() < 0 {
+		return fmt.Errorf("base_minima_uvt debe ser un número no negativo")
+	}
+	if strings.TrimSpace(r.Razon) == "" {
+		return fmt.Errorf("razon es requerido")
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to the concept and parties.
+func (r Rule) matches(concepto string, parties Parties) bool {
+	if !r.Activo {
+		return false
+	}
+	if r.Concepto != "" && !strings.EqualFold(r.Concepto, concepto) {
+		return false
+	}
+	if r.MunCodigo != "" && r.MunCodigo != parties.AgentMunicipio {
+		return false
+	}
+	if len(r.AgenteResponsabilidades) > 0 && !intersects(r.AgenteResponsabilidades, parties.Agent) {
+		return false
+	}
+	return !intersects(r.ExentoResponsabilidades, parties.Beneficiary)
+}
+
+// specificity ranks the matching rules: concept and municipality specific rules win over general ones.
+func (r Rule) specificity() int {
+	score := 0
+	if r.Concepto != "" {
+		score += 2
+	}
+	if r.MunCodigo != "" {
+		score++
+	}
+	return score
+}
+
+// Compute returns the suggested retentions of the document: for each withholding type,
+// the most specific rule that applies is used when the purchase amount reaches its
+// threshold (BaseMinimaUVT × uvt). ReteFuente and ReteICA are computed over the amount
+// before taxes and ReteIVA over the IVA.
+// Bases and values are reported in COP: amounts in other currencies are converted with cdo_trm.
+func Compute(doc invoice.OpenETLDocument, documentType string, rules []Rule, uvt string) ([]invoice.OpenETLRetencion, error) {
+	uvtValue, err := parseDecimal(uvt)
+	if err != nil {
+		return nil, fmt.Errorf("valor UVT: %w", err)
+	}
+	rate, err := currency.DocumentRate(doc)
+	if err != nil {
+		return nil, err
+	}
+	calculated, err := totals.Calculate(doc)
+	if err != nil {
+		return nil, err
+	}
+	t := calculated.InCOP(rate)
+
+	iva := new(big.Rat)
+	for _, tax := range t.Taxes {
+		if tax.Code == taxCodeIVA {
+			iva = tax.Amount
+		}
+	}
+
+	concepto := deref(doc.CdoConceptoRetencion)
+	parties := PartiesOf(doc, documentType)
+
+	var retenciones []invoice.OpenETLRetencion
+	for _, tipo := range Tipos {
+		rule := selectRule(rules, tipo, concepto, parties)
+		if rule == nil {
+			continue
+		}
+
+		minimum, _ := parseDecimal(rule.BaseMinimaUVT)
+		minimum.Mul(minimum, uvtValue)
+		if t.LineExtension.Cmp(minimum) < 0 {
+			continue
+		}
+
+		base := t.LineExtension
+		if tipo == TipoReteIVA {
+			base = iva
+		}
+		if base.Sign() <= 0 {
+			continue
+		}
+
+		percent, _ := parseDecimal(rule.Porcentaje)
+		value := new(big.Rat).Mul(base, percent)
+		value.Quo(value, big.NewRat(100, 1))
+
+		retenciones = append(retenciones, invoice.OpenETLRetencion{
+			Tipo:       tipo,
+			Razon:      rule.Razon,
+			Porcentaje: formatPercent(percent),
+			ValorMonedaNacional: invoice.OpenETLValorMonedaNacional{
+				Base:  base.FloatString(2),
+				Valor: value.FloatString(2),
+			},
+		})
+	}
+	return retenciones, nil
+}
+
+// Total returns the sum of the retention values with two decimals.
+func Total(retenciones []invoice.OpenETLRetencion) (string, error) {
+	total := new(big.Rat)
+	for _, r := range retenciones {
+		value, err := parseDecimal(r.ValorMonedaNacional.Valor)
+		if err != nil {
+			return "", fmt.Errorf("retención %s: %w", r.Tipo, err)
+		}
+		total.Add(total, value)
+	}
+	return total.FloatString(2), nil
+}
+
+// selectRule returns the most specific active rule of the type that applies, or nil.
+// Ties are resolved by the lowest ID.
+func selectRule(rules []Rule, tipo, concepto string, parties Parties) *Rule {
+	candidates := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		if r.Tipo == tipo && r.matches(concepto, parties) {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if si, sj := candidates[i].specificity(), candidates[j].specificity(); si != sj {
+			return si > sj
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	return &candidates[0]
+}
+
+// formatPercent formats a rate with at least two and at most four decimals, so that
+// rates like the ReteICA per mil (0.966) keep their precision.
+func formatPercent(percent *big.Rat) string {
+	s := percent.FloatString(4)
+	for strings.HasSuffix(s, "0") && len(s)-strings.Index(s, ".") > 3 {
+		s = strings.TrimSuffix(s, "0")
+	}
+	return s
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(strings.TrimSpace(x), strings.TrimSpace(y)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
+
+func parseDecimal(value string) (*big.Rat, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return new(big.Rat), nil
+	}
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("valor inválido: %q", value)
+	}
+	return r, nil
+}
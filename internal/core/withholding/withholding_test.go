@@ -0,0 +1,147 @@
+package withholding
+
+import (
+	"strings"
+	"testing"
+
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/totals"
+)
+
+func testRules() []Rule {
+	return []Rule{
+		{ID: 1, Tipo: TipoReteFuente, AgenteResponsabilidades: []string{"O-13", "O-23"}, ExentoResponsabilidades: []string{"O-15"}, BaseMinimaUVT: "27", Porcentaje: "2.5", Razon: "Compras generales", Activo: true},
+		{ID: 2, Tipo: TipoReteFuente, Concepto: "SERVICIOS", AgenteResponsabilidades: []string{"O-13", "O-23"}, ExentoResponsabilidades: []string{"O-15"}, BaseMinimaUVT: "4", Porcentaje: "4", Razon: "Servicios generales", Activo: true},
+		{ID: 3, Tipo: TipoReteIVA, AgenteResponsabilidades: []string{"O-13", "O-23"}, BaseMinimaUVT: "4", Porcentaje: "15", Razon: "Retención de IVA", Activo: true},
+		{ID: 4, Tipo: TipoReteICA, MunCodigo: "11001", AgenteResponsabilidades: []string{"O-13", "O-23"}, BaseMinimaUVT: "4", Porcentaje: "0.966", Razon: "ICA Bogotá", Activo: true},
+		{ID: 5, Tipo: TipoReteICA, BaseMinimaUVT: "0", Porcentaje: "1", Razon: "Inactiva", Activo: false},
+	}
+}
+
+func testDocument() invoice.OpenETLDocument {
+	concepto := "SERVICIOS"
+	municipio := "11001"
+	return invoice.OpenETLDocument{
+		CdoValorSinImpuestos: "1000000.00",
+		CdoImpuestos:         "190000.00",
+		CdoTotal:             "1190000.00",
+		CdoConceptoRetencion: &concepto,
+		AdqMunicipioCodigo:   &municipio,
+		AdqRefCodigo:         []string{"O-13"},
+		OfeRefCodigo:         []string{"O-47"},
+		Items: []invoice.OpenETLItem{
+			{DdoSecuencia: "1", DdoCantidad: "1", DdoValorUnitario: "1000000.00", DdoTotal: "1000000.00"},
+		},
+		Tributos: []invoice.OpenETLTributo{
+			{DdoSecuencia: "1", TriCodigo: "01", IidValor: "190000.00", IidPorcentaje: &invoice.OpenETLTributoPorcentaje{IidBase: "1000000.00", IidPorcentaje: "19"}},
+		},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	got, err := Compute(testDocument(), "FC", testRules(), "49799")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 retentions, got %+v", got)
+	}
+
+	fuente, iva, ica := got[0], got[1], got[2]
+	if fuente.Porcentaje != "4.00" {
+		t.Errorf("expected rate with two decimals, got %s", fuente.Porcentaje)
+	}
+	if fuente.Tipo != TipoReteFuente || fuente.Razon != "Servicios generales" || fuente.ValorMonedaNacional.Valor != "40000.00" {
+		t.Errorf("expected concept specific ReteFuente, got %+v", fuente)
+	}
+	if iva.Tipo != TipoReteIVA || iva.ValorMonedaNacional.Base != "190000.00" || iva.ValorMonedaNacional.Valor != "28500.00" {
+		t.Errorf("expected ReteIVA over the IVA, got %+v", iva)
+	}
+	if ica.Tipo != TipoReteICA || ica.Porcentaje != "0.966" || ica.ValorMonedaNacional.Valor != "9660.00" {
+		t.Errorf("unexpected ReteICA: %+v", ica)
+	}
+
+	doc := testDocument()
+	doc.CdoDetalleRetencionesSugeridas = got
+	doc.CdoRetencionesSugeridas, _ = Total(got)
+	if issues, err := totals.Validate(doc, "FC"); err != nil || len(issues) != 0 {
+		t.Errorf("expected computed retentions to pass the totals validation, got %v %v", issues, err)
+	}
+
+	total, err := Total(got)
+	if err != nil || total != "78160.00" {
+		t.Errorf("expected total 78160.00, got %s %v", total, err)
+	}
+}
+
+func TestCompute_Conditions(t *testing.T) {
+	// Autorretenedor beneficiary: no ReteFuente
+	doc := testDocument()
+	doc.OfeRefCodigo = []string{"O-15"}
+	got, _ := Compute(doc, "FC", testRules(), "49799")
+	if len(got) != 2 || got[0].Tipo != TipoReteIVA {
+		t.Errorf("expected autorretenedor to be exempt from ReteFuente, got %+v", got)
+	}
+
+	// Agent without withholding responsibilities
+	doc = testDocument()
+	doc.AdqRefCodigo = []string{"R-99-PN"}
+	if got, _ := Compute(doc, "FC", testRules(), "49799"); len(got) != 0 {
+		t.Errorf("expected no retentions for a non agent, got %+v", got)
+	}
+
+	// Below the threshold of the general purchases rule (27 UVT)
+	doc = testDocument()
+	doc.CdoConceptoRetencion = nil
+	got, _ = Compute(doc, "FC", testRules(), "49799")
+	for _, r := range got {
+		if r.Tipo == TipoReteFuente {
+			t.Errorf("expected purchases below 27 UVT not to be withheld, got %+v", r)
+		}
+	}
+
+	// DS: the OFE withholds from the provider (adq_* fields)
+	doc = testDocument()
+	doc.OfeRefCodigo, doc.AdqRefCodigo = []string{"O-23"}, []string{"R-99-PN"}
+	got, _ = Compute(doc, "DS", testRules(), "49799")
+	if len(got) != 2 || got[0].Tipo != TipoReteFuente || got[1].Tipo != TipoReteIVA {
+		t.Errorf("expected ReteFuente and ReteIVA over the IVA of DS documents, got %+v", got)
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	rule := testRules()[0]
+	if err := rule.Validate(); err != nil {
+		t.Errorf("expected valid rule, got %v", err)
+	}
+
+	rule.Tipo = "RETECREE"
+	if err := rule.Validate(); err == nil || !strings.Contains(err.Error(), "tipo [RETECREE] inválido") {
+		t.Errorf("expected invalid type error, got %v", err)
+	}
+
+	rule = testRules()[0]
+	rule.Porcentaje = "120"
+	if err := rule.Validate(); err == nil || !strings.Contains(err.Error(), "porcentaje debe ser") {
+		t.Errorf("expected invalid percentage error, got %v", err)
+	}
+}
+
+func TestCompute_ForeignCurrency(t *testing.T) {
+	doc := testDocument()
+	doc.MonCodigo = "USD"
+	if _, err := Compute(doc, "FC", testRules(), "49799"); err == nil || !strings.Contains(err.Error(), "cdo_trm es requerido para documentos en moneda [USD]") {
+		t.Fatalf("expected missing TRM error, got %v", err)
+	}
+
+	// USD 1,000,000 at 4,000 COP: bases and values are reported in COP
+	trm := "4000"
+	doc.CdoTrm = &trm
+	got, err := Compute(doc, "FC", testRules(), "49799")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[0].ValorMonedaNacional.Base != "4000000000.00" || got[0].ValorMonedaNacional.Valor != "160000000.00" {
+		t.Errorf("expected ReteFuente in COP, got %+v", got)
+	}
+}
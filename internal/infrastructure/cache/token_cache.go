@@ -51,6 +51,20 @@ func (c *TokenCache) Clear() {
 	c.expiresAt = time.Time{}
 }
 
+// Remaining returns how long the cached token stays valid (zero if there is none or it expired).
+func (c *TokenCache) Remaining() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.token == "" {
+		return 0
+	}
+	if remaining := time.Until(c.expiresAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
 // IsExpired checks if the current token is expired without acquiring a read lock.
 // This is useful for checking before attempting to refresh.
 func (c *TokenCache) IsExpired() bool {
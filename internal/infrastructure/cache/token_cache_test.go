@@ -231,3 +231,20 @@ func TestTokenCache_TTLExpiration(t *testing.T) {
 		t.Errorf("expected token to be expired, but got %q", token)
 	}
 }
+
+func TestTokenCache_Remaining(t *testing.T) {
+	cache := NewTokenCache()
+	if got := cache.Remaining(); got != 0 {
+		t.Errorf("expected 0 without token, got %v", got)
+	}
+
+	cache.Set("token", time.Hour)
+	if got := cache.Remaining(); got <= 59*time.Minute || got > time.Hour {
+		t.Errorf("expected about 1h remaining, got %v", got)
+	}
+
+	cache.Set("token", -time.Second)
+	if got := cache.Remaining(); got != 0 {
+		t.Errorf("expected 0 for expired token, got %v", got)
+	}
+}
@@ -21,6 +21,11 @@ type AppConfig struct {
 	Audit              AuditSettings
 	InvoiceProviders   InvoiceProvidersSettings
 	DocumentProcessing DocumentProcessingSettings
+	DIAN               DIANSettings
+	Resolutions        ResolutionSettings
+	Contingency        ContingencySettings
+	Metrics            MetricsSettings
+	Tracing            TracingSettings
 }
 
 type AppSettings struct {
@@ -67,10 +72,31 @@ type AuditSettings struct {
 	LogRequestBody  bool
 	LogResponseBody bool
 	MaxBodySize     int
+	RetentionMonths int // Monthly partitions kept before the current month; 0 keeps every partition
+
+	// Signed checkpoints of the hash chains
+	CheckpointSigningKey string        // Base64 Ed25519 seed or private key; empty disables checkpoints
+	CheckpointInterval   time.Duration // How often the chain heads are checkpointed
 }
 
 type InvoiceProvidersSettings struct {
-	Numrot NumrotSettings
+	Numrot  NumrotSettings
+	DIAN    DIANProviderSettings
+	Routing RoutingSettings
+}
+
+// DIANProviderSettings contains the DIAN web services endpoint used as invoicing provider.
+// The software credentials and the signing certificate are taken from DIANSettings.
+type DIANProviderSettings struct {
+	URL       string // WcfDianCustomerServices endpoint; empty disables the provider
+	TestSetID string // Habilitation test set; when set documents are sent with SendTestSetAsync
+}
+
+// RoutingSettings selects the invoicing provider per OFE, document type and operation
+type RoutingSettings struct {
+	Default  string   // Provider used when no rule matches ("numrot", "dian")
+	Fallback string   // Provider used while the circuit of the primary one is open
+	Rules    []string // Rules "ofe:tipo:operacion=primario[/secundario]"
 }
 
 // DocumentProcessingSettings contains configuration for concurrent document processing
@@ -81,6 +107,50 @@ type DocumentProcessingSettings struct {
 	RateLimitRPS          int    // Rate limit in requests per second
 	ConcurrentBatchLimit  int    // Maximum batches processing simultaneously (calculated)
 	CdoAmbienteDefault    string // Default environment value for documents ("1"=production, "2"=test)
+	BatchJobWorkers       int    // Number of asynchronous batches (lotes) processed concurrently
+	TotalsValidation      bool   // Recompute the document totals before sending
+	TotalsAutofill        bool   // Fill the document totals instead of rejecting mismatches
+	Withholdings          bool   // Compute the suggested retentions from the withholding rules
+	WithholdingOverride   bool   // Replace the suggested retentions sent in the request
+}
+
+// DIANSettings contains the DIAN invoicing software credentials
+type DIANSettings struct {
+	TechnicalKey string // Clave técnica of the numbering range, used to compute the CUFE
+	SoftwarePIN  string // PIN of the invoicing software, used to compute the CUDE/CUDS
+	SoftwareID   string // Identifier of the invoicing software registered with DIAN
+	ProviderNIT  string // NIT of the technology provider that owns the software
+
+	CertificatePath     string // PKCS#12 (.p12/.pfx) certificate used to sign the UBL documents
+	CertificatePassword string // Password of the PKCS#12 certificate
+}
+
+// ResolutionSettings contains the local tracking of the numbering ranges (resoluciones)
+type ResolutionSettings struct {
+	RefreshInterval   time.Duration // How often the stored resolutions are refreshed from the provider
+	AlertUsagePercent int           // Alert when a range is used above this percentage
+	AlertExpiryDays   int           // Alert when a range expires within this number of days
+}
+
+// ContingencySettings contains the contingency mode used while the provider or DIAN are unavailable
+type ContingencySettings struct {
+	Enabled       bool          // Enable the contingency mode (requires the database)
+	Prefix        string        // Prefix of the contingency numbering of each OFE
+	Type          string        // Contingency type of the automatic activation ("03" issuer, "04" DIAN)
+	OpenThreshold time.Duration // Activate the mode when the provider circuit stays open this long (0 = manual only)
+	CheckInterval time.Duration // How often the provider circuit and the pending documents are checked
+}
+
+// MetricsSettings contains the Prometheus metrics endpoint
+type MetricsSettings struct {
+	Enabled bool // Record the service metrics and expose them on GET /metrics
+}
+
+// TracingSettings contains the OpenTelemetry tracing of requests, services and provider calls
+type TracingSettings struct {
+	Enabled      bool    // Record traces and export them to the OTLP collector
+	OTLPEndpoint string  // OTLP/HTTP collector endpoint, e.g. http://otel-collector:4318
+	SampleRatio  float64 // Fraction of new traces recorded (0 to 1)
 }
 
 type NumrotSettings struct {
@@ -112,6 +182,10 @@ type NumrotSettings struct {
 	NCInvoicePeriodStartTime string // Start time for InvoicePeriod in NC documents
 	NCInvoicePeriodEndDate   string // End date for InvoicePeriod in NC documents
 	NCInvoicePeriodEndTime   string // End time for InvoicePeriod in NC documents
+	// Token refresh and per-OFE credentials
+	TokenRefreshBefore time.Duration // Refresh the token in background within this window before TokenTTL (0 = 10% of TokenTTL)
+	Credentials        []string      // Per-OFE credential sets "ofe=usuario:password:key:secret"
+	CredentialsKey     string        // Base64 AES-256 key that encrypts the Numrot passwords and secrets of the OFE registry
 }
 
 // Load resolves the application configuration from environment variables.
@@ -163,6 +237,10 @@ func Load() (AppConfig, error) {
 			LogRequestBody:  getEnvAsBool("AUDIT_LOG_REQUEST_BODY", true),
 			LogResponseBody: getEnvAsBool("AUDIT_LOG_RESPONSE_BODY", true),
 			MaxBodySize:     getEnvAsInt("AUDIT_MAX_BODY_SIZE", 102400),
+			RetentionMonths: getEnvAsInt("AUDIT_RETENTION_MONTHS", 0),
+
+			CheckpointSigningKey: strings.TrimSpace(os.Getenv("AUDIT_CHECKPOINT_SIGNING_KEY")),
+			CheckpointInterval:   getEnvAsDuration("AUDIT_CHECKPOINT_INTERVAL", time.Hour),
 		},
 		InvoiceProviders: InvoiceProvidersSettings{
 			Numrot: NumrotSettings{
@@ -171,6 +249,9 @@ func Load() (AppConfig, error) {
 				Username:                 strings.TrimSpace(os.Getenv("NUMROT_USERNAME")),
 				Password:                 strings.TrimSpace(os.Getenv("NUMROT_PASSWORD")),
 				TokenTTL:                 getEnvAsDuration("NUMROT_TOKEN_TTL", 1*time.Hour),
+				TokenRefreshBefore:       getEnvAsDuration("NUMROT_TOKEN_REFRESH_BEFORE", 0),
+				Credentials:              getEnvAsCSV("NUMROT_OFE_CREDENTIALS", nil),
+				CredentialsKey:           strings.TrimSpace(os.Getenv("NUMROT_CREDENTIALS_KEY")),
 				APITimeout:               getEnvAsDuration("NUMROT_API_TIMEOUT", 300*time.Second), // Increased from 30s to 300s (5min) for massive operations
 				Key:                      strings.TrimSpace(os.Getenv("NUMROT_KEY")),
 				Secret:                   strings.TrimSpace(os.Getenv("NUMROT_SECRET")),
@@ -192,6 +273,15 @@ func Load() (AppConfig, error) {
 				NCInvoicePeriodEndDate:   strings.TrimSpace(os.Getenv("NUMROT_NC_INVOICE_PERIOD_END_DATE")),
 				NCInvoicePeriodEndTime:   strings.TrimSpace(os.Getenv("NUMROT_NC_INVOICE_PERIOD_END_TIME")),
 			},
+			DIAN: DIANProviderSettings{
+				URL:       strings.TrimSpace(os.Getenv("DIAN_WS_URL")),
+				TestSetID: strings.TrimSpace(os.Getenv("DIAN_TEST_SET_ID")),
+			},
+			Routing: RoutingSettings{
+				Default:  strings.ToLower(strings.TrimSpace(os.Getenv("INVOICE_PROVIDER_DEFAULT"))),
+				Fallback: strings.ToLower(strings.TrimSpace(os.Getenv("INVOICE_PROVIDER_FALLBACK"))),
+				Rules:    getEnvAsCSV("INVOICE_ROUTES", nil),
+			},
 		},
 		DocumentProcessing: DocumentProcessingSettings{
 			WorkerPoolSize:        getEnvAsInt("DOCUMENT_WORKER_POOL_SIZE", 10),
@@ -199,7 +289,52 @@ func Load() (AppConfig, error) {
 			MaxConcurrentRequests: getEnvAsInt("DOCUMENT_MAX_CONCURRENT_REQUESTS", 50), // Reduced from 1000 to 50 for better stability
 			RateLimitRPS:          getEnvAsInt("DOCUMENT_RATE_LIMIT_RPS", 50),          // Reduced from 100 to 50 to match concurrency
 			CdoAmbienteDefault:    strings.TrimSpace(os.Getenv("CDO_AMBIENTE_DEFAULT")),
+			BatchJobWorkers:       getEnvAsInt("DOCUMENT_BATCH_JOB_WORKERS", 2),
+			TotalsValidation:      getEnvAsBool("DOCUMENT_TOTALS_VALIDATION", true),
+			TotalsAutofill:        getEnvAsBool("DOCUMENT_TOTALS_AUTOFILL", false),
+			Withholdings:          getEnvAsBool("DOCUMENT_WITHHOLDINGS", true),
+			WithholdingOverride:   getEnvAsBool("DOCUMENT_WITHHOLDINGS_OVERRIDE", false),
+		},
+		DIAN: DIANSettings{
+			TechnicalKey: strings.TrimSpace(os.Getenv("DIAN_TECHNICAL_KEY")),
+			SoftwarePIN:  strings.TrimSpace(os.Getenv("DIAN_SOFTWARE_PIN")),
+			SoftwareID:   strings.TrimSpace(os.Getenv("DIAN_SOFTWARE_ID")),
+			ProviderNIT:  strings.TrimSpace(os.Getenv("DIAN_PROVIDER_NIT")),
+
+			CertificatePath:     strings.TrimSpace(os.Getenv("DIAN_CERTIFICATE_PATH")),
+			CertificatePassword: os.Getenv("DIAN_CERTIFICATE_PASSWORD"),
+		},
+		Resolutions: ResolutionSettings{
+			RefreshInterval:   getEnvAsDuration("RESOLUTION_REFRESH_INTERVAL", 6*time.Hour),
+			AlertUsagePercent: getEnvAsInt("RESOLUTION_ALERT_USAGE_PERCENT", 80),
+			AlertExpiryDays:   getEnvAsInt("RESOLUTION_ALERT_EXPIRY_DAYS", 30),
+		},
+		Contingency: ContingencySettings{
+			Enabled:       getEnvAsBool("CONTINGENCY_ENABLED", true),
+			Prefix:        getEnv("CONTINGENCY_PREFIX", "CONT"),
+			Type:          getEnv("CONTINGENCY_TYPE", "04"),
+			OpenThreshold: getEnvAsDuration("CONTINGENCY_OPEN_THRESHOLD", 5*time.Minute),
+			CheckInterval: getEnvAsDuration("CONTINGENCY_CHECK_INTERVAL", 30*time.Second),
 		},
+		Metrics: MetricsSettings{
+			Enabled: getEnvAsBool("METRICS_ENABLED", true),
+		},
+		Tracing: TracingSettings{
+			Enabled:      getEnvAsBool("TRACING_ENABLED", false),
+			OTLPEndpoint: getEnv("TRACING_OTLP_ENDPOINT", "http://localhost:4318"),
+			SampleRatio:  getEnvAsFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+	}
+
+	if cfg.InvoiceProviders.Routing.Default == "" {
+		cfg.InvoiceProviders.Routing.Default = "numrot"
+	}
+
+	if cfg.Audit.RetentionMonths < 0 {
+		return cfg, errors.New("invalid config: AUDIT_RETENTION_MONTHS must be 0 (keep everything) or greater")
+	}
+	if cfg.Audit.CheckpointInterval <= 0 {
+		return cfg, errors.New("invalid config: AUDIT_CHECKPOINT_INTERVAL must be greater than 0")
 	}
 
 	// Validate CDO_AMBIENTE_DEFAULT
@@ -227,6 +362,24 @@ func Load() (AppConfig, error) {
 		cfg.DocumentProcessing.ConcurrentBatchLimit = 1
 	}
 
+	if cfg.Resolutions.AlertUsagePercent <= 0 || cfg.Resolutions.AlertUsagePercent > 100 {
+		return cfg, errors.New("invalid config: RESOLUTION_ALERT_USAGE_PERCENT must be between 1 and 100")
+	}
+
+	if cfg.Contingency.Type != "03" && cfg.Contingency.Type != "04" {
+		return cfg, errors.New("invalid config: CONTINGENCY_TYPE must be '03' (issuer) or '04' (DIAN)")
+	}
+	if cfg.Contingency.CheckInterval <= 0 {
+		return cfg, errors.New("invalid config: CONTINGENCY_CHECK_INTERVAL must be greater than 0")
+	}
+
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		return cfg, errors.New("invalid config: TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+	if cfg.Tracing.Enabled && strings.TrimSpace(cfg.Tracing.OTLPEndpoint) == "" {
+		return cfg, errors.New("invalid config: TRACING_OTLP_ENDPOINT is required when TRACING_ENABLED=true")
+	}
+
 	if cfg.Auth.Enabled {
 		if cfg.Auth.IssuerURI == "" {
 			return cfg, errors.New("invalid config: JWT_ISSUER_URI is required when AUTH_ENABLED=true")
@@ -269,6 +422,15 @@ func getEnvAsInt(key string, fallback int) int {
 	return fallback
 }
 
+func getEnvAsFloat(key string, fallback float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
 func getEnvAsDuration(key string, fallback time.Duration) time.Duration {
 	if value, ok := os.LookupEnv(key); ok && value != "" {
 		if parsed, err := time.ParseDuration(value); err == nil {
@@ -130,6 +130,46 @@ func TestLoad_AuthEnabled_MissingJWKSetURI(t *testing.T) {
 	}
 }
 
+func TestLoad_InvalidContingencyType(t *testing.T) {
+	os.Setenv("CONTINGENCY_TYPE", "01")
+	defer os.Unsetenv("CONTINGENCY_TYPE")
+
+	_, err := Load()
+	if err == nil || err.Error() != "invalid config: CONTINGENCY_TYPE must be '03' (issuer) or '04' (DIAN)" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoad_InvalidTracingSampleRatio(t *testing.T) {
+	os.Setenv("TRACING_SAMPLE_RATIO", "1.5")
+	defer os.Unsetenv("TRACING_SAMPLE_RATIO")
+
+	_, err := Load()
+	if err == nil || err.Error() != "invalid config: TRACING_SAMPLE_RATIO must be between 0 and 1" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoad_InvalidAuditRetentionMonths(t *testing.T) {
+	os.Setenv("AUDIT_RETENTION_MONTHS", "-1")
+	defer os.Unsetenv("AUDIT_RETENTION_MONTHS")
+
+	_, err := Load()
+	if err == nil || err.Error() != "invalid config: AUDIT_RETENTION_MONTHS must be 0 (keep everything) or greater" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoad_InvalidAuditCheckpointInterval(t *testing.T) {
+	os.Setenv("AUDIT_CHECKPOINT_INTERVAL", "0s")
+	defer os.Unsetenv("AUDIT_CHECKPOINT_INTERVAL")
+
+	_, err := Load()
+	if err == nil || err.Error() != "invalid config: AUDIT_CHECKPOINT_INTERVAL must be greater than 0" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func TestHTTPSettings_Address(t *testing.T) {
 	settings := HTTPSettings{Port: 8080}
 	addr := settings.Address()
@@ -217,6 +257,36 @@ func TestGetEnvAsInt(t *testing.T) {
 	}
 }
 
+func TestGetEnvAsFloat(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		fallback float64
+		expected float64
+	}{
+		{"valid float", "0.25", 1, 0.25},
+		{"integer", "1", 0, 1},
+		{"invalid value", "half", 0.5, 0.5},
+		{"missing key", "", 0.5, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != "" {
+				os.Setenv("TEST_FLOAT", tt.envValue)
+				defer os.Unsetenv("TEST_FLOAT")
+			} else {
+				os.Unsetenv("TEST_FLOAT")
+			}
+
+			result := getEnvAsFloat("TEST_FLOAT", tt.fallback)
+			if result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestGetEnvAsDuration(t *testing.T) {
 	tests := []struct {
 		name     string
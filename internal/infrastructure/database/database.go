@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"3tcapital/goclonacion/internal/infrastructure/tracing"
 )
 
 //go:embed migrations/*.sql
@@ -24,6 +26,7 @@ type Config struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	Tracing         bool // Record a span for every query executed within a trace
 }
 
 // NewPool creates a new PostgreSQL connection pool.
@@ -45,6 +48,9 @@ func NewPool(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parse connection string: %w", err)
 	}
+	if cfg.Tracing {
+		config.ConnConfig.Tracer = tracing.QueryTracer{}
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
@@ -67,6 +73,24 @@ func RunMigrations(ctx context.Context, pool *pgxpool.Pool, log *slog.Logger) er
 		"migrations/002_create_acquirer_table.sql",
 		"migrations/003_create_provider_table.sql",
 		"migrations/004_make_pro_telefono_nullable.sql",
+		"migrations/005_make_pro_direccion_domicilio_fiscal_nullable.sql",
+		"migrations/006_create_document_ledger.sql",
+		"migrations/007_create_document_batch.sql",
+		"migrations/008_create_idempotency.sql",
+		"migrations/009_create_ofe_table.sql",
+		"migrations/010_create_resoluciones.sql",
+		"migrations/011_create_retenciones.sql",
+		"migrations/012_create_tasas_cambio.sql",
+		"migrations/013_create_divipola.sql",
+		"migrations/014_add_document_ledger_referencia.sql",
+		"migrations/015_create_contingencia.sql",
+		"migrations/016_partition_provider_audit_log.sql",
+		"migrations/017_add_provider_audit_log_hash_chain.sql",
+		"migrations/018_add_idempotency_key_lease.sql",
+		"migrations/019_create_contingencia_estado.sql",
+		"migrations/020_add_document_batch_lease.sql",
+		"migrations/021_encrypt_ofe_numrot_credentials.sql",
+		"migrations/022_add_document_ledger_cufe_calculado.sql",
 	}
 
 	for _, migration := range migrations {
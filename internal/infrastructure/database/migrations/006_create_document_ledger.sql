@@ -0,0 +1,36 @@
+-- Create document ledger table for storing every electronic document registered through the service
+CREATE TABLE IF NOT EXISTS document_ledger (
+    id BIGSERIAL PRIMARY KEY,
+    ofe_identificacion VARCHAR(20) NOT NULL,
+    tipo VARCHAR(2) NOT NULL,
+    prefijo VARCHAR(10) NOT NULL DEFAULT '',
+    consecutivo VARCHAR(20) NOT NULL,
+    estado VARCHAR(20) NOT NULL,
+    cufe VARCHAR(255),
+    cdo_id BIGINT,
+    payload_original JSONB,
+    payload_enriquecido JSONB,
+    xml_base64 TEXT,
+    pdf_base64 TEXT,
+    xml_ref VARCHAR(500),
+    pdf_ref VARCHAR(500),
+    errores JSONB,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT chk_document_ledger_estado CHECK (estado IN ('received', 'validated', 'sent', 'accepted', 'rejected', 'failed'))
+);
+
+-- A document is identified by OFE, type, prefijo and consecutivo
+CREATE UNIQUE INDEX IF NOT EXISTS idx_unique_document_ledger
+ON document_ledger (ofe_identificacion, tipo, prefijo, consecutivo);
+
+-- Create indexes for efficient querying
+CREATE INDEX IF NOT EXISTS idx_document_ledger_cufe ON document_ledger(cufe);
+CREATE INDEX IF NOT EXISTS idx_document_ledger_cdo_id ON document_ledger(cdo_id);
+CREATE INDEX IF NOT EXISTS idx_document_ledger_estado ON document_ledger(estado);
+CREATE INDEX IF NOT EXISTS idx_document_ledger_created_at ON document_ledger(created_at);
+
+-- Add comments for documentation
+COMMENT ON TABLE document_ledger IS 'Ledger of electronic documents registered with DIAN through the invoicing provider';
+COMMENT ON COLUMN document_ledger.estado IS 'Lifecycle: received -> validated -> sent -> accepted/rejected (failed when local validation fails)';
+COMMENT ON COLUMN document_ledger.cufe IS 'CUFE for invoices, CUDE for notes and support documents';
@@ -0,0 +1,26 @@
+-- Create document batch table for asynchronous registration jobs (lotes)
+CREATE TABLE IF NOT EXISTS document_batch (
+    id VARCHAR(36) PRIMARY KEY,
+    estado VARCHAR(20) NOT NULL,
+    tipo_documento VARCHAR(2) NOT NULL,
+    total INTEGER NOT NULL,
+    cursor_posicion INTEGER NOT NULL DEFAULT 0,
+    procesados INTEGER NOT NULL DEFAULT 0,
+    fallidos INTEGER NOT NULL DEFAULT 0,
+    request JSONB NOT NULL,
+    documentos_procesados JSONB NOT NULL DEFAULT '[]'::jsonb,
+    documentos_fallidos JSONB NOT NULL DEFAULT '[]'::jsonb,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    CONSTRAINT chk_document_batch_estado CHECK (estado IN ('pending', 'processing', 'completed', 'failed'))
+);
+
+-- Unfinished batches are resumed on startup
+CREATE INDEX IF NOT EXISTS idx_document_batch_estado ON document_batch(estado);
+CREATE INDEX IF NOT EXISTS idx_document_batch_created_at ON document_batch(created_at);
+
+-- Add comments for documentation
+COMMENT ON TABLE document_batch IS 'Asynchronous document registration batches (POST /documentos/lotes)';
+COMMENT ON COLUMN document_batch.cursor_posicion IS 'Number of documents already processed; processing resumes from this position';
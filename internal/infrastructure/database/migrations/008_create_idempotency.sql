@@ -0,0 +1,21 @@
+-- Store the payload hash of each ledger entry to detect retries of the same document
+ALTER TABLE document_ledger ADD COLUMN IF NOT EXISTS payload_hash VARCHAR(64);
+
+-- Create idempotency key table for whole-batch retries (Idempotency-Key header)
+CREATE TABLE IF NOT EXISTS idempotency_key (
+    scope VARCHAR(50) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    estado VARCHAR(20) NOT NULL,
+    response JSONB,
+    created_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP,
+    PRIMARY KEY (scope, idempotency_key),
+    CONSTRAINT chk_idempotency_key_estado CHECK (estado IN ('in_progress', 'completed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_key_created_at ON idempotency_key(created_at);
+
+-- Add comments for documentation
+COMMENT ON TABLE idempotency_key IS 'Responses of requests sent with an Idempotency-Key header';
+COMMENT ON COLUMN document_ledger.payload_hash IS 'SHA-256 of the original OpenETL payload';
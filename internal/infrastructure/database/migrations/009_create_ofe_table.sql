@@ -0,0 +1,40 @@
+-- Create OFE table for storing the issuers (Oferentes Facturadores Electrónicos)
+CREATE TABLE IF NOT EXISTS ofe (
+    id BIGSERIAL PRIMARY KEY,
+    ofe_identificacion VARCHAR(20) NOT NULL UNIQUE,
+    ofe_razon_social VARCHAR(255) NOT NULL,
+    ofe_nombre_comercial VARCHAR(255),
+    tdo_codigo VARCHAR(10) NOT NULL,
+    toj_codigo VARCHAR(10) NOT NULL,
+    ofe_direccion VARCHAR(255) NOT NULL,
+    pai_codigo VARCHAR(10) NOT NULL DEFAULT 'CO',
+    dep_codigo VARCHAR(10) NOT NULL,
+    dep_nombre VARCHAR(100) NOT NULL,
+    mun_codigo VARCHAR(10) NOT NULL,
+    mun_nombre VARCHAR(100) NOT NULL,
+    cpo_codigo VARCHAR(10),
+    ofe_telefono VARCHAR(50),
+    ofe_correo VARCHAR(255),
+    rfi_codigo VARCHAR(10),
+    ref_codigo JSONB,
+    cdo_ambiente VARCHAR(1),
+    numrot_usuario VARCHAR(255),
+    numrot_password VARCHAR(255),
+    numrot_key VARCHAR(255),
+    numrot_secret VARCHAR(255),
+    estado VARCHAR(20) DEFAULT 'ACTIVO' NOT NULL,
+    fecha_creacion TIMESTAMP DEFAULT NOW(),
+    fecha_modificacion TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT chk_ofe_estado CHECK (estado IN ('ACTIVO', 'INACTIVO')),
+    CONSTRAINT chk_ofe_cdo_ambiente CHECK (cdo_ambiente IS NULL OR cdo_ambiente IN ('1', '2'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ofe_razon_social ON ofe(ofe_razon_social);
+CREATE INDEX IF NOT EXISTS idx_ofe_estado ON ofe(estado);
+
+-- Add comments for documentation
+COMMENT ON TABLE ofe IS 'Issuers (OFE) that emit electronic documents through the service';
+COMMENT ON COLUMN ofe.ofe_identificacion IS 'NIT of the OFE without verification digit';
+COMMENT ON COLUMN ofe.ref_codigo IS 'Array of fiscal responsibility codes';
+COMMENT ON COLUMN ofe.cdo_ambiente IS 'Default environment of the OFE documents: 1 production, 2 test';
+COMMENT ON COLUMN ofe.estado IS 'OFE status: ACTIVO or INACTIVO';
@@ -0,0 +1,35 @@
+-- Create tables for the numbering ranges (resoluciones de facturación) tracked locally
+CREATE TABLE IF NOT EXISTS resoluciones (
+    id BIGSERIAL PRIMARY KEY,
+    ofe_identificacion VARCHAR(20) NOT NULL,
+    resolution_number VARCHAR(50) NOT NULL,
+    resolution_date DATE,
+    prefix VARCHAR(10) NOT NULL DEFAULT '',
+    from_number BIGINT NOT NULL,
+    to_number BIGINT NOT NULL,
+    valid_date_from DATE NOT NULL,
+    valid_date_to DATE NOT NULL,
+    last_number BIGINT NOT NULL DEFAULT 0,
+    used_count BIGINT NOT NULL DEFAULT 0,
+    synced_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_creacion TIMESTAMP DEFAULT NOW(),
+    fecha_modificacion TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT uq_resoluciones UNIQUE (ofe_identificacion, resolution_number, prefix),
+    CONSTRAINT chk_resoluciones_rango CHECK (from_number <= to_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_resoluciones_ofe_prefix ON resoluciones(ofe_identificacion, prefix);
+
+CREATE TABLE IF NOT EXISTS resolucion_consecutivos (
+    resolucion_id BIGINT NOT NULL REFERENCES resoluciones(id) ON DELETE CASCADE,
+    consecutivo BIGINT NOT NULL,
+    fecha_creacion TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (resolucion_id, consecutivo)
+);
+
+-- Add comments for documentation
+COMMENT ON TABLE resoluciones IS 'DIAN numbering ranges of each OFE, refreshed from the invoicing provider';
+COMMENT ON COLUMN resoluciones.ofe_identificacion IS 'NIT of the OFE without verification digit';
+COMMENT ON COLUMN resoluciones.last_number IS 'Highest consecutivo allocated or used; the allocator hands out last_number + 1';
+COMMENT ON COLUMN resoluciones.used_count IS 'Number of distinct consecutivos recorded in resolucion_consecutivos';
+COMMENT ON TABLE resolucion_consecutivos IS 'Consecutivos of each numbering range already allocated or used';
@@ -0,0 +1,49 @@
+-- Create tables for the withholding (retenciones) rules and UVT values
+CREATE TABLE IF NOT EXISTS retencion_reglas (
+    id BIGSERIAL PRIMARY KEY,
+    tipo VARCHAR(20) NOT NULL,
+    concepto VARCHAR(50) NOT NULL DEFAULT '',
+    mun_codigo VARCHAR(10) NOT NULL DEFAULT '',
+    agente_responsabilidades JSONB NOT NULL DEFAULT '[]',
+    exento_responsabilidades JSONB NOT NULL DEFAULT '[]',
+    base_minima_uvt NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    porcentaje NUMERIC(7, 4) NOT NULL,
+    razon VARCHAR(255) NOT NULL,
+    activo BOOLEAN NOT NULL DEFAULT TRUE,
+    fecha_creacion TIMESTAMP DEFAULT NOW(),
+    fecha_modificacion TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT chk_retencion_reglas_tipo CHECK (tipo IN ('RETEFUENTE', 'RETEIVA', 'RETEICA')),
+    CONSTRAINT chk_retencion_reglas_porcentaje CHECK (porcentaje > 0 AND porcentaje <= 100)
+);
+
+CREATE INDEX IF NOT EXISTS idx_retencion_reglas_tipo ON retencion_reglas(tipo);
+
+CREATE TABLE IF NOT EXISTS uvt_valores (
+    anio INTEGER PRIMARY KEY,
+    valor NUMERIC(12, 2) NOT NULL,
+    fecha_modificacion TIMESTAMP DEFAULT NOW()
+);
+
+-- Reference values; review them against the regulations in force before going live
+INSERT INTO uvt_valores (anio, valor) VALUES
+    (2024, 47065),
+    (2025, 49799),
+    (2026, 52374)
+ON CONFLICT (anio) DO NOTHING;
+
+INSERT INTO retencion_reglas (tipo, concepto, agente_responsabilidades, exento_responsabilidades, base_minima_uvt, porcentaje, razon)
+SELECT * FROM (VALUES
+    ('RETEFUENTE', '', '["O-13", "O-23"]'::jsonb, '["O-15"]'::jsonb, 27::numeric, 2.5::numeric, 'Retención en la fuente por compras'),
+    ('RETEFUENTE', 'SERVICIOS', '["O-13", "O-23"]'::jsonb, '["O-15"]'::jsonb, 4::numeric, 4::numeric, 'Retención en la fuente por servicios'),
+    ('RETEIVA', '', '["O-13", "O-23"]'::jsonb, '[]'::jsonb, 4::numeric, 15::numeric, 'Retención de IVA')
+) AS seed(tipo, concepto, agente_responsabilidades, exento_responsabilidades, base_minima_uvt, porcentaje, razon)
+WHERE NOT EXISTS (SELECT 1 FROM retencion_reglas);
+
+-- Add comments for documentation
+COMMENT ON TABLE retencion_reglas IS 'Withholding rates used to compute the suggested retentions of the documents';
+COMMENT ON COLUMN retencion_reglas.concepto IS 'Withholding concept of the document (cdo_concepto_retencion); empty matches any concept';
+COMMENT ON COLUMN retencion_reglas.mun_codigo IS 'DIVIPOLA code of the withholding agent (ReteICA); empty matches any municipality';
+COMMENT ON COLUMN retencion_reglas.agente_responsabilidades IS 'Responsibilities (ref_codigo, responsable_tributos) that make the buyer a withholding agent; empty matches any buyer';
+COMMENT ON COLUMN retencion_reglas.exento_responsabilidades IS 'Responsibilities of the seller that exempt it from the withholding';
+COMMENT ON COLUMN retencion_reglas.base_minima_uvt IS 'Minimum purchase amount, in UVT, from which the withholding applies';
+COMMENT ON TABLE uvt_valores IS 'Value of the Unidad de Valor Tributario per year';
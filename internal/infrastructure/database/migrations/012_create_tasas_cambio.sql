@@ -0,0 +1,15 @@
+-- Create table for the exchange rates (TRM) used by documents in foreign currency
+CREATE TABLE IF NOT EXISTS tasas_cambio (
+    moneda VARCHAR(3) NOT NULL,
+    fecha DATE NOT NULL,
+    valor NUMERIC(18, 6) NOT NULL,
+    fecha_modificacion TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (moneda, fecha),
+    CONSTRAINT chk_tasas_cambio_valor CHECK (valor > 0)
+);
+
+-- Add comments for documentation
+COMMENT ON TABLE tasas_cambio IS 'Exchange rates to COP per currency and date (TRM for USD)';
+COMMENT ON COLUMN tasas_cambio.moneda IS 'ISO 4217 currency code';
+COMMENT ON COLUMN tasas_cambio.fecha IS 'Date from which the rate is in force';
+COMMENT ON COLUMN tasas_cambio.valor IS 'Value in COP of one unit of the currency';
@@ -0,0 +1,17 @@
+-- Create table for the DIVIPOLA catalog loaded by the divipola refresh command.
+-- When it is empty the catalog embedded in the binary is used.
+CREATE TABLE IF NOT EXISTS divipola_municipios (
+    mun_codigo VARCHAR(5) PRIMARY KEY,
+    mun_nombre VARCHAR(255) NOT NULL,
+    dep_codigo VARCHAR(2) NOT NULL,
+    dep_nombre VARCHAR(255) NOT NULL,
+    fecha_actualizacion TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT chk_divipola_dep_codigo CHECK (mun_codigo LIKE dep_codigo || '%')
+);
+
+CREATE INDEX IF NOT EXISTS idx_divipola_municipios_dep_codigo ON divipola_municipios(dep_codigo);
+
+-- Add comments for documentation
+COMMENT ON TABLE divipola_municipios IS 'DIVIPOLA municipalities (DANE), replaces the embedded catalog when not empty';
+COMMENT ON COLUMN divipola_municipios.mun_codigo IS 'DIVIPOLA code of the municipality (5 digits)';
+COMMENT ON COLUMN divipola_municipios.dep_codigo IS 'DIVIPOLA code of the department (2 digits)';
@@ -0,0 +1,8 @@
+-- Store the invoice corrected by credit and debit notes, to add up the notes of an invoice
+ALTER TABLE document_ledger ADD COLUMN IF NOT EXISTS referencia VARCHAR(40);
+
+CREATE INDEX IF NOT EXISTS idx_document_ledger_referencia
+ON document_ledger (ofe_identificacion, tipo, referencia)
+WHERE referencia IS NOT NULL;
+
+COMMENT ON COLUMN document_ledger.referencia IS 'Prefijo and number of the invoice corrected by NC/ND (prefijo_fc + numero_factura_fc)';
@@ -0,0 +1,38 @@
+-- Contingency numbering of each OFE: documents stored in contingency take the next number
+CREATE TABLE IF NOT EXISTS contingencia_numeracion (
+    ofe_identificacion VARCHAR(20) PRIMARY KEY,
+    ultimo_numero BIGINT NOT NULL DEFAULT 0
+);
+
+-- Documents stored while the contingency mode is active, transmitted in id order when the provider recovers
+CREATE TABLE IF NOT EXISTS contingencia_documentos (
+    id BIGSERIAL PRIMARY KEY,
+    ofe_identificacion VARCHAR(20) NOT NULL,
+    tipo VARCHAR(2) NOT NULL,
+    prefijo VARCHAR(10) NOT NULL DEFAULT '',
+    consecutivo VARCHAR(20) NOT NULL,
+    numero VARCHAR(40) NOT NULL,
+    tipo_contingencia VARCHAR(2) NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pending',
+    payload JSONB NOT NULL,
+    intentos INTEGER NOT NULL DEFAULT 0,
+    cufe VARCHAR(255),
+    errors JSONB,
+    created_at TIMESTAMP DEFAULT NOW(),
+    transmitted_at TIMESTAMP,
+    CONSTRAINT uq_contingencia_documentos_numero UNIQUE (ofe_identificacion, numero),
+    CONSTRAINT chk_contingencia_documentos_estado CHECK (estado IN ('pending', 'transmitted', 'failed')),
+    CONSTRAINT chk_contingencia_documentos_tipo_contingencia CHECK (tipo_contingencia IN ('03', '04'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_contingencia_documentos_pending ON contingencia_documentos(id) WHERE estado = 'pending';
+
+-- Documents stored in contingency are tracked in the ledger until they are transmitted
+ALTER TABLE document_ledger DROP CONSTRAINT IF EXISTS chk_document_ledger_estado;
+ALTER TABLE document_ledger ADD CONSTRAINT chk_document_ledger_estado
+    CHECK (estado IN ('received', 'validated', 'sent', 'accepted', 'rejected', 'failed', 'contingency'));
+
+-- Add comments for documentation
+COMMENT ON TABLE contingencia_documentos IS 'Documents stored in contingency mode (types 03/04), pending transmission';
+COMMENT ON COLUMN contingencia_documentos.numero IS 'Number of the OFE contingency numbering (prefix + contingencia_numeracion.ultimo_numero)';
+COMMENT ON COLUMN contingencia_documentos.intentos IS 'Transmission attempts that could not reach the provider';
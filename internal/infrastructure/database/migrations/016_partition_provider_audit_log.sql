@@ -0,0 +1,71 @@
+-- Creates the monthly partition of provider_audit_log that holds the given date
+CREATE OR REPLACE FUNCTION create_provider_audit_log_partition(month DATE) RETURNS TEXT AS $$
+DECLARE
+    start_date DATE := date_trunc('month', month)::DATE;
+    partition_name TEXT := 'provider_audit_log_' || to_char(start_date, 'YYYY_MM');
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE IF NOT EXISTS %I PARTITION OF provider_audit_log FOR VALUES FROM (%L) TO (%L)',
+        partition_name, start_date, (start_date + INTERVAL '1 month')::DATE
+    );
+    RETURN partition_name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Partition provider_audit_log by month so the retention job can drop old months without
+-- deleting rows one by one. Existing rows are copied into their monthly partitions.
+DO $$
+DECLARE
+    month DATE;
+BEGIN
+    IF (SELECT relkind FROM pg_class WHERE oid = to_regclass('provider_audit_log')) = 'r' THEN
+        ALTER TABLE provider_audit_log RENAME TO provider_audit_log_unpartitioned;
+        ALTER TABLE provider_audit_log_unpartitioned RENAME CONSTRAINT provider_audit_log_pkey TO provider_audit_log_unpartitioned_pkey;
+
+        CREATE TABLE provider_audit_log (
+            id BIGINT NOT NULL DEFAULT nextval('provider_audit_log_id_seq'),
+            correlation_id VARCHAR(255) NOT NULL,
+            provider VARCHAR(100) NOT NULL,
+            operation VARCHAR(100) NOT NULL,
+            request_method VARCHAR(10) NOT NULL,
+            request_url TEXT NOT NULL,
+            request_headers JSONB,
+            request_body JSONB,
+            response_status INTEGER,
+            response_headers JSONB,
+            response_body JSONB,
+            duration_ms BIGINT NOT NULL,
+            error_message TEXT,
+            created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+            PRIMARY KEY (id, created_at)
+        ) PARTITION BY RANGE (created_at);
+        ALTER SEQUENCE provider_audit_log_id_seq OWNED BY provider_audit_log.id;
+
+        FOR month IN
+            SELECT DISTINCT date_trunc('month', COALESCE(created_at, NOW()))::DATE FROM provider_audit_log_unpartitioned
+        LOOP
+            PERFORM create_provider_audit_log_partition(month);
+        END LOOP;
+
+        INSERT INTO provider_audit_log (
+            id, correlation_id, provider, operation, request_method, request_url,
+            request_headers, request_body, response_status, response_headers,
+            response_body, duration_ms, error_message, created_at
+        )
+        SELECT id, correlation_id, provider, operation, request_method, request_url,
+               request_headers, request_body, response_status, response_headers,
+               response_body, duration_ms, error_message, COALESCE(created_at, NOW())
+        FROM provider_audit_log_unpartitioned;
+
+        DROP TABLE provider_audit_log_unpartitioned;
+    END IF;
+END $$;
+
+-- The current and next month always have a partition; the retention job keeps creating them ahead
+SELECT create_provider_audit_log_partition(CURRENT_DATE);
+SELECT create_provider_audit_log_partition((CURRENT_DATE + INTERVAL '1 month')::DATE);
+
+CREATE INDEX IF NOT EXISTS idx_correlation_id ON provider_audit_log(correlation_id);
+CREATE INDEX IF NOT EXISTS idx_provider_operation ON provider_audit_log(provider, operation);
+CREATE INDEX IF NOT EXISTS idx_created_at ON provider_audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_response_status ON provider_audit_log(response_status);
@@ -0,0 +1,19 @@
+-- Hash chain of provider_audit_log: every row stores the SHA-256 of its canonical content chained
+-- to the hash of the previous row of the same monthly partition. Rows stored before this migration
+-- keep NULL hashes and are reported as unsealed by the verification.
+ALTER TABLE provider_audit_log ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64);
+ALTER TABLE provider_audit_log ADD COLUMN IF NOT EXISTS hash VARCHAR(64);
+
+-- Signed checkpoints of the chain heads, kept apart from the chained rows
+CREATE TABLE IF NOT EXISTS provider_audit_checkpoint (
+    id BIGSERIAL PRIMARY KEY,
+    chain VARCHAR(7) NOT NULL,
+    last_id BIGINT NOT NULL,
+    last_hash VARCHAR(64) NOT NULL,
+    signature TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_audit_checkpoint_chain ON provider_audit_checkpoint(chain, id);
+
+COMMENT ON TABLE provider_audit_checkpoint IS 'Ed25519-signed checkpoints of the provider_audit_log hash chains';
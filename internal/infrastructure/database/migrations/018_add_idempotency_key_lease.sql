@@ -0,0 +1,5 @@
+-- In-progress reservations are leased; once the lease expires (e.g. the process crashed
+-- mid-request) a retry with the same Idempotency-Key takes the reservation over
+ALTER TABLE idempotency_key ADD COLUMN IF NOT EXISTS lease_until TIMESTAMP;
+
+COMMENT ON COLUMN idempotency_key.lease_until IS 'End of the lease of an in_progress reservation, renewed while the request runs';
@@ -0,0 +1,16 @@
+-- Contingency mode shared by every instance of the service: a single row updated when the
+-- mode is activated or deactivated
+CREATE TABLE IF NOT EXISTS contingencia_estado (
+    id SMALLINT PRIMARY KEY DEFAULT 1,
+    activa BOOLEAN NOT NULL DEFAULT FALSE,
+    tipo_contingencia VARCHAR(2),
+    origen VARCHAR(20),
+    motivo TEXT,
+    desde TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT chk_contingencia_estado_unica CHECK (id = 1)
+);
+
+-- Add comments for documentation
+COMMENT ON TABLE contingencia_estado IS 'Current contingency mode (single row), read by every instance before storing or transmitting documents';
+COMMENT ON COLUMN contingencia_estado.origen IS 'manual (API) or automatica (provider circuit open past the threshold)';
@@ -0,0 +1,5 @@
+-- Batches are claimed with a lease: an instance takes a pending batch, or a processing one
+-- whose lease expired (the instance stopped), and renews the lease while it processes it
+ALTER TABLE document_batch ADD COLUMN IF NOT EXISTS lease_until TIMESTAMP;
+
+COMMENT ON COLUMN document_batch.lease_until IS 'End of the lease of the instance processing the batch, renewed while it runs';
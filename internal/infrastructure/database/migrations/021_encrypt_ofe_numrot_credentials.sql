@@ -0,0 +1,9 @@
+-- The Numrot password and secret of the OFEs are stored encrypted with AES-256-GCM
+-- (NUMROT_CREDENTIALS_KEY), prefixed with enc:v1:. The encrypted values are longer than
+-- the plaintext ones, so the columns become TEXT. Values stored in plaintext before
+-- are encrypted by the service on startup once the key is configured
+ALTER TABLE ofe ALTER COLUMN numrot_password TYPE TEXT;
+ALTER TABLE ofe ALTER COLUMN numrot_secret TYPE TEXT;
+
+COMMENT ON COLUMN ofe.numrot_password IS 'Numrot password, encrypted with NUMROT_CREDENTIALS_KEY (enc:v1:<base64 nonce+ciphertext>)';
+COMMENT ON COLUMN ofe.numrot_secret IS 'Numrot secret, encrypted with NUMROT_CREDENTIALS_KEY (enc:v1:<base64 nonce+ciphertext>)';
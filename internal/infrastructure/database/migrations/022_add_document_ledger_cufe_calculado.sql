@@ -0,0 +1,5 @@
+-- Keep the locally computed CUFE/CUDE when it differs from the one returned by the
+-- provider, so that mismatches can be audited from the ledger
+ALTER TABLE document_ledger ADD COLUMN IF NOT EXISTS cufe_calculado VARCHAR(255);
+
+COMMENT ON COLUMN document_ledger.cufe_calculado IS 'CUFE/CUDE computed locally, only stored when it differs from cufe';
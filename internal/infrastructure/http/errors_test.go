@@ -7,7 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 // failingResponseWriter is a ResponseWriter that can simulate write failures
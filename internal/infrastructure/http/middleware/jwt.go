@@ -12,8 +12,8 @@ import (
 	"github.com/MicahParks/keyfunc/v3"
 	"github.com/golang-jwt/jwt/v5"
 
-	"3tcapital/ms_facturacion_core/internal/infrastructure/config"
-	httperrors "3tcapital/ms_facturacion_core/internal/infrastructure/http"
+	"3tcapital/goclonacion/internal/infrastructure/config"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
 )
 
 // ContextKeyToken exposes the verified JWT token via request context.
@@ -5,8 +5,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
-	"3tcapital/ms_facturacion_core/internal/infrastructure/config"
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	"3tcapital/goclonacion/internal/infrastructure/config"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 func TestNewJWTAuthenticator_AuthDisabled(t *testing.T) {
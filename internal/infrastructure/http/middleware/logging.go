@@ -5,7 +5,7 @@ import (
 	"net/http"
 	"time"
 
-	ctxutil "3tcapital/ms_facturacion_core/internal/infrastructure/context"
+	ctxutil "3tcapital/goclonacion/internal/infrastructure/context"
 
 	chimw "github.com/go-chi/chi/v5/middleware"
 )
@@ -31,6 +31,18 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Flush forwards to the underlying writer so streaming handlers keep working.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // RequestLogger returns a middleware that logs HTTP requests and responses.
 // It logs request details (method, path, IP, user agent, request ID) and
 // response details (status code, duration, bytes written).
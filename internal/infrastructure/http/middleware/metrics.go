@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// unmatchedRoute labels requests that did not match any route, so that
+// arbitrary paths cannot grow the label cardinality.
+const unmatchedRoute = "unmatched"
+
+// HTTPMetrics records the requests handled by the API.
+type HTTPMetrics interface {
+	ObserveHTTPRequest(method, route string, status int, duration time.Duration)
+}
+
+// Metrics returns a middleware that records request counts and latency per
+// route. Requests are labelled with the chi route pattern (e.g.
+// /api/v1/documentos/{id}) rather than the raw path.
+func Metrics(m HTTPMetrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(rw, r)
+
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+			m.ObserveHTTPRequest(r.Method, route, rw.statusCode, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type observedRequest struct {
+	method string
+	route  string
+	status int
+}
+
+type recordingMetrics struct {
+	requests []observedRequest
+}
+
+func (m *recordingMetrics) ObserveHTTPRequest(method, route string, status int, _ time.Duration) {
+	m.requests = append(m.requests, observedRequest{method: method, route: route, status: status})
+}
+
+func TestMetrics_RecordsRoutePattern(t *testing.T) {
+	m := &recordingMetrics{}
+	r := chi.NewRouter()
+	r.Use(Metrics(m))
+	r.Get("/documentos/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/documentos/123", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/otro/456", nil))
+
+	want := []observedRequest{
+		{method: http.MethodGet, route: "/documentos/{id}", status: http.StatusNotFound},
+		{method: http.MethodGet, route: unmatchedRoute, status: http.StatusNotFound},
+	}
+	if len(m.requests) != len(want) {
+		t.Fatalf("expected %d observations, got %d", len(want), len(m.requests))
+	}
+	for i := range want {
+		if m.requests[i] != want[i] {
+			t.Errorf("observation %d: expected %+v, got %+v", i, want[i], m.requests[i])
+		}
+	}
+}
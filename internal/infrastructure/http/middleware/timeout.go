@@ -3,8 +3,9 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"time"
 
-	"3tcapital/ms_facturacion_core/internal/infrastructure/config"
+	"3tcapital/goclonacion/internal/infrastructure/config"
 )
 
 // ExtendedTimeout wraps a handler to apply an extended timeout for massive operations.
@@ -14,12 +15,14 @@ func ExtendedTimeout(cfg config.HTTPSettings) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Create context with extended timeout for massive operations
-			// Note: This extends the context timeout, but the server's WriteTimeout
-			// still applies. Streaming with periodic flushes is the primary solution
-			// to prevent WriteTimeout issues.
 			ctx, cancel := context.WithTimeout(r.Context(), cfg.WriteTimeoutMassive)
 			defer cancel()
 
+			// Extend the connection write deadline as well, otherwise the server's
+			// WriteTimeout would still cut the response short. Writers that don't
+			// support deadlines (e.g. test recorders) are left untouched.
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(cfg.WriteTimeoutMassive))
+
 			// Use the extended timeout context
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
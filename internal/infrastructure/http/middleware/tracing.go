@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"3tcapital/goclonacion/internal/infrastructure/tracing"
+)
+
+// Tracing returns a middleware that records a server span for every request. The
+// trace of the caller is continued when the request carries a W3C traceparent header.
+// It must run after RequestLogger so that the span gets the correlation ID.
+func Tracing() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := otel.Tracer(tracing.ScopeName).Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			rw := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			// The route is known once chi has matched the request
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					span.SetName(r.Method + " " + pattern)
+					span.SetAttributes(attribute.String("http.route", pattern))
+				}
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", rw.statusCode))
+			// Client errors (4xx) are not failures of the server span
+			if rw.statusCode >= http.StatusInternalServerError {
+				tracing.RecordError(span, fmt.Errorf("HTTP status %d", rw.statusCode))
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"3tcapital/goclonacion/internal/infrastructure/tracing"
+	"3tcapital/goclonacion/internal/testutil"
+)
+
+func TestTracing_RecordsServerSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(exporter, 1)
+	previous, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	tracing.Install(provider)
+	defer func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(propagator)
+	}()
+
+	var handlerSpan trace.SpanContext
+	r := chi.NewRouter()
+	r.Use(chimw.RequestID)
+	r.Use(RequestLogger(testutil.NewNullLogger()))
+	r.Use(Tracing())
+	r.Get("/documentos/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/documentos/123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := provider.ForceFlush(req.Context()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /documentos/{id}" || span.SpanKind != trace.SpanKindServer {
+		t.Errorf("unexpected span %q kind %v", span.Name, span.SpanKind)
+	}
+	if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the trace of the caller to be continued, got %+v", span.SpanContext)
+	}
+	if !handlerSpan.Equal(span.SpanContext) {
+		t.Error("expected the server span in the handler context")
+	}
+	attrs := map[string]any{}
+	for _, attr := range span.Attributes {
+		attrs[string(attr.Key)] = attr.Value.AsInterface()
+	}
+	if attrs["http.response.status_code"] != int64(http.StatusBadGateway) || span.Status.Code != codes.Error {
+		t.Errorf("expected the 5xx status recorded as an error, got %+v", span)
+	}
+	if _, ok := attrs["correlation_id"]; !ok {
+		t.Error("expected the correlation ID attribute")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"path"
@@ -14,20 +15,119 @@ import (
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
+
+	"3tcapital/goclonacion/internal/infrastructure/config"
+	httperrors "3tcapital/goclonacion/internal/infrastructure/http"
+	"3tcapital/goclonacion/internal/infrastructure/http/middleware"
 )
 
-// Server expone únicamente los endpoints solicitados de clonación.
+// Server expone los endpoints de clonación y de facturación electrónica.
 type Server struct {
 	log        *slog.Logger
 	httpServer *http.Server
 	db         *sql.DB
+	auth       *middleware.JWTAuthenticator
+	cfg        config.HTTPSettings
 }
 
-// Options de construcción mínimos.
+// Options de construcción del servidor.
+// Los handlers de facturación son opcionales: si alguno es nil, la ruta
+// correspondiente responde 503 hasta que su dependencia esté configurada.
 type Options struct {
-	Addr   string
+	Config config.AppConfig
 	Logger *slog.Logger
 	DB     *sql.DB
+	// Addr sobrescribe la dirección derivada de Config.HTTP.Port.
+	Addr string
+
+	HealthHandler http.Handler
+
+	// Métricas Prometheus: Metrics registra cada solicitud por ruta y
+	// MetricsHandler expone GET /metrics sin autenticación, como /health.
+	Metrics        middleware.HTTPMetrics
+	MetricsHandler http.Handler
+
+	// Facturación
+	ResolutionHandler        http.Handler
+	InvoiceHandler           http.Handler
+	InvoiceByNumberHandler   http.Handler
+	ReceivedInvoiceHandler   http.Handler
+	DownloadPDFHandler       http.Handler
+	DownloadPDFNumrotHandler http.Handler
+	RegisterDocumentHandler  http.Handler
+	EventHandler             http.Handler
+
+	// Numeración (resoluciones y consecutivos)
+	ListResolutionRangesHandler http.Handler
+	AllocateConsecutivoHandler  http.Handler
+	ResolutionAlertsHandler     http.Handler
+
+	// Adquirentes
+	CreateAcquirerHandler http.Handler
+	UpdateAcquirerHandler http.Handler
+	ListAcquirersHandler  http.Handler
+	SearchAcquirerHandler http.Handler
+
+	// Proveedores
+	CreateProviderHandler http.Handler
+	UpdateProviderHandler http.Handler
+	ListProvidersHandler  http.Handler
+	SearchProviderHandler http.Handler
+
+	// Registro de OFEs (emisores)
+	ListOFEsHandler  http.Handler
+	GetOFEHandler    http.Handler
+	CreateOFEHandler http.Handler
+	UpdateOFEHandler http.Handler
+
+	// Retenciones sugeridas (reglas y UVT)
+	ListWithholdingRulesHandler  http.Handler
+	CreateWithholdingRuleHandler http.Handler
+	UpdateWithholdingRuleHandler http.Handler
+	DeleteWithholdingRuleHandler http.Handler
+	ListUVTHandler               http.Handler
+	SetUVTHandler                http.Handler
+
+	// Tasas de cambio (TRM) para documentos en moneda extranjera
+	ListExchangeRatesHandler http.Handler
+	SetExchangeRateHandler   http.Handler
+
+	// Catálogos de referencia (DIVIPOLA y listas de códigos DIAN)
+	ListDepartmentsHandler      http.Handler
+	SearchMunicipalitiesHandler http.Handler
+	GetMunicipalityHandler      http.Handler
+	GetCodeListHandler          http.Handler
+
+	// Libro de documentos registrados
+	ListDocumentsHandler http.Handler
+	GetDocumentHandler   http.Handler
+
+	// Previsualización de XML UBL
+	PreviewDocumentHandler http.Handler
+
+	// Validación de documentos sin envío
+	ValidateDocumentsHandler http.Handler
+
+	// Lotes asíncronos de registro
+	CreateBatchHandler http.Handler
+	GetBatchHandler    http.Handler
+
+	// Modo de contingencia
+	ContingencyDashboardHandler  http.Handler
+	ActivateContingencyHandler   http.Handler
+	DeactivateContingencyHandler http.Handler
+	TransmitContingencyHandler   http.Handler
+
+	// Auditoría de llamadas a proveedores (consulta, exportación NDJSON y
+	// verificación de la cadena de hashes)
+	SearchAuditLogsHandler   http.Handler
+	ExportAuditLogsHandler   http.Handler
+	VerifyAuditChainsHandler http.Handler
+
+	// Recepción
+	ReceptionRegistrarEventoHandler    http.Handler
+	ReceptionListarDocumentosHandler   http.Handler
+	ReceptionConsultaDocumentosHandler http.Handler
 }
 
 // New crea el servidor con los endpoints requeridos.
@@ -35,20 +135,119 @@ func New(opts Options) (*Server, error) {
 	if opts.Logger == nil {
 		return nil, errors.New("logger is required")
 	}
+	if opts.HealthHandler == nil {
+		return nil, errors.New("health handler is required")
+	}
 	if opts.Addr == "" {
-		opts.Addr = ":8080"
+		opts.Addr = opts.Config.HTTP.Address()
+	}
+
+	auth, err := middleware.NewJWTAuthenticator(opts.Config.Auth, opts.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("create jwt authenticator: %w", err)
 	}
 
 	r := chi.NewRouter()
 	r.Use(chimw.RequestID)
 	r.Use(chimw.RealIP)
+	r.Use(middleware.RequestLogger(opts.Logger))
+	if opts.Metrics != nil {
+		r.Use(middleware.Metrics(opts.Metrics))
+	}
+	if opts.Config.Tracing.Enabled {
+		r.Use(middleware.Tracing())
+	}
 	r.Use(chimw.Recoverer)
 
 	// Health
-	r.Method(http.MethodGet, "/health", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	}))
+	r.Method(http.MethodGet, "/health", opts.HealthHandler)
+
+	// Métricas
+	if opts.MetricsHandler != nil {
+		r.Method(http.MethodGet, "/metrics", opts.MetricsHandler)
+	}
+
+	// Facturación electrónica (protegida con JWT)
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware)
+
+		// Operaciones masivas con timeout extendido
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.ExtendedTimeout(opts.Config.HTTP))
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/registrar-documentos", opts.RegisterDocumentHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/contingencia/transmitir", opts.TransmitContingencyHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/auditoria/exportar", opts.ExportAuditLogsHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/auditoria/verificar", opts.VerifyAuditChainsHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			if opts.Config.HTTP.WriteTimeout > 0 {
+				r.Use(chimw.Timeout(opts.Config.HTTP.WriteTimeout))
+			}
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/configuracion/lista-resoluciones-facturacion", opts.ResolutionHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/resoluciones/alertas", opts.ResolutionAlertsHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/resoluciones/{ofeIdentificacion}", opts.ListResolutionRangesHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/resoluciones/{ofeIdentificacion}/consecutivos", opts.AllocateConsecutivoHandler)
+
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/facturas", opts.InvoiceHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/facturas/by-number", opts.InvoiceByNumberHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/facturas/received", opts.ReceivedInvoiceHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/facturas/download", opts.DownloadPDFHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/facturas/download", opts.DownloadPDFHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/facturas/descargar-pdf", opts.DownloadPDFNumrotHandler)
+
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/eventos", opts.EventHandler)
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/adquirentes", opts.ListAcquirersHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/adquirentes", opts.CreateAcquirerHandler)
+			mount(r, opts.Logger, http.MethodPut, "/api/v1/adquirentes/{ofeIdentificacion}/{adqIdentificacion}", opts.UpdateAcquirerHandler)
+			mount(r, opts.Logger, http.MethodPut, "/api/v1/adquirentes/{ofeIdentificacion}/{adqIdentificacion}/{adqIdPersonalizado}", opts.UpdateAcquirerHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/adquirentes/busqueda/{campoBuscar}/valor/{valorBuscar}/ofe/{valorOfe}/filtro/{filtroColumnas}", opts.SearchAcquirerHandler)
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/proveedores", opts.ListProvidersHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/proveedores", opts.CreateProviderHandler)
+			mount(r, opts.Logger, http.MethodPut, "/api/v1/proveedores/{ofeIdentificacion}/{proIdentificacion}", opts.UpdateProviderHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/proveedores/busqueda/{campoBuscar}/valor/{valorBuscar}/ofe/{valorOfe}/filtro/{filtroColumnas}", opts.SearchProviderHandler)
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/ofes", opts.ListOFEsHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/ofes", opts.CreateOFEHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/ofes/{ofeIdentificacion}", opts.GetOFEHandler)
+			mount(r, opts.Logger, http.MethodPut, "/api/v1/ofes/{ofeIdentificacion}", opts.UpdateOFEHandler)
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/retenciones/reglas", opts.ListWithholdingRulesHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/retenciones/reglas", opts.CreateWithholdingRuleHandler)
+			mount(r, opts.Logger, http.MethodPut, "/api/v1/retenciones/reglas/{id}", opts.UpdateWithholdingRuleHandler)
+			mount(r, opts.Logger, http.MethodDelete, "/api/v1/retenciones/reglas/{id}", opts.DeleteWithholdingRuleHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/retenciones/uvt", opts.ListUVTHandler)
+			mount(r, opts.Logger, http.MethodPut, "/api/v1/retenciones/uvt/{anio}", opts.SetUVTHandler)
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/tasas-cambio/{moneda}", opts.ListExchangeRatesHandler)
+			mount(r, opts.Logger, http.MethodPut, "/api/v1/tasas-cambio/{moneda}/{fecha}", opts.SetExchangeRateHandler)
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/catalogos/departamentos", opts.ListDepartmentsHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/catalogos/municipios", opts.SearchMunicipalitiesHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/catalogos/municipios/{codigo}", opts.GetMunicipalityHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/catalogos/{lista}", opts.GetCodeListHandler)
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/documentos", opts.ListDocumentsHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/documentos/lotes", opts.CreateBatchHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/documentos/lotes/{id}", opts.GetBatchHandler)
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/documentos/{ofe}/{tipo}/{prefijo}/{consecutivo}", opts.GetDocumentHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/documentos/previsualizar-xml", opts.PreviewDocumentHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/documentos/validar", opts.ValidateDocumentsHandler)
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/contingencia", opts.ContingencyDashboardHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/contingencia/activar", opts.ActivateContingencyHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/contingencia/desactivar", opts.DeactivateContingencyHandler)
+
+			mount(r, opts.Logger, http.MethodGet, "/api/v1/auditoria", opts.SearchAuditLogsHandler)
+
+			mount(r, opts.Logger, http.MethodPost, "/api/recepcion/documentos/registrar-evento", opts.ReceptionRegistrarEventoHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/recepcion/documentos/listar-documentos", opts.ReceptionListarDocumentosHandler)
+			mount(r, opts.Logger, http.MethodPost, "/api/v1/recepcion/documentos/consulta-documentos", opts.ReceptionConsultaDocumentosHandler)
+		})
+	})
 
 	// Ejecutar job de alertas manual
 	r.Method(http.MethodPost, "/admin/clonaciones/alertas/run", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -772,12 +971,27 @@ func New(opts Options) (*Server, error) {
 	srv := &http.Server{
 		Addr:         opts.Addr,
 		Handler:      r,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		ReadTimeout:  opts.Config.HTTP.ReadTimeout,
+		WriteTimeout: opts.Config.HTTP.WriteTimeout,
+		IdleTimeout:  opts.Config.HTTP.IdleTimeout,
+	}
+
+	return &Server{log: opts.Logger, httpServer: srv, db: opts.DB, auth: auth, cfg: opts.Config.HTTP}, nil
+}
+
+// mount registra un handler o, si no está configurado, una respuesta 503.
+func mount(r chi.Router, log *slog.Logger, method, pattern string, handler http.Handler) {
+	if handler == nil {
+		handler = unavailable(log)
 	}
+	r.Method(method, pattern, handler)
+}
 
-	return &Server{log: opts.Logger, httpServer: srv, db: opts.DB}, nil
+// unavailable responde 503 para servicios cuyas dependencias no están configuradas.
+func unavailable(log *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		httperrors.WriteError(w, http.StatusServiceUnavailable, "Servicio No Disponible", []string{"El servicio no está configurado"}, log)
+	})
 }
 
 // Run arranca el servidor hasta que el contexto se cancele.
@@ -794,15 +1008,25 @@ func (s *Server) Run(ctx context.Context) error {
 
 	select {
 	case <-ctx.Done():
-		_ = s.httpServer.Shutdown(context.Background())
+		shutdownCtx := context.Background()
+		if s.cfg.ShutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.cfg.ShutdownTimeout)
+			defer cancel()
+		}
+		_ = s.httpServer.Shutdown(shutdownCtx)
 		return nil
 	case err := <-errCh:
 		return err
 	}
 }
 
-// Close cierra recursos (no-op).
-func (s *Server) Close() {}
+// Close libera los recursos del servidor (refresco de JWKS).
+func (s *Server) Close() {
+	if s.auth != nil {
+		s.auth.Close()
+	}
+}
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
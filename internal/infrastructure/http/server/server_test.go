@@ -4,11 +4,13 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/infrastructure/config"
-	"3tcapital/ms_facturacion_core/internal/testutil"
+	"3tcapital/goclonacion/internal/infrastructure/config"
+	"3tcapital/goclonacion/internal/infrastructure/metrics"
+	"3tcapital/goclonacion/internal/testutil"
 )
 
 func TestNew_NilLogger(t *testing.T) {
@@ -376,3 +378,50 @@ func TestNew_WithoutInvoiceHandler(t *testing.T) {
 		t.Errorf("expected status 503, got %d", w.Code)
 	}
 }
+
+func TestServer_MetricsEndpoint(t *testing.T) {
+	m := metrics.New()
+	server, err := New(Options{
+		Config: config.AppConfig{HTTP: config.HTTPSettings{Port: 8080}},
+		Logger: testutil.NewTestLogger(),
+		HealthHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+		Metrics:        m,
+		MetricsHandler: m.Handler(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server.httpServer.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	want := `goclonacion_http_requests_total{method="GET",route="/health",status="200"} 1`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected %q in metrics, got:\n%s", want, w.Body.String())
+	}
+}
+
+func TestServer_WithoutMetricsHandler(t *testing.T) {
+	server, err := New(Options{
+		Config:        config.AppConfig{HTTP: config.HTTPSettings{Port: 8080}},
+		Logger:        testutil.NewTestLogger(),
+		HealthHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 when metrics are disabled, got %d", w.Code)
+	}
+}
@@ -10,9 +10,15 @@ import (
 	"strings"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/audit"
-	ctxutil "3tcapital/ms_facturacion_core/internal/infrastructure/context"
-	"3tcapital/ms_facturacion_core/internal/infrastructure/security"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"3tcapital/goclonacion/internal/core/audit"
+	ctxutil "3tcapital/goclonacion/internal/infrastructure/context"
+	"3tcapital/goclonacion/internal/infrastructure/security"
+	"3tcapital/goclonacion/internal/infrastructure/tracing"
 )
 
 // TracedClient wraps an HTTP client to provide comprehensive request/response tracing.
@@ -26,6 +32,12 @@ type TracedClient struct {
 	logReqBody   bool
 	logRespBody  bool
 	maxBodySize  int
+	metrics      ProviderMetrics
+}
+
+// ProviderMetrics records the requests sent to a provider.
+type ProviderMetrics interface {
+	ObserveProviderRequest(provider, operation string, status int, err error, duration time.Duration)
 }
 
 // TracedClientConfig holds configuration for the traced HTTP client.
@@ -87,6 +99,12 @@ func NewTracedClient(cfg *TracedClientConfig, log *slog.Logger, auditRepo audit.
 	}
 }
 
+// WithMetrics records the count, latency and errors of every request per operation.
+func (c *TracedClient) WithMetrics(m ProviderMetrics) *TracedClient {
+	c.metrics = m
+	return c
+}
+
 // Do executes an HTTP request with full tracing and audit capabilities.
 // It captures request/response details, sanitizes sensitive data, and persists audit logs.
 func (c *TracedClient) Do(req *http.Request) (*http.Response, error) {
@@ -95,6 +113,20 @@ func (c *TracedClient) Do(req *http.Request) (*http.Response, error) {
 	operation := c.extractOperation(req)
 	start := time.Now()
 
+	// Client span of the provider call, propagated to the provider with traceparent
+	ctx, span := otel.Tracer(tracing.ScopeName).Start(ctx, c.provider+" "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("provider", c.provider),
+			attribute.String("provider.operation", operation),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
 	// Add correlation ID header for downstream tracing
 	if correlationID != "" {
 		req.Header.Set("X-Correlation-ID", correlationID)
@@ -133,6 +165,20 @@ func (c *TracedClient) Do(req *http.Request) (*http.Response, error) {
 	// Log response
 	c.logResponse(ctx, correlationID, operation, req, resp, err, duration, responseBody)
 
+	if err != nil {
+		tracing.RecordError(span, err)
+	} else {
+		tracing.RecordResponseStatus(span, resp.StatusCode)
+	}
+
+	if c.metrics != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		c.metrics.ObserveProviderRequest(c.provider, operation, status, err, duration)
+	}
+
 	// Persist audit log asynchronously (don't block on audit failures)
 	if c.auditEnabled && c.auditRepo != nil {
 		// Ensure we have a correlation ID for audit tracking
@@ -10,8 +10,13 @@ import (
 	"testing"
 	"time"
 
-	"3tcapital/ms_facturacion_core/internal/core/audit"
-	ctxutil "3tcapital/ms_facturacion_core/internal/infrastructure/context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"3tcapital/goclonacion/internal/core/audit"
+	ctxutil "3tcapital/goclonacion/internal/infrastructure/context"
+	"3tcapital/goclonacion/internal/infrastructure/tracing"
 )
 
 // mockAuditRepo is a mock implementation of audit.Repository for testing.
@@ -308,3 +313,91 @@ func TestTracedClient_AuditLogWithImmediatelyCancelledContext(t *testing.T) {
 		}
 	}
 }
+
+type recordingProviderMetrics struct {
+	provider  string
+	operation string
+	status    int
+	err       error
+}
+
+func (m *recordingProviderMetrics) ObserveProviderRequest(provider, operation string, status int, err error, _ time.Duration) {
+	m.provider, m.operation, m.status, m.err = provider, operation, status, err
+}
+
+func TestTracedClient_WithMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	m := &recordingProviderMetrics{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := NewTracedClient(&TracedClientConfig{}, log, nil, "numrot").WithMetrics(m)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/registrarDocumentos", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if m.provider != "numrot" || m.operation != "RegistrarDocumentos" || m.status != http.StatusBadGateway || m.err != nil {
+		t.Errorf("unexpected observation: %+v", m)
+	}
+}
+
+func TestTracedClient_RecordsClientSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(exporter, 1)
+	previous, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	tracing.Install(provider)
+	defer func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(propagator)
+	}()
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := NewTracedClient(&TracedClientConfig{}, log, nil, "numrot")
+
+	ctx := ctxutil.WithCorrelationID(context.Background(), "corr-456")
+	ctx, parent := otel.Tracer("test").Start(ctx, "invoice.RegisterDocument")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/api/registrarDocumentos", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "numrot RegistrarDocumentos" || span.SpanKind != trace.SpanKindClient || span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("unexpected provider span %+v", span)
+	}
+	correlationID := ""
+	for _, attr := range span.Attributes {
+		if attr.Key == "correlation_id" {
+			correlationID = attr.Value.AsString()
+		}
+	}
+	if correlationID != "corr-456" {
+		t.Errorf("expected the correlation ID attribute, got %q", correlationID)
+	}
+	if traceparent != "00-"+span.SpanContext.TraceID().String()+"-"+span.SpanContext.SpanID().String()+"-01" {
+		t.Errorf("expected the provider span propagated, got %q", traceparent)
+	}
+}
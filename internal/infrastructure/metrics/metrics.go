@@ -0,0 +1,127 @@
+// Package metrics records the service metrics with the Prometheus client library
+// and exposes them for Prometheus scrapes.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric exposed by the service.
+const namespace = "goclonacion"
+
+// Document results recorded by ObserveDocuments.
+const (
+	resultProcessed   = "processed"
+	resultContingency = "contingency"
+	resultFailed      = "failed"
+)
+
+var (
+	// httpBuckets covers API latencies, from cached lookups to large batches.
+	httpBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	// providerBuckets covers provider calls, which may wait on retries and slow DIAN validations.
+	providerBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+)
+
+// Metrics groups the metrics recorded by the service and the registry that exposes them.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	providerRequests *prometheus.CounterVec
+	providerErrors   *prometheus.CounterVec
+	providerDuration *prometheus.HistogramVec
+	documents        *prometheus.CounterVec
+}
+
+// New creates the service metrics on a new registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency in seconds, by method and route.",
+			Buckets:   httpBuckets,
+		}, []string{"method", "route"}),
+		providerRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "provider_requests_total",
+			Help:      "Requests sent to invoicing providers, by provider, operation and status code.",
+		}, []string{"provider", "operation", "status"}),
+		providerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "provider_errors_total",
+			Help:      "Provider requests that failed at the transport level or returned a 5xx status.",
+		}, []string{"provider", "operation"}),
+		providerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "provider_request_duration_seconds",
+			Help:      "Provider request latency in seconds, by provider and operation.",
+			Buckets:   providerBuckets,
+		}, []string{"provider", "operation"}),
+		documents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "documents_total",
+			Help:      "Documents received for registration, by document type and result.",
+		}, []string{"type", "result"}),
+	}
+	m.registry.MustRegister(m.httpRequests, m.httpDuration, m.providerRequests, m.providerErrors, m.providerDuration, m.documents)
+	return m
+}
+
+// Registry returns the registry holding the metrics, to register additional collectors.
+func (m *Metrics) Registry() prometheus.Registerer {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest records a request handled by the API.
+// route is the matched route pattern, never the raw path, to keep label cardinality bounded.
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveProviderRequest records a request sent to a provider.
+// status is zero when the request failed before a response was received.
+func (m *Metrics) ObserveProviderRequest(provider, operation string, status int, err error, duration time.Duration) {
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(status)
+	}
+	m.providerRequests.WithLabelValues(provider, operation, code).Inc()
+	m.providerDuration.WithLabelValues(provider, operation).Observe(duration.Seconds())
+	if err != nil || status >= http.StatusInternalServerError {
+		m.providerErrors.WithLabelValues(provider, operation).Inc()
+	}
+}
+
+// ObserveDocuments records the results of a registration request: documents accepted by
+// the provider, stored in contingency and failed, for a document type (FC, NC, ND, DS).
+func (m *Metrics) ObserveDocuments(documentType string, processed, contingency, failed int) {
+	for result, count := range map[string]int{
+		resultProcessed:   processed,
+		resultContingency: contingency,
+		resultFailed:      failed,
+	} {
+		if count > 0 {
+			m.documents.WithLabelValues(documentType, result).Add(float64(count))
+		}
+	}
+}
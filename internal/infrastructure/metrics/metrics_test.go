@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics_ObserveProviderRequest(t *testing.T) {
+	m := New()
+
+	m.ObserveProviderRequest("numrot", "RegistrarDocumentos", 200, nil, 100*time.Millisecond)
+	m.ObserveProviderRequest("numrot", "RegistrarDocumentos", 503, nil, time.Second)
+	m.ObserveProviderRequest("numrot", "RegistrarDocumentos", 0, errors.New("timeout"), time.Second)
+
+	if got := testutil.ToFloat64(m.providerRequests.WithLabelValues("numrot", "RegistrarDocumentos", "200")); got != 1 {
+		t.Errorf("expected 1 successful request, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.providerRequests.WithLabelValues("numrot", "RegistrarDocumentos", "error")); got != 1 {
+		t.Errorf("expected 1 transport error, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.providerErrors.WithLabelValues("numrot", "RegistrarDocumentos")); got != 2 {
+		t.Errorf("expected 5xx and transport failures counted as errors, got %v", got)
+	}
+}
+
+func TestMetrics_ObserveDocuments(t *testing.T) {
+	m := New()
+
+	m.ObserveDocuments("FC", 3, 0, 0)
+	m.ObserveDocuments("NC", 0, 2, 1)
+
+	if got := testutil.ToFloat64(m.documents.WithLabelValues("FC", resultProcessed)); got != 3 {
+		t.Errorf("expected 3 processed invoices, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.documents.WithLabelValues("FC", resultFailed)); got != 0 {
+		t.Errorf("expected no failed invoices, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.documents.WithLabelValues("NC", resultContingency)); got != 2 {
+		t.Errorf("expected 2 credit notes in contingency, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.documents.WithLabelValues("NC", resultFailed)); got != 1 {
+		t.Errorf("expected 1 failed credit note, got %v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EncryptedPrefix marks the values written by SecretCipher, so that values stored
+// before encryption was enabled can be told apart and migrated.
+const EncryptedPrefix = "enc:v1:"
+
+// SecretCipher encrypts secrets stored in the database with AES-256-GCM.
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSecretCipher creates a cipher from a base64 encoded 32-byte key.
+func NewSecretCipher(encodedKey string) (*SecretCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return nil, fmt.Errorf("decode encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes (AES-256)")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create GCM: %w", err)
+	}
+	return &SecretCipher{aead: aead}, nil
+}
+
+// IsEncrypted reports whether value was produced by Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// Encrypt seals plaintext with a random nonce. The result is the prefix followed by
+// the base64 encoded nonce and ciphertext.
+func (c *SecretCipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. It fails when the value is not
+// encrypted, was encrypted with another key or was tampered with.
+func (c *SecretCipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("decode encrypted value: %w", err)
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("encrypted value too short")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("decrypt value: wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,87 @@
+package security
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestNewSecretCipher(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"valid key", testKey(1), false},
+		{"not base64", "not-a-key!", true},
+		{"short key", base64.StdEncoding.EncodeToString([]byte("short")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSecretCipher(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSecretCipher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCipher_RoundTrip(t *testing.T) {
+	c, err := NewSecretCipher(testKey(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	encrypted, err := c.Encrypt("clave-numrot")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !IsEncrypted(encrypted) || strings.Contains(encrypted, "clave-numrot") {
+		t.Fatalf("expected an encrypted value, got %s", encrypted)
+	}
+	if again, _ := c.Encrypt("clave-numrot"); again == encrypted {
+		t.Error("expected a random nonce per value")
+	}
+
+	decrypted, err := c.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decrypted != "clave-numrot" {
+		t.Errorf("expected clave-numrot, got %s", decrypted)
+	}
+}
+
+func TestSecretCipher_DecryptErrors(t *testing.T) {
+	c, _ := NewSecretCipher(testKey(1))
+	other, _ := NewSecretCipher(testKey(2))
+	encrypted, _ := c.Encrypt("secreto")
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"plaintext", "secreto"},
+		{"other key", encrypted},
+		{"tampered", encrypted[:len(encrypted)-4] + "AAAA"},
+		{"too short", EncryptedPrefix + "AAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decrypter := c
+			if tt.name == "other key" {
+				decrypter = other
+			}
+			if _, err := decrypter.Decrypt(tt.value); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
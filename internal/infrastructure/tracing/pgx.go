@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxQueryLength bounds the query text recorded in the db.query.text attribute.
+const maxQueryLength = 2000
+
+// QueryTracer is a pgx.QueryTracer that records a client span for every query
+// executed within a trace. Queries outside a trace, such as those of the background
+// jobs, are not recorded so that each of them does not start a new trace.
+type QueryTracer struct{}
+
+var _ pgx.QueryTracer = QueryTracer{}
+
+// querySpanKey holds the span of the query, so that TraceQueryEnd never ends a span
+// of the caller.
+type querySpanKey struct{}
+
+// TraceQueryStart starts the span of a query.
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	query := strings.TrimSpace(data.SQL)
+	if len(query) > maxQueryLength {
+		query = query[:maxQueryLength]
+	}
+	ctx, span := otel.Tracer(ScopeName).Start(ctx, queryOperation(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.query.text", query),
+		),
+	)
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+// TraceQueryEnd ends the span started by TraceQueryStart.
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(querySpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if data.Err != nil {
+		RecordError(span, data.Err)
+	} else {
+		span.SetAttributes(attribute.Int64("db.response.rows", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
+
+// queryOperation names the span after the SQL command of the query (SELECT, INSERT...).
+func queryOperation(query string) string {
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+	return "QUERY"
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestQueryTracer(t *testing.T) {
+	provider, exporter := installProvider(t, 1)
+	tracer := QueryTracer{}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "document.List")
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "  select * from document_ledger where id = $1"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 3")})
+	failedCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "INSERT INTO document_ledger VALUES ($1)"})
+	tracer.TraceQueryEnd(failedCtx, nil, pgx.TraceQueryEndData{Err: errors.New("duplicate key")})
+	parent.End()
+
+	spans := flushed(t, provider, exporter)
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	query := spans[0]
+	if query.Name != "SELECT" || query.SpanKind != trace.SpanKindClient || query.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("unexpected query span %+v", query)
+	}
+	if v, _ := attributeValue(query, "db.response.rows"); v.AsInt64() != 3 {
+		t.Errorf("expected the affected rows, got %v", v.Emit())
+	}
+	if spans[1].Name != "INSERT" || spans[1].Status.Code != codes.Error {
+		t.Errorf("expected the failed insert recorded, got %+v", spans[1])
+	}
+}
+
+func TestQueryTracer_OutsideTrace(t *testing.T) {
+	provider, exporter := installProvider(t, 1)
+	tracer := QueryTracer{}
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	if spans := flushed(t, provider, exporter); len(spans) != 0 {
+		t.Errorf("expected queries outside a trace not recorded, got %d spans", len(spans))
+	}
+}
@@ -0,0 +1,88 @@
+// Package tracing configures OpenTelemetry: the SDK tracer provider exporting with
+// OTLP/HTTP and the W3C Trace Context propagator. It also instruments the outgoing
+// HTTP requests and the database queries.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	ctxutil "3tcapital/goclonacion/internal/infrastructure/context"
+)
+
+// ScopeName is the instrumentation scope of the spans recorded by this package.
+const ScopeName = "3tcapital/goclonacion/internal/infrastructure/tracing"
+
+// NewOTLPExporter creates an exporter for the collector at endpoint, e.g.
+// http://otel-collector:4318. The /v1/traces path is added when missing.
+func NewOTLPExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	url := strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(url))
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// NewProvider creates a tracer provider that records sampleRatio (0 to 1) of the new
+// traces and exports their spans in batches. Traces started by another service follow
+// the sampling decision of their traceparent. attrs are reported as resource attributes
+// of every span.
+func NewProvider(exporter sdktrace.SpanExporter, sampleRatio float64, attrs ...attribute.KeyValue) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+		sdktrace.WithSpanProcessor(correlationProcessor{}),
+		sdktrace.WithBatcher(exporter),
+	)
+}
+
+// Install makes provider the global tracer provider and propagates the traces with
+// the W3C traceparent header.
+func Install(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// correlationProcessor adds the correlation ID of the request as the correlation_id
+// attribute of every span.
+type correlationProcessor struct{}
+
+func (correlationProcessor) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	if id := ctxutil.GetCorrelationID(ctx); id != "" {
+		span.SetAttributes(attribute.String("correlation_id", id))
+	}
+}
+
+func (correlationProcessor) OnEnd(sdktrace.ReadOnlySpan)          {}
+func (correlationProcessor) Shutdown(context.Context) error   { return nil }
+func (correlationProcessor) ForceFlush(context.Context) error { return nil }
+
+// RecordError records err as an exception event and marks the span as failed.
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// RecordResponseStatus adds the status code of an outgoing request to span and marks
+// the span as failed for 4xx and 5xx responses.
+func RecordResponseStatus(span trace.Span, status int) {
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if status >= http.StatusBadRequest {
+		RecordError(span, fmt.Errorf("HTTP status %d", status))
+	}
+}
@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	ctxutil "3tcapital/goclonacion/internal/infrastructure/context"
+)
+
+// installProvider installs a provider exporting to memory for the duration of the test.
+func installProvider(t *testing.T, sampleRatio float64) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(exporter, sampleRatio)
+	previous, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	Install(provider)
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return provider, exporter
+}
+
+func flushed(t *testing.T, provider *sdktrace.TracerProvider, exporter *tracetest.InMemoryExporter) tracetest.SpanStubs {
+	t.Helper()
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	return exporter.GetSpans()
+}
+
+// attributeValue returns the value of the last attribute of span with the given key.
+func attributeValue(span tracetest.SpanStub, key string) (attribute.Value, bool) {
+	for i := len(span.Attributes) - 1; i >= 0; i-- {
+		if string(span.Attributes[i].Key) == key {
+			return span.Attributes[i].Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestProvider_ParentAndChildSpans(t *testing.T) {
+	provider, exporter := installProvider(t, 1)
+	tracer := otel.Tracer("test")
+
+	ctx := ctxutil.WithCorrelationID(context.Background(), "corr-123")
+	ctx, parent := tracer.Start(ctx, "POST /api/v1/documentos", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tracer.Start(ctx, "invoice.RegisterDocument", trace.WithAttributes(attribute.String("document.type", "FC")))
+	RecordError(child, errors.New("proveedor no disponible"))
+	child.End()
+	parent.End()
+
+	spans := flushed(t, provider, exporter)
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	childData, parentData := spans[0], spans[1]
+
+	if parentData.Parent.IsValid() {
+		t.Error("expected the server span to be a root span")
+	}
+	if childData.SpanContext.TraceID() != parentData.SpanContext.TraceID() || childData.Parent.SpanID() != parentData.SpanContext.SpanID() {
+		t.Error("expected the child span in the trace of its parent")
+	}
+	if v, _ := attributeValue(childData, "correlation_id"); v.AsString() != "corr-123" {
+		t.Errorf("expected the correlation ID attribute, got %v", v.Emit())
+	}
+	if v, _ := attributeValue(childData, "document.type"); v.AsString() != "FC" {
+		t.Errorf("expected the start attributes, got %v", v.Emit())
+	}
+	if childData.Status.Code != codes.Error || childData.Status.Description != "proveedor no disponible" || len(childData.Events) != 1 {
+		t.Errorf("expected the recorded error, got %+v", childData)
+	}
+	if parentData.SpanKind != trace.SpanKindServer || parentData.EndTime.Before(parentData.StartTime) {
+		t.Errorf("unexpected server span %+v", parentData)
+	}
+}
+
+func TestProvider_RemoteParent(t *testing.T) {
+	provider, exporter := installProvider(t, 0)
+
+	header := propagation.HeaderCarrier{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), header)
+	_, span := otel.Tracer("test").Start(ctx, "operation")
+	span.End()
+
+	// The caller sampled the trace: it is recorded even with a sample ratio of 0
+	spans := flushed(t, provider, exporter)
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || spans[0].Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the span to continue the remote trace, got %+v", spans[0])
+	}
+}
+
+func TestProvider_NotSampled(t *testing.T) {
+	provider, exporter := installProvider(t, 0)
+	tracer := otel.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	if spans := flushed(t, provider, exporter); len(spans) != 0 {
+		t.Errorf("expected no recorded spans, got %d", len(spans))
+	}
+	if !parent.SpanContext().IsValid() || parent.SpanContext().IsSampled() {
+		t.Errorf("expected a valid unsampled span context to propagate, got %+v", parent.SpanContext())
+	}
+	if child.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Error("expected unsampled children in the same trace")
+	}
+}
+
+func TestProvider_RemoteNotSampled(t *testing.T) {
+	provider, exporter := installProvider(t, 1)
+
+	header := propagation.HeaderCarrier{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), header)
+	_, span := otel.Tracer("test").Start(ctx, "operation")
+	span.End()
+
+	if spans := flushed(t, provider, exporter); len(spans) != 0 {
+		t.Errorf("expected the sampling decision of the caller to be kept, got %d spans", len(spans))
+	}
+}
+
+func TestProvider_ExportsInBatches(t *testing.T) {
+	provider, exporter := installProvider(t, 1)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "operation")
+	span.End()
+
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("expected the ended span queued until the batch is exported, got %d", len(spans))
+	}
+	if spans := flushed(t, provider, exporter); len(spans) != 1 {
+		t.Errorf("expected the queued span exported, got %d", len(spans))
+	}
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport is an http.RoundTripper that records a client span for every request
+// and propagates the trace with the traceparent header.
+type Transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport wraps base, or http.DefaultTransport when base is nil.
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base}
+}
+
+// RoundTrip sends the request within a client span.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(ScopeName).Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Hostname()),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer span.End()
+
+	// RoundTrip must not modify the caller's request
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		RecordError(span, err)
+		return nil, err
+	}
+	RecordResponseStatus(span, resp.StatusCode)
+	return resp, nil
+}
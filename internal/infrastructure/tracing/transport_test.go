@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTransport_RoundTrip(t *testing.T) {
+	provider, exporter := installProvider(t, 1)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "catalog.refresh")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/resource/gdxc-w37w.json", nil)
+	resp, err := (&http.Client{Transport: NewTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if req.Header.Get("traceparent") != "" {
+		t.Error("expected the caller's request to be left unchanged")
+	}
+
+	spans := flushed(t, provider, exporter)
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	client := spans[0]
+	if client.Name != "HTTP GET" || client.SpanKind != trace.SpanKindClient || client.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("unexpected client span %+v", client)
+	}
+	want := "00-" + client.SpanContext.TraceID().String() + "-" + client.SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("expected traceparent of the client span %q, got %q", want, traceparent)
+	}
+	if v, _ := attributeValue(client, "http.response.status_code"); v.AsInt64() != http.StatusNotFound || client.Status.Code != codes.Error {
+		t.Errorf("expected the error status recorded, got %+v", client)
+	}
+}
@@ -0,0 +1,122 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/acquirer"
+)
+
+// MockAcquirerRepository is an in-memory implementation of acquirer.Repository for testing.
+type MockAcquirerRepository struct {
+	mu        sync.Mutex
+	nextID    int64
+	acquirers map[string]*acquirer.Acquirer
+}
+
+// NewMockAcquirerRepository creates an in-memory acquirer registry with the given acquirers.
+func NewMockAcquirerRepository(acquirers ...acquirer.Acquirer) *MockAcquirerRepository {
+	m := &MockAcquirerRepository{acquirers: make(map[string]*acquirer.Acquirer)}
+	for _, a := range acquirers {
+		_, _ = m.Create(context.Background(), a)
+	}
+	return m
+}
+
+func acquirerKey(ofeIdentificacion, adqIdentificacion, adqIdPersonalizado string) string {
+	return ofeIdentificacion + "|" + adqIdentificacion + "|" + adqIdPersonalizado
+}
+
+func (m *MockAcquirerRepository) keyOf(a acquirer.Acquirer) string {
+	personalizado := ""
+	if a.AdqIDPersonalizado != nil {
+		personalizado = *a.AdqIDPersonalizado
+	}
+	return acquirerKey(a.OfeIdentificacion, a.AdqIdentificacion, personalizado)
+}
+
+// Create stores a new acquirer.
+func (m *MockAcquirerRepository) Create(ctx context.Context, a acquirer.Acquirer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := m.keyOf(a)
+	if _, ok := m.acquirers[key]; ok {
+		return 0, fmt.Errorf("el Adquiriente [%s] para el OFE [%s] ya existe", a.AdqIdentificacion, a.OfeIdentificacion)
+	}
+	m.nextID++
+	a.ID = m.nextID
+	a.CreatedAt = time.Now()
+	a.UpdatedAt = a.CreatedAt
+	m.acquirers[key] = &a
+	return a.ID, nil
+}
+
+// Update replaces the stored acquirer, which is re-keyed with the identifiers of a.
+func (m *MockAcquirerRepository) Update(ctx context.Context, ofeIdentificacion, adqIdentificacion, adqIdPersonalizado string, a acquirer.Acquirer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := acquirerKey(ofeIdentificacion, adqIdentificacion, adqIdPersonalizado)
+	existing, ok := m.acquirers[key]
+	if !ok {
+		return fmt.Errorf("el Adquiriente [%s] para el OFE [%s] no existe", adqIdentificacion, ofeIdentificacion)
+	}
+	a.ID = existing.ID
+	a.CreatedAt = existing.CreatedAt
+	a.UpdatedAt = time.Now()
+	delete(m.acquirers, key)
+	m.acquirers[m.keyOf(a)] = &a
+	return nil
+}
+
+// FindByID returns a copy of the stored acquirer, or nil when it does not exist.
+func (m *MockAcquirerRepository) FindByID(ctx context.Context, ofeIdentificacion, adqIdentificacion, adqIdPersonalizado string) (*acquirer.Acquirer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.acquirers[acquirerKey(ofeIdentificacion, adqIdentificacion, adqIdPersonalizado)]
+	if !ok {
+		return nil, nil
+	}
+	cp := *a
+	return &cp, nil
+}
+
+// Exists reports whether the acquirer is stored.
+func (m *MockAcquirerRepository) Exists(ctx context.Context, ofeIdentificacion, adqIdentificacion, adqIdPersonalizado string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.acquirers[acquirerKey(ofeIdentificacion, adqIdentificacion, adqIdPersonalizado)]
+	return ok, nil
+}
+
+// Search returns the acquirers of the OFE whose identification equals valorBuscar; the
+// other fields and filters are not supported.
+func (m *MockAcquirerRepository) Search(ctx context.Context, campoBuscar, valorBuscar, valorOfe, filtroColumnas string) ([]acquirer.Acquirer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result []acquirer.Acquirer
+	for _, a := range m.acquirers {
+		if a.OfeIdentificacion == valorOfe && a.AdqIdentificacion == valorBuscar {
+			result = append(result, *a)
+		}
+	}
+	return result, nil
+}
+
+// List returns every stored acquirer, ignoring pagination, search and sorting.
+func (m *MockAcquirerRepository) List(ctx context.Context, start, length int, buscar, columnaOrden, ordenDireccion string) ([]acquirer.Acquirer, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]acquirer.Acquirer, 0, len(m.acquirers))
+	for _, a := range m.acquirers {
+		result = append(result, *a)
+	}
+	return result, len(result), nil
+}
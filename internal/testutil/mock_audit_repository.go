@@ -0,0 +1,257 @@
+package testutil
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/audit"
+)
+
+// MockAuditRepository is an in-memory implementation of audit.Repository, audit.Reader,
+// audit.Retention and audit.ChainStore for testing. Saved logs are hash chained per month.
+type MockAuditRepository struct {
+	mu          sync.Mutex
+	nextID      int64
+	logs        []audit.ProviderAuditLog
+	partitions  map[string]bool
+	checkpoints []audit.Checkpoint
+
+	// DropErr, when set, is returned by DropPartitionsBefore.
+	DropErr error
+}
+
+// NewMockAuditRepository creates an empty in-memory audit log.
+func NewMockAuditRepository() *MockAuditRepository {
+	return &MockAuditRepository{partitions: make(map[string]bool)}
+}
+
+// Save appends the audit log at the head of its chain, keeping its CreatedAt when set.
+func (m *MockAuditRepository) Save(ctx context.Context, log audit.ProviderAuditLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	log.ID = m.nextID
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+
+	chain := audit.ChainOf(log.CreatedAt)
+	log.PrevHash = ""
+	for i := len(m.logs) - 1; i >= 0; i-- {
+		if audit.ChainOf(m.logs[i].CreatedAt) == chain {
+			log.PrevHash = m.logs[i].Hash
+			break
+		}
+	}
+	hash, err := audit.ChainHash(log.PrevHash, log)
+	if err != nil {
+		return err
+	}
+	log.Hash = hash
+
+	m.logs = append(m.logs, log)
+	return nil
+}
+
+// Tamper modifies a stored log in place, without updating its hash.
+func (m *MockAuditRepository) Tamper(id int64, fn func(log *audit.ProviderAuditLog)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.logs {
+		if m.logs[i].ID == id {
+			fn(&m.logs[i])
+		}
+	}
+}
+
+// FindByCorrelationID returns the audit logs with the given correlation ID.
+func (m *MockAuditRepository) FindByCorrelationID(ctx context.Context, correlationID string) ([]audit.ProviderAuditLog, error) {
+	logs, _, err := m.Search(ctx, audit.Filter{CorrelationID: correlationID})
+	return logs, err
+}
+
+// Search returns the audit logs matching the filter, newest first, paginated.
+func (m *MockAuditRepository) Search(ctx context.Context, filter audit.Filter) ([]audit.ProviderAuditLog, int, error) {
+	logs := m.matching(filter)
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].CreatedAt.After(logs[j].CreatedAt) })
+
+	total := len(logs)
+	if filter.Start > 0 {
+		logs = logs[min(filter.Start, len(logs)):]
+	}
+	if filter.Length > 0 && filter.Length < len(logs) {
+		logs = logs[:filter.Length]
+	}
+	return logs, total, nil
+}
+
+// Export calls fn for every audit log matching the filter, oldest first.
+func (m *MockAuditRepository) Export(ctx context.Context, filter audit.Filter, fn func(audit.ProviderAuditLog) error) error {
+	logs := m.matching(filter)
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].CreatedAt.Before(logs[j].CreatedAt) })
+
+	for _, log := range logs {
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsurePartition records the partition of the month of t.
+func (m *MockAuditRepository) EnsurePartition(ctx context.Context, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.partitions[t.Format("2006_01")] = true
+	return nil
+}
+
+// DropPartitionsBefore removes the recorded partitions of the months before the month of t.
+func (m *MockAuditRepository) DropPartitionsBefore(ctx context.Context, t time.Time) ([]string, error) {
+	if m.DropErr != nil {
+		return nil, m.DropErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff := t.Format("2006_01")
+	var dropped []string
+	for month := range m.partitions {
+		if month < cutoff {
+			delete(m.partitions, month)
+			dropped = append(dropped, month)
+		}
+	}
+	sort.Strings(dropped)
+	return dropped, nil
+}
+
+// Partitions returns the recorded partitions as YYYY_MM, sorted.
+func (m *MockAuditRepository) Partitions() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	partitions := make([]string, 0, len(m.partitions))
+	for month := range m.partitions {
+		partitions = append(partitions, month)
+	}
+	sort.Strings(partitions)
+	return partitions
+}
+
+// Chains returns the months with stored logs, oldest first.
+func (m *MockAuditRepository) Chains(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make(map[string]bool)
+	chains := make([]string, 0)
+	for _, log := range m.logs {
+		chain := audit.ChainOf(log.CreatedAt)
+		if !seen[chain] {
+			seen[chain] = true
+			chains = append(chains, chain)
+		}
+	}
+	sort.Strings(chains)
+	return chains, nil
+}
+
+// WalkChain calls fn for every log of the chain in ID order.
+func (m *MockAuditRepository) WalkChain(ctx context.Context, chain string, fn func(audit.ProviderAuditLog) error) error {
+	for _, log := range m.chain(chain) {
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChainHead returns the last log of the chain, or nil if the chain is empty.
+func (m *MockAuditRepository) ChainHead(ctx context.Context, chain string) (*audit.ProviderAuditLog, error) {
+	logs := m.chain(chain)
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	return &logs[len(logs)-1], nil
+}
+
+// SaveCheckpoint stores the checkpoint.
+func (m *MockAuditRepository) SaveCheckpoint(ctx context.Context, checkpoint audit.Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	checkpoint.ID = int64(len(m.checkpoints) + 1)
+	m.checkpoints = append(m.checkpoints, checkpoint)
+	return nil
+}
+
+// Checkpoints returns the checkpoints of the chain, oldest first.
+func (m *MockAuditRepository) Checkpoints(ctx context.Context, chain string) ([]audit.Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	checkpoints := make([]audit.Checkpoint, 0)
+	for _, cp := range m.checkpoints {
+		if cp.Chain == chain {
+			checkpoints = append(checkpoints, cp)
+		}
+	}
+	return checkpoints, nil
+}
+
+// chain returns a copy of the logs of the chain in ID order.
+func (m *MockAuditRepository) chain(chain string) []audit.ProviderAuditLog {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logs := make([]audit.ProviderAuditLog, 0)
+	for _, log := range m.logs {
+		if audit.ChainOf(log.CreatedAt) == chain {
+			logs = append(logs, log)
+		}
+	}
+	return logs
+}
+
+// matching returns a copy of the audit logs that match the filter.
+func (m *MockAuditRepository) matching(filter audit.Filter) []audit.ProviderAuditLog {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logs := make([]audit.ProviderAuditLog, 0)
+	for _, log := range m.logs {
+		if !filter.From.IsZero() && log.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !log.CreatedAt.Before(filter.To) {
+			continue
+		}
+		if filter.Provider != "" && log.Provider != filter.Provider {
+			continue
+		}
+		if filter.Operation != "" && log.Operation != filter.Operation {
+			continue
+		}
+		if filter.Status != nil && (log.ResponseStatus == nil || *log.ResponseStatus != *filter.Status) {
+			continue
+		}
+		if log.DurationMs < filter.MinDurationMs {
+			continue
+		}
+		if filter.CorrelationID != "" && log.CorrelationID != filter.CorrelationID {
+			continue
+		}
+		if filter.Document != "" && !strings.Contains(log.RequestURL+string(log.RequestBody)+string(log.ResponseBody), filter.Document) {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	return logs
+}
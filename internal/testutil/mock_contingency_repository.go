@@ -0,0 +1,167 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/contingency"
+)
+
+// MockContingencyRepository is an in-memory implementation of contingency.Repository for testing.
+type MockContingencyRepository struct {
+	mu           sync.Mutex
+	numbers      map[string]int64
+	docs         []*contingency.Document
+	state        contingency.State
+	transmitting sync.Mutex
+
+	// SaveErr, when set, is returned by Save.
+	SaveErr error
+}
+
+// NewMockContingencyRepository creates an empty in-memory contingency store.
+func NewMockContingencyRepository() *MockContingencyRepository {
+	return &MockContingencyRepository{numbers: make(map[string]int64)}
+}
+
+// NextNumber reserves the next number of the OFE contingency numbering.
+func (m *MockContingencyRepository) NextNumber(ctx context.Context, ofeIdentificacion string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.numbers[ofeIdentificacion]++
+	return m.numbers[ofeIdentificacion], nil
+}
+
+// Save stores a pending document and returns its ID.
+func (m *MockContingencyRepository) Save(ctx context.Context, doc contingency.Document) (int64, error) {
+	if m.SaveErr != nil {
+		return 0, m.SaveErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	doc.ID = int64(len(m.docs) + 1)
+	doc.Estado = contingency.StatusPending
+	doc.CreatedAt = time.Now()
+	m.docs = append(m.docs, &doc)
+	return doc.ID, nil
+}
+
+// ListPending retrieves the pending documents in the order they were stored.
+func (m *MockContingencyRepository) ListPending(ctx context.Context) ([]contingency.Document, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pending []contingency.Document
+	for _, doc := range m.docs {
+		if doc.Estado == contingency.StatusPending {
+			pending = append(pending, *doc)
+		}
+	}
+	return pending, nil
+}
+
+// RecordAttempt counts a transmission attempt that could not reach the provider.
+func (m *MockContingencyRepository) RecordAttempt(ctx context.Context, id int64, errMsg string) error {
+	return m.update(id, func(doc *contingency.Document) {
+		doc.Intentos++
+		doc.Errors = []string{errMsg}
+	})
+}
+
+// MarkTransmitted flags the document as transmitted and accepted.
+func (m *MockContingencyRepository) MarkTransmitted(ctx context.Context, id int64, cufe string) error {
+	return m.update(id, func(doc *contingency.Document) {
+		now := time.Now()
+		doc.Estado = contingency.StatusTransmitted
+		doc.Intentos++
+		doc.CUFE = cufe
+		doc.Errors = nil
+		doc.TransmittedAt = &now
+	})
+}
+
+// MarkFailed flags the document as transmitted and rejected.
+func (m *MockContingencyRepository) MarkFailed(ctx context.Context, id int64, errs []string) error {
+	return m.update(id, func(doc *contingency.Document) {
+		now := time.Now()
+		doc.Estado = contingency.StatusFailed
+		doc.Intentos++
+		doc.Errors = errs
+		doc.TransmittedAt = &now
+	})
+}
+
+// Summary counts the stored documents by transmission state.
+func (m *MockContingencyRepository) Summary(ctx context.Context) (*contingency.Summary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	summary := &contingency.Summary{PendientesPorOfe: make(map[string]int)}
+	for _, doc := range m.docs {
+		switch doc.Estado {
+		case contingency.StatusPending:
+			summary.Pendientes++
+			summary.PendientesPorOfe[doc.OfeIdentificacion]++
+			if summary.PendienteMasAntiguo == nil || doc.CreatedAt.Before(*summary.PendienteMasAntiguo) {
+				createdAt := doc.CreatedAt
+				summary.PendienteMasAntiguo = &createdAt
+			}
+		case contingency.StatusTransmitted:
+			summary.Transmitidos++
+		case contingency.StatusFailed:
+			summary.Fallidos++
+		}
+	}
+	return summary, nil
+}
+
+// LoadState retrieves the persisted contingency mode.
+func (m *MockContingencyRepository) LoadState(ctx context.Context) (contingency.State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state, nil
+}
+
+// SaveState persists the contingency mode.
+func (m *MockContingencyRepository) SaveState(ctx context.Context, state contingency.State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+	return nil
+}
+
+// LockTransmission takes the transmission lock if no one holds it.
+func (m *MockContingencyRepository) LockTransmission(ctx context.Context) (func(), bool, error) {
+	if !m.transmitting.TryLock() {
+		return nil, false, nil
+	}
+	return m.transmitting.Unlock, true, nil
+}
+
+// Documents returns a copy of every stored document, in storage order.
+func (m *MockContingencyRepository) Documents() []contingency.Document {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs := make([]contingency.Document, 0, len(m.docs))
+	for _, doc := range m.docs {
+		docs = append(docs, *doc)
+	}
+	return docs
+}
+
+func (m *MockContingencyRepository) update(id int64, fn func(doc *contingency.Document)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, doc := range m.docs {
+		if doc.ID == id {
+			fn(doc)
+			return nil
+		}
+	}
+	return nil
+}
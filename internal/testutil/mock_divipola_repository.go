@@ -0,0 +1,34 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+
+	"3tcapital/goclonacion/internal/core/dane"
+)
+
+// MockDivipolaRepository is an in-memory implementation of dane.Repository for testing.
+type MockDivipolaRepository struct {
+	mu             sync.Mutex
+	municipalities []dane.Municipality
+}
+
+// NewMockDivipolaRepository creates an in-memory store with the given municipalities.
+func NewMockDivipolaRepository(municipalities ...dane.Municipality) *MockDivipolaRepository {
+	return &MockDivipolaRepository{municipalities: municipalities}
+}
+
+// ListMunicipalities returns the stored municipalities.
+func (m *MockDivipolaRepository) ListMunicipalities(ctx context.Context) ([]dane.Municipality, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]dane.Municipality(nil), m.municipalities...), nil
+}
+
+// ReplaceMunicipalities replaces the stored municipalities.
+func (m *MockDivipolaRepository) ReplaceMunicipalities(ctx context.Context, municipalities []dane.Municipality) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.municipalities = append([]dane.Municipality(nil), municipalities...)
+	return nil
+}
@@ -0,0 +1,166 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/document"
+)
+
+// MockDocumentRepository is an in-memory implementation of document.Repository for testing.
+type MockDocumentRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	docs   map[document.Key]*document.Document
+
+	// SaveErr and UpdateErr, when set, are returned by Save and UpdateStatus respectively.
+	SaveErr   error
+	UpdateErr error
+}
+
+// NewMockDocumentRepository creates an empty in-memory documents ledger.
+func NewMockDocumentRepository() *MockDocumentRepository {
+	return &MockDocumentRepository{docs: make(map[document.Key]*document.Document)}
+}
+
+// Save stores or resets the ledger entry for the document key.
+func (m *MockDocumentRepository) Save(ctx context.Context, doc document.Document) (int64, error) {
+	if m.SaveErr != nil {
+		return 0, m.SaveErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.save(doc), nil
+}
+
+func (m *MockDocumentRepository) save(doc document.Document) int64 {
+	now := time.Now()
+	key := doc.Key()
+	if existing, ok := m.docs[key]; ok {
+		doc.ID = existing.ID
+		doc.CreatedAt = existing.CreatedAt
+	} else {
+		m.nextID++
+		doc.ID = m.nextID
+		doc.CreatedAt = now
+	}
+	doc.UpdatedAt = now
+	m.docs[key] = &doc
+	return doc.ID
+}
+
+// Claim stores the entry unless one exists for the key in a status that cannot be taken over.
+func (m *MockDocumentRepository) Claim(ctx context.Context, doc document.Document, staleAfter time.Duration, takeover ...document.Status) (bool, error) {
+	if m.SaveErr != nil {
+		return false, m.SaveErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.docs[doc.Key()]; ok && !slices.Contains(takeover, existing.Estado) {
+		stale := (existing.Estado == document.StatusReceived || existing.Estado == document.StatusValidated) &&
+			time.Since(existing.UpdatedAt) > staleAfter
+		if !stale {
+			return false, nil
+		}
+	}
+	m.save(doc)
+	return true, nil
+}
+
+// UpdateStatus applies the update to the stored entry.
+func (m *MockDocumentRepository) UpdateStatus(ctx context.Context, key document.Key, update document.StatusUpdate) error {
+	if m.UpdateErr != nil {
+		return m.UpdateErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	doc, ok := m.docs[key]
+	if !ok {
+		return fmt.Errorf("documento no encontrado: %+v", key)
+	}
+	doc.Estado = update.Estado
+	if len(update.EnrichedPayload) > 0 {
+		doc.EnrichedPayload = update.EnrichedPayload
+	}
+	if update.CUFE != "" {
+		doc.CUFE = update.CUFE
+	}
+	if update.CUFECalculado != "" {
+		doc.CUFECalculado = update.CUFECalculado
+	}
+	if update.CdoID != nil {
+		doc.CdoID = update.CdoID
+	}
+	if update.XmlBase64 != "" {
+		doc.XmlBase64 = update.XmlBase64
+	}
+	if update.PdfBase64 != "" {
+		doc.PdfBase64 = update.PdfBase64
+	}
+	if update.XmlRef != "" {
+		doc.XmlRef = update.XmlRef
+	}
+	if update.PdfRef != "" {
+		doc.PdfRef = update.PdfRef
+	}
+	if update.Errors != nil {
+		doc.Errors = update.Errors
+	}
+	doc.UpdatedAt = time.Now()
+	return nil
+}
+
+// FindByKey returns a copy of the stored entry, or nil if not found.
+func (m *MockDocumentRepository) FindByKey(ctx context.Context, key document.Key) (*document.Document, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	doc, ok := m.docs[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *doc
+	return &copied, nil
+}
+
+// List returns the entries matching the filter. Pagination is not applied.
+func (m *MockDocumentRepository) List(ctx context.Context, filter document.Filter) ([]document.Document, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs := make([]document.Document, 0)
+	for _, doc := range m.docs {
+		if filter.OfeIdentificacion != "" && doc.OfeIdentificacion != filter.OfeIdentificacion {
+			continue
+		}
+		if filter.Tipo != "" && doc.Tipo != filter.Tipo {
+			continue
+		}
+		if filter.Prefijo != "" && doc.Prefijo != filter.Prefijo {
+			continue
+		}
+		if filter.Consecutivo != "" && doc.Consecutivo != filter.Consecutivo {
+			continue
+		}
+		if filter.CUFE != "" && doc.CUFE != filter.CUFE {
+			continue
+		}
+		if filter.Referencia != "" && doc.Referencia != filter.Referencia {
+			continue
+		}
+		if filter.CdoID != nil && (doc.CdoID == nil || *doc.CdoID != *filter.CdoID) {
+			continue
+		}
+		if filter.Estado != "" && doc.Estado != filter.Estado {
+			continue
+		}
+		docs = append(docs, *doc)
+	}
+	return docs, len(docs), nil
+}
@@ -0,0 +1,76 @@
+package testutil
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/currency"
+)
+
+// MockExchangeRateRepository is an in-memory implementation of currency.Repository for testing.
+type MockExchangeRateRepository struct {
+	mu    sync.Mutex
+	rates []currency.Rate
+
+	// Lookups counts the calls to Rate.
+	Lookups int
+}
+
+// NewMockExchangeRateRepository creates an in-memory store with the given rates.
+func NewMockExchangeRateRepository(rates ...currency.Rate) *MockExchangeRateRepository {
+	return &MockExchangeRateRepository{rates: rates}
+}
+
+// Rate returns the latest rate of the currency on or before the date, or nil.
+func (m *MockExchangeRateRepository) Rate(ctx context.Context, code string, date time.Time) (*currency.Rate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Lookups++
+	var found *currency.Rate
+	for i, rate := range m.rates {
+		if rate.Currency != code || rate.Date.After(date) {
+			continue
+		}
+		if found == nil || rate.Date.After(found.Date) {
+			found = &m.rates[i]
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	rate := *found
+	return &rate, nil
+}
+
+// Save creates or replaces the rate of a currency and date.
+func (m *MockExchangeRateRepository) Save(ctx context.Context, rate currency.Rate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.rates {
+		if m.rates[i].Currency == rate.Currency && m.rates[i].Date.Equal(rate.Date) {
+			m.rates[i] = rate
+			return nil
+		}
+	}
+	m.rates = append(m.rates, rate)
+	return nil
+}
+
+// List returns the rates of a currency between two dates, newest first.
+func (m *MockExchangeRateRepository) List(ctx context.Context, code string, from, to time.Time) ([]currency.Rate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rates []currency.Rate
+	for _, rate := range m.rates {
+		if rate.Currency == code && !rate.Date.Before(from) && !rate.Date.After(to) {
+			rates = append(rates, rate)
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Date.After(rates[j].Date) })
+	return rates, nil
+}
@@ -0,0 +1,99 @@
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/idempotency"
+)
+
+// MockIdempotencyRepository is an in-memory implementation of idempotency.Repository for testing.
+type MockIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*idempotency.Record
+
+	// CompleteErr, when set, is returned by Complete.
+	CompleteErr error
+}
+
+// NewMockIdempotencyRepository creates an empty in-memory idempotency store.
+func NewMockIdempotencyRepository() *MockIdempotencyRepository {
+	return &MockIdempotencyRepository{records: make(map[string]*idempotency.Record)}
+}
+
+// Reserve creates an in-progress record if the key has not been used or the lease of the
+// same request expired.
+func (m *MockIdempotencyRepository) Reserve(ctx context.Context, scope, key, requestHash string, lease time.Duration) (*idempotency.Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := m.records[scope+"|"+key]; ok {
+		if existing.Status != idempotency.StatusInProgress || now.Before(existing.LeaseUntil) || existing.RequestHash != requestHash {
+			copied := *existing
+			return &copied, false, nil
+		}
+	}
+	m.records[scope+"|"+key] = &idempotency.Record{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		Status:      idempotency.StatusInProgress,
+		CreatedAt:   now,
+		LeaseUntil:  now.Add(lease),
+	}
+	return nil, true, nil
+}
+
+// Extend renews the lease of an in-progress record.
+func (m *MockIdempotencyRepository) Extend(ctx context.Context, scope, key string, lease time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record, ok := m.records[scope+"|"+key]; ok && record.Status == idempotency.StatusInProgress {
+		record.LeaseUntil = time.Now().Add(lease)
+	}
+	return nil
+}
+
+// Complete stores the response and marks the record as completed.
+func (m *MockIdempotencyRepository) Complete(ctx context.Context, scope, key string, response json.RawMessage) error {
+	if m.CompleteErr != nil {
+		return m.CompleteErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[scope+"|"+key]
+	if !ok {
+		return fmt.Errorf("idempotency key no encontrada: %s", key)
+	}
+	record.Status = idempotency.StatusCompleted
+	record.Response = response
+	return nil
+}
+
+// Release removes the record.
+func (m *MockIdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, scope+"|"+key)
+	return nil
+}
+
+// Get returns a copy of the stored record, or nil if not found.
+func (m *MockIdempotencyRepository) Get(scope, key string) *idempotency.Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[scope+"|"+key]
+	if !ok {
+		return nil
+	}
+	copied := *record
+	return &copied
+}
@@ -0,0 +1,110 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/ofe"
+)
+
+// MockOFERepository is an in-memory implementation of ofe.Repository for testing.
+type MockOFERepository struct {
+	mu     sync.Mutex
+	nextID int64
+	ofes   map[string]*ofe.OFE
+
+	// FindErr, when set, is returned by FindByIdentificacion.
+	FindErr error
+	// Lookups counts the calls to FindByIdentificacion.
+	Lookups int
+}
+
+// NewMockOFERepository creates an in-memory OFE registry with the given OFEs.
+func NewMockOFERepository(ofes ...ofe.OFE) *MockOFERepository {
+	m := &MockOFERepository{ofes: make(map[string]*ofe.OFE)}
+	for _, o := range ofes {
+		_, _ = m.Create(context.Background(), o)
+	}
+	return m
+}
+
+// Create stores a new OFE.
+func (m *MockOFERepository) Create(ctx context.Context, o ofe.OFE) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.ofes[o.OfeIdentificacion]; ok {
+		return 0, fmt.Errorf("ya existe un OFE con el numero de identificacion [%s]", o.OfeIdentificacion)
+	}
+	m.nextID++
+	o.ID = m.nextID
+	o.CreatedAt = time.Now()
+	o.UpdatedAt = o.CreatedAt
+	m.ofes[o.OfeIdentificacion] = &o
+	return o.ID, nil
+}
+
+// Update replaces the stored OFE, keeping the Numrot credentials when o.Numrot is nil.
+func (m *MockOFERepository) Update(ctx context.Context, ofeIdentificacion string, o ofe.OFE) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.ofes[ofeIdentificacion]
+	if !ok {
+		return fmt.Errorf("el OFE [%s] no existe", ofeIdentificacion)
+	}
+	if o.Numrot == nil {
+		o.Numrot = existing.Numrot
+	}
+	o.ID = existing.ID
+	o.OfeIdentificacion = ofeIdentificacion
+	o.CreatedAt = existing.CreatedAt
+	o.UpdatedAt = time.Now()
+	m.ofes[ofeIdentificacion] = &o
+	return nil
+}
+
+// FindByIdentificacion returns a copy of the stored OFE or nil if not found.
+func (m *MockOFERepository) FindByIdentificacion(ctx context.Context, ofeIdentificacion string) (*ofe.OFE, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Lookups++
+	if m.FindErr != nil {
+		return nil, m.FindErr
+	}
+	o, ok := m.ofes[ofeIdentificacion]
+	if !ok {
+		return nil, nil
+	}
+	cp := *o
+	return &cp, nil
+}
+
+// List returns the OFEs matching buscar ordered by identification.
+func (m *MockOFERepository) List(ctx context.Context, start, length int, buscar string) ([]ofe.OFE, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var matched []ofe.OFE
+	for _, o := range m.ofes {
+		if buscar == "" || strings.Contains(o.OfeIdentificacion, buscar) || strings.Contains(strings.ToLower(o.OfeRazonSocial), strings.ToLower(buscar)) {
+			matched = append(matched, *o)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].OfeIdentificacion < matched[j].OfeIdentificacion })
+
+	total := len(matched)
+	if start > total {
+		start = total
+	}
+	end := total
+	if length != -1 && start+length < end {
+		end = start + length
+	}
+	return matched[start:end], total, nil
+}
@@ -3,9 +3,9 @@ package testutil
 import (
 	"context"
 
-	"3tcapital/ms_facturacion_core/internal/core/event"
-	"3tcapital/ms_facturacion_core/internal/core/invoice"
-	"3tcapital/ms_facturacion_core/internal/core/resolution"
+	"3tcapital/goclonacion/internal/core/event"
+	"3tcapital/goclonacion/internal/core/invoice"
+	"3tcapital/goclonacion/internal/core/resolution"
 )
 
 // MockProvider is a mock implementation of invoice.Provider for testing.
@@ -0,0 +1,114 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/provider"
+)
+
+// MockProviderRepository is an in-memory implementation of provider.Repository for testing.
+type MockProviderRepository struct {
+	mu        sync.Mutex
+	nextID    int64
+	providers map[string]*provider.Provider
+}
+
+// NewMockProviderRepository creates an in-memory provider registry with the given providers.
+func NewMockProviderRepository(providers ...provider.Provider) *MockProviderRepository {
+	m := &MockProviderRepository{providers: make(map[string]*provider.Provider)}
+	for _, p := range providers {
+		_, _ = m.Create(context.Background(), p)
+	}
+	return m
+}
+
+func providerKey(ofeIdentificacion, proIdentificacion string) string {
+	return ofeIdentificacion + "|" + proIdentificacion
+}
+
+// Create stores a new provider.
+func (m *MockProviderRepository) Create(ctx context.Context, p provider.Provider) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := providerKey(p.OfeIdentificacion, p.ProIdentificacion)
+	if _, ok := m.providers[key]; ok {
+		return 0, fmt.Errorf("ya existe un Proveedor con el numero de identificacion [%s] para el OFE [%s]", p.ProIdentificacion, p.OfeIdentificacion)
+	}
+	m.nextID++
+	p.ID = m.nextID
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	m.providers[key] = &p
+	return p.ID, nil
+}
+
+// Update replaces the stored provider, which is re-keyed with the identifiers of p.
+func (m *MockProviderRepository) Update(ctx context.Context, ofeIdentificacion, proIdentificacion string, p provider.Provider) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := providerKey(ofeIdentificacion, proIdentificacion)
+	existing, ok := m.providers[key]
+	if !ok {
+		return fmt.Errorf("el Proveedor [%s] para el OFE [%s] no existe", proIdentificacion, ofeIdentificacion)
+	}
+	p.ID = existing.ID
+	p.CreatedAt = existing.CreatedAt
+	p.UpdatedAt = time.Now()
+	delete(m.providers, key)
+	m.providers[providerKey(p.OfeIdentificacion, p.ProIdentificacion)] = &p
+	return nil
+}
+
+// FindByID returns a copy of the stored provider, or nil when it does not exist.
+func (m *MockProviderRepository) FindByID(ctx context.Context, ofeIdentificacion, proIdentificacion string) (*provider.Provider, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.providers[providerKey(ofeIdentificacion, proIdentificacion)]
+	if !ok {
+		return nil, nil
+	}
+	cp := *p
+	return &cp, nil
+}
+
+// Exists reports whether the provider is stored.
+func (m *MockProviderRepository) Exists(ctx context.Context, ofeIdentificacion, proIdentificacion string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.providers[providerKey(ofeIdentificacion, proIdentificacion)]
+	return ok, nil
+}
+
+// List returns every stored provider, ignoring pagination, search and sorting.
+func (m *MockProviderRepository) List(ctx context.Context, start, length int, buscar, columnaOrden, ordenDireccion string) ([]provider.Provider, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]provider.Provider, 0, len(m.providers))
+	for _, p := range m.providers {
+		result = append(result, *p)
+	}
+	return result, len(result), nil
+}
+
+// Search returns the providers of the OFE whose identification equals valorBuscar; the
+// other fields and filters are not supported.
+func (m *MockProviderRepository) Search(ctx context.Context, campoBuscar, valorBuscar, valorOfe, filtroColumnas string) ([]provider.Provider, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result []provider.Provider
+	for _, p := range m.providers {
+		if p.OfeIdentificacion == valorOfe && p.ProIdentificacion == valorBuscar {
+			result = append(result, *p)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,130 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"3tcapital/goclonacion/internal/core/resolution"
+)
+
+// MockResolutionRepository is an in-memory implementation of resolution.Repository for testing.
+type MockResolutionRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	ranges []*resolution.Range
+	used   map[int64]map[int64]bool
+
+	// Syncs counts the calls to Sync.
+	Syncs int
+}
+
+// NewMockResolutionRepository creates an empty in-memory resolution store.
+func NewMockResolutionRepository() *MockResolutionRepository {
+	return &MockResolutionRepository{used: make(map[int64]map[int64]bool)}
+}
+
+// Sync inserts or updates the resolutions of an OFE.
+func (m *MockResolutionRepository) Sync(ctx context.Context, ofeIdentificacion string, resolutions []resolution.Resolution) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Syncs++
+	now := time.Now()
+	for _, res := range resolutions {
+		if existing := m.find(ofeIdentificacion, res.ResolutionNumber, res.Prefix); existing != nil {
+			existing.Resolution = res
+			existing.SyncedAt = now
+			continue
+		}
+		m.nextID++
+		m.ranges = append(m.ranges, &resolution.Range{ID: m.nextID, OfeIdentificacion: ofeIdentificacion, Resolution: res, SyncedAt: now})
+		m.used[m.nextID] = make(map[int64]bool)
+	}
+	return nil
+}
+
+// List returns the ranges of an OFE, or of every OFE when ofeIdentificacion is empty.
+func (m *MockResolutionRepository) List(ctx context.Context, ofeIdentificacion string) ([]resolution.Range, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ranges []resolution.Range
+	for _, rng := range m.ranges {
+		if ofeIdentificacion == "" || rng.OfeIdentificacion == ofeIdentificacion {
+			ranges = append(ranges, *rng)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].OfeIdentificacion < ranges[j].OfeIdentificacion })
+	return ranges, nil
+}
+
+// Find returns a copy of the range or nil if not found.
+func (m *MockResolutionRepository) Find(ctx context.Context, ofeIdentificacion, resolutionNumber, prefix string) (*resolution.Range, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rng := m.find(ofeIdentificacion, resolutionNumber, prefix)
+	if rng == nil {
+		return nil, nil
+	}
+	cp := *rng
+	return &cp, nil
+}
+
+// Allocate hands out the next free consecutivo of the first range valid at the given date.
+func (m *MockResolutionRepository) Allocate(ctx context.Context, ofeIdentificacion, prefix string, at time.Time) (*resolution.Allocation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rng := range m.ranges {
+		if rng.OfeIdentificacion != ofeIdentificacion || rng.Prefix != prefix || !rng.ValidOn(at) {
+			continue
+		}
+		next := rng.LastNumber + 1
+		if next < rng.FromNumber {
+			next = rng.FromNumber
+		}
+		if next > rng.ToNumber {
+			continue
+		}
+		m.markUsed(rng, next)
+		return &resolution.Allocation{Consecutivo: next, Range: *rng}, nil
+	}
+	return nil, fmt.Errorf("no hay una resolución vigente con consecutivos disponibles para el OFE [%s] y prefijo [%s]", ofeIdentificacion, prefix)
+}
+
+// MarkUsed records a consecutivo of the range as used.
+func (m *MockResolutionRepository) MarkUsed(ctx context.Context, rangeID, consecutivo int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rng := range m.ranges {
+		if rng.ID == rangeID {
+			m.markUsed(rng, consecutivo)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *MockResolutionRepository) find(ofeIdentificacion, resolutionNumber, prefix string) *resolution.Range {
+	for _, rng := range m.ranges {
+		if rng.OfeIdentificacion == ofeIdentificacion && rng.ResolutionNumber == resolutionNumber && rng.Prefix == prefix {
+			return rng
+		}
+	}
+	return nil
+}
+
+func (m *MockResolutionRepository) markUsed(rng *resolution.Range, consecutivo int64) {
+	if !m.used[rng.ID][consecutivo] {
+		m.used[rng.ID][consecutivo] = true
+		rng.UsedCount++
+	}
+	if consecutivo > rng.LastNumber {
+		rng.LastNumber = consecutivo
+	}
+}
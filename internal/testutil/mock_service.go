@@ -3,7 +3,7 @@ package testutil
 import (
 	"context"
 
-	"3tcapital/ms_facturacion_core/internal/core/resolution"
+	"3tcapital/goclonacion/internal/core/resolution"
 )
 
 // MockResolutionService is a mock implementation of resolution service for testing.
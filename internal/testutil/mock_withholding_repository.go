@@ -0,0 +1,113 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"3tcapital/goclonacion/internal/core/withholding"
+)
+
+// MockWithholdingRepository is an in-memory implementation of withholding.Repository for testing.
+type MockWithholdingRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	rules  []withholding.Rule
+	uvt    map[int]string
+
+	// RuleLoads counts the calls to ListRules.
+	RuleLoads int
+}
+
+// NewMockWithholdingRepository creates an in-memory store with the given rules.
+func NewMockWithholdingRepository(rules ...withholding.Rule) *MockWithholdingRepository {
+	m := &MockWithholdingRepository{uvt: make(map[int]string)}
+	for _, rule := range rules {
+		m.nextID++
+		if rule.ID == 0 {
+			rule.ID = m.nextID
+		}
+		m.rules = append(m.rules, rule)
+	}
+	return m
+}
+
+// ListRules returns every rule ordered by ID.
+func (m *MockWithholdingRepository) ListRules(ctx context.Context) ([]withholding.Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RuleLoads++
+	rules := append([]withholding.Rule(nil), m.rules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+// SaveRule creates or updates a rule.
+func (m *MockWithholdingRepository) SaveRule(ctx context.Context, rule withholding.Rule) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rule.ID == 0 {
+		m.nextID++
+		rule.ID = m.nextID
+		m.rules = append(m.rules, rule)
+		return rule.ID, nil
+	}
+	for i := range m.rules {
+		if m.rules[i].ID == rule.ID {
+			m.rules[i] = rule
+			return rule.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("la regla de retención [%d] no existe", rule.ID)
+}
+
+// DeleteRule removes a rule.
+func (m *MockWithholdingRepository) DeleteRule(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.rules {
+		if m.rules[i].ID == id {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("la regla de retención [%d] no existe", id)
+}
+
+// ListUVT returns the UVT values ordered by year.
+func (m *MockWithholdingRepository) ListUVT(ctx context.Context) ([]withholding.UVT, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := make([]withholding.UVT, 0, len(m.uvt))
+	for anio, valor := range m.uvt {
+		values = append(values, withholding.UVT{Anio: anio, Valor: valor})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Anio < values[j].Anio })
+	return values, nil
+}
+
+// FindUVT returns the UVT value of a year, or nil.
+func (m *MockWithholdingRepository) FindUVT(ctx context.Context, anio int) (*withholding.UVT, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	valor, ok := m.uvt[anio]
+	if !ok {
+		return nil, nil
+	}
+	return &withholding.UVT{Anio: anio, Valor: valor}, nil
+}
+
+// SetUVT creates or replaces the UVT value of a year.
+func (m *MockWithholdingRepository) SetUVT(ctx context.Context, uvt withholding.UVT) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.uvt[uvt.Anio] = uvt.Valor
+	return nil
+}